@@ -0,0 +1,24 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// readClipboardImage returns the clipboard image as PNG.
+// AppleScript prints the PNG flavour of the clipboard as «data PNGf<hex>».
+func readClipboardImage() ([]byte, error) {
+	output, err := exec.Command("osascript", "-e", "the clipboard as «class PNGf»").Output()
+	if err != nil {
+		return nil, errors.New("the clipboard holds no image")
+	}
+
+	text := strings.TrimSpace(string(output))
+	if !strings.HasPrefix(text, "«data PNGf") || !strings.HasSuffix(text, "»") {
+		return nil, errors.New("unexpected clipboard data from osascript")
+	}
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "«data PNGf"), "»")
+	return hex.DecodeString(text)
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package main
+
+import "errors"
+
+// readClipboardImage is not supported on this platform
+func readClipboardImage() ([]byte, error) {
+	return nil, errors.New("reading the clipboard is not supported on this platform")
+}
@@ -0,0 +1,30 @@
+//go:build unix && !darwin
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// readClipboardImage returns the clipboard image as PNG, using wl-paste on Wayland or xclip on X11
+func readClipboardImage() ([]byte, error) {
+	tools := [][]string{
+		{"wl-paste", "--no-newline", "--type", "image/png"},
+		{"xclip", "-selection", "clipboard", "-target", "image/png", "-out"},
+	}
+	found := false
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		found = true
+		if output, err := exec.Command(tool[0], tool[1:]...).Output(); err == nil && len(output) > 0 {
+			return output, nil
+		}
+	}
+	if !found {
+		return nil, errors.New("reading the clipboard needs wl-paste (Wayland) or xclip (X11)")
+	}
+	return nil, errors.New("the clipboard holds no image")
+}
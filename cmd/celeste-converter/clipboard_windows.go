@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// clipboardScript prints the clipboard image as base64-encoded PNG, or nothing if there is none
+const clipboardScript = `Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+$image = [Windows.Forms.Clipboard]::GetImage()
+if ($image -ne $null) {
+	$stream = New-Object IO.MemoryStream
+	$image.Save($stream, [Drawing.Imaging.ImageFormat]::Png)
+	[Convert]::ToBase64String($stream.ToArray())
+}`
+
+// readClipboardImage returns the clipboard image as PNG, read through PowerShell
+func readClipboardImage() ([]byte, error) {
+	output, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-STA", "-Command", clipboardScript).Output()
+	if err != nil {
+		return nil, errors.New("failed to read the clipboard with PowerShell")
+	}
+
+	encoded := strings.TrimSpace(string(output))
+	if encoded == "" {
+		return nil, errors.New("the clipboard holds no image")
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
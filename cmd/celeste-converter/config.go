@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the config file read from the working directory when -config isn't given
+const configFileName = "celeste-converter.yaml"
+
+// profilesKey is the config key holding the named profiles
+const profilesKey = "profiles"
+
+// cliConfig holds option defaults from a config file, keyed by flag name, and named profiles of
+// further defaults
+type cliConfig struct {
+	path     string
+	options  map[string]any
+	profiles map[string]map[string]any
+}
+
+// loadConfig reads the config file at path, or configFileName in the working directory if path is empty.
+// A missing default config file isn't an error, it returns nil.
+func loadConfig(path string) (*cliConfig, error) {
+	explicit := path != ""
+	if !explicit {
+		path = configFileName
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config '%s': %w", path, err)
+	}
+	config := &cliConfig{path: path, options: raw, profiles: make(map[string]map[string]any)}
+	if profiles, ok := raw[profilesKey]; ok {
+		delete(raw, profilesKey)
+		named, ok := profiles.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("config '%s': %s must map profile names to options", path, profilesKey)
+		}
+		for name, options := range named {
+			profile, ok := options.(map[string]any)
+			if !ok && options != nil {
+				return nil, fmt.Errorf("config '%s': profile '%s' must map option names to values", path, name)
+			}
+			config.profiles[name] = profile
+		}
+	}
+	return config, nil
+}
+
+// apply sets every flag not given on the command line from the config, with the options of the named
+// profile, if any, overriding the top-level ones
+func (c *cliConfig) apply(profile string) error {
+	options := make(map[string]any, len(c.options))
+	for name, value := range c.options {
+		options[name] = value
+	}
+	if profile != "" {
+		profileOptions, ok := c.profiles[profile]
+		if !ok {
+			return fmt.Errorf("config '%s' has no profile '%s', expected one of: %s", c.path, profile, strings.Join(c.profileNames(), ", "))
+		}
+		for name, value := range profileOptions {
+			options[name] = value
+		}
+	}
+
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "config" || name == "profile" || flag.Lookup(name) == nil {
+			return fmt.Errorf("config '%s': unknown option '%s'", c.path, name)
+		}
+		if given[name] {
+			continue
+		}
+		if err := setFlag(name, options[name]); err != nil {
+			return fmt.Errorf("config '%s': %w", c.path, err)
+		}
+	}
+	return nil
+}
+
+// profileNames returns the sorted names of the config's profiles
+func (c *cliConfig) profileNames() []string {
+	names := make([]string, 0, len(c.profiles))
+	for name := range c.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setFlag sets a flag from a config value; lists set repeatable flags such as -include once per element
+func setFlag(name string, value any) error {
+	values := []any{value}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		values = v
+	case map[string]any:
+		return fmt.Errorf("option '%s' must be a value or a list of values", name)
+	}
+	for _, v := range values {
+		if err := flag.Set(name, fmt.Sprint(v)); err != nil {
+			return fmt.Errorf("invalid value '%v' for option '%s': %w", v, name, err)
+		}
+	}
+	return nil
+}
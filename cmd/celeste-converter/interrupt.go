@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+	"github.com/sirupsen/logrus"
+)
+
+// handleInterrupts stops the converter on the first SIGINT or SIGTERM, letting the files being converted
+// finish, and cancels the returned context on the second, which also drops their partial outputs.
+// The returned function stops listening for the signals.
+func handleInterrupts(filesConverter *converter.FilesConverter) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+		case <-ctx.Done():
+			return
+		}
+		logrus.Warnf("Interrupted, interrupt again to cancel the files being converted")
+		filesConverter.Stop()
+
+		select {
+		case <-signals:
+			logrus.Warnf("Interrupted again, cancelling")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
@@ -1,86 +1,1566 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/bot"
 	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/logrusadapter"
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/mapformat"
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/server"
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/xnb"
+	"io"
+	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+const usage = `Usage: celeste-converter [options] <command> [options] <from_dir> [to_dir]
+       celeste-converter [options] watch <command> <from_dir> <to_dir>
+       celeste-converter [options] <command> <from_dir> <from_dir>... <to_dir>
+       celeste-converter [options] <command> <from_file> <to_file>
+       celeste-converter [options] <command> - -   (stdin to stdout)
+       celeste-converter png2data -from-clipboard <to_file>
+       celeste-converter [options] bot             (Discord bot, token in DISCORD_BOT_TOKEN)
+
+Commands:
+  data2png        <from_dir> <to_dir>        Convert DATA files to PNG images
+  png2data        <from_dir> <to_dir>        Convert PNG images to DATA files
+  data2cdat       <from_dir> <to_dir>        Convert DATA files to .cdat.zst
+  cdat2data       <from_dir> <to_dir>        Convert .cdat.zst files to DATA files
+  png2cdat        <from_dir> <to_dir>        Convert PNG images to .cdat.zst
+  cdat2png        <from_dir> <to_dir>        Convert .cdat.zst files to PNG images
+  data2xdat       <from_dir> <to_dir>        Convert DATA files to extended .xdat.zst (requires -extended)
+  xdat2data       <from_dir> <to_dir>        Convert extended .xdat.zst files to DATA files (requires -extended)
+  png2xdat        <from_dir> <to_dir>        Convert PNG images to extended .xdat.zst, keeping 16-bit channels and palettes (requires -extended)
+  xdat2png        <from_dir> <to_dir>        Convert extended .xdat.zst files to PNG images (requires -extended)
+  data2webp       <from_dir> <to_dir>        Convert DATA files to lossless WebP images
+  webp2data       <from_dir> <to_dir>        Convert WebP images to DATA files
+  remap           <from_dir> <to_dir>        Convert and move the textures listed in a mapping file (requires -map)
+  png2atlas       <from_dir> <to_dir>        Pack sprite PNGs into a Celeste atlas
+  bin2json        <from_dir> <to_dir>        Decode Celeste map .bin files to JSON
+  json2bin        <from_dir> <to_dir>        Encode JSON back into Celeste map .bin files
+  xnb2png         <from_dir> <to_dir>        Convert XNB Texture2D assets of XNA/FNA games, compressed or not, to PNG images
+  png2xnb         <from_dir> <to_dir>        Convert PNG images to uncompressed XNB Texture2D assets
+  hash-tree       <dir>                      Print a hash over the decoded pixel content of a texture tree
+  verify          <dir>                      Report textures that change when round-tripped through the other format
+  validate        <dir>                      Report corrupt .data files from their headers and RLE streams, without writing anything
+  repair          <from_dir> <to_dir>        Rewrite truncated or overlong .data files as well-formed ones, logging every fix
+  verify-manifest <manifest> <dir>           Report outputs that are missing or differ from a -manifest file
+  diff            <a> <b>                    Compare the decoded pixels of two textures or trees, in any mix of formats
+  diff-vanilla    -celeste DIR <mod_dir>     Compare mod textures with the vanilla assets they override
+  make-patch      <base> <modified> <patch>  Store the pixels that differ between two textures as a .cpatch file
+  apply-patch     <patch> <base> <output>    Apply a .cpatch file to a texture, reporting conflicting pixels
+  bot                                        Run a Discord bot replying to .data and .png attachments with their conversion
+  serve                                      Run an HTTP server converting uploaded files, see -listen
+  fetch-convert   <url> <out>                Download a texture or mod archive over HTTPS and convert it to the other format
+  gallery         <dir> <site_dir>           Export textures with thumbnails as a static HTML gallery with a search box
+  sheet           <dir> <sheet.png>          Compose every texture into one labeled contact sheet image
+  animate         <dir> <out_dir>            Write an animated preview of every numbered frame sequence, e.g. idle00.data, idle01.data, ...
+  search          <index> <pattern>          List indexed assets whose output or source path matches a glob pattern
+  stats           <index>                    Summarize the assets recorded in an index
+  backup          <dir> <backup>             Snapshot a directory such as Content/Graphics into a .tar.zst backup
+  restore         <backup> <dir>             Restore a directory exactly as it was backed up
+  restore-backups <dir>                      Put back the outputs below dir that a conversion with -backup replaced (requires -backup)
+  conversions                                List the available conversion commands with their extensions
+  bench                                      Measure conversion throughput of synthesized images at worker counts up to -workers
+  info            <file_or_dir>              Print texture sizes, alpha and compression from their headers without converting
+  tui             <from_dir> <to_dir>        Browse textures of a directory or mod zip and convert the selected ones interactively
+
+Options:
+  -config FILE            Read option defaults from FILE (default: celeste-converter.yaml if present)
+  -profile NAME           Apply the options of profile NAME from the config file
+  -workers N              Number of parallel workers (default: number of CPUs)
+  -image-workers N        Goroutines per DATA image of at least 512x512 pixels (default: 1)
+  -schedule ORDER         Order files are converted in: input (default) or largest-first
+  -large-file-mb N        Dedicate -large-file-workers workers to inputs of N megabytes and more
+  -large-file-workers N   Workers dedicated to -large-file-mb inputs (default: 1)
+  -mmap                   Memory-map inputs of 1 MiB and more instead of reading them
+  -verbose                Enable verbose logging, same as -log-level=debug
+  -quiet                  Only log warnings and errors, same as -log-level=warn
+  -log-level LEVEL        Log level: trace, debug, info (default), warn or error
+  -quarantine DIR         Copy inputs that fail conversion, with an error report, into DIR
+  -index FILE             Record converted assets in a SQLite database, for search and stats
+  -on-conflict POLICY     Existing outputs: overwrite (default), skip, fail or rename
+  -backup DIR|SUFFIX      Copy outputs about to be replaced into DIR, or next to them with SUFFIX such as .bak
+  -dedupe MODE            Identical outputs: off (default), hardlink or manifest
+  -incremental            Skip inputs whose output is at least as new as the input (png2atlas: update the atlas in place)
+  -resume                 Journal converted files next to the output and skip those an interrupted run finished
+  -precedence ORDER       Which of several source directories wins a shared path: last (default), first or fail
+  -preserve-attributes    Give outputs their input's modification time and permissions
+  -output-template T      Name outputs from a template, e.g. {dir}/{name}_{width}x{height}{ext}
+  -flatten                Write all outputs into the output directory, renaming clashing names
+  -strip-prefix DIR       Remove DIR from the start of output paths, e.g. Gameplay/characters
+  -add-prefix DIR         Write outputs below DIR of the output directory
+  -error-report FILE      Write converted and failed files, with reasons, as JSON to FILE
+  -report FILE            Write a JSON record of the run to FILE: options, every file, warnings and totals
+  -manifest FILE          Write the SHA-256 of every output to FILE, in the format of sha256sum
+  -cache DIR              Copy outputs of inputs converted before with the same options from DIR
+  -continue-on-error      Report every failed file at the end instead of only the first
+  -stall-timeout TIME     Log a goroutine dump when a file makes no progress for TIME, e.g. 10m
+  -skip-stalled           Fail files stalled for -stall-timeout and carry on with the batch
+  -file-timeout TIME      Fail files taking longer than TIME to convert and carry on, e.g. 2m
+  -ordered-output         Log files in input order instead of the order workers finish them
+  -progress               Show a progress bar with throughput and ETA instead of a line per file
+  -log-format FORMAT      Log as text (default) or json lines, with a JSON summary
+  -utc-timestamps         Log UTC RFC3339 timestamps with an elapsed-seconds field
+  -max-files-per-sec N    Limit conversions to N files per second (default: unlimited)
+  -max-mb-per-sec N       Limit input reads to N megabytes per second (default: unlimited)
+  -io-concurrency N       Read or write at most N files at once, apart from -workers (default: unlimited)
+  -nice N                 Lower the process priority to nice value N (1-19)
+  -low-priority           Run at low priority, equivalent to -nice 10
+  -dry-run                List what would be converted, including collisions and overwrites, without writing anything
+  -plan                   Like -dry-run, also reporting which existing outputs would change content-wise
+  -include GLOB           Only convert inputs matching GLOB, e.g. 'Gameplay/characters/**' (repeatable)
+  -exclude GLOB           Skip inputs matching GLOB, e.g. 'Gui/**' (repeatable)
+  -files-from FILE        Only convert the files listed in FILE, one per line, or read the list from stdin with -
+  -from0                  Entries of -files-from are separated by NUL characters, as find -print0 writes them
+  -ext-map FROM=TO,...    Use custom input/output extensions instead of the command's defaults
+  -sniff                  Select inputs by content instead of extension, skipping everything else
+  -follow-symlinks        Follow symlinked files and directories instead of skipping them
+  -max-dimension N        Largest image width and height accepted when decoding (default: 16384)
+  -max-memory-mb N        Largest decoded pixel buffer per image, in megabytes (default: 256)
+  -png-compression LEVEL  PNG deflate level: none, speed, default or best (default: default)
+  -png-palette MODE       Write paletted PNGs: off (default), exact (up to 256 colors) or quantize
+  -color-management       Convert PNGs with gamma or ICC profiles to sRGB and tag written PNGs sRGB
+  -max-run N              Longest RLE run written to DATA files, 1-256 (default: 256)
+  -alpha-mode MODE        DATA colors: premultiplied (default), straight or auto
+  -alpha MODE             Store alpha in written DATA files: auto (default), force or never
+  -endianness ORDER       Byte order of DATA headers: auto (default, detects big-endian), little or big
+  -channel-order ORDER    Color channel order of DATA runs: bgr (default, PC) or rgb
+  -extended               Enable the experimental extended DATA commands, which Celeste can't load
+  -strict                 Fail on truncated, over-long or otherwise malformed DATA instead of warning
+  -trim                   Crop transparent margins, with offsets in a .trim.json sidecar; to DATA, pad them back
+  -flip AXIS              Mirror converted images: horizontal or vertical
+  -rotate DEGREES         Rotate converted images clockwise: 90, 180 or 270
+  -scale FACTOR           Resize converted images, e.g. 4x or 0.5x (after -flip and -rotate)
+  -filter NAME            Sampling used by -scale: nearest (default) or box
+  -dither                 Dither instead of round when reducing 16-bit PNGs to 8 bits
+  -dither-method METHOD   Dithering used by -dither: floyd-steinberg (default) or ordered
+  -provenance             Record converter version, options and source hashes in outputs
+  -deterministic          Write byte-identical outputs across runs, workers and platforms
+  -from-clipboard         Read the input image from the clipboard: png2data -from-clipboard <to_file>
+  -json                   Print info, validate and bench results as JSON
+  -tolerance N|R,G,B,A    Largest channel difference accepted by verify and diff, for all or each channel (default: 0)
+  -alpha-weighted         Scale color differences by alpha in verify and diff, so faint pixels count less
+  -heatmap DIR            Write a heatmap PNG of every texture diff finds changed into DIR
+  -sha256 HASH            Expected SHA-256 of the file downloaded by fetch-convert
+  -map FILE               CSV of old,new texture paths used by remap
+  -celeste DIR            Celeste installation used by diff-vanilla
+  -format FORMAT          Image format written by data2png: png (default), bmp, tga, qoi, webp, dds or dds-bc7
+  -atlas NAME             Atlas name used by png2atlas (default: Gameplay)
+  -thumbnail-size N       Largest thumbnail size used by gallery (default: 128)
+  -columns N              Cells per row of the contact sheet written by sheet (default: 8)
+  -cell-size N            Largest texture size in a contact sheet cell (default: 128)
+  -background COLOR       Contact sheet background as #rrggbb or #rrggbbaa (default: #202020)
+  -animation-format NAME  Files written by animate: gif (default), apng or aseprite
+  -frame-delay DURATION   Time each frame is shown by animate previews (default: 100ms)
+  -bench-time DURATION    Time bench measures each image, direction and worker count (default: 1s)
+  -bot-max-mb N           Largest attachment the bot converts, in megabytes (default: 8)
+  -bot-max-size N         Largest texture width and height the bot converts (default: 2048)
+  -listen ADDR            Address serve listens on (default: localhost:8080)
+  -serve-max-mb N         Largest request serve accepts, in megabytes (default: 256)
+  -page-size N            Maximum atlas page size used by png2atlas (default: 4096)`
+
+// botTokenEnv names the environment variable holding the Discord bot token, kept off the command line
+const botTokenEnv = "DISCORD_BOT_TOKEN"
+
+// Process exit codes
+const (
+	exitPartialFailure = 1   // Some inputs failed to convert
+	exitFatal          = 2   // Usage error or a failure that stopped the run
+	exitInterrupted    = 130 // Stopped by SIGINT or SIGTERM, as shells report for SIGINT
+)
+
+// noPathCommands take no path arguments
+var noPathCommands = map[string]bool{
+	"bot":         true,
+	"serve":       true,
+	"conversions": true,
+	"bench":       true,
+}
+
+// singleDirCommands take only a single path argument
+var singleDirCommands = map[string]bool{
+	"stats":           true,
+	"hash-tree":       true,
+	"verify":          true,
+	"validate":        true,
+	"restore-backups": true,
+	"diff-vanilla":    true,
+	"info":            true,
+}
+
+// threePathCommands take three path arguments
+var threePathCommands = map[string]bool{
+	"make-patch":  true,
+	"apply-patch": true,
+}
+
+// runFlags change how a run goes but not what it writes, so -deterministic leaves them out of provenance
+var runFlags = map[string]bool{
+	"workers": true, "image-workers": true, "schedule": true, "large-file-mb": true, "large-file-workers": true,
+	"mmap": true, "verbose": true, "quiet": true, "log-level": true, "log-format": true, "utc-timestamps": true,
+	"progress": true, "ordered-output": true, "stall-timeout": true, "max-files-per-sec": true,
+	"max-mb-per-sec": true, "nice": true, "low-priority": true, "config": true, "profile": true,
+	"quarantine": true, "index": true, "error-report": true, "manifest": true, "resume": true, "backup": true,
+	"io-concurrency": true, "cache": true,
+}
+
+// selectionFlags pick which files are converted and what happens around them, not their converted
+// content, so the conversion cache ignores them along with runFlags
+var selectionFlags = map[string]bool{
+	"include": true, "exclude": true, "files-from": true, "from0": true, "sniff": true, "follow-symlinks": true,
+	"incremental": true, "on-conflict": true, "continue-on-error": true, "skip-stalled": true, "file-timeout": true,
+	"dry-run": true, "plan": true, "report": true, "json": true, "dedupe": true, "output-template": true, "flatten": true,
+	"strip-prefix": true, "add-prefix": true,
+	"preserve-attributes": true, "provenance": true, "deterministic": true,
+}
+
 func main() {
-	// Set up logging
+	// Set up logging, with fatal errors exiting with exitFatal rather than logrus' default of 1
+	logrus.StandardLogger().ExitFunc = func(int) { os.Exit(exitFatal) }
 	logrus.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
 	})
 
 	// Define command line flags
 	workers := flag.Int("workers", runtime.NumCPU(), "Number of parallel workers (default: number of CPUs)")
-	verbose := flag.Bool("verbose", false, "Enable verbose logging")
+	imageWorkers := flag.Int("image-workers", 1, "Goroutines decoding and encoding a single large DATA image, for conversions of a few huge atlas pages")
+	schedule := flag.String("schedule", "input", "Order files are converted in: input (directory order) or largest-first, so huge files don't lag at the end")
+	largeFileMB := flag.Float64("large-file-mb", 0, "Inputs of at least this many megabytes go to -large-file-workers dedicated workers")
+	largeFileWorkers := flag.Int("large-file-workers", 1, "Workers dedicated to the inputs of -large-file-mb and more")
+	memoryMap := flag.Bool("mmap", false, "Memory-map inputs of 1 MiB and more, so huge DATA pages decoded with -image-workers aren't held in memory twice")
+	verbose := flag.Bool("verbose", false, "Enable verbose logging, same as -log-level=debug")
+	quiet := flag.Bool("quiet", false, "Only log warnings and errors, same as -log-level=warn")
+	logLevel := flag.String("log-level", "", "Log level: trace, debug, info, warn or error (default info)")
+	exportFormat := flag.String("format", "png", "Image format written by data2png: "+strings.Join(converter.ExportFormats(), ", "))
+	atlasName := flag.String("atlas", "Gameplay", "Atlas name used by png2atlas")
+	thumbnailSize := flag.Int("thumbnail-size", 128, "Largest thumbnail width and height used by gallery")
+	columns := flag.Int("columns", 8, "Cells per row of the contact sheet written by sheet")
+	cellSize := flag.Int("cell-size", 128, "Largest texture width and height in a contact sheet cell, larger textures are scaled down")
+	background := flag.String("background", "#202020", "Contact sheet background color as #rrggbb or #rrggbbaa")
+	animationFormat := flag.String("animation-format", "gif", "Files written by animate: gif, apng (keeps every color and partial transparency) or aseprite (editable sprites)")
+	frameDelay := flag.Duration("frame-delay", 100*time.Millisecond, "Time each frame of animate previews is shown, e.g. 80ms")
+	benchTime := flag.Duration("bench-time", time.Second, "Time bench measures each image, direction and worker count")
+	botMaxMB := flag.Float64("bot-max-mb", 8, "Largest attachment in megabytes the bot converts")
+	botMaxSize := flag.Int("bot-max-size", 2048, "Largest texture width and height the bot converts")
+	listenAddr := flag.String("listen", "localhost:8080", "Address the serve command listens on")
+	serveMaxMB := flag.Float64("serve-max-mb", 256, "Largest request body in megabytes the serve command accepts")
+	pageSize := flag.Int("page-size", 4096, "Maximum atlas page width and height used by png2atlas")
+	quarantineDir := flag.String("quarantine", "", "Copy inputs that fail conversion, with an error report, into this directory")
+	indexPath := flag.String("index", "", "Record converted assets in this SQLite database, for the search and stats commands")
+	onConflict := flag.String("on-conflict", "overwrite", "What to do with existing outputs: overwrite, skip, fail or rename")
+	dedupe := flag.String("dedupe", "off", "What to do with byte-identical outputs: off, hardlink or manifest")
+	resume := flag.Bool("resume", false, "Record converted files in a journal next to the output directory and skip the files an interrupted run already converted")
+	incremental := flag.Bool("incremental", false, "Skip inputs whose output is at least as new as the input; png2atlas updates the existing atlas in place")
+	errorReport := flag.String("error-report", "", "Write the converted and failed files, with failure reasons, as JSON to this file")
+	runReportPath := flag.String("report", "", "Write a JSON record of the run to this file: options, status, duration and sizes of every file, warnings and totals")
+	manifestFile := flag.String("manifest", "", "Write the SHA-256 of every output to this file, in the format of sha256sum")
+	cacheDir := flag.String("cache", "", "Cache converted files in this directory by input content and options, copying them instead of converting unchanged inputs again")
+	precedence := flag.String("precedence", "last", "Which of several source directories wins a shared relative path: last, first or fail")
+	outputTemplate := flag.String("output-template", "", "Name outputs from a template such as {dir}/{name}_{width}x{height}{ext} instead of the input's relative path")
+	flatten := flag.Bool("flatten", false, "Write all outputs straight into the output directory, naming inputs with a shared file name after their whole path")
+	stripPrefix := flag.String("strip-prefix", "", "Remove these directories from the start of output paths, e.g. Gameplay/characters")
+	addPrefix := flag.String("add-prefix", "", "Write outputs below these directories of the output directory, e.g. Graphics/Atlases/Gameplay")
+	preserveAttributes := flag.Bool("preserve-attributes", false, "Copy each input's modification time and permission bits onto its output")
+	continueOnError := flag.Bool("continue-on-error", false, "Report every failed file instead of only the first")
+	stallTimeout := flag.Duration("stall-timeout", 0, "Log the file and a goroutine dump when a conversion makes no progress for this long, e.g. 10m")
+	skipStalled := flag.Bool("skip-stalled", false, "Fail files that stall for -stall-timeout and carry on with the batch")
+	fileTimeout := flag.Duration("file-timeout", 0, "Fail files whose conversion takes longer than this, e.g. 2m, and carry on with the batch")
+	showProgress := flag.Bool("progress", false, "Show a live progress bar with throughput and ETA instead of logging every file; periodic lines when stdout isn't a terminal")
+	orderedOutput := flag.Bool("ordered-output", false, "Log files in input order instead of the order parallel workers finish them")
+	logFormat := flag.String("log-format", "text", "Log format: text or json (JSON lines, plus a JSON summary)")
+	utcTimestamps := flag.Bool("utc-timestamps", false, "Log UTC RFC3339 timestamps with an elapsed-seconds field")
+	maxFilesPerSec := flag.Float64("max-files-per-sec", 0, "Limit conversions to this many files per second (0 = unlimited)")
+	maxMBPerSec := flag.Float64("max-mb-per-sec", 0, "Limit input reads to this many megabytes per second (0 = unlimited)")
+	ioConcurrency := flag.Int("io-concurrency", 0, "Read or write at most this many files at once, independently of -workers converting them in memory (0 = unlimited)")
+	nice := flag.Int("nice", 0, "Lower the process priority to this nice value (1-19; Windows maps it to a priority class)")
+	lowPriority := flag.Bool("low-priority", false, "Run at low priority, equivalent to -nice 10")
+	dryRun := flag.Bool("dry-run", false, "List what would be converted, including collisions and overwrites, without writing anything")
+	plan := flag.Bool("plan", false, "Like -dry-run, also reporting which existing outputs would change content-wise")
+	expectedSHA256 := flag.String("sha256", "", "Expected SHA-256 of the file downloaded by fetch-convert")
+	overwriteBackup := flag.String("backup", "", "Copy outputs about to be replaced into this directory, or next to them with a suffix such as .bak")
+	remapFile := flag.String("map", "", "CSV mapping file of old,new texture paths used by remap")
+	celesteDir := flag.String("celeste", "", "Celeste installation used by diff-vanilla")
+	var include, exclude patternList
+	flag.Var(&include, "include", "Only convert inputs whose relative path matches this glob (repeatable, ** matches directories)")
+	flag.Var(&exclude, "exclude", "Skip inputs whose relative path matches this glob (repeatable, ** matches directories)")
+	filesFrom := flag.String("files-from", "", "Convert the files listed in this file, one per line, instead of walking the source (- reads stdin)")
+	from0 := flag.Bool("from0", false, "Entries of -files-from are separated by NUL characters, as written by find -print0 and git diff -z")
+	extMap := flag.String("ext-map", "", "Comma-separated from=to extension pairs replacing a conversion's default extensions, e.g. .bin=.png.bak")
+	followSymlinks := flag.Bool("follow-symlinks", false, "Follow symlinked input files and directories, skipping symlinks that loop back, instead of skipping every symlink")
+	sniff := flag.Bool("sniff", false, "Select inputs by content (PNG signature, DATA header) instead of extension, skipping everything else")
+	jsonOutput := flag.Bool("json", false, "Print info, validate and bench results as JSON")
+	tolerance := flag.String("tolerance", "0", "Largest channel difference accepted by verify and diff, as N for every channel or R,G,B,A")
+	alphaWeighted := flag.Bool("alpha-weighted", false, "Compare colors premultiplied by alpha in verify and diff, so differences in barely visible pixels count less")
+	heatmapDir := flag.String("heatmap", "", "Directory diff writes heatmap PNGs of changed textures into")
+	maxDimension := flag.Int("max-dimension", converter.DefaultMaxDimension, "Largest image width and height accepted when decoding")
+	maxMemoryMB := flag.Int("max-memory-mb", converter.DefaultMaxImageMemory>>20, "Largest decoded pixel buffer in megabytes allowed per image")
+	pngCompression := flag.String("png-compression", "default", "Deflate level of written PNGs: none, speed, default or best")
+	colorManagement := flag.Bool("color-management", false, "Convert PNG inputs with a gAMA or iCCP chunk to sRGB, and tag written PNGs as sRGB")
+	pngPalette := flag.String("png-palette", "off", "Write 8-bit paletted PNGs: off, exact (images of up to 256 colors) or quantize (every image, reduced by median cut)")
+	maxRun := flag.Int("max-run", converter.MaxRunLength, "Longest RLE run written to DATA files, below 256 for decoders that mishandle a count of 0")
+	alphaChannel := flag.String("alpha", "auto", "Whether written DATA files store alpha: auto (if any pixel isn't opaque), force or never")
+	alphaMode := flag.String("alpha-mode", "premultiplied", "How DATA colors relate to alpha: premultiplied, straight or auto")
+	endianness := flag.String("endianness", "auto", "Byte order of DATA headers: auto (little-endian, detecting big-endian console headers), little or big")
+	channelOrder := flag.String("channel-order", "bgr", "Order of the color channels of DATA runs: bgr, as on PC, or rgb, as in some console dumps")
+	extended := flag.Bool("extended", false, "Enable the experimental, non-vanilla extended DATA commands (16-bit channels and palettes)")
+	strict := flag.Bool("strict", false, "Fail on truncated, over-long or otherwise malformed DATA streams instead of warning and decoding what is there")
+	trim := flag.Bool("trim", false, "Crop fully transparent margins, recording the offsets in a .trim.json sidecar; conversions to DATA pad inputs with a sidecar back")
+	flip := flag.String("flip", "", "Mirror converted images: horizontal or vertical")
+	rotate := flag.String("rotate", "", "Rotate converted images clockwise by 90, 180 or 270 degrees")
+	scale := flag.String("scale", "", "Resize converted images by a factor such as 4x or 0.5x, applied after -flip and -rotate")
+	filter := flag.String("filter", "nearest", "Sampling used by -scale: nearest (sharp pixel art) or box (smooth downscales)")
+	dither := flag.Bool("dither", false, "Dither color channels when reducing 16-bit PNGs to 8 bits instead of rounding")
+	ditherMethod := flag.String("dither-method", "floyd-steinberg", "Dithering used by -dither: floyd-steinberg or ordered")
+	provenance := flag.Bool("provenance", false, "Record converter version, options and source hashes in outputs")
+	deterministic := flag.Bool("deterministic", false, "Write byte-identical outputs across runs, worker counts and platforms: zip entries in name order with a fixed time, and provenance without options that don't affect outputs")
+	fromClipboard := flag.Bool("from-clipboard", false, "Read the input image from the clipboard, for png2data <to_file>")
+	configPath := flag.String("config", "", "Read option defaults from this YAML file instead of "+configFileName+" in the working directory")
+	profile := flag.String("profile", "", "Apply the options of this named profile from the config file")
 	flag.Parse()
+	args := parseCommandArgs()
+
+	// Options given on the command line win over the config file
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		logrus.Fatalf("%v", err)
+	}
+	if config != nil {
+		if err := config.apply(*profile); err != nil {
+			logrus.Fatalf("%v", err)
+		}
+	} else if *profile != "" {
+		logrus.Fatalf("-profile needs a config file, such as %s in the working directory", configFileName)
+	}
+
+	var formatter logrus.Formatter
+	switch *logFormat {
+	case "text":
+		formatter = &logrus.TextFormatter{FullTimestamp: true}
+		if *utcTimestamps {
+			formatter = &logrus.TextFormatter{FullTimestamp: true, TimestampFormat: time.RFC3339Nano}
+		}
+	case "json":
+		formatter = &logrus.JSONFormatter{}
+		if *utcTimestamps {
+			formatter = &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+		}
+	default:
+		logrus.Fatalf("Invalid -log-format %q, expected text or json", *logFormat)
+	}
+	if *utcTimestamps {
+		formatter = logrusadapter.NewUTCFormatter(formatter)
+	}
+	logrus.SetFormatter(formatter)
+	converter.SetDefaultLogger(logrusadapter.New(logrus.StandardLogger()))
+
+	level, err := resolveLogLevel(*logLevel, *verbose, *quiet)
+	if err != nil {
+		logrus.Fatalf("%v", err)
+	}
+	logrus.SetLevel(level)
 
-	// Set log level based on verbose flag
-	if *verbose {
-		logrus.SetLevel(logrus.DebugLevel)
-	} else {
-		logrus.SetLevel(logrus.InfoLevel)
+	// Lower priority before any work starts so background runs don't compete with games or editors
+	if *lowPriority && *nice == 0 {
+		*nice = 10
+	}
+	if *nice != 0 {
+		if err := setNice(*nice); err != nil {
+			logrus.Warnf("Failed to lower process priority: %v", err)
+		}
 	}
 
-	// Process remaining arguments
-	args := flag.Args()
-	if len(args) < 3 {
-		logrus.Fatal("Usage: celeste-converter [options] [data2png|png2data] <from_dir> <to_dir>\n\nOptions:\n  -workers N  Number of parallel workers (default: number of CPUs)\n  -verbose    Enable verbose logging")
+	// "watch <command>" runs a conversion command continuously
+	watch := len(args) > 0 && args[0] == "watch"
+	if watch {
+		args = args[1:]
+	}
+	if len(args) == 0 || (!noPathCommands[args[0]] && (len(args) < 2 ||
+		(!singleDirCommands[args[0]] && !*fromClipboard && len(args) < 3) || (threePathCommands[args[0]] && len(args) < 4))) {
+		logrus.Fatal(usage)
 	}
 
 	command := args[0]
-	from := args[1]
-	to := args[2]
 
-	// Create absolute paths
-	fromPath, err := filepath.Abs(from)
-	if err != nil {
-		logrus.Fatalf("Invalid 'from' path: %v", err)
+	// Create absolute paths, leaving "-" for stdin/stdout as is
+	var from, fromPath string
+	if len(args) >= 2 {
+		from = args[1]
+		fromPath, err = absPath(from)
+		if err != nil {
+			logrus.Fatalf("Invalid 'from' path: %v", err)
+		}
 	}
 
-	toPath, err := filepath.Abs(to)
-	if err != nil {
-		logrus.Fatalf("Invalid 'to' path: %v", err)
+	var toPath string
+	if len(args) >= 3 {
+		toPath, err = absPath(args[2])
+		if err != nil {
+			logrus.Fatalf("Invalid 'to' path: %v", err)
+		}
+	}
+
+	// With -from-clipboard the only path argument is the output file
+	if *fromClipboard {
+		if command != "png2data" || len(args) != 2 {
+			logrus.Fatal("-from-clipboard takes a single output file: png2data -from-clipboard <to_file>")
+		}
+		fromPath, toPath = "", fromPath
 	}
 
 	// Log configuration
 	logrus.Infof("Workers: %d", *workers)
 	logrus.Debugf("Verbose: %v", *verbose)
 
+	// The run report records the warnings of every converter created from here on
+	var runReport *converter.RunReport
+	if *runReportPath != "" {
+		options := make(map[string]string)
+		flag.Visit(func(f *flag.Flag) {
+			options[f.Name] = f.Value.String()
+		})
+		runReport = converter.NewRunReport(command, options, time.Now())
+		converter.SetDefaultLogger(runReport.Logger(converter.DefaultLogger()))
+	}
+
 	// Initialize converters
 	graphicsConverter := converter.NewGraphicsConverter()
-	filesConverter := converter.NewFilesConverter(graphicsConverter)
+	if *dither {
+		method, err := converter.ParseDitherMethod(*ditherMethod)
+		if err != nil {
+			logrus.Fatalf("Invalid -dither-method: %v", err)
+		}
+		graphicsConverter.SetDitherMethod(method)
+	}
+	graphicsConverter.SetMaxDimension(*maxDimension)
+	graphicsConverter.SetMaxImageMemory(int64(*maxMemoryMB) << 20)
+	pngLevel, err := converter.ParsePngCompression(*pngCompression)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	graphicsConverter.SetPngCompression(pngLevel)
+	palette, err := converter.ParsePngPalette(*pngPalette)
+	if err != nil {
+		logrus.Fatalf("Invalid -png-palette: %v", err)
+	}
+	graphicsConverter.SetPngPalette(palette)
+	graphicsConverter.SetColorManagement(*colorManagement)
+	mode, err := converter.ParseAlphaMode(*alphaMode)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	graphicsConverter.SetAlphaMode(mode)
+	channel, err := converter.ParseAlphaChannel(*alphaChannel)
+	if err != nil {
+		logrus.Fatalf("Invalid -alpha: %v", err)
+	}
+	graphicsConverter.SetAlphaChannel(channel)
+	byteOrder, err := converter.ParseEndianness(*endianness)
+	if err != nil {
+		logrus.Fatalf("Invalid -endianness: %v", err)
+	}
+	graphicsConverter.SetEndianness(byteOrder)
+	channels, err := converter.ParseChannelOrder(*channelOrder)
+	if err != nil {
+		logrus.Fatalf("Invalid -channel-order: %v", err)
+	}
+	graphicsConverter.SetChannelOrder(channels)
+	var transforms []converter.Transform
+	if *flip != "" {
+		axis, err := converter.ParseFlipAxis(*flip)
+		if err != nil {
+			logrus.Fatalf("Invalid -flip: %v", err)
+		}
+		transforms = append(transforms, converter.Flip(axis))
+	}
+	if *rotate != "" {
+		rotation, err := converter.ParseRotation(*rotate)
+		if err != nil {
+			logrus.Fatalf("Invalid -rotate: %v", err)
+		}
+		transforms = append(transforms, converter.Rotate(rotation))
+	}
+	if *scale != "" {
+		factor, err := converter.ParseScale(*scale)
+		if err != nil {
+			logrus.Fatalf("Invalid -scale: %v", err)
+		}
+		scaleFilter, err := converter.ParseScaleFilter(*filter)
+		if err != nil {
+			logrus.Fatalf("Invalid -filter: %v", err)
+		}
+		transforms = append(transforms, converter.Scale(factor, scaleFilter))
+	}
+	graphicsConverter.SetTransforms(transforms...)
+	if *maxRun < 1 || *maxRun > converter.MaxRunLength {
+		logrus.Fatalf("-max-run must be between 1 and %d", converter.MaxRunLength)
+	}
+	graphicsConverter.SetMaxRunLength(*maxRun)
+	graphicsConverter.SetImageWorkers(*imageWorkers)
+	graphicsConverter.SetStrict(*strict)
+	graphicsConverter.SetExtended(*extended)
+	filesConverter := converter.NewFilesConverter(graphicsConverter, converter.WithMemoryMap(*memoryMap))
 
 	// Set number of workers
 	if *workers > 0 {
 		filesConverter.SetMaxWorkers(*workers)
 	}
 
-	// Execute command
+	scheduleOrder, err := converter.ParseSchedule(*schedule)
+	if err != nil {
+		logrus.Fatalf("Invalid -schedule: %v", err)
+	}
+	filesConverter.SetSchedule(scheduleOrder)
+	filesConverter.SetLargeFilePool(int64(*largeFileMB*1024*1024), *largeFileWorkers)
+
+	filesConverter.SetRateLimit(*maxFilesPerSec, int64(*maxMBPerSec*1024*1024))
+	filesConverter.SetIOConcurrency(*ioConcurrency)
+
+	if *quarantineDir != "" {
+		quarantinePath, err := filepath.Abs(*quarantineDir)
+		if err != nil {
+			logrus.Fatalf("Invalid quarantine path: %v", err)
+		}
+		filesConverter.SetQuarantineDir(quarantinePath)
+	}
+
+	var backup converter.OverwriteBackup
+	if *overwriteBackup != "" {
+		if backup, err = converter.ParseOverwriteBackup(*overwriteBackup); err != nil {
+			logrus.Fatalf("Invalid -backup: %v", err)
+		}
+		if backup.Dir != "" {
+			if backup.Dir, err = filepath.Abs(backup.Dir); err != nil {
+				logrus.Fatalf("Invalid -backup: %v", err)
+			}
+		}
+		filesConverter.SetOverwriteBackup(backup)
+	}
+
+	// Record explicitly set flags as the options used for this run
+	if *provenance {
+		options := make(map[string]string)
+		flag.Visit(func(f *flag.Flag) {
+			if !*deterministic || !runFlags[f.Name] {
+				options[f.Name] = f.Value.String()
+			}
+		})
+		filesConverter.SetProvenance(true, options)
+	}
+
+	var cache *converter.ConversionCache
+	if *cacheDir != "" {
+		options := make(map[string]string)
+		flag.Visit(func(f *flag.Flag) {
+			if !runFlags[f.Name] && !selectionFlags[f.Name] {
+				options[f.Name] = f.Value.String()
+			}
+		})
+		if cache, err = converter.NewConversionCache(*cacheDir, options); err != nil {
+			logrus.Fatal(err)
+		}
+		filesConverter.SetCache(cache)
+	}
+
+	// -plan is a dry run that also compares existing outputs
+	if *plan {
+		*dryRun = true
+	}
+	filesConverter.SetDryRun(*dryRun)
+	filesConverter.SetPlan(*plan)
+	filesConverter.SetSniff(*sniff)
+	filesConverter.SetFollowSymlinks(*followSymlinks)
+	filesConverter.SetContinueOnError(*continueOnError)
+	if *skipStalled && *stallTimeout == 0 {
+		logrus.Fatal("-skip-stalled requires -stall-timeout")
+	}
+	filesConverter.SetStallTimeout(*stallTimeout)
+	filesConverter.SetSkipStalled(*skipStalled)
+	filesConverter.SetFileTimeout(*fileTimeout)
+	filesConverter.SetIncremental(*incremental)
+	filesConverter.SetResume(*resume)
+	filesConverter.SetTrim(*trim)
+	var manifest *converter.Manifest
+	if *manifestFile != "" {
+		manifest = converter.NewManifest()
+		filesConverter.SetManifest(manifest)
+	}
+	filesConverter.SetPreserveAttributes(*preserveAttributes)
+	filesConverter.SetDeterministic(*deterministic)
+	filesConverter.SetOrderedOutput(*orderedOutput)
+	filesConverter.SetQuietFiles(*showProgress)
+
+	overwritePolicy, err := converter.ParseOverwritePolicy(*onConflict)
+	if err != nil {
+		logrus.Fatalf("Invalid -on-conflict: %v", err)
+	}
+	filesConverter.SetOverwritePolicy(overwritePolicy)
+
+	mergePrecedence, err := converter.ParseMergePrecedence(*precedence)
+	if err != nil {
+		logrus.Fatalf("Invalid -precedence: %v", err)
+	}
+	filesConverter.SetMergePrecedence(mergePrecedence)
+
+	if *outputTemplate != "" {
+		template, err := converter.ParseOutputTemplate(*outputTemplate)
+		if err != nil {
+			logrus.Fatalf("Invalid -output-template: %v", err)
+		}
+		filesConverter.SetOutputTemplate(template)
+	}
+	filesConverter.SetFlatten(*flatten)
+	filesConverter.SetStripPrefix(*stripPrefix)
+	filesConverter.SetAddPrefix(*addPrefix)
+
+	dedupeMode, err := converter.ParseDedupeMode(*dedupe)
+	if err != nil {
+		logrus.Fatalf("Invalid -dedupe: %v", err)
+	}
+	filesConverter.SetDedupe(dedupeMode)
+
+	if *indexPath != "" {
+		index, err := converter.OpenAssetIndex(*indexPath)
+		if err != nil {
+			logrus.Fatalf("%v", err)
+		}
+		defer index.Close()
+		filesConverter.SetIndex(index)
+	}
+
+	if err := filesConverter.SetFilter(include, exclude); err != nil {
+		logrus.Fatalf("Invalid -include or -exclude: %v", err)
+	}
+	compareOptions := imagecompare.Options{AlphaWeighted: *alphaWeighted}
+	if compareOptions.Tolerance, err = imagecompare.ParseTolerance(*tolerance); err != nil {
+		logrus.Fatalf("Invalid -tolerance: %v", err)
+	}
+	if *filesFrom != "" {
+		listFile := os.Stdin
+		if *filesFrom != "-" {
+			if listFile, err = os.Open(*filesFrom); err != nil {
+				logrus.Fatalf("Invalid -files-from: %v", err)
+			}
+		}
+		fileList, err := converter.ReadFileList(listFile, *from0)
+		listFile.Close()
+		if err != nil {
+			logrus.Fatalf("Reading -files-from failed: %v", err)
+		}
+		filesConverter.SetFileList(fileList)
+	}
+
+	var extMappings []converter.ExtensionMapping
+	if *extMap != "" {
+		extMappings, err = converter.ParseExtensionMap(*extMap)
+		if err != nil {
+			logrus.Fatalf("Invalid -ext-map: %v", err)
+		}
+	}
+
 	startTime := time.Now()
 
-	switch command {
-	case "data2png":
-		if err := filesConverter.DataToPng(fromPath, toPath); err != nil {
-			logrus.Fatalf("Conversion failed: %v", err)
+	// Collect statistics for the final summary and failures for the error report
+	runStats := converter.NewRunStats(startTime)
+	var report converter.ErrorReport
+	// Keep stdout clean when it carries the converted data
+	summary := os.Stdout
+	if toPath == stdioPath {
+		summary = os.Stderr
+	}
+
+	var progressBar *converter.ProgressBar
+	if *showProgress {
+		if *logFormat == "json" {
+			logrus.Fatal("-progress can't be combined with -log-format=json")
+		}
+		progressBar = converter.NewProgressBar(summary, isTerminal(summary))
+	}
+	recordProgress := func(event converter.ProgressEvent) {
+		runStats.Record(event)
+		report.Record(event)
+		if runReport != nil {
+			runReport.Record(event)
+		}
+		if progressBar != nil {
+			progressBar.Record(event)
+		}
+	}
+	filesConverter.Progress(recordProgress)
+
+	// finish writes the error report, the run report and the final summary
+	finish := func(status string) {
+		if *errorReport != "" {
+			if err := report.WriteFile(*errorReport); err != nil {
+				logrus.Errorf("%v", err)
+			}
+		}
+		if runReport != nil {
+			if err := runReport.WriteFile(*runReportPath, status); err != nil {
+				logrus.Errorf("%v", err)
+			}
 		}
-	case "png2data":
-		if err := filesConverter.PngToData(fromPath, toPath); err != nil {
+		if manifest != nil && status != "fatal" {
+			if err := manifest.WriteFile(*manifestFile); err != nil {
+				logrus.Errorf("%v", err)
+			}
+		}
+		if cache != nil {
+			hits, misses := cache.Stats()
+			logrus.Infof("Conversion cache: %d files copied, %d converted", hits, misses)
+		}
+		elapsed := time.Since(startTime)
+		totals := runStats.Summary()
+		if *logFormat == "json" {
+			stats := summaryStats{Status: status, Command: command, RunSummary: totals}
+			if err := json.NewEncoder(summary).Encode(stats); err != nil {
+				logrus.Errorf("Failed to write summary: %v", err)
+			}
+			return
+		}
+		if status == "interrupted" {
+			fmt.Fprintf(summary, "Conversion interrupted after %v: %d files converted, %d failed\n",
+				elapsed, totals.Converted, totals.Failed)
+		} else if status == "ok" && logrus.IsLevelEnabled(logrus.InfoLevel) {
+			fmt.Fprintf(summary, "Conversion completed successfully in %v\n", elapsed)
+		}
+		// Batch runs end with their statistics, single files have none
+		if totals.Converted+totals.Failed > 0 && logrus.IsLevelEnabled(logrus.InfoLevel) {
+			fmt.Fprint(summary, totals)
+		}
+	}
+
+	// conversionFailed ends the run with exitPartialFailure if inputs failed to convert,
+	// or as a fatal error if the conversion couldn't run at all
+	conversionFailed := func(err error) {
+		if errors.Is(err, converter.ErrStopped) || errors.Is(err, context.Canceled) {
+			finish("interrupted")
+			os.Exit(exitInterrupted)
+		}
+		if len(report.Failed) == 0 {
+			finish("fatal")
 			logrus.Fatalf("Conversion failed: %v", err)
 		}
+		finish("partial")
+		logrus.Errorf("Conversion failed: %v", err)
+		os.Exit(exitPartialFailure)
+	}
+
+	// Allow pausing and resuming long runs with SIGUSR1/SIGUSR2
+	handlePauseSignals(filesConverter)
+
+	// Execute command
+	mapConverter := mapformat.NewMapConverter()
+	xnbConverter := xnb.NewTextureConverter()
+	xnbConverter.SetMaxDimension(*maxDimension)
+	xnbConverter.SetMaxImageMemory(int64(*maxMemoryMB) << 20)
+
+	// Map and XNB conversions are registered like a third-party format would be
+	registry := filesConverter.Registry()
+	for _, c := range []converter.Conversion{
+		{Name: "bin2json", FromExt: ".bin", ToExt: ".json", Convert: mapConverter.BinToJson},
+		{Name: "json2bin", FromExt: ".json", ToExt: ".bin", Convert: mapConverter.JsonToBin},
+		{Name: "xnb2png", FromExt: ".xnb", ToExt: ".png", Convert: xnbConverter.XnbToPng},
+		{Name: "png2xnb", FromExt: ".png", ToExt: ".xnb", Convert: xnbConverter.PngToXnb},
+	} {
+		if err := registry.Register(c); err != nil {
+			logrus.Fatalf("Failed to register %s: %v", c.Name, err)
+		}
+	}
+
+	// Conversion commands work on whole directories or, when the source is a file, on a single file
+	conversions := make(map[string]conversion)
+	for _, c := range registry.Conversions() {
+		name := c.Name
+		conversions[name] = conversion{c.FromExt, c.ToExt, func(ctx context.Context, fromDir, toDir string) error {
+			return filesConverter.RunConversionContext(ctx, name, fromDir, toDir)
+		}, c.Convert}
+	}
+
+	// The extended DATA container is experimental and can't be loaded by the game
+	if strings.Contains(command, "xdat") && !*extended {
+		logrus.Fatalf("%s uses the non-vanilla extended DATA format and requires -extended", command)
+	}
+
+	// -format exports DATA files to another image format instead of PNG
+	if !strings.EqualFold(*exportFormat, "png") {
+		if command != "data2png" {
+			logrus.Fatal("-format is only supported by data2png")
+		}
+		ext, err := converter.ExportExtension(*exportFormat)
+		if err != nil {
+			logrus.Fatalf("Invalid -format: %v", err)
+		}
+		convertFunc, err := graphicsConverter.DataToImage(*exportFormat)
+		if err != nil {
+			logrus.Fatalf("Invalid -format: %v", err)
+		}
+		conversions[command] = conversion{".data", ext, func(ctx context.Context, fromDir, toDir string) error {
+			return filesConverter.ConvertContext(ctx, fromDir, toDir, ".data", ext, convertFunc)
+		}, convertFunc}
+	}
+
+	if watch {
+		conv, ok := conversions[command]
+		if !ok {
+			logrus.Fatalf("Command cannot be watched: %s", command)
+		}
+		if len(args) > 3 {
+			logrus.Fatal("watch supports a single source directory")
+		}
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by watch")
+		}
+
+		// Stop watching cleanly on Ctrl+C
+		fromExt, toExt := conv.fromExt, conv.toExt
+		if len(extMappings) > 1 {
+			logrus.Fatal("watch supports a single -ext-map pair")
+		} else if len(extMappings) == 1 {
+			fromExt, toExt = extMappings[0].From, extMappings[0].To
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := filesConverter.Watch(ctx, fromPath, toPath, fromExt, toExt, conv.file); err != nil {
+			logrus.Fatalf("Watch failed: %v", err)
+		}
+		return
+	}
+
+	switch command {
+	case "png2atlas":
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by png2atlas")
+		}
+		atlasPacker := converter.NewAtlasPacker(graphicsConverter)
+		if err := atlasPacker.SetMaxPageSize(*pageSize); err != nil {
+			logrus.Fatalf("Invalid -page-size: %v", err)
+		}
+		atlasPacker.SetIncremental(*incremental)
+		if err := atlasPacker.Pack(fromPath, toPath, *atlasName); err != nil {
+			logrus.Fatalf("Packing failed: %v", err)
+		}
+	case "remap":
+		if *remapFile == "" {
+			logrus.Fatal("remap requires -map <file>")
+		}
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by remap")
+		}
+		mapping, err := converter.ReadRemapFile(*remapFile)
+		if err != nil {
+			logrus.Fatalf("%v", err)
+		}
+		if err := filesConverter.Remap(fromPath, toPath, mapping); err != nil {
+			conversionFailed(err)
+		}
+	case "hash-tree":
+		treeHash, err := converter.NewTreeHasher(graphicsConverter).HashTree(fromPath)
+		if err != nil {
+			logrus.Fatalf("Hashing failed: %v", err)
+		}
+		logrus.Infof("%d textures hashed in %v", len(treeHash.Files), time.Since(startTime))
+		fmt.Printf("%s  %s\n", treeHash.Root, from)
+		return
+	case "verify":
+		results, err := filesConverter.VerifyWith(fromPath, compareOptions)
+		if err != nil {
+			logrus.Fatalf("Verification failed: %v", err)
+		}
+		failed := 0
+		for _, r := range results {
+			switch {
+			case r.Err != nil:
+				failed++
+				fmt.Printf("FAIL %s: %v\n", r.RelPath, r.Err)
+			case r.MismatchedPixels > 0:
+				failed++
+				fmt.Printf("FAIL %s: %d pixels differ after round trip in %v (max delta %d, PSNR %.1f dB)\n", r.RelPath, r.MismatchedPixels, r.Bounds, r.MaxDelta, r.PSNR)
+			}
+		}
+		fmt.Printf("%d files verified, %d failed\n", len(results), failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	case "validate":
+		results, err := filesConverter.Validate(fromPath)
+		if err != nil {
+			logrus.Fatalf("Validation failed: %v", err)
+		}
+		invalid := 0
+		for _, r := range results {
+			if !r.Valid() {
+				invalid++
+			}
+		}
+		if *jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(results); err != nil {
+				logrus.Fatalf("Writing results failed: %v", err)
+			}
+		} else {
+			for _, r := range results {
+				if !r.Valid() {
+					fmt.Printf("FAIL %s: %s: %v\n", r.Path, r.Problem, r.Err)
+				}
+			}
+			fmt.Printf("%d files validated, %d invalid\n", len(results), invalid)
+		}
+		if invalid > 0 {
+			os.Exit(1)
+		}
+		return
+	case "repair":
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by repair")
+		}
+		results, err := filesConverter.Repair(fromPath, toPath)
+		if err != nil {
+			logrus.Fatalf("Repair failed: %v", err)
+		}
+		repaired, failed := 0, 0
+		for _, r := range results {
+			switch {
+			case r.Err != nil:
+				failed++
+				fmt.Printf("FAIL %s: %v\n", r.Path, r.Err)
+			case r.Repair.Repaired():
+				repaired++
+				fmt.Printf("FIXED %s: %v\n", r.Path, r.Repair)
+			}
+		}
+		fmt.Printf("%d files written, %d repaired, %d unrepairable\n", len(results)-failed, repaired, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	case "verify-manifest":
+		manifest, err := converter.ReadManifestFile(fromPath)
+		if err != nil {
+			logrus.Fatalf("%v", err)
+		}
+		mismatches, err := manifest.VerifyDir(toPath)
+		if err != nil {
+			logrus.Fatalf("Verification failed: %v", err)
+		}
+		for _, m := range mismatches {
+			if m.Err != nil {
+				fmt.Printf("FAIL %s: %v\n", m.Path, m.Err)
+			} else {
+				fmt.Printf("FAIL %s: SHA-256 is %s, expected %s\n", m.Path, m.Actual, m.Expected)
+			}
+		}
+		fmt.Printf("%d files verified, %d failed\n", manifest.Len(), len(mismatches))
+		if len(mismatches) > 0 {
+			os.Exit(1)
+		}
+		return
+	case "diff":
+		results, err := filesConverter.DiffWith(fromPath, toPath, compareOptions, *heatmapDir)
+		if err != nil {
+			logrus.Fatalf("Diff failed: %v", err)
+		}
+		printDiffResults(results)
+		for _, r := range results {
+			if !r.OK() {
+				os.Exit(1)
+			}
+		}
+		return
+	case "conversions":
+		for _, c := range registry.Conversions() {
+			fmt.Printf("%-12s %s -> %s\n", c.Name, c.FromExt, c.ToExt)
+		}
+		return
+	case "bench":
+		bencher := converter.NewBencher(graphicsConverter)
+		bencher.SetMaxWorkers(*workers)
+		bencher.SetDuration(*benchTime)
+
+		// Report what was measured so far on Ctrl+C
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		results, err := bencher.Run(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logrus.Fatalf("Benchmark failed: %v", err)
+		}
+		if *jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(results); err != nil {
+				logrus.Fatalf("Writing results failed: %v", err)
+			}
+			return
+		}
+		printBenchResults(results)
+		return
+	case "serve":
+		httpServer := server.NewServer(registry)
+		httpServer.SetMaxRequestSize(int64(*serveMaxMB * 1024 * 1024))
+		httpServer.SetMaxConcurrent(*workers)
+
+		// Finish requests in flight and stop on Ctrl+C
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := httpServer.Run(ctx, *listenAddr); err != nil {
+			logrus.Fatalf("Server failed: %v", err)
+		}
+		return
+	case "bot":
+		token := os.Getenv(botTokenEnv)
+		if token == "" {
+			logrus.Fatalf("bot requires a Discord bot token in %s", botTokenEnv)
+		}
+		discordBot := bot.NewBot(token, graphicsConverter)
+		discordBot.SetMaxFileSize(int64(*botMaxMB * 1024 * 1024))
+		discordBot.SetMaxDimension(*botMaxSize)
+
+		// Disconnect cleanly on Ctrl+C
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := discordBot.Run(ctx); err != nil {
+			logrus.Fatalf("Bot failed: %v", err)
+		}
+		return
+	case "fetch-convert":
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by fetch-convert")
+		}
+		fetcher := converter.NewFetcher(filesConverter)
+		if err := fetcher.SetExpectedSHA256(*expectedSHA256); err != nil {
+			logrus.Fatalf("Invalid -sha256: %v", err)
+		}
+		// The URL is used as given, not as a path
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := fetcher.Fetch(ctx, from, toPath); err != nil {
+			conversionFailed(err)
+		}
+	case "gallery":
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by gallery")
+		}
+		galleryBuilder := converter.NewGalleryBuilder(graphicsConverter)
+		galleryBuilder.SetThumbnailSize(*thumbnailSize)
+		galleryBuilder.SetMaxWorkers(*workers)
+		entries, err := galleryBuilder.Build(fromPath, toPath)
+		if err != nil {
+			logrus.Fatalf("Gallery export failed: %v", err)
+		}
+		logrus.Infof("%d textures exported, open %s", len(entries), filepath.Join(toPath, "index.html"))
+	case "sheet":
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by sheet")
+		}
+		sheetBackground, err := converter.ParseHexColor(*background)
+		if err != nil {
+			logrus.Fatalf("Invalid -background: %v", err)
+		}
+		sheetBuilder := converter.NewContactSheetBuilder(graphicsConverter)
+		sheetBuilder.SetColumns(*columns)
+		sheetBuilder.SetCellSize(*cellSize)
+		sheetBuilder.SetBackground(sheetBackground)
+		sheetBuilder.SetMaxWorkers(*workers)
+		sheet, err := sheetBuilder.WriteFile(fromPath, toPath)
+		if err != nil {
+			logrus.Fatalf("Contact sheet failed: %v", err)
+		}
+		bounds := sheet.Image.Bounds()
+		logrus.Infof("%d textures on a %dx%d contact sheet written to %s", len(sheet.Entries), bounds.Dx(), bounds.Dy(), toPath)
+	case "animate":
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by animate")
+		}
+		format, err := converter.ParseAnimationFormat(*animationFormat)
+		if err != nil {
+			logrus.Fatalf("Invalid -animation-format: %v", err)
+		}
+		animationBuilder := converter.NewAnimationBuilder(graphicsConverter)
+		animationBuilder.SetFormat(format)
+		animationBuilder.SetFrameDelay(*frameDelay)
+		animationBuilder.SetMaxWorkers(*workers)
+		animations, err := animationBuilder.Build(fromPath, toPath)
+		if err != nil {
+			logrus.Fatalf("Animation previews failed: %v", err)
+		}
+		logrus.Infof("%d animation previews written to %s", len(animations), toPath)
+	case "search", "stats":
+		index, err := converter.OpenAssetIndex(fromPath)
+		if err != nil {
+			logrus.Fatalf("%v", err)
+		}
+		defer index.Close()
+		if command == "search" {
+			records, err := index.Search(args[2])
+			if err != nil {
+				logrus.Fatalf("Search failed: %v", err)
+			}
+			printRecords(records)
+			return
+		}
+		stats, err := index.Stats()
+		if err != nil {
+			logrus.Fatalf("Reading index failed: %v", err)
+		}
+		printIndexStats(stats)
+		return
+	case "info":
+		infos, err := graphicsConverter.TextureInfos(from)
+		if err != nil {
+			logrus.Fatalf("Reading headers failed: %v", err)
+		}
+		if *jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(infos); err != nil {
+				logrus.Fatalf("Writing info failed: %v", err)
+			}
+			return
+		}
+		printTextureInfos(infos)
+		return
+	case "tui":
+		if *showProgress {
+			logrus.Fatal("-progress is not supported by tui, which shows its own progress")
+		}
+		if !*extended {
+			for name := range conversions {
+				if strings.Contains(name, "xdat") {
+					delete(conversions, name)
+				}
+			}
+		}
+		if err := runTUI(fromPath, toPath, graphicsConverter, filesConverter, conversions, recordProgress); err != nil {
+			logrus.Fatalf("tui failed: %v", err)
+		}
+	case "diff-vanilla":
+		if *celesteDir == "" {
+			logrus.Fatal("diff-vanilla requires -celeste <install>")
+		}
+		comparisons, err := converter.NewVanillaComparer(graphicsConverter, *celesteDir).Compare(fromPath)
+		if err != nil {
+			logrus.Fatalf("Comparison failed: %v", err)
+		}
+		printComparisons(comparisons)
+		return
+	case "backup":
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by backup")
+		}
+		if _, err := filesConverter.Backup(fromPath, toPath); err != nil {
+			logrus.Fatalf("Backup failed: %v", err)
+		}
+	case "restore":
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by restore")
+		}
+		if _, err := filesConverter.Restore(fromPath, toPath); err != nil {
+			logrus.Fatalf("Restore failed: %v", err)
+		}
+	case "restore-backups":
+		if !backup.Enabled() {
+			logrus.Fatal("restore-backups requires -backup <dir|suffix>")
+		}
+		if *dryRun {
+			logrus.Fatal("-dry-run is not supported by restore-backups")
+		}
+		restored, err := filesConverter.RestoreBackups(backup, fromPath)
+		if err != nil {
+			logrus.Fatalf("Restore failed: %v", err)
+		}
+		for _, path := range restored {
+			fmt.Println(path)
+		}
+		return
+	case "make-patch":
+		patchPath, err := filepath.Abs(args[3])
+		if err != nil {
+			logrus.Fatalf("Invalid patch path: %v", err)
+		}
+		if _, err := graphicsConverter.CreatePatchFile(fromPath, toPath, patchPath); err != nil {
+			logrus.Fatalf("Creating patch failed: %v", err)
+		}
+	case "apply-patch":
+		outputPath, err := filepath.Abs(args[3])
+		if err != nil {
+			logrus.Fatalf("Invalid output path: %v", err)
+		}
+		if err := graphicsConverter.ApplyPatchFile(fromPath, toPath, outputPath); err != nil {
+			var conflictErr *converter.PatchConflictError
+			if errors.As(err, &conflictErr) {
+				for _, c := range conflictErr.Conflicts {
+					fmt.Printf("conflict at (%d,%d): expected %v, found %v, patch writes %v\n",
+						c.X, c.Y, c.Expected, c.Actual, c.Patched)
+				}
+			}
+			logrus.Fatalf("Applying patch failed: %v", err)
+		}
 	default:
-		logrus.Fatalf("Unrecognized command: %s", command)
+		conv, ok := conversions[command]
+		if !ok {
+			logrus.Fatalf("Unrecognized command: %s", command)
+		}
+
+		// The first interrupt lets the files being converted finish, the second cancels them
+		ctx, stopInterrupts := handleInterrupts(filesConverter)
+		defer stopInterrupts()
+
+		if *fromClipboard {
+			if *dryRun {
+				logrus.Infof("[dry-run] clipboard -> %s", toPath)
+			} else if err := convertClipboard(toPath, conv.file); err != nil {
+				report.RecordFailure("clipboard", toPath, err)
+				conversionFailed(err)
+			}
+			break
+		}
+
+		// Several source directories are merged into the last path
+		if len(args) > 3 {
+			if *fromClipboard {
+				logrus.Fatal("-from-clipboard takes a single output file: png2data -from-clipboard <to_file>")
+			}
+			fromPaths := make([]string, 0, len(args)-2)
+			for _, arg := range args[1 : len(args)-1] {
+				path, err := filepath.Abs(arg)
+				if err != nil {
+					logrus.Fatalf("Invalid 'from' path: %v", err)
+				}
+				fromPaths = append(fromPaths, path)
+			}
+			if toPath, err = filepath.Abs(args[len(args)-1]); err != nil {
+				logrus.Fatalf("Invalid 'to' path: %v", err)
+			}
+
+			mappings := extMappings
+			if len(mappings) == 0 {
+				mappings = []converter.ExtensionMapping{{From: conv.fromExt, To: conv.toExt}}
+			}
+			for _, m := range mappings {
+				if err := filesConverter.ConvertMergedContext(ctx, fromPaths, toPath, m.From, m.To, conv.file); err != nil {
+					conversionFailed(err)
+				}
+			}
+			break
+		}
+
+		// Zip archives are read like source directories
+		info, err := os.Stat(fromPath)
+		singleFile := err == nil && !info.IsDir() && !converter.IsZipArchive(fromPath)
+		if fromPath == stdioPath || toPath == stdioPath || singleFile {
+			if *dryRun {
+				logrus.Infof("[dry-run] %s -> %s", fromPath, toPath)
+			} else if err := convertFile(ctx, fromPath, toPath, *memoryMap, conv.file); err != nil {
+				report.RecordFailure(fromPath, toPath, err)
+				conversionFailed(err)
+			}
+		} else if len(extMappings) > 0 {
+			// Custom extensions run the command's conversion once per pair
+			for _, m := range extMappings {
+				if err := filesConverter.ConvertContext(ctx, fromPath, toPath, m.From, m.To, conv.file); err != nil {
+					conversionFailed(err)
+				}
+			}
+		} else if err := conv.dir(ctx, fromPath, toPath); err != nil {
+			conversionFailed(err)
+		}
+	}
+
+	finish("ok")
+}
+
+// patternList collects the values of a repeatable flag, also splitting each value at commas
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternList) Set(value string) error {
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			*p = append(*p, pattern)
+		}
+	}
+	return nil
+}
+
+// summaryStats is the final summary printed with -log-format=json
+type summaryStats struct {
+	Status  string `json:"status"`
+	Command string `json:"command"`
+	converter.RunSummary
+}
+
+// logLevels maps the names accepted by -log-level to logrus levels
+var logLevels = map[string]logrus.Level{
+	"trace": logrus.TraceLevel,
+	"debug": logrus.DebugLevel,
+	"info":  logrus.InfoLevel,
+	"warn":  logrus.WarnLevel,
+	"error": logrus.ErrorLevel,
+}
+
+// resolveLogLevel combines -log-level with its -verbose and -quiet shorthands, which can't be mixed
+func resolveLogLevel(name string, verbose, quiet bool) (logrus.Level, error) {
+	set := 0
+	for _, given := range []bool{name != "", verbose, quiet} {
+		if given {
+			set++
+		}
+	}
+	if set > 1 {
+		return 0, errors.New("only one of -log-level, -verbose and -quiet can be given")
+	}
+
+	switch {
+	case verbose:
+		return logrus.DebugLevel, nil
+	case quiet:
+		return logrus.WarnLevel, nil
+	case name == "":
+		return logrus.InfoLevel, nil
+	}
+	level, ok := logLevels[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("invalid -log-level '%s', expected trace, debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// isTerminal reports whether file is a terminal rather than a pipe or regular file
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// conversion pairs the directory and single-file forms of a conversion command
+type conversion struct {
+	fromExt string
+	toExt   string
+	dir     func(ctx context.Context, fromDir, toDir string) error
+	file    func(io.Reader, io.Writer) error
+}
+
+// parseCommandArgs returns the command and its path arguments, parsing options given after the command
+// name too, as in diff-vanilla -celeste <install> <mod_dir>: flag stops at the first argument that isn't
+// an option. Options after "watch" and its command are parsed the same way.
+func parseCommandArgs() []string {
+	var command []string
+	for flag.NArg() > 0 {
+		name := flag.Arg(0)
+		command = append(command, name)
+		// CommandLine exits on invalid options, like the first parse
+		flag.CommandLine.Parse(flag.Args()[1:])
+		if name != "watch" || len(command) == 2 {
+			break
+		}
+	}
+	return append(command, flag.Args()...)
+}
+
+// stdioPath is the path argument standing for stdin (as input) or stdout (as output)
+const stdioPath = "-"
+
+// absPath makes path absolute unless it is stdioPath
+func absPath(path string) (string, error) {
+	if path == stdioPath {
+		return path, nil
+	}
+	return filepath.Abs(path)
+}
+
+// convertFile converts a single file, removing the output again if conversion fails.
+// Either path may be stdioPath to stream through stdin or stdout. With memoryMap, a large input is memory-mapped.
+// Reads fail once ctx is cancelled.
+func convertFile(ctx context.Context, fromPath, toPath string, memoryMap bool, convertFunc func(io.Reader, io.Writer) error) error {
+	logrus.Infof("Converting %s -> %s", fromPath, toPath)
+
+	var input io.Reader = os.Stdin
+	if fromPath != stdioPath {
+		var inputFile io.ReadCloser
+		var err error
+		if memoryMap {
+			inputFile, err = converter.OpenMapped(fromPath)
+		} else {
+			inputFile, err = os.Open(fromPath)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open input file '%s': %w", fromPath, err)
+		}
+		defer inputFile.Close()
+		input = inputFile
+	}
+
+	return writeOutput(converter.NewContextReader(ctx, input), toPath, convertFunc)
+}
+
+// convertClipboard converts the image on the clipboard
+func convertClipboard(toPath string, convertFunc func(io.Reader, io.Writer) error) error {
+	logrus.Infof("Converting clipboard -> %s", toPath)
+
+	data, err := readClipboardImage()
+	if err != nil {
+		return fmt.Errorf("failed to read clipboard image: %w", err)
 	}
+	return writeOutput(bytes.NewReader(data), toPath, convertFunc)
+}
 
-	// Calculate elapsed time
-	elapsed := time.Since(startTime)
+// writeOutput converts input into toPath, or stdout for stdioPath, removing the output again if conversion fails
+func writeOutput(input io.Reader, toPath string, convertFunc func(io.Reader, io.Writer) error) error {
+	if toPath == stdioPath {
+		return convertFunc(input, os.Stdout)
+	}
 
-	fmt.Printf("Conversion completed successfully in %v\n", elapsed)
+	if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(toPath), err)
+	}
+
+	outputFile, err := os.Create(toPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file '%s': %w", toPath, err)
+	}
+
+	err = convertFunc(input, outputFile)
+	if closeErr := outputFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(toPath)
+		return err
+	}
+	return nil
+}
+
+// printRecords prints one line per indexed asset
+func printRecords(records []converter.AssetRecord) {
+	for _, r := range records {
+		details := fmt.Sprintf("%d bytes", r.Size)
+		if r.IsTexture() {
+			format := "RGB"
+			if r.HasAlpha {
+				format = "RGBA"
+			}
+			details = fmt.Sprintf("%dx%d %s, %s", r.Width, r.Height, format, details)
+		}
+		fmt.Printf("%s (%s, %s from %s)\n", r.Path, details, r.Conversion, r.Source)
+	}
+	fmt.Printf("%d assets found\n", len(records))
+}
+
+// printIndexStats prints the totals of an index followed by the asset count per conversion
+func printIndexStats(stats *converter.IndexStats) {
+	fmt.Printf("%d assets, %d textures (%d with alpha), %d bytes, %d pixels\n",
+		stats.Assets, stats.Textures, stats.WithAlpha, stats.TotalBytes, stats.TotalPixels)
+
+	conversions := make([]string, 0, len(stats.ByConversion))
+	for conversion := range stats.ByConversion {
+		conversions = append(conversions, conversion)
+	}
+	sort.Strings(conversions)
+	for _, conversion := range conversions {
+		fmt.Printf("  %s: %d\n", conversion, stats.ByConversion[conversion])
+	}
+}
+
+// printTextureInfos prints one line per texture with what its header says
+func printTextureInfos(infos []converter.TextureInfo) {
+	for _, info := range infos {
+		if info.Error != "" {
+			fmt.Printf("%s: %s\n", info.Path, info.Error)
+			continue
+		}
+		format := "RGB"
+		if info.HasAlpha {
+			format = "RGBA"
+		}
+		if info.BitDepth > 0 {
+			format = fmt.Sprintf("%s, %d-bit %s", format, info.BitDepth, info.ColorType)
+			if info.Interlaced {
+				format += " interlaced"
+			}
+		}
+		fmt.Printf("%s: %s %dx%d %s, %d bytes, %d bytes decoded (%.1f:1)\n", info.Path, info.Format,
+			info.Width, info.Height, format, info.EncodedSize, info.DecodedSize, info.CompressionRatio)
+	}
+}
+
+// printBenchResults prints a table of the throughput of every case, direction and worker count
+func printBenchResults(results []converter.BenchResult) {
+	fmt.Printf("%-16s %-9s %7s %10s %10s\n", "IMAGE", "DIRECTION", "WORKERS", "MB/S", "IMAGES/S")
+	for _, r := range results {
+		fmt.Printf("%-16s %-9s %7d %10.1f %10.1f\n", r.Case, r.Direction, r.Workers, r.MBPerSecond, r.ImagesPerSecond)
+	}
+}
+
+// printDiffResults prints one line per texture with how it differs, followed by the overall result
+func printDiffResults(results []converter.DiffResult) {
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Printf("FAIL %s: %v\n", r.Name, r.Err)
+		case r.SizeA != r.SizeB:
+			failed++
+			fmt.Printf("FAIL %s: size changed from %dx%d to %dx%d\n", r.Name, r.SizeA.X, r.SizeA.Y, r.SizeB.X, r.SizeB.Y)
+		case r.ChangedPixels > 0:
+			failed++
+			fmt.Printf("FAIL %s: %d pixels differ in %v (max delta %d, mean delta %.3f)\n", r.Name, r.ChangedPixels, r.Bounds, r.MaxDelta, r.MeanDelta)
+		default:
+			fmt.Printf("OK   %s (max delta %d, mean delta %.3f)\n", r.Name, r.MaxDelta, r.MeanDelta)
+		}
+		if r.HeatmapPath != "" {
+			fmt.Printf("     heatmap: %s\n", r.HeatmapPath)
+		}
+	}
+	result := "PASS"
+	if failed > 0 {
+		result = "FAIL"
+	}
+	fmt.Printf("%s: %d textures compared, %d differ\n", result, len(results), failed)
+}
+
+// printComparisons prints one line per mod texture describing how it differs from vanilla
+func printComparisons(comparisons []converter.TextureComparison) {
+	overrides, changed := 0, 0
+	for _, c := range comparisons {
+		name := c.Atlas + "/" + c.Key
+		if !c.VanillaFound {
+			fmt.Printf("%s: new texture (%dx%d)\n", name, c.ModSize.X, c.ModSize.Y)
+			continue
+		}
+
+		overrides++
+		size := fmt.Sprintf("%dx%d", c.ModSize.X, c.ModSize.Y)
+		if c.SizeChanged() {
+			size = fmt.Sprintf("%dx%d -> %dx%d", c.VanillaSize.X, c.VanillaSize.Y, c.ModSize.X, c.ModSize.Y)
+		}
+		if c.ChangedPixels == 0 {
+			fmt.Printf("%s: %s, identical to vanilla\n", name, size)
+			continue
+		}
+
+		changed++
+		total := c.ModSize.X * c.ModSize.Y
+		fmt.Printf("%s: %s, %d/%d pixels changed (%.1f%%), max delta %d, PSNR %.1f dB, region %v\n",
+			name, size, c.ChangedPixels, total, float64(c.ChangedPixels)*100/float64(total), c.MaxDelta, c.PSNR, c.ChangedBounds)
+	}
+	fmt.Printf("%d textures, %d override vanilla, %d differ from it\n", len(comparisons), overrides, changed)
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// setNice applies a nice value to the whole process.
+// Linux tracks niceness per thread, so every existing thread is updated; threads started later inherit it.
+func setNice(nice int) error {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice)
+	}
+
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
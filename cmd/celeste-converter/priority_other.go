@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package main
+
+import "errors"
+
+// setNice is not supported on this platform
+func setNice(nice int) error {
+	return errors.New("process priority is not supported on this platform")
+}
@@ -0,0 +1,15 @@
+package main
+
+import "golang.org/x/sys/windows"
+
+// setNice maps a Unix nice value onto a Windows priority class
+func setNice(nice int) error {
+	var class uint32 = windows.NORMAL_PRIORITY_CLASS
+	switch {
+	case nice >= 15:
+		class = windows.IDLE_PRIORITY_CLASS
+	case nice > 0:
+		class = windows.BELOW_NORMAL_PRIORITY_CLASS
+	}
+	return windows.SetPriorityClass(windows.CurrentProcess(), class)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+
+// handlePauseSignals is a no-op on platforms without SIGUSR1/SIGUSR2
+func handlePauseSignals(filesConverter *converter.FilesConverter) {}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+)
+
+// handlePauseSignals pauses the converter on SIGUSR1 and resumes it on SIGUSR2
+func handlePauseSignals(filesConverter *converter.FilesConverter) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGUSR1 {
+				filesConverter.Pause()
+			} else {
+				filesConverter.Resume()
+			}
+		}
+	}()
+}
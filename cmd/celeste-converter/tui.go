@@ -0,0 +1,411 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+)
+
+const tuiHelp = `Commands:
+  N          Open directory N, or show the header of file N
+  s N...     Select or unselect entries, e.g. "s 1 3-5"; "s *" toggles every entry
+  ..         Go up a directory
+  c [NAME]   Convert the selection, choosing from the conversions of its files without NAME
+  x          Clear the selection
+  q          Quit
+Ctrl+C during a conversion cancels it.`
+
+// tuiEntry is a listed file or directory with the header of textures
+type tuiEntry struct {
+	name  string
+	dir   bool
+	size  int64
+	info  *converter.TextureInfo
+	error string
+}
+
+// tui is the interactive front end of the tui command: a browser over the source, a directory or mod
+// zip, listing texture headers and converting the selected files and directories with live progress
+type tui struct {
+	in          *bufio.Scanner
+	out         io.Writer
+	clear       bool // Clear the screen before every listing
+	source      fs.FS
+	fromPath    string
+	toPath      string
+	graphics    *converter.GraphicsConverter
+	files       *converter.FilesConverter
+	conversions map[string]conversion
+	record      func(converter.ProgressEvent) // Receives the events of every conversion run
+
+	dir      string // Current directory, slash-separated, "." at the root
+	entries  []tuiEntry
+	selected map[string]bool // Selected relative paths of files and directories
+	status   string          // Shown below the next listing
+}
+
+// runTUI browses fromPath interactively until the user quits or stdin ends, converting selections
+// into toPath
+func runTUI(fromPath, toPath string, graphics *converter.GraphicsConverter, files *converter.FilesConverter,
+	conversions map[string]conversion, record func(converter.ProgressEvent)) error {
+	var source fs.FS = os.DirFS(fromPath)
+	if converter.IsZipArchive(fromPath) {
+		archive, err := zip.OpenReader(fromPath)
+		if err != nil {
+			return fmt.Errorf("failed to open zip archive '%s': %w", fromPath, err)
+		}
+		defer archive.Close()
+		source = archive
+	}
+	files.SetQuietFiles(true)
+
+	t := &tui{
+		in:          bufio.NewScanner(os.Stdin),
+		out:         os.Stdout,
+		clear:       isTerminal(os.Stdout),
+		source:      source,
+		fromPath:    fromPath,
+		toPath:      toPath,
+		graphics:    graphics,
+		files:       files,
+		conversions: conversions,
+		record:      record,
+		dir:         ".",
+		selected:    make(map[string]bool),
+	}
+	if err := t.open("."); err != nil {
+		return err
+	}
+	for {
+		t.list()
+		line, ok := t.prompt("> ")
+		if !ok {
+			return nil
+		}
+		if quit := t.handle(line); quit {
+			return nil
+		}
+	}
+}
+
+// prompt reads a line, reporting false once stdin ends
+func (t *tui) prompt(text string) (string, bool) {
+	fmt.Fprint(t.out, text)
+	if !t.in.Scan() {
+		fmt.Fprintln(t.out)
+		return "", false
+	}
+	return strings.TrimSpace(t.in.Text()), true
+}
+
+// handle runs a command line, reporting whether the user quit
+func (t *tui) handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "q", "quit":
+		return true
+	case "?", "h", "help":
+		t.status = tuiHelp
+	case "..":
+		if t.dir != "." {
+			t.reportError(t.open(path.Dir(t.dir)))
+		}
+	case "s":
+		t.toggle(fields[1:])
+	case "x":
+		clear(t.selected)
+	case "c":
+		t.convert(strings.Join(fields[1:], " "))
+	default:
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 1 || n > len(t.entries) {
+			t.status = fmt.Sprintf("Unknown command %q, ? lists the commands", line)
+			return false
+		}
+		entry := t.entries[n-1]
+		if entry.dir {
+			t.reportError(t.open(t.child(entry.name)))
+		} else {
+			t.status = t.describe(entry)
+		}
+	}
+	return false
+}
+
+// reportError shows err, if any, below the next listing
+func (t *tui) reportError(err error) {
+	if err != nil {
+		t.status = "Error: " + err.Error()
+	}
+}
+
+// child returns the relative path of the entry name of the current directory
+func (t *tui) child(name string) string {
+	return path.Join(t.dir, name)
+}
+
+// open lists dir, reading the header of every texture in it
+func (t *tui) open(dir string) error {
+	dirEntries, err := fs.ReadDir(t.source, dir)
+	if err != nil {
+		return err
+	}
+	entries := make([]tuiEntry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		entry := tuiEntry{name: dirEntry.Name(), dir: dirEntry.IsDir()}
+		if !entry.dir {
+			if stat, err := dirEntry.Info(); err == nil {
+				entry.size = stat.Size()
+			}
+			if info, err := t.graphics.TextureInfoFS(t.source, path.Join(dir, entry.name)); err == nil {
+				entry.info = info
+			} else {
+				entry.error = err.Error()
+			}
+		}
+		entries = append(entries, entry)
+	}
+	// Directories first, each group by name
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].dir != entries[j].dir {
+			return entries[i].dir
+		}
+		return entries[i].name < entries[j].name
+	})
+	t.dir, t.entries = dir, entries
+	return nil
+}
+
+// list shows the current directory with the status line of the last command
+func (t *tui) list() {
+	if t.clear {
+		fmt.Fprint(t.out, "\033[H\033[2J")
+	}
+	fmt.Fprintf(t.out, "%s: %s  (%d selected, ? for help)\n\n", t.fromPath, t.dir, len(t.selected))
+	for i, entry := range t.entries {
+		mark := " "
+		if t.selected[t.child(entry.name)] {
+			mark = "*"
+		}
+		switch {
+		case entry.dir:
+			fmt.Fprintf(t.out, "%s%4d  %s/\n", mark, i+1, entry.name)
+		case entry.info != nil:
+			alpha := ""
+			if entry.info.HasAlpha {
+				alpha = ", alpha"
+			}
+			fmt.Fprintf(t.out, "%s%4d  %-40s %10s  %s %dx%d%s\n", mark, i+1, entry.name, formatSize(entry.size),
+				entry.info.Format, entry.info.Width, entry.info.Height, alpha)
+		default:
+			fmt.Fprintf(t.out, "%s%4d  %-40s %10s\n", mark, i+1, entry.name, formatSize(entry.size))
+		}
+	}
+	if t.status != "" {
+		fmt.Fprintf(t.out, "\n%s\n", t.status)
+		t.status = ""
+	}
+}
+
+// describe returns the header of a listed file, or why it couldn't be read
+func (t *tui) describe(entry tuiEntry) string {
+	if entry.info == nil {
+		return fmt.Sprintf("%s: %s", entry.name, entry.error)
+	}
+	info := entry.info
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n  Format:      %s\n  Size:        %dx%d\n  Alpha:       %v\n", t.child(entry.name),
+		info.Format, info.Width, info.Height, info.HasAlpha)
+	if info.ColorType != "" {
+		fmt.Fprintf(&b, "  Color type:  %s, %d-bit\n", info.ColorType, info.BitDepth)
+	}
+	fmt.Fprintf(&b, "  Encoded:     %s\n  Decoded:     %s (%.1fx)", formatSize(info.EncodedSize),
+		formatSize(info.DecodedSize), info.CompressionRatio)
+	return b.String()
+}
+
+// toggle selects or unselects the entries numbered by args, single numbers, ranges such as 3-5 or *
+func (t *tui) toggle(args []string) {
+	var numbers []int
+	for _, arg := range args {
+		if arg == "*" {
+			for i := range t.entries {
+				numbers = append(numbers, i+1)
+			}
+			continue
+		}
+		first, last, isRange := strings.Cut(arg, "-")
+		from, err := strconv.Atoi(first)
+		to := from
+		if err == nil && isRange {
+			to, err = strconv.Atoi(last)
+		}
+		if err != nil || from < 1 || to > len(t.entries) || from > to {
+			t.status = fmt.Sprintf("Invalid entry %q", arg)
+			return
+		}
+		for n := from; n <= to; n++ {
+			numbers = append(numbers, n)
+		}
+	}
+	for _, n := range numbers {
+		relPath := t.child(t.entries[n-1].name)
+		if t.selected[relPath] {
+			delete(t.selected, relPath)
+		} else {
+			t.selected[relPath] = true
+		}
+	}
+}
+
+// selectedFiles returns the selected files and the files below the selected directories, sorted
+func (t *tui) selectedFiles() ([]string, error) {
+	found := make(map[string]bool)
+	for relPath := range t.selected {
+		err := fs.WalkDir(t.source, relPath, func(filePath string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				found[filePath] = true
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	files := make([]string, 0, len(found))
+	for filePath := range found {
+		files = append(files, filePath)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// convert runs the conversion name over the selection, asking which one to run if name is empty
+func (t *tui) convert(name string) {
+	files, err := t.selectedFiles()
+	if err != nil {
+		t.reportError(err)
+		return
+	}
+	if len(files) == 0 {
+		t.status = "Nothing selected, s N selects entries"
+		return
+	}
+
+	// Offer the conversions reading at least one of the selected files
+	inputs := make(map[string][]string)
+	for _, file := range files {
+		for convName, conv := range t.conversions {
+			if strings.HasSuffix(strings.ToLower(file), conv.fromExt) {
+				inputs[convName] = append(inputs[convName], file)
+			}
+		}
+	}
+	if name == "" {
+		names := make([]string, 0, len(inputs))
+		for convName := range inputs {
+			names = append(names, convName)
+		}
+		if len(names) == 0 {
+			t.status = "No conversion reads the selected files"
+			return
+		}
+		sort.Strings(names)
+		fmt.Fprintln(t.out)
+		for i, convName := range names {
+			fmt.Fprintf(t.out, "%4d  %-12s %d files\n", i+1, convName, len(inputs[convName]))
+		}
+		answer, ok := t.prompt("Conversion: ")
+		n, err := strconv.Atoi(answer)
+		if !ok || err != nil || n < 1 || n > len(names) {
+			t.status = "Conversion cancelled"
+			return
+		}
+		name = names[n-1]
+	}
+	conv, ok := t.conversions[name]
+	if !ok {
+		t.status = fmt.Sprintf("Unknown conversion %q", name)
+		return
+	}
+	if len(inputs[name]) == 0 {
+		t.status = fmt.Sprintf("None of the selected files is read by %s", name)
+		return
+	}
+
+	t.status = t.run(name, conv, inputs[name])
+}
+
+// run converts files with conv, printing a line per file as it finishes, and returns a summary
+func (t *tui) run(name string, conv conversion, files []string) string {
+	fmt.Fprintf(t.out, "\nConverting %d files with %s into %s\n", len(files), name, t.toPath)
+	summary := name + ": nothing converted"
+	listed := files
+	if !converter.IsZipArchive(t.fromPath) {
+		// Relative entries would be resolved from the working directory first
+		listed = make([]string, len(files))
+		for i, file := range files {
+			listed[i] = filepath.Join(t.fromPath, filepath.FromSlash(file))
+		}
+	}
+	t.files.SetFileList(listed)
+	t.files.Progress(func(event converter.ProgressEvent) {
+		t.record(event)
+		done := event.CompletedFiles + event.FailedFiles
+		switch event.Type {
+		case converter.FileFinished:
+			fmt.Fprintf(t.out, "[%d/%d] %s  %s in %v\n", done, event.TotalFiles, event.RelPath,
+				formatSize(event.BytesWritten), event.Duration.Round(time.Millisecond))
+		case converter.FileFailed:
+			fmt.Fprintf(t.out, "[%d/%d] %s  FAILED: %v\n", done, event.TotalFiles, event.RelPath, event.Err)
+		case converter.BatchFinished:
+			summary = fmt.Sprintf("%s: %d converted, %d failed in %v", name, event.CompletedFiles,
+				event.FailedFiles, event.Elapsed.Round(time.Millisecond))
+		}
+	})
+	defer func() {
+		t.files.SetFileList(nil)
+		t.files.Progress(t.record)
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := conv.dir(ctx, t.fromPath, t.toPath)
+	if ctx.Err() != nil {
+		return "Conversion cancelled"
+	}
+	if err != nil {
+		summary = fmt.Sprintf("%s failed: %v", name, err)
+	}
+	t.prompt("\nPress Enter to continue ")
+	return summary
+}
+
+// formatSize formats a byte count with a binary unit
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, units := float64(bytes)/unit, "KMGT"
+	for value >= unit && len(units) > 1 {
+		value, units = value/unit, units[1:]
+	}
+	return fmt.Sprintf("%.1f %ciB", value, units[0])
+}
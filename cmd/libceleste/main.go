@@ -0,0 +1,92 @@
+// Command libceleste builds the converter as a C library, so C#, Python and existing Celeste modding
+// tools can convert textures in memory without running the binary:
+//
+//	go build -buildmode=c-shared -o libceleste.so ./cmd/libceleste
+//	go build -buildmode=c-archive -o libceleste.a ./cmd/libceleste
+//
+// Both also write libceleste.h declaring the exported functions. Conversions use the default settings
+// of the command line tool. Every function is safe to call from several threads at once.
+//
+// The exported API is stable: functions are only ever added, and their signatures and ownership
+// rules never change.
+package main
+
+/*
+#include <stdlib.h>
+
+// The conversion functions return NULL on success, with the result in a malloc'd buffer stored in
+// output and outputLen, or an error message. Release both with ConverterFree.
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+)
+
+func main() {}
+
+// ConvertDataToPng converts the DATA texture of inputLen bytes at input to a PNG image. On success it
+// returns NULL and stores a buffer allocated with malloc and its length in output and outputLen, to be
+// released with ConverterFree. On failure it returns the error message, also to be released with
+// ConverterFree, and leaves output untouched.
+//
+//export ConvertDataToPng
+func ConvertDataToPng(input *C.uchar, inputLen C.size_t, output **C.uchar, outputLen *C.size_t) *C.char {
+	return convertBuffer(converter.NewGraphicsConverter().DataToPng, input, inputLen, output, outputLen)
+}
+
+// ConvertPngToData converts the PNG image of inputLen bytes at input to a DATA texture, with the same
+// conventions as ConvertDataToPng.
+//
+//export ConvertPngToData
+func ConvertPngToData(input *C.uchar, inputLen C.size_t, output **C.uchar, outputLen *C.size_t) *C.char {
+	return convertBuffer(converter.NewGraphicsConverter().PngToData, input, inputLen, output, outputLen)
+}
+
+// ConverterFree releases a buffer or error message returned by the library. NULL is ignored.
+//
+//export ConverterFree
+func ConverterFree(pointer unsafe.Pointer) {
+	C.free(pointer)
+}
+
+// ConverterVersion returns the version of the converter, to be released with ConverterFree
+//
+//export ConverterVersion
+func ConverterVersion() *C.char {
+	return C.CString(converter.Version)
+}
+
+// convertBuffer runs convertFunc on a C buffer, copying the result into a malloc'd buffer. Panics are
+// returned as errors, since one crossing into the host would crash it.
+func convertBuffer(convertFunc func(io.Reader, io.Writer) error, input *C.uchar, inputLen C.size_t, output **C.uchar, outputLen *C.size_t) (message *C.char) {
+	defer func() {
+		if r := recover(); r != nil {
+			message = C.CString(fmt.Sprintf("conversion panicked: %v", r))
+		}
+	}()
+	if output == nil || outputLen == nil {
+		return C.CString("output and outputLen must not be NULL")
+	}
+	if input == nil && inputLen > 0 {
+		return C.CString("input is NULL")
+	}
+
+	// The input is only read during the call, so it isn't copied
+	var data []byte
+	if inputLen > 0 {
+		data = unsafe.Slice((*byte)(unsafe.Pointer(input)), int(inputLen))
+	}
+	var converted bytes.Buffer
+	if err := convertFunc(bytes.NewReader(data), &converted); err != nil {
+		return C.CString(err.Error())
+	}
+	*output = (*C.uchar)(C.CBytes(converted.Bytes()))
+	*outputLen = C.size_t(converted.Len())
+	return nil
+}
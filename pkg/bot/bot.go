@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Defaults keep a single attachment from using much memory: a 2048×2048 texture decodes to 16 MiB
+const (
+	defaultMaxFileSize   = 8 << 20
+	defaultMaxDimension  = 2048
+	defaultMaxConcurrent = 4
+)
+
+// Bot is a Discord bot that replies to .data and .png attachments with their conversion
+// to the other format, for quick sprite previews
+type Bot struct {
+	graphicsConverter *converter.GraphicsConverter
+	log               converter.Logger
+	token             string
+	client            *http.Client
+	maxFileSize       int64
+	maxDimension      int
+	slots             chan struct{} // Limits attachments converted at once
+}
+
+// NewBot creates a new Bot authenticating with a Discord bot token
+func NewBot(token string, graphicsConverter *converter.GraphicsConverter) *Bot {
+	return &Bot{
+		graphicsConverter: graphicsConverter,
+		log:               converter.DefaultLogger(),
+		token:             token,
+		client:            &http.Client{Timeout: 30 * time.Second},
+		maxFileSize:       defaultMaxFileSize,
+		maxDimension:      defaultMaxDimension,
+		slots:             make(chan struct{}, defaultMaxConcurrent),
+	}
+}
+
+// SetLogger sets where the bot logs, nil to discard its messages
+func (b *Bot) SetLogger(logger converter.Logger) {
+	if logger == nil {
+		logger = converter.NopLogger{}
+	}
+	b.log = logger
+}
+
+// SetMaxFileSize sets the largest attachment in bytes the bot downloads
+func (b *Bot) SetMaxFileSize(size int64) {
+	if size > 0 {
+		b.maxFileSize = size
+	}
+}
+
+// SetMaxDimension sets the largest texture width and height the bot converts
+func (b *Bot) SetMaxDimension(pixels int) {
+	if pixels > 0 {
+		b.maxDimension = pixels
+	}
+}
+
+// Run connects to Discord and handles messages until ctx is cancelled
+func (b *Bot) Run(ctx context.Context) error {
+	session, err := discordgo.New("Bot " + b.token)
+	if err != nil {
+		return fmt.Errorf("failed to create Discord session: %w", err)
+	}
+	// Attachments of guild messages are only delivered with the message content intent
+	session.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages | discordgo.IntentsMessageContent
+	session.AddHandler(b.onMessage)
+
+	if err := session.Open(); err != nil {
+		return fmt.Errorf("failed to connect to Discord: %w", err)
+	}
+	defer session.Close()
+
+	b.log.Infof("Bot is running, attach a .data or .png file to a message to convert it")
+	<-ctx.Done()
+	return nil
+}
+
+// onMessage converts every supported attachment of a message and replies with the results
+func (b *Bot) onMessage(session *discordgo.Session, message *discordgo.MessageCreate) {
+	if message.Author == nil || message.Author.Bot {
+		return
+	}
+
+	for _, attachment := range message.Attachments {
+		if targetExtension(attachment.Filename) == "" {
+			continue
+		}
+
+		b.slots <- struct{}{}
+		reply := b.convertAttachment(attachment)
+		<-b.slots
+
+		reply.Reference = message.Reference()
+		if _, err := session.ChannelMessageSendComplex(message.ChannelID, reply); err != nil {
+			b.log.Warnf("Failed to reply to %s: %v", attachment.Filename, err)
+		}
+	}
+}
+
+// convertAttachment downloads and converts an attachment, returning the reply to send
+func (b *Bot) convertAttachment(attachment *discordgo.MessageAttachment) *discordgo.MessageSend {
+	b.log.Infof("Converting attachment %s (%d bytes)", attachment.Filename, attachment.Size)
+
+	name, output, err := b.fetchAndConvert(attachment)
+	if err != nil {
+		b.log.Warnf("Failed to convert %s: %v", attachment.Filename, err)
+		return &discordgo.MessageSend{Content: fmt.Sprintf("Couldn't convert %s: %v", attachment.Filename, err)}
+	}
+	return &discordgo.MessageSend{
+		Files: []*discordgo.File{{Name: name, Reader: bytes.NewReader(output)}},
+	}
+}
+
+// fetchAndConvert downloads an attachment within the size limit and converts it
+func (b *Bot) fetchAndConvert(attachment *discordgo.MessageAttachment) (string, []byte, error) {
+	if int64(attachment.Size) > b.maxFileSize {
+		return "", nil, fmt.Errorf("file is larger than %d bytes", b.maxFileSize)
+	}
+
+	response, err := b.client.Get(attachment.URL)
+	if err != nil {
+		return "", nil, errors.New("download failed")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("download failed with status %d", response.StatusCode)
+	}
+
+	return b.convert(attachment.Filename, response.Body)
+}
+
+// convert converts a .data file to PNG or a .png file to DATA, returning the output name and content.
+// Input is read up to the size limit and texture dimensions are checked before any pixels are decoded.
+func (b *Bot) convert(filename string, input io.Reader) (string, []byte, error) {
+	toExt := targetExtension(filename)
+	if toExt == "" {
+		return "", nil, errors.New("only .data and .png files are supported")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(input, b.maxFileSize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > b.maxFileSize {
+		return "", nil, fmt.Errorf("file is larger than %d bytes", b.maxFileSize)
+	}
+
+	width, height, err := textureSize(data, toExt)
+	if err != nil {
+		return "", nil, err
+	}
+	if width <= 0 || height <= 0 || width > b.maxDimension || height > b.maxDimension {
+		return "", nil, fmt.Errorf("%dx%d is outside the supported size of up to %dx%d", width, height, b.maxDimension, b.maxDimension)
+	}
+
+	convertFunc := b.graphicsConverter.DataToPng
+	if toExt == ".data" {
+		convertFunc = b.graphicsConverter.PngToData
+	}
+
+	var output bytes.Buffer
+	if err := convertFunc(bytes.NewReader(data), &output); err != nil {
+		return "", nil, fmt.Errorf("invalid texture: %w", err)
+	}
+
+	base := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	return strings.TrimSuffix(base, path.Ext(base)) + toExt, output.Bytes(), nil
+}
+
+// targetExtension returns the extension an attachment is converted to, or "" if it isn't supported
+func targetExtension(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".data":
+		return ".png"
+	case ".png":
+		return ".data"
+	}
+	return ""
+}
+
+// textureSize reads the dimensions from the header of a DATA file (when converting to PNG) or a PNG file
+func textureSize(data []byte, toExt string) (width, height int, err error) {
+	if toExt == ".png" {
+		if len(data) < 8 {
+			return 0, 0, errors.New("file is too short to be a DATA texture")
+		}
+		return int(int32(binary.LittleEndian.Uint32(data[0:4]))), int(int32(binary.LittleEndian.Uint32(data[4:8]))), nil
+	}
+
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid PNG: %w", err)
+	}
+	return config.Width, config.Height, nil
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+)
+
+// TestConvert tests that DATA attachments become PNGs and PNG attachments become DATA
+func TestConvert(t *testing.T) {
+	b := NewBot("", converter.NewGraphicsConverter())
+
+	data, err := os.ReadFile(filepath.Join("..", "converter", "testdata", "data", "red.data"))
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+
+	name, output, err := b.convert("sprites/red.data", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+	if name != "red.png" {
+		t.Errorf("Expected red.png, got %s", name)
+	}
+	if _, err := png.Decode(bytes.NewReader(output)); err != nil {
+		t.Fatalf("Output is not a PNG: %v", err)
+	}
+
+	name, output, err = b.convert("red.PNG", bytes.NewReader(output))
+	if err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+	if name != "red.data" || len(output) < 12 {
+		t.Errorf("Unexpected DATA output %s of %d bytes", name, len(output))
+	}
+}
+
+// TestConvertRejectsUnsafeInput tests the limits applied before any pixels are decoded
+func TestConvertRejectsUnsafeInput(t *testing.T) {
+	b := NewBot("", converter.NewGraphicsConverter())
+	b.SetMaxDimension(64)
+	b.SetMaxFileSize(1024)
+
+	// A header claiming a huge texture
+	huge := make([]byte, 12)
+	binary.LittleEndian.PutUint32(huge[0:], 100000)
+	binary.LittleEndian.PutUint32(huge[4:], 100000)
+
+	var largePng bytes.Buffer
+	if err := png.Encode(&largePng, image.NewNRGBA(image.Rect(0, 0, 65, 1))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		wantErr string
+	}{
+		{"huge.data", huge, "outside the supported size"},
+		{"wide.png", largePng.Bytes(), "outside the supported size"},
+		{"big.data", make([]byte, 2048), "larger than"},
+		{"short.data", []byte{1, 2}, "too short"},
+		{"notes.txt", []byte("hello"), "only .data and .png"},
+		{"broken.png", []byte("not a png"), "invalid PNG"},
+	}
+	for _, tt := range tests {
+		_, _, err := b.convert(tt.name, bytes.NewReader(tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Package celestedata registers Celeste's DATA texture format with the image package, so image.Decode
+// and image.DecodeConfig read .data streams once it is imported for its side effect:
+//
+//	import _ "github.com/VictoriqueMoe/celeste-converter-go/pkg/celestedata"
+//
+// DATA files have no magic number, so they are recognized by their header: a little-endian int32 width
+// and height below 16777216 followed by an alpha flag of 0 or 1. Decoded images are *image.NRGBA.
+package celestedata
+
+import (
+	"image"
+	"io"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+)
+
+// Name is the format name image.Decode reports for DATA textures
+const Name = "celeste-data"
+
+func init() {
+	// '?' matches any byte. The zero high bytes rule out PNG, JPEG and GIF, the alpha flag TIFF.
+	image.RegisterFormat(Name, "???\x00???\x00\x00", Decode, DecodeConfig)
+	image.RegisterFormat(Name, "???\x00???\x00\x01", Decode, DecodeConfig)
+}
+
+// Decode reads a DATA texture with the converter's default settings
+func Decode(r io.Reader) (image.Image, error) {
+	return converter.DecodeData(r)
+}
+
+// DecodeConfig reads the size of a DATA texture without decoding its pixels
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	return converter.DecodeDataConfig(r)
+}
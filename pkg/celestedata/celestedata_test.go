@@ -0,0 +1,57 @@
+package celestedata
+
+import (
+	"bytes"
+	"image"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// readTestResource reads a file from the converter's test data
+func readTestResource(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "converter", "testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read test resource %s: %v", name, err)
+	}
+	return data
+}
+
+// TestImageDecode tests that image.Decode recognizes DATA textures next to the standard formats
+func TestImageDecode(t *testing.T) {
+	for _, name := range []string{"multi-color", "transparent", "red"} {
+		dataBytes := readTestResource(t, filepath.Join("data", name+".data"))
+		img, format, err := image.Decode(bytes.NewReader(dataBytes))
+		if err != nil {
+			t.Fatalf("%s: image.Decode failed: %v", name, err)
+		}
+		if format != Name {
+			t.Errorf("%s: expected format %s, got %s", name, Name, format)
+		}
+		expected, err := converter.DecodeData(bytes.NewReader(dataBytes))
+		if err != nil {
+			t.Fatalf("%s: DecodeData failed: %v", name, err)
+		}
+		if err := imagecompare.Check(expected, img, 0); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(dataBytes))
+		if err != nil || format != Name {
+			t.Fatalf("%s: image.DecodeConfig returned %s, %v", name, format, err)
+		}
+		if config.Width != img.Bounds().Dx() || config.Height != img.Bounds().Dy() {
+			t.Errorf("%s: expected %v, got %dx%d", name, img.Bounds().Size(), config.Width, config.Height)
+		}
+	}
+
+	// PNG still decodes as PNG
+	if _, format, err := image.Decode(bytes.NewReader(readTestResource(t, filepath.Join("png", "red.png")))); err != nil || format != "png" {
+		t.Errorf("Expected red.png to decode as png, got %s, %v", format, err)
+	}
+}
@@ -0,0 +1,160 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// AlphaMode selects how the color channels of DATA files with alpha are interpreted
+type AlphaMode int
+
+const (
+	// AlphaPremultiplied treats DATA colors as premultiplied by alpha, as Celeste stores them
+	AlphaPremultiplied AlphaMode = iota
+	// AlphaStraight treats DATA colors as straight (unassociated) alpha, as some modding tools write them
+	AlphaStraight
+	// AlphaAuto decodes files whose colors never exceed their alpha as premultiplied and others as straight.
+	// Encoding writes premultiplied colors.
+	AlphaAuto
+)
+
+// alphaModes maps the names accepted by ParseAlphaMode to alpha modes
+var alphaModes = map[string]AlphaMode{
+	"premultiplied": AlphaPremultiplied,
+	"straight":      AlphaStraight,
+	"auto":          AlphaAuto,
+}
+
+// ParseAlphaMode parses an alpha mode name: premultiplied, straight or auto
+func ParseAlphaMode(name string) (AlphaMode, error) {
+	mode, ok := alphaModes[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown alpha mode '%s', expected premultiplied, straight or auto", name)
+	}
+	return mode, nil
+}
+
+// String returns the name of an alpha mode
+func (m AlphaMode) String() string {
+	for name, mode := range alphaModes {
+		if mode == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("AlphaMode(%d)", int(m))
+}
+
+// SetAlphaMode sets how DATA colors are interpreted; the default is AlphaPremultiplied
+func (g *GraphicsConverter) SetAlphaMode(mode AlphaMode) {
+	g.alphaMode = mode
+}
+
+// AlphaChannel selects whether DATA files written by the converter store alpha
+type AlphaChannel int
+
+const (
+	// AlphaChannelAuto stores alpha only for images with a pixel that isn't fully opaque
+	AlphaChannelAuto AlphaChannel = iota
+	// AlphaChannelForce always stores alpha, so every frame of a sprite set gets the same format
+	AlphaChannelForce
+	// AlphaChannelNever never stores alpha. Translucent pixels keep their premultiplied colors, as if
+	// drawn over black.
+	AlphaChannelNever
+)
+
+// alphaChannels maps the names accepted by ParseAlphaChannel to alpha channel settings
+var alphaChannels = map[string]AlphaChannel{
+	"auto":  AlphaChannelAuto,
+	"force": AlphaChannelForce,
+	"never": AlphaChannelNever,
+}
+
+// ParseAlphaChannel parses an alpha channel setting: auto, force or never
+func ParseAlphaChannel(name string) (AlphaChannel, error) {
+	channel, ok := alphaChannels[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown alpha channel setting '%s', expected auto, force or never", name)
+	}
+	return channel, nil
+}
+
+// String returns the name of an alpha channel setting
+func (c AlphaChannel) String() string {
+	for name, channel := range alphaChannels {
+		if channel == c {
+			return name
+		}
+	}
+	return fmt.Sprintf("AlphaChannel(%d)", int(c))
+}
+
+// SetAlphaChannel sets whether encoded DATA files store alpha; the default is AlphaChannelAuto, which
+// scans the pixels and can give opaque frames of an otherwise transparent sprite set a different format
+func (g *GraphicsConverter) SetAlphaChannel(channel AlphaChannel) {
+	g.alphaChannel = channel
+}
+
+// dataHasAlpha reports whether img is encoded as DATA with alpha
+func (g *GraphicsConverter) dataHasAlpha(img image.Image) bool {
+	switch g.alphaChannel {
+	case AlphaChannelForce:
+		return true
+	case AlphaChannelNever:
+		return false
+	}
+	return hasAlphaChannel(img)
+}
+
+// isPremultiplied reports whether RGBA pixels are valid premultiplied colors, with no channel above alpha
+func isPremultiplied(pix []byte) bool {
+	for p := 0; p < len(pix); p += 4 {
+		a := pix[p+3]
+		if pix[p] > a || pix[p+1] > a || pix[p+2] > a {
+			return false
+		}
+	}
+	return true
+}
+
+// unpremultiplyPix converts premultiplied RGBA pixels to straight alpha in place, rounding to nearest.
+// Channels above alpha, which premultiplied data can't hold, are clamped instead of wrapping around.
+func unpremultiplyPix(pix []byte) {
+	for p := 0; p < len(pix); p += 4 {
+		a := uint32(pix[p+3])
+		if a == 0xff || a == 0 {
+			continue
+		}
+		for c := p; c < p+3; c++ {
+			v := (uint32(pix[c])*0xff + a/2) / a
+			if v > 0xff {
+				v = 0xff
+			}
+			pix[c] = uint8(v)
+		}
+	}
+}
+
+// premultiply scales a straight color channel by alpha, rounding to nearest.
+// It exactly reverses unpremultiplyPix for valid premultiplied colors.
+func premultiply(c, a uint8) uint8 {
+	return uint8((uint32(c)*uint32(a) + 0x7f) / 0xff)
+}
+
+// premultiplyNRGBA converts a straight alpha image to a premultiplied one anchored at the origin
+func premultiplyNRGBA(img *image.NRGBA) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		premultiplyRow(rgba.Pix[y*rgba.Stride:y*rgba.Stride+bounds.Dx()*4], img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):])
+	}
+	return rgba
+}
+
+// premultiplyRow fills dst with the straight alpha pixels at the start of src, premultiplied
+func premultiplyRow(dst, src []byte) {
+	for p := 0; p < len(dst); p += 4 {
+		a := src[p+3]
+		dst[p], dst[p+1], dst[p+2], dst[p+3] = premultiply(src[p], a), premultiply(src[p+1], a), premultiply(src[p+2], a), a
+	}
+}
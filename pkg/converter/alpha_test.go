@@ -0,0 +1,143 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// translucentData is a 2×1 DATA image with alpha: a premultiplied half-transparent red pixel,
+// then a pixel whose red channel exceeds its alpha
+var translucentData = []byte{
+	2, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0,
+	1, 128, 0, 0, 64, // Count, alpha, then BGR
+	1, 128, 0, 0, 200,
+}
+
+// premultipliedData is a 1×1 DATA image with alpha holding the first pixel of translucentData
+var premultipliedData = []byte{
+	1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0,
+	1, 128, 0, 0, 64,
+}
+
+// TestAlphaModes tests how each alpha mode decodes translucent DATA colors
+func TestAlphaModes(t *testing.T) {
+	tests := []struct {
+		mode   AlphaMode
+		first  uint8 // Decoded red of the first pixel
+		second uint8 // Decoded red of the second pixel
+	}{
+		{AlphaPremultiplied, 128, 255}, // The second pixel is clamped
+		{AlphaStraight, 64, 200},
+		{AlphaAuto, 64, 200}, // Not valid premultiplied data, so straight
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			graphicsConverter := NewGraphicsConverter()
+			graphicsConverter.SetAlphaMode(tt.mode)
+			img, err := graphicsConverter.decodeData(bytes.NewReader(translucentData))
+			if err != nil {
+				t.Fatalf("decodeData failed: %v", err)
+			}
+			if c := img.NRGBAAt(0, 0); c != (color.NRGBA{R: tt.first, A: 128}) {
+				t.Errorf("Expected red %d, got %v", tt.first, c)
+			}
+			if c := img.NRGBAAt(1, 0); c != (color.NRGBA{R: tt.second, A: 128}) {
+				t.Errorf("Expected red %d, got %v", tt.second, c)
+			}
+		})
+	}
+
+	// Valid premultiplied data is detected by auto mode
+	graphicsConverter := NewGraphicsConverter()
+	graphicsConverter.SetAlphaMode(AlphaAuto)
+	img, err := graphicsConverter.decodeData(bytes.NewReader(premultipliedData))
+	if err != nil {
+		t.Fatalf("decodeData failed: %v", err)
+	}
+	if c := img.NRGBAAt(0, 0); c.R != 128 {
+		t.Errorf("Expected auto mode to unpremultiply, got %v", c)
+	}
+
+	if _, err := ParseAlphaMode("linear"); err == nil {
+		t.Error("Expected error for unknown alpha mode, got nil")
+	}
+}
+
+// TestAlphaRoundTrip tests that DATA survives decoding and encoding unchanged in each mode
+func TestAlphaRoundTrip(t *testing.T) {
+	for _, mode := range []AlphaMode{AlphaPremultiplied, AlphaStraight, AlphaAuto} {
+		graphicsConverter := NewGraphicsConverter()
+		graphicsConverter.SetAlphaMode(mode)
+
+		var png, data bytes.Buffer
+		if err := graphicsConverter.DataToPng(bytes.NewReader(premultipliedData), &png); err != nil {
+			t.Fatalf("DataToPng failed: %v", err)
+		}
+		if err := graphicsConverter.PngToData(&png, &data); err != nil {
+			t.Fatalf("PngToData failed: %v", err)
+		}
+		if !bytes.Equal(data.Bytes(), premultipliedData) {
+			t.Errorf("%s: expected %v after round trip, got %v", mode, premultipliedData, data.Bytes())
+		}
+	}
+}
+
+// TestUnpremultiplyExact tests that every valid premultiplied color survives unpremultiplying and premultiplying
+func TestUnpremultiplyExact(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for c := 0; c <= a; c++ {
+			pix := []byte{uint8(c), 0, 0, uint8(a)}
+			unpremultiplyPix(pix)
+			if got := premultiply(pix[0], uint8(a)); got != uint8(c) {
+				t.Fatalf("Color %d at alpha %d became %d", c, a, got)
+			}
+		}
+	}
+}
+
+// TestAlphaChannel tests pinning whether encoded DATA files store alpha
+func TestAlphaChannel(t *testing.T) {
+	opaque := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	opaque.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	opaque.SetNRGBA(1, 0, color.NRGBA{0, 0, 255, 255})
+	translucent := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	translucent.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 128})
+
+	for _, test := range []struct {
+		channel   AlphaChannel
+		img       image.Image
+		wantAlpha bool
+		want      color.NRGBA // Decoded first pixel
+	}{
+		{AlphaChannelAuto, opaque, false, color.NRGBA{255, 0, 0, 255}},
+		{AlphaChannelAuto, translucent, true, color.NRGBA{255, 0, 0, 128}},
+		{AlphaChannelForce, opaque, true, color.NRGBA{255, 0, 0, 255}},
+		{AlphaChannelNever, translucent, false, color.NRGBA{128, 0, 0, 255}},
+	} {
+		gc := NewGraphicsConverter(WithAlphaChannel(test.channel))
+		var data bytes.Buffer
+		if err := gc.EncodeData(&data, test.img); err != nil {
+			t.Fatalf("%s: EncodeData failed: %v", test.channel, err)
+		}
+		header, err := gc.ReadDataHeader(bytes.NewReader(data.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: ReadDataHeader failed: %v", test.channel, err)
+		}
+		if header.HasAlpha() != test.wantAlpha {
+			t.Errorf("%s: expected alpha %v, got %v", test.channel, test.wantAlpha, header.HasAlpha())
+		}
+		img, err := gc.DecodeData(bytes.NewReader(data.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: DecodeData failed: %v", test.channel, err)
+		}
+		if got := img.(*image.NRGBA).NRGBAAt(0, 0); got != test.want {
+			t.Errorf("%s: expected %v, got %v", test.channel, test.want, got)
+		}
+	}
+
+	if _, err := ParseAlphaChannel("sometimes"); err == nil {
+		t.Error("Expected an unknown alpha channel setting to be rejected")
+	}
+}
@@ -0,0 +1,295 @@
+package converter
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnimationFormat selects the file format of animation previews
+type AnimationFormat int
+
+const (
+	// AnimationGif writes animated GIFs, which everything can show but which only have 256 colors
+	// per frame and no translucency
+	AnimationGif AnimationFormat = iota
+	// AnimationApng writes animated PNGs, keeping every color and alpha value
+	AnimationApng
+	// AnimationAseprite writes Aseprite sprites with a frame per texture and a tag named after the
+	// animation, for editing rather than previewing
+	AnimationAseprite
+)
+
+// animationFormats maps the names accepted by ParseAnimationFormat to formats
+var animationFormats = map[string]AnimationFormat{
+	"gif":      AnimationGif,
+	"apng":     AnimationApng,
+	"aseprite": AnimationAseprite,
+}
+
+// ParseAnimationFormat parses an animation format name: gif, apng or aseprite
+func ParseAnimationFormat(name string) (AnimationFormat, error) {
+	format, ok := animationFormats[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown animation format '%s', expected gif, apng or aseprite", name)
+	}
+	return format, nil
+}
+
+// String returns the name of an animation format
+func (f AnimationFormat) String() string {
+	for name, format := range animationFormats {
+		if format == f {
+			return name
+		}
+	}
+	return fmt.Sprintf("AnimationFormat(%d)", int(f))
+}
+
+// Extension returns the file extension of the format, including the dot
+func (f AnimationFormat) Extension() string {
+	return "." + f.String()
+}
+
+// Animation is a sequence of numbered frames sharing a base name, like Celeste's idle00.data, idle01.data, ...
+type Animation struct {
+	Name   string   // Slash-separated base path, e.g. "characters/player/idle"
+	Frames []string // Paths of the frames relative to the source directory, in frame order
+}
+
+// frameName splits a frame's file name without extension into its base name and frame number
+var frameName = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// FindAnimations groups the textures below dir into animations by base name. Textures that aren't
+// numbered, and base names with a single frame, are left out.
+func FindAnimations(dir string) ([]Animation, error) {
+	type frame struct {
+		relPath string
+		number  int
+	}
+	groups := make(map[string][]frame)
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := textureExtension(filePath)
+		if d.IsDir() || ext == "" {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		slashPath := filepath.ToSlash(relPath)
+		match := frameName.FindStringSubmatch(path.Base(slashPath[:len(slashPath)-len(ext)]))
+		if match == nil {
+			return nil
+		}
+		number, err := strconv.Atoi(match[2])
+		if err != nil {
+			return nil // Too many digits to be a frame number
+		}
+		name := path.Join(path.Dir(slashPath), match[1])
+		groups[name] = append(groups[name], frame{relPath, number})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	var animations []Animation
+	for name, frames := range groups {
+		if len(frames) < 2 {
+			continue
+		}
+		slices.SortFunc(frames, func(a, b frame) int {
+			return cmp.Or(cmp.Compare(a.number, b.number), cmp.Compare(a.relPath, b.relPath))
+		})
+		animation := Animation{Name: name}
+		for _, f := range frames {
+			animation.Frames = append(animation.Frames, f.relPath)
+		}
+		animations = append(animations, animation)
+	}
+	slices.SortFunc(animations, func(a, b Animation) int { return cmp.Compare(a.Name, b.Name) })
+	return animations, nil
+}
+
+// AnimationBuilder writes previews of the animations of texture trees
+type AnimationBuilder struct {
+	graphicsConverter *GraphicsConverter
+	log               Logger
+	format            AnimationFormat
+	frameDelay        time.Duration
+	maxWorkers        int
+}
+
+// NewAnimationBuilder creates a new AnimationBuilder instance
+func NewAnimationBuilder(graphicsConverter *GraphicsConverter) *AnimationBuilder {
+	return &AnimationBuilder{
+		graphicsConverter: graphicsConverter,
+		log:               DefaultLogger(),
+		frameDelay:        100 * time.Millisecond,
+		maxWorkers:        runtime.NumCPU(),
+	}
+}
+
+// SetFormat sets the format previews are written in, AnimationGif by default
+func (b *AnimationBuilder) SetFormat(format AnimationFormat) {
+	b.format = format
+}
+
+// SetFrameDelay sets how long each frame is shown, 100ms by default. GIFs round it to hundredths of
+// a second, APNGs and Aseprite sprites to milliseconds.
+func (b *AnimationBuilder) SetFrameDelay(delay time.Duration) {
+	if delay > 0 {
+		b.frameDelay = delay
+	}
+}
+
+// SetMaxWorkers allows overriding the number of animations written in parallel
+func (b *AnimationBuilder) SetMaxWorkers(workers int) {
+	if workers > 0 {
+		b.maxWorkers = workers
+	}
+}
+
+// Build writes a looping preview of every animation below fromDir to toDir, at the animation's
+// path with the format's extension, e.g. characters/player/idle.gif. Animations with a frame that
+// fails to decode are logged and left out; the others are returned.
+func (b *AnimationBuilder) Build(fromDir, toDir string) ([]Animation, error) {
+	animations, err := FindAnimations(fromDir)
+	if err != nil {
+		return nil, err
+	}
+	b.log.Infof("%d animations to write", len(animations))
+
+	written := make([]bool, len(animations))
+	indexes := make(chan int, len(animations))
+	for i := range animations {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < b.maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				outputPath := filepath.Join(toDir, filepath.FromSlash(animations[i].Name)+b.format.Extension())
+				if err := b.writeAnimation(fromDir, animations[i], outputPath); err != nil {
+					b.log.Warnf("Skipping %s: %v", animations[i].Name, err)
+					continue
+				}
+				written[i] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	var built []Animation
+	for i, animation := range animations {
+		if written[i] {
+			built = append(built, animation)
+		}
+	}
+	return built, nil
+}
+
+// writeAnimation decodes the frames of an animation and writes its preview to outputPath, removing
+// it again on failure
+func (b *AnimationBuilder) writeAnimation(fromDir string, animation Animation, outputPath string) error {
+	frames := make([]image.Image, len(animation.Frames))
+	for i, frame := range animation.Frames {
+		img, err := b.graphicsConverter.decodeFile(filepath.Join(fromDir, frame))
+		if err != nil {
+			return fmt.Errorf("failed to decode frame '%s': %w", frame, err)
+		}
+		frames[i] = img
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file '%s': %w", outputPath, err)
+	}
+	switch b.format {
+	case AnimationApng:
+		err = encodeApng(file, frames, b.frameDelay)
+	case AnimationAseprite:
+		err = encodeAseprite(file, frames, b.frameDelay, path.Base(animation.Name))
+	default:
+		err = encodeGif(file, frames, b.frameDelay)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outputPath)
+	}
+	return err
+}
+
+// animationCanvas returns the size of the canvas fitting every frame, which are placed at its top left
+func animationCanvas(frames []image.Image) image.Point {
+	var canvas image.Point
+	for _, frame := range frames {
+		size := frame.Bounds().Size()
+		canvas.X, canvas.Y = max(canvas.X, size.X), max(canvas.Y, size.Y)
+	}
+	return canvas
+}
+
+// encodeGif writes frames as a looping animated GIF. Pixels are fully transparent below half alpha
+// and opaque from it, and every frame gets its own palette, reduced by median cut if needed.
+func encodeGif(output io.Writer, frames []image.Image, delay time.Duration) error {
+	canvas := animationCanvas(frames)
+	anim := &gif.GIF{Config: image.Config{Width: canvas.X, Height: canvas.Y}}
+	for _, frame := range frames {
+		nrgba := thresholdAlpha(toNRGBA(frame))
+		paletted := quantize(nrgba, colorHistogram(nrgba, false))
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, max(int((delay+5*time.Millisecond)/(10*time.Millisecond)), 1))
+		// Cleared after being shown, so transparent pixels don't show the previous frame
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+	if err := gif.EncodeAll(output, anim); err != nil {
+		return fmt.Errorf("failed to encode GIF: %w", err)
+	}
+	return nil
+}
+
+// thresholdAlpha returns a copy of img with every pixel fully transparent or opaque, as GIFs have no
+// partial transparency
+func thresholdAlpha(img *image.NRGBA) *image.NRGBA {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		row := out.Pix[y*out.Stride : (y+1)*out.Stride]
+		copy(row, img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):])
+		for p := 0; p < len(row); p += 4 {
+			if row[p+3] < 128 {
+				row[p], row[p+1], row[p+2], row[p+3] = 0, 0, 0, 0
+			} else {
+				row[p+3] = 0xff
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeAnimationFrames copies test textures into dir under the given frame names
+func writeAnimationFrames(t *testing.T, dir string, frames map[string]string) {
+	for name, resource := range frames {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		copyFile(t, filepath.Join("testdata", resource), filepath.Join(dir, name))
+	}
+}
+
+// TestFindAnimations tests grouping numbered frames by directory and base name
+func TestFindAnimations(t *testing.T) {
+	dir := t.TempDir()
+	writeAnimationFrames(t, dir, map[string]string{
+		"player/idle00.data":  "data/red.data",
+		"player/idle01.data":  "data/green.data",
+		"player/idle10.png":   "png/blue.png",
+		"player/idle2.data":   "data/white.data",
+		"player/jump00.data":  "data/red.data", // A single frame isn't an animation
+		"player/logo.data":    "data/red.data", // Neither is an unnumbered texture
+		"badeline/idle00.png": "png/red.png",
+		"badeline/idle01.png": "png/red.png",
+	})
+
+	animations, err := FindAnimations(dir)
+	if err != nil {
+		t.Fatalf("FindAnimations failed: %v", err)
+	}
+	if len(animations) != 2 || animations[0].Name != "badeline/idle" || animations[1].Name != "player/idle" {
+		t.Fatalf("Unexpected animations: %+v", animations)
+	}
+	want := []string{"idle00.data", "idle01.data", "idle2.data", "idle10.png"}
+	for i := range want {
+		want[i] = filepath.Join("player", want[i])
+	}
+	if !slices.Equal(animations[1].Frames, want) {
+		t.Errorf("Expected frames in numeric order %v, got %v", want, animations[1].Frames)
+	}
+}
+
+// TestAnimationBuild tests writing GIF and APNG previews, leaving out animations with broken frames
+func TestAnimationBuild(t *testing.T) {
+	fromDir := t.TempDir()
+	writeAnimationFrames(t, fromDir, map[string]string{
+		"player/idle00.data": "data/red.data",
+		"player/idle02.data": "data/blue.data",
+		"broken/run00.data":  "data/red.data",
+	})
+	transparent := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	if err := NewGraphicsConverter().encodeFile(transparent, filepath.Join(fromDir, "player", "idle01.png")); err != nil {
+		t.Fatalf("Failed to write transparent frame: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(fromDir, "broken", "run01.data"), []byte{1, 2}, 0644); err != nil {
+		t.Fatalf("Failed to write broken frame: %v", err)
+	}
+
+	toDir := t.TempDir()
+	builder := NewAnimationBuilder(NewGraphicsConverter())
+	builder.SetFrameDelay(80 * time.Millisecond)
+	built, err := builder.Build(fromDir, toDir)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(built) != 1 || built[0].Name != "player/idle" {
+		t.Fatalf("Expected only player/idle to be written, got %+v", built)
+	}
+	if fileExists(filepath.Join(toDir, "broken", "run.gif")) {
+		t.Error("Expected no preview for an animation with a broken frame")
+	}
+
+	file, err := os.Open(filepath.Join(toDir, "player", "idle.gif"))
+	if err != nil {
+		t.Fatalf("Failed to open GIF: %v", err)
+	}
+	defer file.Close()
+	anim, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatalf("Failed to decode GIF: %v", err)
+	}
+	if len(anim.Image) != 3 || anim.Delay[0] != 8 || anim.Disposal[0] != gif.DisposalBackground {
+		t.Errorf("Expected 3 frames of 8/100s cleared after showing, got %d frames, delays %v, disposal %v",
+			len(anim.Image), anim.Delay, anim.Disposal)
+	}
+	if _, _, _, a := anim.Image[1].At(0, 0).RGBA(); a != 0 {
+		t.Error("Expected the transparent frame to stay transparent")
+	}
+	if got := color.NRGBAModel.Convert(anim.Image[0].At(0, 0)); got != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected a red first frame, got %v", got)
+	}
+
+	builder.SetFormat(AnimationApng)
+	if _, err := builder.Build(fromDir, toDir); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if !fileExists(filepath.Join(toDir, "player", "idle.apng")) {
+		t.Error("Expected an APNG preview")
+	}
+}
+
+// TestParseAnimationFormat tests parsing animation format names
+func TestParseAnimationFormat(t *testing.T) {
+	if format, err := ParseAnimationFormat("APNG"); err != nil || format != AnimationApng || format.Extension() != ".apng" {
+		t.Errorf("Expected AnimationApng, got %v, %v", format, err)
+	}
+	if _, err := ParseAnimationFormat("webm"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,135 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"io"
+	"time"
+)
+
+// APNG frame control values: frames are cleared to transparent after being shown, and replace the
+// canvas rather than being blended over it
+const (
+	apngDisposeBackground = 1
+	apngBlendSource       = 0
+)
+
+// apngWriter writes the chunks of an animated PNG, numbering the frame chunks as it goes
+type apngWriter struct {
+	w        *bufio.Writer
+	sequence uint32
+	err      error
+}
+
+// chunk writes a chunk with its length and CRC
+func (a *apngWriter) chunk(name string, data []byte) {
+	if a.err != nil {
+		return
+	}
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], name)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	var footer [4]byte
+	binary.BigEndian.PutUint32(footer[:], crc.Sum32())
+	for _, part := range [][]byte{header[:], data, footer[:]} {
+		if _, err := a.w.Write(part); err != nil {
+			a.err = err
+			return
+		}
+	}
+}
+
+// next returns the next sequence number of fcTL and fdAT chunks
+func (a *apngWriter) next() uint32 {
+	a.sequence++
+	return a.sequence - 1
+}
+
+// encodeApng writes frames as a looping animated PNG with 8-bit RGBA pixels. Frames smaller than the
+// largest are placed at the top left of the canvas.
+func encodeApng(output io.Writer, frames []image.Image, delay time.Duration) error {
+	canvas := animationCanvas(frames)
+	a := &apngWriter{w: bufio.NewWriterSize(output, dataBufferSize)}
+	if _, err := a.w.Write(pngSignature); err != nil {
+		return err
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(canvas.X))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(canvas.Y))
+	ihdr[8], ihdr[9] = 8, 6 // 8 bits per channel, RGBA
+	a.chunk("IHDR", ihdr)
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+	// A play count of 0 loops forever
+	a.chunk("acTL", actl)
+
+	delayMs := max(uint16(min(delay.Milliseconds(), 0xffff)), 1)
+	for i, frame := range frames {
+		size := frame.Bounds().Size()
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], a.next())
+		binary.BigEndian.PutUint32(fctl[4:], uint32(size.X))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(size.Y))
+		// Offsets of 0 place the frame at the top left
+		binary.BigEndian.PutUint16(fctl[20:], delayMs)
+		binary.BigEndian.PutUint16(fctl[22:], 1000)
+		fctl[24], fctl[25] = apngDisposeBackground, apngBlendSource
+		a.chunk("fcTL", fctl)
+
+		data, err := apngFrameData(toNRGBA(frame))
+		if err != nil {
+			return fmt.Errorf("failed to compress frame %d: %w", i, err)
+		}
+		if i == 0 {
+			// The first frame doubles as the still image shown by decoders without APNG support
+			a.chunk("IDAT", data)
+		} else {
+			fdat := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, a.next())
+			a.chunk("fdAT", append(fdat, data...))
+		}
+	}
+	a.chunk("IEND", nil)
+	if a.err != nil {
+		return a.err
+	}
+	return a.w.Flush()
+}
+
+// apngFrameData compresses the scanlines of a frame, each with the Sub filter, which suits the long
+// runs of equal pixels sprites have
+func apngFrameData(img *image.NRGBA) ([]byte, error) {
+	bounds := img.Bounds()
+	rowSize := bounds.Dx() * 4
+	var compressed bytes.Buffer
+	z := zlib.NewWriter(&compressed)
+	line := make([]byte, 1+rowSize)
+	line[0] = 1 // Sub: each byte minus the same channel of the pixel to its left
+	for y := 0; y < bounds.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+rowSize]
+		for i := range row {
+			if i < 4 {
+				line[1+i] = row[i]
+			} else {
+				line[1+i] = row[i] - row[i-4]
+			}
+		}
+		if _, err := z.Write(line); err != nil {
+			return nil, err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
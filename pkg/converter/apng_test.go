@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// readPngChunks splits a PNG file into its chunks
+func readPngChunks(t *testing.T, data []byte) []pngChunk {
+	if !bytes.HasPrefix(data, pngSignature) {
+		t.Fatal("Missing PNG signature")
+	}
+	var chunks []pngChunk
+	for rest := data[len(pngSignature):]; len(rest) > 0; {
+		length := binary.BigEndian.Uint32(rest)
+		chunks = append(chunks, pngChunk{string(rest[4:8]), rest[8 : 8+length]})
+		rest = rest[12+length:]
+	}
+	return chunks
+}
+
+// stillPng builds a PNG file holding a single APNG frame of the given size
+func stillPng(size image.Point, frameData []byte) []byte {
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(size.X))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(size.Y))
+	ihdr[8], ihdr[9] = 8, 6
+	for _, chunk := range []pngChunk{{"IHDR", ihdr}, {"IDAT", frameData}, {"IEND", nil}} {
+		payload := append([]byte(chunk.name), chunk.data...)
+		binary.Write(&out, binary.BigEndian, uint32(len(chunk.data)))
+		out.Write(payload)
+		binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(payload))
+	}
+	return out.Bytes()
+}
+
+// TestEncodeApng tests the chunk layout of animated PNGs and that every frame decodes to its pixels
+func TestEncodeApng(t *testing.T) {
+	first := numberedImage(4, 3)
+	second := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	second.SetNRGBA(1, 1, color.NRGBA{10, 20, 30, 40})
+
+	var output bytes.Buffer
+	if err := encodeApng(&output, []image.Image{first, second}, 80*time.Millisecond); err != nil {
+		t.Fatalf("encodeApng failed: %v", err)
+	}
+
+	// Decoders without APNG support show the first frame
+	still, err := png.Decode(bytes.NewReader(output.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to decode the APNG as a PNG: %v", err)
+	}
+	if err := imagecompare.Check(first, still, 0); err != nil {
+		t.Errorf("Expected the first frame as the still image: %v", err)
+	}
+
+	var names []string
+	chunks := readPngChunks(t, output.Bytes())
+	for _, chunk := range chunks {
+		names = append(names, chunk.name)
+	}
+	if got, want := names, []string{"IHDR", "acTL", "fcTL", "IDAT", "fcTL", "fdAT", "IEND"}; !slices.Equal(got, want) {
+		t.Fatalf("Expected chunks %v, got %v", want, got)
+	}
+	if frames := binary.BigEndian.Uint32(chunks[1].data); frames != 2 {
+		t.Errorf("Expected 2 frames in acTL, got %d", frames)
+	}
+
+	fctl := chunks[4].data
+	if sequence := binary.BigEndian.Uint32(fctl); sequence != 1 {
+		t.Errorf("Expected the second fcTL to be chunk 1, got %d", sequence)
+	}
+	if width, height := binary.BigEndian.Uint32(fctl[4:]), binary.BigEndian.Uint32(fctl[8:]); width != 2 || height != 2 {
+		t.Errorf("Expected a 2x2 second frame, got %dx%d", width, height)
+	}
+	if num, den := binary.BigEndian.Uint16(fctl[20:]), binary.BigEndian.Uint16(fctl[22:]); num != 80 || den != 1000 {
+		t.Errorf("Expected a delay of 80/1000, got %d/%d", num, den)
+	}
+
+	fdat := chunks[5].data
+	if sequence := binary.BigEndian.Uint32(fdat); sequence != 2 {
+		t.Errorf("Expected fdAT to be chunk 2, got %d", sequence)
+	}
+	decoded, err := png.Decode(bytes.NewReader(stillPng(image.Pt(2, 2), fdat[4:])))
+	if err != nil {
+		t.Fatalf("Failed to decode the second frame: %v", err)
+	}
+	if err := imagecompare.Check(second, decoded, 0); err != nil {
+		t.Errorf("Second frame differs: %v", err)
+	}
+}
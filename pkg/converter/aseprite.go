@@ -0,0 +1,183 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"time"
+)
+
+// Aseprite file structure, see https://github.com/aseprite/aseprite/blob/main/docs/ase-file-specs.md
+const (
+	asepriteMagic      = 0xa5e0
+	asepriteFrameMagic = 0xf1fa
+
+	asepriteChunkLayer = 0x2004
+	asepriteChunkCel   = 0x2005
+	asepriteChunkTags  = 0x2018
+
+	asepriteLayerVisible  = 1
+	asepriteLayerEditable = 2
+	asepriteCelCompressed = 2
+)
+
+// asepriteHeader is the 128-byte file header
+type asepriteHeader struct {
+	FileSize     uint32
+	Magic        uint16
+	Frames       uint16
+	Width        uint16
+	Height       uint16
+	ColorDepth   uint16 // Bits per pixel, 32 for RGBA
+	Flags        uint32 // 1 when layer opacity is valid
+	Speed        uint16 // Deprecated frame duration in milliseconds
+	_            [2]uint32
+	Transparent  uint8 // Transparent palette index, for indexed sprites only
+	_            [3]byte
+	Colors       uint16
+	PixelWidth   uint8
+	PixelHeight  uint8
+	GridX, GridY int16
+	GridWidth    uint16
+	GridHeight   uint16
+	_            [84]byte
+}
+
+// asepriteFrameHeader starts every frame
+type asepriteFrameHeader struct {
+	Size      uint32 // Including this header
+	Magic     uint16
+	OldChunks uint16 // 0xffff if there are more, in which case Chunks holds the count
+	Duration  uint16 // Milliseconds
+	_         [2]byte
+	Chunks    uint32
+}
+
+// asepriteWriter collects the chunks of a frame, little-endian as Aseprite stores every value
+type asepriteWriter struct {
+	frame  bytes.Buffer
+	chunks int
+	err    error
+}
+
+// chunk appends a chunk of the given type holding the values of fields in order; []byte values and
+// strings are written as they are, and strings prefixed by their length
+func (a *asepriteWriter) chunk(chunkType uint16, fields ...any) {
+	var data bytes.Buffer
+	for _, field := range fields {
+		switch v := field.(type) {
+		case string:
+			binary.Write(&data, binary.LittleEndian, uint16(len(v)))
+			data.WriteString(v)
+		case []byte:
+			data.Write(v)
+		default:
+			if err := binary.Write(&data, binary.LittleEndian, v); err != nil && a.err == nil {
+				a.err = err
+			}
+		}
+	}
+	binary.Write(&a.frame, binary.LittleEndian, uint32(6+data.Len()))
+	binary.Write(&a.frame, binary.LittleEndian, chunkType)
+	a.frame.Write(data.Bytes())
+	a.chunks++
+}
+
+// endFrame writes the collected chunks to output as a frame shown for duration milliseconds
+func (a *asepriteWriter) endFrame(output *bytes.Buffer, duration uint16) {
+	header := asepriteFrameHeader{
+		Size:     uint32(16 + a.frame.Len()),
+		Magic:    asepriteFrameMagic,
+		Duration: duration,
+		Chunks:   uint32(a.chunks),
+	}
+	header.OldChunks = uint16(min(a.chunks, 0xffff))
+	binary.Write(output, binary.LittleEndian, &header)
+	output.Write(a.frame.Bytes())
+	a.frame.Reset()
+	a.chunks = 0
+}
+
+// encodeAseprite writes frames as an RGBA Aseprite sprite with a single layer and a tag named tag
+// spanning every frame, each shown for delay. Frames smaller than the largest are placed at the top
+// left of the canvas.
+func encodeAseprite(output io.Writer, frames []image.Image, delay time.Duration, tag string) error {
+	canvas := animationCanvas(frames)
+	if canvas.X > 0xffff || canvas.Y > 0xffff {
+		return fmt.Errorf("%dx%d is too large for Aseprite", canvas.X, canvas.Y)
+	}
+	duration := uint16(max(1, min(int(delay.Round(time.Millisecond)/time.Millisecond), 0xffff)))
+
+	var body bytes.Buffer
+	a := &asepriteWriter{}
+	for i, frame := range frames {
+		if i == 0 {
+			a.chunk(asepriteChunkLayer,
+				uint16(asepriteLayerVisible|asepriteLayerEditable),
+				uint16(0),   // Normal layer
+				uint16(0),   // Child level
+				[2]uint16{}, // Ignored default size
+				uint16(0),   // Normal blend mode
+				uint8(255),  // Opacity
+				[3]byte{},   // Reserved
+				"Layer 1")
+			a.chunk(asepriteChunkTags,
+				uint16(1),
+				[8]byte{},
+				[2]uint16{0, uint16(len(frames) - 1)},
+				uint8(0),  // Forward
+				uint16(0), // Repeat forever
+				[6]byte{},
+				[3]byte{}, // Deprecated color
+				uint8(0),
+				tag)
+		}
+
+		nrgba := toNRGBA(frame)
+		width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+		var pixels bytes.Buffer
+		zw := zlib.NewWriter(&pixels)
+		for y := 0; y < height; y++ {
+			zw.Write(nrgba.Pix[y*nrgba.Stride : y*nrgba.Stride+width*4])
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		a.chunk(asepriteChunkCel,
+			uint16(0),  // Layer index
+			[2]int16{}, // Position
+			uint8(255), // Opacity
+			uint16(asepriteCelCompressed),
+			int16(0), // Z-index
+			[5]byte{},
+			[2]uint16{uint16(width), uint16(height)},
+			pixels.Bytes())
+		a.endFrame(&body, duration)
+	}
+	if a.err != nil {
+		return a.err
+	}
+
+	header := asepriteHeader{
+		FileSize:    uint32(128 + body.Len()),
+		Magic:       asepriteMagic,
+		Frames:      uint16(len(frames)),
+		Width:       uint16(canvas.X),
+		Height:      uint16(canvas.Y),
+		ColorDepth:  32,
+		Flags:       1,
+		Speed:       duration,
+		PixelWidth:  1,
+		PixelHeight: 1,
+		GridWidth:   16,
+		GridHeight:  16,
+	}
+	if err := binary.Write(output, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	_, err := output.Write(body.Bytes())
+	return err
+}
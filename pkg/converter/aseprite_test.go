@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// asepriteChunk is a chunk read back from an Aseprite file
+type asepriteChunk struct {
+	chunkType uint16
+	data      []byte
+}
+
+// readAseprite splits an Aseprite file into its header and the chunks and durations of its frames
+func readAseprite(t *testing.T, data []byte) (asepriteHeader, [][]asepriteChunk, []uint16) {
+	var header asepriteHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &header); err != nil || header.Magic != asepriteMagic {
+		t.Fatalf("Missing Aseprite header: %v", err)
+	}
+	if int(header.FileSize) != len(data) {
+		t.Fatalf("Header gives a file size of %d, got %d bytes", header.FileSize, len(data))
+	}
+
+	var frames [][]asepriteChunk
+	var durations []uint16
+	rest := data[128:]
+	for i := 0; i < int(header.Frames); i++ {
+		var frameHeader asepriteFrameHeader
+		binary.Read(bytes.NewReader(rest), binary.LittleEndian, &frameHeader)
+		if frameHeader.Magic != asepriteFrameMagic || uint32(frameHeader.OldChunks) != frameHeader.Chunks {
+			t.Fatalf("Invalid header of frame %d: %+v", i, frameHeader)
+		}
+		var chunks []asepriteChunk
+		for chunkData := rest[16:frameHeader.Size]; len(chunkData) > 0; {
+			size := binary.LittleEndian.Uint32(chunkData)
+			chunks = append(chunks, asepriteChunk{binary.LittleEndian.Uint16(chunkData[4:]), chunkData[6:size]})
+			chunkData = chunkData[size:]
+		}
+		if len(chunks) != int(frameHeader.Chunks) {
+			t.Fatalf("Frame %d declares %d chunks, got %d", i, frameHeader.Chunks, len(chunks))
+		}
+		frames = append(frames, chunks)
+		durations = append(durations, frameHeader.Duration)
+		rest = rest[frameHeader.Size:]
+	}
+	if len(rest) != 0 {
+		t.Errorf("Expected nothing after the last frame, got %d bytes", len(rest))
+	}
+	return header, frames, durations
+}
+
+// decodeAsepriteCel decodes the pixels of a compressed cel chunk
+func decodeAsepriteCel(t *testing.T, chunk asepriteChunk) *image.NRGBA {
+	if chunk.chunkType != asepriteChunkCel || binary.LittleEndian.Uint16(chunk.data[7:]) != asepriteCelCompressed {
+		t.Fatalf("Expected a compressed cel, got chunk %#x", chunk.chunkType)
+	}
+	width, height := int(binary.LittleEndian.Uint16(chunk.data[16:])), int(binary.LittleEndian.Uint16(chunk.data[18:]))
+	zr, err := zlib.NewReader(bytes.NewReader(chunk.data[20:]))
+	if err != nil {
+		t.Fatalf("Invalid cel pixels: %v", err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if _, err := io.ReadFull(zr, img.Pix); err != nil {
+		t.Fatalf("Failed to read cel pixels: %v", err)
+	}
+	return img
+}
+
+// TestEncodeAseprite tests the layer, tag and cels of an Aseprite sprite
+func TestEncodeAseprite(t *testing.T) {
+	first := numberedImage(4, 3)
+	second := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	second.SetNRGBA(1, 1, color.NRGBA{10, 20, 30, 40})
+
+	var output bytes.Buffer
+	if err := encodeAseprite(&output, []image.Image{first, second}, 80*time.Millisecond, "idle"); err != nil {
+		t.Fatalf("encodeAseprite failed: %v", err)
+	}
+	header, frames, durations := readAseprite(t, output.Bytes())
+	if header.Width != 4 || header.Height != 3 || header.ColorDepth != 32 || len(frames) != 2 {
+		t.Fatalf("Expected two frames on a 4x3 RGBA canvas, got %+v", header)
+	}
+	if durations[0] != 80 || durations[1] != 80 {
+		t.Errorf("Expected frames of 80ms, got %v", durations)
+	}
+
+	if len(frames[0]) != 3 || frames[0][0].chunkType != asepriteChunkLayer || frames[0][1].chunkType != asepriteChunkTags {
+		t.Fatalf("Expected a layer, tags and a cel in the first frame, got %+v", frames[0])
+	}
+	tags := frames[0][1].data
+	if count, from, to := binary.LittleEndian.Uint16(tags), binary.LittleEndian.Uint16(tags[10:]), binary.LittleEndian.Uint16(tags[12:]); count != 1 || from != 0 || to != 1 {
+		t.Errorf("Expected a single tag over frames 0 to 1, got %d tags from %d to %d", count, from, to)
+	}
+	if nameLength := binary.LittleEndian.Uint16(tags[27:]); string(tags[29:29+nameLength]) != "idle" {
+		t.Errorf("Expected the tag to be named idle, got %q", tags[29:29+nameLength])
+	}
+
+	if err := imagecompare.Check(first, decodeAsepriteCel(t, frames[0][2]), 0); err != nil {
+		t.Errorf("First cel differs: %v", err)
+	}
+	if len(frames[1]) != 1 {
+		t.Fatalf("Expected only a cel in the second frame, got %+v", frames[1])
+	}
+	if err := imagecompare.Check(second, decodeAsepriteCel(t, frames[1][0]), 0); err != nil {
+		t.Errorf("Second cel differs: %v", err)
+	}
+}
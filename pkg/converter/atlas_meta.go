@@ -0,0 +1,179 @@
+package converter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// AtlasMeta describes a Celeste packed atlas (.meta file) and the pages it references
+type AtlasMeta struct {
+	Version int32
+	Args    string
+	Hash    int32
+	Pages   []AtlasPage
+}
+
+// AtlasPage is a single texture page of an atlas, stored as <Name>.data next to the .meta file
+type AtlasPage struct {
+	Name    string
+	Sprites []AtlasSprite
+}
+
+// AtlasSprite is a sub-texture within an atlas page
+type AtlasSprite struct {
+	Key        string // Forward-slash separated sprite path without extension
+	X, Y       int16  // Position within the page
+	Width      int16  // Width of the packed (trimmed) region
+	Height     int16  // Height of the packed (trimmed) region
+	OffsetX    int16  // Trim offset, stored negated by Celeste
+	OffsetY    int16
+	RealWidth  int16 // Width of the original untrimmed sprite
+	RealHeight int16
+}
+
+// ReadAtlasMeta parses a Celeste .meta file in the packer format
+func ReadAtlasMeta(input io.Reader) (*AtlasMeta, error) {
+	r := bufio.NewReader(input)
+	meta := &AtlasMeta{}
+
+	if err := binary.Read(r, binary.LittleEndian, &meta.Version); err != nil {
+		return nil, fmt.Errorf("failed to read atlas version: %w", err)
+	}
+	args, err := readDotNetString(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read atlas args: %w", err)
+	}
+	meta.Args = args
+	if err := binary.Read(r, binary.LittleEndian, &meta.Hash); err != nil {
+		return nil, fmt.Errorf("failed to read atlas hash: %w", err)
+	}
+
+	var pageCount int16
+	if err := binary.Read(r, binary.LittleEndian, &pageCount); err != nil {
+		return nil, fmt.Errorf("failed to read page count: %w", err)
+	}
+
+	for i := 0; i < int(pageCount); i++ {
+		name, err := readDotNetString(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page name: %w", err)
+		}
+		page := AtlasPage{Name: name}
+
+		var spriteCount int16
+		if err := binary.Read(r, binary.LittleEndian, &spriteCount); err != nil {
+			return nil, fmt.Errorf("failed to read sprite count for page '%s': %w", name, err)
+		}
+
+		for j := 0; j < int(spriteCount); j++ {
+			key, err := readDotNetString(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read sprite key: %w", err)
+			}
+			// Celeste stores keys with backslashes on Windows-built atlases
+			sprite := AtlasSprite{Key: strings.ReplaceAll(key, "\\", "/")}
+
+			fields := []*int16{
+				&sprite.X, &sprite.Y, &sprite.Width, &sprite.Height,
+				&sprite.OffsetX, &sprite.OffsetY, &sprite.RealWidth, &sprite.RealHeight,
+			}
+			for _, field := range fields {
+				if err := binary.Read(r, binary.LittleEndian, field); err != nil {
+					return nil, fmt.Errorf("failed to read sprite '%s': %w", sprite.Key, err)
+				}
+			}
+			page.Sprites = append(page.Sprites, sprite)
+		}
+		meta.Pages = append(meta.Pages, page)
+	}
+
+	return meta, nil
+}
+
+// WriteAtlasMeta writes a Celeste .meta file in the packer format
+func WriteAtlasMeta(output io.Writer, meta *AtlasMeta) error {
+	w := bufio.NewWriter(output)
+
+	if err := binary.Write(w, binary.LittleEndian, meta.Version); err != nil {
+		return err
+	}
+	if err := writeDotNetString(w, meta.Args); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, meta.Hash); err != nil {
+		return err
+	}
+
+	if len(meta.Pages) > 0x7fff {
+		return errors.New("too many atlas pages")
+	}
+	if err := binary.Write(w, binary.LittleEndian, int16(len(meta.Pages))); err != nil {
+		return err
+	}
+
+	for _, page := range meta.Pages {
+		if err := writeDotNetString(w, page.Name); err != nil {
+			return err
+		}
+		if len(page.Sprites) > 0x7fff {
+			return fmt.Errorf("too many sprites on page '%s'", page.Name)
+		}
+		if err := binary.Write(w, binary.LittleEndian, int16(len(page.Sprites))); err != nil {
+			return err
+		}
+
+		for _, sprite := range page.Sprites {
+			if err := writeDotNetString(w, sprite.Key); err != nil {
+				return err
+			}
+			fields := []int16{
+				sprite.X, sprite.Y, sprite.Width, sprite.Height,
+				sprite.OffsetX, sprite.OffsetY, sprite.RealWidth, sprite.RealHeight,
+			}
+			if err := binary.Write(w, binary.LittleEndian, fields); err != nil {
+				return err
+			}
+		}
+	}
+
+	return w.Flush()
+}
+
+// readDotNetString reads a string prefixed with a 7-bit encoded length, as written by .NET's BinaryWriter
+func readDotNetString(r io.ByteReader) (string, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if length > 0xffff {
+		return "", fmt.Errorf("string length %d too large", length)
+	}
+
+	buf := make([]byte, length)
+	for i := range buf {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return "", io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		buf[i] = b
+	}
+	return string(buf), nil
+}
+
+// writeDotNetString writes a string prefixed with a 7-bit encoded length, as read by .NET's BinaryReader
+func writeDotNetString(w io.Writer, s string) error {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(s)))
+	if _, err := w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
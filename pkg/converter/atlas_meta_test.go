@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// TestAtlasMetaRoundTrip tests that a meta file survives a write/read cycle
+func TestAtlasMetaRoundTrip(t *testing.T) {
+	meta := &AtlasMeta{
+		Pages: []AtlasPage{
+			{
+				Name: "Gameplay0",
+				Sprites: []AtlasSprite{
+					{Key: "characters/player/idle00", X: 0, Y: 0, Width: 32, Height: 32, RealWidth: 32, RealHeight: 32},
+					{Key: "objects/booster/booster00", X: 33, Y: 0, Width: 16, Height: 12, OffsetX: -2, OffsetY: -4, RealWidth: 20, RealHeight: 20},
+				},
+			},
+			{
+				Name:    "Gameplay1",
+				Sprites: []AtlasSprite{{Key: "bgs/04/bg0", Width: 320, Height: 180, RealWidth: 320, RealHeight: 180}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAtlasMeta(&buf, meta); err != nil {
+		t.Fatalf("WriteAtlasMeta failed: %v", err)
+	}
+
+	read, err := ReadAtlasMeta(&buf)
+	if err != nil {
+		t.Fatalf("ReadAtlasMeta failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(meta, read) {
+		t.Fatalf("Meta mismatch after round trip:\nexpected %+v\ngot      %+v", meta, read)
+	}
+}
+
+// TestAtlasMetaBackslashKeys tests that Windows-style keys are normalized on read
+func TestAtlasMetaBackslashKeys(t *testing.T) {
+	meta := &AtlasMeta{
+		Pages: []AtlasPage{{Name: "Gui0", Sprites: []AtlasSprite{{Key: `icons\heart`}}}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAtlasMeta(&buf, meta); err != nil {
+		t.Fatalf("WriteAtlasMeta failed: %v", err)
+	}
+
+	read, err := ReadAtlasMeta(&buf)
+	if err != nil {
+		t.Fatalf("ReadAtlasMeta failed: %v", err)
+	}
+
+	if key := read.Pages[0].Sprites[0].Key; key != "icons/heart" {
+		t.Errorf("Expected key 'icons/heart', got '%s'", key)
+	}
+}
@@ -0,0 +1,289 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AtlasPacker builds Celeste packed atlases (.meta + page .data files) from a directory of PNG sprites
+type AtlasPacker struct {
+	graphicsConverter *GraphicsConverter
+	log               Logger
+	maxPageSize       int // Maximum width and height of a single page
+	padding           int // Empty pixels left between sprites
+	incremental       bool
+}
+
+// NewAtlasPacker creates a new AtlasPacker instance
+func NewAtlasPacker(graphicsConverter *GraphicsConverter) *AtlasPacker {
+	return &AtlasPacker{
+		graphicsConverter: graphicsConverter,
+		log:               DefaultLogger(),
+		maxPageSize:       4096,
+		padding:           1,
+	}
+}
+
+// SetMaxPageSize overrides the maximum page width and height. Sizes above the maximum dimension of the
+// GraphicsConverter are rejected, as it couldn't read such pages back.
+func (p *AtlasPacker) SetMaxPageSize(size int) error {
+	if size > p.graphicsConverter.maxDimension {
+		return fmt.Errorf("%w: page size %d exceeds the maximum dimension of %d", ErrImageTooLarge,
+			size, p.graphicsConverter.maxDimension)
+	}
+	if size > 0 {
+		p.maxPageSize = size
+	}
+	return nil
+}
+
+// SetPadding overrides the number of empty pixels left between sprites
+func (p *AtlasPacker) SetPadding(padding int) {
+	if padding >= 0 {
+		p.padding = padding
+	}
+}
+
+// packedSprite is a sprite loaded from disk together with its placement
+type packedSprite struct {
+	key  string
+	img  image.Image
+	page int
+	x, y int
+}
+
+// Pack reads every .png file below fromDir and writes <atlasName>.meta plus its page .data files into toDir
+func (p *AtlasPacker) Pack(fromDir, toDir, atlasName string) error {
+	p.log.Infof("Packing PNG -> ATLAS")
+	p.log.Infof("From directory: %s", fromDir)
+	p.log.Infof("To directory: %s", toDir)
+
+	sprites, err := p.loadSprites(fromDir)
+	if err != nil {
+		return err
+	}
+
+	p.log.Infof("%d sprites to pack", len(sprites))
+
+	metaPath := filepath.Join(toDir, atlasName+".meta")
+	if p.incremental {
+		existing, err := readAtlasMetaFile(metaPath)
+		if err == nil {
+			return p.update(toDir, atlasName, existing, sprites)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		p.log.Infof("No existing atlas in %s, packing from scratch", toDir)
+	}
+
+	if len(sprites) == 0 {
+		return nil // Nothing to pack
+	}
+
+	pageSizes, err := p.layout(sprites)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(toDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", toDir, err)
+	}
+
+	meta := &AtlasMeta{}
+	for i, size := range pageSizes {
+		pageName := atlasName + strconv.Itoa(i)
+		page := AtlasPage{Name: pageName}
+		pageImg := image.NewNRGBA(image.Rect(0, 0, size.X, size.Y))
+
+		for _, sprite := range sprites {
+			if sprite.page != i {
+				continue
+			}
+			bounds := sprite.img.Bounds()
+			dest := image.Rect(sprite.x, sprite.y, sprite.x+bounds.Dx(), sprite.y+bounds.Dy())
+			draw.Draw(pageImg, dest, sprite.img, bounds.Min, draw.Src)
+
+			page.Sprites = append(page.Sprites, AtlasSprite{
+				Key:        sprite.key,
+				X:          int16(sprite.x),
+				Y:          int16(sprite.y),
+				Width:      int16(bounds.Dx()),
+				Height:     int16(bounds.Dy()),
+				RealWidth:  int16(bounds.Dx()),
+				RealHeight: int16(bounds.Dy()),
+			})
+		}
+
+		pagePath := filepath.Join(toDir, pageName+".data")
+		p.log.Infof("[%d/%d] writing page %s (%dx%d, %d sprites)",
+			i+1, len(pageSizes), pageName, size.X, size.Y, len(page.Sprites))
+		if err := p.writePage(pagePath, pageImg); err != nil {
+			return err
+		}
+
+		meta.Pages = append(meta.Pages, page)
+	}
+
+	return writeAtlasMetaFile(metaPath, meta)
+}
+
+// readAtlasMetaFile reads the .meta file at path
+func readAtlasMetaFile(path string) (*AtlasMeta, error) {
+	metaFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer metaFile.Close()
+	meta, err := ReadAtlasMeta(metaFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read meta file '%s': %w", path, err)
+	}
+	return meta, nil
+}
+
+// writeAtlasMetaFile writes meta to the .meta file at path
+func writeAtlasMetaFile(path string, meta *AtlasMeta) error {
+	metaFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create meta file '%s': %w", path, err)
+	}
+	if err := WriteAtlasMeta(metaFile, meta); err != nil {
+		metaFile.Close()
+		return fmt.Errorf("failed to write meta file '%s': %w", path, err)
+	}
+	return metaFile.Close()
+}
+
+// loadSprites decodes all PNG files below dir, keyed by their normalized relative path.
+// Keys the game can't load, or can't tell apart, are rejected.
+func (p *AtlasPacker) loadSprites(dir string) ([]*packedSprite, error) {
+	var sprites []*packedSprite
+	keys := make(spriteKeySet)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".png") {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		key := NormalizeSpriteKey(filepath.ToSlash(relPath))
+		if err := ValidateSpriteKey(key); err != nil {
+			return err
+		}
+		if err := keys.add(key); err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open input file '%s': %w", path, err)
+		}
+		img, err := p.graphicsConverter.decodePng(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode sprite '%s': %w", relPath, err)
+		}
+
+		sprites = append(sprites, &packedSprite{key: key, img: img})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	// Sort by key first so the layout is deterministic regardless of walk order
+	sort.Slice(sprites, func(i, j int) bool {
+		return sprites[i].key < sprites[j].key
+	})
+
+	return sprites, nil
+}
+
+// layout assigns every sprite a page and position using shelf packing, tallest sprites first.
+// It returns the size of each page.
+func (p *AtlasPacker) layout(sprites []*packedSprite) ([]image.Point, error) {
+	order := make([]*packedSprite, len(sprites))
+	copy(order, sprites)
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].img.Bounds().Dy() > order[j].img.Bounds().Dy()
+	})
+
+	var pageSizes []image.Point
+	page := -1
+	shelfX, shelfY, shelfHeight := 0, 0, 0
+
+	newPage := func() {
+		page++
+		pageSizes = append(pageSizes, image.Point{})
+		shelfX, shelfY, shelfHeight = 0, 0, 0
+	}
+
+	for _, sprite := range order {
+		w := sprite.img.Bounds().Dx()
+		h := sprite.img.Bounds().Dy()
+		if w > p.maxPageSize || h > p.maxPageSize {
+			return nil, fmt.Errorf("sprite '%s' (%dx%d) exceeds maximum page size %d", sprite.key, w, h, p.maxPageSize)
+		}
+
+		if page < 0 {
+			newPage()
+		}
+
+		// Start a new shelf when the sprite doesn't fit on the current one
+		if shelfX > 0 && shelfX+w > p.maxPageSize {
+			shelfY += shelfHeight + p.padding
+			shelfX, shelfHeight = 0, 0
+		}
+		// Start a new page when the shelf doesn't fit on the current page
+		if shelfY+h > p.maxPageSize {
+			newPage()
+		}
+
+		sprite.page = page
+		sprite.x = shelfX
+		sprite.y = shelfY
+
+		shelfX += w + p.padding
+		if h > shelfHeight {
+			shelfHeight = h
+		}
+
+		size := &pageSizes[page]
+		if sprite.x+w > size.X {
+			size.X = sprite.x + w
+		}
+		if sprite.y+h > size.Y {
+			size.Y = sprite.y + h
+		}
+	}
+
+	return pageSizes, nil
+}
+
+// writePage encodes a page image to a .data file
+func (p *AtlasPacker) writePage(path string, img image.Image) error {
+	outputFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file '%s': %w", path, err)
+	}
+	if err := p.graphicsConverter.encodeData(img, outputFile); err != nil {
+		outputFile.Close()
+		return fmt.Errorf("failed to encode page '%s': %w", path, err)
+	}
+	return outputFile.Close()
+}
@@ -0,0 +1,143 @@
+package converter
+
+import (
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAtlasPackerPack tests that packed sprites can be read back from the written pages
+func TestAtlasPackerPack(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	// Put half the sprites in a subdirectory to exercise nested keys
+	if err := os.MkdirAll(filepath.Join(fromDir, "colors"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for i, imgName := range testImages {
+		destPath := filepath.Join(fromDir, imgName+".png")
+		if i%2 == 0 {
+			destPath = filepath.Join(fromDir, "colors", imgName+".png")
+		}
+		copyFile(t, filepath.Join("testdata", "png", imgName+".png"), destPath)
+	}
+
+	graphicsConverter := NewGraphicsConverter()
+	atlasPacker := NewAtlasPacker(graphicsConverter)
+
+	if err := atlasPacker.Pack(fromDir, toDir, "Gameplay"); err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	metaFile, err := os.Open(filepath.Join(toDir, "Gameplay.meta"))
+	if err != nil {
+		t.Fatalf("Failed to open meta file: %v", err)
+	}
+	defer metaFile.Close()
+
+	meta, err := ReadAtlasMeta(metaFile)
+	if err != nil {
+		t.Fatalf("ReadAtlasMeta failed: %v", err)
+	}
+
+	found := 0
+	for _, page := range meta.Pages {
+		pageData, err := os.ReadFile(filepath.Join(toDir, page.Name+".data"))
+		if err != nil {
+			t.Fatalf("Failed to read page %s: %v", page.Name, err)
+		}
+		pageImage := bytesToImage(t, dataToPngBytes(t, graphicsConverter, pageData))
+
+		for _, sprite := range page.Sprites {
+			found++
+			original := bytesToImage(t, readTestResource(t, filepath.Join("png", filepath.Base(sprite.Key)+".png")))
+			rect := image.Rect(int(sprite.X), int(sprite.Y), int(sprite.X+sprite.Width), int(sprite.Y+sprite.Height))
+			region := pageImage.(interface {
+				SubImage(image.Rectangle) image.Image
+			}).SubImage(rect)
+			assertImageEquals(t, original, translateImage(region), 5)
+		}
+	}
+
+	if found != len(testImages) {
+		t.Errorf("Expected %d sprites in atlas, got %d", len(testImages), found)
+	}
+}
+
+// TestAtlasPackerMultiplePages tests that sprites overflow onto additional pages
+func TestAtlasPackerMultiplePages(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	setupTestFiles(t, fromDir, ".png", "png")
+
+	atlasPacker := NewAtlasPacker(NewGraphicsConverter())
+
+	// Find the largest sprite so every page holds exactly one sprite
+	largest := 0
+	for _, imgName := range testImages {
+		img := bytesToImage(t, readTestResource(t, filepath.Join("png", imgName+".png")))
+		if img.Bounds().Dx() > largest {
+			largest = img.Bounds().Dx()
+		}
+		if img.Bounds().Dy() > largest {
+			largest = img.Bounds().Dy()
+		}
+	}
+	if err := atlasPacker.SetMaxPageSize(largest); err != nil {
+		t.Fatalf("SetMaxPageSize failed: %v", err)
+	}
+	atlasPacker.SetPadding(largest)
+
+	if err := atlasPacker.Pack(fromDir, toDir, "Gui"); err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	metaFile, err := os.Open(filepath.Join(toDir, "Gui.meta"))
+	if err != nil {
+		t.Fatalf("Failed to open meta file: %v", err)
+	}
+	defer metaFile.Close()
+
+	meta, err := ReadAtlasMeta(metaFile)
+	if err != nil {
+		t.Fatalf("ReadAtlasMeta failed: %v", err)
+	}
+
+	if len(meta.Pages) != len(testImages) {
+		t.Errorf("Expected %d pages, got %d", len(testImages), len(meta.Pages))
+	}
+	for _, page := range meta.Pages {
+		if _, err := os.Stat(filepath.Join(toDir, page.Name+".data")); os.IsNotExist(err) {
+			t.Errorf("Expected page file not found: %s", page.Name+".data")
+		}
+	}
+}
+
+// translateImage copies an image so its bounds start at the origin
+func translateImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			out.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return out
+}
+
+// TestAtlasPackerPageSizeLimit tests that pages larger than the converter reads back are refused
+func TestAtlasPackerPageSizeLimit(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+	graphicsConverter.SetMaxDimension(2048)
+	atlasPacker := NewAtlasPacker(graphicsConverter)
+	if err := atlasPacker.SetMaxPageSize(4096); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected %v, got %v", ErrImageTooLarge, err)
+	}
+	if err := atlasPacker.SetMaxPageSize(2048); err != nil {
+		t.Errorf("Expected the maximum dimension to be accepted, got %v", err)
+	}
+}
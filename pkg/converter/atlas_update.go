@@ -0,0 +1,235 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// SetIncremental makes Pack update an existing atlas in toDir instead of repacking it from scratch.
+// Unchanged sprites keep their place, changed sprites of the same size are redrawn in place, and only
+// new or resized sprites are placed into free space, growing a page or adding one when nothing fits.
+// Only pages that changed are rewritten.
+func (p *AtlasPacker) SetIncremental(enabled bool) {
+	p.incremental = enabled
+}
+
+// atlasPageState is an existing atlas page being updated
+type atlasPageState struct {
+	page  AtlasPage
+	img   *image.NRGBA
+	used  []image.Rectangle // Regions of the sprites kept on the page
+	dirty bool
+}
+
+// update brings the atlas described by meta in line with sprites, touching as little of it as possible
+func (p *AtlasPacker) update(toDir, atlasName string, meta *AtlasMeta, sprites []*packedSprite) error {
+	metaPath := filepath.Join(toDir, atlasName+".meta")
+	p.log.Infof("Updating existing atlas %s", metaPath)
+
+	byKey := make(map[string]*packedSprite, len(sprites))
+	for _, sprite := range sprites {
+		byKey[strings.ToLower(sprite.key)] = sprite
+	}
+	placed := make(map[*packedSprite]bool, len(sprites))
+
+	var pages []*atlasPageState
+	unchanged, redrawn, removed := 0, 0, 0
+	for _, page := range meta.Pages {
+		img, err := p.readPage(filepath.Join(toDir, page.Name+".data"))
+		if err != nil {
+			return err
+		}
+		state := &atlasPageState{page: AtlasPage{Name: page.Name}, img: img}
+
+		for _, entry := range page.Sprites {
+			rect := image.Rect(int(entry.X), int(entry.Y), int(entry.X)+int(entry.Width), int(entry.Y)+int(entry.Height))
+			sprite, ok := byKey[strings.ToLower(entry.Key)]
+			if !ok || placed[sprite] || !sameSpriteSize(entry, sprite.img.Bounds()) {
+				// Removed or resized: free its region, resized sprites are placed again below
+				draw.Draw(img, rect, image.Transparent, image.Point{}, draw.Src)
+				state.dirty = true
+				if !ok {
+					p.log.Infof("Removed sprite %s", entry.Key)
+					removed++
+				}
+				continue
+			}
+			placed[sprite] = true
+
+			stored, err := p.storedSprite(sprite.img)
+			if err != nil {
+				return fmt.Errorf("failed to encode sprite '%s': %w", sprite.key, err)
+			}
+			if imagecompare.Compare(stored, img.SubImage(rect), 0).ChangedPixels == 0 {
+				unchanged++
+			} else {
+				bounds := sprite.img.Bounds()
+				draw.Draw(img, rect, sprite.img, bounds.Min, draw.Src)
+				state.dirty = true
+				p.log.Infof("Redrew sprite %s", sprite.key)
+				redrawn++
+			}
+			entry.Key = sprite.key
+			state.page.Sprites = append(state.page.Sprites, entry)
+			state.used = append(state.used, rect)
+		}
+		pages = append(pages, state)
+	}
+
+	// Place new and resized sprites, tallest first
+	var pending []*packedSprite
+	for _, sprite := range sprites {
+		if !placed[sprite] {
+			pending = append(pending, sprite)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].img.Bounds().Dy() > pending[j].img.Bounds().Dy()
+	})
+	for _, sprite := range pending {
+		if err := p.place(&pages, sprite, atlasName); err != nil {
+			return err
+		}
+	}
+
+	p.log.Infof("%d sprites unchanged, %d redrawn in place, %d placed, %d removed",
+		unchanged, redrawn, len(pending), removed)
+
+	meta.Pages = meta.Pages[:0]
+	for i, state := range pages {
+		if state.dirty {
+			pagePath := filepath.Join(toDir, state.page.Name+".data")
+			bounds := state.img.Bounds()
+			p.log.Infof("[%d/%d] writing page %s (%dx%d, %d sprites)",
+				i+1, len(pages), state.page.Name, bounds.Dx(), bounds.Dy(), len(state.page.Sprites))
+			if err := p.writePage(pagePath, state.img); err != nil {
+				return err
+			}
+		}
+		meta.Pages = append(meta.Pages, state.page)
+	}
+	return writeAtlasMetaFile(metaPath, meta)
+}
+
+// place puts a sprite into free space on the first page it fits, preferring spots that don't grow
+// the page, and adds a page if none has room
+func (p *AtlasPacker) place(pages *[]*atlasPageState, sprite *packedSprite, atlasName string) error {
+	bounds := sprite.img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > p.maxPageSize || h > p.maxPageSize {
+		return fmt.Errorf("sprite '%s' (%dx%d) exceeds maximum page size %d", sprite.key, w, h, p.maxPageSize)
+	}
+
+	var state *atlasPageState
+	var at image.Point
+	for _, candidate := range *pages {
+		if point, ok := p.findSpace(candidate, w, h); ok {
+			state, at = candidate, point
+			break
+		}
+	}
+	if state == nil {
+		state = &atlasPageState{
+			page: AtlasPage{Name: atlasName + strconv.Itoa(len(*pages))},
+			img:  image.NewNRGBA(image.Rectangle{}),
+		}
+		*pages = append(*pages, state)
+	}
+
+	rect := image.Rect(at.X, at.Y, at.X+w, at.Y+h)
+	if size := state.img.Bounds().Union(rect); size != state.img.Bounds() {
+		grown := image.NewNRGBA(size)
+		draw.Draw(grown, state.img.Bounds(), state.img, state.img.Bounds().Min, draw.Src)
+		state.img = grown
+	}
+	draw.Draw(state.img, rect, sprite.img, bounds.Min, draw.Src)
+
+	state.page.Sprites = append(state.page.Sprites, AtlasSprite{
+		Key:        sprite.key,
+		X:          int16(at.X),
+		Y:          int16(at.Y),
+		Width:      int16(w),
+		Height:     int16(h),
+		RealWidth:  int16(w),
+		RealHeight: int16(h),
+	})
+	state.used = append(state.used, rect)
+	state.dirty = true
+	p.log.Infof("Placed sprite %s on page %s at %d,%d", sprite.key, state.page.Name, at.X, at.Y)
+	return nil
+}
+
+// findSpace looks for a free w×h spot on a page, trying the corners next to the sprites already on it.
+// Spots within the current page size win over ones that grow it, then the topmost, then the leftmost.
+func (p *AtlasPacker) findSpace(state *atlasPageState, w, h int) (image.Point, bool) {
+	size := state.img.Bounds().Max
+	candidates := []image.Point{{0, 0}, {size.X + p.padding, 0}, {0, size.Y + p.padding}}
+	for _, r := range state.used {
+		candidates = append(candidates, image.Pt(r.Max.X+p.padding, r.Min.Y), image.Pt(r.Min.X, r.Max.Y+p.padding))
+	}
+
+	best, bestGrowth, found := image.Point{}, 0, false
+	for _, c := range candidates {
+		rect := image.Rect(c.X, c.Y, c.X+w, c.Y+h)
+		if rect.Max.X > p.maxPageSize || rect.Max.Y > p.maxPageSize || !p.isFree(state, rect) {
+			continue
+		}
+		grown := state.img.Bounds().Union(rect)
+		growth := grown.Dx()*grown.Dy() - size.X*size.Y
+		if !found || growth < bestGrowth || growth == bestGrowth && (c.Y < best.Y || c.Y == best.Y && c.X < best.X) {
+			best, bestGrowth, found = c, growth, true
+		}
+	}
+	return best, found
+}
+
+// isFree reports whether rect keeps at least the padding away from every sprite on the page
+func (p *AtlasPacker) isFree(state *atlasPageState, rect image.Rectangle) bool {
+	padded := rect.Inset(-p.padding)
+	for _, r := range state.used {
+		if padded.Overlaps(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameSpriteSize reports whether an atlas entry holds an untrimmed sprite of the given size
+func sameSpriteSize(entry AtlasSprite, bounds image.Rectangle) bool {
+	return entry.OffsetX == 0 && entry.OffsetY == 0 &&
+		int(entry.Width) == bounds.Dx() && int(entry.Height) == bounds.Dy() &&
+		entry.RealWidth == entry.Width && entry.RealHeight == entry.Height
+}
+
+// storedSprite returns a sprite's pixels as they read back from a page, so an unchanged translucent
+// sprite still matches after the premultiplied alpha round trip
+func (p *AtlasPacker) storedSprite(img image.Image) (image.Image, error) {
+	var buf bytes.Buffer
+	if err := p.graphicsConverter.encodeData(img, &buf); err != nil {
+		return nil, err
+	}
+	return p.graphicsConverter.DecodeData(&buf)
+}
+
+// readPage decodes a page .data file
+func (p *AtlasPacker) readPage(path string) (*image.NRGBA, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open page '%s': %w", path, err)
+	}
+	defer file.Close()
+	img, err := p.graphicsConverter.decodeData(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page '%s': %w", path, err)
+	}
+	return img, nil
+}
@@ -0,0 +1,128 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// atlasTestSprite is a sprite read back from an atlas
+type atlasTestSprite struct {
+	AtlasSprite
+	page string
+	img  image.Image
+}
+
+// rect returns the region of the sprite on its page
+func (s atlasTestSprite) rect() image.Rectangle {
+	return image.Rect(int(s.X), int(s.Y), int(s.X+s.Width), int(s.Y+s.Height))
+}
+
+// readAtlasSprites reads an atlas back as its sprites, keyed by sprite key
+func readAtlasSprites(t *testing.T, dir, atlasName string) map[string]atlasTestSprite {
+	t.Helper()
+	meta, err := readAtlasMetaFile(filepath.Join(dir, atlasName+".meta"))
+	if err != nil {
+		t.Fatalf("Failed to read meta: %v", err)
+	}
+	sprites := make(map[string]atlasTestSprite)
+	for _, page := range meta.Pages {
+		pageImg, err := DecodeData(bytes.NewReader(readFile(t, filepath.Join(dir, page.Name+".data"))))
+		if err != nil {
+			t.Fatalf("Failed to decode page %s: %v", page.Name, err)
+		}
+		for _, entry := range page.Sprites {
+			sprite := atlasTestSprite{AtlasSprite: entry, page: page.Name}
+			sprite.img = translateImage(pageImg.(*image.NRGBA).SubImage(sprite.rect()))
+			sprites[entry.Key] = sprite
+		}
+	}
+	return sprites
+}
+
+// TestAtlasPackerIncremental tests that updating an atlas keeps unchanged sprites where they were
+func TestAtlasPackerIncremental(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	for _, imgName := range []string{"red", "green", "blue", "multi-color"} {
+		copyFile(t, filepath.Join("testdata", "png", imgName+".png"), filepath.Join(fromDir, imgName+".png"))
+	}
+
+	atlasPacker := NewAtlasPacker(NewGraphicsConverter())
+	atlasPacker.SetMaxPageSize(160)
+	atlasPacker.SetIncremental(true)
+	if err := atlasPacker.Pack(fromDir, toDir, "Gameplay"); err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	before := readAtlasSprites(t, toDir, "Gameplay")
+	metaBefore := readFile(t, filepath.Join(toDir, "Gameplay.meta"))
+	pageBefore := readFile(t, filepath.Join(toDir, "Gameplay0.data"))
+
+	// Nothing changed, so nothing is rewritten differently
+	if err := atlasPacker.Pack(fromDir, toDir, "Gameplay"); err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if !bytes.Equal(readFile(t, filepath.Join(toDir, "Gameplay.meta")), metaBefore) ||
+		!bytes.Equal(readFile(t, filepath.Join(toDir, "Gameplay0.data")), pageBefore) {
+		t.Error("Expected an unchanged atlas to stay byte for byte the same")
+	}
+
+	// Recolor red, drop green, grow blue and add two new sprites
+	recolored := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for i := range recolored.Pix {
+		recolored.Pix[i] = 128
+	}
+	grown := image.NewNRGBA(image.Rect(0, 0, 40, 40))
+	grown.Set(3, 3, color.NRGBA{B: 255, A: 255})
+	writePng := func(name string, img image.Image) {
+		var buf bytes.Buffer
+		if err := atlasPacker.graphicsConverter.encodePng(&buf, img); err != nil {
+			t.Fatalf("Failed to encode %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(fromDir, name+".png"), buf.Bytes(), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	writePng("red", recolored)
+	writePng("blue", grown)
+	if err := os.Remove(filepath.Join(fromDir, "green.png")); err != nil {
+		t.Fatalf("Failed to remove green.png: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "png", "yellow.png"), filepath.Join(fromDir, "yellow.png"))
+	copyFile(t, filepath.Join("testdata", "png", "cyan.png"), filepath.Join(fromDir, "cyan.png"))
+
+	if err := atlasPacker.Pack(fromDir, toDir, "Gameplay"); err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	after := readAtlasSprites(t, toDir, "Gameplay")
+
+	if len(after) != 5 {
+		t.Fatalf("Expected 5 sprites, got %d", len(after))
+	}
+	if _, ok := after["green"]; ok {
+		t.Error("Expected green to be removed")
+	}
+	for _, key := range []string{"red", "multi-color"} {
+		if after[key].page != before[key].page || after[key].AtlasSprite != before[key].AtlasSprite {
+			t.Errorf("Expected %s to stay at %+v, got %+v", key, before[key].AtlasSprite, after[key].AtlasSprite)
+		}
+	}
+	assertImageEquals(t, recolored, after["red"].img, 0)
+	assertImageEquals(t, grown, after["blue"].img, 0)
+	for _, key := range []string{"multi-color", "yellow", "cyan"} {
+		expected := bytesToImage(t, readTestResource(t, filepath.Join("png", key+".png")))
+		assertImageEquals(t, expected, after[key].img, 0)
+	}
+
+	// No two sprites on a page overlap, padding included
+	for a, sa := range after {
+		for b, sb := range after {
+			if a != b && sa.page == sb.page && sa.rect().Inset(-1).Overlaps(sb.rect()) {
+				t.Errorf("Sprites %s and %s overlap", a, b)
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package converter
+
+import (
+	"io/fs"
+	"os"
+)
+
+// SetPreserveAttributes makes batch conversions copy each input's modification time and permission
+// bits onto its output, so make-style build systems and incremental runs see outputs exactly as new as
+// their inputs. Outputs of read-only inputs are read-only too. Outputs written into zip archives can't
+// keep attributes.
+func (f *FilesConverter) SetPreserveAttributes(enabled bool) {
+	f.preserveAttributes = enabled
+}
+
+// copyAttributes sets the modification time and permission bits of the file at outputPath to those of input
+func copyAttributes(input fs.File, outputPath string) error {
+	info, err := input.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(outputPath, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(outputPath, info.ModTime(), info.ModTime())
+}
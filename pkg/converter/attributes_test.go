@@ -0,0 +1,44 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestPreserveAttributes tests that outputs get their input's modification time and permissions
+func TestPreserveAttributes(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	input := filepath.Join(fromDir, "red.data")
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), input)
+	modTime := time.Date(2018, 1, 25, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(input, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+	if err := os.Chmod(input, 0640); err != nil {
+		t.Fatalf("Failed to set permissions: %v", err)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetPreserveAttributes(true)
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(toDir, "red.png"))
+	if err != nil {
+		t.Fatalf("Expected red.png to be written: %v", err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("Expected modification time %v, got %v", modTime, info.ModTime())
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected permissions 0640, got %v", info.Mode().Perm())
+	}
+
+	if err := filesConverter.DataToPng(fromDir, filepath.Join(t.TempDir(), "out.zip")); err == nil {
+		t.Error("Expected preserving attributes in a zip archive to fail")
+	}
+}
@@ -0,0 +1,359 @@
+package converter
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// BackupExtension is the file extension of backups written by Backup
+const BackupExtension = ".tar.zst"
+
+// backupManifestName is the tar entry holding the manifest, written after every file
+const backupManifestName = ".celeste-backup.json"
+
+// BackupManifest lists everything a backup holds, so a restore can check it got every file back intact
+type BackupManifest struct {
+	Converter string        `json:"converter"`
+	Version   string        `json:"version"`
+	Created   time.Time     `json:"created"`
+	Source    string        `json:"source"` // Directory that was backed up
+	Entries   []BackupEntry `json:"entries"`
+}
+
+// BackupEntry is a file, directory or symlink in a backup
+type BackupEntry struct {
+	Path    string      `json:"path"` // Slash-separated, relative to the backed up directory
+	Mode    fs.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+	Size    int64       `json:"size,omitempty"`
+	SHA256  string      `json:"sha256,omitempty"`
+	Target  string      `json:"target,omitempty"` // Symlink target
+}
+
+// Files returns the number of regular files in the backup and their total size
+func (m *BackupManifest) Files() (count int, size int64) {
+	for _, entry := range m.Entries {
+		if entry.Mode.IsRegular() {
+			count++
+			size += entry.Size
+		}
+	}
+	return count, size
+}
+
+// Backup snapshots dir, such as a Celeste Content/Graphics tree, into a zstd-compressed tar archive at
+// archivePath with a manifest of every file's size, mode, modification time and SHA-256
+func (f *FilesConverter) Backup(dir, archivePath string) (*BackupManifest, error) {
+	f.log.Infof("Backing up %s to %s", dir, archivePath)
+
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("'%s' is not a directory", dir)
+	}
+	absDir, dirErr := filepath.Abs(dir)
+	absArchive, archiveErr := filepath.Abs(archivePath)
+	if dirErr == nil && archiveErr == nil {
+		if isWithin(absDir, absArchive) {
+			return nil, fmt.Errorf("backup '%s' can't be written inside the directory it backs up", archivePath)
+		}
+	}
+
+	output, err := os.Create(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup '%s': %w", archivePath, err)
+	}
+	manifest, err := f.writeBackup(dir, output)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(archivePath)
+		return nil, fmt.Errorf("failed to write backup '%s': %w", archivePath, err)
+	}
+
+	count, size := manifest.Files()
+	f.log.Infof("Backed up %d files (%d bytes)", count, size)
+	return manifest, nil
+}
+
+// writeBackup writes the archive of dir to output
+func (f *FilesConverter) writeBackup(dir string, output io.Writer) (*BackupManifest, error) {
+	encoder, err := zstd.NewWriter(output, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		return nil, err
+	}
+	tw := tar.NewWriter(encoder)
+
+	manifest := &BackupManifest{Converter: converterName, Version: Version, Created: time.Now().UTC(), Source: dir}
+	err = filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil || relPath == "." {
+			return err
+		}
+		entry := BackupEntry{Path: filepath.ToSlash(relPath), Mode: info.Mode(), ModTime: info.ModTime()}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = entry.Path
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			if entry.Target, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+			header.Linkname = entry.Target
+		case info.Mode().IsRegular():
+			entry.Size = info.Size()
+		case !info.IsDir():
+			f.log.Warnf("Skipping %s: not a regular file, directory or symlink", relPath)
+			return nil
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			if entry.SHA256, err = copyHashed(tw, filePath, entry.Size); err != nil {
+				return fmt.Errorf("failed to back up '%s': %w", relPath, err)
+			}
+			f.log.Debugf("Backed up %s", relPath)
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+		return nil
+	})
+	if err == nil {
+		err = writeBackupManifest(tw, manifest)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if closeErr := encoder.Close(); err == nil {
+		err = closeErr
+	}
+	return manifest, err
+}
+
+// writeBackupManifest appends the manifest to a backup, after every file it lists
+func writeBackupManifest(tw *tar.Writer, manifest *BackupManifest) error {
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: backupManifestName, Mode: 0644, Size: int64(len(manifestJSON)), ModTime: manifest.Created}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = tw.Write(manifestJSON)
+	return err
+}
+
+// copyHashed copies exactly size bytes of the file at filePath to w, returning their SHA-256
+func copyHashed(w io.Writer, filePath string, size int64) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	// A file changing size while it is backed up would corrupt the tar stream
+	if _, err := io.CopyN(io.MultiWriter(w, hash), file, size); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Restore replaces dir with the contents of a backup written by Backup, leaving it exactly as it was
+// backed up: files added since are removed, and modes and modification times are restored. The backup
+// is extracted and checked against its manifest next to dir first, so dir is only replaced once
+// every file came back intact.
+func (f *FilesConverter) Restore(archivePath, dir string) (*BackupManifest, error) {
+	f.log.Infof("Restoring %s from %s", dir, archivePath)
+
+	dir = filepath.Clean(dir)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+	// Staging next to dir keeps it on the same filesystem, so it is swapped in with a rename
+	stagingDir, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+".restore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	input, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup '%s': %w", archivePath, err)
+	}
+	manifest, err := f.extractBackup(input, stagingDir)
+	input.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore backup '%s': %w", archivePath, err)
+	}
+
+	// Move the current tree aside before swapping in the restored one, and back if that fails
+	var previous string
+	if _, err := os.Lstat(dir); err == nil {
+		previous = stagingDir + ".previous"
+		if err := os.Rename(dir, previous); err != nil {
+			return nil, fmt.Errorf("failed to move '%s' aside: %w", dir, err)
+		}
+	}
+	if err := os.Rename(stagingDir, dir); err != nil {
+		if previous != "" {
+			os.Rename(previous, dir)
+		}
+		return nil, fmt.Errorf("failed to move the restored tree into place: %w", err)
+	}
+	if previous != "" {
+		if err := os.RemoveAll(previous); err != nil {
+			f.log.Warnf("Failed to remove the replaced tree %s: %v", previous, err)
+		}
+	}
+
+	count, size := manifest.Files()
+	f.log.Infof("Restored %d files (%d bytes)", count, size)
+	return manifest, nil
+}
+
+// extractBackup extracts a backup into dir and checks it against its manifest
+func (f *FilesConverter) extractBackup(input io.Reader, dir string) (*BackupManifest, error) {
+	decoder, err := zstd.NewReader(input, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+	tr := tar.NewReader(decoder)
+
+	hashes := make(map[string]string)
+	var manifest *BackupManifest
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == backupManifestName {
+			manifest = &BackupManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			continue
+		}
+
+		name, target, err := backupTarget(dir, header.Name)
+		if err != nil {
+			return nil, err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeSymlink:
+			// Links may only point at other entries, so nothing restored later can be written through them
+			link := filepath.ToSlash(header.Linkname)
+			if path.IsAbs(link) || filepath.IsAbs(header.Linkname) || !fs.ValidPath(path.Join(path.Dir(name), link)) {
+				return nil, fmt.Errorf("symlink '%s' in backup points outside it, to '%s'", name, header.Linkname)
+			}
+			err = os.Symlink(header.Linkname, target)
+		case tar.TypeReg:
+			hashes[name], err = extractHashed(tr, target)
+		default:
+			err = fmt.Errorf("unsupported entry type %c", header.Typeflag)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore '%s': %w", name, err)
+		}
+	}
+	if manifest == nil {
+		return nil, errors.New("backup has no manifest, it may be truncated")
+	}
+
+	if count, _ := manifest.Files(); len(hashes) != count {
+		return nil, fmt.Errorf("backup holds %d files, its manifest lists %d", len(hashes), count)
+	}
+	for _, entry := range manifest.Entries {
+		if entry.Mode.IsRegular() && hashes[entry.Path] != entry.SHA256 {
+			return nil, fmt.Errorf("'%s' doesn't match its checksum in the manifest", entry.Path)
+		}
+	}
+
+	// Modes and times last, deepest first, so restoring files doesn't touch their directories' times
+	for i := len(manifest.Entries) - 1; i >= 0; i-- {
+		entry := manifest.Entries[i]
+		if entry.Mode&fs.ModeSymlink != 0 {
+			continue
+		}
+		_, target, err := backupTarget(dir, entry.Path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(target, entry.Mode.Perm()); err != nil {
+			return nil, err
+		}
+		if err := os.Chtimes(target, entry.ModTime, entry.ModTime); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, os.Chmod(dir, 0755)
+}
+
+// backupTarget checks that name, a path in a backup, stays inside it and that nothing already restored
+// on the way to it in dir is a symlink, returning the cleaned name and the path to restore it to
+func backupTarget(dir, name string) (string, string, error) {
+	clean := path.Clean(name)
+	if !fs.ValidPath(clean) || clean == "." {
+		return "", "", fmt.Errorf("invalid path '%s' in backup", name)
+	}
+	target := dir
+	for _, part := range strings.Split(clean, "/") {
+		target = filepath.Join(target, part)
+		info, err := os.Lstat(target)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return "", "", fmt.Errorf("path '%s' in backup goes through a symlink", name)
+		}
+	}
+	return clean, filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+// extractHashed writes r to a new file at filePath, returning the SHA-256 of its content
+func extractHashed(r io.Reader, filePath string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return "", err
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return hex.EncodeToString(hash.Sum(nil)), err
+}
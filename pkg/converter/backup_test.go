@@ -0,0 +1,159 @@
+package converter
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// TestBackupRestore tests that restoring a backup undoes changes made to the tree since
+func TestBackupRestore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Graphics")
+	if err := os.MkdirAll(filepath.Join(dir, "Atlases", "Gameplay"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(dir, "Atlases", "Gameplay", "red.data"))
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(dir, "Atlases", "blue.data"))
+	modTime := time.Date(2018, 1, 25, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "Atlases", "blue.data"), modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	archivePath := filepath.Join(t.TempDir(), "graphics"+BackupExtension)
+	manifest, err := filesConverter.Backup(dir, archivePath)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if count, _ := manifest.Files(); count != 2 {
+		t.Errorf("Expected 2 files in the manifest, got %d", count)
+	}
+
+	// Convert in place: overwrite one file, delete another and add a new one
+	if err := os.WriteFile(filepath.Join(dir, "Atlases", "Gameplay", "red.data"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "Atlases", "blue.data")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "png", "green.png"), filepath.Join(dir, "Atlases", "green.png"))
+
+	if _, err := filesConverter.Restore(archivePath, dir); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if string(readFile(t, filepath.Join(dir, "Atlases", "Gameplay", "red.data"))) != string(readTestResource(t, filepath.Join("data", "red.data"))) {
+		t.Error("Expected red.data to be restored")
+	}
+	info, err := os.Stat(filepath.Join(dir, "Atlases", "blue.data"))
+	if err != nil {
+		t.Fatalf("Expected blue.data to be restored: %v", err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("Expected modification time %v, got %v", modTime, info.ModTime())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Atlases", "green.png")); !os.IsNotExist(err) {
+		t.Error("Expected green.png added after the backup to be removed")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dir)); len(entries) != 1 {
+		t.Errorf("Expected only the restored directory next to it, found %d entries", len(entries))
+	}
+}
+
+// TestRestoreDamagedBackup tests that a truncated backup leaves the tree untouched
+func TestRestoreDamagedBackup(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(dir, "red.data"))
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	archivePath := filepath.Join(t.TempDir(), "backup"+BackupExtension)
+	if _, err := filesConverter.Backup(dir, archivePath); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if _, err := filesConverter.Backup(dir, filepath.Join(dir, "inside"+BackupExtension)); err == nil {
+		t.Error("Expected a backup inside the backed up directory to be rejected")
+	}
+
+	archive := readFile(t, archivePath)
+	if err := os.WriteFile(archivePath, archive[:len(archive)/2], 0644); err != nil {
+		t.Fatalf("Failed to truncate backup: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.data"), []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if _, err := filesConverter.Restore(archivePath, dir); err == nil {
+		t.Fatal("Expected restoring a truncated backup to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.data")); err != nil {
+		t.Error("Expected the tree to be left as it was")
+	}
+}
+
+// writeTestBackup writes a backup holding the given tar entries, with a file's content as its Linkname
+func writeTestBackup(t *testing.T, archivePath string, headers ...tar.Header) {
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+	defer file.Close()
+	encoder, err := zstd.NewWriter(file)
+	if err != nil {
+		t.Fatalf("Failed to create encoder: %v", err)
+	}
+	tw := tar.NewWriter(encoder)
+	for _, header := range headers {
+		content := ""
+		if header.Typeflag == tar.TypeReg {
+			content, header.Linkname = header.Linkname, ""
+			header.Size = int64(len(content))
+		}
+		header.Mode = 0644
+		if err := tw.WriteHeader(&header); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to write tar: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		t.Fatalf("Failed to compress backup: %v", err)
+	}
+}
+
+// TestRestoreEscapingBackup tests that backups writing outside the restored directory are rejected
+func TestRestoreEscapingBackup(t *testing.T) {
+	outside := t.TempDir()
+	tests := map[string][]tar.Header{
+		"parent path":      {{Name: "../escaped", Typeflag: tar.TypeReg, Linkname: "x"}},
+		"absolute symlink": {{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside}},
+		"escaping symlink": {{Name: "Atlases/link", Typeflag: tar.TypeSymlink, Linkname: "../../.."}},
+		"through symlink": {
+			{Name: "Atlases", Typeflag: tar.TypeDir},
+			{Name: "Atlases/link", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "Atlases/link/escaped", Typeflag: tar.TypeReg, Linkname: "x"},
+		},
+		"directory over symlink": {
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "Atlases"},
+			{Name: "link", Typeflag: tar.TypeDir},
+		},
+	}
+	for name, headers := range tests {
+		t.Run(name, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), "backup"+BackupExtension)
+			writeTestBackup(t, archivePath, headers...)
+			filesConverter := NewFilesConverter(NewGraphicsConverter())
+			if _, err := filesConverter.Restore(archivePath, filepath.Join(t.TempDir(), "Graphics")); err == nil {
+				t.Error("Expected the backup to be rejected")
+			}
+			if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+				t.Errorf("Expected nothing written outside the restored directory, found %d entries", len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BenchPattern selects the run-length characteristics of images synthesized by a Bencher
+type BenchPattern int
+
+const (
+	// BenchFlat has long runs of a few colors, like large flat backgrounds
+	BenchFlat BenchPattern = iota
+	// BenchSprite has transparent margins around short runs of a small palette, like Celeste's sprites
+	BenchSprite
+	// BenchNoise gives every pixel its own color, the worst case for run-length encoding
+	BenchNoise
+)
+
+// benchPatternNames maps the names accepted by ParseBenchPattern to patterns
+var benchPatternNames = map[string]BenchPattern{
+	"flat":   BenchFlat,
+	"sprite": BenchSprite,
+	"noise":  BenchNoise,
+}
+
+// ParseBenchPattern parses "flat", "sprite" or "noise"
+func ParseBenchPattern(s string) (BenchPattern, error) {
+	pattern, ok := benchPatternNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown benchmark pattern '%s', expected flat, sprite or noise", s)
+	}
+	return pattern, nil
+}
+
+// String returns the name of the pattern as accepted by ParseBenchPattern
+func (p BenchPattern) String() string {
+	for name, pattern := range benchPatternNames {
+		if pattern == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("BenchPattern(%d)", int(p))
+}
+
+// BenchCase is an image a Bencher synthesizes and converts
+type BenchCase struct {
+	Size    int // Width and height
+	Pattern BenchPattern
+}
+
+// String describes the case, e.g. "512x512 sprite"
+func (c BenchCase) String() string {
+	return fmt.Sprintf("%dx%d %v", c.Size, c.Size, c.Pattern)
+}
+
+// BenchResult is the throughput of one conversion direction of a case at a worker count
+type BenchResult struct {
+	Case            string  `json:"case"`
+	Direction       string  `json:"direction"` // data2png or png2data
+	Workers         int     `json:"workers"`
+	InputBytes      int64   `json:"inputBytes"` // Size of a single input
+	Images          int     `json:"images"`
+	Seconds         float64 `json:"seconds"`
+	MBPerSecond     float64 `json:"mbPerSecond"` // Megabytes of RGBA pixels per second, comparable across directions
+	ImagesPerSecond float64 `json:"imagesPerSecond"`
+}
+
+// Bencher measures the conversion throughput of synthesized images in memory, without file I/O, to
+// compare performance changes and pick a worker count
+type Bencher struct {
+	graphicsConverter *GraphicsConverter
+	log               Logger
+	cases             []BenchCase
+	workerCounts      []int
+	duration          time.Duration
+}
+
+// NewBencher creates a new Bencher converting with graphicsConverter, as configured. By default every
+// pattern is measured at 64, 512 and 2048 pixels square, for a second each, with worker counts
+// doubling from 1 up to the number of CPUs.
+func NewBencher(graphicsConverter *GraphicsConverter) *Bencher {
+	b := &Bencher{
+		graphicsConverter: graphicsConverter,
+		log:               DefaultLogger(),
+		duration:          time.Second,
+	}
+	for _, size := range []int{64, 512, 2048} {
+		for _, pattern := range []BenchPattern{BenchFlat, BenchSprite, BenchNoise} {
+			b.cases = append(b.cases, BenchCase{size, pattern})
+		}
+	}
+	b.SetMaxWorkers(runtime.NumCPU())
+	return b
+}
+
+// SetCases sets the images measured, ignoring an empty list
+func (b *Bencher) SetCases(cases []BenchCase) {
+	if len(cases) > 0 {
+		b.cases = cases
+	}
+}
+
+// SetMaxWorkers sets the highest worker count measured; counts double from 1 up to it
+func (b *Bencher) SetMaxWorkers(workers int) {
+	if workers <= 0 {
+		return
+	}
+	b.workerCounts = nil
+	for count := 1; count < workers; count *= 2 {
+		b.workerCounts = append(b.workerCounts, count)
+	}
+	b.workerCounts = append(b.workerCounts, workers)
+}
+
+// SetDuration sets how long each case, direction and worker count is measured, 1s by default
+func (b *Bencher) SetDuration(duration time.Duration) {
+	if duration > 0 {
+		b.duration = duration
+	}
+}
+
+// Run measures both conversion directions of every case at every worker count, in that order.
+// Every measurement converts at least one image per worker, however short the duration.
+func (b *Bencher) Run(ctx context.Context) ([]BenchResult, error) {
+	var results []BenchResult
+	for _, benchCase := range b.cases {
+		img := synthesizeBenchImage(benchCase)
+		var data, pngBytes bytes.Buffer
+		if err := b.graphicsConverter.encodeData(img, &data); err != nil {
+			return results, fmt.Errorf("failed to encode %v: %w", benchCase, err)
+		}
+		if err := b.graphicsConverter.encodePng(&pngBytes, img); err != nil {
+			return results, fmt.Errorf("failed to encode %v: %w", benchCase, err)
+		}
+
+		directions := []struct {
+			name    string
+			input   []byte
+			convert func(io.Reader, io.Writer) error
+		}{
+			{"data2png", data.Bytes(), b.graphicsConverter.DataToPng},
+			{"png2data", pngBytes.Bytes(), b.graphicsConverter.PngToData},
+		}
+		for _, direction := range directions {
+			for _, workers := range b.workerCounts {
+				if err := ctx.Err(); err != nil {
+					return results, err
+				}
+				images, elapsed, err := b.measure(ctx, direction.input, direction.convert, workers)
+				if err != nil {
+					return results, fmt.Errorf("%v %s failed: %w", benchCase, direction.name, err)
+				}
+				pixelBytes := float64(benchCase.Size) * float64(benchCase.Size) * 4
+				result := BenchResult{
+					Case:            benchCase.String(),
+					Direction:       direction.name,
+					Workers:         workers,
+					InputBytes:      int64(len(direction.input)),
+					Images:          images,
+					Seconds:         elapsed.Seconds(),
+					MBPerSecond:     float64(images) * pixelBytes / (1024 * 1024) / elapsed.Seconds(),
+					ImagesPerSecond: float64(images) / elapsed.Seconds(),
+				}
+				b.log.Debugf("%s %s, %d workers: %.1f MB/s", result.Case, result.Direction, workers, result.MBPerSecond)
+				results = append(results, result)
+			}
+		}
+	}
+	return results, nil
+}
+
+// measure converts input on workers goroutines until the duration is over, returning the number of
+// images converted and the time taken
+func (b *Bencher) measure(ctx context.Context, input []byte, convert func(io.Reader, io.Writer) error, workers int) (int, time.Duration, error) {
+	var images atomic.Int64
+	var firstErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	deadline := start.Add(b.duration)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for first := true; first || time.Now().Before(deadline) && ctx.Err() == nil; first = false {
+				if err := convert(bytes.NewReader(input), io.Discard); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					return
+				}
+				images.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(images.Load()), time.Since(start), firstErr
+}
+
+// synthesizeBenchImage draws the image of a case, the same for every run
+func synthesizeBenchImage(benchCase BenchCase) *image.NRGBA {
+	size := benchCase.Size
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	random := rand.New(rand.NewSource(int64(size)))
+	palette := make([][4]byte, 16)
+	for i := range palette {
+		palette[i] = [4]byte{byte(random.Intn(256)), byte(random.Intn(256)), byte(random.Intn(256)), 255}
+	}
+
+	for y := 0; y < size; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+size*4]
+		switch benchCase.Pattern {
+		case BenchFlat:
+			// Bands of a single color, with a transparent quarter at the bottom
+			if y < size*3/4 {
+				color := palette[y*len(palette)/size]
+				for x := 0; x < size; x++ {
+					copy(row[x*4:], color[:])
+				}
+			}
+		case BenchSprite:
+			// Runs of 1 to 8 pixels inside transparent margins of an eighth on every side
+			margin := size / 8
+			if y < margin || y >= size-margin {
+				continue
+			}
+			for x := margin; x < size-margin; {
+				color := palette[random.Intn(len(palette))]
+				for end := min(x+1+random.Intn(8), size-margin); x < end; x++ {
+					copy(row[x*4:], color[:])
+				}
+			}
+		case BenchNoise:
+			random.Read(row)
+			for x := 0; x < size; x++ {
+				row[x*4+3] = 255
+			}
+		}
+	}
+	return img
+}
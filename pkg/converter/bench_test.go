@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestBencher tests that every case, direction and worker count is measured
+func TestBencher(t *testing.T) {
+	bencher := NewBencher(NewGraphicsConverter())
+	bencher.SetCases([]BenchCase{{16, BenchFlat}, {16, BenchNoise}})
+	bencher.SetMaxWorkers(3)
+	bencher.SetDuration(time.Millisecond)
+
+	results, err := bencher.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 12 {
+		t.Fatalf("Expected 2 cases in 2 directions at 3 worker counts, got %d results", len(results))
+	}
+	var workers []int
+	for _, r := range results[:3] {
+		workers = append(workers, r.Workers)
+	}
+	if !slices.Equal(workers, []int{1, 2, 3}) {
+		t.Errorf("Expected worker counts 1, 2 and 3, got %v", workers)
+	}
+	for _, r := range results {
+		if r.Images < r.Workers || r.MBPerSecond <= 0 || r.ImagesPerSecond <= 0 {
+			t.Errorf("Expected at least one image per worker and a throughput, got %+v", r)
+		}
+	}
+	if first := results[0]; first.Case != "16x16 flat" || first.Direction != "data2png" {
+		t.Errorf("Expected 16x16 flat data2png first, got %s %s", first.Case, first.Direction)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := bencher.Run(ctx); err != context.Canceled {
+		t.Errorf("Expected a cancelled run to stop, got %v", err)
+	}
+}
+
+// TestSynthesizeBenchImage tests that the patterns span the range of run-length compression
+func TestSynthesizeBenchImage(t *testing.T) {
+	gc := NewGraphicsConverter()
+	var sizes []int
+	for _, pattern := range []BenchPattern{BenchFlat, BenchSprite, BenchNoise} {
+		var data bytes.Buffer
+		if err := gc.encodeData(synthesizeBenchImage(BenchCase{256, pattern}), &data); err != nil {
+			t.Fatalf("encodeData failed: %v", err)
+		}
+		sizes = append(sizes, data.Len())
+	}
+	if !(sizes[0] < sizes[1] && sizes[1] < sizes[2]) {
+		t.Errorf("Expected flat, sprite and noise images to compress ever worse, got %v bytes", sizes)
+	}
+	if pattern, err := ParseBenchPattern("Sprite"); err != nil || pattern != BenchSprite {
+		t.Errorf("Expected BenchSprite, got %v, %v", pattern, err)
+	}
+}
+
+// BenchmarkConversions converts every pattern at a few sizes in both directions
+func BenchmarkConversions(b *testing.B) {
+	gc := NewGraphicsConverter()
+	for _, size := range []int{64, 512} {
+		for _, pattern := range []BenchPattern{BenchFlat, BenchSprite, BenchNoise} {
+			benchCase := BenchCase{size, pattern}
+			img := synthesizeBenchImage(benchCase)
+			var data, pngBytes bytes.Buffer
+			if err := gc.encodeData(img, &data); err != nil {
+				b.Fatalf("encodeData failed: %v", err)
+			}
+			if err := gc.encodePng(&pngBytes, img); err != nil {
+				b.Fatalf("encodePng failed: %v", err)
+			}
+
+			for name, run := range map[string]struct {
+				input   []byte
+				convert func(io.Reader, io.Writer) error
+			}{
+				"data2png": {data.Bytes(), gc.DataToPng},
+				"png2data": {pngBytes.Bytes(), gc.PngToData},
+			} {
+				b.Run(benchCase.String()+"/"+name, func(b *testing.B) {
+					b.ReportAllocs()
+					b.SetBytes(int64(size * size * 4)) // Pixel bytes, comparable across directions
+					for i := 0; i < b.N; i++ {
+						if err := run.convert(bytes.NewReader(run.input), io.Discard); err != nil {
+							b.Fatalf("%s failed: %v", name, err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package converter
+
+import (
+	"bufio"
+	"image"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+// Pools let the workers of a batch reuse the buffers of earlier files instead of allocating new ones
+// for every conversion, which keeps the garbage collector quiet at high worker counts
+var (
+	dataReaders = sync.Pool{New: func() any { return bufio.NewReaderSize(nil, dataBufferSize) }}
+	dataWriters = sync.Pool{New: func() any { return bufio.NewWriterSize(nil, dataBufferSize) }}
+	pngReaders  = sync.Pool{New: func() any { return bufio.NewReader(nil) }}
+)
+
+// maxPooledBufferBits is the size class of the largest pooled buffer, 64 MiB or a 4096×4096 RGBA
+// texture. Larger buffers are left to the garbage collector so a single huge image isn't kept alive.
+const maxPooledBufferBits = 26
+
+// byteBuffers holds free byte buffers by size class, the buffers of class n having a capacity of 1<<n
+var byteBuffers [maxPooledBufferBits + 1]sync.Pool
+
+// getBuffer returns a buffer of length size, with unspecified contents
+func getBuffer(size int) []byte {
+	class := bits.Len(uint(size - 1))
+	if size <= 0 || class > maxPooledBufferBits {
+		return make([]byte, max(size, 0))
+	}
+	if buf, ok := byteBuffers[class].Get().(*[]byte); ok {
+		return (*buf)[:size]
+	}
+	return make([]byte, size, 1<<class)
+}
+
+// putBuffer hands a buffer from getBuffer back for reuse. The caller must not use it afterwards.
+func putBuffer(buf []byte) {
+	class := bits.Len(uint(cap(buf) - 1))
+	if cap(buf) == 0 || class > maxPooledBufferBits || cap(buf) != 1<<class {
+		return
+	}
+	buf = buf[:0]
+	byteBuffers[class].Put(&buf)
+}
+
+// newPooledNRGBA returns a transparent image whose pixels may reuse those of an image given to releaseNRGBA
+func newPooledNRGBA(rect image.Rectangle) *image.NRGBA {
+	pix := getBuffer(rect.Dx() * rect.Dy() * 4)
+	clear(pix)
+	return &image.NRGBA{Pix: pix, Stride: rect.Dx() * 4, Rect: rect}
+}
+
+// releaseNRGBA hands the pixels of an image from newPooledNRGBA back for reuse. The caller must not use
+// the image afterwards.
+func releaseNRGBA(img *image.NRGBA) {
+	putBuffer(img.Pix)
+	img.Pix = nil
+}
+
+// getDataReader returns a pooled reader buffering input in dataBufferSize chunks
+func getDataReader(input io.Reader) *bufio.Reader {
+	r := dataReaders.Get().(*bufio.Reader)
+	r.Reset(input)
+	return r
+}
+
+// putDataReader hands a reader from getDataReader back, dropping its reference to the input
+func putDataReader(r *bufio.Reader) {
+	r.Reset(nil)
+	dataReaders.Put(r)
+}
+
+// getDataWriter returns a pooled writer buffering output in dataBufferSize chunks
+func getDataWriter(output io.Writer) *bufio.Writer {
+	w := dataWriters.Get().(*bufio.Writer)
+	w.Reset(output)
+	return w
+}
+
+// putDataWriter hands a writer from getDataWriter back, discarding anything that wasn't flushed
+func putDataWriter(w *bufio.Writer) {
+	w.Reset(nil)
+	dataWriters.Put(w)
+}
+
+// getPngReader returns a pooled reader buffering PNG input
+func getPngReader(input io.Reader) *bufio.Reader {
+	r := pngReaders.Get().(*bufio.Reader)
+	r.Reset(input)
+	return r
+}
+
+// putPngReader hands a reader from getPngReader back, dropping its reference to the input
+func putPngReader(r *bufio.Reader) {
+	r.Reset(nil)
+	pngReaders.Put(r)
+}
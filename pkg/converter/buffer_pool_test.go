@@ -0,0 +1,99 @@
+package converter
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestGetBuffer tests that buffers are sized by class and that oversized ones aren't pooled
+func TestGetBuffer(t *testing.T) {
+	buf := getBuffer(1000)
+	if len(buf) != 1000 || cap(buf) != 1024 {
+		t.Fatalf("Expected length 1000 in a 1024 class, got %d/%d", len(buf), cap(buf))
+	}
+	putBuffer(buf)
+	if huge := getBuffer(1<<maxPooledBufferBits + 1); cap(huge) != 1<<maxPooledBufferBits+1 {
+		t.Errorf("Expected buffers above the largest class to be allocated exactly, got capacity %d", cap(huge))
+	}
+	if empty := getBuffer(0); len(empty) != 0 {
+		t.Errorf("Expected an empty buffer, got length %d", len(empty))
+	}
+}
+
+// TestPooledImageIsCleared tests that a truncated DATA image decoded into the pixels of a released image
+// leaves its missing pixels transparent
+func TestPooledImageIsCleared(t *testing.T) {
+	gc := NewGraphicsConverter()
+	var data bytes.Buffer
+	if err := gc.encodeData(parallelTestImage(), &data); err != nil {
+		t.Fatalf("encodeData failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		img, err := gc.decodeData(bytes.NewReader(data.Bytes()))
+		if err != nil {
+			t.Fatalf("decodeData failed: %v", err)
+		}
+		releaseNRGBA(img)
+	}
+
+	// Only the header and the first run, leaving every other pixel uncovered
+	truncated, err := gc.decodeData(bytes.NewReader(data.Bytes()[:14]))
+	if err != nil {
+		t.Fatalf("decodeData failed: %v", err)
+	}
+	defer releaseNRGBA(truncated)
+	for i := 256 * 4; i < len(truncated.Pix); i++ {
+		if truncated.Pix[i] != 0 {
+			t.Fatalf("Expected uncovered pixels to be transparent, got byte %d = %d", i, truncated.Pix[i])
+		}
+	}
+}
+
+// benchmarkTextures returns the DATA and PNG encodings of the parallel test image
+func benchmarkTextures(b *testing.B) (data, pngBytes []byte) {
+	gc := NewGraphicsConverter()
+	var dataBuf, pngBuf bytes.Buffer
+	if err := gc.encodeData(parallelTestImage(), &dataBuf); err != nil {
+		b.Fatalf("encodeData failed: %v", err)
+	}
+	if err := gc.encodePng(&pngBuf, parallelTestImage()); err != nil {
+		b.Fatalf("encodePng failed: %v", err)
+	}
+	return dataBuf.Bytes(), pngBuf.Bytes()
+}
+
+// BenchmarkDataToPngParallel converts DATA to PNG on every CPU, as the workers of a batch do
+func BenchmarkDataToPngParallel(b *testing.B) {
+	data, _ := benchmarkTextures(b)
+	gc := NewGraphicsConverter()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := gc.DataToPng(bytes.NewReader(data), io.Discard); err != nil {
+				b.Errorf("DataToPng failed: %v", err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkPngToDataParallel converts PNG to DATA on every CPU, as the workers of a batch do
+func BenchmarkPngToDataParallel(b *testing.B) {
+	_, pngBytes := benchmarkTextures(b)
+	gc := NewGraphicsConverter()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(pngBytes)))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := gc.PngToData(bytes.NewReader(pngBytes), io.Discard); err != nil {
+				b.Errorf("PngToData failed: %v", err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package converter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// cdatMagic identifies the zstd-compressed intermediate container (.cdat.zst)
+var cdatMagic = [4]byte{'C', 'D', 'A', 'T'}
+
+// cdatVersion is the current version of the container layout
+const cdatVersion uint8 = 1
+
+// cdatHeader is the metadata stored ahead of the raw pixels in a .cdat.zst container.
+// The whole container, header included, is a single zstd stream.
+type cdatHeader struct {
+	Magic    [4]byte
+	Version  uint8
+	Width    int32
+	Height   int32
+	HasAlpha uint8
+}
+
+// DataToCdat converts from Celeste's DATA format to the zstd-compressed intermediate format
+func (g *GraphicsConverter) DataToCdat(input io.Reader, output io.Writer) error {
+	img, err := g.decodeData(input)
+	if err != nil {
+		return err
+	}
+	transformed, err := g.applyTransforms(img)
+	if err != nil {
+		return err
+	}
+	return g.encodeCdat(transformed, output)
+}
+
+// CdatToData converts from the zstd-compressed intermediate format to Celeste's DATA format
+func (g *GraphicsConverter) CdatToData(input io.Reader, output io.Writer) error {
+	img, err := g.decodeCdat(input)
+	if err != nil {
+		return err
+	}
+	transformed, err := g.applyTransforms(img)
+	if err != nil {
+		return err
+	}
+	return g.encodeData(transformed, output)
+}
+
+// PngToCdat converts from a PNG image to the zstd-compressed intermediate format
+func (g *GraphicsConverter) PngToCdat(input io.Reader, output io.Writer) error {
+	img, err := g.decodePng(input)
+	if err != nil {
+		return err
+	}
+	if img, err = g.applyTransforms(img); err != nil {
+		return err
+	}
+	return g.encodeCdat(img, output)
+}
+
+// CdatToPng converts from the zstd-compressed intermediate format to a PNG image
+func (g *GraphicsConverter) CdatToPng(input io.Reader, output io.Writer) error {
+	img, err := g.decodeCdat(input)
+	if err != nil {
+		return err
+	}
+	transformed, err := g.applyTransforms(img)
+	if err != nil {
+		return err
+	}
+	return g.encodePng(output, transformed)
+}
+
+// encodeCdat writes raw RGBA pixels plus a small header as a single zstd stream
+func (g *GraphicsConverter) encodeCdat(img image.Image, output io.Writer) error {
+	rgba := toRGBA(img)
+	bounds := rgba.Bounds()
+
+	header := cdatHeader{
+		Magic:   cdatMagic,
+		Version: cdatVersion,
+		Width:   int32(bounds.Dx()),
+		Height:  int32(bounds.Dy()),
+	}
+	if hasAlphaChannel(rgba) {
+		header.HasAlpha = 1
+	}
+
+	g.log.Debugf("CDAT image parameters: %dx%d, %s", header.Width, header.Height,
+		boolToFormat(header.HasAlpha != 0))
+
+	encoder, err := zstd.NewWriter(output, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+
+	if err := binary.Write(encoder, binary.LittleEndian, header); err != nil {
+		encoder.Close()
+		return err
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		row := rgba.Pix[y*rgba.Stride : y*rgba.Stride+bounds.Dx()*4]
+		if _, err := encoder.Write(row); err != nil {
+			encoder.Close()
+			return err
+		}
+	}
+
+	return encoder.Close()
+}
+
+// decodeCdat reads an image from the zstd-compressed intermediate format
+func (g *GraphicsConverter) decodeCdat(input io.Reader) (*image.RGBA, error) {
+	decoder, err := zstd.NewReader(input, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	r := bufio.NewReader(decoder)
+
+	var header cdatHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read CDAT header: %w", err)
+	}
+	if header.Magic != cdatMagic {
+		return nil, errors.New("not a CDAT container")
+	}
+	if header.Version != cdatVersion {
+		return nil, fmt.Errorf("unsupported CDAT version %d", header.Version)
+	}
+
+	g.log.Debugf("CDAT image parameters: %dx%d, %s", header.Width, header.Height,
+		boolToFormat(header.HasAlpha != 0))
+
+	if err := g.checkImageSize(int(header.Width), int(header.Height), 4); err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, int(header.Width), int(header.Height)))
+	if _, err := io.ReadFull(r, img.Pix); err != nil {
+		return nil, fmt.Errorf("failed to read CDAT pixels: %w", err)
+	}
+
+	return img, nil
+}
+
+// toRGBA returns img as an *image.RGBA anchored at the origin, copying only when necessary
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return premultiplyNRGBA(nrgba) // Rounds, so decoded DATA colors survive the round trip exactly
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
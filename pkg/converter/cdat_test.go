@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+// TestCdatRoundTrip tests that DATA -> CDAT -> DATA preserves the image exactly
+func TestCdatRoundTrip(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+
+	for _, imageName := range testImages {
+		t.Run(imageName, func(t *testing.T) {
+			dataBytes := readTestResource(t, filepath.Join("data", imageName+".data"))
+
+			cdat := new(bytes.Buffer)
+			if err := graphicsConverter.DataToCdat(bytes.NewReader(dataBytes), cdat); err != nil {
+				t.Fatalf("DataToCdat failed: %v", err)
+			}
+
+			roundTrip := new(bytes.Buffer)
+			if err := graphicsConverter.CdatToData(cdat, roundTrip); err != nil {
+				t.Fatalf("CdatToData failed: %v", err)
+			}
+
+			originalImage := bytesToImage(t, dataToPngBytes(t, graphicsConverter, dataBytes))
+			convertedImage := bytesToImage(t, dataToPngBytes(t, graphicsConverter, roundTrip.Bytes()))
+			assertImageEquals(t, originalImage, convertedImage, 0)
+		})
+	}
+}
+
+// TestCdatPngRoundTrip tests that PNG -> CDAT -> PNG preserves the image
+func TestCdatPngRoundTrip(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+
+	for _, imageName := range testImages {
+		t.Run(imageName, func(t *testing.T) {
+			pngBytes := readTestResource(t, filepath.Join("png", imageName+".png"))
+
+			cdat := new(bytes.Buffer)
+			if err := graphicsConverter.PngToCdat(bytes.NewReader(pngBytes), cdat); err != nil {
+				t.Fatalf("PngToCdat failed: %v", err)
+			}
+
+			roundTrip := new(bytes.Buffer)
+			if err := graphicsConverter.CdatToPng(cdat, roundTrip); err != nil {
+				t.Fatalf("CdatToPng failed: %v", err)
+			}
+
+			assertImageEquals(t, bytesToImage(t, pngBytes), bytesToImage(t, roundTrip.Bytes()), 5)
+		})
+	}
+}
+
+// TestCdatRejectsGarbage tests that non-container input is rejected
+func TestCdatRejectsGarbage(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+
+	err := graphicsConverter.CdatToPng(bytes.NewReader([]byte("definitely not zstd")), new(bytes.Buffer))
+	if err == nil {
+		t.Fatal("Expected error for invalid input, got nil")
+	}
+}
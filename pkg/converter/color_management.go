@@ -0,0 +1,434 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"sync"
+)
+
+// maxIccProfileSize limits how far an embedded ICC profile is decompressed
+const maxIccProfileSize = 4 << 20
+
+// maxPngColorPrefix limits how much of a PNG before its image data is kept while looking for color chunks
+const maxPngColorPrefix = 16 << 20
+
+// srgbGAMA is the gAMA value of sRGB, 1/2.2 scaled by 100000. Files declaring a gamma this close to it
+// were written for sRGB displays and are read as sRGB.
+const srgbGAMA = 45455
+
+// srgbToXYZ is the matrix from linear sRGB to CIE XYZ relative to D50, the connection space of ICC profiles,
+// as sRGB profiles store it
+var srgbToXYZ = [3][3]float64{
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+// srgbEncodeSteps is the number of linear light levels srgbEncodeTable maps to 8-bit sRGB values
+const srgbEncodeSteps = 1 << 14
+
+// srgbEncodeTable maps linear light, scaled to srgbEncodeSteps-1, to the nearest 8-bit sRGB value
+var srgbEncodeTable = sync.OnceValue(func() *[srgbEncodeSteps]uint8 {
+	var table [srgbEncodeSteps]uint8
+	for i := range table {
+		v := float64(i) / (srgbEncodeSteps - 1)
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		table[i] = uint8(math.Round(v * 255))
+	}
+	return &table
+})
+
+// SetColorManagement makes PNG inputs that declare a color space with a gAMA or iCCP chunk convert to sRGB,
+// the color space the game draws in, so sprites from editors working in another space keep their look.
+// Inputs marked sRGB or without color information are read as they are. Written PNGs are tagged sRGB.
+// ICC profiles are supported in their matrix and curve form, used by RGB and grayscale display
+// profiles such as Adobe RGB or Display P3; others are ignored with a warning. Disabled by default.
+func (g *GraphicsConverter) SetColorManagement(enabled bool) {
+	g.colorManaged = enabled
+}
+
+// pngColorChunks holds the contents of the color space chunks of a PNG, nil for those it lacks
+type pngColorChunks struct {
+	srgb []byte
+	iccp []byte
+	gama []byte
+}
+
+// record keeps the contents of a chunk if it describes the color space
+func (c *pngColorChunks) record(name string, data []byte) {
+	switch name {
+	case "sRGB":
+		c.srgb = data
+	case "iCCP":
+		c.iccp = data
+	case "gAMA":
+		c.gama = data
+	}
+}
+
+// pngColorRecorder collects the color chunks of a PNG written to it, up to its image data
+type pngColorRecorder struct {
+	chunks pngColorChunks
+	buf    []byte
+	pos    int // Start of the next chunk in buf
+	done   bool
+}
+
+func (r *pngColorRecorder) Write(p []byte) (int, error) {
+	if r.done {
+		return len(p), nil
+	}
+	if r.pos == 0 {
+		r.pos = len(pngSignature)
+	}
+	r.buf = append(r.buf, p...)
+	for len(r.buf)-r.pos >= 8 {
+		length := int(binary.BigEndian.Uint32(r.buf[r.pos:]))
+		name := string(r.buf[r.pos+4 : r.pos+8])
+		// Color chunks come before the image data, so nothing after it is kept
+		if name == "IDAT" || name == "IEND" || length > maxPngColorPrefix || len(r.buf) > maxPngColorPrefix {
+			r.done, r.buf = true, nil
+			break
+		}
+		if len(r.buf)-r.pos < 12+length {
+			break
+		}
+		r.chunks.record(name, r.buf[r.pos+8:r.pos+8+length])
+		r.pos += 12 + length
+	}
+	return len(p), nil
+}
+
+// colorProfile converts the colors of a color space to sRGB
+type colorProfile struct {
+	name   string          // Describes the color space for logs
+	linear [3][256]float32 // Linear light of each channel's 8-bit values
+	matrix [3][3]float32   // From linear source RGB to linear sRGB
+	mix    bool            // The matrix isn't the identity
+}
+
+// pngColorProfile returns the conversion to sRGB of a PNG with the given color chunks, nil when it's sRGB or
+// declares no color space. An sRGB chunk takes precedence over an ICC profile, and both over gAMA.
+func (g *GraphicsConverter) pngColorProfile(chunks pngColorChunks) *colorProfile {
+	if chunks.srgb != nil {
+		return nil
+	}
+	if chunks.iccp != nil {
+		profile, err := parsePngIccProfile(chunks.iccp)
+		if err == nil {
+			if profile != nil {
+				g.log.Infof("Converting PNG from %s to sRGB", profile.name)
+			}
+			return profile
+		}
+		g.log.Warnf("Ignoring the color profile of PNG: %v", err)
+	}
+	if len(chunks.gama) == 4 {
+		gama := binary.BigEndian.Uint32(chunks.gama)
+		if gama == 0 || math.Abs(float64(gama)-srgbGAMA) < 500 {
+			return nil
+		}
+		profile := gammaProfile(float64(gama) / 100000)
+		g.log.Infof("Converting PNG from %s to sRGB", profile.name)
+		return profile
+	}
+	return nil
+}
+
+// gammaProfile returns the profile of a PNG whose samples are linear light raised to encodingGamma
+func gammaProfile(encodingGamma float64) *colorProfile {
+	p := &colorProfile{name: fmt.Sprintf("gamma %.2f", 1/encodingGamma)}
+	for v := 0; v < 256; v++ {
+		linear := float32(math.Pow(float64(v)/255, 1/encodingGamma))
+		p.linear[0][v], p.linear[1][v], p.linear[2][v] = linear, linear, linear
+	}
+	return p
+}
+
+// parsePngIccProfile decompresses and parses the contents of an iCCP chunk, returning nil for profiles
+// that are sRGB as far as 8-bit colors go
+func parsePngIccProfile(chunk []byte) (*colorProfile, error) {
+	nameEnd := bytes.IndexByte(chunk, 0)
+	if nameEnd < 1 || len(chunk) < nameEnd+2 || chunk[nameEnd+1] != 0 {
+		return nil, errors.New("malformed iCCP chunk")
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(chunk[nameEnd+2:]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress ICC profile: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(zr, maxIccProfileSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress ICC profile: %w", err)
+	}
+
+	profile, err := parseIccProfile(data)
+	if err != nil {
+		return nil, err
+	}
+	profile.name = fmt.Sprintf("ICC profile '%s'", chunk[:nameEnd])
+	if profile.isSRGB() {
+		return nil, nil
+	}
+	return profile, nil
+}
+
+// parseIccProfile reads the tone curves and, for RGB profiles, the colorant matrix of an ICC profile
+func parseIccProfile(data []byte) (*colorProfile, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return nil, errors.New("not an ICC profile")
+	}
+	tags := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint32(data[128:]))
+	for i := 0; i < count && 132+i*12+12 <= len(data); i++ {
+		entry := data[132+i*12:]
+		offset, size := binary.BigEndian.Uint32(entry[4:]), binary.BigEndian.Uint32(entry[8:])
+		if uint64(offset)+uint64(size) <= uint64(len(data)) {
+			tags[string(entry[:4])] = data[offset : offset+size]
+		}
+	}
+
+	p := &colorProfile{}
+	switch string(data[16:20]) {
+	case "GRAY":
+		curve, err := iccCurve(tags["kTRC"])
+		if err != nil {
+			return nil, fmt.Errorf("ICC gray curve: %w", err)
+		}
+		p.linear = [3][256]float32{curve, curve, curve}
+		return p, nil
+	case "RGB ":
+	default:
+		return nil, fmt.Errorf("unsupported ICC color space '%s'", data[16:20])
+	}
+
+	var toXYZ [3][3]float64
+	for c, name := range []string{"r", "g", "b"} {
+		curve, err := iccCurve(tags[name+"TRC"])
+		if err != nil {
+			return nil, fmt.Errorf("ICC %sTRC curve: %w", name, err)
+		}
+		p.linear[c] = curve
+
+		xyz := tags[name+"XYZ"]
+		if len(xyz) < 20 || string(xyz[:4]) != "XYZ " {
+			return nil, fmt.Errorf("ICC profile has no %sXYZ colorant, only matrix profiles are supported", name)
+		}
+		for row := 0; row < 3; row++ {
+			toXYZ[row][c] = s15Fixed16(xyz[8+row*4:])
+		}
+	}
+
+	fromXYZ, ok := invert3x3(srgbToXYZ)
+	if !ok {
+		return nil, errors.New("singular sRGB matrix")
+	}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			var sum float64
+			for k := 0; k < 3; k++ {
+				sum += fromXYZ[row][k] * toXYZ[k][col]
+			}
+			p.matrix[row][col] = float32(sum)
+			identity := 0.0
+			if row == col {
+				identity = 1
+			}
+			if math.Abs(sum-identity) > 2e-3 {
+				p.mix = true
+			}
+		}
+	}
+	return p, nil
+}
+
+// iccCurve returns the linear light of 8-bit values through a curv or para tone curve
+func iccCurve(tag []byte) ([256]float32, error) {
+	var curve [256]float32
+	if len(tag) < 12 {
+		return curve, errors.New("missing curve")
+	}
+	var f func(x float64) float64
+	switch string(tag[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		switch {
+		case n == 0:
+			f = func(x float64) float64 { return x }
+		case n == 1 && len(tag) >= 14:
+			gamma := float64(binary.BigEndian.Uint16(tag[12:])) / 256
+			f = func(x float64) float64 { return math.Pow(x, gamma) }
+		case n > 1 && len(tag) >= 12+n*2:
+			f = func(x float64) float64 {
+				pos := x * float64(n-1)
+				i := min(int(pos), n-2)
+				a := float64(binary.BigEndian.Uint16(tag[12+i*2:]))
+				b := float64(binary.BigEndian.Uint16(tag[14+i*2:]))
+				return (a + (b-a)*(pos-float64(i))) / 0xffff
+			}
+		default:
+			return curve, errors.New("truncated curv curve")
+		}
+	case "para":
+		kind := binary.BigEndian.Uint16(tag[8:])
+		counts := []int{1, 3, 4, 5, 7}
+		if int(kind) >= len(counts) || len(tag) < 12+counts[kind]*4 {
+			return curve, fmt.Errorf("unsupported para curve type %d", kind)
+		}
+		// Missing parameters keep the values that make their terms vanish
+		params := [7]float64{1, 1, 0, 0, math.Inf(-1), 0, 0}
+		for i := 0; i < counts[kind]; i++ {
+			params[i] = s15Fixed16(tag[12+i*4:])
+		}
+		g, a, b, c, d, e, fOffset := params[0], params[1], params[2], params[3], params[4], params[5], params[6]
+		switch kind {
+		case 1, 2:
+			d = -b / a
+		}
+		offset := 0.0
+		if kind == 2 {
+			offset = c
+			c = 0
+		}
+		if kind == 4 {
+			offset = e
+		}
+		f = func(x float64) float64 {
+			if x >= d {
+				return math.Pow(math.Max(a*x+b, 0), g) + offset
+			}
+			if kind == 2 {
+				return offset
+			}
+			return c*x + fOffset
+		}
+	default:
+		return curve, fmt.Errorf("unsupported curve type '%s'", tag[:4])
+	}
+
+	for v := range curve {
+		curve[v] = float32(min(max(f(float64(v)/255), 0), 1))
+	}
+	return curve, nil
+}
+
+// s15Fixed16 reads an ICC signed 15.16 fixed point number
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// invert3x3 inverts a 3x3 matrix, reporting false for singular ones
+func invert3x3(m [3][3]float64) ([3][3]float64, bool) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if det == 0 {
+		return m, false
+	}
+	var inv [3][3]float64
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			// The cofactor of the transposed position, from the cyclic neighbours of col and row
+			r1, r2 := (col+1)%3, (col+2)%3
+			c1, c2 := (row+1)%3, (row+2)%3
+			inv[row][col] = (m[r1][c1]*m[r2][c2] - m[r1][c2]*m[r2][c1]) / det
+		}
+	}
+	return inv, true
+}
+
+// isSRGB reports whether the profile leaves every 8-bit sRGB color unchanged
+func (p *colorProfile) isSRGB() bool {
+	if p.mix {
+		return false
+	}
+	table := srgbEncodeTable()
+	for c := range p.linear {
+		for v, linear := range p.linear[c] {
+			if table[int(linear*(srgbEncodeSteps-1)+0.5)] != uint8(v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// convert returns the sRGB color of a color of the profile's space
+func (p *colorProfile) convert(r, g, b uint8) (uint8, uint8, uint8) {
+	lr, lg, lb := p.linear[0][r], p.linear[1][g], p.linear[2][b]
+	if p.mix {
+		m := &p.matrix
+		lr, lg, lb = m[0][0]*lr+m[0][1]*lg+m[0][2]*lb,
+			m[1][0]*lr+m[1][1]*lg+m[1][2]*lb,
+			m[2][0]*lr+m[2][1]*lg+m[2][2]*lb
+	}
+	table := srgbEncodeTable()
+	encode := func(v float32) uint8 {
+		return table[int(min(max(v, 0), 1)*(srgbEncodeSteps-1)+0.5)]
+	}
+	return encode(lr), encode(lg), encode(lb)
+}
+
+// convertRow converts a row of straight alpha RGBA pixels to sRGB in place, keeping their alpha
+func (p *colorProfile) convertRow(pix []byte) {
+	for i := 0; i+3 < len(pix); i += 4 {
+		pix[i], pix[i+1], pix[i+2] = p.convert(pix[i], pix[i+1], pix[i+2])
+	}
+}
+
+// convertPalette converts palette entries to sRGB in place, keeping their alpha
+func (p *colorProfile) convertPalette(palette color.Palette) {
+	for i, c := range palette {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		n.R, n.G, n.B = p.convert(n.R, n.G, n.B)
+		palette[i] = n
+	}
+}
+
+// convertImage converts a decoded image to sRGB, in place for paletted and NRGBA images
+func (p *colorProfile) convertImage(img image.Image) image.Image {
+	if paletted, ok := img.(*image.Paletted); ok {
+		p.convertPalette(paletted.Palette)
+		return paletted
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		bounds := img.Bounds()
+		nrgba = image.NewNRGBA(bounds)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				nrgba.SetNRGBA(x, y, color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA))
+			}
+		}
+	}
+	bounds := nrgba.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		start := nrgba.PixOffset(bounds.Min.X, y)
+		p.convertRow(nrgba.Pix[start : start+bounds.Dx()*4])
+	}
+	return nrgba
+}
+
+// srgbChunks tag a PNG as sRGB, with the gAMA and cHRM values the PNG specification recommends alongside
+// for decoders that don't know the sRGB chunk
+var srgbChunks = []pngChunk{
+	{"sRGB", []byte{0}}, // Perceptual rendering intent
+	{"gAMA", binary.BigEndian.AppendUint32(nil, srgbGAMA)},
+	{"cHRM", func() []byte {
+		var data []byte
+		for _, v := range []uint32{31270, 32900, 64000, 33000, 30000, 60000, 15000, 6000} {
+			data = binary.BigEndian.AppendUint32(data, v)
+		}
+		return data
+	}()},
+}
@@ -0,0 +1,249 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// withPngChunks inserts chunks after the IHDR chunk of a PNG
+func withPngChunks(t *testing.T, data []byte, chunks ...pngChunk) []byte {
+	var out bytes.Buffer
+	if _, err := (&pngChunkWriter{w: &out, chunks: chunks}).Write(data); err != nil {
+		t.Fatalf("Failed to insert chunks: %v", err)
+	}
+	return out.Bytes()
+}
+
+// iccProfile builds a minimal ICC profile of the given color space holding the given tags
+func iccProfile(colorSpace string, tags map[string][]byte) []byte {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	header := make([]byte, 132)
+	copy(header[16:], colorSpace)
+	copy(header[36:], "acsp")
+	binary.BigEndian.PutUint32(header[128:], uint32(len(names)))
+	table := make([]byte, len(names)*12)
+	var data []byte
+	for i, name := range names {
+		copy(table[i*12:], name)
+		binary.BigEndian.PutUint32(table[i*12+4:], uint32(len(header)+len(table)+len(data)))
+		binary.BigEndian.PutUint32(table[i*12+8:], uint32(len(tags[name])))
+		data = append(data, tags[name]...)
+	}
+	return slices.Concat(header, table, data)
+}
+
+// iccGammaCurve returns a curv tag of a pure gamma curve
+func iccGammaCurve(gamma float64) []byte {
+	tag := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01")
+	return binary.BigEndian.AppendUint16(tag, uint16(gamma*256))
+}
+
+// iccSRGBCurve returns a para tag of the sRGB tone curve
+func iccSRGBCurve() []byte {
+	tag := []byte("para\x00\x00\x00\x00\x00\x03\x00\x00")
+	for _, v := range []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045} {
+		tag = binary.BigEndian.AppendUint32(tag, uint32(int32(math.Round(v*65536))))
+	}
+	return tag
+}
+
+// iccXYZ returns an XYZ tag
+func iccXYZ(x, y, z float64) []byte {
+	tag := []byte("XYZ \x00\x00\x00\x00")
+	for _, v := range []float64{x, y, z} {
+		tag = binary.BigEndian.AppendUint32(tag, uint32(int32(math.Round(v*65536))))
+	}
+	return tag
+}
+
+// rgbIccProfile builds an RGB matrix profile with the same curve for every channel and the given colorants
+func rgbIccProfile(curve []byte, colorants [3][3]float64) []byte {
+	return iccProfile("RGB ", map[string][]byte{
+		"rTRC": curve, "gTRC": curve, "bTRC": curve,
+		"rXYZ": iccXYZ(colorants[0][0], colorants[1][0], colorants[2][0]),
+		"gXYZ": iccXYZ(colorants[0][1], colorants[1][1], colorants[2][1]),
+		"bXYZ": iccXYZ(colorants[0][2], colorants[1][2], colorants[2][2]),
+	})
+}
+
+// iccpChunk wraps an ICC profile in an iCCP chunk
+func iccpChunk(t *testing.T, name string, profile []byte) pngChunk {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(profile)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to compress profile: %v", err)
+	}
+	return pngChunk{"iCCP", slices.Concat([]byte(name), []byte{0, 0}, compressed.Bytes())}
+}
+
+// colorTestPngs returns an opaque RGB and a paletted PNG with the same colors
+func colorTestPngs(t *testing.T) map[string][]byte {
+	colors := []color.NRGBA{{128, 128, 128, 255}, {200, 50, 10, 255}, {0, 0, 0, 255}, {255, 255, 255, 128}}
+	nrgba := image.NewNRGBA(image.Rect(0, 0, len(colors), 1))
+	palette := make(color.Palette, len(colors))
+	paletted := image.NewPaletted(nrgba.Rect, palette)
+	for i, c := range colors {
+		nrgba.SetNRGBA(i, 0, c)
+		palette[i] = c
+		paletted.SetColorIndex(i, 0, uint8(i))
+	}
+
+	pngs := make(map[string][]byte)
+	for name, img := range map[string]image.Image{"nrgba": nrgba, "paletted": paletted} {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatalf("Failed to encode PNG: %v", err)
+		}
+		pngs[name] = buf.Bytes()
+	}
+	return pngs
+}
+
+// colorManagedPixels converts a PNG to DATA, streamed and decoded whole, checks both agree and returns
+// the decoded DATA pixels
+func colorManagedPixels(t *testing.T, data []byte) []byte {
+	g := NewGraphicsConverter()
+	g.SetColorManagement(true)
+	g.SetAlphaMode(AlphaStraight)
+	if g.newPngStream(data) == nil {
+		t.Fatal("Expected the PNG to be streamed")
+	}
+	streamed := pngToDataBytes(t, g, data)
+	if !bytes.Equal(streamed, bufferedPngToData(t, g, data)) {
+		t.Fatal("Streamed DATA differs from the decoded image's")
+	}
+
+	img, err := g.DecodeData(bytes.NewReader(streamed))
+	if err != nil {
+		t.Fatalf("Failed to decode DATA: %v", err)
+	}
+	return img.(*image.NRGBA).Pix
+}
+
+// TestColorManagement tests that PNGs declaring another color space convert to sRGB and sRGB ones don't
+func TestColorManagement(t *testing.T) {
+	srgbProfile := rgbIccProfile(iccSRGBCurve(), srgbToXYZ)
+	linearProfile := rgbIccProfile(iccGammaCurve(1), srgbToXYZ)
+	// Adobe RGB (1998) adapted to D50
+	adobeProfile := rgbIccProfile(iccGammaCurve(2.19921875), [3][3]float64{
+		{0.6097559, 0.2052401, 0.1492240},
+		{0.3111242, 0.6256560, 0.0632197},
+		{0.0194811, 0.0608902, 0.7448387},
+	})
+	linearGray := iccProfile("GRAY", map[string][]byte{"kTRC": iccGammaCurve(1)})
+
+	unchanged := []byte{128, 128, 128, 255, 200, 50, 10, 255, 0, 0, 0, 255, 255, 255, 255, 128}
+	// The same colors taken as linear light, such as 128 becoming 188 in sRGB
+	linear := []byte{188, 188, 188, 255, 229, 122, 56, 255, 0, 0, 0, 255, 255, 255, 255, 128}
+	tests := []struct {
+		name     string
+		chunks   []pngChunk
+		expected []byte // nil to only check the colors changed
+	}{
+		{"none", nil, unchanged},
+		{"sRGB", []pngChunk{{"sRGB", []byte{0}}}, unchanged},
+		{"sRGB gamma", []pngChunk{{"gAMA", binary.BigEndian.AppendUint32(nil, 45455)}}, unchanged},
+		{"sRGB profile", []pngChunk{iccpChunk(t, "sRGB", srgbProfile)}, unchanged},
+		{"linear gamma", []pngChunk{{"gAMA", binary.BigEndian.AppendUint32(nil, 100000)}}, linear},
+		{"linear profile", []pngChunk{iccpChunk(t, "Linear", linearProfile)}, linear},
+		{"linear gray profile", []pngChunk{iccpChunk(t, "Linear gray", linearGray)}, linear},
+		{"sRGB chunk over linear profile", []pngChunk{{"sRGB", []byte{0}}, iccpChunk(t, "Linear", linearProfile)}, unchanged},
+		{"profile over gamma", []pngChunk{{"gAMA", binary.BigEndian.AppendUint32(nil, 100000)}, iccpChunk(t, "sRGB", srgbProfile)}, unchanged},
+		{"broken profile", []pngChunk{{"iCCP", []byte("Broken\x00\x00junk")}}, unchanged},
+		{"wide gamut profile", []pngChunk{iccpChunk(t, "Adobe RGB", adobeProfile)}, nil},
+	}
+
+	for _, tt := range tests {
+		for name, data := range colorTestPngs(t) {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				pix := colorManagedPixels(t, withPngChunks(t, data, tt.chunks...))
+				if tt.expected != nil {
+					if !bytes.Equal(pix, tt.expected) {
+						t.Errorf("Expected pixels %v, got %v", tt.expected, pix)
+					}
+					return
+				}
+
+				// Gray stays gray, nearly as bright since the curves are close, while saturated colors grow
+				// more saturated in sRGB
+				if pix[0] != pix[1] || pix[1] != pix[2] || absDiff(pix[0], 128) > 1 {
+					t.Errorf("Expected gray to stay close to %v, got %v", unchanged[:4], pix[:4])
+				}
+				if pix[4] <= 200 || pix[5] >= 50 {
+					t.Errorf("Expected the red of a wide gamut to convert to a more saturated sRGB red, got %v", pix[4:8])
+				}
+			})
+		}
+	}
+}
+
+// TestColorManagementDisabled tests that color chunks are ignored by default
+func TestColorManagementDisabled(t *testing.T) {
+	data := withPngChunks(t, colorTestPngs(t)["nrgba"], pngChunk{"gAMA", binary.BigEndian.AppendUint32(nil, 100000)})
+	g := NewGraphicsConverter()
+	if !bytes.Equal(pngToDataBytes(t, g, data), pngToDataBytes(t, g, colorTestPngs(t)["nrgba"])) {
+		t.Error("Expected the gamma to be ignored")
+	}
+}
+
+// TestSRGBTag tests that written PNGs are tagged sRGB with color management, and read back unchanged
+func TestSRGBTag(t *testing.T) {
+	g := NewGraphicsConverter()
+	g.SetColorManagement(true)
+	data := pngToDataBytes(t, g, colorTestPngs(t)["nrgba"])
+
+	var out bytes.Buffer
+	if err := g.DataToPng(bytes.NewReader(data), &out); err != nil {
+		t.Fatalf("Failed to convert to PNG: %v", err)
+	}
+	var names []string
+	for _, chunk := range readPngChunks(t, out.Bytes()) {
+		names = append(names, chunk.name)
+	}
+	if len(names) < 5 || !slices.Equal(names[:4], []string{"IHDR", "sRGB", "gAMA", "cHRM"}) {
+		t.Errorf("Expected sRGB, gAMA and cHRM chunks after the IHDR, got %v", names)
+	}
+	if !bytes.Equal(pngToDataBytes(t, g, out.Bytes()), data) {
+		t.Error("Expected the tagged PNG to convert back to the same DATA")
+	}
+}
+
+// TestInvert3x3 tests that a matrix times its inverse is the identity
+func TestInvert3x3(t *testing.T) {
+	inv, ok := invert3x3(srgbToXYZ)
+	if !ok {
+		t.Fatal("Expected the sRGB matrix to be invertible")
+	}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			var sum float64
+			for k := 0; k < 3; k++ {
+				sum += srgbToXYZ[row][k] * inv[k][col]
+			}
+			expected := 0.0
+			if row == col {
+				expected = 1
+			}
+			if math.Abs(sum-expected) > 1e-9 {
+				t.Errorf("Expected %v at %d,%d, got %v", expected, row, col, sum)
+			}
+		}
+	}
+	if _, ok := invert3x3([3][3]float64{{1, 2, 3}, {2, 4, 6}, {0, 0, 1}}); ok {
+		t.Error("Expected a singular matrix to be reported")
+	}
+}
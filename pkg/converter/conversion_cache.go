@@ -0,0 +1,122 @@
+package converter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+)
+
+// ConversionCache stores converted files in a directory keyed by the SHA-256 of their input's content
+// and the conversion settings, so unchanged inputs of repeated full conversions are copied from the
+// cache instead of being decoded and encoded again. A cache directory can be shared by any number of
+// runs and conversions; entries are never evicted, delete the directory to start over.
+type ConversionCache struct {
+	dir      string
+	settings []byte // Hashed into every key
+	hits     atomic.Int64
+	misses   atomic.Int64
+}
+
+// NewConversionCache opens the cache in dir, creating it if needed. options must describe every
+// setting the converted content depends on, such as the CLI flags set; entries written with other
+// options or by another version of the converter are never used.
+func NewConversionCache(dir string, options map[string]string) (*ConversionCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory '%s': %w", dir, err)
+	}
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var settings bytes.Buffer
+	fmt.Fprintf(&settings, "%s %s\n", converterName, Version)
+	for _, name := range names {
+		fmt.Fprintf(&settings, "%q=%q\n", name, options[name])
+	}
+	return &ConversionCache{dir: dir, settings: settings.Bytes()}, nil
+}
+
+// Stats returns the number of files copied from the cache and converted into it since it was opened
+func (c *ConversionCache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// SetCache makes batch conversions copy the outputs of inputs converted before from cache, and store
+// the others in it. Every input is read into memory to be hashed before converting. Conversions with
+// trimming don't use the cache. nil disables it.
+func (f *FilesConverter) SetCache(cache *ConversionCache) {
+	f.cache = cache
+}
+
+// key returns the cache key of input converted from fromExt to toExt
+func (c *ConversionCache) key(fromExt, toExt string, input []byte) string {
+	hasher := sha256.New()
+	hasher.Write(c.settings)
+	fmt.Fprintf(hasher, "%s -> %s\n", fromExt, toExt)
+	hasher.Write(input)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// path returns where the entry of key is stored, spread over subdirectories by its first two digits
+func (c *ConversionCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// convert writes the converted input to output, from the cache if it was converted before, and
+// otherwise converting it with convertFunc and storing the result. Failing to use the cache only
+// logs a warning.
+func (c *ConversionCache) convert(log Logger, fromExt, toExt string, convertFunc func(io.Reader, io.Writer) error, input io.Reader, output io.Writer) error {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	key := c.key(fromExt, toExt, data)
+	if cached, err := os.ReadFile(c.path(key)); err == nil {
+		c.hits.Add(1)
+		_, err := output.Write(cached)
+		return err
+	} else if !os.IsNotExist(err) {
+		log.Warnf("Failed to read cache entry %s: %v", key, err)
+	}
+
+	c.misses.Add(1)
+	var converted bytes.Buffer
+	if err := safeConvert(convertFunc, bytes.NewReader(data), io.MultiWriter(output, &converted)); err != nil {
+		return err
+	}
+	if err := c.store(key, converted.Bytes()); err != nil {
+		log.Warnf("Failed to store cache entry %s: %v", key, err)
+	}
+	return nil
+}
+
+// store writes the entry of key, through a temporary file so concurrent runs never read a partial one
+func (c *ConversionCache) store(key string, data []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
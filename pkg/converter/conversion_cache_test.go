@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// TestConversionCache tests that unchanged inputs are copied from the cache instead of converted again
+func TestConversionCache(t *testing.T) {
+	fromDir := t.TempDir()
+	setupTestFiles(t, fromDir, ".data", "data")
+	cacheDir := t.TempDir()
+
+	graphicsConverter := NewGraphicsConverter()
+	var conversions atomic.Int64
+	convertFunc := func(input io.Reader, output io.Writer) error {
+		conversions.Add(1)
+		return graphicsConverter.DataToPng(input, output)
+	}
+
+	// run converts fromDir into a new directory with a cache opened with options
+	run := func(options map[string]string) (string, *ConversionCache) {
+		cache, err := NewConversionCache(cacheDir, options)
+		if err != nil {
+			t.Fatalf("NewConversionCache failed: %v", err)
+		}
+		toDir := t.TempDir()
+		conversions.Store(0)
+		filesConverter := NewFilesConverter(graphicsConverter, WithCache(cache))
+		if err := filesConverter.Convert(fromDir, toDir, ".data", ".png", convertFunc); err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		return toDir, cache
+	}
+
+	firstDir, cache := run(map[string]string{"png-compression": "best"})
+	if hits, misses := cache.Stats(); hits != 0 || misses != int64(len(testImages)) || conversions.Load() != misses {
+		t.Fatalf("Expected every file to be converted, got %d hits, %d misses, %d conversions", hits, misses, conversions.Load())
+	}
+
+	secondDir, cache := run(map[string]string{"png-compression": "best"})
+	if hits, misses := cache.Stats(); hits != int64(len(testImages)) || misses != 0 || conversions.Load() != 0 {
+		t.Errorf("Expected every file to come from the cache, got %d hits, %d misses, %d conversions", hits, misses, conversions.Load())
+	}
+	for _, name := range testImages {
+		first, err := os.ReadFile(filepath.Join(firstDir, name+".png"))
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		second, err := os.ReadFile(filepath.Join(secondDir, name+".png"))
+		if err != nil {
+			t.Fatalf("Failed to read cached output: %v", err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Errorf("%s.png from the cache differs", name)
+		}
+	}
+
+	// Other settings and changed inputs miss
+	if _, cache := run(map[string]string{"png-compression": "speed"}); conversions.Load() != int64(len(testImages)) {
+		hits, misses := cache.Stats()
+		t.Errorf("Expected other options to miss, got %d hits, %d misses", hits, misses)
+	}
+	var changed bytes.Buffer
+	if err := EncodeData(&changed, image.NewNRGBA(image.Rect(0, 0, 2, 3))); err != nil {
+		t.Fatalf("EncodeData failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(fromDir, testImages[0]+".data"), changed.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to change input: %v", err)
+	}
+	if _, cache := run(map[string]string{"png-compression": "best"}); conversions.Load() != 1 {
+		hits, misses := cache.Stats()
+		t.Errorf("Expected only the changed input to miss, got %d hits, %d misses", hits, misses)
+	}
+}
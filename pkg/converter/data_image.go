@@ -0,0 +1,74 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// DecodeData reads a texture in Celeste's DATA format with the default settings.
+// The image is an *image.NRGBA with straight alpha, anchored at the origin.
+func DecodeData(r io.Reader) (image.Image, error) {
+	return NewGraphicsConverter().DecodeData(r)
+}
+
+// EncodeData writes img in Celeste's DATA format with the default settings
+func EncodeData(w io.Writer, img image.Image) error {
+	return NewGraphicsConverter().EncodeData(w, img)
+}
+
+// DataHeader is the header at the start of every DATA texture
+type DataHeader struct {
+	Width, Height int32
+	AlphaFlag     int32 // Nonzero if runs store alpha
+}
+
+// HasAlpha reports whether the texture's runs store alpha
+func (h DataHeader) HasAlpha() bool {
+	return h.AlphaFlag != 0
+}
+
+// readDataHeader reads the header of a DATA stream, detecting big-endian headers
+func readDataHeader(r io.Reader) (DataHeader, error) {
+	header, _, err := readDataHeaderIn(r, EndianAuto, DefaultMaxDimension)
+	return header, err
+}
+
+// ReadDataHeader reads the header of a DATA texture in the converter's endianness without decoding
+// its pixels, rejecting invalid alpha flags in strict mode
+func (g *GraphicsConverter) ReadDataHeader(r io.Reader) (DataHeader, error) {
+	header, _, err := g.readHeader(r)
+	if err != nil {
+		return header, err
+	}
+	return header, g.checkAlphaFlag(header.AlphaFlag)
+}
+
+// DecodeDataConfig reads the size of a DATA texture from its header without decoding the pixels
+func DecodeDataConfig(r io.Reader) (image.Config, error) {
+	header, err := readDataHeader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if header.Width < 0 || header.Height < 0 {
+		return image.Config{}, fmt.Errorf("invalid DATA image size %dx%d", header.Width, header.Height)
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: int(header.Width), Height: int(header.Height)}, nil
+}
+
+// DecodeData reads a texture in Celeste's DATA format, applying the converter's alpha mode and size limits.
+// The image is an *image.NRGBA with straight alpha, anchored at the origin.
+func (g *GraphicsConverter) DecodeData(r io.Reader) (image.Image, error) {
+	img, err := g.decodeData(r)
+	if err != nil {
+		return nil, err // Not a typed nil *image.NRGBA
+	}
+	return img, nil
+}
+
+// EncodeData writes img in Celeste's DATA format, applying the converter's alpha mode and run length.
+// Any image type is accepted; images not anchored at the origin are written from their top-left corner.
+func (g *GraphicsConverter) EncodeData(w io.Writer, img image.Image) error {
+	return g.encodeData(img, w)
+}
@@ -0,0 +1,51 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+	"testing"
+)
+
+// TestDecodeEncodeData tests working with decoded textures directly, without PNG in between
+func TestDecodeEncodeData(t *testing.T) {
+	dataBytes := readTestResource(t, filepath.Join("data", "multi-color.data"))
+	img, err := DecodeData(bytes.NewReader(dataBytes))
+	if err != nil {
+		t.Fatalf("DecodeData failed: %v", err)
+	}
+	if _, ok := img.(*image.NRGBA); !ok {
+		t.Fatalf("Expected *image.NRGBA, got %T", img)
+	}
+	assertImageEquals(t, bytesToImage(t, dataToPngBytes(t, NewGraphicsConverter(), dataBytes)), img, 0)
+
+	var encoded bytes.Buffer
+	if err := EncodeData(&encoded, img); err != nil {
+		t.Fatalf("EncodeData failed: %v", err)
+	}
+	roundTrip, err := DecodeData(&encoded)
+	if err != nil {
+		t.Fatalf("DecodeData failed: %v", err)
+	}
+	assertImageEquals(t, img, roundTrip, 0)
+
+	// Crop and composite, then write the result back
+	bounds := img.Bounds()
+	crop := image.NewRGBA(image.Rect(0, 0, bounds.Dx()/2, bounds.Dy()/2))
+	draw.Draw(crop, crop.Bounds(), img, bounds.Min, draw.Src)
+	crop.Set(0, 0, color.RGBA{R: 255, A: 255})
+	if err := EncodeData(&encoded, crop); err != nil {
+		t.Fatalf("EncodeData failed: %v", err)
+	}
+	decoded, err := DecodeData(&encoded)
+	if err != nil {
+		t.Fatalf("DecodeData failed: %v", err)
+	}
+	assertImageEquals(t, crop, decoded, 0)
+
+	if img, err := DecodeData(bytes.NewReader(dataBytes[:4])); err == nil || img != nil {
+		t.Errorf("Expected nil image and error for truncated header, got %v, %v", img, err)
+	}
+}
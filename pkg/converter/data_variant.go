@@ -0,0 +1,171 @@
+package converter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Endianness selects the byte order of DATA header fields. The PC release writes them little-endian,
+// while some console dumps and third-party packers write them big-endian.
+type Endianness int
+
+const (
+	// EndianAuto reads headers as little-endian, unless only the big-endian reading gives valid
+	// dimensions and alpha flag. Encoding writes little-endian headers.
+	EndianAuto Endianness = iota
+	// EndianLittle reads and writes little-endian headers, as the PC release does
+	EndianLittle
+	// EndianBig reads and writes big-endian headers
+	EndianBig
+)
+
+// endiannessNames maps the names accepted by ParseEndianness to endiannesses
+var endiannessNames = map[string]Endianness{
+	"auto":   EndianAuto,
+	"little": EndianLittle,
+	"big":    EndianBig,
+}
+
+// ParseEndianness parses an endianness name: auto, little or big
+func ParseEndianness(name string) (Endianness, error) {
+	endianness, ok := endiannessNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown endianness '%s', expected auto, little or big", name)
+	}
+	return endianness, nil
+}
+
+// String returns the name of an endianness
+func (e Endianness) String() string {
+	for name, endianness := range endiannessNames {
+		if endianness == e {
+			return name
+		}
+	}
+	return fmt.Sprintf("Endianness(%d)", int(e))
+}
+
+// ChannelOrder selects the order of the color channels stored in DATA runs
+type ChannelOrder int
+
+const (
+	// ChannelsBGR stores blue, green then red, as the PC release does
+	ChannelsBGR ChannelOrder = iota
+	// ChannelsRGB stores red, green then blue, as some console dumps do
+	ChannelsRGB
+)
+
+// channelOrderNames maps the names accepted by ParseChannelOrder to channel orders
+var channelOrderNames = map[string]ChannelOrder{
+	"bgr": ChannelsBGR,
+	"rgb": ChannelsRGB,
+}
+
+// ParseChannelOrder parses a channel order name: bgr or rgb
+func ParseChannelOrder(name string) (ChannelOrder, error) {
+	order, ok := channelOrderNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown channel order '%s', expected bgr or rgb", name)
+	}
+	return order, nil
+}
+
+// String returns the name of a channel order
+func (o ChannelOrder) String() string {
+	for name, order := range channelOrderNames {
+		if order == o {
+			return name
+		}
+	}
+	return fmt.Sprintf("ChannelOrder(%d)", int(o))
+}
+
+// SetEndianness sets the byte order of DATA headers; the default EndianAuto detects big-endian headers
+// when reading and writes little-endian ones
+func (g *GraphicsConverter) SetEndianness(endianness Endianness) {
+	g.endianness = endianness
+}
+
+// SetChannelOrder sets the order of the color channels of DATA runs, in both directions; the default
+// is ChannelsBGR. Both orders are equally valid in any file, so this is never detected.
+func (g *GraphicsConverter) SetChannelOrder(order ChannelOrder) {
+	g.channelOrder = order
+}
+
+// headerByteOrder returns the byte order DATA headers are written in
+func (g *GraphicsConverter) headerByteOrder() binary.ByteOrder {
+	if g.endianness == EndianBig {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// readHeader reads the header of a DATA stream in the converter's endianness, returning the byte
+// order it was read in
+func (g *GraphicsConverter) readHeader(r io.Reader) (DataHeader, binary.ByteOrder, error) {
+	header, order, err := readDataHeaderIn(r, g.endianness, g.maxDimension)
+	if err == nil && order == binary.BigEndian && g.endianness == EndianAuto {
+		g.log.Debugf("Detected a big-endian DATA header")
+	}
+	return header, order, err
+}
+
+// readDataHeaderIn reads the header of a DATA stream in the byte order picked for endianness,
+// returning that order
+func readDataHeaderIn(r io.Reader, endianness Endianness, maxDimension int) (DataHeader, binary.ByteOrder, error) {
+	var buf [dataHeaderSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return DataHeader{}, binary.LittleEndian, err
+	}
+	order := dataHeaderByteOrder(buf[:], endianness, maxDimension)
+	return parseDataHeader(buf[:], order), order, nil
+}
+
+// dataHeaderByteOrder picks the byte order of a raw DATA header for endianness. Automatically, it is
+// big-endian only if the little-endian dimensions are invalid while the big-endian reading has valid
+// dimensions and a 0 or 1 alpha flag, so lenient decoding of odd alpha flags isn't affected.
+func dataHeaderByteOrder(buf []byte, endianness Endianness, maxDimension int) binary.ByteOrder {
+	switch endianness {
+	case EndianLittle:
+		return binary.LittleEndian
+	case EndianBig:
+		return binary.BigEndian
+	}
+	little := parseDataHeader(buf, binary.LittleEndian)
+	big := parseDataHeader(buf, binary.BigEndian)
+	if !validDimensions(little, maxDimension) && validDimensions(big, maxDimension) && (big.AlphaFlag == 0 || big.AlphaFlag == 1) {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// parseDataHeader decodes a raw DATA header in order
+func parseDataHeader(buf []byte, order binary.ByteOrder) DataHeader {
+	return DataHeader{
+		Width:     int32(order.Uint32(buf[0:])),
+		Height:    int32(order.Uint32(buf[4:])),
+		AlphaFlag: int32(order.Uint32(buf[8:])),
+	}
+}
+
+// validDimensions reports whether a header's dimensions are positive and at most maxDimension
+func validDimensions(header DataHeader, maxDimension int) bool {
+	return header.Width > 0 && header.Height > 0 && int(header.Width) <= maxDimension && int(header.Height) <= maxDimension
+}
+
+// swapRedBlue swaps the red and blue channels of RGBA pixels, between the BGR and RGB channel orders
+func swapRedBlue(pix []byte) {
+	for p := 0; p+2 < len(pix); p += 4 {
+		pix[p], pix[p+2] = pix[p+2], pix[p]
+	}
+}
+
+// swappedRows returns a rowReader reading the rows of read with red and blue swapped
+func swappedRows(read rowReader) rowReader {
+	return func(y int, row []byte) {
+		read(y, row)
+		swapRedBlue(row)
+	}
+}
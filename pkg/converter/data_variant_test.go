@@ -0,0 +1,146 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseEndianness(t *testing.T) {
+	for name, want := range endiannessNames {
+		if endianness, err := ParseEndianness(name); err != nil || endianness != want || endianness.String() != name {
+			t.Errorf("ParseEndianness(%q) = %v, %v", name, endianness, err)
+		}
+	}
+	if _, err := ParseEndianness("middle"); err == nil {
+		t.Error("Expected an unknown endianness to be rejected")
+	}
+}
+
+func TestParseChannelOrder(t *testing.T) {
+	for name, want := range channelOrderNames {
+		if order, err := ParseChannelOrder(name); err != nil || order != want || order.String() != name {
+			t.Errorf("ParseChannelOrder(%q) = %v, %v", name, order, err)
+		}
+	}
+	if _, err := ParseChannelOrder("grb"); err == nil {
+		t.Error("Expected an unknown channel order to be rejected")
+	}
+}
+
+// TestBigEndianHeader tests writing big-endian headers and detecting them when reading
+func TestBigEndianHeader(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	img.SetNRGBA(1, 1, color.NRGBA{G: 255, A: 128})
+
+	var data bytes.Buffer
+	if err := NewGraphicsConverter(WithEndianness(EndianBig)).EncodeData(&data, img); err != nil {
+		t.Fatalf("EncodeData failed: %v", err)
+	}
+	if width, alpha := binary.BigEndian.Uint32(data.Bytes()[0:]), binary.BigEndian.Uint32(data.Bytes()[8:]); width != 3 || alpha != 1 {
+		t.Fatalf("Expected a big-endian header, got width %d and alpha flag %d", width, alpha)
+	}
+
+	decoded, err := DecodeData(bytes.NewReader(data.Bytes()))
+	if err != nil {
+		t.Fatalf("DecodeData failed to detect the big-endian header: %v", err)
+	}
+	assertImageEquals(t, img, decoded, 0)
+
+	config, err := DecodeDataConfig(bytes.NewReader(data.Bytes()))
+	if err != nil || config.Width != 3 || config.Height != 2 {
+		t.Errorf("DecodeDataConfig = %+v, %v, expected 3x2", config, err)
+	}
+	if ext, err := SniffFormat(bytes.NewReader(data.Bytes())); err != nil || ext != ".data" {
+		t.Errorf("SniffFormat = %q, %v, expected .data", ext, err)
+	}
+	if _, err := NewGraphicsConverter().ValidateData(bytes.NewReader(data.Bytes())); err != nil {
+		t.Errorf("ValidateData failed: %v", err)
+	}
+	if _, err := NewGraphicsConverter(WithEndianness(EndianLittle)).DecodeData(bytes.NewReader(data.Bytes())); err == nil {
+		t.Error("Expected forced little-endian decoding to reject the big-endian header")
+	}
+
+	// Repairs keep the byte order
+	var repaired bytes.Buffer
+	if _, err := NewGraphicsConverter().RepairData(bytes.NewReader(data.Bytes()), &repaired); err != nil {
+		t.Fatalf("RepairData failed: %v", err)
+	}
+	if !bytes.Equal(repaired.Bytes(), data.Bytes()) {
+		t.Error("Expected the repaired file to be unchanged")
+	}
+}
+
+// TestAutoEndiannessPrefersLittle tests that detection keeps little-endian headers with valid
+// dimensions, even with an odd alpha flag
+func TestAutoEndiannessPrefersLittle(t *testing.T) {
+	header := make([]byte, dataHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:], 256)
+	binary.LittleEndian.PutUint32(header[4:], 1)
+	binary.LittleEndian.PutUint32(header[8:], 65280)
+	if order := dataHeaderByteOrder(header, EndianAuto, DefaultMaxDimension); order != binary.LittleEndian {
+		t.Errorf("Expected little-endian, got %v", order)
+	}
+
+	// Invalid either way stays little-endian, so the error reports the usual reading
+	binary.LittleEndian.PutUint32(header[0:], 0)
+	if order := dataHeaderByteOrder(header, EndianAuto, DefaultMaxDimension); order != binary.LittleEndian {
+		t.Errorf("Expected little-endian, got %v", order)
+	}
+}
+
+// TestChannelOrderRGB tests that runs are written and read in RGB order, sequentially and in parallel
+func TestChannelOrderRGB(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+	img.SetNRGBA(1, 0, color.NRGBA{R: 40, G: 50, B: 60, A: 255})
+
+	rgb := NewGraphicsConverter(WithChannelOrder(ChannelsRGB))
+	var data bytes.Buffer
+	if err := rgb.EncodeData(&data, img); err != nil {
+		t.Fatalf("EncodeData failed: %v", err)
+	}
+	if runs := data.Bytes()[dataHeaderSize:]; !bytes.Equal(runs, []byte{1, 10, 20, 30, 1, 40, 50, 60}) {
+		t.Errorf("Expected RGB runs, got %v", runs)
+	}
+	decoded, err := rgb.DecodeData(bytes.NewReader(data.Bytes()))
+	if err != nil {
+		t.Fatalf("DecodeData failed: %v", err)
+	}
+	assertImageEquals(t, img, decoded, 0)
+
+	// Read as BGR, red and blue trade places
+	swapped, err := DecodeData(bytes.NewReader(data.Bytes()))
+	if err != nil {
+		t.Fatalf("DecodeData failed: %v", err)
+	}
+	if c := swapped.(*image.NRGBA).NRGBAAt(0, 0); c != (color.NRGBA{R: 30, G: 20, B: 10, A: 255}) {
+		t.Errorf("Expected swapped channels, got %v", c)
+	}
+
+	large := parallelTestImage()
+	var expected, actual bytes.Buffer
+	if err := rgb.EncodeData(&expected, large); err != nil {
+		t.Fatalf("EncodeData failed: %v", err)
+	}
+	parallel := NewGraphicsConverter(WithChannelOrder(ChannelsRGB))
+	parallel.SetImageWorkers(4)
+	if err := parallel.EncodeData(&actual, large); err != nil {
+		t.Fatalf("EncodeData failed: %v", err)
+	}
+	if !bytes.Equal(actual.Bytes(), expected.Bytes()) {
+		t.Error("Parallel RGB encoding differs")
+	}
+	sequentialImg, err := rgb.DecodeData(bytes.NewReader(expected.Bytes()))
+	if err != nil {
+		t.Fatalf("DecodeData failed: %v", err)
+	}
+	parallelImg, err := parallel.DecodeData(bytes.NewReader(expected.Bytes()))
+	if err != nil {
+		t.Fatalf("DecodeData failed: %v", err)
+	}
+	assertImageEquals(t, sequentialImg, parallelImg, 0)
+}
@@ -0,0 +1,350 @@
+package converter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"io"
+	"math"
+)
+
+// DDS header flags and capabilities, see https://learn.microsoft.com/windows/win32/direct3ddds/dds-header
+const (
+	ddsFlagCaps        = 0x1
+	ddsFlagHeight      = 0x2
+	ddsFlagWidth       = 0x4
+	ddsFlagPitch       = 0x8
+	ddsFlagPixelFormat = 0x1000
+	ddsFlagLinearSize  = 0x80000
+
+	ddsPixelAlpha  = 0x1
+	ddsPixelFourCC = 0x4
+	ddsPixelRGB    = 0x40
+
+	ddsCapsTexture = 0x1000
+)
+
+// DDS_HEADER_DXT10 values for BC7 textures
+const (
+	dxgiFormatBc7Unorm    = 98
+	d3d10DimensionTexture = 3
+	ddsAlphaModeStraight  = 1
+)
+
+// ddsHeader is the magic and DDS_HEADER, including its DDS_PIXELFORMAT
+type ddsHeader struct {
+	Magic             [4]byte
+	Size              uint32
+	Flags             uint32
+	Height            uint32
+	Width             uint32
+	PitchOrLinearSize uint32
+	Depth             uint32
+	MipMapCount       uint32
+	Reserved1         [11]uint32
+
+	PixelFormatSize uint32
+	PixelFlags      uint32
+	FourCC          [4]byte
+	RGBBitCount     uint32
+	Masks           [4]uint32 // Red, green, blue and alpha
+
+	Caps      [4]uint32
+	Reserved2 uint32
+}
+
+// newDdsHeader returns the header of a single-level 2D texture of the given size
+func newDdsHeader(width, height int) ddsHeader {
+	return ddsHeader{
+		Magic:           [4]byte{'D', 'D', 'S', ' '},
+		Size:            124,
+		Flags:           ddsFlagCaps | ddsFlagHeight | ddsFlagWidth | ddsFlagPixelFormat,
+		Height:          uint32(height),
+		Width:           uint32(width),
+		PixelFormatSize: 32,
+		Caps:            [4]uint32{ddsCapsTexture},
+	}
+}
+
+// encodeDds writes img as an uncompressed 32-bit DDS texture in B8G8R8A8 order, which every DDS reader
+// supports
+func encodeDds(output io.Writer, img image.Image) error {
+	nrgba := toNRGBA(img)
+	width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+
+	w := bufio.NewWriterSize(output, dataBufferSize)
+	header := newDdsHeader(width, height)
+	header.Flags |= ddsFlagPitch
+	header.PitchOrLinearSize = uint32(width * 4)
+	header.PixelFlags = ddsPixelRGB | ddsPixelAlpha
+	header.RGBBitCount = 32
+	header.Masks = [4]uint32{0x00ff0000, 0x0000ff00, 0x000000ff, 0xff000000}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	row := make([]byte, width*4)
+	for y := 0; y < height; y++ {
+		writeBgraRow(row, nrgba.Pix[y*nrgba.Stride:])
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// encodeDdsBc7 writes img as a BC7-compressed DDS texture with a DX10 header, the format GPU tools and
+// engines use for high-quality RGBA textures. Every 4x4 block is encoded in mode 6, which keeps alpha.
+func encodeDdsBc7(output io.Writer, img image.Image) error {
+	nrgba := toNRGBA(img)
+	width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+	blocksX, blocksY := max(1, (width+3)/4), max(1, (height+3)/4)
+
+	w := bufio.NewWriterSize(output, dataBufferSize)
+	header := newDdsHeader(width, height)
+	header.Flags |= ddsFlagLinearSize
+	header.PitchOrLinearSize = uint32(blocksX * blocksY * 16)
+	header.PixelFlags = ddsPixelFourCC
+	header.FourCC = [4]byte{'D', 'X', '1', '0'}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	dx10 := [5]uint32{dxgiFormatBc7Unorm, d3d10DimensionTexture, 0, 1, ddsAlphaModeStraight}
+	if err := binary.Write(w, binary.LittleEndian, &dx10); err != nil {
+		return err
+	}
+
+	var pixels [16][4]byte
+	for by := 0; by < blocksY; by++ {
+		for bx := 0; bx < blocksX; bx++ {
+			// Blocks past the edge of the image repeat its last row and column
+			for i := range pixels {
+				x := min(bx*4+i%4, width-1)
+				y := min(by*4+i/4, height-1)
+				if x >= 0 && y >= 0 {
+					copy(pixels[i][:], nrgba.Pix[y*nrgba.Stride+x*4:])
+				}
+			}
+			block := encodeBc7Block(&pixels)
+			if _, err := w.Write(block[:]); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// bc7Weights are the interpolation weights of BC7's 4-bit indices, out of 64
+var bc7Weights = [16]int{0, 4, 9, 13, 17, 21, 26, 30, 34, 38, 43, 47, 51, 55, 60, 64}
+
+// bc7Fit is a BC7 mode 6 encoding of a block
+type bc7Fit struct {
+	endpoints [2][4]int // 7-bit channels
+	pBits     [2]int
+	indices   [16]int
+	err       int // Sum of squared channel differences
+}
+
+// encodeBc7Block encodes 16 RGBA pixels, in row order, as a BC7 mode 6 block: two RGBA endpoints of 7
+// bits plus a shared lowest bit each, and a 4-bit palette index per pixel. The endpoints start out
+// spanning the pixels along their principal axis or their bounding box diagonal, and are then refined
+// by least squares. A single segment can't hold more than two unrelated colors, so blocks mixing
+// several come out approximate.
+func encodeBc7Block(pixels *[16][4]byte) [16]byte {
+	best := bc7Fit{err: math.MaxInt}
+	for _, endpoints := range [][2][4]float64{bc7Axis(pixels), bc7Bounds(pixels)} {
+		if fit := fitBc7(pixels, endpoints); fit.err < best.err {
+			best = fit
+		}
+	}
+	for iteration := 0; iteration < 3 && best.err > 0; iteration++ {
+		refined := fitBc7(pixels, refineBc7Endpoints(pixels, best.indices))
+		if refined.err >= best.err {
+			break
+		}
+		best = refined
+	}
+
+	// The first pixel's index is stored without its highest bit, which must therefore be clear
+	if best.indices[0] >= 8 {
+		best.endpoints[0], best.endpoints[1] = best.endpoints[1], best.endpoints[0]
+		best.pBits[0], best.pBits[1] = best.pBits[1], best.pBits[0]
+		for i := range best.indices {
+			best.indices[i] = 15 - best.indices[i]
+		}
+	}
+
+	var packer bitPacker
+	packer.put(1<<6, 7) // Mode 6
+	for c := 0; c < 4; c++ {
+		packer.put(best.endpoints[0][c], 7)
+		packer.put(best.endpoints[1][c], 7)
+	}
+	packer.put(best.pBits[0], 1)
+	packer.put(best.pBits[1], 1)
+	packer.put(best.indices[0], 3)
+	for _, index := range best.indices[1:] {
+		packer.put(index, 4)
+	}
+	return packer.block
+}
+
+// fitBc7 quantizes a pair of endpoints with each combination of lowest bits and returns the one
+// whose palette is closest to the pixels
+func fitBc7(pixels *[16][4]byte, endpoints [2][4]float64) bc7Fit {
+	best := bc7Fit{err: math.MaxInt}
+	for pBits := 0; pBits < 4; pBits++ {
+		fit := bc7Fit{pBits: [2]int{pBits & 1, pBits >> 1}}
+		var palette [16][4]int
+		for c := 0; c < 4; c++ {
+			fit.endpoints[0][c] = quantizeBc7(endpoints[0][c], fit.pBits[0])
+			fit.endpoints[1][c] = quantizeBc7(endpoints[1][c], fit.pBits[1])
+			e0, e1 := fit.endpoints[0][c]<<1|fit.pBits[0], fit.endpoints[1][c]<<1|fit.pBits[1]
+			for i, weight := range bc7Weights {
+				palette[i][c] = ((64-weight)*e0 + weight*e1 + 32) >> 6
+			}
+		}
+
+		for i, px := range pixels {
+			nearest := math.MaxInt
+			for j, entry := range palette {
+				dist := 0
+				for c := 0; c < 4; c++ {
+					d := int(px[c]) - entry[c]
+					dist += d * d
+				}
+				if dist < nearest {
+					nearest, fit.indices[i] = dist, j
+				}
+			}
+			fit.err += nearest
+		}
+		if fit.err < best.err {
+			best = fit
+		}
+	}
+	return best
+}
+
+// refineBc7Endpoints returns the endpoints whose interpolation at the weights of indices is closest to
+// the pixels, by least squares
+func refineBc7Endpoints(pixels *[16][4]byte, indices [16]int) (endpoints [2][4]float64) {
+	var aa, ab, bb float64
+	var ax, bx [4]float64
+	for i, px := range pixels {
+		w := float64(bc7Weights[indices[i]]) / 64
+		a, b := 1-w, w
+		aa, ab, bb = aa+a*a, ab+a*b, bb+b*b
+		for c := 0; c < 4; c++ {
+			ax[c] += a * float64(px[c])
+			bx[c] += b * float64(px[c])
+		}
+	}
+	det := aa*bb - ab*ab
+	if det == 0 {
+		// Every pixel uses the same index, so a single color fits them all
+		for c := 0; c < 4; c++ {
+			endpoints[0][c] = (ax[c] + bx[c]) / 16
+			endpoints[1][c] = endpoints[0][c]
+		}
+		return endpoints
+	}
+	for c := 0; c < 4; c++ {
+		endpoints[0][c] = math.Max(0, math.Min(255, (bb*ax[c]-ab*bx[c])/det))
+		endpoints[1][c] = math.Max(0, math.Min(255, (aa*bx[c]-ab*ax[c])/det))
+	}
+	return endpoints
+}
+
+// bc7Axis returns the two ends of the segment covering the pixels along their principal axis
+func bc7Axis(pixels *[16][4]byte) (endpoints [2][4]float64) {
+	var mean [4]float64
+	for _, px := range pixels {
+		for c := range mean {
+			mean[c] += float64(px[c]) / 16
+		}
+	}
+	var cov [4][4]float64
+	for _, px := range pixels {
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				cov[i][j] += (float64(px[i]) - mean[i]) * (float64(px[j]) - mean[j])
+			}
+		}
+	}
+
+	// Power iteration, starting from the bounding box diagonal
+	var axis [4]float64
+	for c := range axis {
+		lo, hi := 255.0, 0.0
+		for _, px := range pixels {
+			lo, hi = math.Min(lo, float64(px[c])), math.Max(hi, float64(px[c]))
+		}
+		axis[c] = hi - lo
+	}
+	for iteration := 0; iteration < 8; iteration++ {
+		var next [4]float64
+		length := 0.0
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				next[i] += cov[i][j] * axis[j]
+			}
+			length += next[i] * next[i]
+		}
+		if length == 0 {
+			break
+		}
+		length = math.Sqrt(length)
+		for i := range axis {
+			axis[i] = next[i] / length
+		}
+	}
+
+	minT, maxT := 0.0, 0.0
+	for _, px := range pixels {
+		t := 0.0
+		for c := range axis {
+			t += (float64(px[c]) - mean[c]) * axis[c]
+		}
+		minT, maxT = math.Min(minT, t), math.Max(maxT, t)
+	}
+	for c := range axis {
+		endpoints[0][c] = math.Max(0, math.Min(255, mean[c]+minT*axis[c]))
+		endpoints[1][c] = math.Max(0, math.Min(255, mean[c]+maxT*axis[c]))
+	}
+	return endpoints
+}
+
+// bc7Bounds returns the lowest and highest value of each channel, a segment that suits blocks of a few
+// colors better than the principal axis when their channels rise together
+func bc7Bounds(pixels *[16][4]byte) (endpoints [2][4]float64) {
+	for c := 0; c < 4; c++ {
+		endpoints[0][c], endpoints[1][c] = 255, 0
+		for _, px := range pixels {
+			endpoints[0][c] = math.Min(endpoints[0][c], float64(px[c]))
+			endpoints[1][c] = math.Max(endpoints[1][c], float64(px[c]))
+		}
+	}
+	return endpoints
+}
+
+// quantizeBc7 returns the 7-bit value that, with lowest bit p, comes closest to the 8-bit value v
+func quantizeBc7(v float64, p int) int {
+	return max(0, min(127, int(math.Round((v-float64(p))/2))))
+}
+
+// bitPacker fills a 128-bit block from its lowest bit up, as BC7 stores its fields
+type bitPacker struct {
+	block [16]byte
+	pos   int
+}
+
+// put appends the lowest bits of value
+func (b *bitPacker) put(value, bits int) {
+	for i := 0; i < bits; i++ {
+		if value>>i&1 != 0 {
+			b.block[b.pos/8] |= 1 << (b.pos % 8)
+		}
+		b.pos++
+	}
+}
@@ -0,0 +1,123 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// decodeDds is a minimal decoder of the DDS files written by encodeDds and encodeDdsBc7
+func decodeDds(t *testing.T, data []byte) *image.NRGBA {
+	var header ddsHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &header); err != nil || string(header.Magic[:]) != "DDS " || header.Size != 124 {
+		t.Fatalf("Missing DDS header: %v", err)
+	}
+	width, height := int(header.Width), int(header.Height)
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	pix := data[128:]
+
+	if header.PixelFlags&ddsPixelFourCC == 0 {
+		if header.PitchOrLinearSize != uint32(width*4) || len(pix) != width*height*4 {
+			t.Fatalf("Unexpected pitch %d or size %d", header.PitchOrLinearSize, len(pix))
+		}
+		for i := 0; i < len(pix); i += 4 {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = pix[i+2], pix[i+1], pix[i], pix[i+3]
+		}
+		return img
+	}
+
+	if string(header.FourCC[:]) != "DX10" || binary.LittleEndian.Uint32(pix) != dxgiFormatBc7Unorm {
+		t.Fatalf("Expected a BC7 DX10 header, got %q", header.FourCC)
+	}
+	blocks := pix[20:]
+	blocksX, blocksY := (width+3)/4, (height+3)/4
+	if len(blocks) != blocksX*blocksY*16 || header.PitchOrLinearSize != uint32(len(blocks)) {
+		t.Fatalf("Expected %d blocks, got %d bytes", blocksX*blocksY, len(blocks))
+	}
+	for by := 0; by < blocksY; by++ {
+		for bx := 0; bx < blocksX; bx++ {
+			decoded := decodeBc7Mode6(t, blocks[(by*blocksX+bx)*16:])
+			for i, px := range decoded {
+				if x, y := bx*4+i%4, by*4+i/4; x < width && y < height {
+					img.SetNRGBA(x, y, color.NRGBA{px[0], px[1], px[2], px[3]})
+				}
+			}
+		}
+	}
+	return img
+}
+
+// decodeBc7Mode6 decodes a BC7 block, which must use mode 6
+func decodeBc7Mode6(t *testing.T, block []byte) [16][4]byte {
+	pos := 0
+	bits := func(n int) int {
+		value := 0
+		for i := 0; i < n; i++ {
+			value |= int(block[pos/8]>>(pos%8)&1) << i
+			pos++
+		}
+		return value
+	}
+	if mode := bits(7); mode != 1<<6 {
+		t.Fatalf("Expected a mode 6 block, got mode bits %07b", mode)
+	}
+	var endpoints [2][4]int
+	for c := 0; c < 4; c++ {
+		endpoints[0][c], endpoints[1][c] = bits(7), bits(7)
+	}
+	p0, p1 := bits(1), bits(1)
+	var pixels [16][4]byte
+	for i := range pixels {
+		index := bits(4)
+		if i == 0 {
+			pos -= 1 // The anchor index has 3 bits
+			index &= 7
+		}
+		for c := 0; c < 4; c++ {
+			e0, e1 := endpoints[0][c]<<1|p0, endpoints[1][c]<<1|p1
+			pixels[i][c] = byte(((64-bc7Weights[index])*e0 + bc7Weights[index]*e1 + 32) >> 6)
+		}
+	}
+	return pixels
+}
+
+// TestEncodeDdsBc7 tests that BC7 blocks stay close to the original pixels, including flat, transparent
+// and partial blocks
+func TestEncodeDdsBc7(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 7))
+	for y := 0; y < 7; y++ {
+		for x := 0; x < 10; x++ {
+			switch {
+			case x < 4 && y < 4:
+				img.SetNRGBA(x, y, color.NRGBA{201, 37, 118, 255}) // Flat
+			case x < 8 && y < 4:
+				// Fully transparent stays fully transparent
+			default:
+				img.SetNRGBA(x, y, color.NRGBA{uint8(x * 25), uint8(255 - x*20), 90, uint8(128 + x*10)}) // Gradient
+			}
+		}
+	}
+
+	var output bytes.Buffer
+	if err := encodeDdsBc7(&output, img); err != nil {
+		t.Fatalf("encodeDdsBc7 failed: %v", err)
+	}
+	decoded := decodeDds(t, output.Bytes())
+	assertImageEquals(t, img, decoded, 4)
+	if got := decoded.NRGBAAt(0, 0); got.A != 255 || absDiff(got.R, 201) > 1 || absDiff(got.G, 37) > 1 || absDiff(got.B, 118) > 1 {
+		t.Errorf("Expected a flat block within 1 of its color, got %v", got)
+	}
+	if got := decoded.NRGBAAt(5, 2); got.A != 0 {
+		t.Errorf("Expected a transparent block to stay transparent, got %v", got)
+	}
+}
+
+// absDiff returns the absolute difference of two channel values
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
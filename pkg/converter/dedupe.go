@@ -0,0 +1,168 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DedupeMode selects what batch conversions do with outputs byte-identical to an earlier output,
+// such as mirrored sprite variants and placeholder frames
+type DedupeMode int
+
+const (
+	// DedupeOff keeps every output as its own file
+	DedupeOff DedupeMode = iota
+	// DedupeHardlink replaces duplicates with hardlinks to the first output with their content
+	DedupeHardlink
+	// DedupeManifest removes duplicates and records which output each one duplicates in DedupeFileName
+	DedupeManifest
+)
+
+// dedupeModes maps the names accepted by ParseDedupeMode to dedupe modes
+var dedupeModes = map[string]DedupeMode{
+	"off":      DedupeOff,
+	"hardlink": DedupeHardlink,
+	"manifest": DedupeManifest,
+}
+
+// ParseDedupeMode parses a dedupe mode name: off, hardlink or manifest
+func ParseDedupeMode(name string) (DedupeMode, error) {
+	mode, ok := dedupeModes[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown dedupe mode '%s', expected off, hardlink or manifest", name)
+	}
+	return mode, nil
+}
+
+// String returns the name of a dedupe mode
+func (m DedupeMode) String() string {
+	for name, mode := range dedupeModes {
+		if mode == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("DedupeMode(%d)", int(m))
+}
+
+// DedupeFileName is the manifest of removed duplicates written to the output directory root by DedupeManifest
+const DedupeFileName = "dedupe.json"
+
+// DuplicateManifest lists the outputs removed as duplicates
+type DuplicateManifest struct {
+	// Duplicates maps each removed output to the output with the same content, both slash-separated
+	// and relative to the output directory
+	Duplicates map[string]string `json:"duplicates"`
+}
+
+// SetDedupe sets what batch conversions do with identical outputs once every file is converted,
+// DedupeOff by default. Deduplication needs a directory output, not a zip archive.
+func (f *FilesConverter) SetDedupe(mode DedupeMode) {
+	f.dedupe = mode
+}
+
+// dedupeOutputs hashes the outputs of the tasks that succeeded and deduplicates them in task order,
+// so the first output with some content is the one kept
+func (f *FilesConverter) dedupeOutputs(tasks []ConversionTask, failures []error, toDir string) error {
+	var manifest *DuplicateManifest
+	if f.dedupe == DedupeManifest {
+		var err error
+		if manifest, err = readDedupeManifest(toDir); err != nil {
+			return err
+		}
+	}
+
+	originals := make(map[string]string) // Content hash to the first output with it
+	duplicates, saved := 0, int64(0)
+	for _, task := range tasks {
+		if failures[task.index-1] != nil {
+			continue
+		}
+		hash, err := hashFile(task.outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to hash '%s': %w", task.outputPath, err)
+		}
+		original, ok := originals[hash]
+		if !ok {
+			originals[hash] = task.outputPath
+			continue
+		}
+
+		info, err := os.Stat(task.outputPath)
+		if err != nil {
+			return err
+		}
+		if f.dedupe == DedupeHardlink {
+			if err := replaceWithHardlink(original, task.outputPath); err != nil {
+				f.log.Warnf("Keeping duplicate %s: %v", task.outputPath, err)
+				continue
+			}
+		} else {
+			if err := os.Remove(task.outputPath); err != nil {
+				return fmt.Errorf("failed to remove duplicate '%s': %w", task.outputPath, err)
+			}
+			manifest.Duplicates[relSlash(toDir, task.outputPath)] = relSlash(toDir, original)
+		}
+		f.log.Debugf("%s duplicates %s", task.outputPath, original)
+		duplicates++
+		saved += info.Size()
+	}
+
+	if manifest != nil && len(manifest.Duplicates) > 0 {
+		if err := writeJSONOutput(dirSink{}, filepath.Join(toDir, DedupeFileName), manifest); err != nil {
+			return fmt.Errorf("failed to write dedupe manifest: %w", err)
+		}
+	}
+	f.log.Infof("Deduplicated %d outputs, saving %d bytes", duplicates, saved)
+	return nil
+}
+
+// readDedupeManifest reads the dedupe manifest of an earlier run into toDir, dropping duplicates that
+// have since been written again, or returns an empty manifest if there is none
+func readDedupeManifest(toDir string) (*DuplicateManifest, error) {
+	manifest := &DuplicateManifest{Duplicates: make(map[string]string)}
+	data, err := os.ReadFile(filepath.Join(toDir, DedupeFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid dedupe manifest: %w", err)
+	}
+	if manifest.Duplicates == nil {
+		manifest.Duplicates = make(map[string]string)
+	}
+	for duplicate := range manifest.Duplicates {
+		if fileExists(filepath.Join(toDir, filepath.FromSlash(duplicate))) {
+			delete(manifest.Duplicates, duplicate)
+		}
+	}
+	return manifest, nil
+}
+
+// replaceWithHardlink replaces path with a hardlink to original, leaving path as it was on failure
+func replaceWithHardlink(original, path string) error {
+	temp := path + ".dedupe"
+	if err := os.Link(original, temp); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+// relSlash returns path relative to dir with forward slashes, or path itself if it isn't below dir
+func relSlash(dir, path string) string {
+	relPath, err := filepath.Rel(dir, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(relPath)
+}
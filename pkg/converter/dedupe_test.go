@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setupDuplicateFiles creates a source directory where a.data and sub/copy.data are identical
+func setupDuplicateFiles(t *testing.T) string {
+	fromDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(fromDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "a.data"))
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "sub", "copy.data"))
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(fromDir, "b.data"))
+	return fromDir
+}
+
+// TestDedupeHardlink tests that identical outputs become hardlinks to the first one
+func TestDedupeHardlink(t *testing.T) {
+	fromDir := setupDuplicateFiles(t)
+	toDir := t.TempDir()
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetDedupe(DedupeHardlink)
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	a, errA := os.Stat(filepath.Join(toDir, "a.png"))
+	duplicate, errCopy := os.Stat(filepath.Join(toDir, "sub", "copy.png"))
+	b, errB := os.Stat(filepath.Join(toDir, "b.png"))
+	if errA != nil || errCopy != nil || errB != nil {
+		t.Fatalf("Expected every output to exist: %v, %v, %v", errA, errCopy, errB)
+	}
+	if !os.SameFile(a, duplicate) {
+		t.Error("Expected sub/copy.png to be a hardlink to a.png")
+	}
+	if os.SameFile(a, b) {
+		t.Error("Expected b.png to stay a separate file")
+	}
+}
+
+// TestDedupeManifest tests that identical outputs are removed and recorded, across runs
+func TestDedupeManifest(t *testing.T) {
+	fromDir := setupDuplicateFiles(t)
+	toDir := t.TempDir()
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetDedupe(DedupeManifest)
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(toDir, "sub", "copy.png")); !os.IsNotExist(err) {
+		t.Error("Expected sub/copy.png to be removed")
+	}
+	var manifest DuplicateManifest
+	if err := json.Unmarshal(readFile(t, filepath.Join(toDir, DedupeFileName)), &manifest); err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	want := map[string]string{"sub/copy.png": "a.png"}
+	if !reflect.DeepEqual(manifest.Duplicates, want) {
+		t.Errorf("Expected duplicates %v, got %v", want, manifest.Duplicates)
+	}
+
+	// A later run that only converts b.data keeps the earlier entry
+	if err := os.Remove(filepath.Join(fromDir, "a.data")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(fromDir, "sub", "copy.data")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	manifest = DuplicateManifest{}
+	if err := json.Unmarshal(readFile(t, filepath.Join(toDir, DedupeFileName)), &manifest); err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if !reflect.DeepEqual(manifest.Duplicates, want) {
+		t.Errorf("Expected duplicates %v to be kept, got %v", want, manifest.Duplicates)
+	}
+}
+
+// TestDedupeRejectsZip tests that deduplicating into a zip archive is refused
+func TestDedupeRejectsZip(t *testing.T) {
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetDedupe(DedupeHardlink)
+	if err := filesConverter.DataToPng(setupDuplicateFiles(t), filepath.Join(t.TempDir(), "out.zip")); err == nil {
+		t.Error("Expected deduplicating into a zip archive to fail")
+	}
+}
+
+// TestParseDedupeMode tests parsing dedupe mode names
+func TestParseDedupeMode(t *testing.T) {
+	for name, want := range dedupeModes {
+		if mode, err := ParseDedupeMode(name); err != nil || mode != want || mode.String() != name {
+			t.Errorf("ParseDedupeMode(%q) = %v, %v", name, mode, err)
+		}
+	}
+	if _, err := ParseDedupeMode("symlink"); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+}
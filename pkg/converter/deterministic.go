@@ -0,0 +1,19 @@
+package converter
+
+import "time"
+
+// DeterministicModTime is the modification time of every entry of zip archives written in
+// deterministic mode, the earliest time a zip can hold
+var DeterministicModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SetDeterministic makes batch conversions write byte-identical outputs for the same inputs and
+// options, whatever the worker count, platform or time of the run, for content-addressed pipelines.
+// Zip archives get their entries in name order with DeterministicModTime instead of in completion
+// order with the current time, which means holding every entry in memory until the batch is done.
+//
+// Everything else is deterministic either way: encoders use fixed settings with no timestamps, PNG
+// palettes are built from sorted colors, parallel image bands give the same bytes as a single
+// goroutine, and provenance, manifests and dedupe manifests are sorted by path with slash separators.
+func (f *FilesConverter) SetDeterministic(enabled bool) {
+	f.deterministic = enabled
+}
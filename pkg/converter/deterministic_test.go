@@ -0,0 +1,55 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestDeterministicZip tests that deterministic zip archives are byte-identical across runs, with
+// entries in name order and a fixed modification time
+func TestDeterministicZip(t *testing.T) {
+	fromDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(fromDir, "sprites"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, name := range []string{"red", "blue", "green", "multi-color", "big-test", "transparent"} {
+		copyFile(t, filepath.Join("testdata", "data", name+".data"), filepath.Join(fromDir, "sprites", name+".data"))
+	}
+
+	var archives [][]byte
+	for _, workers := range []int{1, 4} {
+		filesConverter := NewFilesConverter(NewGraphicsConverter(),
+			WithWorkers(workers), WithDeterministic(true), WithProvenance(true, map[string]string{"deterministic": "true"}))
+		zipPath := filepath.Join(t.TempDir(), "out.zip")
+		if err := filesConverter.DataToPng(fromDir, zipPath); err != nil {
+			t.Fatalf("DataToPng failed: %v", err)
+		}
+		archive, err := os.ReadFile(zipPath)
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		archives = append(archives, archive)
+	}
+	if !bytes.Equal(archives[0], archives[1]) {
+		t.Fatal("Expected identical archives with 1 and 4 workers")
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archives[0]), int64(len(archives[0])))
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	var names []string
+	for _, entry := range reader.File {
+		names = append(names, entry.Name)
+		if !entry.Modified.Equal(DeterministicModTime) {
+			t.Errorf("Expected %s to be modified at %v, got %v", entry.Name, DeterministicModTime, entry.Modified)
+		}
+	}
+	if len(names) != 13 || !slices.IsSorted(names) {
+		t.Errorf("Expected 13 entries in name order, got %v", names)
+	}
+}
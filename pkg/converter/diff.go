@@ -0,0 +1,206 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// HeatmapExtension is appended to the name of a texture for its diff heatmap
+const HeatmapExtension = ".diff.png"
+
+// DiffResult describes how a texture differs between the two sides of a diff
+type DiffResult struct {
+	Name          string          // Path relative to the compared directories, without the texture extension
+	PathA, PathB  string          // Files compared, empty for the side the texture is missing from
+	Err           error           // Set when the texture is missing from a side or couldn't be decoded
+	SizeA, SizeB  image.Point     // Dimensions of both sides
+	ChangedPixels int             // Pixels differing by more than the tolerance, including pixels only one side has
+	MaxDelta      int             // Largest per-channel difference
+	MeanDelta     float64         // Mean absolute channel difference
+	Bounds        image.Rectangle // Bounding box of the changed pixels within b, empty if none
+	HeatmapPath   string          // Heatmap written for a changed texture, if requested
+}
+
+// OK reports whether both sides decode to the same pixels within the tolerance
+func (r *DiffResult) OK() bool {
+	return r.Err == nil && r.ChangedPixels == 0
+}
+
+// Diff decodes the textures at a and b, two files or two directory trees in any mix of DATA, PNG,
+// .cdat.zst and WebP, and compares their pixels. In directories textures are paired by relative path
+// without extension, so a re-exported PNG dump can be checked against the DATA files it came from.
+// Channel differences up to tolerance are accepted. When heatmapDir is set, a heatmap of every changed
+// texture is written below it. Results are sorted by name; the returned error is only set if a side
+// can't be scanned.
+func (f *FilesConverter) Diff(a, b string, tolerance int, heatmapDir string) ([]DiffResult, error) {
+	return f.DiffWith(a, b, imagecompare.Options{Tolerance: imagecompare.UniformTolerance(tolerance)}, heatmapDir)
+}
+
+// DiffWith is Diff with a tolerance per channel and optionally alpha-weighted color differences
+func (f *FilesConverter) DiffWith(a, b string, options imagecompare.Options, heatmapDir string) ([]DiffResult, error) {
+	pairs, err := diffPairs(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	f.log.Infof("%d textures to compare", len(pairs))
+
+	results := make([]DiffResult, len(pairs))
+	indexes := make(chan int, len(pairs))
+	for i := range pairs {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < f.maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = f.compare(pairs[i], options, heatmapDir)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
+
+// diffPairs pairs the textures of a and b, by name for directories
+func diffPairs(a, b string) ([]DiffResult, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return nil, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return nil, err
+	}
+	if infoA.IsDir() != infoB.IsDir() {
+		return nil, fmt.Errorf("can't compare '%s' with '%s': both must be files or both directories", a, b)
+	}
+	if !infoA.IsDir() {
+		name := strings.TrimSuffix(filepath.Base(b), fileExtension(b))
+		return []DiffResult{{Name: name, PathA: a, PathB: b}}, nil
+	}
+
+	texturesA, err := texturesByName(a)
+	if err != nil {
+		return nil, err
+	}
+	texturesB, err := texturesByName(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []DiffResult
+	for name, pathsA := range texturesA {
+		pairs = append(pairs, diffPair(name, pathsA, texturesB[name]))
+	}
+	for name, pathsB := range texturesB {
+		if _, ok := texturesA[name]; !ok {
+			pairs = append(pairs, diffPair(name, nil, pathsB))
+		}
+	}
+	return pairs, nil
+}
+
+// diffPair pairs the files named name on both sides, recording why they can't be compared if a side
+// has none or several of them
+func diffPair(name string, pathsA, pathsB []string) DiffResult {
+	pair := DiffResult{Name: name}
+	for _, side := range []struct {
+		label string
+		paths []string
+		path  *string
+	}{{"first", pathsA, &pair.PathA}, {"second", pathsB, &pair.PathB}} {
+		switch {
+		case len(side.paths) == 1:
+			*side.path = side.paths[0]
+		case len(side.paths) == 0:
+			pair.Err = fmt.Errorf("missing from the %s side", side.label)
+		case pair.Err == nil:
+			pair.Err = fmt.Errorf("ambiguous on the %s side: %s", side.label, strings.Join(side.paths, ", "))
+		}
+	}
+	return pair
+}
+
+// texturesByName maps the relative path without extension of every texture below dir to its paths,
+// of which there are several if the texture is there in several formats
+func texturesByName(dir string) (map[string][]string, error) {
+	textures := make(map[string][]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := textureExtension(path)
+		if d.IsDir() || ext == "" {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relPath[:len(relPath)-len(ext)])
+		textures[name] = append(textures[name], path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory '%s': %w", dir, err)
+	}
+	return textures, nil
+}
+
+// compare decodes and compares a pair of textures
+func (f *FilesConverter) compare(result DiffResult, options imagecompare.Options, heatmapDir string) DiffResult {
+	if result.Err != nil {
+		return result
+	}
+
+	imgA, err := f.graphicsConverter.decodeFile(result.PathA)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to decode '%s': %w", result.PathA, err)
+		return result
+	}
+	imgB, err := f.graphicsConverter.decodeFile(result.PathB)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to decode '%s': %w", result.PathB, err)
+		return result
+	}
+
+	result.SizeA, result.SizeB = imgA.Bounds().Size(), imgB.Bounds().Size()
+	diff := imagecompare.CompareWith(imgA, imgB, options)
+	// Compare counts the pixels only b has as changed, add those only a has
+	diff.ChangedPixels += result.SizeA.X*result.SizeA.Y - min(result.SizeA.X, result.SizeB.X)*min(result.SizeA.Y, result.SizeB.Y)
+	result.ChangedPixels, result.MaxDelta, result.MeanDelta = diff.ChangedPixels, diff.MaxDelta, diff.MeanDelta
+	result.Bounds = diff.Bounds
+
+	if heatmapDir != "" && !result.OK() {
+		result.HeatmapPath = filepath.Join(heatmapDir, filepath.FromSlash(result.Name)+HeatmapExtension)
+		if err := f.writeHeatmap(imagecompare.HeatmapWith(imgA, imgB, options), result.HeatmapPath); err != nil {
+			result.Err = fmt.Errorf("failed to write heatmap: %w", err)
+		}
+	}
+	return result
+}
+
+// writeHeatmap writes a heatmap PNG, creating its directory
+func (f *FilesConverter) writeHeatmap(heatmap image.Image, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return f.graphicsConverter.encodeFile(heatmap, path)
+}
@@ -0,0 +1,124 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// TestDiff tests comparing a DATA tree against a re-exported PNG tree
+func TestDiff(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+	heatmapDir := t.TempDir()
+
+	for _, name := range []string{"red", "multi-color"} {
+		copyFile(t, filepath.Join("testdata", "data", name+".data"), filepath.Join(dirA, name+".data"))
+	}
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(dirA, "only-a.data"))
+	if err := os.MkdirAll(filepath.Join(dirB, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "png", "blue.png"), filepath.Join(dirB, "sub", "only-b.png"))
+
+	// red.png is an exact re-export, multi-color.png has one pixel changed
+	redPng := dataToPngBytes(t, graphicsConverter, readTestResource(t, filepath.Join("data", "red.data")))
+	if err := os.WriteFile(filepath.Join(dirB, "red.png"), redPng, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	multiColor := bytesToImage(t, dataToPngBytes(t, graphicsConverter, readTestResource(t, filepath.Join("data", "multi-color.data"))))
+	changed := image.NewNRGBA(multiColor.Bounds())
+	for y := 0; y < changed.Rect.Dy(); y++ {
+		for x := 0; x < changed.Rect.Dx(); x++ {
+			changed.Set(x, y, multiColor.At(x, y))
+		}
+	}
+	for i := 3; i < len(changed.Pix); i += 4 {
+		if changed.Pix[i] == 255 {
+			changed.Pix[i-3] ^= 0x40 // Red of the first opaque pixel
+			break
+		}
+	}
+	var buf bytes.Buffer
+	if err := graphicsConverter.encodePng(&buf, changed); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dirB, "multi-color.png"), buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	results, err := NewFilesConverter(graphicsConverter).Diff(dirA, dirB, 0, heatmapDir)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %+v", results)
+	}
+
+	multi, onlyA, red, onlyB := results[0], results[1], results[2], results[3]
+	if multi.Name != "multi-color" || multi.ChangedPixels != 1 || multi.MaxDelta != 0x40 || multi.MeanDelta <= 0 {
+		t.Errorf("Expected one changed pixel in multi-color, got %+v", multi)
+	}
+	if multi.Bounds.Dx() != 1 || multi.Bounds.Dy() != 1 {
+		t.Errorf("Expected the changed pixel's bounds, got %v", multi.Bounds)
+	}
+	if multi.HeatmapPath != filepath.Join(heatmapDir, "multi-color"+HeatmapExtension) {
+		t.Errorf("Unexpected heatmap path %q", multi.HeatmapPath)
+	} else if heatmap := bytesToImage(t, readFile(t, multi.HeatmapPath)); heatmap.Bounds() != multiColor.Bounds() {
+		t.Errorf("Expected a heatmap of the texture's size, got %v", heatmap.Bounds())
+	}
+	if onlyA.Name != "only-a" || onlyA.Err == nil || !strings.Contains(onlyA.Err.Error(), "second") {
+		t.Errorf("Expected only-a to be missing from the second side, got %+v", onlyA)
+	}
+	if red.Name != "red" || !red.OK() || red.HeatmapPath != "" {
+		t.Errorf("Expected red to match without a heatmap, got %+v", red)
+	}
+	if onlyB.Name != "sub/only-b" || onlyB.Err == nil || !strings.Contains(onlyB.Err.Error(), "first") {
+		t.Errorf("Expected sub/only-b to be missing from the first side, got %+v", onlyB)
+	}
+
+	// The same pixel passes with a large enough tolerance
+	results, err = NewFilesConverter(graphicsConverter).Diff(filepath.Join(dirA, "multi-color.data"), filepath.Join(dirB, "multi-color.png"), 0x40, "")
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(results) != 1 || !results[0].OK() {
+		t.Errorf("Expected the files to match within the tolerance, got %+v", results)
+	}
+
+	// Only if that tolerance applies to the red channel
+	for _, test := range []struct {
+		tolerance imagecompare.Tolerance
+		ok        bool
+	}{{imagecompare.Tolerance{0x40, 0, 0, 0}, true}, {imagecompare.Tolerance{0, 0x40, 0x40, 0x40}, false}} {
+		options := imagecompare.Options{Tolerance: test.tolerance}
+		results, err = NewFilesConverter(graphicsConverter).DiffWith(filepath.Join(dirA, "multi-color.data"), filepath.Join(dirB, "multi-color.png"), options, "")
+		if err != nil {
+			t.Fatalf("DiffWith failed: %v", err)
+		}
+		if len(results) != 1 || results[0].OK() != test.ok {
+			t.Errorf("Expected OK %v with tolerance %v, got %+v", test.ok, test.tolerance, results)
+		}
+	}
+}
+
+// TestDiffSizeChange tests that pixels only the first side has count as changed
+func TestDiffSizeChange(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "data", "multi-color.data"), filepath.Join(dir, "a.data"))
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(dir, "b.data"))
+
+	results, err := NewFilesConverter(NewGraphicsConverter()).Diff(filepath.Join(dir, "a.data"), filepath.Join(dir, "b.data"), 255, "")
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if results[0].ChangedPixels != 128*96-32*32 {
+		t.Errorf("Expected the pixels outside the smaller texture to differ, got %d", results[0].ChangedPixels)
+	}
+}
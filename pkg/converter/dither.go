@@ -0,0 +1,69 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DitherMethod selects how 16-bit color channels are reduced to 8 bits
+type DitherMethod int
+
+const (
+	// DitherNone rounds every channel to the nearest 8-bit value
+	DitherNone DitherMethod = iota
+	// DitherFloydSteinberg diffuses each channel's rounding error to the neighbouring pixels
+	DitherFloydSteinberg
+	// DitherOrdered offsets channels by an 8×8 Bayer matrix before rounding. Its regular pattern doesn't
+	// depend on the neighbouring pixels, so editing one part of an image leaves the rest unchanged.
+	DitherOrdered
+)
+
+// ditherMethods maps the names accepted by ParseDitherMethod to dither methods
+var ditherMethods = map[string]DitherMethod{
+	"none":            DitherNone,
+	"floyd-steinberg": DitherFloydSteinberg,
+	"ordered":         DitherOrdered,
+}
+
+// ParseDitherMethod parses a dither method name: none, floyd-steinberg or ordered
+func ParseDitherMethod(name string) (DitherMethod, error) {
+	method, ok := ditherMethods[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown dither method '%s', expected none, floyd-steinberg or ordered", name)
+	}
+	return method, nil
+}
+
+// String returns the name of a dither method
+func (m DitherMethod) String() string {
+	for name, method := range ditherMethods {
+		if method == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("DitherMethod(%d)", int(m))
+}
+
+// SetDitherMethod sets how color channels are reduced when 16-bit PNGs and extended DATA files are read
+// as 8 bits, DitherNone by default. Alpha is always rounded.
+func (g *GraphicsConverter) SetDitherMethod(method DitherMethod) {
+	g.dither = method
+}
+
+// bayerMatrix is the 8×8 ordered dither threshold map, holding every value from 0 to 63 once
+var bayerMatrix = [8][8]int32{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// bayerOffset returns the ordered dither offset of pixel (x, y) in 16-bit units, spread evenly over
+// just under half an 8-bit step either way
+func bayerOffset(x, y int) int32 {
+	return (2*bayerMatrix[y&7][x&7]+1)*257/128 - 128
+}
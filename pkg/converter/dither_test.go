@@ -0,0 +1,53 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestOrderedDither tests that ordered dithering keeps the average of a flat area and only depends on
+// each pixel's own value
+func TestOrderedDither(t *testing.T) {
+	const value = 128*257 + 64 // A quarter of the way from 128 to 129
+	img := image.NewNRGBA64(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetNRGBA64(x, y, color.NRGBA64{R: value, G: value, B: value, A: 0xffff})
+		}
+	}
+
+	reduced := reduceTo8Bit(img, DitherOrdered)
+	up := 0
+	for i := 0; i < len(reduced.Pix); i += 4 {
+		if reduced.Pix[i] == 129 {
+			up++
+		} else if reduced.Pix[i] != 128 {
+			t.Fatalf("Expected only 128 and 129, got %d", reduced.Pix[i])
+		}
+	}
+	if up != 16*16/4 {
+		t.Errorf("Expected a quarter of the pixels to round up, got %d of %d", up, 16*16)
+	}
+
+	img.SetNRGBA64(5, 5, color.NRGBA64{A: 0xffff})
+	edited := reduceTo8Bit(img, DitherOrdered)
+	for i := range edited.Pix {
+		if i/4 != 5*16+5 && edited.Pix[i] != reduced.Pix[i] {
+			t.Fatalf("Expected editing one pixel to leave the others unchanged, byte %d differs", i)
+		}
+	}
+}
+
+// TestParseDitherMethod tests that dither method names round-trip
+func TestParseDitherMethod(t *testing.T) {
+	for _, method := range []DitherMethod{DitherNone, DitherFloydSteinberg, DitherOrdered} {
+		parsed, err := ParseDitherMethod(method.String())
+		if err != nil || parsed != method {
+			t.Errorf("Expected %v to round-trip, got %v, %v", method, parsed, err)
+		}
+	}
+	if _, err := ParseDitherMethod("random"); err == nil {
+		t.Error("Expected an unknown dither method to be rejected")
+	}
+}
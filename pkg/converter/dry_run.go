@@ -0,0 +1,204 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// PlannedConversion describes what converting a single file would do
+type PlannedConversion struct {
+	RelPath    string // Input path relative to the source directory
+	InputPath  string
+	OutputPath string
+	Overwrite  bool  // The output file already exists
+	Collision  bool  // Another input maps to the same output, ignoring case
+	Compared   bool  // The existing output was compared with a fresh conversion, only in plan mode
+	Changed    bool  // Converting would change the existing output's content
+	Err        error // Why the existing output couldn't be compared
+}
+
+// SetDryRun enables a mode where conversions only log every input -> output mapping,
+// collisions and overwrites, without writing anything
+func (f *FilesConverter) SetDryRun(enabled bool) {
+	f.dryRun = enabled
+}
+
+// SetPlan enables a dry-run mode that also converts inputs with existing outputs in memory and reports
+// which outputs would actually change. Textures are compared by their decoded pixels, other files byte for byte.
+func (f *FilesConverter) SetPlan(enabled bool) {
+	f.plan = enabled
+}
+
+// PlanConversion lists what converting all fromExt files in fromDir to toExt files in toDir would do
+func (f *FilesConverter) PlanConversion(fromDir, toDir, fromExt, toExt string) ([]PlannedConversion, error) {
+	source, closer, err := openSource(fromDir)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	tasks, err := f.collectTasks(context.Background(), source, fromDir, toDir, fromExt, toExt)
+	if err != nil {
+		return nil, err
+	}
+	return planTasks(tasks), nil
+}
+
+// PlanChanges is like PlanConversion but also compares every existing output with what convertFunc would write
+func (f *FilesConverter) PlanChanges(
+	ctx context.Context,
+	fromDir, toDir, fromExt, toExt string,
+	convertFunc func(io.Reader, io.Writer) error,
+) ([]PlannedConversion, error) {
+	source, closer, err := openSource(fromDir)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	tasks, err := f.collectTasks(ctx, source, fromDir, toDir, fromExt, toExt)
+	if err != nil {
+		return nil, err
+	}
+	plan := planTasks(tasks)
+	if err := f.comparePlan(ctx, tasks, plan, convertFunc); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// planTasks checks each task's output for existing files and collisions.
+// Collisions are detected case-insensitively because the outputs may land on a case-insensitive filesystem.
+func planTasks(tasks []ConversionTask) []PlannedConversion {
+	outputs := make(map[string]int, len(tasks))
+	for _, task := range tasks {
+		outputs[strings.ToLower(task.outputPath)]++
+	}
+
+	plan := make([]PlannedConversion, 0, len(tasks))
+	for _, task := range tasks {
+		_, statErr := os.Stat(task.outputPath)
+		plan = append(plan, PlannedConversion{
+			RelPath:    task.relPath,
+			InputPath:  task.inputPath,
+			OutputPath: task.outputPath,
+			Overwrite:  statErr == nil,
+			Collision:  outputs[strings.ToLower(task.outputPath)] > 1,
+		})
+	}
+	return plan
+}
+
+// comparePlan converts the input of every planned overwrite in memory and compares the result with the existing output.
+// Collisions are skipped since their outcome depends on which input is written last.
+func (f *FilesConverter) comparePlan(
+	ctx context.Context,
+	tasks []ConversionTask,
+	plan []PlannedConversion,
+	convertFunc func(io.Reader, io.Writer) error,
+) error {
+	indexes := make(chan int, len(plan))
+	for i, p := range plan {
+		if p.Overwrite && !p.Collision {
+			indexes <- i
+		}
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < f.maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					return
+				}
+				plan[i].Compared = true
+				plan[i].Changed, plan[i].Err = f.outputChanges(ctx, tasks[i], convertFunc)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// outputChanges reports whether converting a task's input would change its existing output
+func (f *FilesConverter) outputChanges(ctx context.Context, task ConversionTask, convertFunc func(io.Reader, io.Writer) error) (bool, error) {
+	input, err := task.open()
+	if err != nil {
+		return false, err
+	}
+	defer input.Close()
+
+	var converted bytes.Buffer
+	if err := safeConvert(convertFunc, &contextReader{ctx: ctx, r: input}, &converted); err != nil {
+		return false, fmt.Errorf("failed to convert: %w", err)
+	}
+
+	existing, err := os.ReadFile(task.outputPath)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(existing, converted.Bytes()) {
+		return false, nil
+	}
+
+	// Encoders may write the same pixels differently, so textures are compared decoded
+	ext := textureExtension(task.outputPath)
+	if ext == "" {
+		return true, nil
+	}
+	before, err := f.graphicsConverter.decodeImage(bytes.NewReader(existing), ext)
+	if err != nil {
+		return true, nil // An undecodable output is replaced by a valid one
+	}
+	after, err := f.graphicsConverter.decodeImage(&converted, ext)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode converted output: %w", err)
+	}
+	if before.Bounds().Size() != after.Bounds().Size() {
+		return true, nil
+	}
+	return imagecompare.Compare(before, after, 0).ChangedPixels > 0, nil
+}
+
+// logPlan logs each planned conversion followed by a summary
+func (f *FilesConverter) logPlan(plan []PlannedConversion) {
+	overwrites, collisions, unchanged := 0, 0, 0
+	for _, p := range plan {
+		switch {
+		case p.Collision:
+			collisions++
+			f.log.Warnf("[dry-run] %s -> %s (collision: another input writes the same output)", p.InputPath, p.OutputPath)
+		case p.Compared && p.Err != nil:
+			overwrites++
+			f.log.Warnf("[plan] %s -> %s (would overwrite, comparison failed: %v)", p.InputPath, p.OutputPath, p.Err)
+		case p.Compared && p.Changed:
+			overwrites++
+			f.log.Warnf("[plan] %s -> %s (would change)", p.InputPath, p.OutputPath)
+		case p.Compared:
+			unchanged++
+			f.log.Infof("[plan] %s -> %s (unchanged)", p.InputPath, p.OutputPath)
+		case p.Overwrite:
+			overwrites++
+			f.log.Warnf("[dry-run] %s -> %s (would overwrite)", p.InputPath, p.OutputPath)
+		default:
+			f.log.Infof("[dry-run] %s -> %s", p.InputPath, p.OutputPath)
+		}
+	}
+	if f.plan {
+		f.log.Infof("[plan] %d files would be converted, %d created, %d changed, %d unchanged, %d colliding",
+			len(plan), len(plan)-overwrites-unchanged-collisions, overwrites, unchanged, collisions)
+		return
+	}
+	f.log.Infof("[dry-run] %d files would be converted, %d overwritten, %d colliding", len(plan), overwrites, collisions)
+}
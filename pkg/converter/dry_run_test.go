@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDryRunWritesNothing tests that dry-run mode leaves the target directory untouched
+func TestDryRunWritesNothing(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := filepath.Join(t.TempDir(), "out")
+
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetDryRun(true)
+
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	if _, err := os.Stat(toDir); !os.IsNotExist(err) {
+		t.Errorf("Expected target directory not to be created in dry-run mode")
+	}
+}
+
+// TestPlanConversion tests that overwrites and case-insensitive collisions are flagged
+func TestPlanConversion(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(fromDir, "blue.data"))
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(fromDir, "Blue.data"))
+	if err := os.WriteFile(filepath.Join(toDir, "red.png"), []byte("existing"), 0644); err != nil {
+		t.Fatalf("Failed to write existing output: %v", err)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	plan, err := filesConverter.PlanConversion(fromDir, toDir, ".data", ".png")
+	if err != nil {
+		t.Fatalf("PlanConversion failed: %v", err)
+	}
+
+	if len(plan) != 3 {
+		t.Fatalf("Expected 3 planned conversions, got %d", len(plan))
+	}
+	for _, p := range plan {
+		switch p.RelPath {
+		case "red.data":
+			if !p.Overwrite || p.Collision {
+				t.Errorf("Expected red.data to overwrite without collision: %+v", p)
+			}
+		case "blue.data", "Blue.data":
+			if p.Overwrite || !p.Collision {
+				t.Errorf("Expected %s to collide without overwrite: %+v", p.RelPath, p)
+			}
+		default:
+			t.Errorf("Unexpected planned input %s", p.RelPath)
+		}
+	}
+}
+
+// TestPlanChanges tests that existing outputs are reported as changed only when their content would differ
+func TestPlanChanges(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	graphicsConverter := NewGraphicsConverter()
+	for _, name := range []string{"red", "blue", "white", "magenta"} {
+		copyFile(t, filepath.Join("testdata", "data", name+".data"), filepath.Join(fromDir, name+".data"))
+	}
+
+	// red.png is identical, blue.png has other pixels and magenta.png the same pixels encoded differently
+	writeOutput := func(name string, content []byte) {
+		if err := os.WriteFile(filepath.Join(toDir, name), content, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	redPng := dataToPngBytes(t, graphicsConverter, readTestResource(t, "data/red.data"))
+	writeOutput("red.png", redPng)
+	writeOutput("blue.png", redPng)
+
+	var magentaPng bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.NoCompression}
+	magenta := bytesToImage(t, dataToPngBytes(t, graphicsConverter, readTestResource(t, "data/magenta.data")))
+	if err := encoder.Encode(&magentaPng, magenta); err != nil {
+		t.Fatalf("Failed to encode magenta.png: %v", err)
+	}
+	writeOutput("magenta.png", magentaPng.Bytes())
+
+	filesConverter := NewFilesConverter(graphicsConverter)
+	plan, err := filesConverter.PlanChanges(context.Background(), fromDir, toDir, ".data", ".png", graphicsConverter.DataToPng)
+	if err != nil {
+		t.Fatalf("PlanChanges failed: %v", err)
+	}
+
+	for _, p := range plan {
+		if p.Err != nil {
+			t.Errorf("Comparison of %s failed: %v", p.RelPath, p.Err)
+		}
+		switch p.RelPath {
+		case "red.data", "magenta.data":
+			if !p.Compared || p.Changed {
+				t.Errorf("Expected %s to be unchanged: %+v", p.RelPath, p)
+			}
+		case "blue.data":
+			if !p.Compared || !p.Changed {
+				t.Errorf("Expected blue.data to change: %+v", p)
+			}
+		case "white.data":
+			if p.Overwrite || p.Compared {
+				t.Errorf("Expected white.data to be created without comparison: %+v", p)
+			}
+		}
+	}
+
+	// Plan mode writes nothing
+	filesConverter.SetPlan(true)
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(toDir, "white.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected plan mode not to write outputs")
+	}
+}
@@ -0,0 +1,69 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// FailedConversion describes a single input that couldn't be converted
+type FailedConversion struct {
+	Path   string `json:"path"`
+	Output string `json:"output,omitempty"`
+	Reason string `json:"reason"`
+	Stack  string `json:"stack,omitempty"` // Stack trace when the conversion panicked
+}
+
+// ErrorReport records which files of a run were converted and why the others failed, for scripted callers.
+// Register Record as a progress hook to fill it from batch conversions.
+type ErrorReport struct {
+	Converted []string           `json:"converted"`
+	Failed    []FailedConversion `json:"failed"`
+}
+
+// Record adds a finished or failed file from a progress event; other events are ignored.
+// Files interrupted by cancellation were neither converted nor failed, so they aren't recorded.
+func (r *ErrorReport) Record(event ProgressEvent) {
+	switch event.Type {
+	case FileFinished:
+		r.Converted = append(r.Converted, event.InputPath)
+	case FileFailed:
+		if !errors.Is(event.Err, context.Canceled) {
+			r.RecordFailure(event.InputPath, event.OutputPath, event.Err)
+		}
+	}
+}
+
+// RecordFailure adds a failed conversion that didn't go through a batch
+func (r *ErrorReport) RecordFailure(path, output string, err error) {
+	failure := FailedConversion{Path: path, Output: output, Reason: err.Error()}
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		failure.Stack = panicErr.Stack
+	}
+	r.Failed = append(r.Failed, failure)
+}
+
+// WriteFile writes the report as indented JSON with both lists sorted by path
+func (r *ErrorReport) WriteFile(path string) error {
+	report := ErrorReport{
+		Converted: append([]string{}, r.Converted...),
+		Failed:    append([]FailedConversion{}, r.Failed...),
+	}
+	sort.Strings(report.Converted)
+	sort.Slice(report.Failed, func(i, j int) bool {
+		return report.Failed[i].Path < report.Failed[j].Path
+	})
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write error report '%s': %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package converter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestErrorReport tests that converted and failed files of a batch end up in the written report
+func TestErrorReport(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+	if err := os.WriteFile(filepath.Join(fromDir, "broken.data"), []byte("not a texture"), 0644); err != nil {
+		t.Fatalf("Failed to write broken file: %v", err)
+	}
+
+	var report ErrorReport
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.Progress(report.Record)
+
+	if err := filesConverter.DataToPng(fromDir, toDir); err == nil {
+		t.Fatal("Expected conversion error for broken file, got nil")
+	}
+
+	reportPath := filepath.Join(t.TempDir(), "errors.json")
+	if err := report.WriteFile(reportPath); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	var written ErrorReport
+	if err := json.Unmarshal(content, &written); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+
+	if len(written.Converted) != 1 || written.Converted[0] != filepath.Join(fromDir, "red.data") {
+		t.Errorf("Unexpected converted files: %v", written.Converted)
+	}
+	if len(written.Failed) != 1 {
+		t.Fatalf("Expected 1 failure, got %v", written.Failed)
+	}
+	failure := written.Failed[0]
+	if failure.Path != filepath.Join(fromDir, "broken.data") || failure.Output != filepath.Join(toDir, "broken.png") || failure.Reason == "" {
+		t.Errorf("Unexpected failure entry: %+v", failure)
+	}
+}
@@ -0,0 +1,310 @@
+package converter
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"sort"
+	"strings"
+)
+
+// exportFormat is an image format DATA files can be exported to
+type exportFormat struct {
+	ext    string
+	encode func(io.Writer, image.Image) error
+}
+
+// exportFormats maps the names accepted by DataToImage to their formats
+var exportFormats = map[string]exportFormat{
+	"png":     {".png", png.Encode},
+	"bmp":     {".bmp", encodeBmp},
+	"tga":     {".tga", encodeTga},
+	"qoi":     {".qoi", encodeQoi},
+	"webp":    {".webp", encodeWebp},
+	"dds":     {".dds", encodeDds},
+	"dds-bc7": {".dds", encodeDdsBc7},
+}
+
+// ExportFormats returns the names of the formats DATA files can be exported to, sorted
+func ExportFormats() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExportExtension returns the file extension of an export format, such as ".tga" for "tga"
+func ExportExtension(format string) (string, error) {
+	exportFormat, err := lookupExportFormat(format)
+	if err != nil {
+		return "", err
+	}
+	return exportFormat.ext, nil
+}
+
+// lookupExportFormat finds an export format by case-insensitive name
+func lookupExportFormat(format string) (exportFormat, error) {
+	exportFormat, ok := exportFormats[strings.ToLower(format)]
+	if !ok {
+		return exportFormat, fmt.Errorf("unsupported export format '%s', expected one of %s",
+			format, strings.Join(ExportFormats(), ", "))
+	}
+	return exportFormat, nil
+}
+
+// DataToImage returns a conversion from Celeste's DATA format to an image in the given export format:
+// png, bmp, tga, qoi, webp, dds (uncompressed) or dds-bc7
+func (g *GraphicsConverter) DataToImage(format string) (func(io.Reader, io.Writer) error, error) {
+	exportFormat, err := lookupExportFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	encode := exportFormat.encode
+	if exportFormat.ext == ".png" {
+		encode = g.encodePng // Honours the compression level and shares encoder buffers
+	}
+	return func(input io.Reader, output io.Writer) error {
+		img, err := g.decodeData(input)
+		if err != nil {
+			return err
+		}
+		transformed, err := g.applyTransforms(img)
+		if err != nil {
+			return err
+		}
+		return encode(output, transformed)
+	}, nil
+}
+
+// DataToImage converts all .data files in the source directory to images of the given export format
+// in the target directory
+func (f *FilesConverter) DataToImage(fromDir, toDir, format string) error {
+	exportFormat, err := lookupExportFormat(format)
+	if err != nil {
+		return err
+	}
+	convertFunc, err := f.graphicsConverter.DataToImage(format)
+	if err != nil {
+		return err
+	}
+	f.log.Infof("Converting DATA -> %s", strings.ToUpper(format))
+	return f.convert(context.Background(), fromDir, toDir, ".data", exportFormat.ext, convertFunc)
+}
+
+// toNRGBA returns img as an *image.NRGBA anchored at the origin, copying only when necessary.
+// BMP, TGA, QOI, WebP and DDS all store straight alpha.
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	return nrgba
+}
+
+// bmpHeaderSize is the size of the file header plus a BITMAPV4HEADER, which is needed to declare an alpha mask
+const bmpHeaderSize = 14 + 108
+
+// encodeBmp writes img as a bottom-up 32-bit BGRA bitmap with an alpha channel mask
+func encodeBmp(output io.Writer, img image.Image) error {
+	nrgba := toNRGBA(img)
+	width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+	pixelBytes := width * height * 4
+
+	w := bufio.NewWriterSize(output, dataBufferSize)
+	header := struct {
+		Signature   [2]byte
+		FileSize    uint32
+		Reserved    uint32
+		PixelOffset uint32
+
+		HeaderSize    uint32
+		Width         int32
+		Height        int32
+		Planes        uint16
+		BitCount      uint16
+		Compression   uint32
+		ImageSize     uint32
+		XPelsPerMeter int32
+		YPelsPerMeter int32
+		ColorsUsed    uint32
+		ColorsImpt    uint32
+		Masks         [4]uint32 // Red, green, blue and alpha
+		ColorSpace    [4]byte
+		Endpoints     [36]byte
+		Gamma         [3]uint32
+	}{
+		Signature:     [2]byte{'B', 'M'},
+		FileSize:      uint32(bmpHeaderSize + pixelBytes),
+		PixelOffset:   bmpHeaderSize,
+		HeaderSize:    108,
+		Width:         int32(width),
+		Height:        int32(height),
+		Planes:        1,
+		BitCount:      32,
+		Compression:   3, // BI_BITFIELDS
+		ImageSize:     uint32(pixelBytes),
+		XPelsPerMeter: 2835, // 72 DPI
+		YPelsPerMeter: 2835,
+		Masks:         [4]uint32{0x00ff0000, 0x0000ff00, 0x000000ff, 0xff000000},
+		ColorSpace:    [4]byte{'B', 'G', 'R', 's'}, // LCS_sRGB, stored little-endian
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	row := make([]byte, width*4)
+	for y := height - 1; y >= 0; y-- {
+		writeBgraRow(row, nrgba.Pix[y*nrgba.Stride:])
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// tgaFooter marks a file as TGA 2.0, with no extension or developer area
+var tgaFooter = append(make([]byte, 8), "TRUEVISION-XFILE.\x00"...)
+
+// encodeTga writes img as an uncompressed 32-bit top-down TGA
+func encodeTga(output io.Writer, img image.Image) error {
+	nrgba := toNRGBA(img)
+	width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+	if width > 0xffff || height > 0xffff {
+		return fmt.Errorf("%dx%d is too large for TGA", width, height)
+	}
+
+	w := bufio.NewWriterSize(output, dataBufferSize)
+	header := struct {
+		IDLength     uint8
+		ColorMapType uint8
+		ImageType    uint8
+		ColorMap     [5]byte
+		XOrigin      uint16
+		YOrigin      uint16
+		Width        uint16
+		Height       uint16
+		PixelDepth   uint8
+		Descriptor   uint8
+	}{
+		ImageType:  2, // Uncompressed true-color
+		Width:      uint16(width),
+		Height:     uint16(height),
+		PixelDepth: 32,
+		Descriptor: 0x28, // 8 alpha bits, top-left origin
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	row := make([]byte, width*4)
+	for y := 0; y < height; y++ {
+		writeBgraRow(row, nrgba.Pix[y*nrgba.Stride:])
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Write(tgaFooter); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// writeBgraRow fills row with the RGBA pixels at the start of pix in BGRA order
+func writeBgraRow(row, pix []byte) {
+	for i := 0; i < len(row); i += 4 {
+		row[i], row[i+1], row[i+2], row[i+3] = pix[i+2], pix[i+1], pix[i], pix[i+3]
+	}
+}
+
+// QOI chunk tags, see https://qoiformat.org/qoi-specification.pdf
+const (
+	qoiOpIndex = 0x00
+	qoiOpDiff  = 0x40
+	qoiOpLuma  = 0x80
+	qoiOpRun   = 0xc0
+	qoiOpRGB   = 0xfe
+	qoiOpRGBA  = 0xff
+)
+
+// qoiEndMarker terminates a QOI stream
+var qoiEndMarker = []byte{0, 0, 0, 0, 0, 0, 0, 1}
+
+// encodeQoi writes img as a 4-channel sRGB QOI image
+func encodeQoi(output io.Writer, img image.Image) error {
+	nrgba := toNRGBA(img)
+	width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+
+	w := bufio.NewWriterSize(output, dataBufferSize)
+	header := struct {
+		Magic      [4]byte
+		Width      uint32
+		Height     uint32
+		Channels   uint8
+		Colorspace uint8
+	}{[4]byte{'q', 'o', 'i', 'f'}, uint32(width), uint32(height), 4, 0}
+	if err := binary.Write(w, binary.BigEndian, &header); err != nil {
+		return err
+	}
+
+	var index [64][4]byte
+	prev := [4]byte{0, 0, 0, 255}
+	run := 0
+	total := width * height
+
+	for i := 0; i < total; i++ {
+		offset := (i/width)*nrgba.Stride + (i%width)*4
+		var px [4]byte
+		copy(px[:], nrgba.Pix[offset:offset+4])
+
+		if px == prev {
+			run++
+			if run == 62 || i == total-1 {
+				w.WriteByte(qoiOpRun | byte(run-1))
+				run = 0
+			}
+			continue
+		}
+		if run > 0 {
+			w.WriteByte(qoiOpRun | byte(run-1))
+			run = 0
+		}
+
+		hash := (int(px[0])*3 + int(px[1])*5 + int(px[2])*7 + int(px[3])*11) % 64
+		switch {
+		case index[hash] == px:
+			w.WriteByte(qoiOpIndex | byte(hash))
+		case px[3] != prev[3]:
+			index[hash] = px
+			w.Write([]byte{qoiOpRGBA, px[0], px[1], px[2], px[3]})
+		default:
+			index[hash] = px
+			// Differences wrap around, so 0 - 255 is 1
+			dr, dg, db := int(int8(px[0]-prev[0])), int(int8(px[1]-prev[1])), int(int8(px[2]-prev[2]))
+			drg, dbg := dr-dg, db-dg
+			switch {
+			case dr >= -2 && dr <= 1 && dg >= -2 && dg <= 1 && db >= -2 && db <= 1:
+				w.WriteByte(qoiOpDiff | byte(dr+2)<<4 | byte(dg+2)<<2 | byte(db+2))
+			case dg >= -32 && dg <= 31 && drg >= -8 && drg <= 7 && dbg >= -8 && dbg <= 7:
+				w.Write([]byte{qoiOpLuma | byte(dg+32), byte(drg+8)<<4 | byte(dbg+8)})
+			default:
+				w.Write([]byte{qoiOpRGB, px[0], px[1], px[2]})
+			}
+		}
+		prev = px
+	}
+
+	// bufio.Writer keeps the first write error, which Flush returns
+	if _, err := w.Write(qoiEndMarker); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,156 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"path/filepath"
+	"testing"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// decodeQoi is a minimal QOI decoder used to check the encoder
+func decodeQoi(t *testing.T, data []byte) *image.NRGBA {
+	if len(data) < 14+len(qoiEndMarker) || string(data[:4]) != "qoif" {
+		t.Fatal("Missing QOI header")
+	}
+	width := int(binary.BigEndian.Uint32(data[4:]))
+	height := int(binary.BigEndian.Uint32(data[8:]))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	var index [64][4]byte
+	px := [4]byte{0, 0, 0, 255}
+	pos, run := 14, 0
+	for i := 0; i < width*height; i++ {
+		if run > 0 {
+			run--
+		} else {
+			op := data[pos]
+			pos++
+			switch {
+			case op == qoiOpRGB:
+				copy(px[:3], data[pos:pos+3])
+				pos += 3
+			case op == qoiOpRGBA:
+				copy(px[:], data[pos:pos+4])
+				pos += 4
+			case op&0xc0 == qoiOpIndex:
+				px = index[op]
+			case op&0xc0 == qoiOpDiff:
+				px[0] += (op>>4)&3 - 2
+				px[1] += (op>>2)&3 - 2
+				px[2] += op&3 - 2
+			case op&0xc0 == qoiOpLuma:
+				dg := op&0x3f - 32
+				px[0] += dg + data[pos]>>4 - 8
+				px[1] += dg
+				px[2] += dg + data[pos]&0xf - 8
+				pos++
+			default:
+				run = int(op & 0x3f)
+			}
+			index[(int(px[0])*3+int(px[1])*5+int(px[2])*7+int(px[3])*11)%64] = px
+		}
+		copy(img.Pix[i*4:], px[:])
+	}
+
+	if !bytes.Equal(data[pos:], qoiEndMarker) {
+		t.Errorf("Expected end marker after %d pixels, got %v", width*height, data[pos:])
+	}
+	return img
+}
+
+// TestDataToImage tests that each export format stores the same pixels as the PNG export
+func TestDataToImage(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+	dataBytes := readTestResource(t, filepath.Join("data", "multi-color.data"))
+	expected := toNRGBA(bytesToImage(t, dataToPngBytes(t, graphicsConverter, dataBytes)))
+	width, height := expected.Rect.Dx(), expected.Rect.Dy()
+
+	// pixelAt reads the RGBA pixel at x, y from BGRA rows of the given stride
+	bgraAt := func(pix []byte, stride, x, y int) [4]byte {
+		i := y*stride + x*4
+		return [4]byte{pix[i+2], pix[i+1], pix[i], pix[i+3]}
+	}
+	expectedAt := func(x, y int) [4]byte {
+		c := expected.NRGBAAt(x, y)
+		return [4]byte{c.R, c.G, c.B, c.A}
+	}
+
+	for _, format := range ExportFormats() {
+		t.Run(format, func(t *testing.T) {
+			convertFunc, err := graphicsConverter.DataToImage(format)
+			if err != nil {
+				t.Fatalf("DataToImage failed: %v", err)
+			}
+			var output bytes.Buffer
+			if err := convertFunc(bytes.NewReader(dataBytes), &output); err != nil {
+				t.Fatalf("Conversion failed: %v", err)
+			}
+			out := output.Bytes()
+
+			switch format {
+			case "png":
+				assertImageEquals(t, expected, bytesToImage(t, out), 0)
+			case "qoi":
+				assertImageEquals(t, expected, decodeQoi(t, out), 0)
+			case "webp":
+				img, err := graphicsConverter.decodeWebp(bytes.NewReader(out))
+				if err != nil {
+					t.Fatalf("Failed to decode WebP: %v", err)
+				}
+				assertImageEquals(t, expected, img, 0)
+			case "dds":
+				assertImageEquals(t, expected, decodeDds(t, out), 0)
+			case "dds-bc7":
+				// Lossy, and this texture is full of blocks mixing more than two colors
+				decoded := decodeDds(t, out)
+				if result := imagecompare.Compare(expected, decoded, 0); decoded.Rect != expected.Rect || result.PSNR() < 20 {
+					t.Errorf("Expected BC7 close to the original, got %v at PSNR %.1f dB", decoded.Rect, result.PSNR())
+				}
+			case "bmp":
+				if string(out[:2]) != "BM" || len(out) != bmpHeaderSize+width*height*4 {
+					t.Fatalf("Unexpected BMP header or size %d", len(out))
+				}
+				// Rows are stored bottom-up
+				for _, p := range []image.Point{{0, 0}, {width - 1, height - 1}, {width / 2, height / 3}} {
+					if got := bgraAt(out[bmpHeaderSize:], width*4, p.X, height-1-p.Y); got != expectedAt(p.X, p.Y) {
+						t.Errorf("BMP pixel at %v: expected %v, got %v", p, expectedAt(p.X, p.Y), got)
+					}
+				}
+			case "tga":
+				if out[2] != 2 || out[16] != 32 || len(out) != 18+width*height*4+len(tgaFooter) {
+					t.Fatalf("Unexpected TGA header or size %d", len(out))
+				}
+				for _, p := range []image.Point{{0, 0}, {width - 1, height - 1}, {width / 2, height / 3}} {
+					if got := bgraAt(out[18:], width*4, p.X, p.Y); got != expectedAt(p.X, p.Y) {
+						t.Errorf("TGA pixel at %v: expected %v, got %v", p, expectedAt(p.X, p.Y), got)
+					}
+				}
+			}
+		})
+	}
+
+	if _, err := graphicsConverter.DataToImage("jpeg"); err == nil {
+		t.Error("Expected error for unsupported format, got nil")
+	}
+}
+
+// TestEncodeQoiRuns tests runs longer than a single QOI run chunk and pixels reached through every chunk type
+func TestEncodeQoiRuns(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 3))
+	for x := 0; x < 100; x++ {
+		img.Pix[x*4+3] = 255 // First row is one long run of opaque black
+		i := img.PixOffset(x, 1)
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(x), uint8(x*2), uint8(255-x), 255
+		i = img.PixOffset(x, 2)
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(x*37), uint8(x*3), uint8(x%3), uint8(x*5)
+	}
+
+	var output bytes.Buffer
+	if err := encodeQoi(&output, img); err != nil {
+		t.Fatalf("encodeQoi failed: %v", err)
+	}
+	assertImageEquals(t, img, decodeQoi(t, output.Bytes()), 0)
+}
@@ -0,0 +1,55 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExtensionMapping pairs an input extension with the output extension it is converted to
+type ExtensionMapping struct {
+	From string
+	To   string
+}
+
+// ParseExtensionMap parses a comma-separated list of from=to extension pairs, such as ".bin=.png.bak,.dat=.png".
+// Extensions may be given with or without their leading dot.
+func ParseExtensionMap(s string) ([]ExtensionMapping, error) {
+	var mappings []ExtensionMapping
+	seen := make(map[string]bool)
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid extension mapping '%s', expected from=to", pair)
+		}
+		from, to = normalizeExtension(from), normalizeExtension(to)
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("invalid extension mapping '%s', extensions must not be empty", pair)
+		}
+		if seen[strings.ToLower(from)] {
+			return nil, fmt.Errorf("extension '%s' is mapped more than once", from)
+		}
+		seen[strings.ToLower(from)] = true
+
+		mappings = append(mappings, ExtensionMapping{From: from, To: to})
+	}
+
+	if len(mappings) == 0 {
+		return nil, fmt.Errorf("no extension mappings in '%s'", s)
+	}
+	return mappings, nil
+}
+
+// normalizeExtension trims whitespace and ensures a leading dot, returning "" for empty extensions
+func normalizeExtension(ext string) string {
+	ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+	if ext == "" {
+		return ""
+	}
+	return "." + ext
+}
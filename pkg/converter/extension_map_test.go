@@ -0,0 +1,50 @@
+package converter
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestParseExtensionMap tests parsing of valid and invalid extension mappings
+func TestParseExtensionMap(t *testing.T) {
+	mappings, err := ParseExtensionMap(".bin=.png.bak, dat=png")
+	if err != nil {
+		t.Fatalf("ParseExtensionMap failed: %v", err)
+	}
+	expected := []ExtensionMapping{{From: ".bin", To: ".png.bak"}, {From: ".dat", To: ".png"}}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Errorf("Expected %v, got %v", expected, mappings)
+	}
+
+	for _, invalid := range []string{"", ".bin", ".bin=", "=.png", ".bin=.png,.BIN=.png.bak"} {
+		if _, err := ParseExtensionMap(invalid); err == nil {
+			t.Errorf("Expected '%s' to be rejected", invalid)
+		}
+	}
+}
+
+// TestConvertWithExtensionMap tests converting DATA content stored under a custom extension
+func TestConvertWithExtensionMap(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.bin"))
+
+	mappings, err := ParseExtensionMap(".bin=.png.bak")
+	if err != nil {
+		t.Fatalf("ParseExtensionMap failed: %v", err)
+	}
+
+	graphicsConverter := NewGraphicsConverter()
+	filesConverter := NewFilesConverter(graphicsConverter)
+	for _, m := range mappings {
+		if err := filesConverter.Convert(fromDir, toDir, m.From, m.To, graphicsConverter.DataToPng); err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+	}
+
+	expected := bytesToImage(t, dataToPngBytes(t, graphicsConverter, readTestResource(t, filepath.Join("data", "red.data"))))
+	actual := bytesToImage(t, readFile(t, filepath.Join(toDir, "red.png.bak")))
+	assertImageEquals(t, expected, actual, 0)
+}
@@ -0,0 +1,233 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// zipSignature starts every zip archive with at least one entry
+var zipSignature = []byte("PK\x03\x04")
+
+// Fetcher downloads textures and mod archives over HTTPS and converts them,
+// for scripted setups installing remote texture packs
+type Fetcher struct {
+	filesConverter *FilesConverter
+	log            Logger
+	client         *http.Client
+	expectedSHA256 string // Lowercase hex, empty to skip verification
+}
+
+// NewFetcher creates a new Fetcher converting downloads with filesConverter
+func NewFetcher(filesConverter *FilesConverter) *Fetcher {
+	return &Fetcher{
+		filesConverter: filesConverter,
+		log:            DefaultLogger(),
+		client:         &http.Client{Timeout: 10 * time.Minute, CheckRedirect: httpsOnlyRedirect},
+	}
+}
+
+// SetClient sets the HTTP client used for downloads. A client without a redirect policy is given
+// Fetch's, which refuses redirects away from HTTPS.
+func (f *Fetcher) SetClient(client *http.Client) {
+	if client.CheckRedirect == nil {
+		withPolicy := *client
+		withPolicy.CheckRedirect = httpsOnlyRedirect
+		client = &withPolicy
+	}
+	f.client = client
+}
+
+// httpsOnlyRedirect follows redirects like the default policy, but only to HTTPS URLs, so a download
+// checked to be HTTPS can't be downgraded to plain HTTP
+func httpsOnlyRedirect(request *http.Request, via []*http.Request) error {
+	if request.URL.Scheme != "https" {
+		return fmt.Errorf("refusing redirect to non-HTTPS URL '%s'", request.URL)
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// SetExpectedSHA256 makes Fetch reject downloads whose SHA-256 isn't hash; empty disables verification
+func (f *Fetcher) SetExpectedSHA256(hash string) error {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if hash != "" {
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return fmt.Errorf("'%s' is not a SHA-256 hash", hash)
+		}
+	}
+	f.expectedSHA256 = hash
+	return nil
+}
+
+// Fetch downloads rawURL into a temporary directory, verifies its hash if one is expected and converts it:
+// a single texture to toPath in the other format (DATA to PNG, PNG or .cdat.zst to DATA),
+// or every .data and .png file of a zip archive to the other format below the toPath directory.
+// The download is removed afterwards, whether conversion succeeds or not.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL, toPath string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL '%s': %w", rawURL, err)
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("only HTTPS URLs are supported, got '%s'", rawURL)
+	}
+
+	tempDir, err := os.MkdirTemp("", "celeste-fetch-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	name := filepath.Base(filepath.FromSlash(parsed.Path))
+	if name == "." || name == string(filepath.Separator) {
+		name = "download"
+	}
+	downloadPath := filepath.Join(tempDir, name)
+	if err := f.download(ctx, rawURL, downloadPath); err != nil {
+		return err
+	}
+
+	header, err := readHeader(downloadPath)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(header, zipSignature) {
+		// Batch conversions only read sources ending in .zip as archives
+		if !hasZipExtension(downloadPath) {
+			archivePath := downloadPath + ".zip"
+			if err := os.Rename(downloadPath, archivePath); err != nil {
+				return err
+			}
+			downloadPath = archivePath
+		}
+		return f.convertArchive(ctx, downloadPath, toPath)
+	}
+	return f.convertTexture(downloadPath, toPath)
+}
+
+// download writes the response body of rawURL to path, verifying its hash
+func (f *Fetcher) download(ctx context.Context, rawURL, path string) error {
+	f.log.Infof("Downloading %s", rawURL)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	response, err := f.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed with status %s", response.Status)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create '%s': %w", path, err)
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hasher), response.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	f.log.Infof("Downloaded %d bytes, SHA-256 %s", size, hash)
+	if f.expectedSHA256 != "" && hash != f.expectedSHA256 {
+		return fmt.Errorf("checksum mismatch: expected SHA-256 %s, got %s", f.expectedSHA256, hash)
+	}
+	return nil
+}
+
+// convertArchive converts the textures of a downloaded zip archive into the toDir directory
+func (f *Fetcher) convertArchive(ctx context.Context, archivePath, toDir string) error {
+	// Both conversions write to toDir, and a zip output would be recreated by the second one
+	if hasZipExtension(toDir) {
+		return errors.New("archives are converted into a directory, not a zip archive")
+	}
+	if err := f.filesConverter.DataToPngContext(ctx, archivePath, toDir); err != nil {
+		return err
+	}
+	return f.filesConverter.PngToDataContext(ctx, archivePath, toDir)
+}
+
+// convertTexture converts a single downloaded texture, recognised by content, to toPath
+func (f *Fetcher) convertTexture(inputPath, toPath string) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	format, err := SniffFormat(input)
+	if err != nil {
+		return err
+	}
+	if _, err := input.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	graphicsConverter := f.filesConverter.graphicsConverter
+	var convertFunc func(io.Reader, io.Writer) error
+	switch format {
+	case ".data":
+		convertFunc = graphicsConverter.DataToPng
+	case ".png":
+		convertFunc = graphicsConverter.PngToData
+	case ".cdat.zst":
+		convertFunc = graphicsConverter.CdatToData
+	default:
+		return errors.New("download is neither a texture nor a zip archive")
+	}
+
+	f.log.Infof("Converting %s -> %s", formatLabel(format), toPath)
+	if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(toPath), err)
+	}
+	output, err := os.Create(toPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file '%s': %w", toPath, err)
+	}
+	err = convertFunc(input, output)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(toPath)
+		return err
+	}
+	return nil
+}
+
+// readHeader reads up to sniffLen leading bytes of a file
+func readHeader(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return header[:n], nil
+}
@@ -0,0 +1,164 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestFetch tests downloading, verifying and converting single textures and zip archives
+func TestFetch(t *testing.T) {
+	dataBytes := readTestResource(t, filepath.Join("data", "red.data"))
+	pngBytes := readTestResource(t, filepath.Join("png", "red.png"))
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, content := range map[string][]byte{"Graphics/red.data": dataBytes, "Graphics/blue.png": pngBytes} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		w.Write(content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+
+	files := map[string][]byte{
+		"/red.data":    dataBytes,
+		"/texture":     pngBytes, // Recognised by content without an extension
+		"/pack.zip":    archive.Bytes(),
+		"/pack-latest": archive.Bytes(),
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	// Downloads go to the temporary directory, which must be empty again afterwards
+	toDir := t.TempDir()
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	newFetcher := func() *Fetcher {
+		fetcher := NewFetcher(NewFilesConverter(NewGraphicsConverter()))
+		fetcher.SetClient(server.Client())
+		return fetcher
+	}
+	hashOf := func(content []byte) string {
+		sum := sha256.Sum256(content)
+		return hex.EncodeToString(sum[:])
+	}
+
+	t.Run("texture with hash", func(t *testing.T) {
+		fetcher := newFetcher()
+		if err := fetcher.SetExpectedSHA256(strings.ToUpper(hashOf(dataBytes))); err != nil {
+			t.Fatalf("SetExpectedSHA256 failed: %v", err)
+		}
+		outputPath := filepath.Join(toDir, "red.png")
+		if err := fetcher.Fetch(context.Background(), server.URL+"/red.data", outputPath); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		assertImageEquals(t, bytesToImage(t, dataToPngBytes(t, NewGraphicsConverter(), dataBytes)),
+			bytesToImage(t, readFile(t, outputPath)), 0)
+	})
+
+	t.Run("texture without extension", func(t *testing.T) {
+		outputPath := filepath.Join(toDir, "texture.data")
+		if err := newFetcher().Fetch(context.Background(), server.URL+"/texture", outputPath); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if !bytes.Equal(readFile(t, outputPath), pngToDataBytes(t, NewGraphicsConverter(), pngBytes)) {
+			t.Error("Converted texture doesn't match direct conversion")
+		}
+	})
+
+	for _, path := range []string{"/pack.zip", "/pack-latest"} {
+		t.Run("archive "+path, func(t *testing.T) {
+			packDir := filepath.Join(toDir, strings.TrimSuffix(path, ".zip"))
+			if err := newFetcher().Fetch(context.Background(), server.URL+path, packDir); err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+			for _, name := range []string{"red.png", "blue.data"} {
+				if _, err := os.Stat(filepath.Join(packDir, "Graphics", name)); err != nil {
+					t.Errorf("Expected %s to be converted: %v", name, err)
+				}
+			}
+		})
+	}
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		fetcher := newFetcher()
+		if err := fetcher.SetExpectedSHA256(hashOf(pngBytes)); err != nil {
+			t.Fatalf("SetExpectedSHA256 failed: %v", err)
+		}
+		outputPath := filepath.Join(toDir, "mismatch.png")
+		err := fetcher.Fetch(context.Background(), server.URL+"/red.data", outputPath)
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Fatalf("Expected checksum mismatch, got %v", err)
+		}
+		if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+			t.Error("Output was written despite the checksum mismatch")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if err := newFetcher().SetExpectedSHA256("abc"); err == nil {
+			t.Error("Expected error for invalid hash, got nil")
+		}
+		plainURL := strings.Replace(server.URL, "https://", "http://", 1) + "/red.data"
+		if err := newFetcher().Fetch(context.Background(), plainURL, filepath.Join(toDir, "plain.png")); err == nil {
+			t.Error("Expected error for plain HTTP URL, got nil")
+		}
+		if err := newFetcher().Fetch(context.Background(), server.URL+"/missing.data", filepath.Join(toDir, "missing.png")); err == nil {
+			t.Error("Expected error for missing file, got nil")
+		}
+		if err := newFetcher().Fetch(context.Background(), server.URL+"/pack.zip", filepath.Join(toDir, "out.zip")); err == nil {
+			t.Error("Expected error for archive converted into a zip, got nil")
+		}
+	})
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read temporary directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected downloads to be cleaned up, found %d entries", len(entries))
+	}
+}
+
+// TestFetchRedirect tests that redirects from HTTPS to plain HTTP are refused
+func TestFetchRedirect(t *testing.T) {
+	var requested atomic.Bool
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested.Store(true)
+		w.Write(readTestResource(t, filepath.Join("data", "red.data")))
+	}))
+	defer plain.Close()
+	server := httptest.NewTLSServer(http.RedirectHandler(plain.URL+"/red.data", http.StatusFound))
+	defer server.Close()
+
+	fetcher := NewFetcher(NewFilesConverter(NewGraphicsConverter()))
+	fetcher.SetClient(server.Client())
+	err := fetcher.Fetch(context.Background(), server.URL+"/red.data", filepath.Join(t.TempDir(), "red.png"))
+	if err == nil || !strings.Contains(err.Error(), "non-HTTPS") {
+		t.Errorf("Expected the redirect to plain HTTP to be refused, got %v", err)
+	}
+	if requested.Load() {
+		t.Error("Expected the plain HTTP server not to be requested")
+	}
+}
@@ -0,0 +1,113 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SetFileList makes batch conversions convert the listed files instead of walking the whole source,
+// so tools like find or git diff --name-only can pick exactly what gets converted. Entries are paths
+// relative to the source directory, or paths from the current directory to files below it, as find
+// prints them. Listed files still need the input extension and have to pass the filter; missing
+// files, directories and entries outside the source are skipped with a warning. A nil list walks the
+// source as usual.
+func (f *FilesConverter) SetFileList(paths []string) {
+	f.fileList = paths
+}
+
+// ReadFileList reads a file list for SetFileList, one path per line, or separated by NUL characters
+// with nulSeparated, as written by find -print0 and git diff -z. Empty entries are ignored.
+func ReadFileList(r io.Reader, nulSeparated bool) ([]string, error) {
+	separator := byte('\n')
+	if nulSeparated {
+		separator = 0
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, separator); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+
+	paths := []string{}
+	for scanner.Scan() {
+		entry := scanner.Text()
+		if !nulSeparated {
+			entry = strings.TrimSuffix(entry, "\r")
+		}
+		if entry != "" {
+			paths = append(paths, entry)
+		}
+	}
+	return paths, scanner.Err()
+}
+
+// walkFileList calls fn like walkSource does, for each file of the file list found in source, the
+// contents of fromDir, in list order and once per file
+func (f *FilesConverter) walkFileList(source fs.FS, fromDir string, fn fs.WalkDirFunc) error {
+	seen := make(map[string]bool)
+	for _, entry := range f.fileList {
+		relPath, ok := listedPath(fromDir, entry)
+		if !ok {
+			f.log.Warnf("Skipping %s: not below the source directory", entry)
+			continue
+		}
+		if seen[relPath] {
+			continue
+		}
+		seen[relPath] = true
+
+		if fromDir != "" && !f.followSymlinks && !IsZipArchive(fromDir) {
+			if info, err := os.Lstat(filepath.Join(fromDir, filepath.FromSlash(relPath))); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+				f.log.Warnf("Skipping symlink %s: following symlinks is disabled", relPath)
+				continue
+			}
+		}
+		info, err := fs.Stat(source, relPath)
+		if err != nil {
+			f.log.Warnf("Skipping %s: %v", entry, err)
+			continue
+		}
+		if info.IsDir() {
+			f.log.Warnf("Skipping %s: listed directories aren't converted, list their files instead", entry)
+			continue
+		}
+		if err := fn(relPath, fs.FileInfoToDirEntry(info), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listedPath resolves an entry of the file list to a slash-separated path relative to fromDir. Entries
+// that lead to a file below fromDir from the current directory are taken that way, others as relative
+// to fromDir already.
+func listedPath(fromDir, entry string) (string, bool) {
+	if fromDir != "" && !IsZipArchive(fromDir) {
+		absDir, dirErr := filepath.Abs(fromDir)
+		absEntry, entryErr := filepath.Abs(entry)
+		if dirErr == nil && entryErr == nil && (filepath.IsAbs(entry) || isWithin(absDir, absEntry)) {
+			relPath, err := filepath.Rel(absDir, absEntry)
+			if err != nil || !isWithin(absDir, absEntry) {
+				return "", false
+			}
+			entry = relPath
+		}
+	}
+	relPath := path.Clean(filepath.ToSlash(entry))
+	if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") || path.IsAbs(relPath) {
+		return "", false
+	}
+	return relPath, true
+}
@@ -0,0 +1,92 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReadFileList tests that file lists are split on newlines or NUL characters, without empty entries
+func TestReadFileList(t *testing.T) {
+	tests := []struct {
+		input        string
+		nulSeparated bool
+		expected     []string
+	}{
+		{"a.data\nsub/b.data\n", false, []string{"a.data", "sub/b.data"}},
+		{"a.data\r\n\r\nb.data", false, []string{"a.data", "b.data"}},
+		{"a b.data\x00sub/c\nd.data\x00", true, []string{"a b.data", "sub/c\nd.data"}},
+		{"", false, []string{}},
+	}
+	for _, test := range tests {
+		paths, err := ReadFileList(strings.NewReader(test.input), test.nulSeparated)
+		if err != nil {
+			t.Fatalf("ReadFileList(%q) failed: %v", test.input, err)
+		}
+		if strings.Join(paths, "|") != strings.Join(test.expected, "|") || len(paths) != len(test.expected) {
+			t.Errorf("ReadFileList(%q): expected %q, got %q", test.input, test.expected, paths)
+		}
+	}
+}
+
+// TestFileList tests that only the listed files are converted, whether listed relative to the source
+// or from the current directory
+func TestFileList(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(fromDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, name := range []string{"red", "blue", "green"} {
+		copyFile(t, filepath.Join("testdata", "data", name+".data"), filepath.Join(fromDir, name+".data"))
+	}
+	copyFile(t, filepath.Join("testdata", "data", "white.data"), filepath.Join(fromDir, "sub", "white.data"))
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithFileList([]string{
+		"red.data",
+		filepath.Join(fromDir, "sub", "white.data"),
+		"./red.data",      // Listed twice
+		"missing.data",    // Deleted since the list was made
+		"sub",             // Directory
+		"../outside.data", // Outside the source
+		"notes.txt",       // Not a DATA file
+	}))
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	for _, name := range []string{"red.png", filepath.Join("sub", "white.png")} {
+		if !fileExists(filepath.Join(toDir, name)) {
+			t.Errorf("Expected %s to be converted", name)
+		}
+	}
+	for _, name := range []string{"blue.png", "green.png"} {
+		if fileExists(filepath.Join(toDir, name)) {
+			t.Errorf("Expected %s not to be converted", name)
+		}
+	}
+}
+
+// TestListedPath tests that entries are resolved relative to the source directory
+func TestListedPath(t *testing.T) {
+	fromDir := t.TempDir()
+	tests := []struct {
+		entry    string
+		expected string
+		ok       bool
+	}{
+		{"a.data", "a.data", true},
+		{"sub/../b.data", "b.data", true},
+		{filepath.Join(fromDir, "sub", "c.data"), "sub/c.data", true},
+		{filepath.Join(filepath.Dir(fromDir), "d.data"), "", false},
+		{"../e.data", "", false},
+		{".", "", false},
+	}
+	for _, test := range tests {
+		relPath, ok := listedPath(fromDir, test.entry)
+		if relPath != test.expected || ok != test.ok {
+			t.Errorf("listedPath(%q): expected %q, %v, got %q, %v", test.entry, test.expected, test.ok, relPath, ok)
+		}
+	}
+}
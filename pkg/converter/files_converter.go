@@ -1,37 +1,98 @@
 package converter
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
 // FilesConverter handles batch conversion of files between formats
 type FilesConverter struct {
-	graphicsConverter *GraphicsConverter
-	log               *logrus.Logger
-	maxWorkers        int // Number of concurrent workers
+	graphicsConverter  *GraphicsConverter
+	log                Logger
+	maxWorkers         int // Number of concurrent workers
+	provenance         bool
+	provenanceOptions  map[string]string // Options recorded alongside provenance
+	quarantineDir      string            // Where failed inputs are copied, empty to disable
+	progressHook       func(ProgressEvent)
+	fileLimiter        *rate.Limiter // Limits files started per second, nil when unlimited
+	byteLimiter        *rate.Limiter // Limits input bytes read per second, nil when unlimited
+	ioSlots            chan struct{} // Limits files read or written at once, nil when unlimited
+	pause              *pauseGate
+	dryRun             bool        // Only report what would be converted
+	plan               bool        // Like dryRun, also comparing existing outputs with their new content
+	sniff              bool        // Select inputs by content instead of extension
+	continueOnError    bool        // Report every failed file instead of only the first
+	incremental        bool        // Skip inputs whose output is newer than the input
+	index              *AssetIndex // Records converted assets, nil to disable
+	overwritePolicy    OverwritePolicy
+	include            []string      // Globs an input's relative path must match one of, empty for all
+	exclude            []string      // Globs of relative paths left out
+	fileList           []string      // Inputs converted instead of walking the source, nil to walk it
+	orderedOutput      bool          // Log files in input order instead of completion order
+	stallTimeout       time.Duration // Progress-free time after which a file counts as stalled, 0 to disable
+	skipStalled        bool          // Fail stalled files instead of only reporting them
+	fileTimeout        time.Duration // Longest a single file may take to convert, 0 for no limit
+	dedupe             DedupeMode
+	followSymlinks     bool // Follow symlinks while collecting inputs instead of skipping them
+	preserveAttributes bool // Copy each input's modification time and permissions onto its output
+	deterministic      bool // Write byte-identical outputs across runs, see SetDeterministic
+	mergePrecedence    MergePrecedence
+	outputTemplate     *OutputTemplate  // Names outputs instead of the input's relative path, nil for the default
+	flatten            bool             // Write outputs into the target's root, see SetFlatten
+	stripPrefix        string           // Directories removed from the start of output paths
+	addPrefix          string           // Directories output paths are placed below
+	quietFiles         bool             // Log converted files at debug level, for progress bars
+	memoryMap          bool             // Memory-map large inputs instead of reading them
+	resume             bool             // Journal converted files and skip those of an earlier run
+	manifest           *Manifest        // Receives the hash of every output written, nil to disable
+	cache              *ConversionCache // Outputs of inputs converted before, nil to disable
+	schedule           Schedule
+	largeFileSize      int64 // Inputs of at least this size go to the large file pool, 0 without one
+	largeFileWorkers   int   // Workers dedicated to the large file pool
+	trim               bool  // Crop transparent margins, or pad them back when converting to DATA
+	registry           *ConversionRegistry
+	overwriteBackup    OverwriteBackup // Where replaced outputs are copied, zero to disable
 }
 
-// NewFilesConverter creates a new FilesConverter instance
-func NewFilesConverter(graphicsConverter *GraphicsConverter) *FilesConverter {
+// NewFilesConverter creates a new FilesConverter instance, configured by options
+func NewFilesConverter(graphicsConverter *GraphicsConverter, options ...FilesOption) *FilesConverter {
 	numCPU := runtime.NumCPU()
 	maxWorkers := numCPU
 	if maxWorkers > 8 {
 		maxWorkers = 8
 	}
 
-	return &FilesConverter{
+	registry := NewConversionRegistry()
+	for _, conversion := range builtinConversions(graphicsConverter) {
+		_ = registry.Register(conversion) // Built-in names are unique
+	}
+
+	f := &FilesConverter{
 		graphicsConverter: graphicsConverter,
-		log:               logrus.StandardLogger(),
+		log:               DefaultLogger(),
 		maxWorkers:        maxWorkers,
+		pause:             &pauseGate{},
+		registry:          registry,
 	}
+	for _, option := range options {
+		option(f)
+	}
+	return f
 }
 
 // SetMaxWorkers allows overriding the default number of workers
@@ -41,16 +102,83 @@ func (f *FilesConverter) SetMaxWorkers(workers int) {
 	}
 }
 
+// SetProvenance enables recording of converter version, options and source hashes.
+// PNG outputs get tEXt chunks, every output gets a sidecar and the batch gets a provenance.json.
+func (f *FilesConverter) SetProvenance(enabled bool, options map[string]string) {
+	f.provenance = enabled
+	f.provenanceOptions = options
+}
+
+// SetContinueOnError makes batch conversions report every failed file, joined with errors.Join,
+// instead of only the first one. Successful outputs and batch provenance are kept either way.
+func (f *FilesConverter) SetContinueOnError(enabled bool) {
+	f.continueOnError = enabled
+}
+
 // DataToPng converts all .data files in the source directory to .png files in the target directory
 func (f *FilesConverter) DataToPng(fromDir, toDir string) error {
-	f.log.Info("Converting DATA -> PNG")
-	return f.convert(fromDir, toDir, ".data", ".png", f.graphicsConverter.DataToPng)
+	return f.DataToPngContext(context.Background(), fromDir, toDir)
+}
+
+// DataToPngContext is like DataToPng but stops when ctx is cancelled, removing partially written outputs
+func (f *FilesConverter) DataToPngContext(ctx context.Context, fromDir, toDir string) error {
+	return f.RunConversionContext(ctx, "data2png", fromDir, toDir)
 }
 
 // PngToData converts all .png files in the source directory to .data files in the target directory
 func (f *FilesConverter) PngToData(fromDir, toDir string) error {
-	f.log.Info("Converting PNG -> DATA")
-	return f.convert(fromDir, toDir, ".png", ".data", f.graphicsConverter.PngToData)
+	return f.PngToDataContext(context.Background(), fromDir, toDir)
+}
+
+// PngToDataContext is like PngToData but stops when ctx is cancelled, removing partially written outputs
+func (f *FilesConverter) PngToDataContext(ctx context.Context, fromDir, toDir string) error {
+	return f.RunConversionContext(ctx, "png2data", fromDir, toDir)
+}
+
+// DataToCdat converts all .data files in the source directory to .cdat.zst files in the target directory
+func (f *FilesConverter) DataToCdat(fromDir, toDir string) error {
+	return f.RunConversion("data2cdat", fromDir, toDir)
+}
+
+// CdatToData converts all .cdat.zst files in the source directory to .data files in the target directory
+func (f *FilesConverter) CdatToData(fromDir, toDir string) error {
+	return f.RunConversion("cdat2data", fromDir, toDir)
+}
+
+// PngToCdat converts all .png files in the source directory to .cdat.zst files in the target directory
+func (f *FilesConverter) PngToCdat(fromDir, toDir string) error {
+	return f.RunConversion("png2cdat", fromDir, toDir)
+}
+
+// CdatToPng converts all .cdat.zst files in the source directory to .png files in the target directory
+func (f *FilesConverter) CdatToPng(fromDir, toDir string) error {
+	return f.RunConversion("cdat2png", fromDir, toDir)
+}
+
+// DataToWebp converts all .data files in the source directory to lossless .webp files in the target directory
+func (f *FilesConverter) DataToWebp(fromDir, toDir string) error {
+	return f.RunConversion("data2webp", fromDir, toDir)
+}
+
+// WebpToData converts all .webp files in the source directory to .data files in the target directory
+func (f *FilesConverter) WebpToData(fromDir, toDir string) error {
+	return f.RunConversion("webp2data", fromDir, toDir)
+}
+
+// Convert converts all files with fromExt in the source directory to toExt files in the target directory
+// using convertFunc, so formats handled outside this package can reuse the batch pipeline
+func (f *FilesConverter) Convert(fromDir, toDir, fromExt, toExt string, convertFunc func(io.Reader, io.Writer) error) error {
+	return f.ConvertContext(context.Background(), fromDir, toDir, fromExt, toExt, convertFunc)
+}
+
+// ConvertContext is like Convert but stops when ctx is cancelled, removing partially written outputs
+func (f *FilesConverter) ConvertContext(
+	ctx context.Context,
+	fromDir, toDir, fromExt, toExt string,
+	convertFunc func(io.Reader, io.Writer) error,
+) error {
+	f.log.Infof("Converting %s -> %s", formatLabel(fromExt), formatLabel(toExt))
+	return f.convert(ctx, fromDir, toDir, fromExt, toExt, convertFunc)
 }
 
 // ConversionTask represents a single file conversion task
@@ -58,129 +186,619 @@ type ConversionTask struct {
 	index      int
 	totalFiles int
 	relPath    string
-	inputPath  string
+	inputPath  string // Display path of the input, inside the archive for zip sources
 	outputPath string
+	source     fs.FS      // Filesystem the input is read from, relPath being its path within it
+	heartbeat  *heartbeat // Touched as the task reads and writes, nil without a watchdog
+}
+
+// conversionBatch holds the settings and results shared by all tasks of one convert call
+type conversionBatch struct {
+	toDir          string
+	fromExt        string
+	toExt          string
+	conversion     string // Human readable label such as "DATA -> PNG"
+	convertFunc    func(io.Reader, io.Writer) error
+	sink           outputSink
+	fileProvenance []FileProvenance // Indexed by task index - 1, only set when provenance is enabled
+	indexRecords   []AssetRecord    // Indexed by task index - 1, only set when an index is enabled
 }
 
 // convert does the actual conversion between file formats using goroutines for parallelism
 func (f *FilesConverter) convert(
+	ctx context.Context,
 	fromDir, toDir string,
 	fromExt, toExt string,
 	convertFunc func(io.Reader, io.Writer) error,
 ) error {
-	f.log.Infof("From directory: %s", fromDir)
-	f.log.Infof("To directory: %s", toDir)
+	return f.convertSources(ctx, []string{fromDir}, toDir, fromExt, toExt, convertFunc)
+}
 
-	var files []string
-	err := filepath.Walk(fromDir, func(path string, info os.FileInfo, err error) error {
+// convertSources converts the files of one or more source directories, merged into one tree
+func (f *FilesConverter) convertSources(
+	ctx context.Context,
+	fromDirs []string, toDir string,
+	fromExt, toExt string,
+	convertFunc func(io.Reader, io.Writer) error,
+) error {
+	sources := make([][]ConversionTask, len(fromDirs))
+	for i, fromDir := range fromDirs {
+		f.log.Infof("From directory: %s", fromDir)
+
+		source, closer, err := openSource(fromDir)
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(path), strings.ToLower(fromExt)) {
-			relPath, err := filepath.Rel(fromDir, path)
-			if err != nil {
-				return err
-			}
-			files = append(files, relPath)
+		defer closer.Close()
+
+		if sources[i], err = f.collectTasks(ctx, source, fromDir, toDir, fromExt, toExt); err != nil {
+			return err
 		}
-		return nil
-	})
+	}
+	f.log.Infof("To directory: %s", toDir)
 
+	tasks, err := f.mergeTasks(sources)
 	if err != nil {
-		return fmt.Errorf("error scanning directory: %w", err)
+		return err
+	}
+	return f.runTasks(ctx, tasks, toDir, nil, fromExt, toExt, convertFunc)
+}
+
+// runTasks converts the collected tasks of a batch, writing below toDir or, if target is set, into target
+// with toDir "."
+func (f *FilesConverter) runTasks(
+	ctx context.Context,
+	tasks []ConversionTask,
+	toDir string, target WritableFS,
+	fromExt, toExt string,
+	convertFunc func(io.Reader, io.Writer) error,
+) error {
+	if err := f.checkTrim(fromExt, toExt); err != nil {
+		return err
+	}
+	var err error
+	var resumeJournal *journal
+	if f.resume {
+		if hasZipExtension(toDir) || target != nil {
+			return errors.New("conversions into zip archives can't be resumed")
+		}
+		if resumeJournal, err = loadJournal(toDir); err != nil {
+			return err
+		}
+		defer resumeJournal.close()
+		tasks = f.skipCompleted(resumeJournal, tasks)
+	}
+	if f.incremental {
+		tasks = f.skipUpToDate(tasks)
+	}
+	if !hasZipExtension(toDir) && target == nil {
+		if tasks, err = f.applyOverwritePolicy(tasks, toExt); err != nil {
+			return err
+		}
 	}
 
-	f.log.Infof("%d files to convert", len(files))
+	f.log.Infof("%d files to convert", len(tasks))
+
+	if f.dryRun || f.plan {
+		plan := planTasks(tasks)
+		if f.plan {
+			if err := f.comparePlan(ctx, tasks, plan, convertFunc); err != nil {
+				return err
+			}
+		}
+		f.logPlan(plan)
+		return nil // Nothing is written in dry-run mode
+	}
 
-	if len(files) == 0 {
+	if len(tasks) == 0 {
+		if resumeJournal != nil {
+			resumeJournal.remove()
+		}
 		return nil // No files to convert
 	}
 
 	var wg sync.WaitGroup
 
-	errChan := make(chan error, len(files))
-
-	// Create task queue
-	taskQueue := make(chan ConversionTask, len(files))
+	// Indexed by task index - 1 so failures are reported in input order
+	failures := make([]error, len(tasks))
 
-	if err := os.MkdirAll(toDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory '%s': %w", toDir, err)
+	if f.index != nil && hasZipExtension(toDir) {
+		return errors.New("assets written into zip archives can't be indexed")
+	}
+	if f.dedupe != DedupeOff && hasZipExtension(toDir) {
+		return errors.New("outputs written into zip archives can't be deduplicated")
+	}
+	if f.preserveAttributes && hasZipExtension(toDir) {
+		return errors.New("outputs written into zip archives can't keep their inputs' attributes")
 	}
 
-	for i, relPath := range files {
-		inputPath := filepath.Join(fromDir, relPath)
-		outputDir := filepath.Join(toDir, filepath.Dir(relPath))
-		outputPath := filepath.Join(outputDir, strings.TrimSuffix(filepath.Base(relPath), fromExt)+toExt)
-
-		taskQueue <- ConversionTask{
-			index:      i + 1,
-			totalFiles: len(files),
-			relPath:    relPath,
-			inputPath:  inputPath,
-			outputPath: outputPath,
+	var sink outputSink = fsSink{target}
+	if target == nil {
+		if sink, err = newOutputSink(toDir, f.deterministic); err != nil {
+			return err
 		}
 	}
-	close(taskQueue) // No more tasks will be added
+
+	queues := f.newTaskQueues(tasks)
 
 	// Create a mutex for synchronized logging
 	var logMutex sync.Mutex
 
+	batch := &conversionBatch{
+		toDir:       toDir,
+		fromExt:     fromExt,
+		toExt:       toExt,
+		conversion:  formatLabel(fromExt) + " -> " + formatLabel(toExt),
+		convertFunc: convertFunc,
+		sink:        sink,
+	}
+	if f.provenance {
+		batch.fileProvenance = make([]FileProvenance, len(tasks))
+	}
+	if f.index != nil {
+		batch.indexRecords = make([]AssetRecord, len(tasks))
+	}
+
+	var ordered *orderedOutput
+	if f.orderedOutput {
+		ordered = newOrderedOutput(f.log)
+	}
+
+	progress := newProgressTracker(f.progressHook, len(tasks))
+	progress.emit(ProgressEvent{Type: BatchStarted})
+
 	// Start worker goroutines
 	for w := 0; w < f.maxWorkers; w++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			for task := range taskQueue {
-				logMutex.Lock()
-				f.log.Infof("[%d/%d] converting %s", task.index, task.totalFiles, task.relPath)
-				logMutex.Unlock()
-
-				outputDir := filepath.Dir(task.outputPath)
-				if err := os.MkdirAll(outputDir, 0755); err != nil {
-					errChan <- fmt.Errorf("failed to create output directory '%s': %w", outputDir, err)
-					continue
+			for task := range queues.forWorker(w) {
+				// Stop picking up new work once cancelled or stopped, and hold it while paused
+				if ctx.Err() != nil {
+					return
 				}
-
-				inputFile, err := os.Open(task.inputPath)
-				if err != nil {
-					errChan <- fmt.Errorf("failed to open input file '%s': %w", task.inputPath, err)
-					continue
+				if err := f.pause.wait(ctx); err != nil {
+					return
 				}
-
-				outputFile, err := os.Create(task.outputPath)
-				if err != nil {
-					inputFile.Close()
-					errChan <- fmt.Errorf("failed to create output file '%s': %w", task.outputPath, err)
-					continue
+				if f.fileLimiter != nil {
+					if err := f.fileLimiter.Wait(ctx); err != nil {
+						return
+					}
+					if f.Stopped() {
+						return
+					}
 				}
 
-				err = convertFunc(inputFile, outputFile)
-				if err != nil {
-					errChan <- fmt.Errorf("failed to convert file '%s': %w", task.relPath, err)
-					continue
+				log, lines := f.log, (*logBuffer)(nil)
+				if ordered != nil {
+					lines = new(logBuffer)
+					log = lines
 				}
 
-				err = inputFile.Close()
-				if err != nil {
-					return
-				}
+				logMutex.Lock()
+				logf(log, f.fileLogLevel(), "[%d/%d] converting %s", task.index, task.totalFiles, task.relPath)
+				logMutex.Unlock()
 
-				err = outputFile.Close()
+				progress.fileStarted(task)
+				started := time.Now()
+				metrics, err := f.runWatched(ctx, batch, task)
+				duration := time.Since(started)
+				progress.fileDone(task, metrics, duration, err)
+				logFileResult(log, f.fileLogLevel(), task, duration, err)
 				if err != nil {
-					return
+					// Reading a stalled input again would likely hang the worker too
+					if f.quarantineDir != "" && ctx.Err() == nil && !errors.Is(err, ErrStalled) {
+						if qErr := f.quarantine(task, err); qErr != nil {
+							log.Warnf("Failed to quarantine %s: %v", task.relPath, qErr)
+						}
+					}
+					failures[task.index-1] = err
+				} else if resumeJournal != nil {
+					if jErr := resumeJournal.record(task); jErr != nil {
+						log.Warnf("Failed to record %s in the journal: %v", task.relPath, jErr)
+					}
+				}
+				if ordered != nil {
+					ordered.release(task.index, lines)
 				}
-
 			}
 		}()
 	}
 
 	wg.Wait()
-	close(errChan)
+	if ordered != nil {
+		ordered.flush()
+	}
 
-	for err := range errChan {
+	progress.emit(ProgressEvent{Type: BatchFinished})
+	f.log.Infof("%d files converted, %d failed, %d bytes read in %v", progress.completed.Load(), progress.failed.Load(),
+		progress.bytesRead.Load(), time.Since(progress.start).Round(time.Millisecond))
+
+	if err := ctx.Err(); err != nil {
+		sink.abort()
 		return err
 	}
+	// The files converted before stopping are complete, so a zip archive keeps them
+	if f.Stopped() {
+		if err := sink.close(); err != nil {
+			return err
+		}
+		return ErrStopped
+	}
+
+	var errs []error
+	for _, err := range failures {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 && !f.continueOnError {
+		sink.close()
+		return errs[0]
+	}
+
+	if f.provenance {
+		provenance := BatchProvenance{
+			Converter:  converterName,
+			Version:    Version,
+			Conversion: batch.conversion,
+			Options:    f.provenanceOptions,
+			Files:      make([]FileProvenance, 0, len(tasks)),
+		}
+		for _, file := range batch.fileProvenance {
+			if file.Source != "" { // Failed files have no provenance
+				provenance.Files = append(provenance.Files, file)
+			}
+		}
+		sort.Slice(provenance.Files, func(i, j int) bool {
+			return provenance.Files[i].Source < provenance.Files[j].Source
+		})
+		if err := writeJSONOutput(sink, filepath.Join(toDir, ProvenanceFileName), provenance); err != nil {
+			sink.close()
+			return fmt.Errorf("failed to write batch provenance: %w", err)
+		}
+	}
+
+	if f.index != nil {
+		if err := f.putIndexRecords(batch); err != nil {
+			sink.close()
+			return err
+		}
+	}
+
+	if f.dedupe != DedupeOff {
+		if err := f.dedupeOutputs(tasks, failures, toDir); err != nil {
+			sink.close()
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		sink.close()
+		return fmt.Errorf("%d of %d files failed to convert:\n%w", len(errs), len(tasks), errors.Join(errs...))
+	}
+
+	if resumeJournal != nil {
+		resumeJournal.remove()
+	}
+	return sink.close()
+}
+
+// collectTasks walks source, the contents of fromDir, or the file list if there is one, for files with
+// fromExt and maps each to its output path in toDir
+func (f *FilesConverter) collectTasks(ctx context.Context, source fs.FS, fromDir, toDir, fromExt, toExt string) ([]ConversionTask, error) {
+	var files []string
+	exts := make(map[string]string) // Extension replaced in each file's output name
+	walk := f.walkSource
+	if f.fileList != nil {
+		walk = f.walkFileList
+	}
+	err := walk(source, fromDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != "." && f.excludedDir(path) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if f.filtered(path) {
+			return nil
+		}
+
+		if f.sniff {
+			matches, err := f.sniffMatches(source, path, fromExt)
+			if err != nil || !matches {
+				return err
+			}
+			exts[path] = fileExtension(path)
+		} else if strings.HasSuffix(strings.ToLower(path), strings.ToLower(fromExt)) {
+			exts[path] = fromExt
+		} else {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	if f.outputTemplate != nil && f.rewritesPaths() {
+		return nil, errors.New("output templates can't be combined with flattening or path prefixes")
+	}
+	tasks := make([]ConversionTask, 0, len(files))
+	for i, path := range files {
+		relPath := filepath.FromSlash(path)
+		outputPath := outputPathFor(toDir, relPath, exts[path], toExt)
+		if f.outputTemplate != nil {
+			if outputPath, err = f.outputTemplate.outputPath(source, toDir, path, exts[path], toExt); err != nil {
+				return nil, err
+			}
+		}
+		tasks = append(tasks, ConversionTask{
+			index:      i + 1,
+			totalFiles: len(files),
+			relPath:    relPath,
+			inputPath:  filepath.Join(fromDir, relPath),
+			outputPath: outputPath,
+			source:     source,
+		})
+	}
+
+	if f.outputTemplate != nil {
+		if err := checkOutputCollisions(tasks); err != nil {
+			return nil, fmt.Errorf("output template: %w", err)
+		}
+	} else if f.rewritesPaths() {
+		if err := f.rewriteOutputPaths(tasks, toDir); err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
+
+// outputPathFor maps an input path relative to the source directory to its output path in toDir
+func outputPathFor(toDir, relPath, fromExt, toExt string) string {
+	outputDir := filepath.Join(toDir, filepath.Dir(relPath))
+	return filepath.Join(outputDir, strings.TrimSuffix(filepath.Base(relPath), fromExt)+toExt)
+}
+
+// convertTask converts a single file and returns the number of input bytes consumed.
+// A partially written output is removed if conversion fails or ctx is cancelled mid-conversion.
+func (f *FilesConverter) convertTask(ctx context.Context, batch *conversionBatch, task ConversionTask) (taskMetrics, error) {
+	var provenance *Provenance
+	if f.provenance {
+		sourceHash, err := hashTaskInput(task)
+		if err != nil {
+			return taskMetrics{}, fmt.Errorf("failed to hash input file '%s': %w", task.inputPath, err)
+		}
+		provenance = &Provenance{
+			Converter:    converterName,
+			Version:      Version,
+			Conversion:   batch.conversion,
+			Options:      f.provenanceOptions,
+			Source:       filepath.ToSlash(task.relPath),
+			SourceSHA256: sourceHash,
+			Output:       filepath.ToSlash(relOrSelf(batch.toDir, task.outputPath)),
+		}
+	}
+
+	convertFunc := batch.convertFunc
+	var trimmed *TrimInfo
+	if f.trim {
+		var err error
+		if convertFunc, trimmed, err = f.trimmedConvert(batch, task); err != nil {
+			return taskMetrics{}, err
+		}
+	}
+
+	inputFile, err := task.open()
+	if err != nil {
+		return taskMetrics{}, err
+	}
+	if f.memoryMap && f.ioSlots == nil {
+		inputFile = mapInput(inputFile)
+	}
+	defer inputFile.Close()
+
+	var source io.Reader = inputFile
+	if f.byteLimiter != nil {
+		source = &rateLimitedReader{ctx: ctx, limiter: f.byteLimiter, r: inputFile}
+	}
+	var outputFile outputFile
+	if f.ioSlots != nil {
+		// Read the input now and write the output after converting, each while holding an I/O slot
+		if source, err = f.readStaged(ctx, source, task.heartbeat); err != nil {
+			if ctx.Err() != nil {
+				return taskMetrics{}, ctx.Err()
+			}
+			return taskMetrics{}, fmt.Errorf("failed to read file '%s': %w", task.relPath, err)
+		}
+		outputFile = &stagedOutput{ctx: ctx, f: f, sink: batch.sink, path: task.outputPath}
+	} else if outputFile, err = batch.sink.create(task.outputPath); err != nil {
+		return taskMetrics{}, err
+	}
+
+	output := &countingWriter{w: outputFile}
+	var writer io.Writer = output
+	var outputHash hash.Hash
+	if f.manifest != nil {
+		outputHash = sha256.New()
+		writer = io.MultiWriter(output, outputHash)
+	}
+	if provenance != nil && batch.toExt == ".png" {
+		writer = newPngTextWriter(writer, provenance.textChunks())
+	}
+	if task.heartbeat != nil {
+		writer = &heartbeatWriter{w: writer, heartbeat: task.heartbeat}
+	}
+
+	reader := &contextReader{ctx: ctx, r: source, heartbeat: task.heartbeat}
+	if batch.fromExt == ".data" {
+		reader.head = new(dataHeaderRecorder)
+	} else if batch.toExt == ".data" {
+		output.head = new(dataHeaderRecorder)
+	}
+	if f.cache != nil && !f.trim {
+		err = f.cache.convert(f.log, batch.fromExt, batch.toExt, convertFunc, reader, writer)
+	} else {
+		err = safeConvert(convertFunc, reader, writer)
+	}
+	if ctx.Err() != nil {
+		outputFile.discard()
+		return newTaskMetrics(reader, output), ctx.Err()
+	}
+	if err != nil {
+		outputFile.discard()
+		return newTaskMetrics(reader, output), fmt.Errorf("failed to convert file '%s': %w", task.relPath, err)
+	}
+	if f.overwriteBackup.Enabled() {
+		if err := f.backupOutput(batch.toDir, task.outputPath); err != nil {
+			outputFile.discard()
+			return newTaskMetrics(reader, output), err
+		}
+	}
+	if err := outputFile.commit(); err != nil {
+		return newTaskMetrics(reader, output), err
+	}
+	if outputHash != nil {
+		f.manifest.Add(filepath.ToSlash(relOrSelf(batch.toDir, task.outputPath)), hex.EncodeToString(outputHash.Sum(nil)))
+	}
+	if f.preserveAttributes {
+		if err := copyAttributes(inputFile, task.outputPath); err != nil {
+			return newTaskMetrics(reader, output), fmt.Errorf("failed to copy the attributes of '%s': %w", task.relPath, err)
+		}
+	}
+
+	if trimmed != nil && trimmed.Width > 0 {
+		if err := writeJSONOutput(batch.sink, task.outputPath+TrimSidecarSuffix, trimmed); err != nil {
+			return newTaskMetrics(reader, output), fmt.Errorf("failed to write trim sidecar for '%s': %w", task.relPath, err)
+		}
+	}
+
+	if provenance != nil {
+		if err := writeJSONOutput(batch.sink, task.outputPath+provenanceSidecarSuffix, provenance); err != nil {
+			return newTaskMetrics(reader, output), fmt.Errorf("failed to write provenance for '%s': %w", task.relPath, err)
+		}
+		batch.fileProvenance[task.index-1] = FileProvenance{
+			Source:       provenance.Source,
+			SourceSHA256: provenance.SourceSHA256,
+			Output:       provenance.Output,
+		}
+	}
+
+	if f.index != nil {
+		record, err := f.indexRecord(batch, task)
+		if err != nil {
+			return newTaskMetrics(reader, output), fmt.Errorf("failed to index '%s': %w", task.relPath, err)
+		}
+		batch.indexRecords[task.index-1] = record
+	}
+
+	return newTaskMetrics(reader, output), nil
+}
 
-	return nil
+// logFileResult logs the outcome of a single file with structured fields, so machine-readable logs
+// can tell which files were converted
+func logFileResult(log Logger, level LogLevel, task ConversionTask, duration time.Duration, err error) {
+	fields := map[string]any{
+		"file":     filepath.ToSlash(task.relPath),
+		"index":    task.index,
+		"total":    task.totalFiles,
+		"duration": duration.Seconds(),
+	}
+	if err != nil {
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			fields["stack"] = panicErr.Stack
+		}
+		fields["status"] = "failed"
+		fields["error"] = err
+		logFields(log, LevelError, "File failed", fields)
+		return
+	}
+	fields["status"] = "ok"
+	logFields(log, level, "File converted", fields)
+}
+
+// contextReader fails reads once its context is done, so long conversions stop promptly on cancellation.
+// It also counts the bytes read for progress reporting.
+type contextReader struct {
+	ctx       context.Context
+	r         io.Reader
+	n         int64
+	heartbeat *heartbeat          // Touched after every read, nil if unwatched
+	head      *dataHeaderRecorder // Keeps the DATA header of the input for statistics, nil if not DATA
+}
+
+// NewContextReader returns a reader failing with ctx's error once ctx is done, so long conversions of
+// single files can be cancelled like batches. Memory-mapped inputs from OpenMapped are still decoded in place.
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.head != nil {
+		c.head.record(p[:n])
+	}
+	if c.heartbeat != nil {
+		c.heartbeat.touch()
+	}
+	return n, err
+}
+
+// formatLabel turns a file extension such as ".data" into a label such as "DATA"
+func formatLabel(ext string) string {
+	label, _, _ := strings.Cut(strings.TrimPrefix(ext, "."), ".")
+	return strings.ToUpper(label)
+}
+
+// hashFile returns the hex-encoded SHA-256 of a file's contents
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return hashReader(file)
+}
+
+// hashTaskInput returns the hex-encoded SHA-256 of a task's input
+func hashTaskInput(task ConversionTask) (string, error) {
+	file, err := task.open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return hashReader(file)
+}
+
+// hashReader returns the hex-encoded SHA-256 of everything read from r
+func hashReader(r io.Reader) (string, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// relOrSelf returns path relative to base, falling back to path itself when no relative path exists
+func relOrSelf(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return path
+	}
+	return rel
 }
@@ -1,9 +1,15 @@
 package converter
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -158,6 +164,101 @@ func TestRoundTripConversion(t *testing.T) {
 	}
 }
 
+func TestFileConverterContextCancelled(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	setupTestDataFiles(t, fromDir)
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := filesConverter.DataToPngContext(ctx, fromDir, toDir)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	entries, err := os.ReadDir(toDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no output files after cancellation, found %d", len(entries))
+	}
+}
+
+func TestFileConverterContextCleansUpPartialOutput(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Write some output, then cancel and keep reading as a long conversion would
+	convertFunc := func(input io.Reader, output io.Writer) error {
+		if _, err := output.Write([]byte("partial")); err != nil {
+			return err
+		}
+		cancel()
+		_, err := io.Copy(io.Discard, input)
+		return err
+	}
+
+	err := filesConverter.ConvertContext(ctx, fromDir, toDir, ".data", ".png", convertFunc)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(toDir, "red.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected partial output to be removed, got %v", err)
+	}
+}
+
+// TestFileResultLogging tests that every converted file is logged with structured fields
+func TestFileResultLogging(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+
+	output := new(bytes.Buffer)
+	logger := SlogLogger(slog.New(slog.NewJSONHandler(output, nil)))
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetLogger(logger)
+
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	found := false
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Log line is not JSON: %s", line)
+		}
+		if entry["file"] != "red.data" {
+			continue
+		}
+		found = true
+		if entry["status"] != "ok" || entry["index"] != float64(1) || entry["total"] != float64(1) {
+			t.Errorf("Unexpected file result entry: %v", entry)
+		}
+		if _, ok := entry["duration"].(float64); !ok {
+			t.Errorf("Expected numeric duration, got %v", entry["duration"])
+		}
+	}
+	if !found {
+		t.Errorf("Expected a log entry for red.data, got %s", output.String())
+	}
+}
+
 // Helper functions for setting up test files
 
 func setupTestDataFiles(t *testing.T, dir string) {
@@ -216,3 +317,39 @@ func copyFile(t *testing.T, sourcePath, destPath string) {
 		t.Fatalf("Failed to copy file content from %s to %s: %v", sourcePath, destPath, err)
 	}
 }
+
+// TestFileConverterContinueOnError tests that every failed file is reported and the rest still converted
+func TestFileConverterContinueOnError(t *testing.T) {
+	fromDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+	for _, name := range []string{"broken-a.data", "broken-b.data"} {
+		if err := os.WriteFile(filepath.Join(fromDir, name), []byte("not a texture"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+
+	// Only the first failure is returned by default
+	err := filesConverter.DataToPng(fromDir, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "broken-a.data") || strings.Contains(err.Error(), "broken-b.data") {
+		t.Errorf("Expected only the first failure, got %v", err)
+	}
+
+	toDir := t.TempDir()
+	filesConverter.SetContinueOnError(true)
+	err = filesConverter.DataToPng(fromDir, toDir)
+	if err == nil {
+		t.Fatal("Expected an error for the broken files")
+	}
+	for _, want := range []string{"2 of 3 files failed", "broken-a.data", "broken-b.data"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %v", want, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(toDir, "red.png")); err != nil {
+		t.Errorf("Expected the valid file to be converted: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package converter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SetFilter limits batch conversions to inputs whose path relative to the source directory matches
+// at least one include pattern (or any path when there are none) and no exclude pattern.
+// Patterns are slash-separated globs as understood by path.Match, where a "**" segment matches
+// any number of directories, such as "Gameplay/characters/**" or "**/*_old.png".
+func (f *FilesConverter) SetFilter(include, exclude []string) error {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if err := validateGlob(pattern); err != nil {
+			return err
+		}
+	}
+	f.include = include
+	f.exclude = exclude
+	return nil
+}
+
+// filtered reports whether the filter leaves out the file at relPath, a slash-separated path
+func (f *FilesConverter) filtered(relPath string) bool {
+	for _, pattern := range f.exclude {
+		if matchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	if len(f.include) == 0 {
+		return false
+	}
+	for _, pattern := range f.include {
+		if matchGlob(pattern, relPath) {
+			return false
+		}
+	}
+	return true
+}
+
+// excludedDir reports whether an exclude pattern ending in "/**" covers the whole directory at relPath,
+// so it doesn't need to be walked
+func (f *FilesConverter) excludedDir(relPath string) bool {
+	for _, pattern := range f.exclude {
+		if dir, ok := strings.CutSuffix(pattern, "/**"); ok && matchGlob(dir, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateGlob checks that every segment of a pattern is valid for path.Match
+func validateGlob(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty pattern")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether a slash-separated path matches a pattern whose "**" segments match
+// zero or more directories
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
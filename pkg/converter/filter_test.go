@@ -0,0 +1,67 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMatchGlob tests "**" and single-segment wildcards
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"Gameplay/characters/**", "Gameplay/characters/player/idle00.data", true},
+		{"Gameplay/characters/**", "Gameplay/characters", true},
+		{"Gameplay/characters/**", "Gameplay/objects/door.data", false},
+		{"**/*.data", "idle00.data", true},
+		{"**/*.data", "a/b/c/idle00.data", true},
+		{"**/*.data", "a/b/c/idle00.png", false},
+		{"Gui/*", "Gui/title.data", true},
+		{"Gui/*", "Gui/menu/title.data", false},
+		{"a/**/b/*.png", "a/b/x.png", true},
+		{"a/**/b/*.png", "a/x/y/b/x.png", true},
+		{"*.png", "sub/x.png", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestFilterSelectsInputs tests include and exclude patterns on a batch conversion
+func TestFilterSelectsInputs(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	for _, relPath := range []string{"Gameplay/characters/red.data", "Gameplay/characters/old/blue.data", "Gameplay/objects/green.data", "Gui/white.data"} {
+		if err := os.MkdirAll(filepath.Join(fromDir, filepath.Dir(relPath)), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		copyFile(t, filepath.Join("testdata", "data", filepath.Base(relPath)), filepath.Join(fromDir, relPath))
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	if err := filesConverter.SetFilter([]string{"Gameplay/**", "Gui/*"}, []string{"**/old/**", "Gui/**"}); err != nil {
+		t.Fatalf("SetFilter failed: %v", err)
+	}
+	plan, err := filesConverter.PlanConversion(fromDir, toDir, ".data", ".png")
+	if err != nil {
+		t.Fatalf("PlanConversion failed: %v", err)
+	}
+
+	var got []string
+	for _, p := range plan {
+		got = append(got, filepath.ToSlash(p.RelPath))
+	}
+	want := []string{"Gameplay/characters/red.data", "Gameplay/objects/green.data"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if err := filesConverter.SetFilter([]string{"[bad"}, nil); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,234 @@
+package converter
+
+import (
+	"fmt"
+	"html/template"
+	"image"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// GalleryEntry describes one texture of a gallery
+type GalleryEntry struct {
+	Name      string // Slash-separated path without extension
+	Source    string // Path of the texture relative to the source directory
+	Image     string // Full-size PNG, relative to the site directory
+	Thumbnail string // Thumbnail PNG, relative to the site directory
+	Width     int
+	Height    int
+}
+
+// GalleryBuilder exports texture trees as static HTML galleries
+type GalleryBuilder struct {
+	graphicsConverter *GraphicsConverter
+	log               Logger
+	thumbnailSize     int
+	maxWorkers        int
+}
+
+// NewGalleryBuilder creates a new GalleryBuilder instance
+func NewGalleryBuilder(graphicsConverter *GraphicsConverter) *GalleryBuilder {
+	return &GalleryBuilder{
+		graphicsConverter: graphicsConverter,
+		log:               DefaultLogger(),
+		thumbnailSize:     128,
+		maxWorkers:        runtime.NumCPU(),
+	}
+}
+
+// SetThumbnailSize sets the largest thumbnail width and height in pixels
+func (b *GalleryBuilder) SetThumbnailSize(size int) {
+	if size > 0 {
+		b.thumbnailSize = size
+	}
+}
+
+// SetMaxWorkers allows overriding the number of textures converted in parallel
+func (b *GalleryBuilder) SetMaxWorkers(workers int) {
+	if workers > 0 {
+		b.maxWorkers = workers
+	}
+}
+
+// Build converts every texture below fromDir to PNG in siteDir/images, writes thumbnails to siteDir/thumbs
+// and generates siteDir/index.html listing them with their names and dimensions.
+// Textures that fail to decode are logged and left out.
+func (b *GalleryBuilder) Build(fromDir, siteDir string) ([]GalleryEntry, error) {
+	var sources []string
+	err := filepath.WalkDir(fromDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && textureExtension(filePath) != "" {
+			relPath, err := filepath.Rel(fromDir, filePath)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	b.log.Infof("%d textures to export", len(sources))
+
+	entries := make([]*GalleryEntry, len(sources))
+	indexes := make(chan int, len(sources))
+	for i := range sources {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < b.maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				entry, err := b.exportTexture(fromDir, siteDir, sources[i])
+				if err != nil {
+					b.log.Warnf("Skipping %s: %v", sources[i], err)
+					continue
+				}
+				entries[i] = entry
+			}
+		}()
+	}
+	wg.Wait()
+
+	gallery := make([]GalleryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry != nil {
+			gallery = append(gallery, *entry)
+		}
+	}
+	sort.Slice(gallery, func(i, j int) bool {
+		return gallery[i].Name < gallery[j].Name
+	})
+
+	if err := writeGalleryIndex(filepath.Join(siteDir, "index.html"), gallery); err != nil {
+		return nil, err
+	}
+	return gallery, nil
+}
+
+// exportTexture writes the full-size PNG and thumbnail of a single texture
+func (b *GalleryBuilder) exportTexture(fromDir, siteDir, relPath string) (*GalleryEntry, error) {
+	img, err := b.graphicsConverter.decodeFile(filepath.Join(fromDir, relPath))
+	if err != nil {
+		return nil, err
+	}
+
+	slashPath := filepath.ToSlash(relPath)
+	name := slashPath[:len(slashPath)-len(textureExtension(slashPath))]
+	entry := &GalleryEntry{
+		Name:      name,
+		Source:    slashPath,
+		Image:     path.Join("images", name+".png"),
+		Thumbnail: path.Join("thumbs", name+".png"),
+		Width:     img.Bounds().Dx(),
+		Height:    img.Bounds().Dy(),
+	}
+
+	for _, output := range []struct {
+		path string
+		img  image.Image
+	}{
+		{entry.Image, img},
+		{entry.Thumbnail, thumbnail(img, b.thumbnailSize)},
+	} {
+		outputPath := filepath.Join(siteDir, filepath.FromSlash(output.path))
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+		if err := b.graphicsConverter.encodeFile(output.img, outputPath); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// thumbnail scales img down with nearest-neighbour sampling, keeping pixel art crisp,
+// so that it fits within size×size. Smaller images are returned unchanged.
+func thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	thumbWidth, thumbHeight := size, size
+	if width > height {
+		thumbHeight = max(1, height*size/width)
+	} else {
+		thumbWidth = max(1, width*size/height)
+	}
+
+	thumb := image.NewNRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		for x := 0; x < thumbWidth; x++ {
+			thumb.Set(x, y, img.At(bounds.Min.X+x*width/thumbWidth, bounds.Min.Y+y*height/thumbHeight))
+		}
+	}
+	return thumb
+}
+
+// writeGalleryIndex renders the gallery page
+func writeGalleryIndex(indexPath string, entries []GalleryEntry) error {
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return fmt.Errorf("failed to create site directory: %w", err)
+	}
+	file, err := os.Create(indexPath)
+	if err != nil {
+		return fmt.Errorf("failed to create '%s': %w", indexPath, err)
+	}
+	if err := galleryTemplate.Execute(file, entries); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write '%s': %w", indexPath, err)
+	}
+	return file.Close()
+}
+
+// galleryTemplate is a self-contained page, so the site works when opened from disk
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Texture gallery</title>
+<style>
+body { font-family: sans-serif; margin: 1em; background: #222; color: #eee; }
+input { font-size: 1em; padding: 0.4em; width: 100%; max-width: 30em; margin-bottom: 1em; }
+ul { list-style: none; padding: 0; display: flex; flex-wrap: wrap; gap: 0.8em; }
+li { width: 10em; background: #333; padding: 0.5em; border-radius: 4px; word-break: break-all; font-size: 0.8em; }
+li a { display: flex; align-items: center; justify-content: center; height: 8em;
+	background: repeating-conic-gradient(#555 0% 25%, #444 0% 50%) 0 0 / 16px 16px; }
+img { max-width: 100%; max-height: 100%; image-rendering: pixelated; }
+.size { color: #aaa; }
+</style>
+</head>
+<body>
+<input id="search" type="search" placeholder="Search {{len .}} textures" autofocus>
+<ul id="gallery">
+{{- range .}}
+<li data-name="{{.Name}}"><a href="{{.Image}}"><img src="{{.Thumbnail}}" alt="{{.Name}}" loading="lazy"></a>
+<div>{{.Name}}</div><div class="size">{{.Width}}×{{.Height}}</div></li>
+{{- end}}
+</ul>
+<script>
+document.getElementById("search").addEventListener("input", function () {
+	var query = this.value.toLowerCase();
+	document.querySelectorAll("#gallery li").forEach(function (item) {
+		item.hidden = item.dataset.name.toLowerCase().indexOf(query) < 0;
+	});
+});
+</script>
+</body>
+</html>
+`))
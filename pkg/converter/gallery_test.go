@@ -0,0 +1,74 @@
+package converter
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGalleryBuild tests that every texture gets an image, a thumbnail and an entry on the page
+func TestGalleryBuild(t *testing.T) {
+	fromDir := t.TempDir()
+	siteDir := filepath.Join(t.TempDir(), "site")
+
+	copyFile(t, filepath.Join("testdata", "data", "big-test.data"), filepath.Join(fromDir, "big-test.data"))
+	if err := os.MkdirAll(filepath.Join(fromDir, "player"), 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "png", "red.png"), filepath.Join(fromDir, "player", "red.png"))
+
+	builder := NewGalleryBuilder(NewGraphicsConverter())
+	builder.SetThumbnailSize(64)
+	entries, err := builder.Build(fromDir, siteDir)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].Name != "big-test" || entries[1].Name != "player/red" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+
+	graphicsConverter := NewGraphicsConverter()
+	for _, entry := range entries {
+		full, err := graphicsConverter.decodeFile(filepath.Join(siteDir, entry.Image))
+		if err != nil {
+			t.Fatalf("Failed to decode %s: %v", entry.Image, err)
+		}
+		if full.Bounds().Dx() != entry.Width || full.Bounds().Dy() != entry.Height {
+			t.Errorf("%s: expected %dx%d, got %v", entry.Image, entry.Width, entry.Height, full.Bounds().Size())
+		}
+
+		thumb, err := graphicsConverter.decodeFile(filepath.Join(siteDir, entry.Thumbnail))
+		if err != nil {
+			t.Fatalf("Failed to decode %s: %v", entry.Thumbnail, err)
+		}
+		if thumb.Bounds().Dx() > 64 || thumb.Bounds().Dy() > 64 {
+			t.Errorf("%s: thumbnail too large: %v", entry.Thumbnail, thumb.Bounds().Size())
+		}
+	}
+
+	page, err := os.ReadFile(filepath.Join(siteDir, "index.html"))
+	if err != nil {
+		t.Fatalf("Failed to read index.html: %v", err)
+	}
+	for _, want := range []string{`data-name="player/red"`, `src="thumbs/big-test.png"`, "2048×2048", `id="search"`} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("Expected index.html to contain %q", want)
+		}
+	}
+}
+
+// TestThumbnailKeepsAspectRatio tests that thumbnails fit the size and keep the aspect ratio
+func TestThumbnailKeepsAspectRatio(t *testing.T) {
+	wide := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+	if size := thumbnail(wide, 100).Bounds().Size(); size != image.Pt(100, 25) {
+		t.Errorf("Expected 100x25 thumbnail, got %v", size)
+	}
+
+	small := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	if thumbnail(small, 100) != image.Image(small) {
+		t.Error("Expected small images to be used as their own thumbnail")
+	}
+}
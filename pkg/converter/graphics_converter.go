@@ -1,181 +1,322 @@
 package converter
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"image"
-	"image/color"
 	"image/png"
 	"io"
+)
 
-	"github.com/sirupsen/logrus"
+// Default limits for decoded images. Decoded pixels take 4 bytes each, so the memory budget
+// fits an 8192×8192 texture or a 16384×4096 atlas page.
+const (
+	DefaultMaxDimension   = 16384
+	DefaultMaxImageMemory = 256 << 20
 )
 
+// MaxRunLength is the longest run a DATA run-length count can describe, stored as 0
+const MaxRunLength = 256
+
+// ErrImageTooLarge is returned for images exceeding the maximum dimension or memory budget
+var ErrImageTooLarge = errors.New("image is too large")
+
 // GraphicsConverter handles the conversion between the Celeste DATA format and PNG images
 type GraphicsConverter struct {
-	log *logrus.Logger
+	log            Logger
+	dither         DitherMethod // How 16-bit PNGs are reduced to 8 bits
+	maxDimension   int          // Largest accepted width and height
+	maxImageMemory int64        // Largest pixel buffer in bytes allocated for a decoded image
+	alphaMode      AlphaMode
+	alphaChannel   AlphaChannel
+	maxRunLength   int // Longest run written when encoding DATA
+	imageWorkers   int // Goroutines decoding or encoding a single large DATA image
+	strict         bool
+	extended       bool // Allow the non-vanilla extended DATA conversions
+	pngEncoder     *png.Encoder
+	colorManaged   bool // Convert PNGs declaring another color space to sRGB, see SetColorManagement
+	pngPalette     PngPalette
+	transforms     []Transform  // Applied between decoding and encoding
+	endianness     Endianness   // Byte order of DATA headers
+	channelOrder   ChannelOrder // Order of the color channels of DATA runs
+}
+
+// NewGraphicsConverter creates a new GraphicsConverter instance, configured by options
+func NewGraphicsConverter(options ...GraphicsOption) *GraphicsConverter {
+	g := &GraphicsConverter{
+		log:            DefaultLogger(),
+		maxDimension:   DefaultMaxDimension,
+		maxImageMemory: DefaultMaxImageMemory,
+		maxRunLength:   MaxRunLength,
+		imageWorkers:   1,
+		pngEncoder:     &png.Encoder{BufferPool: &pngBufferPool{}},
+	}
+	for _, option := range options {
+		option(g)
+	}
+	return g
+}
+
+// SetMaxDimension sets the largest image width and height accepted when decoding
+func (g *GraphicsConverter) SetMaxDimension(pixels int) {
+	if pixels > 0 {
+		g.maxDimension = pixels
+	}
 }
 
-// NewGraphicsConverter creates a new GraphicsConverter instance
-func NewGraphicsConverter() *GraphicsConverter {
-	return &GraphicsConverter{
-		log: logrus.StandardLogger(),
+// SetMaxImageMemory sets the largest pixel buffer in bytes a single decoded image may take.
+// It is checked against the header dimensions before anything is allocated, so a tiny file
+// claiming a huge size is rejected up front.
+func (g *GraphicsConverter) SetMaxImageMemory(bytes int64) {
+	if bytes > 0 {
+		g.maxImageMemory = bytes
 	}
 }
 
+// SetMaxRunLength caps the runs written when encoding DATA at length pixels, between 1 and MaxRunLength.
+// Below 256, no run count is ever written as 0, for decoders that don't treat 0 as 256.
+func (g *GraphicsConverter) SetMaxRunLength(length int) {
+	if length > 0 && length <= MaxRunLength {
+		g.maxRunLength = length
+	}
+}
+
+// checkImageSize validates header dimensions against the limits, for an image decoded
+// with bytesPerPixel bytes per pixel
+func (g *GraphicsConverter) checkImageSize(width, height, bytesPerPixel int) error {
+	if width <= 0 || height <= 0 {
+		return errors.New("invalid image dimensions")
+	}
+	if width > g.maxDimension || height > g.maxDimension {
+		return fmt.Errorf("%w: %dx%d exceeds the maximum of %dx%d", ErrImageTooLarge,
+			width, height, g.maxDimension, g.maxDimension)
+	}
+	if size := int64(width) * int64(height) * int64(bytesPerPixel); size > g.maxImageMemory {
+		return fmt.Errorf("%w: %dx%d needs %d bytes, more than the budget of %d bytes", ErrImageTooLarge,
+			width, height, size, g.maxImageMemory)
+	}
+	return nil
+}
+
 // DataToPng converts from Celeste's DATA format to a PNG image
 func (g *GraphicsConverter) DataToPng(input io.Reader, output io.Writer) error {
-	// Read image header (width, height, alpha flag)
-	var width, height int32
-	var alphaFlag int32 // Changed to int32 to match binary format
-
-	if err := binary.Read(input, binary.LittleEndian, &width); err != nil {
+	img, err := g.decodeData(input)
+	if err != nil {
 		return err
 	}
-	if err := binary.Read(input, binary.LittleEndian, &height); err != nil {
+	defer releaseNRGBA(img)
+
+	transformed, err := g.applyTransforms(img)
+	if err != nil {
 		return err
 	}
-	if err := binary.Read(input, binary.LittleEndian, &alphaFlag); err != nil {
-		return err
+	// Encode to PNG even if we didn't fill all pixels
+	return g.encodePng(output, transformed)
+}
+
+// dataBufferSize is the size of the buffers used when reading and writing DATA streams
+const dataBufferSize = 64 * 1024
+
+// decodeData reads an image in Celeste's DATA format, converting its colors to straight alpha
+// according to the alpha mode
+func (g *GraphicsConverter) decodeData(input io.Reader) (*image.NRGBA, error) {
+	// Memory-mapped inputs are read through the mapping, and decoded in place by the parallel decoder
+	mapped, isMapped := mappedData(input)
+	var mappedReader *bytes.Reader
+	if isMapped {
+		mappedReader = bytes.NewReader(mapped)
+		input = mappedReader
 	}
 
-	hasAlpha := alphaFlag != 0 // Convert integer flag to boolean
+	r := getDataReader(input)
+	defer putDataReader(r)
+
+	header, err := g.ReadDataHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	width, height := header.Width, header.Height
+	hasAlpha := header.AlphaFlag != 0 // Convert integer flag to boolean
 
-	g.log.Infof("DATA image parameters: %dx%d, %s", width, height,
+	g.log.Debugf("DATA image parameters: %dx%d, %s", width, height,
 		boolToFormat(hasAlpha))
 
-	if width <= 0 || height <= 0 || width > 8192 || height > 8192 {
-		return errors.New("invalid image dimensions")
+	if err := g.checkImageSize(int(width), int(height), 4); err != nil {
+		return nil, err
 	}
 
-	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	// The pixels may come from an earlier image, released by callers done with the result
+	img := newPooledNRGBA(image.Rect(0, 0, int(width), int(height)))
+	pix := img.Pix // Stride is exactly width*4, so pixel i starts at pix[i*4]
 
-	for y := 0; y < int(height); y++ {
-		for x := 0; x < int(width); x++ {
-			if hasAlpha {
-				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 0})
-			} else {
-				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
-			}
+	// Pixels not covered by the stream stay transparent, or opaque black without alpha
+	if !hasAlpha {
+		for p := 3; p < len(pix); p += 4 {
+			pix[p] = 255
 		}
 	}
 
+	total := int(width) * int(height)
+	if g.splitImage(total) && isMapped {
+		consumed := len(mapped) - mappedReader.Len() - r.Buffered()
+		err = g.decodeStreamParallel(mapped[consumed:], pix, int(width), hasAlpha)
+		// The stream was parsed to its end, trailing bytes included
+		mappedReader.Seek(0, io.SeekEnd)
+		r.Reset(mappedReader)
+	} else if g.splitImage(total) {
+		err = g.decodeRunsParallel(r, pix, int(width), hasAlpha)
+	} else {
+		err = g.decodeRuns(r, pix, hasAlpha)
+	}
+	if err == nil {
+		err = g.checkTrailing(r)
+	}
+	if err != nil {
+		releaseNRGBA(img)
+		return nil, err
+	}
+	if g.channelOrder == ChannelsRGB {
+		g.forEachBand(pix, int(width), swapRedBlue)
+	}
+
+	// Opaque pixels are the same either way
+	if hasAlpha && g.alphaMode != AlphaStraight {
+		if g.alphaMode == AlphaPremultiplied || g.bandsAll(pix, int(width), isPremultiplied) {
+			g.forEachBand(pix, int(width), unpremultiplyPix)
+		} else {
+			g.log.Debugf("DATA colors exceed alpha, decoding as straight alpha")
+		}
+	}
+
+	return img, nil
+}
+
+// decodeRuns reads the runs of a DATA stream into pix, stopping early at the end of the stream
+func (g *GraphicsConverter) decodeRuns(r *bufio.Reader, pix []byte, hasAlpha bool) error {
+	total := len(pix) / 4
+	var rgbBuf [3]byte // Declared outside the loop, as it escapes to the heap through io.ReadFull
 	i := 0
-	for i < int(width*height) {
+	for i < total {
 		// Read RLE count
-		var countBuf [1]byte
-		n, err := input.Read(countBuf[:])
+		countByte, err := r.ReadByte()
 		if err != nil {
 			if err == io.EOF {
 				// If we've reached EOF, we'll just use what we have so far
-				g.log.Warnf("Reached end of file with %d/%d pixels processed", i, int(width*height))
-				break
+				return g.truncated(i, total)
 			}
 			return err
 		}
-		if n != 1 {
-			return errors.New("failed to read count byte")
-		}
 
-		count := int(countBuf[0])
+		count := int(countByte)
 		if count == 0 {
 			count = 256 // Treat 0 as 256
 		}
 
-		var r, g, b, a byte = 0, 0, 0, 255 // Default to opaque black
+		var r8, g8, b8, a8 byte = 0, 0, 0, 255 // Default to opaque black
 
 		if hasAlpha {
-			var alphaBuf [1]byte
-			n, err := input.Read(alphaBuf[:])
+			a8, err = r.ReadByte()
 			if err != nil {
 				if err == io.EOF {
-					break
+					return g.truncated(i, total)
 				}
 				return err
 			}
-			if n != 1 {
-				return errors.New("failed to read alpha byte")
-			}
-
-			a = alphaBuf[0]
-
-			// Only read RGB if alpha is non-zero
-			if a != 0 {
-				var rgbBuf [3]byte
-				n, err := io.ReadFull(input, rgbBuf[:])
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
-					return err
-				}
-				if n != 3 {
-					return errors.New("failed to read RGB bytes")
-				}
+		}
 
-				b, g, r = rgbBuf[0], rgbBuf[1], rgbBuf[2]
-			}
-		} else {
-			// Always read RGB for non-alpha images
-			var rgbBuf [3]byte
-			n, err := io.ReadFull(input, rgbBuf[:])
-			if err != nil {
-				if err == io.EOF {
-					break
+		// Alpha images only store RGB for non-transparent runs
+		if !hasAlpha || a8 != 0 {
+			if _, err := io.ReadFull(r, rgbBuf[:]); err != nil {
+				if err == io.EOF || err == io.ErrUnexpectedEOF && g.strict {
+					return g.truncated(i, total)
 				}
 				return err
 			}
-			if n != 3 {
-				return errors.New("failed to read RGB bytes")
-			}
 
-			b, g, r = rgbBuf[0], rgbBuf[1], rgbBuf[2]
+			b8, g8, r8 = rgbBuf[0], rgbBuf[1], rgbBuf[2]
 		}
 
 		// Make sure we don't exceed image bounds
-		pixelsLeft := int(width*height) - i
+		pixelsLeft := total - i
 		if count > pixelsLeft {
+			if err := g.overlong(i, count, total); err != nil {
+				return err
+			}
 			count = pixelsLeft
 		}
 
 		// Apply the run-length encoding
-		c := color.RGBA{R: r, G: g, B: b, A: a}
-		for j := 0; j < count; j++ {
-			x := (i + j) % int(width)
-			y := (i + j) / int(width)
-			img.SetRGBA(x, y, c)
-		}
+		fillRun(pix[i*4:], [4]byte{r8, g8, b8, a8}, count)
 
 		i += count
 	}
-
-	// Encode to PNG even if we didn't fill all pixels
-	return png.Encode(output, img)
+	return nil
 }
 
-// PngToData converts from a PNG image to Celeste's DATA format
+// PngToData converts from a PNG image to Celeste's DATA format. Plain PNGs are read a row at a time rather
+// than decoded whole, so their memory use stays bounded by the compressed file and a few rows.
 func (g *GraphicsConverter) PngToData(input io.Reader, output io.Writer) error {
+	// Without transforms or parallel encoding, rows can go straight from the PNG to the DATA runs
+	if len(g.transforms) == 0 {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return err
+		}
+		if s := g.newPngStream(data); s != nil && !g.splitImage(s.info.width*s.info.height) {
+			// The limits apply as if the image were decoded, so they don't depend on the path taken
+			bytesPerPixel := 4
+			if s.info.bitDepth == 16 {
+				bytesPerPixel = 8
+			}
+			if err := g.checkImageSize(s.info.width, s.info.height, bytesPerPixel); err != nil {
+				return err
+			}
+			return g.encodePngStream(s, output)
+		}
+		input = bytes.NewReader(data)
+	}
+
 	// Decode the PNG
-	img, err := png.Decode(input)
+	img, err := g.decodePng(input)
 	if err != nil {
 		return err
 	}
+	if img, err = g.applyTransforms(img); err != nil {
+		return err
+	}
+
+	return g.encodeData(img, output)
+}
 
+// encodeData writes an image in Celeste's DATA format
+func (g *GraphicsConverter) encodeData(img image.Image, output io.Writer) error {
 	bounds := img.Bounds()
 	width := bounds.Max.X - bounds.Min.X
 	height := bounds.Max.Y - bounds.Min.Y
 
 	// Determine if we need to handle alpha
-	hasAlpha := hasAlphaChannel(img)
+	hasAlpha := g.dataHasAlpha(img)
 
-	g.log.Infof("PNG image parameters: %dx%d, %s", width, height,
+	return g.writeData(output, g.dataRows(img), width, height, hasAlpha)
+}
+
+// writeData writes the DATA header and the runs of the rows produced by read
+func (g *GraphicsConverter) writeData(output io.Writer, read rowReader, width, height int, hasAlpha bool) error {
+	g.log.Debugf("PNG image parameters: %dx%d, %s", width, height,
 		boolToFormat(hasAlpha))
 
+	w := getDataWriter(output)
+	defer putDataWriter(w)
+
 	// Write image header
-	if err := binary.Write(output, binary.LittleEndian, int32(width)); err != nil {
+	order := g.headerByteOrder()
+	if err := binary.Write(w, order, int32(width)); err != nil {
 		return err
 	}
-	if err := binary.Write(output, binary.LittleEndian, int32(height)); err != nil {
+	if err := binary.Write(w, order, int32(height)); err != nil {
 		return err
 	}
 
@@ -184,88 +325,77 @@ func (g *GraphicsConverter) PngToData(input io.Reader, output io.Writer) error {
 	if hasAlpha {
 		alphaFlag = 1
 	}
-	if err := binary.Write(output, binary.LittleEndian, alphaFlag); err != nil {
+	if err := binary.Write(w, order, alphaFlag); err != nil {
 		return err
 	}
 
-	// Compress and write pixel data
-	i := 0
-	for i < width*height {
-		// Get current pixel
-		x := i % width
-		y := i / width
-		r, g, b, a := getRGBA(img, x, y)
-
-		// Calculate run length by looking ahead
-		count := 1
-		for {
-			// Don't step out of bounds
-			if i+count >= width*height {
-				break
-			}
-
-			// Compare with next pixel color
-			x2 := (i + count) % width
-			y2 := (i + count) / width
-			r2, g2, b2, a2 := getRGBA(img, x2, y2)
-
-			if r != r2 || g != g2 || b != b2 || a != a2 {
-				break
-			}
-
-			// Increment, but don't exceed maximum 8-bit value
-			count++
-			if count >= 256 {
-				count = 256
-				break
-			}
-		}
-
-		// Write RLE count (0 for 256)
-		countByte := uint8(count)
-		if count == 256 {
-			countByte = 0
-		}
-		if err := binary.Write(output, binary.LittleEndian, countByte); err != nil {
+	if g.channelOrder == ChannelsRGB {
+		read = swappedRows(read)
+	}
+	if g.splitImage(width * height) {
+		if err := g.encodeRunsParallel(w, read, width, height, hasAlpha); err != nil {
 			return err
 		}
+	} else if err := g.encodeRuns(w, read, width, height, hasAlpha); err != nil {
+		return err
+	}
+	return w.Flush()
+}
 
-		// Write pixel data
-		if hasAlpha {
-			// Write alpha value
-			if err := binary.Write(output, binary.LittleEndian, a); err != nil {
-				return err
+// encodeRuns writes the pixels produced by read as DATA runs
+func (g *GraphicsConverter) encodeRuns(w io.Writer, read rowReader, width, height int, hasAlpha bool) error {
+	maxRun := g.maxRunLength
+	row := getBuffer(width * 4)
+	defer putBuffer(row)
+
+	// Runs are collected in a pooled slice and flushed in large chunks
+	runs := getBuffer(dataBufferSize)[:0]
+	defer func() { putBuffer(runs) }()
+
+	// Runs continue across rows, so the current run is carried from one row to the next
+	var current [4]byte
+	count := 0
+	for y := 0; y < height; y++ {
+		read(y, row)
+		for p := 0; p < len(row); p += 4 {
+			px := [4]byte{row[p], row[p+1], row[p+2], row[p+3]}
+			if count > 0 && px == current && count < maxRun {
+				count++
+				continue
 			}
 
-			// Only write color channels for non-transparent pixels
-			if a != 0 {
-				if err := binary.Write(output, binary.LittleEndian, b); err != nil {
-					return err
-				}
-				if err := binary.Write(output, binary.LittleEndian, g); err != nil {
-					return err
-				}
-				if err := binary.Write(output, binary.LittleEndian, r); err != nil {
-					return err
+			if count > 0 {
+				runs = appendRun(runs, current, count, hasAlpha)
+				if len(runs) > dataBufferSize-8 {
+					if _, err := w.Write(runs); err != nil {
+						return err
+					}
+					runs = runs[:0]
 				}
 			}
-		} else {
-			// Always write color channels for non-alpha images
-			if err := binary.Write(output, binary.LittleEndian, b); err != nil {
-				return err
-			}
-			if err := binary.Write(output, binary.LittleEndian, g); err != nil {
-				return err
-			}
-			if err := binary.Write(output, binary.LittleEndian, r); err != nil {
-				return err
-			}
+			current, count = px, 1
 		}
-
-		i += count
+	}
+	if count > 0 {
+		runs = appendRun(runs, current, count, hasAlpha)
 	}
 
-	return nil
+	_, err := w.Write(runs)
+	return err
+}
+
+// appendRun appends a run of count pixels of color px in the DATA layout.
+// The count is written as a byte, so a run of 256 is stored as 0.
+func appendRun(runs []byte, px [4]byte, count int, hasAlpha bool) []byte {
+	runs = append(runs, uint8(count))
+	if hasAlpha {
+		// Only write color channels for non-transparent pixels
+		runs = append(runs, px[3])
+		if px[3] == 0 {
+			return runs
+		}
+	}
+	return append(runs, px[2], px[1], px[0])
 }
 
 // Helper function to get RGBA values from any image type
@@ -285,16 +415,67 @@ func boolToFormat(hasAlpha bool) string {
 
 // Helper function to detect if an image has an alpha channel with non-255 values
 func hasAlphaChannel(img image.Image) bool {
-	switch img.(type) {
-	case *image.RGBA, *image.NRGBA:
-		// These types have alpha channels, but verify if any pixel actually uses it
-		bounds := img.Bounds()
-		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-			for x := bounds.Min.X; x < bounds.Max.X; x++ {
-				_, _, _, a := img.At(x, y).RGBA()
-				if a < 0xffff { // Check if any alpha value is less than fully opaque
-					return true
-				}
+	switch img := img.(type) {
+	case *image.Gray, *image.Gray16, *image.YCbCr, *image.CMYK:
+		return false // No alpha channel at all
+	case *image.Paletted:
+		return palettedHasAlpha(img)
+	case *image.RGBA:
+		return pixHasAlpha(img.Pix, img.Stride, img.Rect)
+	case *image.NRGBA:
+		return pixHasAlpha(img.Pix, img.Stride, img.Rect)
+	}
+
+	// Any other type may carry alpha, so check whether a pixel actually uses it
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			_, _, _, a := img.At(x, y).RGBA()
+			if a < 0xffff { // Check if any alpha value is less than fully opaque
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pixHasAlpha reports whether any pixel within rect of 8-bit RGBA pixels starting at rect.Min isn't fully opaque
+func pixHasAlpha(pix []byte, stride int, rect image.Rectangle) bool {
+	for y := 0; y < rect.Dy(); y++ {
+		row := pix[y*stride : y*stride+rect.Dx()*4]
+		for p := 3; p < len(row); p += 4 {
+			if row[p] != 0xff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// palettedHasAlpha reports whether any pixel of a paletted image uses a translucent palette entry,
+// such as one made transparent by a PNG tRNS chunk. Unused translucent entries don't count.
+func palettedHasAlpha(img *image.Paletted) bool {
+	var translucent [256]bool
+	found := false
+	for i, c := range img.Palette {
+		if i >= len(translucent) {
+			break
+		}
+		if _, _, _, a := c.RGBA(); a < 0xffff {
+			translucent[i] = true
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(bounds.Min.X, y):img.PixOffset(bounds.Max.X, y)]
+		for _, index := range row {
+			if translucent[index] {
+				return true
 			}
 		}
 	}
@@ -2,12 +2,16 @@ package converter
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"image"
 	"image/png"
-	"math"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
 )
 
 // List of test images for multiple conversion test
@@ -88,6 +92,129 @@ func TestPngToDataRoundTrip(t *testing.T) {
 	}
 }
 
+// TestLargeImageRoundTrip tests a large generated image with long runs and flush boundaries
+func TestLargeImageRoundTrip(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 1024, 1024))
+	for y := 0; y < 1024; y++ {
+		for x := 0; x < 1024; x++ {
+			i := img.PixOffset(x, y)
+			switch {
+			case y < 256:
+				// Long runs that span several 256-pixel RLE chunks
+				img.Pix[i+0], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 255, 0, 0, 255
+			case y < 512:
+				// Fully transparent
+			default:
+				// Unique pixels so every run has length 1
+				img.Pix[i+0], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(x), uint8(y), uint8(x^y), 255
+			}
+		}
+	}
+
+	pngBuf := new(bytes.Buffer)
+	if err := png.Encode(pngBuf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	dataBytes := pngToDataBytes(t, graphicsConverter, pngBuf.Bytes())
+	roundTrip := bytesToImage(t, dataToPngBytes(t, graphicsConverter, dataBytes))
+
+	assertImageEquals(t, img, roundTrip, 0)
+}
+
+// TestImageSizeLimits tests that oversized headers are rejected before allocating pixels
+// and that wide atlas pages beyond the former 8192 limit are accepted
+func TestImageSizeLimits(t *testing.T) {
+	dataHeader := func(width, height int32) []byte {
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.LittleEndian, [3]int32{width, height, 1})
+		return buf.Bytes()
+	}
+
+	t.Run("huge header with tiny payload", func(t *testing.T) {
+		err := NewGraphicsConverter().DataToPng(bytes.NewReader(dataHeader(16384, 16384)), io.Discard)
+		if !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("Expected ErrImageTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("wide atlas page", func(t *testing.T) {
+		if err := NewGraphicsConverter().DataToPng(bytes.NewReader(dataHeader(12000, 16)), io.Discard); err != nil {
+			t.Errorf("Expected 12000x16 to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("configured dimension", func(t *testing.T) {
+		graphicsConverter := NewGraphicsConverter()
+		graphicsConverter.SetMaxDimension(8)
+		err := graphicsConverter.DataToPng(bytes.NewReader(dataHeader(16, 4)), io.Discard)
+		if !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("Expected ErrImageTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("configured memory budget", func(t *testing.T) {
+		img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+		pngBuf := new(bytes.Buffer)
+		if err := png.Encode(pngBuf, img); err != nil {
+			t.Fatalf("Failed to encode PNG: %v", err)
+		}
+
+		graphicsConverter := NewGraphicsConverter()
+		graphicsConverter.SetMaxImageMemory(32 * 32 * 4)
+		pngToDataBytes(t, graphicsConverter, pngBuf.Bytes())
+
+		graphicsConverter.SetMaxImageMemory(32*32*4 - 1)
+		err := graphicsConverter.PngToData(bytes.NewReader(pngBuf.Bytes()), io.Discard)
+		if !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("Expected ErrImageTooLarge, got %v", err)
+		}
+	})
+}
+
+// TestMaxRunLength tests that runs are split at the configured maximum length
+func TestMaxRunLength(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 1))
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+
+	tests := []struct {
+		maxRun int
+		counts []byte
+	}{
+		{0, []byte{0, 44}}, // Ignored, 256 is written as 0
+		{255, []byte{255, 45}},
+		{100, []byte{100, 100, 100}},
+	}
+	for _, tt := range tests {
+		graphicsConverter := NewGraphicsConverter()
+		graphicsConverter.SetMaxRunLength(tt.maxRun)
+
+		var data bytes.Buffer
+		if err := graphicsConverter.encodeData(img, &data); err != nil {
+			t.Fatalf("encodeData failed: %v", err)
+		}
+
+		// Opaque runs are a count followed by BGR
+		var counts []byte
+		for runs := data.Bytes()[12:]; len(runs) >= 4; runs = runs[4:] {
+			counts = append(counts, runs[0])
+		}
+		if !bytes.Equal(counts, tt.counts) {
+			t.Errorf("Max run %d: expected counts %v, got %v", tt.maxRun, tt.counts, counts)
+		}
+
+		decoded, err := graphicsConverter.decodeData(&data)
+		if err != nil {
+			t.Fatalf("decodeData failed: %v", err)
+		}
+		assertImageEquals(t, img, decoded, 0)
+	}
+}
+
 // TestFilesConverterRoundTrip tests the FilesConverter through a complete round trip
 func TestFilesConverterRoundTrip(t *testing.T) {
 	// Create temporary directories for test
@@ -243,36 +370,8 @@ func bytesToImage(t *testing.T, imgBytes []byte) image.Image {
 // assertImageEquals asserts that two images are equal in dimensions and pixel data
 // with a tolerance for color variations
 func assertImageEquals(t *testing.T, expected, actual image.Image, tolerance int) {
-	// Check dimensions
-	expectedBounds := expected.Bounds()
-	actualBounds := actual.Bounds()
-
-	if expectedBounds.Dx() != actualBounds.Dx() || expectedBounds.Dy() != actualBounds.Dy() {
-		t.Fatalf("Image dimensions don't match: expected %dx%d, got %dx%d",
-			expectedBounds.Dx(), expectedBounds.Dy(), actualBounds.Dx(), actualBounds.Dy())
+	t.Helper()
+	if err := imagecompare.Check(expected, actual, tolerance); err != nil {
+		t.Fatal(err)
 	}
-
-	// Check pixel by pixel with tolerance
-	for y := expectedBounds.Min.Y; y < expectedBounds.Max.Y; y++ {
-		for x := expectedBounds.Min.X; x < expectedBounds.Max.X; x++ {
-			expectedR, expectedG, expectedB, expectedA := expected.At(x, y).RGBA()
-			actualR, actualG, actualB, actualA := actual.At(x, y).RGBA()
-
-			// Check if colors are within tolerance (converting from 16-bit to 8-bit for comparison)
-			if !colorsWithinTolerance(expectedR>>8, actualR>>8, tolerance) ||
-				!colorsWithinTolerance(expectedG>>8, actualG>>8, tolerance) ||
-				!colorsWithinTolerance(expectedB>>8, actualB>>8, tolerance) ||
-				!colorsWithinTolerance(expectedA>>8, actualA>>8, tolerance) {
-				t.Fatalf("Pixel mismatch at (%d,%d): expected rgba(%d,%d,%d,%d), got rgba(%d,%d,%d,%d)",
-					x, y, expectedR>>8, expectedG>>8, expectedB>>8, expectedA>>8,
-					actualR>>8, actualG>>8, actualB>>8, actualA>>8)
-			}
-		}
-	}
-}
-
-// colorsWithinTolerance checks if two color values are within the specified tolerance
-func colorsWithinTolerance(c1, c2 uint32, tolerance int) bool {
-	diff := int(c1) - int(c2)
-	return math.Abs(float64(diff)) <= float64(tolerance)
 }
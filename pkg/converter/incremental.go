@@ -0,0 +1,52 @@
+package converter
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SetIncremental enables skipping inputs whose output already exists and is at least as new as the input,
+// so re-running a conversion only redoes files that changed since the last run
+func (f *FilesConverter) SetIncremental(enabled bool) {
+	f.incremental = enabled
+}
+
+// upToDate reports whether a task's output exists and was modified no earlier than its input
+func upToDate(task ConversionTask) bool {
+	output, err := os.Stat(task.outputPath)
+	if err != nil || !output.Mode().IsRegular() {
+		return false
+	}
+	input, err := fs.Stat(task.source, filepath.ToSlash(task.relPath))
+	if err != nil {
+		return false
+	}
+	return !output.ModTime().Before(input.ModTime())
+}
+
+// skipUpToDate drops tasks whose output is up to date and renumbers the rest
+func (f *FilesConverter) skipUpToDate(tasks []ConversionTask) []ConversionTask {
+	remaining := make([]ConversionTask, 0, len(tasks))
+	for _, task := range tasks {
+		if upToDate(task) {
+			f.log.Debugf("Skipping %s, output is up to date", task.relPath)
+			continue
+		}
+		remaining = append(remaining, task)
+	}
+
+	if skipped := len(tasks) - len(remaining); skipped > 0 {
+		f.log.Infof("Skipping %d files with up-to-date outputs", skipped)
+	}
+	return renumberTasks(remaining)
+}
+
+// renumberTasks updates task indexes and totals after tasks were left out of a batch
+func renumberTasks(tasks []ConversionTask) []ConversionTask {
+	for i := range tasks {
+		tasks[i].index = i + 1
+		tasks[i].totalFiles = len(tasks)
+	}
+	return tasks
+}
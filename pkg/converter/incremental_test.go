@@ -0,0 +1,55 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestIncrementalSkipsUpToDateOutputs tests that only inputs newer than their outputs are converted again
+func TestIncrementalSkipsUpToDateOutputs(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	for _, name := range []string{"red", "blue", "green"} {
+		copyFile(t, filepath.Join("testdata", "data", name+".data"), filepath.Join(fromDir, name+".data"))
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	// red.data changes after its output was written, and green.png goes missing
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(fromDir, "red.data"), future, future); err != nil {
+		t.Fatalf("Failed to touch red.data: %v", err)
+	}
+	if err := os.Remove(filepath.Join(toDir, "green.png")); err != nil {
+		t.Fatalf("Failed to remove green.png: %v", err)
+	}
+
+	var converted []string
+	filesConverter.Progress(func(event ProgressEvent) {
+		if event.Type == FileFinished {
+			converted = append(converted, event.RelPath)
+			if event.TotalFiles != 2 {
+				t.Errorf("Expected 2 files in the batch, got %d", event.TotalFiles)
+			}
+		}
+	})
+	filesConverter.SetIncremental(true)
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("Incremental DataToPng failed: %v", err)
+	}
+
+	if len(converted) != 2 {
+		t.Fatalf("Expected red.data and green.data to be converted, got %v", converted)
+	}
+	for _, relPath := range converted {
+		if relPath != "red.data" && relPath != "green.data" {
+			t.Errorf("Unexpected conversion of %s", relPath)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package converter
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/klauspost/compress/zstd"
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, so builds stay cgo-free
+)
+
+// indexSchema creates the asset table of an index database
+const indexSchema = `
+CREATE TABLE IF NOT EXISTS assets (
+	path          TEXT PRIMARY KEY,
+	source        TEXT NOT NULL,
+	conversion    TEXT NOT NULL,
+	version       TEXT NOT NULL,
+	source_sha256 TEXT NOT NULL,
+	output_sha256 TEXT NOT NULL,
+	size          INTEGER NOT NULL,
+	width         INTEGER,
+	height        INTEGER,
+	has_alpha     INTEGER,
+	indexed_at    TEXT NOT NULL
+)`
+
+// AssetRecord describes a converted asset as stored in an AssetIndex.
+// Width, Height and HasAlpha are only set for texture outputs.
+type AssetRecord struct {
+	Path         string // Absolute output path, with forward slashes
+	Source       string // Absolute input path, with forward slashes
+	Conversion   string
+	Version      string
+	SourceSHA256 string
+	OutputSHA256 string
+	Size         int64 // Output size in bytes
+	Width        int
+	Height       int
+	HasAlpha     bool
+	IndexedAt    time.Time
+}
+
+// IsTexture reports whether the record has texture dimensions
+func (r *AssetRecord) IsTexture() bool {
+	return r.Width > 0 && r.Height > 0
+}
+
+// IndexStats summarizes the assets of an AssetIndex
+type IndexStats struct {
+	Assets       int
+	Textures     int
+	TotalBytes   int64
+	TotalPixels  int64
+	WithAlpha    int
+	ByConversion map[string]int
+}
+
+// AssetIndex is a SQLite database of converted assets, queryable without rescanning the filesystem
+type AssetIndex struct {
+	db *sql.DB
+}
+
+// OpenAssetIndex opens the index database at path, creating it if it doesn't exist
+func OpenAssetIndex(path string) (*AssetIndex, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index '%s': %w", path, err)
+	}
+	if _, err := db.Exec(indexSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize index '%s': %w", path, err)
+	}
+	return &AssetIndex{db: db}, nil
+}
+
+// Close closes the index database
+func (i *AssetIndex) Close() error {
+	return i.db.Close()
+}
+
+// Put inserts records in a single transaction, replacing existing records of the same paths
+func (i *AssetIndex) Put(records []AssetRecord) error {
+	tx, err := i.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO assets
+		(path, source, conversion, version, source_sha256, output_sha256, size, width, height, has_alpha, indexed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		var width, height, hasAlpha any
+		if r.IsTexture() {
+			width, height, hasAlpha = r.Width, r.Height, r.HasAlpha
+		}
+		_, err := stmt.Exec(r.Path, r.Source, r.Conversion, r.Version, r.SourceSHA256, r.OutputSHA256,
+			r.Size, width, height, hasAlpha, r.IndexedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return fmt.Errorf("failed to index '%s': %w", r.Path, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Search returns the records whose output or source path matches a glob pattern such as "*/characters/*",
+// sorted by output path
+func (i *AssetIndex) Search(pattern string) ([]AssetRecord, error) {
+	rows, err := i.db.Query(`SELECT path, source, conversion, version, source_sha256, output_sha256,
+		size, width, height, has_alpha, indexed_at
+		FROM assets WHERE path GLOB ?1 OR source GLOB ?1 ORDER BY path`, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []AssetRecord
+	for rows.Next() {
+		var r AssetRecord
+		var width, height sql.NullInt64
+		var hasAlpha sql.NullBool
+		var indexedAt string
+		err := rows.Scan(&r.Path, &r.Source, &r.Conversion, &r.Version, &r.SourceSHA256, &r.OutputSHA256,
+			&r.Size, &width, &height, &hasAlpha, &indexedAt)
+		if err != nil {
+			return nil, err
+		}
+		r.Width, r.Height, r.HasAlpha = int(width.Int64), int(height.Int64), hasAlpha.Bool
+		r.IndexedAt, _ = time.Parse(time.RFC3339, indexedAt)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Stats summarizes every asset in the index
+func (i *AssetIndex) Stats() (*IndexStats, error) {
+	stats := &IndexStats{ByConversion: make(map[string]int)}
+	err := i.db.QueryRow(`SELECT COUNT(*), COUNT(width), COALESCE(SUM(size), 0),
+		COALESCE(SUM(width * height), 0), COALESCE(SUM(has_alpha), 0) FROM assets`).
+		Scan(&stats.Assets, &stats.Textures, &stats.TotalBytes, &stats.TotalPixels, &stats.WithAlpha)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.db.Query(`SELECT conversion, COUNT(*) FROM assets GROUP BY conversion`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conversion string
+		var count int
+		if err := rows.Scan(&conversion, &count); err != nil {
+			return nil, err
+		}
+		stats.ByConversion[conversion] = count
+	}
+	return stats, rows.Err()
+}
+
+// SetIndex records every converted asset in index; nil disables indexing.
+// Indexing needs to read outputs back, so it isn't supported when writing into zip archives.
+func (f *FilesConverter) SetIndex(index *AssetIndex) {
+	f.index = index
+}
+
+// putIndexRecords stores the records of every successfully converted file of a batch
+func (f *FilesConverter) putIndexRecords(batch *conversionBatch) error {
+	records := make([]AssetRecord, 0, len(batch.indexRecords))
+	for _, record := range batch.indexRecords {
+		if record.Path != "" { // Failed files have no record
+			records = append(records, record)
+		}
+	}
+	if err := f.index.Put(records); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	return nil
+}
+
+// indexRecord builds the index record of a converted task from its input and output files
+func (f *FilesConverter) indexRecord(batch *conversionBatch, task ConversionTask) (AssetRecord, error) {
+	record := AssetRecord{
+		Path:       filepath.ToSlash(task.outputPath),
+		Source:     filepath.ToSlash(task.inputPath),
+		Conversion: batch.conversion,
+		Version:    Version,
+		IndexedAt:  time.Now(),
+	}
+
+	var err error
+	if record.SourceSHA256, err = hashTaskInput(task); err != nil {
+		return record, err
+	}
+	if record.OutputSHA256, err = hashFile(task.outputPath); err != nil {
+		return record, err
+	}
+	info, err := os.Stat(task.outputPath)
+	if err != nil {
+		return record, err
+	}
+	record.Size = info.Size()
+
+	if textureExtension(task.outputPath) != "" {
+		record.Width, record.Height, record.HasAlpha, err = textureInfo(task.outputPath)
+		if err != nil {
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// textureInfo reads a texture's dimensions and whether its header declares alpha, without decoding the pixels
+func textureInfo(path string) (width, height int, hasAlpha bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer file.Close()
+	return textureHeader(file, textureExtension(path))
+}
+
+// textureHeader reads the dimensions and alpha of a texture in the format named by ext from its header
+func textureHeader(file io.Reader, ext string) (width, height int, hasAlpha bool, err error) {
+	switch ext {
+	case ".data":
+		header, err := readDataHeader(file)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return int(header.Width), int(header.Height), header.HasAlpha(), nil
+
+	case ".png":
+		config, err := png.DecodeConfig(file)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return config.Width, config.Height, colorModelHasAlpha(config.ColorModel), nil
+
+	case ".cdat.zst":
+		decoder, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return 0, 0, false, err
+		}
+		defer decoder.Close()
+		var header cdatHeader
+		if err := binary.Read(decoder, binary.LittleEndian, &header); err != nil {
+			return 0, 0, false, err
+		}
+		return int(header.Width), int(header.Height), header.HasAlpha != 0, nil
+
+	case ".webp":
+		config, err := nativewebp.DecodeConfig(file)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return config.Width, config.Height, colorModelHasAlpha(config.ColorModel), nil
+	}
+	return 0, 0, false, fmt.Errorf("'%s' is not a supported texture format", ext)
+}
+
+// colorModelHasAlpha reports whether a PNG color model can store transparency
+func colorModelHasAlpha(model color.Model) bool {
+	switch model {
+	case color.GrayModel, color.Gray16Model, color.RGBAModel, color.RGBA64Model:
+		// image/png reports opaque truecolor images as RGBA, and translucent ones as NRGBA
+		return false
+	}
+	if palette, ok := model.(color.Palette); ok {
+		for _, c := range palette {
+			if _, _, _, a := c.RGBA(); a < 0xffff {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAssetIndex tests that converted assets are recorded and can be searched and summarized
+func TestAssetIndex(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+	if err := os.MkdirAll(filepath.Join(fromDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "data", "transparent.data"), filepath.Join(fromDir, "sub", "transparent.data"))
+
+	index, err := OpenAssetIndex(filepath.Join(t.TempDir(), "assets.db"))
+	if err != nil {
+		t.Fatalf("OpenAssetIndex failed: %v", err)
+	}
+	defer index.Close()
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetIndex(index)
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	records, err := index.Search("*/sub/*")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record below sub, got %d", len(records))
+	}
+	record := records[0]
+	if record.Path != filepath.ToSlash(filepath.Join(toDir, "sub", "transparent.png")) || record.Conversion != "DATA -> PNG" {
+		t.Errorf("Unexpected record: %+v", record)
+	}
+	if !record.IsTexture() || !record.HasAlpha || len(record.SourceSHA256) != 64 || len(record.OutputSHA256) != 64 {
+		t.Errorf("Expected texture details and hashes: %+v", record)
+	}
+
+	stats, err := index.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Assets != 2 || stats.Textures != 2 || stats.ByConversion["DATA -> PNG"] != 2 || stats.TotalBytes == 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Converting again replaces the records instead of adding new ones
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	if stats, err := index.Stats(); err != nil || stats.Assets != 2 {
+		t.Errorf("Expected 2 assets after reconverting, got %+v (%v)", stats, err)
+	}
+}
+
+// TestTextureInfo tests that dimensions are read from the headers of every texture format
+func TestTextureInfo(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+	img, err := graphicsConverter.decodeData(bytes.NewReader(readTestResource(t, "data/multi-color.data")))
+	if err != nil {
+		t.Fatalf("Failed to decode test texture: %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, ext := range textureExtensions {
+		path := filepath.Join(dir, "texture"+ext)
+		if err := graphicsConverter.encodeFile(img, path); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		width, height, _, err := textureInfo(path)
+		if err != nil {
+			t.Fatalf("textureInfo(%s) failed: %v", ext, err)
+		}
+		if width != img.Bounds().Dx() || height != img.Bounds().Dy() {
+			t.Errorf("%s: expected %v, got %dx%d", ext, img.Bounds().Size(), width, height)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package converter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TextureInfo describes a texture file from its header, without decoding its pixels
+type TextureInfo struct {
+	Path             string  `json:"path"`
+	Format           string  `json:"format"` // Extension without the dot, such as "data" or "png"
+	Width            int     `json:"width"`
+	Height           int     `json:"height"`
+	HasAlpha         bool    `json:"hasAlpha"`            // The header declares transparency, such as the DATA alpha flag
+	BitDepth         int     `json:"bitDepth,omitempty"`  // Bits per PNG channel
+	ColorType        string  `json:"colorType,omitempty"` // PNG color type, such as "rgba" or "indexed"
+	Interlaced       bool    `json:"interlaced,omitempty"`
+	EncodedSize      int64   `json:"encodedSize"`      // Size of the file in bytes
+	DecodedSize      int64   `json:"decodedSize"`      // Size of the decoded 8-bit RGBA pixels in bytes
+	CompressionRatio float64 `json:"compressionRatio"` // DecodedSize / EncodedSize
+	Error            string  `json:"error,omitempty"`  // Why the header couldn't be read, for directory listings
+}
+
+// pngColorTypes names the PNG color types
+var pngColorTypes = map[uint8]string{0: "gray", 2: "rgb", 3: "indexed", 4: "gray-alpha", 6: "rgba"}
+
+// TextureInfo reads the header of the texture at path, recognized by its extension or, failing that,
+// its content
+func (g *GraphicsConverter) TextureInfo(path string) (*TextureInfo, error) {
+	return g.textureInfo(os.DirFS(filepath.Dir(path)), filepath.Base(path), path)
+}
+
+// TextureInfoFS reads the header of the texture name in fsys, such as a zip reader, like TextureInfo.
+// The Path of the returned info is name.
+func (g *GraphicsConverter) TextureInfoFS(fsys fs.FS, name string) (*TextureInfo, error) {
+	return g.textureInfo(fsys, name, name)
+}
+
+// textureInfo reads the header of the texture name in fsys, describing it as path
+func (g *GraphicsConverter) textureInfo(fsys fs.FS, name, path string) (*TextureInfo, error) {
+	ext := textureExtension(name)
+	if ext == "" {
+		var err error
+		if ext, err = sniffFile(fsys, name); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(textureExtensions, ext) {
+		return nil, errors.New("not a supported texture")
+	}
+
+	info := &TextureInfo{Path: path, Format: strings.TrimPrefix(ext, "."), EncodedSize: stat.Size()}
+	r := bufio.NewReader(file)
+	if ext == ".png" {
+		header := peekPngInfo(r)
+		info.BitDepth = int(header.bitDepth)
+		info.ColorType = pngColorTypes[header.colorType]
+		info.Interlaced = header.interlaced
+	}
+	if info.Width, info.Height, info.HasAlpha, err = textureHeader(r, ext); err != nil {
+		return nil, fmt.Errorf("failed to read %s header: %w", formatLabel(ext), err)
+	}
+	if info.Width <= 0 || info.Height <= 0 {
+		return nil, fmt.Errorf("invalid image size %dx%d", info.Width, info.Height)
+	}
+
+	info.DecodedSize = int64(info.Width) * int64(info.Height) * 4
+	if info.EncodedSize > 0 {
+		info.CompressionRatio = float64(info.DecodedSize) / float64(info.EncodedSize)
+	}
+	return info, nil
+}
+
+// TextureInfos reads the headers of the texture at path or, for a directory, of every texture below it,
+// sorted by path. Files in a directory whose header can't be read are listed with their Error.
+func (g *GraphicsConverter) TextureInfos(path string) ([]TextureInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		info, err := g.TextureInfo(path)
+		if err != nil {
+			return nil, err
+		}
+		return []TextureInfo{*info}, nil
+	}
+
+	var infos []TextureInfo
+	err = filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || textureExtension(filePath) == "" {
+			return nil
+		}
+		info, err := g.TextureInfo(filePath)
+		if err != nil {
+			info = &TextureInfo{Path: filePath, Error: err.Error()}
+		}
+		infos = append(infos, *info)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Path < infos[j].Path
+	})
+	return infos, nil
+}
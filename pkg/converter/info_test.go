@@ -0,0 +1,72 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// TestTextureInfos tests describing textures and directories of them from their headers
+func TestTextureInfos(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+
+	info, err := graphicsConverter.TextureInfo(filepath.Join("testdata", "data", "multi-color.data"))
+	if err != nil {
+		t.Fatalf("TextureInfo failed: %v", err)
+	}
+	dataSize := int64(len(readTestResource(t, filepath.Join("data", "multi-color.data"))))
+	if info.Format != "data" || info.Width != 128 || info.Height != 96 || !info.HasAlpha {
+		t.Errorf("Unexpected DATA info %+v", info)
+	}
+	if info.EncodedSize != dataSize || info.DecodedSize != 128*96*4 ||
+		info.CompressionRatio != float64(128*96*4)/float64(dataSize) {
+		t.Errorf("Unexpected DATA sizes %+v", info)
+	}
+
+	info, err = graphicsConverter.TextureInfo(filepath.Join("testdata", "png", "red.png"))
+	if err != nil {
+		t.Fatalf("TextureInfo failed: %v", err)
+	}
+	if info.Format != "png" || info.Width != 32 || info.Height != 32 || info.BitDepth != 4 || info.ColorType != "indexed" {
+		t.Errorf("Unexpected PNG info %+v", info)
+	}
+
+	// Directories list every texture, including the broken ones
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(dir, "red.data"))
+	copyFile(t, filepath.Join("testdata", "png", "blue.png"), filepath.Join(dir, "blue.png"))
+	if err := os.WriteFile(filepath.Join(dir, "broken.png"), []byte("nope"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("skipped"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	infos, err := graphicsConverter.TextureInfos(dir)
+	if err != nil {
+		t.Fatalf("TextureInfos failed: %v", err)
+	}
+	if len(infos) != 3 || filepath.Base(infos[0].Path) != "blue.png" || infos[1].Error == "" || infos[2].Format != "data" {
+		t.Errorf("Unexpected directory infos %+v", infos)
+	}
+}
+
+// TestTextureInfoFS tests reading headers from an fs.FS, sniffing files without a texture extension
+func TestTextureInfoFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Gameplay/red.data": {Data: readTestResource(t, filepath.Join("data", "red.data"))},
+		"Gameplay/blue":     {Data: readTestResource(t, filepath.Join("png", "blue.png"))},
+	}
+	graphicsConverter := NewGraphicsConverter()
+
+	info, err := graphicsConverter.TextureInfoFS(fsys, "Gameplay/red.data")
+	if err != nil {
+		t.Fatalf("TextureInfoFS failed: %v", err)
+	}
+	if info.Path != "Gameplay/red.data" || info.Format != "data" || info.Width != 32 || info.EncodedSize != int64(len(fsys["Gameplay/red.data"].Data)) {
+		t.Errorf("Unexpected DATA info %+v", info)
+	}
+	if info, err := graphicsConverter.TextureInfoFS(fsys, "Gameplay/blue"); err != nil || info.Format != "png" {
+		t.Errorf("Expected the PNG to be sniffed, got %+v, %v", info, err)
+	}
+}
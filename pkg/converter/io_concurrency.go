@@ -0,0 +1,86 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// SetIOConcurrency limits batch conversions to reading or writing at most files files at a time,
+// independently of the workers decoding and encoding them. On network shares and spinning disks a
+// few concurrent reads beat many workers seeking at once, while decoding still wants every CPU.
+// With a limit, every file goes through an I/O stage reading its input into memory once a slot is
+// free, a CPU stage converting it in memory, and an I/O stage writing the output in one go; with
+// more workers than slots, conversions overlap the I/O of other files. Inputs aren't memory-mapped
+// then. Zero, the default, streams every file through its worker without a limit.
+func (f *FilesConverter) SetIOConcurrency(files int) {
+	f.ioSlots = nil
+	if files > 0 {
+		f.ioSlots = make(chan struct{}, files)
+	}
+}
+
+// acquireIO waits for an I/O slot, failing if ctx is done first
+func (f *FilesConverter) acquireIO(ctx context.Context) error {
+	select {
+	case f.ioSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseIO frees an I/O slot
+func (f *FilesConverter) releaseIO() {
+	<-f.ioSlots
+}
+
+// readStaged reads all of input into memory while holding an I/O slot
+func (f *FilesConverter) readStaged(ctx context.Context, input io.Reader, beat *heartbeat) (*bytes.Reader, error) {
+	if err := f.acquireIO(ctx); err != nil {
+		return nil, err
+	}
+	defer f.releaseIO()
+	if beat != nil {
+		beat.touch() // Waiting for a slot isn't a stall
+	}
+	data, err := io.ReadAll(&contextReader{ctx: ctx, r: input, heartbeat: beat})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// stagedOutput collects an output in memory and writes it to the sink on commit, while holding an
+// I/O slot
+type stagedOutput struct {
+	ctx  context.Context
+	f    *FilesConverter
+	sink outputSink
+	path string
+	buf  bytes.Buffer
+}
+
+func (s *stagedOutput) Write(p []byte) (int, error) {
+	return s.buf.Write(p)
+}
+
+func (s *stagedOutput) commit() error {
+	if err := s.f.acquireIO(s.ctx); err != nil {
+		return err
+	}
+	defer s.f.releaseIO()
+	file, err := s.sink.create(s.path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(s.buf.Bytes()); err != nil {
+		file.discard()
+		return err
+	}
+	return file.commit()
+}
+
+func (s *stagedOutput) discard() {
+	s.buf = bytes.Buffer{}
+}
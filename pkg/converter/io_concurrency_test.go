@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestIOConcurrency tests that staged I/O writes the same outputs as streaming every file
+func TestIOConcurrency(t *testing.T) {
+	fromDir := t.TempDir()
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	expectedDir, actualDir := t.TempDir(), t.TempDir()
+	if err := NewFilesConverter(NewGraphicsConverter()).DataToPng(fromDir, expectedDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	var bytesRead int64
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithWorkers(4), WithIOConcurrency(1), WithMemoryMap(true))
+	filesConverter.Progress(func(event ProgressEvent) {
+		bytesRead = event.TotalBytesRead
+	})
+	if err := filesConverter.DataToPng(fromDir, actualDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	if len(filesConverter.ioSlots) != 0 {
+		t.Errorf("Expected every I/O slot to be released, %d are held", len(filesConverter.ioSlots))
+	}
+
+	var inputBytes int64
+	for _, name := range testImages {
+		expected, err := os.ReadFile(filepath.Join(expectedDir, name+".png"))
+		if err != nil {
+			t.Fatalf("Failed to read expected output: %v", err)
+		}
+		actual, err := os.ReadFile(filepath.Join(actualDir, name+".png"))
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("%s.png differs from the streamed conversion", name)
+		}
+		info, err := os.Stat(filepath.Join(fromDir, name+".data"))
+		if err != nil {
+			t.Fatalf("Failed to stat input: %v", err)
+		}
+		inputBytes += info.Size()
+	}
+	if bytesRead != inputBytes {
+		t.Errorf("Expected %d bytes read, got %d", inputBytes, bytesRead)
+	}
+}
+
+// TestIOConcurrencyCancelled tests that files waiting for an I/O slot stop when the batch is cancelled
+func TestIOConcurrencyCancelled(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetIOConcurrency(1)
+	filesConverter.ioSlots <- struct{}{} // Held by someone else for the whole batch
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := filesConverter.ConvertContext(ctx, fromDir, toDir, ".data", ".png", filesConverter.graphicsConverter.DataToPng)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(toDir, "red.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected no output, got %v", err)
+	}
+}
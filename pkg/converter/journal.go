@@ -0,0 +1,160 @@
+package converter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JournalSuffix is appended to the output directory to name the journal of resumable batch conversions,
+// such as "Gameplay-png.journal" next to "Gameplay-png"
+const JournalSuffix = ".journal"
+
+// JournalEntry is a line of the journal, recording a file a resumable batch converted
+type JournalEntry struct {
+	Input       string `json:"input"`  // Path relative to the source directory, slash-separated
+	Output      string `json:"output"` // Path relative to the output directory, slash-separated
+	InputSHA256 string `json:"inputSha256"`
+}
+
+// SetResume makes batch conversions record every converted file in a journal next to the output directory
+// and skip the files an earlier, crashed or interrupted run already recorded there, as long as their input
+// is unchanged and their output still exists. The journal is removed once a batch converts every file.
+// Outputs written to zip archives or a WritableFS can't be resumed.
+func (f *FilesConverter) SetResume(enabled bool) {
+	f.resume = enabled
+}
+
+// journal is the record of the files a resumable batch converted
+type journal struct {
+	path  string
+	toDir string
+	done  map[string]JournalEntry // Entries of earlier runs, by output path
+
+	mu   sync.Mutex
+	file *os.File // Opened for appending on the first record
+}
+
+// journalPath returns where the journal of a batch writing below toDir is kept
+func journalPath(toDir string) string {
+	return filepath.Clean(toDir) + JournalSuffix
+}
+
+// loadJournal reads the journal of a batch writing below toDir, which is empty if there is none yet.
+// A last line cut short by a crash is ignored.
+func loadJournal(toDir string) (*journal, error) {
+	j := &journal{path: journalPath(toDir), toDir: toDir, done: make(map[string]JournalEntry)}
+
+	file, err := os.Open(j.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal '%s': %w", j.path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		j.done[entry.Output] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal '%s': %w", j.path, err)
+	}
+	return j, nil
+}
+
+// entryFor returns the journal entry describing task, without the input hash
+func (j *journal) entryFor(task ConversionTask) JournalEntry {
+	return JournalEntry{
+		Input:  filepath.ToSlash(task.relPath),
+		Output: filepath.ToSlash(relOrSelf(j.toDir, task.outputPath)),
+	}
+}
+
+// completed reports whether an earlier run converted task from the same input to an output that still exists
+func (j *journal) completed(task ConversionTask) bool {
+	want := j.entryFor(task)
+	entry, ok := j.done[want.Output]
+	if !ok || entry.Input != want.Input {
+		return false
+	}
+	if info, err := os.Stat(task.outputPath); err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	hash, err := hashTaskInput(task)
+	return err == nil && hash == entry.InputSHA256
+}
+
+// record appends a converted task to the journal
+func (j *journal) record(task ConversionTask) error {
+	entry := j.entryFor(task)
+	hash, err := hashTaskInput(task)
+	if err != nil {
+		return err
+	}
+	entry.InputSHA256 = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		if j.file, err = os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+			return fmt.Errorf("failed to open journal '%s': %w", j.path, err)
+		}
+	}
+	// A single write per line keeps lines whole however the process ends
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal '%s': %w", j.path, err)
+	}
+	return nil
+}
+
+// close closes the journal file, keeping it for a later run
+func (j *journal) close() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+}
+
+// remove deletes the journal once its batch is complete
+func (j *journal) remove() {
+	j.close()
+	// A journal left behind only makes the next resumed run skip files that are already done
+	os.Remove(j.path)
+}
+
+// skipCompleted drops the tasks an earlier run completed and renumbers the rest
+func (f *FilesConverter) skipCompleted(j *journal, tasks []ConversionTask) []ConversionTask {
+	if len(j.done) == 0 {
+		return tasks
+	}
+	remaining := make([]ConversionTask, 0, len(tasks))
+	for _, task := range tasks {
+		if j.completed(task) {
+			f.log.Debugf("Skipping %s, converted by an earlier run", task.relPath)
+			continue
+		}
+		remaining = append(remaining, task)
+	}
+
+	if skipped := len(tasks) - len(remaining); skipped > 0 {
+		f.log.Infof("Resuming, skipping %d files converted by an earlier run", skipped)
+	}
+	return renumberTasks(remaining)
+}
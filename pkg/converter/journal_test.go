@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countJournalLines returns the number of entries in the journal of toDir
+func countJournalLines(t *testing.T, toDir string) int {
+	file, err := os.Open(journalPath(toDir))
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	defer file.Close()
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		lines++
+	}
+	return lines
+}
+
+// TestResume tests that a resumed batch only converts the files an interrupted run didn't finish
+func TestResume(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := filepath.Join(t.TempDir(), "out")
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	// The first run stops after two files
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithWorkers(1), WithResume(true))
+	var finished []string
+	filesConverter.Progress(func(event ProgressEvent) {
+		if event.Type == FileFinished {
+			if finished = append(finished, event.InputPath); len(finished) == 2 {
+				filesConverter.Stop()
+			}
+		}
+	})
+	if err := filesConverter.DataToPng(fromDir, toDir); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Expected ErrStopped, got %v", err)
+	}
+	if lines := countJournalLines(t, toDir); lines != 2 {
+		t.Fatalf("Expected 2 journal entries, got %d", lines)
+	}
+
+	// The resumed run converts the rest, and the first file again once its input changed
+	copyFile(t, filepath.Join("testdata", "data", "transparent.data"), finished[0])
+
+	var converted []string
+	resumed := NewFilesConverter(NewGraphicsConverter(), WithResume(true), WithProgress(func(event ProgressEvent) {
+		if event.Type == FileFinished {
+			converted = append(converted, event.RelPath)
+		}
+	}))
+	if err := resumed.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("Resumed DataToPng failed: %v", err)
+	}
+	if want := len(testImages) - 1; len(converted) != want {
+		t.Errorf("Expected %d files to be converted on resume, got %v", want, converted)
+	}
+	if _, err := os.Stat(journalPath(toDir)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the journal to be removed after a complete run, got %v", err)
+	}
+}
+
+// TestResumeRejectsZip tests that conversions into zip archives can't be resumed
+func TestResumeRejectsZip(t *testing.T) {
+	fromDir := t.TempDir()
+	setupTestFiles(t, fromDir, ".data", "data")
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithResume(true))
+	if err := filesConverter.DataToPng(fromDir, filepath.Join(t.TempDir(), "out.zip")); err == nil {
+		t.Error("Expected resuming into a zip archive to fail")
+	}
+}
@@ -0,0 +1,158 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LogLevel is the severity of a log message
+type LogLevel int
+
+const (
+	// LevelDebug is for details such as the parameters of every decoded image
+	LevelDebug LogLevel = iota
+	// LevelInfo is for progress, such as every converted file
+	LevelInfo
+	// LevelWarn is for problems the conversion recovered from
+	LevelWarn
+	// LevelError is for failed files
+	LevelError
+)
+
+// Logger receives the log messages of the converters. *logrus.Logger satisfies it, pkg/logrusadapter
+// adds structured fields to it, and SlogLogger adapts a *slog.Logger.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// FieldLogger is a Logger that also takes structured fields, such as the file, index and duration of
+// each file of a batch. Plain Loggers get the fields appended to the message as key=value pairs.
+type FieldLogger interface {
+	Logger
+	LogFields(level LogLevel, message string, fields map[string]any)
+}
+
+// NopLogger is a Logger discarding every message, the default of the converters
+type NopLogger struct{}
+
+func (NopLogger) Debugf(string, ...any) {}
+func (NopLogger) Infof(string, ...any)  {}
+func (NopLogger) Warnf(string, ...any)  {}
+func (NopLogger) Errorf(string, ...any) {}
+
+var (
+	defaultLoggerMu sync.RWMutex
+	defaultLogger   Logger = NopLogger{}
+)
+
+// SetDefaultLogger sets the logger of converters created afterwards, nil to discard their messages again.
+// Converters can also be given their own logger with SetLogger or WithLogger.
+func SetDefaultLogger(logger Logger) {
+	if logger == nil {
+		logger = NopLogger{}
+	}
+	defaultLoggerMu.Lock()
+	defer defaultLoggerMu.Unlock()
+	defaultLogger = logger
+}
+
+// DefaultLogger returns the logger converters are created with
+func DefaultLogger() Logger {
+	defaultLoggerMu.RLock()
+	defer defaultLoggerMu.RUnlock()
+	return defaultLogger
+}
+
+// SetLogger sets where the converter logs, nil to discard its messages
+func (g *GraphicsConverter) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = NopLogger{}
+	}
+	g.log = logger
+}
+
+// SetLogger sets where the converter logs, nil to discard its messages. It doesn't change the logger
+// of the GraphicsConverter doing the conversions.
+func (f *FilesConverter) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = NopLogger{}
+	}
+	f.log = logger
+}
+
+// logf logs a formatted message at level
+func logf(log Logger, level LogLevel, format string, args ...any) {
+	switch level {
+	case LevelDebug:
+		log.Debugf(format, args...)
+	case LevelInfo:
+		log.Infof(format, args...)
+	case LevelWarn:
+		log.Warnf(format, args...)
+	default:
+		log.Errorf(format, args...)
+	}
+}
+
+// logFields logs a message with structured fields at level
+func logFields(log Logger, level LogLevel, message string, fields map[string]any) {
+	if fieldLogger, ok := log.(FieldLogger); ok {
+		fieldLogger.LogFields(level, message, fields)
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(message)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, fields[key])
+	}
+	logf(log, level, "%s", b.String())
+}
+
+// slogLogger adapts a *slog.Logger to a FieldLogger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// SlogLogger returns a FieldLogger writing to logger, with fields as attributes
+func SlogLogger(logger *slog.Logger) FieldLogger {
+	return slogLogger{logger}
+}
+
+func (s slogLogger) Debugf(format string, args ...any) { s.logger.Debug(fmt.Sprintf(format, args...)) }
+func (s slogLogger) Infof(format string, args ...any)  { s.logger.Info(fmt.Sprintf(format, args...)) }
+func (s slogLogger) Warnf(format string, args ...any)  { s.logger.Warn(fmt.Sprintf(format, args...)) }
+func (s slogLogger) Errorf(format string, args ...any) { s.logger.Error(fmt.Sprintf(format, args...)) }
+
+func (s slogLogger) LogFields(level LogLevel, message string, fields map[string]any) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, key := range keys {
+		attrs = append(attrs, slog.Any(key, fields[key]))
+	}
+	s.logger.LogAttrs(context.Background(), slogLevels[level], message, attrs...)
+}
+
+// slogLevels maps log levels to slog levels
+var slogLevels = map[LogLevel]slog.Level{
+	LevelDebug: slog.LevelDebug,
+	LevelInfo:  slog.LevelInfo,
+	LevelWarn:  slog.LevelWarn,
+	LevelError: slog.LevelError,
+}
@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestDefaultLogger tests that converters start with the default logger and discard messages without one
+func TestDefaultLogger(t *testing.T) {
+	if _, ok := DefaultLogger().(NopLogger); !ok {
+		t.Fatalf("Expected the default logger to discard messages, got %T", DefaultLogger())
+	}
+
+	logger := new(logBuffer)
+	SetDefaultLogger(logger)
+	defer SetDefaultLogger(nil)
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	if filesConverter.log != logger || filesConverter.graphicsConverter.log != logger {
+		t.Error("Expected new converters to use the default logger")
+	}
+	filesConverter.SetLogger(nil)
+	if _, ok := filesConverter.log.(NopLogger); !ok {
+		t.Errorf("Expected SetLogger(nil) to discard messages, got %T", filesConverter.log)
+	}
+}
+
+// TestLogFieldsFallback tests that plain loggers get the fields appended in key order
+func TestLogFieldsFallback(t *testing.T) {
+	logger := &plainLogger{}
+	logFields(logger, LevelInfo, "File converted", map[string]any{"status": "ok", "file": "red.data"})
+	if logger.last != "File converted file=red.data status=ok" {
+		t.Errorf("Unexpected message %q", logger.last)
+	}
+}
+
+// TestSlogLogger tests that fields become slog attributes at the mapped level
+func TestSlogLogger(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := SlogLogger(slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	logger.Debugf("%d sprites", 3)
+	logger.LogFields(LevelError, "File failed", map[string]any{"file": "red.data"})
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `level=DEBUG msg="3 sprites"`) ||
+		!strings.Contains(lines[1], `level=ERROR msg="File failed" file=red.data`) {
+		t.Errorf("Unexpected output:\n%s", output.String())
+	}
+}
+
+// plainLogger is a Logger without fields, remembering the last message
+type plainLogger struct {
+	NopLogger
+	last string
+}
+
+func (p *plainLogger) Infof(format string, args ...any) {
+	p.last = fmt.Sprintf(format, args...)
+}
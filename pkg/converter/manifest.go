@@ -0,0 +1,185 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Manifest lists the SHA-256 of converted outputs by slash-separated path relative to their output directory.
+// It is written in the format of sha256sum, so `sha256sum -c` can check it too. It is safe for concurrent use.
+type Manifest struct {
+	mu     sync.Mutex
+	hashes map[string]string
+}
+
+// ManifestEntry is a file listed in a Manifest
+type ManifestEntry struct {
+	Path   string
+	SHA256 string // Hex-encoded
+}
+
+// ManifestMismatch is a file that doesn't match its manifest entry
+type ManifestMismatch struct {
+	Path     string
+	Expected string
+	Actual   string // Empty if the file couldn't be read
+	Err      error  // Why the file couldn't be read, such as fs.ErrNotExist
+}
+
+// NewManifest creates an empty Manifest
+func NewManifest() *Manifest {
+	return &Manifest{hashes: make(map[string]string)}
+}
+
+// SetManifest makes batch conversions add the SHA-256 of every output they write to manifest, nil to disable.
+// Outputs skipped or left unchanged, such as by SetIncremental, aren't added.
+func (f *FilesConverter) SetManifest(manifest *Manifest) {
+	f.manifest = manifest
+}
+
+// Add records the hex-encoded SHA-256 of the file at path, replacing an earlier entry
+func (m *Manifest) Add(path, sha256 string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hashes[path] = sha256
+}
+
+// Entries returns the files of the manifest, sorted by path
+func (m *Manifest) Entries() []ManifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]ManifestEntry, 0, len(m.hashes))
+	for path, hash := range m.hashes {
+		entries = append(entries, ManifestEntry{Path: path, SHA256: hash})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// Len returns the number of files in the manifest
+func (m *Manifest) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.hashes)
+}
+
+// WriteTo writes the manifest in the format of sha256sum, a line of hash, two spaces and path per file
+func (m *Manifest) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	for _, entry := range m.Entries() {
+		fmt.Fprintf(&b, "%s  %s\n", entry.SHA256, entry.Path)
+	}
+	return b.WriteTo(w)
+}
+
+// WriteFile writes the manifest to path
+func (m *Manifest) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest '%s': %w", path, err)
+	}
+	_, err = m.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write manifest '%s': %w", path, err)
+	}
+	return nil
+}
+
+// ReadManifest parses a manifest in the format of sha256sum, in text or binary mode
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	manifest := NewManifest()
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		hash, path, ok := strings.Cut(text, " ")
+		path = strings.TrimPrefix(path, " ")
+		path = strings.TrimPrefix(path, "*") // Binary mode marker
+		if decoded, err := hex.DecodeString(hash); !ok || err != nil || len(decoded) != 32 || path == "" {
+			return nil, fmt.Errorf("invalid manifest line %d: %q", line, text)
+		}
+		manifest.Add(path, strings.ToLower(hash))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ReadManifestFile parses the manifest at path
+func ReadManifestFile(path string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest '%s': %w", path, err)
+	}
+	defer file.Close()
+	manifest, err := ReadManifest(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest '%s': %w", path, err)
+	}
+	return manifest, nil
+}
+
+// Verify hashes every file of the manifest in fsys, returning the files that are missing or differ.
+// Files in fsys the manifest doesn't list are ignored.
+func (m *Manifest) Verify(fsys fs.FS) ([]ManifestMismatch, error) {
+	var mismatches []ManifestMismatch
+	for _, entry := range m.Entries() {
+		if !fs.ValidPath(entry.Path) {
+			return nil, fmt.Errorf("invalid manifest path %q", entry.Path)
+		}
+		actual, err := hashFSFile(fsys, entry.Path)
+		if err != nil {
+			mismatches = append(mismatches, ManifestMismatch{Path: entry.Path, Expected: entry.SHA256, Err: err})
+		} else if actual != entry.SHA256 {
+			mismatches = append(mismatches, ManifestMismatch{Path: entry.Path, Expected: entry.SHA256, Actual: actual})
+		}
+	}
+	return mismatches, nil
+}
+
+// VerifyDir checks the outputs below dir, a directory or a zip archive, against the manifest
+func (m *Manifest) VerifyDir(dir string) ([]ManifestMismatch, error) {
+	source, closer, err := openSource(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return m.Verify(source)
+}
+
+// VerifyManifest checks the outputs below dir, a directory or a zip archive, against the manifest at path
+func VerifyManifest(path, dir string) ([]ManifestMismatch, error) {
+	manifest, err := ReadManifestFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.VerifyDir(dir)
+}
+
+// hashFSFile returns the hex-encoded SHA-256 of the file name in fsys
+func hashFSFile(fsys fs.FS, name string) (string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil && info.IsDir() {
+		return "", errors.New("is a directory")
+	}
+	return hashReader(file)
+}
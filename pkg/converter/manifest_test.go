@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestManifest tests that a batch's manifest lists its outputs and catches changed and missing files
+func TestManifest(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	manifest := NewManifest()
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithManifest(manifest), WithProvenance(true, nil))
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	entries := manifest.Entries()
+	if len(entries) != len(testImages) {
+		t.Fatalf("Expected %d entries, got %d", len(testImages), len(entries))
+	}
+	sum := sha256.Sum256(readFile(t, filepath.Join(toDir, entries[0].Path)))
+	if entries[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected the hash of %s as written, provenance included", entries[0].Path)
+	}
+
+	manifestPath := filepath.Join(t.TempDir(), "out.sha256")
+	if err := manifest.WriteFile(manifestPath); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if mismatches, err := VerifyManifest(manifestPath, toDir); err != nil || len(mismatches) != 0 {
+		t.Fatalf("Expected the outputs to verify, got %v, %v", mismatches, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(toDir, entries[0].Path), []byte("tampered"), 0644); err != nil {
+		t.Fatalf("Failed to modify output: %v", err)
+	}
+	if err := os.Remove(filepath.Join(toDir, entries[1].Path)); err != nil {
+		t.Fatalf("Failed to remove output: %v", err)
+	}
+	mismatches, err := VerifyManifest(manifestPath, toDir)
+	if err != nil {
+		t.Fatalf("VerifyManifest failed: %v", err)
+	}
+	if len(mismatches) != 2 {
+		t.Fatalf("Expected 2 mismatches, got %v", mismatches)
+	}
+	if mismatches[0].Path != entries[0].Path || mismatches[0].Actual == "" {
+		t.Errorf("Expected %s to be reported as modified, got %+v", entries[0].Path, mismatches[0])
+	}
+	if mismatches[1].Path != entries[1].Path || !errors.Is(mismatches[1].Err, fs.ErrNotExist) {
+		t.Errorf("Expected %s to be reported as missing, got %+v", entries[1].Path, mismatches[1])
+	}
+}
+
+// TestReadManifest tests parsing the text and binary mode lines of sha256sum and rejecting other lines
+func TestReadManifest(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	manifest, err := ReadManifest(strings.NewReader(hash + "  a b.png\n" + strings.ToUpper(hash) + " *sub/c.png\r\n\n"))
+	if err != nil {
+		t.Fatalf("ReadManifest failed: %v", err)
+	}
+	var written bytes.Buffer
+	manifest.WriteTo(&written)
+	if want := hash + "  a b.png\n" + hash + "  sub/c.png\n"; written.String() != want {
+		t.Errorf("Expected %q, got %q", want, written.String())
+	}
+
+	for _, line := range []string{"not a manifest", "abcd  short.png", hash + "  "} {
+		if _, err := ReadManifest(strings.NewReader(line)); err == nil {
+			t.Errorf("Expected %q to be rejected", line)
+		}
+	}
+}
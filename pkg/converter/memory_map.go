@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+)
+
+// memoryMapMinSize is the size from which inputs are memory-mapped, smaller files being cheaper to read
+const memoryMapMinSize = 1 << 20
+
+// errMemoryMapUnsupported is returned by mapFile on platforms without memory mapping
+var errMemoryMapUnsupported = errors.New("memory mapping is not supported on this platform")
+
+// SetMemoryMap makes batch conversions memory-map input files of 1 MiB and more instead of reading them.
+// Large DATA textures decoded with several image workers are then parsed in place, so their stream isn't
+// held in memory next to the decoded pixels. Inputs that can't be mapped, such as files in zip archives or
+// on platforms without memory mapping, are read as usual. A mapped input must not be truncated while it
+// is converted.
+func (f *FilesConverter) SetMemoryMap(enabled bool) {
+	f.memoryMap = enabled
+}
+
+// mappedInput is implemented by inputs whose remaining contents are available as a byte slice
+type mappedInput interface {
+	mappedBytes() ([]byte, bool)
+}
+
+// mappedData returns the unread contents of input if it is memory-mapped. They are considered read.
+func mappedData(input io.Reader) ([]byte, bool) {
+	mapped, ok := input.(mappedInput)
+	if !ok {
+		return nil, false
+	}
+	return mapped.mappedBytes()
+}
+
+// MappedFile is a file read through a memory mapping
+type MappedFile struct {
+	*bytes.Reader
+	file *os.File
+	data []byte
+}
+
+// OpenMapped opens the file path for reading, memory-mapping it when it is large enough and the platform
+// supports it. The result is an *os.File otherwise. Like SetMemoryMap, it lets DATA decoding parse large
+// textures in place.
+func OpenMapped(path string) (fs.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return mapInput(file), nil
+}
+
+// mapInput memory-maps input if it is a large enough regular file, returning it unchanged otherwise
+func mapInput(input fs.File) fs.File {
+	file, ok := input.(*os.File)
+	if !ok {
+		return input
+	}
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() < memoryMapMinSize || info.Size() > math.MaxInt {
+		return input
+	}
+	data, err := mapFile(file, int(info.Size()))
+	if err != nil {
+		return input
+	}
+	return &MappedFile{Reader: bytes.NewReader(data), file: file, data: data}
+}
+
+// Stat returns the FileInfo of the mapped file
+func (m *MappedFile) Stat() (fs.FileInfo, error) {
+	return m.file.Stat()
+}
+
+// Close unmaps and closes the file. Slices of its contents must not be used afterwards.
+func (m *MappedFile) Close() error {
+	if m.data == nil {
+		return os.ErrClosed
+	}
+	err := unmapFile(m.data)
+	m.data = nil
+	m.Reader = bytes.NewReader(nil)
+	return errors.Join(err, m.file.Close())
+}
+
+func (m *MappedFile) mappedBytes() ([]byte, bool) {
+	rest := m.data[len(m.data)-m.Len():]
+	m.Seek(0, io.SeekEnd)
+	return rest, true
+}
+
+// mappedBytes passes on the contents of a memory-mapped input, counting them as read. Inputs read
+// through a rate limiter are never passed on, as they would skip the limit.
+func (c *contextReader) mappedBytes() ([]byte, bool) {
+	if c.ctx.Err() != nil {
+		return nil, false
+	}
+	data, ok := mappedData(c.r)
+	if ok {
+		c.n += int64(len(data))
+		if c.head != nil {
+			c.head.record(data)
+		}
+		if c.heartbeat != nil {
+			c.heartbeat.touch()
+		}
+	}
+	return data, ok
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package converter
+
+import "os"
+
+// mapFile is not supported on this platform, so inputs are read instead
+func mapFile(file *os.File, size int) ([]byte, error) {
+	return nil, errMemoryMapUnsupported
+}
+
+// unmapFile has nothing to release on this platform
+func unmapFile(data []byte) error {
+	return nil
+}
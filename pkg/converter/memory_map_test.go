@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeLargeData writes a noisy texture whose DATA stream is large enough to be memory-mapped
+func writeLargeData(t *testing.T, path string, trailing []byte) []byte {
+	random := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, 1024, 512))
+	random.Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+
+	var data bytes.Buffer
+	if err := NewGraphicsConverter().encodeData(img, &data); err != nil {
+		t.Fatalf("encodeData failed: %v", err)
+	}
+	if data.Len() < memoryMapMinSize {
+		t.Fatalf("Expected at least %d bytes of DATA, got %d", memoryMapMinSize, data.Len())
+	}
+	data.Write(trailing)
+	if err := os.WriteFile(path, data.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	return data.Bytes()
+}
+
+// openMappedData opens path with OpenMapped, skipping the test where it isn't mapped
+func openMappedData(t *testing.T, path string) *MappedFile {
+	file, err := OpenMapped(path)
+	if err != nil {
+		t.Fatalf("OpenMapped failed: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+	mapped, ok := file.(*MappedFile)
+	if !ok {
+		t.Skipf("Memory mapping isn't supported on %s", runtime.GOOS)
+	}
+	return mapped
+}
+
+// TestMappedDecodeMatchesRead tests that decoding a mapped file in place gives the same pixels as reading it
+func TestMappedDecodeMatchesRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "large.data")
+	data := writeLargeData(t, path, nil)
+
+	gc := NewGraphicsConverter()
+	gc.SetImageWorkers(4)
+	expected, err := gc.decodeData(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decodeData failed: %v", err)
+	}
+	actual, err := gc.decodeData(openMappedData(t, path))
+	if err != nil {
+		t.Fatalf("decodeData of the mapped file failed: %v", err)
+	}
+	if !bytes.Equal(actual.Pix, expected.Pix) {
+		t.Error("Expected the mapped file to decode to the same pixels")
+	}
+}
+
+// TestMappedDecodeTrailing tests that strict decoding of a mapped file still reports trailing bytes
+func TestMappedDecodeTrailing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trailing.data")
+	writeLargeData(t, path, []byte{1, 2, 3})
+
+	gc := NewGraphicsConverter()
+	gc.SetImageWorkers(4)
+	gc.SetStrict(true)
+	if _, err := gc.decodeData(openMappedData(t, path)); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("Expected ErrTrailingData, got %v", err)
+	}
+}
+
+// TestMemoryMapBatch tests that batch conversions with memory mapping write the same outputs
+func TestMemoryMapBatch(t *testing.T) {
+	fromDir := t.TempDir()
+	writeLargeData(t, filepath.Join(fromDir, "large.data"), nil)
+	setupTestDataFiles(t, fromDir)
+
+	gc := NewGraphicsConverter(WithImageWorkers(4))
+	readDir, mappedDir := t.TempDir(), t.TempDir()
+	if err := NewFilesConverter(gc).DataToPng(fromDir, readDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	if err := NewFilesConverter(gc, WithMemoryMap(true)).DataToPng(fromDir, mappedDir); err != nil {
+		t.Fatalf("DataToPng with memory mapping failed: %v", err)
+	}
+	for _, name := range []string{"large.png", "red.png"} {
+		if !bytes.Equal(readFile(t, filepath.Join(readDir, name)), readFile(t, filepath.Join(mappedDir, name))) {
+			t.Errorf("Expected %s to be the same with memory mapping", name)
+		}
+	}
+}
+
+// TestOpenMappedSmallFile tests that small files are read instead of mapped
+func TestOpenMappedSmallFile(t *testing.T) {
+	file, err := OpenMapped(filepath.Join("testdata", "data", "red.data"))
+	if err != nil {
+		t.Fatalf("OpenMapped failed: %v", err)
+	}
+	defer file.Close()
+	if _, ok := file.(*os.File); !ok {
+		t.Errorf("Expected an *os.File for a small file, got %T", file)
+	}
+}
@@ -0,0 +1,116 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrMergeConflict is returned with PrecedenceFail when several source directories hold the same file
+var ErrMergeConflict = errors.New("file is in more than one source directory")
+
+// MergePrecedence selects which source directory's file is converted when several hold the same
+// relative path
+type MergePrecedence int
+
+const (
+	// PrecedenceLast converts the file of the last directory, as if the directories were copied over each
+	// other in order, like mod content folders loaded after one another
+	PrecedenceLast MergePrecedence = iota
+	// PrecedenceFirst converts the file of the first directory
+	PrecedenceFirst
+	// PrecedenceFail refuses to convert anything if any relative path is in more than one directory
+	PrecedenceFail
+)
+
+// mergePrecedences maps the names accepted by ParseMergePrecedence to merge precedences
+var mergePrecedences = map[string]MergePrecedence{
+	"last":  PrecedenceLast,
+	"first": PrecedenceFirst,
+	"fail":  PrecedenceFail,
+}
+
+// ParseMergePrecedence parses a merge precedence name: last, first or fail
+func ParseMergePrecedence(name string) (MergePrecedence, error) {
+	precedence, ok := mergePrecedences[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown merge precedence '%s', expected last, first or fail", name)
+	}
+	return precedence, nil
+}
+
+// String returns the name of a merge precedence
+func (p MergePrecedence) String() string {
+	for name, precedence := range mergePrecedences {
+		if precedence == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("MergePrecedence(%d)", int(p))
+}
+
+// SetMergePrecedence sets which file ConvertMerged converts when several source directories hold the
+// same relative path, PrecedenceLast by default
+func (f *FilesConverter) SetMergePrecedence(precedence MergePrecedence) {
+	f.mergePrecedence = precedence
+}
+
+// ConvertMerged converts all files with fromExt in several source directories or zip archives, such as
+// the content folders of a mod pack, into toDir as if they were a single tree. Files with the same
+// relative path in several sources are logged and resolved by the merge precedence.
+func (f *FilesConverter) ConvertMerged(fromDirs []string, toDir, fromExt, toExt string, convertFunc func(io.Reader, io.Writer) error) error {
+	return f.ConvertMergedContext(context.Background(), fromDirs, toDir, fromExt, toExt, convertFunc)
+}
+
+// ConvertMergedContext is like ConvertMerged but stops when ctx is cancelled, removing partially written outputs
+func (f *FilesConverter) ConvertMergedContext(
+	ctx context.Context,
+	fromDirs []string, toDir, fromExt, toExt string,
+	convertFunc func(io.Reader, io.Writer) error,
+) error {
+	if len(fromDirs) == 0 {
+		return errors.New("no source directories")
+	}
+	f.log.Infof("Converting %s -> %s from %d directories", formatLabel(fromExt), formatLabel(toExt), len(fromDirs))
+	return f.convertSources(ctx, fromDirs, toDir, fromExt, toExt, convertFunc)
+}
+
+// mergeTasks merges the tasks collected from each source directory, in order, into one batch. Tasks
+// writing the same output, ignoring case, collide when they come from different sources.
+func (f *FilesConverter) mergeTasks(sources [][]ConversionTask) ([]ConversionTask, error) {
+	var merged []ConversionTask
+	type owner struct{ task, source int }
+	owners := make(map[string]owner) // Lowercased output path to the task writing it
+	var conflicts []string
+
+	for i, tasks := range sources {
+		for _, task := range tasks {
+			key := strings.ToLower(task.outputPath)
+			current, ok := owners[key]
+			if !ok || current.source == i {
+				owners[key] = owner{len(merged), i}
+				merged = append(merged, task)
+				continue
+			}
+
+			previous := merged[current.task]
+			switch f.mergePrecedence {
+			case PrecedenceFail:
+				conflicts = append(conflicts, fmt.Sprintf("%s and %s", previous.inputPath, task.inputPath))
+			case PrecedenceFirst:
+				f.log.Warnf("Skipping %s: %s takes precedence", task.inputPath, previous.inputPath)
+			default:
+				f.log.Warnf("Skipping %s: %s takes precedence", previous.inputPath, task.inputPath)
+				merged[current.task] = task
+				owners[key] = owner{current.task, i}
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMergeConflict, strings.Join(conflicts, "; "))
+	}
+	return renumberTasks(merged), nil
+}
@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupMergeSources creates two source directories that both hold sub/shared.data, red in the first
+// and blue in the second, plus a file only each of them has
+func setupMergeSources(t *testing.T) []string {
+	dirs := []string{t.TempDir(), t.TempDir()}
+	for i, color := range []string{"red", "blue"} {
+		if err := os.MkdirAll(filepath.Join(dirs[i], "sub"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		copyFile(t, filepath.Join("testdata", "data", color+".data"), filepath.Join(dirs[i], "sub", "shared.data"))
+		copyFile(t, filepath.Join("testdata", "data", color+".data"), filepath.Join(dirs[i], color+".data"))
+	}
+	return dirs
+}
+
+// TestConvertMerged tests that source directories are merged, with collisions resolved by precedence
+func TestConvertMerged(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+	for _, test := range []struct {
+		precedence MergePrecedence
+		winner     string
+	}{
+		{PrecedenceLast, "blue"},
+		{PrecedenceFirst, "red"},
+	} {
+		t.Run(test.precedence.String(), func(t *testing.T) {
+			toDir := t.TempDir()
+			filesConverter := NewFilesConverter(graphicsConverter)
+			filesConverter.SetMergePrecedence(test.precedence)
+			if err := filesConverter.ConvertMerged(setupMergeSources(t), toDir, ".data", ".png", graphicsConverter.DataToPng); err != nil {
+				t.Fatalf("ConvertMerged failed: %v", err)
+			}
+
+			for _, name := range []string{"red.png", "blue.png"} {
+				if !fileExists(filepath.Join(toDir, name)) {
+					t.Errorf("Expected %s from a single source to be converted", name)
+				}
+			}
+			expected := bytesToImage(t, dataToPngBytes(t, graphicsConverter, readTestResource(t, filepath.Join("data", test.winner+".data"))))
+			actual := bytesToImage(t, readFile(t, filepath.Join(toDir, "sub", "shared.png")))
+			assertImageEquals(t, expected, actual, 0)
+		})
+	}
+}
+
+// TestConvertMergedConflict tests that PrecedenceFail converts nothing when sources collide
+func TestConvertMergedConflict(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+	toDir := t.TempDir()
+	filesConverter := NewFilesConverter(graphicsConverter)
+	filesConverter.SetMergePrecedence(PrecedenceFail)
+
+	err := filesConverter.ConvertMerged(setupMergeSources(t), toDir, ".data", ".png", graphicsConverter.DataToPng)
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("Expected ErrMergeConflict, got %v", err)
+	}
+	if entries, _ := os.ReadDir(toDir); len(entries) != 0 {
+		t.Errorf("Expected nothing to be written, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,284 @@
+package converter
+
+import (
+	"image/png"
+	"time"
+)
+
+// GraphicsOption configures a GraphicsConverter in NewGraphicsConverter. Each option does what the
+// setter of the same name does.
+type GraphicsOption func(*GraphicsConverter)
+
+// FilesOption configures a FilesConverter in NewFilesConverter. Each option does what the setter of
+// the same name does; SetFilter has no option since it can fail.
+type FilesOption func(*FilesConverter)
+
+// WithAlphaMode is the option form of SetAlphaMode
+func WithAlphaMode(mode AlphaMode) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetAlphaMode(mode) }
+}
+
+// WithAlphaChannel is the option form of SetAlphaChannel
+func WithAlphaChannel(channel AlphaChannel) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetAlphaChannel(channel) }
+}
+
+// WithMaxDimension is the option form of SetMaxDimension
+func WithMaxDimension(pixels int) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetMaxDimension(pixels) }
+}
+
+// WithMaxImageMemory is the option form of SetMaxImageMemory
+func WithMaxImageMemory(bytes int64) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetMaxImageMemory(bytes) }
+}
+
+// WithMaxRunLength is the option form of SetMaxRunLength
+func WithMaxRunLength(length int) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetMaxRunLength(length) }
+}
+
+// WithImageWorkers is the option form of SetImageWorkers
+func WithImageWorkers(workers int) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetImageWorkers(workers) }
+}
+
+// WithDither is the option form of SetDither
+func WithDither(enabled bool) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetDither(enabled) }
+}
+
+// WithDitherMethod is the option form of SetDitherMethod
+func WithDitherMethod(method DitherMethod) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetDitherMethod(method) }
+}
+
+// WithPngCompression is the option form of SetPngCompression
+func WithPngCompression(level png.CompressionLevel) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetPngCompression(level) }
+}
+
+// WithColorManagement is the option form of SetColorManagement
+func WithColorManagement(enabled bool) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetColorManagement(enabled) }
+}
+
+// WithPngPalette is the option form of SetPngPalette
+func WithPngPalette(palette PngPalette) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetPngPalette(palette) }
+}
+
+// WithTransforms is the option form of SetTransforms
+func WithTransforms(transforms ...Transform) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetTransforms(transforms...) }
+}
+
+// WithStrict is the option form of SetStrict
+func WithStrict(enabled bool) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetStrict(enabled) }
+}
+
+// WithExtended is the option form of SetExtended
+func WithExtended(enabled bool) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetExtended(enabled) }
+}
+
+// WithGraphicsLogger is the option form of SetLogger
+func WithGraphicsLogger(logger Logger) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetLogger(logger) }
+}
+
+// WithEndianness is the option form of SetEndianness
+func WithEndianness(endianness Endianness) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetEndianness(endianness) }
+}
+
+// WithChannelOrder is the option form of SetChannelOrder
+func WithChannelOrder(order ChannelOrder) GraphicsOption {
+	return func(g *GraphicsConverter) { g.SetChannelOrder(order) }
+}
+
+// WithLogger is the option form of SetLogger
+func WithLogger(logger Logger) FilesOption {
+	return func(f *FilesConverter) { f.SetLogger(logger) }
+}
+
+// WithWorkers is the option form of SetMaxWorkers
+func WithWorkers(workers int) FilesOption {
+	return func(f *FilesConverter) { f.SetMaxWorkers(workers) }
+}
+
+// WithProvenance is the option form of SetProvenance
+func WithProvenance(enabled bool, options map[string]string) FilesOption {
+	return func(f *FilesConverter) { f.SetProvenance(enabled, options) }
+}
+
+// WithContinueOnError is the option form of SetContinueOnError
+func WithContinueOnError(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetContinueOnError(enabled) }
+}
+
+// WithQuarantineDir is the option form of SetQuarantineDir
+func WithQuarantineDir(dir string) FilesOption {
+	return func(f *FilesConverter) { f.SetQuarantineDir(dir) }
+}
+
+// WithProgress is the option form of Progress
+func WithProgress(hook func(ProgressEvent)) FilesOption {
+	return func(f *FilesConverter) { f.Progress(hook) }
+}
+
+// WithRateLimit is the option form of SetRateLimit
+func WithRateLimit(filesPerSecond float64, bytesPerSecond int64) FilesOption {
+	return func(f *FilesConverter) { f.SetRateLimit(filesPerSecond, bytesPerSecond) }
+}
+
+// WithDryRun is the option form of SetDryRun
+func WithDryRun(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetDryRun(enabled) }
+}
+
+// WithPlan is the option form of SetPlan
+func WithPlan(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetPlan(enabled) }
+}
+
+// WithSniff is the option form of SetSniff
+func WithSniff(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetSniff(enabled) }
+}
+
+// WithIncremental is the option form of SetIncremental
+func WithIncremental(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetIncremental(enabled) }
+}
+
+// WithIndex is the option form of SetIndex
+func WithIndex(index *AssetIndex) FilesOption {
+	return func(f *FilesConverter) { f.SetIndex(index) }
+}
+
+// WithOverwritePolicy is the option form of SetOverwritePolicy
+func WithOverwritePolicy(policy OverwritePolicy) FilesOption {
+	return func(f *FilesConverter) { f.SetOverwritePolicy(policy) }
+}
+
+// WithOrderedOutput is the option form of SetOrderedOutput
+func WithOrderedOutput(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetOrderedOutput(enabled) }
+}
+
+// WithStallTimeout is the option form of SetStallTimeout
+func WithStallTimeout(timeout time.Duration) FilesOption {
+	return func(f *FilesConverter) { f.SetStallTimeout(timeout) }
+}
+
+// WithSkipStalled is the option form of SetSkipStalled
+func WithSkipStalled(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetSkipStalled(enabled) }
+}
+
+// WithFileTimeout is the option form of SetFileTimeout
+func WithFileTimeout(timeout time.Duration) FilesOption {
+	return func(f *FilesConverter) { f.SetFileTimeout(timeout) }
+}
+
+// WithDedupe is the option form of SetDedupe
+func WithDedupe(mode DedupeMode) FilesOption {
+	return func(f *FilesConverter) { f.SetDedupe(mode) }
+}
+
+// WithFollowSymlinks is the option form of SetFollowSymlinks
+func WithFollowSymlinks(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetFollowSymlinks(enabled) }
+}
+
+// WithPreserveAttributes is the option form of SetPreserveAttributes
+func WithPreserveAttributes(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetPreserveAttributes(enabled) }
+}
+
+// WithMergePrecedence is the option form of SetMergePrecedence
+func WithMergePrecedence(precedence MergePrecedence) FilesOption {
+	return func(f *FilesConverter) { f.SetMergePrecedence(precedence) }
+}
+
+// WithOutputTemplate is the option form of SetOutputTemplate
+func WithOutputTemplate(template *OutputTemplate) FilesOption {
+	return func(f *FilesConverter) { f.SetOutputTemplate(template) }
+}
+
+// WithMemoryMap is the option form of SetMemoryMap
+func WithMemoryMap(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetMemoryMap(enabled) }
+}
+
+// WithResume is the option form of SetResume
+func WithResume(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetResume(enabled) }
+}
+
+// WithManifest is the option form of SetManifest
+func WithManifest(manifest *Manifest) FilesOption {
+	return func(f *FilesConverter) { f.SetManifest(manifest) }
+}
+
+// WithQuietFiles is the option form of SetQuietFiles
+func WithQuietFiles(quiet bool) FilesOption {
+	return func(f *FilesConverter) { f.SetQuietFiles(quiet) }
+}
+
+// WithSchedule is the option form of SetSchedule
+func WithSchedule(schedule Schedule) FilesOption {
+	return func(f *FilesConverter) { f.SetSchedule(schedule) }
+}
+
+// WithLargeFilePool is the option form of SetLargeFilePool
+func WithLargeFilePool(minSize int64, workers int) FilesOption {
+	return func(f *FilesConverter) { f.SetLargeFilePool(minSize, workers) }
+}
+
+// WithTrim is the option form of SetTrim
+func WithTrim(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetTrim(enabled) }
+}
+
+// WithOverwriteBackup is the option form of SetOverwriteBackup
+func WithOverwriteBackup(backup OverwriteBackup) FilesOption {
+	return func(f *FilesConverter) { f.SetOverwriteBackup(backup) }
+}
+
+// WithDeterministic is the option form of SetDeterministic
+func WithDeterministic(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetDeterministic(enabled) }
+}
+
+// WithFileList is the option form of SetFileList
+func WithFileList(paths []string) FilesOption {
+	return func(f *FilesConverter) { f.SetFileList(paths) }
+}
+
+// WithIOConcurrency is the option form of SetIOConcurrency
+func WithIOConcurrency(files int) FilesOption {
+	return func(f *FilesConverter) { f.SetIOConcurrency(files) }
+}
+
+// WithCache is the option form of SetCache
+func WithCache(cache *ConversionCache) FilesOption {
+	return func(f *FilesConverter) { f.SetCache(cache) }
+}
+
+// WithFlatten is the option form of SetFlatten
+func WithFlatten(enabled bool) FilesOption {
+	return func(f *FilesConverter) { f.SetFlatten(enabled) }
+}
+
+// WithStripPrefix is the option form of SetStripPrefix
+func WithStripPrefix(prefix string) FilesOption {
+	return func(f *FilesConverter) { f.SetStripPrefix(prefix) }
+}
+
+// WithAddPrefix is the option form of SetAddPrefix
+func WithAddPrefix(prefix string) FilesOption {
+	return func(f *FilesConverter) { f.SetAddPrefix(prefix) }
+}
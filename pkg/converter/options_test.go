@@ -0,0 +1,33 @@
+package converter
+
+import (
+	"image/png"
+	"testing"
+)
+
+// TestGraphicsOptions tests that constructor options configure like their setters
+func TestGraphicsOptions(t *testing.T) {
+	g := NewGraphicsConverter(WithMaxDimension(512), WithStrict(true), WithPngCompression(png.BestSpeed), WithMaxRunLength(-1))
+	if g.maxDimension != 512 || !g.strict || g.pngEncoder.CompressionLevel != png.BestSpeed {
+		t.Errorf("Expected the options to be applied, got %+v", g)
+	}
+	if g.maxRunLength != MaxRunLength {
+		t.Errorf("Expected an invalid run length to be ignored like SetMaxRunLength does, got %d", g.maxRunLength)
+	}
+}
+
+// TestFilesOptions tests that constructor options configure like their setters, in order
+func TestFilesOptions(t *testing.T) {
+	f := NewFilesConverter(NewGraphicsConverter(),
+		WithWorkers(3),
+		WithOverwritePolicy(SkipExisting),
+		WithProgress(func(ProgressEvent) {}),
+		WithWorkers(5),
+	)
+	if f.maxWorkers != 5 {
+		t.Errorf("Expected the last WithWorkers to win, got %d", f.maxWorkers)
+	}
+	if f.overwritePolicy != SkipExisting || f.progressHook == nil {
+		t.Errorf("Expected the options to be applied, got %+v", f)
+	}
+}
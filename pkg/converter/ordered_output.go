@@ -0,0 +1,96 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// SetOrderedOutput makes batch conversions buffer the log lines of each file and release them in input order,
+// even though files are converted in parallel, so the logs of two runs can be diffed
+func (f *FilesConverter) SetOrderedOutput(enabled bool) {
+	f.orderedOutput = enabled
+}
+
+// logEntry is a message recorded by a logBuffer
+type logEntry struct {
+	level   LogLevel
+	message string
+	fields  map[string]any // nil for formatted messages
+}
+
+// logBuffer is a FieldLogger recording messages so they can be replayed to another logger later
+type logBuffer struct {
+	entries []logEntry
+}
+
+func (b *logBuffer) Debugf(format string, args ...any) { b.record(LevelDebug, format, args) }
+func (b *logBuffer) Infof(format string, args ...any)  { b.record(LevelInfo, format, args) }
+func (b *logBuffer) Warnf(format string, args ...any)  { b.record(LevelWarn, format, args) }
+func (b *logBuffer) Errorf(format string, args ...any) { b.record(LevelError, format, args) }
+
+func (b *logBuffer) LogFields(level LogLevel, message string, fields map[string]any) {
+	b.entries = append(b.entries, logEntry{level: level, message: message, fields: fields})
+}
+
+func (b *logBuffer) record(level LogLevel, format string, args []any) {
+	b.entries = append(b.entries, logEntry{level: level, message: fmt.Sprintf(format, args...)})
+}
+
+// replay logs the recorded messages to log, in the order they were recorded
+func (b *logBuffer) replay(log Logger) {
+	for _, entry := range b.entries {
+		if entry.fields != nil {
+			logFields(log, entry.level, entry.message, entry.fields)
+		} else {
+			logf(log, entry.level, "%s", entry.message)
+		}
+	}
+}
+
+// orderedOutput releases the buffered log lines of a batch's files in task order
+type orderedOutput struct {
+	mu      sync.Mutex
+	out     Logger
+	pending map[int]*logBuffer // Lines of finished files waiting for an earlier file, by task index
+	next    int                // Index of the next file to write
+}
+
+// newOrderedOutput creates an orderedOutput logging to out, starting at the first task
+func newOrderedOutput(out Logger) *orderedOutput {
+	return &orderedOutput{out: out, pending: make(map[int]*logBuffer), next: 1}
+}
+
+// release hands over the lines of a finished file, logging them along with every following file
+// that is already done once all earlier files have been logged
+func (o *orderedOutput) release(index int, lines *logBuffer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending[index] = lines
+	for {
+		lines, ok := o.pending[o.next]
+		if !ok {
+			return
+		}
+		lines.replay(o.out)
+		delete(o.pending, o.next)
+		o.next++
+	}
+}
+
+// flush logs the lines still waiting, in order, for batches stopped before every file was done
+func (o *orderedOutput) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	indexes := make([]int, 0, len(o.pending))
+	for index := range o.pending {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		o.pending[index].replay(o.out)
+		delete(o.pending, index)
+	}
+}
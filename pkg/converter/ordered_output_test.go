@@ -0,0 +1,85 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// bufferedMessages returns the messages a logBuffer recorded
+func bufferedMessages(b *logBuffer) string {
+	var messages []string
+	for _, entry := range b.entries {
+		messages = append(messages, entry.message)
+	}
+	return strings.Join(messages, ",")
+}
+
+// bufferOf returns a logBuffer holding a single message
+func bufferOf(message string) *logBuffer {
+	b := new(logBuffer)
+	b.Infof("%s", message)
+	return b
+}
+
+// TestOrderedOutputRelease tests that lines are logged in index order whatever order files finish in
+func TestOrderedOutputRelease(t *testing.T) {
+	out := new(logBuffer)
+	ordered := newOrderedOutput(out)
+
+	ordered.release(3, bufferOf("3"))
+	ordered.release(2, bufferOf("2"))
+	if len(out.entries) != 0 {
+		t.Fatalf("Expected nothing before the first file is done, got %q", bufferedMessages(out))
+	}
+	ordered.release(1, bufferOf("1"))
+	ordered.release(5, bufferOf("5"))
+	if got := bufferedMessages(out); got != "1,2,3" {
+		t.Fatalf("Expected files 1 to 3, got %q", got)
+	}
+
+	// File 4 never finished, as after cancellation
+	ordered.flush()
+	if got := bufferedMessages(out); got != "1,2,3,5" {
+		t.Errorf("Expected the remaining file after flush, got %q", got)
+	}
+}
+
+// TestOrderedOutputBatch tests that a parallel batch logs every file's lines together and in input order
+func TestOrderedOutputBatch(t *testing.T) {
+	fromDir := t.TempDir()
+	setupTestDataFiles(t, fromDir)
+
+	logger := new(logBuffer)
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithLogger(logger))
+	filesConverter.SetMaxWorkers(4)
+	filesConverter.SetOrderedOutput(true)
+
+	if err := filesConverter.DataToPng(fromDir, t.TempDir()); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	// Each "converting" line must be directly followed by the result of the same file
+	files := 0
+	for i, entry := range logger.entries {
+		var index int
+		var name string
+		if _, err := fmt.Sscanf(entry.message, "[%d/10] converting %s", &index, &name); err != nil {
+			continue
+		}
+		files++
+		if index != files {
+			t.Errorf("Expected file %d, got %q", files, entry.message)
+		}
+		if i+1 >= len(logger.entries) {
+			t.Fatalf("Missing the result of %s", name)
+		}
+		result := logger.entries[i+1].fields
+		if result["file"] != name || result["index"] != index {
+			t.Errorf("Expected the result of %s to follow it, got %v", name, result)
+		}
+	}
+	if files != 10 {
+		t.Errorf("Expected 10 files logged, got %d in %q", files, bufferedMessages(logger))
+	}
+}
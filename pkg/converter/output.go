@@ -0,0 +1,229 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outputSink receives the files written by a batch conversion, either as loose files or as zip entries
+type outputSink interface {
+	// create starts a new output file at path, which lies below the batch's target directory
+	create(path string) (outputFile, error)
+	// close finishes the sink once all files are written
+	close() error
+	// abort finishes the sink after a cancelled batch, dropping what it can
+	abort()
+}
+
+// outputFile is a single output being written to a sink
+type outputFile interface {
+	io.Writer
+	// commit finishes the file
+	commit() error
+	// discard drops the partially written file
+	discard()
+}
+
+// hasZipExtension reports whether path ends in .zip, ignoring case
+func hasZipExtension(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".zip")
+}
+
+// newOutputSink returns a sink writing below toDir, or into a new zip archive if toDir ends in .zip.
+// Deterministic zip archives get their entries sorted by name, with a fixed modification time.
+func newOutputSink(toDir string, deterministic bool) (outputSink, error) {
+	if !hasZipExtension(toDir) {
+		if err := os.MkdirAll(toDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory '%s': %w", toDir, err)
+		}
+		return dirSink{}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(toDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(toDir), err)
+	}
+	file, err := os.Create(toDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip archive '%s': %w", toDir, err)
+	}
+	return &zipSink{path: toDir, file: file, writer: zip.NewWriter(file), deterministic: deterministic}, nil
+}
+
+// TempFileInfix separates the name of an output from the random suffix of the temporary file it is
+// written to, such as "idle00.png.tmp-3f2a9c1e". Outputs are renamed into place once complete, so a
+// failed or killed conversion never leaves a truncated output behind.
+const TempFileInfix = ".tmp-"
+
+// maxTempFileAttempts bounds the retries when a temporary file name is already taken
+const maxTempFileAttempts = 10
+
+// dirSink writes outputs as loose files
+type dirSink struct{}
+
+func (dirSink) create(path string) (outputFile, error) {
+	outputDir := filepath.Dir(path)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory '%s': %w", outputDir, err)
+	}
+	file, err := createTempFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file '%s': %w", path, err)
+	}
+	return &dirOutputFile{File: file, path: path}, nil
+}
+
+// createTempFile creates a new temporary file next to path, with the permissions os.Create would give path
+func createTempFile(path string) (*os.File, error) {
+	for attempt := 1; ; attempt++ {
+		tempPath := fmt.Sprintf("%s%s%08x", path, TempFileInfix, rand.Uint32())
+		file, err := os.OpenFile(tempPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, fs.ErrExist) && attempt < maxTempFileAttempts {
+			continue
+		}
+		return file, err
+	}
+}
+
+func (dirSink) close() error { return nil }
+
+func (dirSink) abort() {}
+
+// dirOutputFile is a loose output file, written to a temporary file until it is committed
+type dirOutputFile struct {
+	*os.File
+	path string // Where the file is renamed to once complete
+}
+
+func (d *dirOutputFile) commit() error {
+	if err := d.Close(); err != nil {
+		os.Remove(d.Name())
+		return fmt.Errorf("failed to close output file '%s': %w", d.path, err)
+	}
+	if err := os.Rename(d.Name(), d.path); err != nil {
+		os.Remove(d.Name())
+		return fmt.Errorf("failed to move output file '%s' into place: %w", d.path, err)
+	}
+	return nil
+}
+
+func (d *dirOutputFile) discard() {
+	d.Close()
+	os.Remove(d.Name())
+}
+
+// zipSink writes outputs as entries of a new zip archive.
+// Entries are buffered in memory and written whole, since a zip can only be written one entry at a time.
+// Deterministic sinks keep every entry until the archive is closed, so they are written in name order
+// rather than in the order workers finish them.
+type zipSink struct {
+	path          string
+	mu            sync.Mutex
+	file          *os.File
+	writer        *zip.Writer
+	deterministic bool
+	pending       []*zipOutputFile
+}
+
+func (z *zipSink) create(path string) (outputFile, error) {
+	name, err := filepath.Rel(z.path, path)
+	if err != nil || strings.HasPrefix(name, "..") {
+		return nil, fmt.Errorf("output '%s' lies outside zip archive '%s'", path, z.path)
+	}
+	return &zipOutputFile{sink: z, name: filepath.ToSlash(name)}, nil
+}
+
+func (z *zipSink) close() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	var err error
+	slices.SortFunc(z.pending, func(a, b *zipOutputFile) int { return strings.Compare(a.name, b.name) })
+	for _, entry := range z.pending {
+		if err = z.writeEntry(entry.name, entry.Bytes(), DeterministicModTime); err != nil {
+			break
+		}
+	}
+	z.pending = nil
+	if closeErr := z.writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := z.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to finish zip archive '%s': %w", z.path, err)
+	}
+	return nil
+}
+
+func (z *zipSink) abort() {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	z.pending = nil
+	z.file.Close()
+	os.Remove(z.path)
+}
+
+// writeEntry writes a whole entry to the archive
+func (z *zipSink) writeEntry(name string, data []byte, modified time.Time) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified}
+	w, err := z.writer.CreateHeader(header)
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write '%s' to zip archive '%s': %w", name, z.path, err)
+	}
+	return nil
+}
+
+// zipOutputFile buffers an entry until it is committed to its archive
+type zipOutputFile struct {
+	bytes.Buffer
+	sink *zipSink
+	name string
+}
+
+func (z *zipOutputFile) commit() error {
+	z.sink.mu.Lock()
+	defer z.sink.mu.Unlock()
+
+	if z.sink.deterministic {
+		z.sink.pending = append(z.sink.pending, z)
+		return nil
+	}
+	return z.sink.writeEntry(z.name, z.Bytes(), time.Now())
+}
+
+func (z *zipOutputFile) discard() {}
+
+// writeJSONOutput writes v as indented JSON to path through sink
+func writeJSONOutput(sink outputSink, path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := sink.create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.discard()
+		return err
+	}
+	return file.commit()
+}
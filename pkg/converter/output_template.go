@@ -0,0 +1,124 @@
+package converter
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// outputTemplatePlaceholders lists the placeholders of output templates, and whether they need the
+// input's texture header
+var outputTemplatePlaceholders = map[string]bool{
+	"dir":    false, // Directory of the input relative to the source directory, "." at its root
+	"name":   false, // File name of the input without its extension
+	"ext":    false, // Output extension, such as ".png"
+	"width":  true,
+	"height": true,
+	"alpha":  true, // "rgba" if the header declares transparency, "rgb" otherwise
+}
+
+// OutputTemplate names the outputs of batch conversions from their input's path and texture header
+type OutputTemplate struct {
+	segments   []templateSegment
+	usesHeader bool
+}
+
+// templateSegment is literal text or, if placeholder is set, a placeholder of an output template
+type templateSegment struct {
+	text        string
+	placeholder bool
+}
+
+// ParseOutputTemplate parses an output template such as "{dir}/{name}_{width}x{height}{ext}", an output
+// path relative to the target directory with placeholders in braces: {dir}, {name}, {ext}, {width},
+// {height} and {alpha}. Slashes separate directories on every platform, so "{name}{ext}" flattens
+// the tree.
+func ParseOutputTemplate(template string) (*OutputTemplate, error) {
+	t := &OutputTemplate{}
+	rest := template
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			t.segments = append(t.segments, templateSegment{text: rest})
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed '{' in output template '%s'", template)
+		}
+		name := rest[start+1 : start+end]
+		usesHeader, ok := outputTemplatePlaceholders[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder '{%s}' in output template, expected {dir}, {name}, {ext}, {width}, {height} or {alpha}", name)
+		}
+		if start > 0 {
+			t.segments = append(t.segments, templateSegment{text: rest[:start]})
+		}
+		t.segments = append(t.segments, templateSegment{text: name, placeholder: true})
+		t.usesHeader = t.usesHeader || usesHeader
+		rest = rest[start+end+1:]
+	}
+	if strings.TrimSpace(template) == "" {
+		return nil, fmt.Errorf("empty output template")
+	}
+	return t, nil
+}
+
+// SetOutputTemplate names batch conversion outputs with a template instead of the input's relative path
+// with the output extension, nil to restore the default. Watch mode doesn't support templates.
+func (f *FilesConverter) SetOutputTemplate(template *OutputTemplate) {
+	f.outputTemplate = template
+}
+
+// outputPath expands the template for the input at the slash-separated relPath in source, whose
+// extension fromExt is replaced by toExt. The result must lie inside toDir.
+func (t *OutputTemplate) outputPath(source fs.FS, toDir, relPath, fromExt, toExt string) (string, error) {
+	var width, height int
+	var hasAlpha bool
+	if t.usesHeader {
+		ext := textureExtension(relPath)
+		if ext == "" {
+			ext = strings.ToLower(fromExt)
+		}
+		file, err := source.Open(relPath)
+		if err != nil {
+			return "", err
+		}
+		width, height, hasAlpha, err = textureHeader(file, ext)
+		file.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read the header of '%s' for the output template: %w", relPath, err)
+		}
+	}
+
+	var b strings.Builder
+	for _, segment := range t.segments {
+		if !segment.placeholder {
+			b.WriteString(segment.text)
+			continue
+		}
+		switch segment.text {
+		case "dir":
+			b.WriteString(path.Dir(relPath))
+		case "name":
+			b.WriteString(strings.TrimSuffix(path.Base(relPath), fromExt))
+		case "ext":
+			b.WriteString(toExt)
+		case "width":
+			b.WriteString(strconv.Itoa(width))
+		case "height":
+			b.WriteString(strconv.Itoa(height))
+		case "alpha":
+			b.WriteString(strings.ToLower(boolToFormat(hasAlpha)))
+		}
+	}
+
+	expanded := path.Clean(b.String())
+	if path.IsAbs(expanded) || expanded == "." || expanded == ".." || strings.HasPrefix(expanded, "../") {
+		return "", fmt.Errorf("output template maps '%s' to '%s', outside the target directory", relPath, b.String())
+	}
+	return filepath.Join(toDir, filepath.FromSlash(expanded)), nil
+}
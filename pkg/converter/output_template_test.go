@@ -0,0 +1,106 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestOutputTemplate tests that outputs are named from the input's path and texture header
+func TestOutputTemplate(t *testing.T) {
+	fromDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(fromDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(fromDir, "sub", "blue.data"))
+	toDir := t.TempDir()
+
+	template, err := ParseOutputTemplate("{dir}/{name}_{width}x{height}_{alpha}{ext}")
+	if err != nil {
+		t.Fatalf("ParseOutputTemplate failed: %v", err)
+	}
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetOutputTemplate(template)
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	for _, name := range []string{"red", filepath.Join("sub", "blue")} {
+		info, err := NewGraphicsConverter().TextureInfo(filepath.Join(fromDir, name+".data"))
+		if err != nil {
+			t.Fatalf("TextureInfo failed: %v", err)
+		}
+		output := fmt.Sprintf("%s_%dx%d_%s.png", name, info.Width, info.Height, strings.ToLower(boolToFormat(info.HasAlpha)))
+		if !fileExists(filepath.Join(toDir, output)) {
+			t.Errorf("Expected %s to be written", output)
+		}
+	}
+}
+
+// TestOutputTemplateFlatten tests that a template without {dir} flattens the tree, and that outputs
+// named the same are refused before anything is converted
+func TestOutputTemplateFlatten(t *testing.T) {
+	fromDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(fromDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(fromDir, "sub", "blue.data"))
+
+	template, err := ParseOutputTemplate("{name}{ext}")
+	if err != nil {
+		t.Fatalf("ParseOutputTemplate failed: %v", err)
+	}
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetOutputTemplate(template)
+	toDir := t.TempDir()
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	for _, name := range []string{"red.png", "blue.png"} {
+		if !fileExists(filepath.Join(toDir, name)) {
+			t.Errorf("Expected %s in the output root", name)
+		}
+	}
+
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "sub", "Red.data"))
+	toDir = t.TempDir()
+	if err := filesConverter.DataToPng(fromDir, toDir); err == nil {
+		t.Error("Expected red.data and sub/Red.data colliding to fail")
+	}
+	if fileExists(filepath.Join(toDir, "blue.png")) {
+		t.Error("Expected nothing to be converted after a collision")
+	}
+}
+
+// TestOutputTemplateOutsideTarget tests that templates leading outside the target directory are refused
+func TestOutputTemplateOutsideTarget(t *testing.T) {
+	fromDir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+
+	template, err := ParseOutputTemplate("../{name}{ext}")
+	if err != nil {
+		t.Fatalf("ParseOutputTemplate failed: %v", err)
+	}
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetOutputTemplate(template)
+	toDir := filepath.Join(t.TempDir(), "out")
+	if err := filesConverter.DataToPng(fromDir, toDir); err == nil {
+		t.Error("Expected a template leading outside the target directory to fail")
+	}
+}
+
+// TestParseOutputTemplate tests that malformed templates are rejected
+func TestParseOutputTemplate(t *testing.T) {
+	for _, template := range []string{"", " ", "{name", "{size}{ext}"} {
+		if _, err := ParseOutputTemplate(template); err == nil {
+			t.Errorf("Expected %q to be rejected", template)
+		}
+	}
+	if _, err := ParseOutputTemplate("textures/{name}@{width}{ext}"); err != nil {
+		t.Errorf("ParseOutputTemplate failed: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// TestConvertToZip tests that a target path ending in .zip produces an archive with the relative paths as entries
+func TestConvertToZip(t *testing.T) {
+	fromDir := t.TempDir()
+	zipPath := filepath.Join(t.TempDir(), "nested", "Output.zip")
+
+	if err := os.MkdirAll(filepath.Join(fromDir, "objects"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(fromDir, "objects", "blue.data"))
+
+	graphicsConverter := NewGraphicsConverter()
+	filesConverter := NewFilesConverter(graphicsConverter)
+	filesConverter.SetProvenance(true, nil)
+	if err := filesConverter.DataToPng(fromDir, zipPath); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("Failed to open output archive: %v", err)
+	}
+	defer archive.Close()
+
+	var names []string
+	for _, entry := range archive.File {
+		names = append(names, entry.Name)
+	}
+	sort.Strings(names)
+	expected := []string{
+		"objects/blue.png", "objects/blue.png" + provenanceSidecarSuffix,
+		ProvenanceFileName,
+		"red.png", "red.png" + provenanceSidecarSuffix,
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected entries %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("Expected entries %v, got %v", expected, names)
+		}
+	}
+
+	entry, err := archive.Open("red.png")
+	if err != nil {
+		t.Fatalf("Failed to open entry: %v", err)
+	}
+	pngBytes, err := io.ReadAll(entry)
+	entry.Close()
+	if err != nil {
+		t.Fatalf("Failed to read entry: %v", err)
+	}
+	expectedImage := bytesToImage(t, dataToPngBytes(t, graphicsConverter, readTestResource(t, filepath.Join("data", "red.data"))))
+	assertImageEquals(t, expectedImage, bytesToImage(t, pngBytes), 0)
+}
+
+// TestAtomicOutput tests that outputs only appear once complete, and that a failed conversion keeps
+// the previous output and leaves no temporary file behind
+func TestAtomicOutput(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "red.png")
+	if err := os.WriteFile(outputPath, []byte("previous"), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+
+	file, err := dirSink{}.create(outputPath)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	file.Write([]byte("partial"))
+	if got := string(readFile(t, outputPath)); got != "previous" {
+		t.Errorf("Expected the output to be untouched while writing, got %q", got)
+	}
+	file.discard()
+	if got := string(readFile(t, outputPath)); got != "previous" {
+		t.Errorf("Expected a discarded file to keep the previous output, got %q", got)
+	}
+
+	file, err = dirSink{}.create(outputPath)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	file.Write([]byte("complete"))
+	if err := file.commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if got := string(readFile(t, outputPath)); got != "complete" {
+		t.Errorf("Expected the committed output, got %q", got)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left, got %d entries", len(entries))
+	}
+}
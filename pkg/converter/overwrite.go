@@ -0,0 +1,121 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// OverwritePolicy decides what batch conversions do with outputs that already exist
+type OverwritePolicy int
+
+const (
+	// OverwriteExisting replaces existing outputs
+	OverwriteExisting OverwritePolicy = iota
+	// SkipExisting leaves existing outputs alone and doesn't convert their inputs
+	SkipExisting
+	// FailOnExisting fails the batch before anything is written if any output exists
+	FailOnExisting
+	// RenameNew writes to a free name with a numeric suffix, such as "idle00-1.png", instead
+	RenameNew
+)
+
+// overwritePolicyNames maps the names accepted by ParseOverwritePolicy to policies
+var overwritePolicyNames = map[string]OverwritePolicy{
+	"overwrite": OverwriteExisting,
+	"skip":      SkipExisting,
+	"fail":      FailOnExisting,
+	"rename":    RenameNew,
+}
+
+// ParseOverwritePolicy parses "overwrite", "skip", "fail" or "rename"
+func ParseOverwritePolicy(s string) (OverwritePolicy, error) {
+	policy, ok := overwritePolicyNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown overwrite policy '%s', expected overwrite, skip, fail or rename", s)
+	}
+	return policy, nil
+}
+
+// String returns the name of the policy as accepted by ParseOverwritePolicy
+func (p OverwritePolicy) String() string {
+	for name, policy := range overwritePolicyNames {
+		if policy == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("OverwritePolicy(%d)", int(p))
+}
+
+// SetOverwritePolicy sets what batch conversions do with existing outputs, OverwriteExisting by default.
+// Watch mode always overwrites, since it exists to keep outputs up to date.
+func (f *FilesConverter) SetOverwritePolicy(policy OverwritePolicy) {
+	f.overwritePolicy = policy
+}
+
+// applyOverwritePolicy skips, renames or rejects tasks whose output already exists
+func (f *FilesConverter) applyOverwritePolicy(tasks []ConversionTask, toExt string) ([]ConversionTask, error) {
+	if f.overwritePolicy == OverwriteExisting {
+		return tasks, nil
+	}
+
+	remaining := make([]ConversionTask, 0, len(tasks))
+	var existing []string
+	claimed := make(map[string]bool, len(tasks)) // Renamed outputs already taken by earlier tasks
+	for _, task := range tasks {
+		claimed[strings.ToLower(task.outputPath)] = true
+	}
+
+	for _, task := range tasks {
+		if !fileExists(task.outputPath) {
+			remaining = append(remaining, task)
+			continue
+		}
+
+		switch f.overwritePolicy {
+		case SkipExisting:
+			f.log.Debugf("Skipping %s, %s already exists", task.relPath, task.outputPath)
+		case FailOnExisting:
+			existing = append(existing, task.outputPath)
+		case RenameNew:
+			original := task.outputPath
+			task.outputPath = freeOutputPath(original, toExt, claimed)
+			claimed[strings.ToLower(task.outputPath)] = true
+			f.log.Infof("%s already exists, writing %s instead", original, task.outputPath)
+			remaining = append(remaining, task)
+		}
+	}
+
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("%d outputs already exist, including '%s'", len(existing), existing[0])
+	}
+	if skipped := len(tasks) - len(remaining); skipped > 0 {
+		f.log.Infof("Skipping %d files whose output already exists", skipped)
+	}
+	return renumberTasks(remaining), nil
+}
+
+// fileExists reports whether anything exists at path
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// freeOutputPath inserts the lowest numeric suffix before ext that gives a path which neither exists
+// nor is claimed by another output of the batch
+func freeOutputPath(outputPath, ext string, claimed map[string]bool) string {
+	base := outputPath
+	if strings.HasSuffix(strings.ToLower(outputPath), strings.ToLower(ext)) {
+		base = outputPath[:len(outputPath)-len(ext)]
+		ext = outputPath[len(outputPath)-len(ext):]
+	} else {
+		ext = ""
+	}
+
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, n, ext)
+		if !claimed[strings.ToLower(candidate)] && !fileExists(candidate) {
+			return candidate
+		}
+	}
+}
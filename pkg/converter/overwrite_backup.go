@@ -0,0 +1,180 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OverwriteBackup is where batch conversions keep a copy of every existing output they replace
+type OverwriteBackup struct {
+	Dir    string // Copies go below Dir, at their path relative to the output directory
+	Suffix string // Copies go next to their output with Suffix appended, such as ".bak", when Dir is empty
+}
+
+// ParseOverwriteBackup parses a suffix starting with a dot and holding no path separator, such as
+// ".bak", or otherwise a backup directory
+func ParseOverwriteBackup(s string) (OverwriteBackup, error) {
+	switch {
+	case s == "":
+		return OverwriteBackup{}, errors.New("empty backup directory or suffix")
+	case strings.HasPrefix(s, ".") && s != "." && s != ".." && !strings.ContainsAny(s, `/\`):
+		return OverwriteBackup{Suffix: s}, nil
+	default:
+		return OverwriteBackup{Dir: s}, nil
+	}
+}
+
+// Enabled reports whether backups are kept at all
+func (b OverwriteBackup) Enabled() bool {
+	return b.Dir != "" || b.Suffix != ""
+}
+
+// String returns the backup directory or suffix as accepted by ParseOverwriteBackup
+func (b OverwriteBackup) String() string {
+	if b.Dir != "" {
+		return b.Dir
+	}
+	return b.Suffix
+}
+
+// path returns where the backup of the output at outputPath below toDir goes
+func (b OverwriteBackup) path(toDir, outputPath string) (string, error) {
+	if b.Dir == "" {
+		return outputPath + b.Suffix, nil
+	}
+	relPath, err := filepath.Rel(toDir, outputPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(b.Dir, relPath), nil
+}
+
+// SetOverwriteBackup makes batch conversions copy every output that already exists to backup right
+// before replacing it, keeping its modification time and permissions, so converting in place can be
+// undone with RestoreBackups. A backup left by an earlier run is replaced. The backup directory
+// should lie outside the input tree, or later runs convert the backups too. A zero OverwriteBackup
+// disables backups.
+func (f *FilesConverter) SetOverwriteBackup(backup OverwriteBackup) {
+	f.overwriteBackup = backup
+}
+
+// backupOutput copies the output at outputPath below toDir to its backup, if it exists
+func (f *FilesConverter) backupOutput(toDir, outputPath string) error {
+	info, err := os.Stat(outputPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	backupPath, err := f.overwriteBackup.path(toDir, outputPath)
+	if err != nil {
+		return err
+	}
+	if err := copyFileAtomic(outputPath, backupPath, info); err != nil {
+		return fmt.Errorf("failed to back up '%s': %w", outputPath, err)
+	}
+	f.log.Debugf("Backed up %s to %s", outputPath, backupPath)
+	return nil
+}
+
+// RestoreBackups moves the backups of outputs replaced below toDir back over them and returns their
+// paths relative to toDir, sorted. Files that were newly created rather than replaced have no backup
+// and are left alone.
+func (f *FilesConverter) RestoreBackups(backup OverwriteBackup, toDir string) ([]string, error) {
+	if !backup.Enabled() {
+		return nil, errors.New("no backup directory or suffix")
+	}
+	root := backup.Dir
+	if root == "" {
+		root = toDir
+	}
+
+	var restored []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		if backup.Dir == "" && !strings.HasSuffix(path, backup.Suffix) {
+			return nil
+		}
+		if strings.Contains(d.Name(), TempFileInfix) {
+			return nil // Left behind by an interrupted backup
+		}
+		relPath, err := filepath.Rel(root, strings.TrimSuffix(path, backup.Suffix))
+		if err != nil {
+			return err
+		}
+		if err := moveFile(path, filepath.Join(toDir, relPath)); err != nil {
+			return fmt.Errorf("failed to restore '%s': %w", relPath, err)
+		}
+		f.log.Infof("Restored %s", relPath)
+		restored = append(restored, filepath.ToSlash(relPath))
+		return nil
+	})
+	sort.Strings(restored)
+	if err != nil {
+		return restored, fmt.Errorf("error restoring backups: %w", err)
+	}
+	f.log.Infof("Restored %d files", len(restored))
+	return restored, nil
+}
+
+// copyFileAtomic copies the file at src, described by info, to dst through a temporary file, so dst
+// is never left half written, and gives the copy the modification time and permissions of src
+func copyFileAtomic(src, dst string, info fs.FileInfo) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	temp, err := createTempFile(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(temp, source)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Chtimes(temp.Name(), info.ModTime(), info.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
+
+// moveFile renames src to dst, copying it across filesystems when renaming isn't possible
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := copyFileAtomic(src, dst, info); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
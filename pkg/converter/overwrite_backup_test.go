@@ -0,0 +1,91 @@
+package converter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestParseOverwriteBackup tests telling backup suffixes from directories
+func TestParseOverwriteBackup(t *testing.T) {
+	tests := map[string]OverwriteBackup{
+		".bak":      {Suffix: ".bak"},
+		"backups/":  {Dir: "backups/"},
+		"./backups": {Dir: "./backups"},
+		"..":        {Dir: ".."},
+		".old/sub":  {Dir: ".old/sub"},
+	}
+	for s, want := range tests {
+		if got, err := ParseOverwriteBackup(s); err != nil || got != want || got.String() != s {
+			t.Errorf("%q: expected %+v, got %+v, %v", s, want, got, err)
+		}
+	}
+	if _, err := ParseOverwriteBackup(""); err == nil {
+		t.Error("Expected an error for an empty backup")
+	}
+}
+
+// TestOverwriteBackup tests that converting in place backs up replaced outputs and that restoring puts
+// them back, for both backup directories and suffixes
+func TestOverwriteBackup(t *testing.T) {
+	edited := []byte("edited")
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, backup := range []OverwriteBackup{{Dir: "backups"}, {Suffix: ".bak"}} {
+		t.Run(backup.String(), func(t *testing.T) {
+			dir := t.TempDir()
+			if backup.Dir != "" {
+				backup.Dir = filepath.Join(t.TempDir(), backup.Dir)
+			}
+			if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(dir, "sub", "red.data"))
+			copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(dir, "blue.data"))
+			redPng := filepath.Join(dir, "sub", "red.png")
+			if err := os.WriteFile(redPng, edited, 0600); err != nil {
+				t.Fatalf("Failed to write red.png: %v", err)
+			}
+			if err := os.Chtimes(redPng, modTime, modTime); err != nil {
+				t.Fatalf("Failed to set modification time: %v", err)
+			}
+
+			filesConverter := NewFilesConverter(NewGraphicsConverter(), WithOverwriteBackup(backup))
+			if err := filesConverter.DataToPng(dir, dir); err != nil {
+				t.Fatalf("DataToPng failed: %v", err)
+			}
+			assertFileChanged(t, redPng, edited, true)
+
+			backupPath, _ := backup.path(dir, redPng)
+			if data, err := os.ReadFile(backupPath); err != nil || !bytes.Equal(data, edited) {
+				t.Fatalf("Expected the edited red.png in its backup, got %q, %v", data, err)
+			}
+			if info, err := os.Stat(backupPath); err != nil || !info.ModTime().Equal(modTime) || info.Mode().Perm() != 0600 {
+				t.Errorf("Expected the backup to keep its modification time and permissions, got %v", info)
+			}
+			if blueBackup, _ := backup.path(dir, filepath.Join(dir, "blue.png")); fileExists(blueBackup) {
+				t.Error("Expected no backup for a new output")
+			}
+
+			restored, err := filesConverter.RestoreBackups(backup, dir)
+			if err != nil {
+				t.Fatalf("RestoreBackups failed: %v", err)
+			}
+			if want := []string{"sub/red.png"}; !slices.Equal(restored, want) {
+				t.Errorf("Expected %v restored, got %v", want, restored)
+			}
+			if data, err := os.ReadFile(redPng); err != nil || !bytes.Equal(data, edited) {
+				t.Errorf("Expected the edited red.png back, got %q, %v", data, err)
+			}
+			if fileExists(backupPath) {
+				t.Error("Expected the backup to be moved back")
+			}
+			if !fileExists(filepath.Join(dir, "blue.png")) {
+				t.Error("Expected new outputs to be left alone")
+			}
+		})
+	}
+}
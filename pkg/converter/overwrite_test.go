@@ -0,0 +1,95 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestOverwritePolicies tests how each policy treats an existing output
+func TestOverwritePolicies(t *testing.T) {
+	existing := []byte("existing")
+
+	tests := []struct {
+		policy  OverwritePolicy
+		wantErr bool
+		check   func(t *testing.T, toDir string)
+	}{
+		{OverwriteExisting, false, func(t *testing.T, toDir string) {
+			assertFileChanged(t, filepath.Join(toDir, "red.png"), existing, true)
+		}},
+		{SkipExisting, false, func(t *testing.T, toDir string) {
+			assertFileChanged(t, filepath.Join(toDir, "red.png"), existing, false)
+			assertFileChanged(t, filepath.Join(toDir, "blue.png"), nil, true)
+		}},
+		{FailOnExisting, true, func(t *testing.T, toDir string) {
+			assertFileChanged(t, filepath.Join(toDir, "red.png"), existing, false)
+			if _, err := os.Stat(filepath.Join(toDir, "blue.png")); !os.IsNotExist(err) {
+				t.Error("Expected nothing to be written when failing on existing outputs")
+			}
+		}},
+		{RenameNew, false, func(t *testing.T, toDir string) {
+			assertFileChanged(t, filepath.Join(toDir, "red.png"), existing, false)
+			assertFileChanged(t, filepath.Join(toDir, "red-1.png"), existing, false)
+			assertFileChanged(t, filepath.Join(toDir, "red-2.png"), nil, true)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			fromDir := t.TempDir()
+			toDir := t.TempDir()
+			copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+			copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(fromDir, "blue.data"))
+			// With renaming, red.png and red-1.png are taken so red.data goes to red-2.png
+			for _, name := range []string{"red.png", "red-1.png"} {
+				if err := os.WriteFile(filepath.Join(toDir, name), existing, 0644); err != nil {
+					t.Fatalf("Failed to write %s: %v", name, err)
+				}
+			}
+
+			filesConverter := NewFilesConverter(NewGraphicsConverter())
+			filesConverter.SetOverwritePolicy(tt.policy)
+			err := filesConverter.DataToPng(fromDir, toDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			tt.check(t, toDir)
+		})
+	}
+}
+
+// TestFreeOutputPathSkipsClaimedNames tests that renamed outputs avoid names used by other outputs of the batch
+func TestFreeOutputPathSkipsClaimedNames(t *testing.T) {
+	dir := t.TempDir()
+	claimed := map[string]bool{strings.ToLower(filepath.Join(dir, "a-1.cdat.zst")): true}
+	if got := freeOutputPath(filepath.Join(dir, "a.cdat.zst"), ".cdat.zst", claimed); got != filepath.Join(dir, "a-2.cdat.zst") {
+		t.Errorf("Expected a-2.cdat.zst, got %s", got)
+	}
+}
+
+// TestParseOverwritePolicy tests parsing of policy names
+func TestParseOverwritePolicy(t *testing.T) {
+	for _, name := range []string{"overwrite", "skip", "fail", "rename"} {
+		policy, err := ParseOverwritePolicy(name)
+		if err != nil || policy.String() != name {
+			t.Errorf("ParseOverwritePolicy(%q) = %v, %v", name, policy, err)
+		}
+	}
+	if _, err := ParseOverwritePolicy("merge"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
+
+// assertFileChanged checks whether a file's content differs from old, or just that it exists when old is nil
+func assertFileChanged(t *testing.T, path string, old []byte, wantChanged bool) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if old != nil && (string(content) != string(old)) != wantChanged {
+		t.Errorf("%s: expected changed=%v", filepath.Base(path), wantChanged)
+	}
+}
@@ -0,0 +1,244 @@
+package converter
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+)
+
+// PngPalette selects when PNGs are written as 8-bit paletted images instead of truecolor
+type PngPalette int
+
+const (
+	// PaletteOff always writes truecolor PNGs
+	PaletteOff PngPalette = iota
+	// PaletteExact writes images with at most 256 colors as paletted PNGs, which is lossless
+	PaletteExact
+	// PaletteQuantize writes every image as a paletted PNG, reducing images with more than 256 colors
+	// with median cut. Fully transparent pixels always keep a palette entry of their own.
+	PaletteQuantize
+)
+
+// maxPaletteColors is the largest palette of an 8-bit paletted PNG
+const maxPaletteColors = 256
+
+// pngPaletteNames maps the names accepted by ParsePngPalette to palette settings
+var pngPaletteNames = map[string]PngPalette{
+	"off":      PaletteOff,
+	"exact":    PaletteExact,
+	"quantize": PaletteQuantize,
+}
+
+// ParsePngPalette parses a palette setting: off, exact or quantize
+func ParsePngPalette(name string) (PngPalette, error) {
+	palette, ok := pngPaletteNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown PNG palette setting '%s', expected off, exact or quantize", name)
+	}
+	return palette, nil
+}
+
+// String returns the name of a palette setting
+func (p PngPalette) String() string {
+	for name, palette := range pngPaletteNames {
+		if palette == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("PngPalette(%d)", int(p))
+}
+
+// SetPngPalette sets when 8-bit images are written as paletted PNGs, PaletteOff by default. Sprites
+// using few colors get much smaller. 16-bit and grayscale images are always written as they are.
+func (g *GraphicsConverter) SetPngPalette(palette PngPalette) {
+	g.pngPalette = palette
+}
+
+// palettedForPng returns img as a paletted image if the palette setting applies to it, or img itself
+func (g *GraphicsConverter) palettedForPng(img image.Image) image.Image {
+	if g.pngPalette == PaletteOff {
+		return img
+	}
+	switch img.(type) {
+	case *image.NRGBA, *image.RGBA:
+	default:
+		return img
+	}
+
+	nrgba := toNRGBA(img)
+	histogram := colorHistogram(nrgba, g.pngPalette == PaletteExact)
+	if histogram == nil {
+		return img // Too many colors to be written exactly
+	}
+	return quantize(nrgba, histogram)
+}
+
+// paletteKey packs a straight-alpha color into a map key, with every fully transparent color as 0
+func paletteKey(pix []byte) uint32 {
+	if pix[3] == 0 {
+		return 0
+	}
+	return uint32(pix[0])<<24 | uint32(pix[1])<<16 | uint32(pix[2])<<8 | uint32(pix[3])
+}
+
+// colorHistogram counts the pixels of every color of img. With exactOnly it gives up, returning nil,
+// once there are more colors than a palette holds.
+func colorHistogram(img *image.NRGBA, exactOnly bool) map[uint32]int {
+	histogram := make(map[uint32]int)
+	bounds := img.Bounds()
+	for y := 0; y < bounds.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+bounds.Dx()*4]
+		for p := 0; p < len(row); p += 4 {
+			histogram[paletteKey(row[p:])]++
+		}
+		if exactOnly && len(histogram) > maxPaletteColors {
+			return nil
+		}
+	}
+	return histogram
+}
+
+// colorCount is a color of an image and the number of pixels using it
+type colorCount struct {
+	key   uint32
+	count int
+}
+
+// channel returns channel i (0 red to 3 alpha) of the color
+func (c colorCount) channel(i int) uint8 {
+	return uint8(c.key >> (24 - 8*i))
+}
+
+// quantize maps img onto a palette of at most 256 colors built from its histogram. Images with few
+// enough colors keep them exactly; others are reduced by median cut. Fully transparent pixels are left
+// out of the cut so they are never merged with visible colors.
+func quantize(img *image.NRGBA, histogram map[uint32]int) *image.Paletted {
+	var colors []colorCount
+	transparent := histogram[0] > 0
+	for key, count := range histogram {
+		if key != 0 {
+			colors = append(colors, colorCount{key, count})
+		}
+	}
+	// Sorted, so the palette doesn't depend on map order
+	slices.SortFunc(colors, func(a, b colorCount) int { return cmp.Compare(a.key, b.key) })
+
+	budget := maxPaletteColors
+	var palette color.Palette
+	indices := make(map[uint32]uint8, len(histogram))
+	if transparent {
+		palette = append(palette, color.NRGBA{})
+		indices[0] = 0
+		budget--
+	}
+	for _, box := range medianCut(colors, budget) {
+		index := uint8(len(palette))
+		palette = append(palette, box.average())
+		for _, c := range box {
+			indices[c.key] = index
+		}
+	}
+
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(image.Rect(0, 0, bounds.Dx(), bounds.Dy()), palette)
+	for y := 0; y < bounds.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+bounds.Dx()*4]
+		out := paletted.Pix[y*paletted.Stride:]
+		for p := 0; p < len(row); p += 4 {
+			out[p/4] = indices[paletteKey(row[p:])]
+		}
+	}
+	return paletted
+}
+
+// colorBox is a set of colors that become a single palette entry
+type colorBox []colorCount
+
+// widestChannel returns the channel with the largest range in the box and that range
+func (b colorBox) widestChannel() (int, int) {
+	widest, widestRange := 0, -1
+	for i := 0; i < 4; i++ {
+		lo, hi := uint8(255), uint8(0)
+		for _, c := range b {
+			lo, hi = min(lo, c.channel(i)), max(hi, c.channel(i))
+		}
+		if r := int(hi) - int(lo); r > widestRange {
+			widest, widestRange = i, r
+		}
+	}
+	return widest, widestRange
+}
+
+// average returns the pixel-weighted average color of the box
+func (b colorBox) average() color.NRGBA {
+	var sums [4]int
+	total := 0
+	for _, c := range b {
+		for i := range sums {
+			sums[i] += int(c.channel(i)) * c.count
+		}
+		total += c.count
+	}
+	var avg [4]uint8
+	for i, sum := range sums {
+		avg[i] = uint8((sum + total/2) / total)
+	}
+	return color.NRGBA{avg[0], avg[1], avg[2], avg[3]}
+}
+
+// medianCut splits colors into at most n boxes, each time halving the box with the widest channel
+// range at the pixel-weighted median of that channel. With n or fewer colors, each gets its own box.
+func medianCut(colors []colorCount, n int) []colorBox {
+	if len(colors) == 0 {
+		return nil
+	}
+	if len(colors) <= n {
+		boxes := make([]colorBox, len(colors))
+		for i := range colors {
+			boxes[i] = colors[i : i+1]
+		}
+		return boxes
+	}
+
+	// The widest channel of every box, computed once per box
+	type cutCandidate struct {
+		channel, width int
+	}
+	boxes := []colorBox{colors}
+	candidates := []cutCandidate{{}}
+	candidates[0].channel, candidates[0].width = boxes[0].widestChannel()
+	for len(boxes) < n {
+		split := -1
+		for i, candidate := range candidates {
+			if len(boxes[i]) >= 2 && (split < 0 || candidate.width > candidates[split].width) {
+				split = i
+			}
+		}
+		if split < 0 {
+			break // Every box holds a single color
+		}
+
+		box, channel := boxes[split], candidates[split].channel
+		slices.SortStableFunc(box, func(a, b colorCount) int { return cmp.Compare(a.channel(channel), b.channel(channel)) })
+		total := 0
+		for _, c := range box {
+			total += c.count
+		}
+		// Cut after the pixel-weighted median, leaving at least one color on each side
+		cut, seen := 1, box[0].count
+		for cut < len(box)-1 && seen < total/2 {
+			seen += box[cut].count
+			cut++
+		}
+		boxes[split] = box[:cut]
+		boxes = append(boxes, box[cut:])
+		candidates = append(candidates, cutCandidate{})
+		for _, i := range []int{split, len(boxes) - 1} {
+			candidates[i].channel, candidates[i].width = boxes[i].widestChannel()
+		}
+	}
+	return boxes
+}
@@ -0,0 +1,85 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"testing"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// encodeTestPng writes img as a PNG with the palette setting and decodes it again
+func encodeTestPng(t *testing.T, palette PngPalette, img image.Image) image.Image {
+	var output bytes.Buffer
+	if err := NewGraphicsConverter(WithPngPalette(palette)).encodePng(&output, img); err != nil {
+		t.Fatalf("encodePng failed: %v", err)
+	}
+	decoded, err := png.Decode(&output)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+	return decoded
+}
+
+// TestPaletteExact tests that images with few colors are written as paletted PNGs without loss
+func TestPaletteExact(t *testing.T) {
+	gc := NewGraphicsConverter()
+	for _, name := range []string{"transparent", "multi-color", "red"} {
+		data := readTestResource(t, filepath.Join("data", name+".data"))
+		img, err := gc.DecodeData(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("DecodeData failed: %v", err)
+		}
+		decoded := encodeTestPng(t, PaletteExact, img)
+		if _, ok := decoded.(*image.Paletted); !ok {
+			t.Errorf("%s: expected a paletted PNG, got %T", name, decoded)
+		}
+		if err := imagecompare.Check(img, decoded, 0); err != nil {
+			t.Errorf("%s: expected the paletted PNG to hold the same pixels: %v", name, err)
+		}
+	}
+
+	// More colors than a palette holds are written as they are
+	if decoded, ok := encodeTestPng(t, PaletteExact, gradient(300)).(*image.Paletted); ok {
+		t.Errorf("Expected a truecolor PNG for 300 colors, got %d palette entries", len(decoded.Palette))
+	}
+}
+
+// TestPaletteQuantize tests reducing an image with many colors, keeping its transparent pixels
+func TestPaletteQuantize(t *testing.T) {
+	img := gradient(1000)
+	for x := 0; x < 1000; x += 10 {
+		img.SetNRGBA(x, 1, color.NRGBA{uint8(x), 255, 0, 0}) // Transparent, with leftover colors
+	}
+
+	decoded, ok := encodeTestPng(t, PaletteQuantize, img).(*image.Paletted)
+	if !ok {
+		t.Fatal("Expected a paletted PNG")
+	}
+	if len(decoded.Palette) > maxPaletteColors {
+		t.Errorf("Expected at most %d colors, got %d", maxPaletteColors, len(decoded.Palette))
+	}
+	for x := 0; x < 1000; x++ {
+		want, got := img.NRGBAAt(x, 1), imagecompare.Pixel(decoded, x, 1)
+		if want.A == 0 && got != (color.NRGBA{}) {
+			t.Fatalf("Pixel %d: expected to stay transparent, got %v", x, got)
+		}
+		if want.A == 255 && (got.A != 255 || imagecompare.ChannelDelta(want, got) > 16) {
+			t.Fatalf("Pixel %d: expected an opaque color near %v, got %v", x, want, got)
+		}
+	}
+}
+
+// gradient returns an opaque image of width × 2 pixels with width distinct colors
+func gradient(width int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, 2))
+	for x := 0; x < width; x++ {
+		c := color.NRGBA{uint8(x * 255 / width), uint8(x % 7 * 30), uint8(x / 256 * 60), 255}
+		img.SetNRGBA(x, 0, c)
+		img.SetNRGBA(x, 1, c)
+	}
+	return img
+}
@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/debug"
+)
+
+// PanicError is the error of a file whose conversion panicked, for example on a malformed image
+// surprising a decoder. The batch carries on with the other files.
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack string // Stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// recoverPanic turns a panic into a *PanicError stored in err; it must be deferred directly
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: string(debug.Stack())}
+	}
+}
+
+// safeConvert calls convertFunc, returning a panic as a *PanicError so the caller cleans up as after any failure
+func safeConvert(convertFunc func(io.Reader, io.Writer) error, input io.Reader, output io.Writer) (err error) {
+	defer recoverPanic(&err)
+	return convertFunc(input, output)
+}
+
+// runTask converts a single task, returning panics outside the conversion itself, such as while
+// writing provenance or indexing, as a *PanicError
+func (f *FilesConverter) runTask(ctx context.Context, batch *conversionBatch, task ConversionTask) (metrics taskMetrics, err error) {
+	defer recoverPanic(&err)
+	return f.convertTask(ctx, batch, task)
+}
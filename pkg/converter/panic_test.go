@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConversionPanicRecovered tests that a panicking conversion fails only its own file,
+// with the stack trace in the error report
+func TestConversionPanicRecovered(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	for _, name := range []string{"a.txt", "boom.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(fromDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	convertFunc := func(input io.Reader, output io.Writer) error {
+		content, err := io.ReadAll(input)
+		if err != nil {
+			return err
+		}
+		output.Write(content)
+		if string(content) == "boom.txt" {
+			var img []byte
+			_ = img[len(content)] // Index out of range, like a decoder tripping over a malformed file
+		}
+		return nil
+	}
+
+	var report ErrorReport
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetContinueOnError(true)
+	filesConverter.Progress(report.Record)
+
+	err := filesConverter.Convert(fromDir, toDir, ".txt", ".out", convertFunc)
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected a PanicError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "boom.txt") || !strings.Contains(panicErr.Error(), "index out of range") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"a.out", "c.out"} {
+		if _, err := os.Stat(filepath.Join(toDir, name)); err != nil {
+			t.Errorf("Expected %s to be converted: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(toDir, "boom.out")); !os.IsNotExist(err) {
+		t.Error("Expected the output of the panicking file to be removed")
+	}
+
+	if len(report.Failed) != 1 || !strings.Contains(report.Failed[0].Stack, "panic_test.go") {
+		t.Errorf("Expected the failure with its stack trace in the report, got %+v", report.Failed)
+	}
+}
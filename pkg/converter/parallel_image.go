@@ -0,0 +1,279 @@
+package converter
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// parallelMinPixels is the smallest image split across goroutines, below it the overhead outweighs the gain
+const parallelMinPixels = 512 * 512
+
+// SetImageWorkers sets how many goroutines decode and encode a single DATA image of at least 512×512 pixels.
+// The default of 1 suits batches, where the file workers already keep every core busy; raise it when
+// converting a handful of huge atlas pages. The output is identical either way.
+func (g *GraphicsConverter) SetImageWorkers(workers int) {
+	if workers > 0 {
+		g.imageWorkers = workers
+	}
+}
+
+// splitImage reports whether an image of total pixels is processed in parallel bands
+func (g *GraphicsConverter) splitImage(total int) bool {
+	return g.imageWorkers > 1 && total >= parallelMinPixels
+}
+
+// rowBands splits height rows into bands of whole rows, a few per worker to even out their load
+func (g *GraphicsConverter) rowBands(height int) [][2]int {
+	count := min(g.imageWorkers*4, height)
+	bands := make([][2]int, count)
+	for b := range bands {
+		bands[b] = [2]int{b * height / count, (b + 1) * height / count}
+	}
+	return bands
+}
+
+// runBands calls fn for every band of rows, on up to imageWorkers goroutines at once
+func (g *GraphicsConverter) runBands(height int, fn func(band int, rows [2]int)) {
+	bands := g.rowBands(height)
+	next := make(chan int, len(bands))
+	for b := range bands {
+		next <- b
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(g.imageWorkers, len(bands)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range next {
+				fn(b, bands[b])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// forEachBand applies fn to the RGBA pixels of an image width pixels wide, in parallel bands if it is large enough
+func (g *GraphicsConverter) forEachBand(pix []byte, width int, fn func(pix []byte)) {
+	if !g.splitImage(len(pix) / 4) {
+		fn(pix)
+		return
+	}
+	g.runBands(len(pix)/4/width, func(_ int, rows [2]int) {
+		fn(pix[rows[0]*width*4 : rows[1]*width*4])
+	})
+}
+
+// bandsAll reports whether fn holds for every band of an image's RGBA pixels
+func (g *GraphicsConverter) bandsAll(pix []byte, width int, fn func(pix []byte) bool) bool {
+	var mu sync.Mutex
+	all := true
+	g.forEachBand(pix, width, func(band []byte) {
+		if !fn(band) {
+			mu.Lock()
+			all = false
+			mu.Unlock()
+		}
+	})
+	return all
+}
+
+// parseRun parses the DATA run starting at pos of stream, returning its pixel count, color and end.
+// It returns io.EOF if the stream ends cleanly after the count byte, which ends the image like the
+// sequential decoder does, and io.ErrUnexpectedEOF if it ends within the color channels.
+func parseRun(stream []byte, pos int, hasAlpha bool) (count int, px [4]byte, next int, err error) {
+	count = int(stream[pos])
+	if count == 0 {
+		count = 256 // Treat 0 as 256
+	}
+	pos++
+
+	px[3] = 255 // Default to opaque black
+	if hasAlpha {
+		if pos == len(stream) {
+			return 0, px, pos, io.EOF
+		}
+		px[3] = stream[pos]
+		pos++
+	}
+
+	// Alpha images only store RGB for non-transparent runs
+	if !hasAlpha || px[3] != 0 {
+		switch {
+		case pos == len(stream):
+			return 0, px, pos, io.EOF
+		case pos+3 > len(stream):
+			return 0, px, pos, io.ErrUnexpectedEOF
+		}
+		px[0], px[1], px[2] = stream[pos+2], stream[pos+1], stream[pos]
+		pos += 3
+	}
+	return count, px, pos, nil
+}
+
+// runStart locates the run covering the first pixel of a band
+type runStart struct {
+	offset int // Offset of the run in the stream
+	pixel  int // First pixel of the run
+	found  bool
+}
+
+// decodeRunsParallel reads the runs of a DATA stream into pix like decodeRuns, filling bands of rows
+// in parallel. A sequential pre-scan finds where in the stream each band starts.
+func (g *GraphicsConverter) decodeRunsParallel(r *bufio.Reader, pix []byte, width int, hasAlpha bool) error {
+	total := len(pix) / 4
+
+	// Every run takes at most 5 bytes and covers at least one pixel, so this is enough for any image
+	stream, err := io.ReadAll(io.LimitReader(r, int64(total)*5))
+	if err != nil {
+		return err
+	}
+	return g.decodeStreamParallel(stream, pix, width, hasAlpha)
+}
+
+// decodeStreamParallel decodes the runs in stream like decodeRunsParallel, for streams already in memory
+// such as memory-mapped files. Bytes after the last pixel are trailing data.
+func (g *GraphicsConverter) decodeStreamParallel(stream, pix []byte, width int, hasAlpha bool) error {
+	total := len(pix) / 4
+	bands := g.rowBands(total / width)
+	starts := make([]runStart, len(bands))
+	band := 0
+	pos, i := 0, 0
+	for i < total {
+		if pos == len(stream) {
+			if err := g.truncated(i, total); err != nil {
+				return err
+			}
+			break
+		}
+		count, _, next, err := parseRun(stream, pos, hasAlpha)
+		if err == io.EOF || err == io.ErrUnexpectedEOF && g.strict {
+			if err := g.truncated(i, total); err != nil {
+				return err
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if i+count > total {
+			if err := g.overlong(i, count, total); err != nil {
+				return err
+			}
+		}
+		for band < len(bands) && bands[band][0]*width < i+count {
+			starts[band] = runStart{offset: pos, pixel: i, found: true}
+			band++
+		}
+		pos, i = next, i+count
+	}
+	end := min(i, total) // Pixels after the last complete run keep their defaults
+	if g.strict && pos < len(stream) {
+		return ErrTrailingData
+	}
+
+	g.runBands(total/width, func(b int, rows [2]int) {
+		if !starts[b].found {
+			return
+		}
+		bandStart, bandEnd := rows[0]*width, min(rows[1]*width, end)
+		pos, i := starts[b].offset, starts[b].pixel
+		for i < bandEnd {
+			count, px, next, _ := parseRun(stream, pos, hasAlpha) // Complete up to end, checked by the pre-scan
+			from, to := max(i, bandStart), min(i+count, bandEnd)
+			fillRun(pix[from*4:], px, to-from)
+			pos, i = next, i+count
+		}
+	})
+	return nil
+}
+
+// span is a sequence of equal pixels, before it is split into runs of at most the maximum run length
+type span struct {
+	px    [4]byte
+	count int
+}
+
+// encodedBand holds the runs of a band of rows. Its first and last spans are kept apart, as they
+// may continue in the neighbouring bands.
+type encodedBand struct {
+	first, last span
+	single      bool   // The whole band is one span, held in first
+	interior    []byte // Runs between the first and last span
+}
+
+// appendSpan appends a span as runs of at most maxRun pixels, splitting it the way encodeRuns does
+func appendSpan(runs []byte, s span, maxRun int, hasAlpha bool) []byte {
+	for s.count > 0 {
+		count := min(s.count, maxRun)
+		runs = appendRun(runs, s.px, count, hasAlpha)
+		s.count -= count
+	}
+	return runs
+}
+
+// encodeRunsParallel writes the pixels produced by read as DATA runs like encodeRuns, encoding bands
+// of rows in parallel and joining spans that cross band boundaries, so the output is identical
+func (g *GraphicsConverter) encodeRunsParallel(w io.Writer, read rowReader, width, height int, hasAlpha bool) error {
+	maxRun := g.maxRunLength
+	encoded := make([]encodedBand, len(g.rowBands(height)))
+
+	g.runBands(height, func(b int, rows [2]int) {
+		band := &encoded[b]
+		row := getBuffer(width * 4)
+		defer putBuffer(row)
+		var current span
+		started := false
+		for y := rows[0]; y < rows[1]; y++ {
+			read(y, row)
+			for p := 0; p < len(row); p += 4 {
+				px := [4]byte{row[p], row[p+1], row[p+2], row[p+3]}
+				if current.count > 0 && px == current.px {
+					current.count++
+					continue
+				}
+				if current.count > 0 {
+					if !started {
+						band.first, started = current, true
+					} else {
+						band.interior = appendSpan(band.interior, current, maxRun, hasAlpha)
+					}
+				}
+				current = span{px: px, count: 1}
+			}
+		}
+		if started {
+			band.last = current
+		} else {
+			band.first, band.single = current, true
+		}
+	})
+
+	// Join the bands, carrying the open span from one band into the next
+	var carry span
+	var runs []byte
+	for _, band := range encoded {
+		if carry.count > 0 && carry.px == band.first.px {
+			band.first.count += carry.count
+		} else {
+			runs = appendSpan(runs, carry, maxRun, hasAlpha)
+		}
+		if band.single {
+			carry = band.first
+			continue
+		}
+		runs = appendSpan(runs, band.first, maxRun, hasAlpha)
+		if _, err := w.Write(runs); err != nil {
+			return err
+		}
+		if _, err := w.Write(band.interior); err != nil {
+			return err
+		}
+		runs, carry = runs[:0], band.last
+	}
+	runs = appendSpan(runs, carry, maxRun, hasAlpha)
+	_, err := w.Write(runs)
+	return err
+}
@@ -0,0 +1,96 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// parallelTestImage returns an image large enough to be split, mixing uniform bands that span
+// several row bands, noise and translucent pixels
+func parallelTestImage() *image.NRGBA {
+	random := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, 1000, 700))
+	for y := 0; y < 700; y++ {
+		for x := 0; x < 1000; x++ {
+			i := img.PixOffset(x, y)
+			switch {
+			case y < 300: // Transparent, then opaque red, in spans crossing band boundaries
+				if y >= 150 {
+					img.Pix[i], img.Pix[i+3] = 255, 255
+				}
+			case y < 400:
+				img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(x/7), uint8(y), 40, uint8(random.Intn(256))
+			default:
+				img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(random.Intn(2)*255), 0, 0, 255
+			}
+		}
+	}
+	return img
+}
+
+// TestParallelImageMatchesSequential tests that splitting an image across goroutines writes and reads
+// the same DATA as a single goroutine
+func TestParallelImageMatchesSequential(t *testing.T) {
+	img := parallelTestImage()
+
+	for _, maxRun := range []int{MaxRunLength, 100} {
+		sequential := NewGraphicsConverter()
+		sequential.SetMaxRunLength(maxRun)
+		var expected bytes.Buffer
+		if err := sequential.encodeData(img, &expected); err != nil {
+			t.Fatalf("encodeData failed: %v", err)
+		}
+		expectedImg, err := sequential.decodeData(bytes.NewReader(expected.Bytes()))
+		if err != nil {
+			t.Fatalf("decodeData failed: %v", err)
+		}
+
+		for _, workers := range []int{2, 3, 8} {
+			parallel := NewGraphicsConverter()
+			parallel.SetMaxRunLength(maxRun)
+			parallel.SetImageWorkers(workers)
+
+			var actual bytes.Buffer
+			if err := parallel.encodeData(img, &actual); err != nil {
+				t.Fatalf("encodeData failed: %v", err)
+			}
+			if !bytes.Equal(actual.Bytes(), expected.Bytes()) {
+				t.Errorf("Max run %d, %d workers: parallel encoding differs", maxRun, workers)
+			}
+
+			actualImg, err := parallel.decodeData(bytes.NewReader(expected.Bytes()))
+			if err != nil {
+				t.Fatalf("decodeData failed: %v", err)
+			}
+			if !bytes.Equal(actualImg.Pix, expectedImg.Pix) {
+				t.Errorf("Max run %d, %d workers: parallel decoding differs", maxRun, workers)
+			}
+		}
+	}
+}
+
+// TestParallelDecodeTruncated tests that truncated streams decode like they do sequentially
+func TestParallelDecodeTruncated(t *testing.T) {
+	var data bytes.Buffer
+	if err := NewGraphicsConverter().encodeData(parallelTestImage(), &data); err != nil {
+		t.Fatalf("encodeData failed: %v", err)
+	}
+
+	sequential := NewGraphicsConverter()
+	parallel := NewGraphicsConverter()
+	parallel.SetImageWorkers(4)
+	for _, length := range []int{12, 13, 100, data.Len() / 2, data.Len()/2 + 1, data.Len()/2 + 2, data.Len() - 1} {
+		truncated := data.Bytes()[:length]
+		expected, expectedErr := sequential.decodeData(bytes.NewReader(truncated))
+		actual, actualErr := parallel.decodeData(bytes.NewReader(truncated))
+		if (expectedErr == nil) != (actualErr == nil) {
+			t.Errorf("Length %d: expected error %v, got %v", length, expectedErr, actualErr)
+			continue
+		}
+		if expectedErr == nil && !bytes.Equal(actual.Pix, expected.Pix) {
+			t.Errorf("Length %d: parallel decoding differs", length)
+		}
+	}
+}
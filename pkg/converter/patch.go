@@ -0,0 +1,356 @@
+package converter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+	"github.com/klauspost/compress/zstd"
+)
+
+// PatchExtension is the file extension of texture patches
+const PatchExtension = ".cpatch"
+
+// patchMagic identifies a texture patch
+var patchMagic = [4]byte{'C', 'P', 'A', 'T'}
+
+// patchVersion is the current version of the patch layout
+const patchVersion uint8 = 1
+
+// patchHeader is stored at the start of a patch. Like .cdat.zst, the whole patch is a single zstd stream.
+type patchHeader struct {
+	Magic      [4]byte
+	Version    uint8
+	BaseWidth  int32
+	BaseHeight int32
+	Width      int32
+	Height     int32
+	BaseHash   [32]byte
+	RunCount   uint32
+}
+
+// patchRunHeader precedes the old and new pixels of a run
+type patchRunHeader struct {
+	X, Y   int32
+	Length int32
+}
+
+// TexturePatch stores only the pixels that differ between a base texture and a modified one
+type TexturePatch struct {
+	BaseSize image.Point // Size of the texture the patch applies to
+	Size     image.Point // Size of the patched texture
+	BaseHash string      // Pixel hash of the base texture, as printed by hash-tree -verbose
+	Runs     []PatchRun
+}
+
+// PatchRun is a horizontal run of changed pixels.
+// Old and New hold straight-alpha RGBA bytes; Old is transparent outside the base texture.
+type PatchRun struct {
+	X, Y int
+	Old  []byte
+	New  []byte
+}
+
+// Len returns the number of pixels in the run
+func (r *PatchRun) Len() int {
+	return len(r.New) / 4
+}
+
+// ChangedPixels returns the number of pixels the patch changes
+func (p *TexturePatch) ChangedPixels() int {
+	n := 0
+	for i := range p.Runs {
+		n += p.Runs[i].Len()
+	}
+	return n
+}
+
+// CreatePatch records the pixels of modified that differ from base.
+// Areas outside base are treated as transparent, so a patch can also grow or shrink a texture.
+func CreatePatch(base, modified image.Image) *TexturePatch {
+	bb, mb := base.Bounds(), modified.Bounds()
+	patch := &TexturePatch{
+		BaseSize: bb.Size(),
+		Size:     mb.Size(),
+		BaseHash: hashPixels(base),
+	}
+
+	for y := 0; y < mb.Dy(); y++ {
+		var run *PatchRun
+		for x := 0; x < mb.Dx(); x++ {
+			oldColor := imagecompare.Pixel(base, x, y)
+			newColor := color.NRGBAModel.Convert(modified.At(mb.Min.X+x, mb.Min.Y+y)).(color.NRGBA)
+
+			if imagecompare.ChannelDelta(oldColor, newColor) == 0 {
+				run = nil
+				continue
+			}
+			if run == nil {
+				patch.Runs = append(patch.Runs, PatchRun{X: x, Y: y})
+				run = &patch.Runs[len(patch.Runs)-1]
+			}
+			run.Old = append(run.Old, oldColor.R, oldColor.G, oldColor.B, oldColor.A)
+			run.New = append(run.New, newColor.R, newColor.G, newColor.B, newColor.A)
+		}
+	}
+
+	return patch
+}
+
+// Apply returns a copy of base with the patch applied
+func (p *TexturePatch) Apply(base image.Image) (*image.NRGBA, error) {
+	if base.Bounds().Size() != p.BaseSize {
+		return nil, fmt.Errorf("patch expects a %dx%d base, got %dx%d",
+			p.BaseSize.X, p.BaseSize.Y, base.Bounds().Dx(), base.Bounds().Dy())
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, p.Size.X, p.Size.Y))
+	for y := 0; y < p.Size.Y; y++ {
+		for x := 0; x < p.Size.X; x++ {
+			img.SetNRGBA(x, y, imagecompare.Pixel(base, x, y))
+		}
+	}
+
+	for _, run := range p.Runs {
+		if run.Y < 0 || run.Y >= p.Size.Y || run.X < 0 || run.X+run.Len() > p.Size.X {
+			return nil, fmt.Errorf("patch run at (%d,%d) lies outside the %dx%d texture", run.X, run.Y, p.Size.X, p.Size.Y)
+		}
+		copy(img.Pix[img.PixOffset(run.X, run.Y):], run.New)
+	}
+
+	return img, nil
+}
+
+// PatchConflict is a pixel the patch would overwrite although it no longer has the value the patch was made from
+type PatchConflict struct {
+	X, Y     int
+	Expected color.NRGBA // Value in the base the patch was created from
+	Actual   color.NRGBA // Value in the texture being patched
+	Patched  color.NRGBA // Value the patch would write
+}
+
+// PatchConflictError is returned when a patch can't be applied without discarding other changes
+type PatchConflictError struct {
+	Conflicts []PatchConflict
+}
+
+func (e *PatchConflictError) Error() string {
+	c := e.Conflicts[0]
+	return fmt.Sprintf("%d conflicting pixels, first at (%d,%d): expected %v, found %v",
+		len(e.Conflicts), c.X, c.Y, c.Expected, c.Actual)
+}
+
+// Conflicts returns the patched pixels of base that match neither the patch's old nor its new value.
+// Pixels that already have the new value are not conflicts, so re-applying a patch is harmless.
+func (p *TexturePatch) Conflicts(base image.Image) []PatchConflict {
+	var conflicts []PatchConflict
+	for _, run := range p.Runs {
+		for i := 0; i < run.Len(); i++ {
+			x := run.X + i
+			actual := imagecompare.Pixel(base, x, run.Y)
+			expected := color.NRGBA{R: run.Old[i*4], G: run.Old[i*4+1], B: run.Old[i*4+2], A: run.Old[i*4+3]}
+			patched := color.NRGBA{R: run.New[i*4], G: run.New[i*4+1], B: run.New[i*4+2], A: run.New[i*4+3]}
+
+			if imagecompare.ChannelDelta(actual, expected) != 0 && imagecompare.ChannelDelta(actual, patched) != 0 {
+				conflicts = append(conflicts, PatchConflict{X: x, Y: run.Y, Expected: expected, Actual: actual, Patched: patched})
+			}
+		}
+	}
+	return conflicts
+}
+
+// WritePatch writes a patch in the zstd-compressed patch format
+func WritePatch(output io.Writer, patch *TexturePatch) error {
+	baseHash, err := hex.DecodeString(patch.BaseHash)
+	if err != nil || len(baseHash) != 32 {
+		return errors.New("invalid base hash")
+	}
+
+	header := patchHeader{
+		Magic:      patchMagic,
+		Version:    patchVersion,
+		BaseWidth:  int32(patch.BaseSize.X),
+		BaseHeight: int32(patch.BaseSize.Y),
+		Width:      int32(patch.Size.X),
+		Height:     int32(patch.Size.Y),
+		RunCount:   uint32(len(patch.Runs)),
+	}
+	copy(header.BaseHash[:], baseHash)
+
+	encoder, err := zstd.NewWriter(output, zstd.WithEncoderLevel(zstd.SpeedBestCompression), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(encoder)
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		encoder.Close()
+		return err
+	}
+	for _, run := range patch.Runs {
+		if len(run.Old) != len(run.New) || len(run.New)%4 != 0 {
+			encoder.Close()
+			return fmt.Errorf("malformed patch run at (%d,%d)", run.X, run.Y)
+		}
+		runHeader := patchRunHeader{X: int32(run.X), Y: int32(run.Y), Length: int32(run.Len())}
+		if err := binary.Write(w, binary.LittleEndian, runHeader); err != nil {
+			encoder.Close()
+			return err
+		}
+		if _, err := w.Write(run.Old); err != nil {
+			encoder.Close()
+			return err
+		}
+		if _, err := w.Write(run.New); err != nil {
+			encoder.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		encoder.Close()
+		return err
+	}
+	return encoder.Close()
+}
+
+// ReadPatch reads a patch in the zstd-compressed patch format
+func ReadPatch(input io.Reader) (*TexturePatch, error) {
+	decoder, err := zstd.NewReader(input, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	r := bufio.NewReader(decoder)
+
+	var header patchHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read patch header: %w", err)
+	}
+	if header.Magic != patchMagic {
+		return nil, errors.New("not a texture patch")
+	}
+	if header.Version != patchVersion {
+		return nil, fmt.Errorf("unsupported patch version %d", header.Version)
+	}
+	for _, dim := range []int32{header.BaseWidth, header.BaseHeight, header.Width, header.Height} {
+		if dim <= 0 || dim > 8192 {
+			return nil, errors.New("invalid image dimensions")
+		}
+	}
+
+	patch := &TexturePatch{
+		BaseSize: image.Pt(int(header.BaseWidth), int(header.BaseHeight)),
+		Size:     image.Pt(int(header.Width), int(header.Height)),
+		BaseHash: hex.EncodeToString(header.BaseHash[:]),
+	}
+
+	for i := uint32(0); i < header.RunCount; i++ {
+		var runHeader patchRunHeader
+		if err := binary.Read(r, binary.LittleEndian, &runHeader); err != nil {
+			return nil, fmt.Errorf("failed to read patch run: %w", err)
+		}
+		if runHeader.Length <= 0 || runHeader.Length > header.Width {
+			return nil, fmt.Errorf("invalid patch run length %d", runHeader.Length)
+		}
+
+		run := PatchRun{
+			X:   int(runHeader.X),
+			Y:   int(runHeader.Y),
+			Old: make([]byte, runHeader.Length*4),
+			New: make([]byte, runHeader.Length*4),
+		}
+		if _, err := io.ReadFull(r, run.Old); err != nil {
+			return nil, fmt.Errorf("failed to read patch pixels: %w", err)
+		}
+		if _, err := io.ReadFull(r, run.New); err != nil {
+			return nil, fmt.Errorf("failed to read patch pixels: %w", err)
+		}
+		patch.Runs = append(patch.Runs, run)
+	}
+
+	return patch, nil
+}
+
+// CreatePatchFile writes a patch turning the texture at basePath into the one at modifiedPath
+func (g *GraphicsConverter) CreatePatchFile(basePath, modifiedPath, patchPath string) (*TexturePatch, error) {
+	base, err := g.decodeFile(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base texture '%s': %w", basePath, err)
+	}
+	modified, err := g.decodeFile(modifiedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode modified texture '%s': %w", modifiedPath, err)
+	}
+
+	patch := CreatePatch(base, modified)
+	g.log.Infof("Patch changes %d pixels in %d runs", patch.ChangedPixels(), len(patch.Runs))
+
+	if err := os.MkdirAll(filepath.Dir(patchPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(patchPath), err)
+	}
+	patchFile, err := os.Create(patchPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patch file '%s': %w", patchPath, err)
+	}
+	err = WritePatch(patchFile, patch)
+	if closeErr := patchFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(patchPath)
+		return nil, fmt.Errorf("failed to write patch file '%s': %w", patchPath, err)
+	}
+	return patch, nil
+}
+
+// ApplyPatchFile applies the patch at patchPath to the texture at basePath and writes the result to outputPath.
+// The output format follows outputPath's extension.
+// If the base differs from the one the patch was created from, the patch is still applied as long as none of
+// the pixels it changes were modified; otherwise a *PatchConflictError is returned and nothing is written.
+func (g *GraphicsConverter) ApplyPatchFile(patchPath, basePath, outputPath string) error {
+	patchFile, err := os.Open(patchPath)
+	if err != nil {
+		return fmt.Errorf("failed to open patch file '%s': %w", patchPath, err)
+	}
+	patch, err := ReadPatch(patchFile)
+	patchFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read patch file '%s': %w", patchPath, err)
+	}
+
+	base, err := g.decodeFile(basePath)
+	if err != nil {
+		return fmt.Errorf("failed to decode base texture '%s': %w", basePath, err)
+	}
+
+	if base.Bounds().Size() != patch.BaseSize {
+		return fmt.Errorf("patch expects a %dx%d base, got %dx%d",
+			patch.BaseSize.X, patch.BaseSize.Y, base.Bounds().Dx(), base.Bounds().Dy())
+	}
+	if hashPixels(base) != patch.BaseHash {
+		g.log.Warnf("'%s' differs from the texture the patch was created from, checking for conflicts", basePath)
+		if conflicts := patch.Conflicts(base); len(conflicts) > 0 {
+			return &PatchConflictError{Conflicts: conflicts}
+		}
+	}
+
+	img, err := patch.Apply(base)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(outputPath), err)
+	}
+	return g.encodeFile(img, outputPath)
+}
@@ -0,0 +1,179 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// TestPatchRoundTrip tests that a written and read patch turns the base into the modified texture
+func TestPatchRoundTrip(t *testing.T) {
+	base := bytesToImage(t, readTestResource(t, filepath.Join("png", "multi-color.png")))
+
+	bounds := base.Bounds()
+	modified := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			modified.Set(x, y, base.At(x, y))
+		}
+	}
+	modified.SetNRGBA(bounds.Min.X+1, bounds.Min.Y+2, color.NRGBA{R: 1, G: 2, B: 3, A: 255})
+	modified.SetNRGBA(bounds.Min.X+2, bounds.Min.Y+2, color.NRGBA{R: 4, G: 5, B: 6, A: 128})
+
+	patch := CreatePatch(base, modified)
+	if patch.ChangedPixels() != 2 || len(patch.Runs) != 1 {
+		t.Fatalf("Expected 2 changed pixels in 1 run, got %d in %d", patch.ChangedPixels(), len(patch.Runs))
+	}
+	if patch.BaseHash != hashPixels(base) {
+		t.Errorf("Expected base hash %s, got %s", hashPixels(base), patch.BaseHash)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePatch(&buf, patch); err != nil {
+		t.Fatalf("WritePatch failed: %v", err)
+	}
+	readBack, err := ReadPatch(&buf)
+	if err != nil {
+		t.Fatalf("ReadPatch failed: %v", err)
+	}
+
+	result, err := readBack.Apply(base)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if hashPixels(result) != hashPixels(modified) {
+		t.Errorf("Patched texture doesn't match the modified texture")
+	}
+}
+
+// TestPatchResize tests that patches can grow a texture
+func TestPatchResize(t *testing.T) {
+	base := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	modified := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	modified.SetNRGBA(2, 1, color.NRGBA{G: 255, A: 255})
+
+	patch := CreatePatch(base, modified)
+	if patch.ChangedPixels() != 1 {
+		t.Fatalf("Expected 1 changed pixel, got %d", patch.ChangedPixels())
+	}
+
+	result, err := patch.Apply(base)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if hashPixels(result) != hashPixels(modified) {
+		t.Errorf("Patched texture doesn't match the modified texture")
+	}
+
+	if _, err := patch.Apply(modified); err == nil {
+		t.Errorf("Expected applying to a base of the wrong size to fail")
+	}
+}
+
+// TestPatchFiles tests creating and applying patches between texture files of different formats
+func TestPatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	graphicsConverter := NewGraphicsConverter()
+
+	basePath := filepath.Join(dir, "base.data")
+	copyFile(t, filepath.Join("testdata", "data", "big-test.data"), basePath)
+	modifiedPath := filepath.Join(dir, "modified.png")
+	copyFile(t, filepath.Join("testdata", "png", "big-test-no-background.png"), modifiedPath)
+
+	patchPath := filepath.Join(dir, "patch"+PatchExtension)
+	if _, err := graphicsConverter.CreatePatchFile(basePath, modifiedPath, patchPath); err != nil {
+		t.Fatalf("CreatePatchFile failed: %v", err)
+	}
+
+	outputPath := filepath.Join(dir, "out", "patched.png")
+	if err := graphicsConverter.ApplyPatchFile(patchPath, basePath, outputPath); err != nil {
+		t.Fatalf("ApplyPatchFile failed: %v", err)
+	}
+
+	modified, err := graphicsConverter.decodeFile(modifiedPath)
+	if err != nil {
+		t.Fatalf("Failed to decode modified texture: %v", err)
+	}
+	patched, err := graphicsConverter.decodeFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to decode patched texture: %v", err)
+	}
+	if hashPixels(patched) != hashPixels(modified) {
+		t.Errorf("Patched texture doesn't match the modified texture")
+	}
+}
+
+// TestPatchConflicts tests that changes to a modified base only conflict where the patch writes
+func TestPatchConflicts(t *testing.T) {
+	dir := t.TempDir()
+	graphicsConverter := NewGraphicsConverter()
+
+	base := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	modified := image.NewNRGBA(base.Rect)
+	modified.SetNRGBA(1, 1, color.NRGBA{R: 255, A: 255})
+
+	patchPath := filepath.Join(dir, "patch"+PatchExtension)
+	basePath := filepath.Join(dir, "base.png")
+	modifiedPath := filepath.Join(dir, "modified.png")
+	for path, img := range map[string]image.Image{basePath: base, modifiedPath: modified} {
+		if err := graphicsConverter.encodeFile(img, path); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	if _, err := graphicsConverter.CreatePatchFile(basePath, modifiedPath, patchPath); err != nil {
+		t.Fatalf("CreatePatchFile failed: %v", err)
+	}
+
+	// A change elsewhere in the base merges cleanly and is kept
+	otherEdit := image.NewNRGBA(base.Rect)
+	otherEdit.SetNRGBA(3, 3, color.NRGBA{B: 255, A: 255})
+	otherPath := filepath.Join(dir, "other.png")
+	if err := graphicsConverter.encodeFile(otherEdit, otherPath); err != nil {
+		t.Fatalf("Failed to write base: %v", err)
+	}
+	outputPath := filepath.Join(dir, "out.png")
+	if err := graphicsConverter.ApplyPatchFile(patchPath, otherPath, outputPath); err != nil {
+		t.Fatalf("Expected non-overlapping change to apply, got %v", err)
+	}
+	result, err := graphicsConverter.decodeFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if c := imagecompare.Pixel(result, 3, 3); c.B != 255 {
+		t.Errorf("Expected other change to be kept, got %v", c)
+	}
+	if c := imagecompare.Pixel(result, 1, 1); c.R != 255 {
+		t.Errorf("Expected patched pixel, got %v", c)
+	}
+
+	// A change to a patched pixel is a conflict
+	conflicting := image.NewNRGBA(base.Rect)
+	conflicting.SetNRGBA(1, 1, color.NRGBA{G: 255, A: 255})
+	conflictPath := filepath.Join(dir, "conflict.png")
+	if err := graphicsConverter.encodeFile(conflicting, conflictPath); err != nil {
+		t.Fatalf("Failed to write base: %v", err)
+	}
+	conflictOutput := filepath.Join(dir, "conflict-out.png")
+	err = graphicsConverter.ApplyPatchFile(patchPath, conflictPath, conflictOutput)
+	var conflictErr *PatchConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Expected PatchConflictError, got %v", err)
+	}
+	if len(conflictErr.Conflicts) != 1 || conflictErr.Conflicts[0].X != 1 || conflictErr.Conflicts[0].Y != 1 {
+		t.Errorf("Expected a single conflict at (1,1), got %+v", conflictErr.Conflicts)
+	}
+	if _, err := os.Stat(conflictOutput); !os.IsNotExist(err) {
+		t.Errorf("Expected no output to be written on conflict")
+	}
+
+	// Re-applying to an already patched texture is not a conflict
+	if err := graphicsConverter.ApplyPatchFile(patchPath, outputPath, filepath.Join(dir, "again.png")); err != nil {
+		t.Errorf("Expected re-applying the patch to succeed, got %v", err)
+	}
+}
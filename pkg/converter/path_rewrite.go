@@ -0,0 +1,121 @@
+package converter
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SetFlatten writes the outputs of batch conversions straight into the target directory instead of
+// mirroring the source tree. Outputs keep their input's file name unless several inputs share it,
+// ignoring case: those are named after their whole relative path with underscores between the
+// directories, such as characters_player_idle00.png, plus -2, -3 and so on should that collide too.
+// Watch mode doesn't support flattening.
+func (f *FilesConverter) SetFlatten(enabled bool) {
+	f.flatten = enabled
+}
+
+// SetStripPrefix removes the slash-separated directories prefix, such as "Gameplay/characters", from
+// the start of the relative path of every output of batch conversions. Outputs of inputs outside the
+// prefix keep their path. Empty disables stripping.
+func (f *FilesConverter) SetStripPrefix(prefix string) {
+	f.stripPrefix = cleanPrefix(prefix)
+}
+
+// SetAddPrefix writes the outputs of batch conversions below the slash-separated directories prefix
+// of the target, such as "Graphics/Atlases/Gameplay", after stripping and flattening. Empty writes
+// them at the root of the target.
+func (f *FilesConverter) SetAddPrefix(prefix string) {
+	f.addPrefix = cleanPrefix(prefix)
+}
+
+// cleanPrefix normalizes a path prefix to slash-separated directories without surrounding slashes
+func cleanPrefix(prefix string) string {
+	prefix = path.Clean(filepath.ToSlash(prefix))
+	if prefix == "." || prefix == "/" {
+		return ""
+	}
+	return strings.TrimSuffix(prefix, "/")
+}
+
+// rewritesPaths reports whether outputs are flattened or re-rooted
+func (f *FilesConverter) rewritesPaths() bool {
+	return f.flatten || f.stripPrefix != "" || f.addPrefix != ""
+}
+
+// rewriteOutputPaths strips, flattens and prefixes the default output paths of tasks in toDir,
+// rejecting outputs outside toDir and outputs named the same
+func (f *FilesConverter) rewriteOutputPaths(tasks []ConversionTask, toDir string) error {
+	if path.IsAbs(f.addPrefix) || f.addPrefix == ".." || strings.HasPrefix(f.addPrefix, "../") {
+		return fmt.Errorf("output prefix '%s' leads outside the target directory", f.addPrefix)
+	}
+	relPaths := make([]string, len(tasks))
+	for i, task := range tasks {
+		relPath, err := filepath.Rel(toDir, task.outputPath)
+		if err != nil {
+			return err
+		}
+		relPaths[i] = stripPathPrefix(filepath.ToSlash(relPath), f.stripPrefix)
+	}
+	if f.flatten {
+		relPaths = flattenPaths(relPaths)
+	}
+
+	for i := range tasks {
+		tasks[i].outputPath = filepath.Join(toDir, filepath.FromSlash(path.Join(f.addPrefix, relPaths[i])))
+	}
+	return checkOutputCollisions(tasks)
+}
+
+// stripPathPrefix removes the directories prefix from the start of the slash-separated relPath, if
+// it lies below them
+func stripPathPrefix(relPath, prefix string) string {
+	if prefix == "" {
+		return relPath
+	}
+	if rest, ok := strings.CutPrefix(relPath, prefix+"/"); ok {
+		return rest
+	}
+	return relPath
+}
+
+// flattenPaths names slash-separated relative paths by their file name, or their whole path joined
+// by underscores where file names collide, adding a number to names still taken
+func flattenPaths(relPaths []string) []string {
+	shared := make(map[string]int, len(relPaths))
+	for _, relPath := range relPaths {
+		shared[strings.ToLower(path.Base(relPath))]++
+	}
+
+	flat := make([]string, len(relPaths))
+	taken := make(map[string]bool, len(relPaths))
+	for i, relPath := range relPaths {
+		name := path.Base(relPath)
+		if shared[strings.ToLower(name)] > 1 {
+			name = strings.ReplaceAll(relPath, "/", "_")
+		}
+		unique := name
+		ext := fileExtension(name)
+		for n := 2; taken[strings.ToLower(unique)]; n++ {
+			unique = strings.TrimSuffix(name, ext) + "-" + strconv.Itoa(n) + ext
+		}
+		taken[strings.ToLower(unique)] = true
+		flat[i] = unique
+	}
+	return flat
+}
+
+// checkOutputCollisions rejects tasks naming several inputs' outputs the same, ignoring case
+func checkOutputCollisions(tasks []ConversionTask) error {
+	inputs := make(map[string]string, len(tasks))
+	for _, task := range tasks {
+		key := strings.ToLower(task.outputPath)
+		if other, ok := inputs[key]; ok {
+			return fmt.Errorf("both '%s' and '%s' would be written to '%s'", other, task.inputPath, task.outputPath)
+		}
+		inputs[key] = task.inputPath
+	}
+	return nil
+}
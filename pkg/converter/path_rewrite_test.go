@@ -0,0 +1,89 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setupNestedFiles creates a source tree like an atlas dump, with two inputs named idle.data
+func setupNestedFiles(t *testing.T) string {
+	fromDir := t.TempDir()
+	for _, relPath := range []string{"Gameplay/a/idle.data", "Gameplay/b/idle.data", "Gameplay/c/run.data", "root.data"} {
+		target := filepath.Join(fromDir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		copyFile(t, filepath.Join("testdata", "data", "red.data"), target)
+	}
+	return fromDir
+}
+
+// TestPathRewrites tests flattening and re-rooting outputs
+func TestPathRewrites(t *testing.T) {
+	fromDir := setupNestedFiles(t)
+	tests := []struct {
+		name     string
+		options  []FilesOption
+		expected []string
+	}{
+		{"flatten", []FilesOption{WithFlatten(true)},
+			[]string{"Gameplay_a_idle.png", "Gameplay_b_idle.png", "run.png", "root.png"}},
+		{"prefixes", []FilesOption{WithStripPrefix("Gameplay/"), WithAddPrefix("Mods/MyMod")},
+			[]string{"Mods/MyMod/a/idle.png", "Mods/MyMod/b/idle.png", "Mods/MyMod/c/run.png", "Mods/MyMod/root.png"}},
+		{"strip and flatten", []FilesOption{WithStripPrefix("Gameplay"), WithFlatten(true), WithAddPrefix("sprites")},
+			[]string{"sprites/a_idle.png", "sprites/b_idle.png", "sprites/run.png", "sprites/root.png"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			toDir := t.TempDir()
+			if err := NewFilesConverter(NewGraphicsConverter(), test.options...).DataToPng(fromDir, toDir); err != nil {
+				t.Fatalf("DataToPng failed: %v", err)
+			}
+			var written []string
+			filepath.WalkDir(toDir, func(path string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					relPath, _ := filepath.Rel(toDir, path)
+					written = append(written, filepath.ToSlash(relPath))
+				}
+				return err
+			})
+			for _, expected := range test.expected {
+				if _, err := os.Stat(filepath.Join(toDir, filepath.FromSlash(expected))); err != nil {
+					t.Errorf("Expected %s to be written, got %v", expected, written)
+				}
+			}
+			if len(written) != len(test.expected) {
+				t.Errorf("Expected %d outputs, got %v", len(test.expected), written)
+			}
+		})
+	}
+}
+
+// TestFlattenPaths tests that flattened names stay unique when joined paths collide too
+func TestFlattenPaths(t *testing.T) {
+	actual := flattenPaths([]string{"a_b/c.png", "a/b/c.png", "d/Single.cdat.zst", "e/IDLE.png", "f/idle.png"})
+	expected := []string{"a_b_c.png", "a_b_c-2.png", "Single.cdat.zst", "e_IDLE.png", "f_idle.png"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+// TestPathRewriteErrors tests that prefixes leading outside the target and output templates are rejected
+func TestPathRewriteErrors(t *testing.T) {
+	fromDir := setupNestedFiles(t)
+
+	if err := NewFilesConverter(NewGraphicsConverter(), WithAddPrefix("../outside")).DataToPng(fromDir, t.TempDir()); err == nil {
+		t.Error("Expected a prefix leading outside the target to be rejected")
+	}
+
+	template, err := ParseOutputTemplate("{name}{ext}")
+	if err != nil {
+		t.Fatalf("ParseOutputTemplate failed: %v", err)
+	}
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithFlatten(true), WithOutputTemplate(template))
+	if err := filesConverter.DataToPng(fromDir, t.TempDir()); err == nil {
+		t.Error("Expected flattening to be refused with an output template")
+	}
+}
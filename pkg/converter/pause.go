@@ -0,0 +1,97 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrStopped is returned by batch conversions stopped with Stop
+var ErrStopped = errors.New("conversion stopped")
+
+// pauseGate blocks workers while paused, and turns them away once stopped
+type pauseGate struct {
+	mu      sync.Mutex
+	paused  bool
+	stopped bool
+	resumed chan struct{} // Closed when the gate is resumed or stopped
+}
+
+// Pause stops workers from picking up new files until Resume is called.
+// Files already being converted are finished.
+func (f *FilesConverter) Pause() {
+	f.pause.mu.Lock()
+	defer f.pause.mu.Unlock()
+
+	if !f.pause.paused {
+		f.pause.paused = true
+		f.pause.resumed = make(chan struct{})
+		f.log.Infof("Conversion paused")
+	}
+}
+
+// Resume lets workers continue after Pause
+func (f *FilesConverter) Resume() {
+	f.pause.mu.Lock()
+	defer f.pause.mu.Unlock()
+
+	if f.pause.paused {
+		f.pause.paused = false
+		close(f.pause.resumed)
+		f.log.Infof("Conversion resumed")
+	}
+}
+
+// Paused reports whether the converter is currently paused
+func (f *FilesConverter) Paused() bool {
+	f.pause.mu.Lock()
+	defer f.pause.mu.Unlock()
+	return f.pause.paused
+}
+
+// Stop makes batch conversions finish the files being converted but start no new ones, returning
+// ErrStopped. Unlike cancelling their context, files already started are completed rather than removed.
+// A stopped converter stays stopped, so later batches stop right away.
+func (f *FilesConverter) Stop() {
+	f.pause.mu.Lock()
+	defer f.pause.mu.Unlock()
+
+	if !f.pause.stopped {
+		f.pause.stopped = true
+		if f.pause.paused {
+			f.pause.paused = false
+			close(f.pause.resumed)
+		}
+		f.log.Infof("Conversion stopping, finishing the files being converted")
+	}
+}
+
+// Stopped reports whether Stop was called
+func (f *FilesConverter) Stopped() bool {
+	f.pause.mu.Lock()
+	defer f.pause.mu.Unlock()
+	return f.pause.stopped
+}
+
+// wait blocks while the gate is paused, returning early with the context's error if it is cancelled,
+// or ErrStopped once the gate is stopped
+func (p *pauseGate) wait(ctx context.Context) error {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return ErrStopped
+	}
+	if !p.paused {
+		p.mu.Unlock()
+		return nil
+	}
+	resumed := p.resumed
+	p.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return p.wait(ctx)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestPauseResume tests that a paused converter starts no files until resumed
+func TestPauseResume(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.Pause()
+	if !filesConverter.Paused() {
+		t.Fatal("Expected converter to report paused")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- filesConverter.DataToPng(fromDir, toDir)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	entries, err := os.ReadDir(toDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("Expected no output while paused, found %d files", len(entries))
+	}
+
+	filesConverter.Resume()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("DataToPng failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Conversion did not finish after resume")
+	}
+
+	entries, err = os.ReadDir(toDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != len(testImages) {
+		t.Errorf("Expected %d outputs after resume, found %d", len(testImages), len(entries))
+	}
+}
+
+// TestPauseCancel tests that cancelling a paused conversion doesn't hang
+func TestPauseCancel(t *testing.T) {
+	fromDir := t.TempDir()
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := filesConverter.DataToPngContext(ctx, fromDir, t.TempDir())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// TestStop tests that a stopped batch finishes the file being converted, starts no more and keeps its output
+func TestStop(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithWorkers(1))
+	filesConverter.Progress(func(event ProgressEvent) {
+		if event.Type == FileFinished {
+			filesConverter.Stop()
+		}
+	})
+	if err := filesConverter.DataToPng(fromDir, toDir); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Expected ErrStopped, got %v", err)
+	}
+
+	entries, err := os.ReadDir(toDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the first output, found %d files", len(entries))
+	}
+}
+
+// TestStopWhilePaused tests that stopping a paused conversion ends it without converting anything
+func TestStopWhilePaused(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.Pause()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- filesConverter.DataToPng(fromDir, toDir)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	filesConverter.Stop()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrStopped) {
+			t.Fatalf("Expected ErrStopped, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Conversion did not end after stop")
+	}
+	if entries, _ := os.ReadDir(toDir); len(entries) != 0 {
+		t.Errorf("Expected no outputs, found %d files", len(entries))
+	}
+}
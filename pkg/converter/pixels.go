@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+)
+
+// rowReader fills row with the pixels of row y, relative to the image origin, as RGBA bytes
+type rowReader func(y int, row []byte)
+
+// dataRows returns a reader producing the rows of img with the colors stored in a DATA file in the
+// configured alpha mode. The common decoded types are read straight from their Pix slices; anything
+// else goes through img.At.
+func (g *GraphicsConverter) dataRows(img image.Image) rowReader {
+	bounds := img.Bounds()
+	straight := g.alphaMode == AlphaStraight
+
+	switch img := img.(type) {
+	case *image.RGBA:
+		return func(y int, row []byte) {
+			copy(row, img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):])
+			if straight {
+				unpremultiplyPix(row)
+			}
+		}
+	case *image.NRGBA:
+		return func(y int, row []byte) {
+			src := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			if straight {
+				copy(row, src)
+			} else {
+				premultiplyRow(row, src)
+			}
+		}
+	case *image.Paletted:
+		palette := g.dataPalette(img.Palette)
+		return func(y int, row []byte) {
+			src := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for p := 0; p < len(row); p += 4 {
+				copy(row[p:p+4], palette[src[p/4]][:])
+			}
+		}
+	}
+
+	return func(y int, row []byte) {
+		for p := 0; p < len(row); p += 4 {
+			x := bounds.Min.X + p/4
+			if straight {
+				c := color.NRGBAModel.Convert(img.At(x, bounds.Min.Y+y)).(color.NRGBA)
+				row[p], row[p+1], row[p+2], row[p+3] = c.R, c.G, c.B, c.A
+			} else {
+				row[p], row[p+1], row[p+2], row[p+3] = getRGBA(img, x, bounds.Min.Y+y)
+			}
+		}
+	}
+}
+
+// dataPalette converts a palette to the colors stored in a DATA file, indexed by palette index.
+// Entries past the end of the palette are transparent black.
+func (g *GraphicsConverter) dataPalette(palette color.Palette) [256][4]byte {
+	var colors [256][4]byte
+	for i, c := range palette {
+		if i >= len(colors) {
+			break
+		}
+		if g.alphaMode == AlphaStraight {
+			n := color.NRGBAModel.Convert(c).(color.NRGBA)
+			colors[i] = [4]byte{n.R, n.G, n.B, n.A}
+		} else {
+			r, g, b, a := c.RGBA()
+			colors[i] = [4]byte{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		}
+	}
+	return colors
+}
+
+// fillRun writes count copies of the RGBA pixel px to the start of pix, doubling the filled part with copy
+func fillRun(pix []byte, px [4]byte, count int) {
+	run := pix[:count*4]
+	filled := copy(run, px[:])
+	for filled < len(run) {
+		filled += copy(run[filled:], run[:filled])
+	}
+}
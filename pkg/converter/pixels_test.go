@@ -0,0 +1,90 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// opaqueImage hides the concrete type of an image, forcing the generic pixel path
+type opaqueImage struct {
+	image.Image
+}
+
+// TestDataRowsFastPaths tests that the Pix fast paths write the same DATA as the generic path
+func TestDataRowsFastPaths(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	palette := color.Palette{color.NRGBA{A: 0}, color.NRGBA{R: 255, A: 255}, color.NRGBA{G: 200, B: 40, A: 128}}
+	paletted := image.NewPaletted(image.Rect(0, 0, 37, 23), palette)
+	for i := range paletted.Pix {
+		if random.Intn(4) > 0 { // Leave runs behind
+			paletted.Pix[i] = uint8(random.Intn(len(palette)))
+		}
+	}
+	nrgba := image.NewNRGBA(paletted.Rect)
+	for y := 0; y < 23; y++ {
+		for x := 0; x < 37; x++ {
+			nrgba.Set(x, y, paletted.At(x, y))
+		}
+	}
+	nrgba.SetNRGBA(5, 5, color.NRGBA{R: 201, G: 3, B: 77, A: 9})
+
+	images := map[string]image.Image{
+		"paletted": paletted,
+		"rgba":     premultiplyNRGBA(nrgba),
+		"nrgba":    nrgba,
+		"subimage": nrgba.SubImage(image.Rect(3, 2, 30, 20)),
+		"no alpha": paletted.SubImage(image.Rect(0, 0, 1, 1)),
+	}
+	for _, mode := range []AlphaMode{AlphaPremultiplied, AlphaStraight} {
+		graphicsConverter := NewGraphicsConverter()
+		graphicsConverter.SetAlphaMode(mode)
+		for name, img := range images {
+			// The generic path truncates when converting between premultiplied and straight alpha, while
+			// the fast paths round, so they are compared against the rounded conversion
+			reference := img
+			switch img := img.(type) {
+			case *image.NRGBA:
+				if mode == AlphaPremultiplied {
+					reference = premultiplyNRGBA(img)
+				}
+			case *image.RGBA:
+				if mode == AlphaStraight {
+					straight := &image.NRGBA{Pix: bytes.Clone(img.Pix), Stride: img.Stride, Rect: img.Rect}
+					unpremultiplyPix(straight.Pix)
+					reference = straight
+				}
+			}
+
+			var fast, generic bytes.Buffer
+			if err := graphicsConverter.encodeData(img, &fast); err != nil {
+				t.Fatalf("encodeData failed: %v", err)
+			}
+			if err := graphicsConverter.encodeData(opaqueImage{reference}, &generic); err != nil {
+				t.Fatalf("encodeData failed: %v", err)
+			}
+			if !bytes.Equal(fast.Bytes(), generic.Bytes()) {
+				t.Errorf("%s, %s: fast path differs from generic path", mode, name)
+			}
+		}
+	}
+}
+
+// TestFillRun tests runs of every length up to the longest DATA run
+func TestFillRun(t *testing.T) {
+	px := [4]byte{1, 2, 3, 4}
+	for count := 1; count <= MaxRunLength; count++ {
+		pix := make([]byte, (count+1)*4)
+		fillRun(pix, px, count)
+		for p := 0; p < count*4; p += 4 {
+			if [4]byte(pix[p:p+4]) != px {
+				t.Fatalf("Run of %d: pixel %d is %v", count, p/4, pix[p:p+4])
+			}
+		}
+		if [4]byte(pix[count*4:]) != ([4]byte{}) {
+			t.Fatalf("Run of %d wrote past its end", count)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package converter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// pngInfo holds the IHDR fields that affect how a PNG is decoded
+type pngInfo struct {
+	width      int
+	height     int
+	bitDepth   uint8
+	colorType  uint8
+	interlaced bool
+}
+
+// SetDither enables Floyd-Steinberg dithering of color channels when 16-bit PNGs are reduced to 8 bits.
+// Without it every channel is rounded to the nearest 8-bit value. Alpha is always rounded.
+// SetDitherMethod selects other methods.
+func (g *GraphicsConverter) SetDither(enabled bool) {
+	if enabled {
+		g.dither = DitherFloydSteinberg
+	} else {
+		g.dither = DitherNone
+	}
+}
+
+// decodePng decodes a PNG, including Adam7-interlaced ones, and reduces 16-bit images to 8 bits per channel
+// so that every caller sees the same pixels regardless of the source depth. With color management the
+// pixels are converted to sRGB.
+func (g *GraphicsConverter) decodePng(input io.Reader) (image.Image, error) {
+	var colors *pngColorRecorder
+	if g.colorManaged {
+		colors = new(pngColorRecorder)
+		input = io.TeeReader(input, colors)
+	}
+	img, info, err := g.decodePngImage(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.bitDepth == 16 {
+		if g.dither != DitherNone {
+			g.log.Infof("Reducing 16-bit PNG to 8 bits per channel with %s dithering", g.dither)
+		} else {
+			g.log.Infof("Reducing 16-bit PNG to 8 bits per channel")
+		}
+		img = reduceTo8Bit(img, g.dither)
+	}
+	if colors != nil {
+		if profile := g.pngColorProfile(colors.chunks); profile != nil {
+			img = profile.convertImage(img)
+		}
+	}
+	return img, nil
+}
+
+// decodePngImage decodes a PNG as stored, at its own bit depth and with its palette, after checking its
+// size against the limits
+func (g *GraphicsConverter) decodePngImage(input io.Reader) (image.Image, pngInfo, error) {
+	r := getPngReader(input)
+	defer putPngReader(r)
+	info := peekPngInfo(r)
+
+	// Malformed headers are left for png.Decode to report
+	if info.width != 0 || info.height != 0 {
+		bytesPerPixel := 4
+		if info.bitDepth == 16 {
+			bytesPerPixel = 8
+		}
+		if err := g.checkImageSize(info.width, info.height, bytesPerPixel); err != nil {
+			return nil, info, err
+		}
+	}
+
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, info, err
+	}
+
+	if info.interlaced {
+		g.log.Debugf("PNG is Adam7-interlaced")
+	}
+	return img, info, nil
+}
+
+// peekPngInfo reads the IHDR fields without consuming them, returning zero values for malformed headers
+func peekPngInfo(r *bufio.Reader) pngInfo {
+	// Signature (8), IHDR length and type (8), width and height (8), then bit depth, color type,
+	// compression, filter and interlace method
+	header, err := r.Peek(pngSignatureAndHeaderLen)
+	if err != nil || string(header[12:16]) != "IHDR" || binary.BigEndian.Uint32(header[8:12]) != 13 {
+		return pngInfo{}
+	}
+	return pngInfo{
+		width:      int(int32(binary.BigEndian.Uint32(header[16:20]))),
+		height:     int(int32(binary.BigEndian.Uint32(header[20:24]))),
+		bitDepth:   header[24],
+		colorType:  header[25],
+		interlaced: header[28] == 1,
+	}
+}
+
+// reduceTo8Bit converts an image to 8-bit straight alpha, rounding each color channel to the nearest
+// value, diffusing its rounding error Floyd-Steinberg style or offsetting it by a Bayer matrix first
+func reduceTo8Bit(img image.Image, method DitherMethod) *image.NRGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	// Accumulated error for the current and next row, per pixel and color channel, in 16-bit units
+	dither := method == DitherFloydSteinberg
+	var current, next []int32
+	if dither {
+		current = make([]int32, (width+2)*3)
+		next = make([]int32, (width+2)*3)
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA64Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA64)
+			channels := [3]uint16{c.R, c.G, c.B}
+			var quantized [3]uint8
+
+			for ch, v := range channels {
+				if method == DitherOrdered {
+					quantized[ch] = round16To8(int32(v) + bayerOffset(x, y))
+					continue
+				}
+				if !dither {
+					quantized[ch] = round16To8(int32(v))
+					continue
+				}
+
+				// Errors are stored shifted by one pixel so x-1 never goes negative
+				i := (x+1)*3 + ch
+				wanted := int32(v) + current[i]/16
+				q := round16To8(wanted)
+				quantized[ch] = q
+
+				diff := wanted - int32(q)*257
+				current[i+3] += diff * 7
+				next[i-3] += diff * 3
+				next[i] += diff * 5
+				next[i+3] += diff * 1
+			}
+
+			out.SetNRGBA(x, y, color.NRGBA{
+				R: quantized[0],
+				G: quantized[1],
+				B: quantized[2],
+				A: round16To8(int32(c.A)),
+			})
+		}
+
+		if dither {
+			current, next = next, current
+			clear(next)
+		}
+	}
+
+	return out
+}
+
+// round16To8 maps a 16-bit channel value to the nearest 8-bit value, clamping out-of-range input
+func round16To8(v int32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 0xffff {
+		return 0xff
+	}
+	return uint8((v + 128) / 257)
+}
@@ -0,0 +1,202 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// encodeInterlacedPng writes img as an 8-bit RGBA Adam7-interlaced PNG, which image/png can't produce
+func encodeInterlacedPng(t *testing.T, img *image.NRGBA) []byte {
+	passes := []struct{ x, y, dx, dy int }{
+		{0, 0, 8, 8}, {4, 0, 8, 8}, {0, 4, 4, 8}, {2, 0, 4, 4}, {0, 2, 2, 4}, {1, 0, 2, 2}, {0, 1, 1, 2},
+	}
+	bounds := img.Bounds()
+
+	var raw bytes.Buffer
+	for _, pass := range passes {
+		for y := pass.y; y < bounds.Dy(); y += pass.dy {
+			if pass.x >= bounds.Dx() {
+				break
+			}
+			raw.WriteByte(0) // No filter
+			for x := pass.x; x < bounds.Dx(); x += pass.dx {
+				c := img.NRGBAAt(x, y)
+				raw.Write([]byte{c.R, c.G, c.B, c.A})
+			}
+		}
+	}
+
+	return encodeRawPng(t, bounds.Dx(), bounds.Dy(), 6, true, raw.Bytes())
+}
+
+// encodeRawPng wraps already filtered 8-bit scanlines of the given PNG color type in a minimal PNG
+func encodeRawPng(t *testing.T, width, height int, colorType byte, interlaced bool, raw []byte) []byte {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(raw)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to compress image data: %v", err)
+	}
+
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	writeChunk := func(name string, data []byte) {
+		binary.Write(&out, binary.BigEndian, uint32(len(data)))
+		chunk := append([]byte(name), data...)
+		out.Write(chunk)
+		binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(height))
+	ihdr[8], ihdr[9] = 8, colorType
+	if interlaced {
+		ihdr[12] = 1 // Adam7
+	}
+	writeChunk("IHDR", ihdr)
+	writeChunk("IDAT", compressed.Bytes())
+	writeChunk("IEND", nil)
+	return out.Bytes()
+}
+
+// TestInterlacedPngToData tests that an Adam7-interlaced PNG converts to the same DATA as a plain one
+func TestInterlacedPngToData(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 11, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 11; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 20), G: uint8(y * 25), B: uint8(x * y), A: 255})
+		}
+	}
+
+	var plain bytes.Buffer
+	if err := png.Encode(&plain, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	converter := NewGraphicsConverter()
+	expected := pngToDataBytes(t, converter, plain.Bytes())
+	actual := pngToDataBytes(t, converter, encodeInterlacedPng(t, img))
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Interlaced PNG produced different DATA than the plain PNG")
+	}
+}
+
+// TestSixteenBitPngToData tests that 16-bit PNGs keep their alpha and are rounded to the nearest 8-bit value
+func TestSixteenBitPngToData(t *testing.T) {
+	img := image.NewNRGBA64(image.Rect(0, 0, 2, 1))
+	img.SetNRGBA64(0, 0, color.NRGBA64{R: 0x00ff, G: 0xffff, B: 0x8000, A: 0xffff})
+	img.SetNRGBA64(1, 0, color.NRGBA64{R: 0xffff, A: 0x8000})
+
+	var pngBytes bytes.Buffer
+	if err := png.Encode(&pngBytes, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	converter := NewGraphicsConverter()
+	decoded, err := converter.decodeData(bytes.NewReader(pngToDataBytes(t, converter, pngBytes.Bytes())))
+	if err != nil {
+		t.Fatalf("Failed to decode DATA: %v", err)
+	}
+
+	if c := decoded.NRGBAAt(0, 0); c != (color.NRGBA{R: 1, G: 255, B: 128, A: 255}) {
+		t.Errorf("Expected rounded opaque pixel, got %v", c)
+	}
+	if c := decoded.NRGBAAt(1, 0); c.A != 128 {
+		t.Errorf("Expected alpha to survive 16-bit conversion, got %v", c)
+	}
+}
+
+// TestReduceTo8BitDither tests that dithering preserves the average of values between two 8-bit levels
+func TestReduceTo8BitDither(t *testing.T) {
+	const value = 128*257 + 64 // A quarter of the way from 128 to 129
+	img := image.NewNRGBA64(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetNRGBA64(x, y, color.NRGBA64{R: value, G: value, B: value, A: 0xffff})
+		}
+	}
+
+	average := func(reduced *image.NRGBA) float64 {
+		sum := 0
+		for i := 0; i < len(reduced.Pix); i += 4 {
+			sum += int(reduced.Pix[i])
+		}
+		return float64(sum) / float64(len(reduced.Pix)/4)
+	}
+
+	if avg := average(reduceTo8Bit(img, DitherNone)); avg != 128 {
+		t.Errorf("Expected rounding to give 128 everywhere, got average %f", avg)
+	}
+	if avg := average(reduceTo8Bit(img, DitherFloydSteinberg)); avg < 128.2 || avg > 128.3 {
+		t.Errorf("Expected dithered average near 128.25, got %f", avg)
+	}
+}
+
+// dataAlphaFlag returns the alpha flag from a DATA header
+func dataAlphaFlag(t *testing.T, data []byte) int32 {
+	if len(data) < 12 {
+		t.Fatalf("DATA output too short: %d bytes", len(data))
+	}
+	return int32(binary.LittleEndian.Uint32(data[8:12]))
+}
+
+// TestPalettedPngToData tests that paletted PNGs with tRNS transparency keep their alpha
+func TestPalettedPngToData(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+
+	palette := color.Palette{
+		color.NRGBA{R: 255, A: 255},
+		color.NRGBA{B: 255, A: 0},
+		color.NRGBA{G: 255, A: 128},
+	}
+	img := image.NewPaletted(image.Rect(0, 0, 3, 2), palette)
+	img.Pix = []uint8{0, 1, 2, 2, 1, 0}
+
+	var pngBytes bytes.Buffer
+	if err := png.Encode(&pngBytes, img); err != nil {
+		t.Fatalf("Failed to encode paletted PNG: %v", err)
+	}
+
+	data := pngToDataBytes(t, graphicsConverter, pngBytes.Bytes())
+	if flag := dataAlphaFlag(t, data); flag != 1 {
+		t.Errorf("Expected alpha flag 1, got %d", flag)
+	}
+	assertImageEquals(t, img, bytesToImage(t, dataToPngBytes(t, graphicsConverter, data)), 1)
+
+	// A translucent palette entry that no pixel uses doesn't make the image transparent
+	img.Pix = []uint8{0, 0, 0, 0, 0, 0}
+	pngBytes.Reset()
+	if err := png.Encode(&pngBytes, img); err != nil {
+		t.Fatalf("Failed to encode paletted PNG: %v", err)
+	}
+	if flag := dataAlphaFlag(t, pngToDataBytes(t, graphicsConverter, pngBytes.Bytes())); flag != 0 {
+		t.Errorf("Expected alpha flag 0 for an opaque paletted image, got %d", flag)
+	}
+}
+
+// TestGrayAlphaPngToData tests that grayscale PNGs with and without alpha get the right alpha flag
+func TestGrayAlphaPngToData(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+
+	// Gray and alpha byte pairs, one scanline with no filter
+	grayAlpha := encodeRawPng(t, 3, 1, 4, false, []byte{0, 200, 255, 50, 0, 100, 128})
+	data := pngToDataBytes(t, graphicsConverter, grayAlpha)
+	if flag := dataAlphaFlag(t, data); flag != 1 {
+		t.Errorf("Expected alpha flag 1, got %d", flag)
+	}
+	assertImageEquals(t, bytesToImage(t, grayAlpha), bytesToImage(t, dataToPngBytes(t, graphicsConverter, data)), 1)
+
+	gray := encodeRawPng(t, 3, 1, 0, false, []byte{0, 200, 50, 100})
+	data = pngToDataBytes(t, graphicsConverter, gray)
+	if flag := dataAlphaFlag(t, data); flag != 0 {
+		t.Errorf("Expected alpha flag 0 for plain grayscale, got %d", flag)
+	}
+	assertImageEquals(t, bytesToImage(t, gray), bytesToImage(t, dataToPngBytes(t, graphicsConverter, data)), 1)
+}
@@ -0,0 +1,58 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"sync"
+)
+
+// pngCompressionLevels maps the names accepted by ParsePngCompression to compression levels
+var pngCompressionLevels = map[string]png.CompressionLevel{
+	"none":    png.NoCompression,
+	"speed":   png.BestSpeed,
+	"default": png.DefaultCompression,
+	"best":    png.BestCompression,
+}
+
+// ParsePngCompression parses a PNG compression level name: none, speed, default or best
+func ParsePngCompression(name string) (png.CompressionLevel, error) {
+	level, ok := pngCompressionLevels[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown PNG compression '%s', expected none, speed, default or best", name)
+	}
+	return level, nil
+}
+
+// pngBufferPool shares encoder buffers between all PNG encodes of a GraphicsConverter,
+// so parallel workers don't allocate new deflate state for every file
+type pngBufferPool struct {
+	pool sync.Pool
+}
+
+func (p *pngBufferPool) Get() *png.EncoderBuffer {
+	buffer, _ := p.pool.Get().(*png.EncoderBuffer)
+	return buffer // nil makes the encoder allocate a new buffer
+}
+
+func (p *pngBufferPool) Put(buffer *png.EncoderBuffer) {
+	p.pool.Put(buffer)
+}
+
+// SetPngCompression sets the deflate level of written PNGs. png.BestSpeed is much faster than the default
+// for large batches, at the cost of somewhat bigger files.
+func (g *GraphicsConverter) SetPngCompression(level png.CompressionLevel) {
+	// Buffers hold deflate state for one level, so a new level gets a new pool
+	g.pngEncoder = &png.Encoder{CompressionLevel: level, BufferPool: &pngBufferPool{}}
+}
+
+// encodePng writes img as a PNG with the configured compression level and palette setting, tagged sRGB
+// with color management
+func (g *GraphicsConverter) encodePng(output io.Writer, img image.Image) error {
+	if g.colorManaged {
+		output = &pngChunkWriter{w: output, chunks: srgbChunks}
+	}
+	return g.pngEncoder.Encode(output, g.palettedForPng(img))
+}
@@ -0,0 +1,66 @@
+package converter
+
+import (
+	"bytes"
+	"image/png"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestPngCompression tests that every compression level writes the same pixels and that better
+// compression doesn't produce larger files
+func TestPngCompression(t *testing.T) {
+	dataBytes := readTestResource(t, filepath.Join("data", "multi-color.data"))
+	expected := bytesToImage(t, dataToPngBytes(t, NewGraphicsConverter(), dataBytes))
+
+	sizes := make(map[string]int)
+	for _, name := range []string{"none", "speed", "default", "best"} {
+		level, err := ParsePngCompression(name)
+		if err != nil {
+			t.Fatalf("ParsePngCompression(%q) failed: %v", name, err)
+		}
+		graphicsConverter := NewGraphicsConverter()
+		graphicsConverter.SetPngCompression(level)
+
+		pngBytes := dataToPngBytes(t, graphicsConverter, dataBytes)
+		assertImageEquals(t, expected, bytesToImage(t, pngBytes), 0)
+		sizes[name] = len(pngBytes)
+	}
+
+	if sizes["none"] < sizes["speed"] || sizes["speed"] < sizes["best"] {
+		t.Errorf("Expected sizes to shrink with better compression, got %v", sizes)
+	}
+
+	if _, err := ParsePngCompression("fastest"); err == nil {
+		t.Error("Expected error for unknown compression level, got nil")
+	}
+}
+
+// TestPngEncoderConcurrent tests that parallel encodes sharing the buffer pool produce identical output
+func TestPngEncoderConcurrent(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+	graphicsConverter.SetPngCompression(png.BestSpeed)
+	dataBytes := readTestResource(t, filepath.Join("data", "multi-color.data"))
+	expected := dataToPngBytes(t, graphicsConverter, dataBytes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				var output bytes.Buffer
+				if err := graphicsConverter.DataToPng(bytes.NewReader(dataBytes), &output); err != nil {
+					t.Errorf("DataToPng failed: %v", err)
+					return
+				}
+				if !bytes.Equal(output.Bytes(), expected) {
+					t.Error("Parallel encode differs from sequential encode")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
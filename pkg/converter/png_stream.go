@@ -0,0 +1,378 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image/color"
+	"io"
+)
+
+// PNG color types
+const (
+	pngGray      = 0
+	pngRGB       = 2
+	pngPaletted  = 3
+	pngGrayAlpha = 4
+	pngRGBA      = 6
+)
+
+// pngStream reads the rows of a non-interlaced PNG one at a time, so it can be encoded as DATA without
+// holding the decoded image. Its rows hold the same pixels image/png would decode.
+type pngStream struct {
+	info    pngInfo
+	idat    [][]byte       // Contents of the IDAT chunks
+	plte    []byte         // Contents of the PLTE chunk
+	trns    []byte         // Contents of the tRNS chunk
+	colors  pngColorChunks // Color space chunks, resolved to profile by resolveColors
+	palette [256][4]byte   // DATA colors of paletted images by index
+	opaque  bool           // No pixel can be translucent
+	profile *colorProfile  // Converts the colors to sRGB, nil to keep them
+	g       *GraphicsConverter
+}
+
+// newPngStream parses the chunks of data, returning nil for PNGs left to image/png: interlaced ones,
+// 16-bit ones reduced with dithering, ones with transparency keys other than a palette's and
+// malformed ones, for which image/png reports the error
+func (g *GraphicsConverter) newPngStream(data []byte) *pngStream {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil
+	}
+	info := peekPngInfo(bufio.NewReader(bytes.NewReader(data)))
+	if info.width <= 0 || info.height <= 0 || info.interlaced || (info.bitDepth == 16 && g.dither != DitherNone) {
+		return nil
+	}
+	switch {
+	case info.colorType == pngGray && (info.bitDepth == 1 || info.bitDepth == 2 || info.bitDepth == 4 || info.bitDepth == 8 || info.bitDepth == 16):
+	case info.colorType == pngPaletted && (info.bitDepth == 1 || info.bitDepth == 2 || info.bitDepth == 4 || info.bitDepth == 8):
+	case (info.colorType == pngRGB || info.colorType == pngGrayAlpha || info.colorType == pngRGBA) && (info.bitDepth == 8 || info.bitDepth == 16):
+	default:
+		return nil
+	}
+
+	s := &pngStream{info: info, g: g, opaque: info.colorType == pngGray || info.colorType == pngRGB}
+	var previous string
+	ended := false
+	for rest := data[len(pngSignature):]; len(rest) > 0 && !ended; {
+		if len(rest) < 12 {
+			return nil
+		}
+		length := binary.BigEndian.Uint32(rest)
+		if uint64(length) > uint64(len(rest)-12) {
+			return nil
+		}
+		chunk := rest[4 : 8+length]
+		if crc32.ChecksumIEEE(chunk) != binary.BigEndian.Uint32(rest[8+length:]) {
+			return nil
+		}
+		content, name := chunk[4:], string(chunk[:4])
+		// image/png wants the palette and transparency before the image data, and that in one run of chunks
+		if len(s.idat) > 0 && (name == "PLTE" || name == "tRNS" || name == "IDAT" && previous != "IDAT") {
+			return nil
+		}
+		previous = name
+		switch name {
+		case "PLTE":
+			s.plte = content
+		case "tRNS":
+			s.trns = content
+		case "IDAT":
+			s.idat = append(s.idat, content)
+		case "sRGB", "iCCP", "gAMA":
+			if len(s.idat) == 0 {
+				s.colors.record(name, content)
+			}
+		case "IEND":
+			ended = true
+		default:
+			// Unknown critical chunks, with an uppercase first letter, can't be skipped
+			if chunk[0]&0x20 == 0 && name != "IHDR" {
+				return nil
+			}
+		}
+		rest = rest[12+length:]
+	}
+	if !ended || len(s.idat) == 0 {
+		return nil
+	}
+
+	if info.colorType == pngPaletted {
+		if len(s.plte) == 0 || len(s.plte)%3 != 0 || len(s.plte)/3 > 1<<info.bitDepth || len(s.trns) > len(s.plte)/3 {
+			return nil
+		}
+		s.opaque = len(s.trns) == 0
+	} else if s.trns != nil {
+		return nil
+	}
+	return s
+}
+
+// resolveColors looks up the color profile with color management and builds the palette. It's left
+// until the image is known to be streamed, so a profile is only logged once.
+func (s *pngStream) resolveColors() {
+	if s.g.colorManaged {
+		s.profile = s.g.pngColorProfile(s.colors)
+	}
+	if s.info.colorType == pngPaletted {
+		palette := pngPalette(s.plte, s.trns)
+		if s.profile != nil {
+			s.profile.convertPalette(palette[:len(s.plte)/3])
+		}
+		s.palette = s.g.dataPalette(palette)
+	}
+}
+
+// pngPalette builds the palette image/png decodes from PLTE and tRNS contents: entries with
+// transparency as color.NRGBA, and opaque black for the indices past the end of the PLTE
+func pngPalette(plte, trns []byte) color.Palette {
+	palette := make(color.Palette, 256)
+	for i := range palette {
+		palette[i] = color.RGBA{0, 0, 0, 0xff}
+	}
+	for i := 0; i < len(plte)/3; i++ {
+		palette[i] = color.RGBA{plte[i*3], plte[i*3+1], plte[i*3+2], 0xff}
+		if i < len(trns) {
+			palette[i] = color.NRGBA{plte[i*3], plte[i*3+1], plte[i*3+2], trns[i]}
+		}
+	}
+	return palette
+}
+
+// rows returns a reader of the DATA colors of the image's rows in order, and a function returning the
+// first error met. Rows past an error are left transparent black.
+func (s *pngStream) rows() (rowReader, func() error, error) {
+	var readers []io.Reader
+	for _, idat := range s.idat {
+		readers = append(readers, bytes.NewReader(idat))
+	}
+	compressed := io.MultiReader(readers...)
+	zr, err := zlib.NewReader(compressed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bitsPerPixel := int(s.info.bitDepth) * map[uint8]int{pngGray: 1, pngRGB: 3, pngPaletted: 1, pngGrayAlpha: 2, pngRGBA: 4}[s.info.colorType]
+	bytesPerPixel := max(bitsPerPixel/8, 1)
+	rowSize := (s.info.width*bitsPerPixel + 7) / 8
+	current := make([]byte, 1+rowSize)
+	previous := make([]byte, 1+rowSize)
+	straight := make([]byte, s.info.width*4)
+	premultiplied := s.g.alphaMode != AlphaStraight
+
+	var readErr error
+	read := func(y int, row []byte) {
+		if readErr != nil {
+			clear(row)
+			return
+		}
+		if _, err := io.ReadFull(zr, current); err != nil {
+			readErr = pngDataError(err)
+			clear(row)
+			return
+		}
+		if err := unfilterPngRow(current[0], current[1:], previous[1:], bytesPerPixel); err != nil {
+			readErr = err
+			clear(row)
+			return
+		}
+		if s.info.colorType == pngPaletted {
+			s.paletteRow(current[1:], row)
+		} else {
+			s.straightRow(current[1:], straight)
+			if s.profile != nil {
+				s.profile.convertRow(straight)
+			}
+			if premultiplied {
+				premultiplyRow(row, straight)
+			} else {
+				copy(row, straight)
+			}
+		}
+		current, previous = previous, current
+	}
+
+	// After the last row the zlib stream has to end, verifying its checksum
+	finish := func() error {
+		if readErr != nil {
+			return readErr
+		}
+		var extra [1]byte
+		n, err := 0, error(nil)
+		for i := 0; n == 0 && err == nil; i++ {
+			if i == 100 {
+				return io.ErrNoProgress
+			}
+			n, err = zr.Read(extra[:])
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("png: invalid format: %s", err)
+		}
+		if n != 0 {
+			return errors.New("png: invalid format: too much pixel data")
+		}
+		return nil
+	}
+	return read, finish, nil
+}
+
+// pngDataError reports image data ending early with the error image/png gives
+func pngDataError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("png: invalid format: not enough pixel data")
+	}
+	return err
+}
+
+// unfilterPngRow reverses the filter of a row in place, given the unfiltered previous row
+func unfilterPngRow(filter byte, row, previous []byte, bytesPerPixel int) error {
+	switch filter {
+	case 0:
+	case 1:
+		for i := bytesPerPixel; i < len(row); i++ {
+			row[i] += row[i-bytesPerPixel]
+		}
+	case 2:
+		for i := range row {
+			row[i] += previous[i]
+		}
+	case 3:
+		for i := range row {
+			var left byte
+			if i >= bytesPerPixel {
+				left = row[i-bytesPerPixel]
+			}
+			row[i] += byte((int(left) + int(previous[i])) / 2)
+		}
+	case 4:
+		for i := range row {
+			var left, upperLeft byte
+			if i >= bytesPerPixel {
+				left, upperLeft = row[i-bytesPerPixel], previous[i-bytesPerPixel]
+			}
+			row[i] += paeth(left, previous[i], upperLeft)
+		}
+	default:
+		return errors.New("png: invalid format: bad filter type")
+	}
+	return nil
+}
+
+// paeth returns whichever of a (left), b (up) and c (upper left) is closest to a + b - c
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := absInt(p-int(a)), absInt(p-int(b)), absInt(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+// absInt returns the absolute value of v
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// paletteRow writes the DATA colors of a row of palette indices
+func (s *pngStream) paletteRow(src, row []byte) {
+	depth := int(s.info.bitDepth)
+	perByte := 8 / depth
+	mask := byte(1<<depth - 1) // All ones for 8-bit indices
+	for x := 0; x < s.info.width; x++ {
+		index := src[x/perByte] >> (8 - depth*(x%perByte+1)) & mask
+		copy(row[x*4:x*4+4], s.palette[index][:])
+	}
+}
+
+// straightRow writes the 8-bit straight alpha colors of a row of gray or truecolor pixels, reducing
+// 16-bit channels like reduceTo8Bit does without dithering
+func (s *pngStream) straightRow(src, row []byte) {
+	depth := int(s.info.bitDepth)
+	channel := func(i int) uint8 {
+		if depth == 16 {
+			return round16To8(int32(binary.BigEndian.Uint16(src[i*2:])))
+		}
+		return src[i]
+	}
+	for x := 0; x < s.info.width; x++ {
+		p := row[x*4 : x*4+4]
+		switch s.info.colorType {
+		case pngGray:
+			var v uint8
+			if depth < 8 {
+				perByte := 8 / depth
+				v = src[x/perByte] >> (8 - depth*(x%perByte+1)) & byte(1<<depth-1)
+				v = uint8(int(v) * 0xff / (1<<depth - 1))
+			} else {
+				v = channel(x)
+			}
+			p[0], p[1], p[2], p[3] = v, v, v, 0xff
+		case pngGrayAlpha:
+			v := channel(x * 2)
+			p[0], p[1], p[2], p[3] = v, v, v, channel(x*2+1)
+		case pngRGB:
+			p[0], p[1], p[2], p[3] = channel(x*3), channel(x*3+1), channel(x*3+2), 0xff
+		case pngRGBA:
+			p[0], p[1], p[2], p[3] = channel(x*4), channel(x*4+1), channel(x*4+2), channel(x*4+3)
+		}
+	}
+}
+
+// hasAlpha reports whether any row of the image isn't fully opaque, decoding them all
+func (s *pngStream) hasAlpha() (bool, error) {
+	if s.opaque {
+		return false, nil
+	}
+	read, finish, err := s.rows()
+	if err != nil {
+		return false, err
+	}
+	row := make([]byte, s.info.width*4)
+	for y := 0; y < s.info.height; y++ {
+		read(y, row)
+		for p := 3; p < len(row); p += 4 {
+			if row[p] != 0xff {
+				// Errors in the rest of the image are left to the encoding pass
+				return true, nil
+			}
+		}
+	}
+	return false, finish()
+}
+
+// encodePngStream encodes the streamed PNG as DATA, decoding the rows a second time to choose the
+// alpha flag when the image may be translucent and the alpha channel setting is automatic
+func (g *GraphicsConverter) encodePngStream(s *pngStream, output io.Writer) error {
+	if s.info.bitDepth == 16 {
+		g.log.Infof("Reducing 16-bit PNG to 8 bits per channel")
+	}
+	s.resolveColors()
+	var hasAlpha bool
+	switch g.alphaChannel {
+	case AlphaChannelForce:
+		hasAlpha = true
+	case AlphaChannelAuto:
+		var err error
+		if hasAlpha, err = s.hasAlpha(); err != nil {
+			return err
+		}
+	}
+
+	read, finish, err := s.rows()
+	if err != nil {
+		return err
+	}
+	if err := g.writeData(output, read, s.info.width, s.info.height, hasAlpha); err != nil {
+		return err
+	}
+	return finish()
+}
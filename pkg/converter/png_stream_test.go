@@ -0,0 +1,170 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// bufferedPngToData converts a PNG the way PngToData does for images it can't stream
+func bufferedPngToData(t *testing.T, g *GraphicsConverter, data []byte) []byte {
+	img, err := g.decodePng(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+	var out bytes.Buffer
+	if err := g.encodeData(img, &out); err != nil {
+		t.Fatalf("Failed to encode DATA: %v", err)
+	}
+	return out.Bytes()
+}
+
+// setPngBitDepth rewrites the bit depth in the IHDR of a PNG written by encodeRawPng
+func setPngBitDepth(data []byte, depth byte) []byte {
+	data = bytes.Clone(data)
+	data[24] = depth
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+// streamTestPngs returns PNGs of every color type and bit depth the stream reads
+func streamTestPngs(t *testing.T) map[string][]byte {
+	pngs := make(map[string][]byte)
+	encode := func(name string, img image.Image) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatalf("Failed to encode %s: %v", name, err)
+		}
+		pngs[name] = buf.Bytes()
+	}
+
+	const width, height = 37, 23
+	rect := image.Rect(0, 0, width, height)
+	gray, gray16 := image.NewGray(rect), image.NewGray16(rect)
+	rgba, nrgba, nrgba64 := image.NewRGBA(rect), image.NewNRGBA(rect), image.NewNRGBA64(rect)
+	palette := color.Palette{color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 255, 0, 128}, color.NRGBA{0, 0, 255, 0}}
+	opaquePalette := color.Palette{color.RGBA{10, 20, 30, 255}, color.RGBA{200, 100, 50, 255}}
+	paletted, opaquePaletted := image.NewPaletted(rect, palette), image.NewPaletted(rect, opaquePalette)
+	bigPalette := make(color.Palette, 200)
+	for i := range bigPalette {
+		bigPalette[i] = color.NRGBA{uint8(i), uint8(i * 3), uint8(i * 7), uint8(i + 55)}
+	}
+	bigPaletted := image.NewPaletted(rect, bigPalette)
+
+	var grayAlpha, gray4 bytes.Buffer
+	for y := 0; y < height; y++ {
+		grayAlpha.WriteByte(byte(y % 5)) // Every filter type
+		gray4.WriteByte(0)
+		for x := 0; x < width; x++ {
+			v := uint8(x*7 + y*13)
+			gray.SetGray(x, y, color.Gray{v})
+			gray16.SetGray16(x, y, color.Gray16{uint16(x*1733 + y*977)})
+			rgba.SetRGBA(x, y, color.RGBA{v, uint8(x * 3), uint8(y * 5), 255})
+			nrgba.SetNRGBA(x, y, color.NRGBA{v, uint8(x * 3), uint8(y * 5), uint8(x * y)})
+			nrgba64.SetNRGBA64(x, y, color.NRGBA64{uint16(x * 1733), uint16(y * 977), 0x8080, uint16(x*y*101 + 3)})
+			paletted.SetColorIndex(x, y, uint8((x+y)%3))
+			opaquePaletted.SetColorIndex(x, y, uint8(x%2))
+			bigPaletted.SetColorIndex(x, y, uint8((x*y)%200))
+			grayAlpha.Write([]byte{v, uint8(x * 11)})
+		}
+		for x := 0; x < (width+1)/2; x++ {
+			gray4.WriteByte(uint8(x*0x13 + y))
+		}
+	}
+
+	encode("gray", gray)
+	encode("gray16", gray16)
+	encode("opaque rgba", rgba)
+	encode("nrgba", nrgba)
+	encode("nrgba64", nrgba64)
+	encode("2-bit paletted", paletted)
+	encode("1-bit opaque paletted", opaquePaletted)
+	encode("8-bit paletted", bigPaletted)
+	pngs["gray alpha"] = encodeRawPng(t, width, height, 4, false, grayAlpha.Bytes())
+	pngs["4-bit gray"] = setPngBitDepth(encodeRawPng(t, width, height, 0, false, gray4.Bytes()), 4)
+
+	files, _ := filepath.Glob(filepath.Join("testdata", "png", "*.png"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		pngs[filepath.Base(file)] = data
+	}
+	return pngs
+}
+
+// TestPngStream tests that streamed PNGs convert to the same DATA as decoded ones in every alpha setting
+func TestPngStream(t *testing.T) {
+	for name, data := range streamTestPngs(t) {
+		t.Run(name, func(t *testing.T) {
+			g := NewGraphicsConverter()
+			if g.newPngStream(data) == nil {
+				t.Fatal("Expected the PNG to be streamed")
+			}
+			for _, mode := range []AlphaMode{AlphaPremultiplied, AlphaStraight} {
+				for _, channel := range []AlphaChannel{AlphaChannelAuto, AlphaChannelForce, AlphaChannelNever} {
+					g.SetAlphaMode(mode)
+					g.SetAlphaChannel(channel)
+					streamed := pngToDataBytes(t, g, data)
+					if !bytes.Equal(streamed, bufferedPngToData(t, g, data)) {
+						t.Errorf("Streamed DATA differs with %s alpha and %s alpha channel", mode, channel)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestPngStreamFallback tests that PNGs the stream doesn't read are still decoded
+func TestPngStreamFallback(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 5, 4))
+	img.SetNRGBA(1, 1, color.NRGBA{1, 2, 3, 4})
+
+	g := NewGraphicsConverter()
+	interlaced := encodeInterlacedPng(t, img)
+	if g.newPngStream(interlaced) != nil {
+		t.Error("Expected interlaced PNGs to be decoded")
+	}
+	if !bytes.Equal(pngToDataBytes(t, g, interlaced), bufferedPngToData(t, g, interlaced)) {
+		t.Error("Interlaced PNG converted differently")
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA64(image.Rect(0, 0, 5, 4))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	g.SetDither(true)
+	if g.newPngStream(buf.Bytes()) != nil {
+		t.Error("Expected dithered 16-bit PNGs to be decoded")
+	}
+}
+
+// TestPngStreamErrors tests that broken image data fails the conversion
+func TestPngStreamErrors(t *testing.T) {
+	row := []byte{0, 1, 2, 3, 4}
+	tests := map[string][]byte{
+		"missing rows":   encodeRawPng(t, 1, 3, 6, false, bytes.Repeat(row, 2)),
+		"extra rows":     encodeRawPng(t, 1, 3, 6, false, bytes.Repeat(row, 4)),
+		"bad filter":     encodeRawPng(t, 1, 3, 6, false, bytes.Repeat([]byte{9, 1, 2, 3, 4}, 3)),
+		"truncated data": encodeRawPng(t, 1, 3, 6, false, nil),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGraphicsConverter()
+			if g.newPngStream(data) == nil {
+				t.Fatal("Expected the PNG to be streamed")
+			}
+			if err := g.PngToData(bytes.NewReader(data), io.Discard); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
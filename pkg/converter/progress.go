@@ -0,0 +1,147 @@
+package converter
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressEventType identifies what a ProgressEvent reports
+type ProgressEventType int
+
+const (
+	// BatchStarted is emitted once the input files have been listed
+	BatchStarted ProgressEventType = iota
+	// FileStarted is emitted when a worker picks up a file
+	FileStarted
+	// FileFinished is emitted when a file was converted successfully
+	FileFinished
+	// FileFailed is emitted when a file could not be converted
+	FileFailed
+	// BatchFinished is emitted after all workers have stopped
+	BatchFinished
+)
+
+// String returns a readable name for the event type
+func (t ProgressEventType) String() string {
+	switch t {
+	case BatchStarted:
+		return "batch-started"
+	case FileStarted:
+		return "file-started"
+	case FileFinished:
+		return "file-finished"
+	case FileFailed:
+		return "file-failed"
+	case BatchFinished:
+		return "batch-finished"
+	}
+	return "unknown"
+}
+
+// ProgressEvent describes a step of a batch conversion
+type ProgressEvent struct {
+	Type    ProgressEventType
+	Time    time.Time     // UTC wall clock time of the event
+	Elapsed time.Duration // Monotonic time since the batch started
+
+	// Per-file fields, unset for batch events
+	Index      int    // 1-based position of the file in the batch
+	RelPath    string // Input path relative to the source directory
+	InputPath  string
+	OutputPath string
+	BytesRead  int64 // Input bytes consumed for this file
+	Err        error // Set for FileFailed
+
+	// Metrics of a finished file, unset for failed files
+	Duration     time.Duration // Time spent converting the file
+	BytesWritten int64         // Size of the output
+	DataBytes    int64         // Size of the DATA input or output, 0 if neither side is DATA
+	PixelBytes   int64         // Uncompressed RGBA size of that DATA texture
+
+	// Running totals
+	TotalFiles     int
+	CompletedFiles int
+	FailedFiles    int
+	TotalBytesRead int64
+}
+
+// Progress registers a hook receiving structured progress events.
+// Calls to the hook are serialized, so it doesn't need its own locking. Pass nil to remove it.
+func (f *FilesConverter) Progress(hook func(ProgressEvent)) {
+	f.progressHook = hook
+}
+
+// progressTracker keeps the running totals of a batch and forwards events to the hook
+type progressTracker struct {
+	hook       func(ProgressEvent)
+	start      time.Time
+	totalFiles int
+	completed  atomic.Int64
+	failed     atomic.Int64
+	bytesRead  atomic.Int64
+	mu         sync.Mutex
+}
+
+// newProgressTracker creates a tracker for a batch of totalFiles files; hook may be nil
+func newProgressTracker(hook func(ProgressEvent), totalFiles int) *progressTracker {
+	return &progressTracker{
+		hook:       hook,
+		start:      time.Now(),
+		totalFiles: totalFiles,
+	}
+}
+
+// fileDone updates the totals for a finished or failed file and emits the matching event
+func (p *progressTracker) fileDone(task ConversionTask, metrics taskMetrics, duration time.Duration, err error) {
+	p.bytesRead.Add(metrics.bytesRead)
+	event := ProgressEvent{
+		Type:       FileFinished,
+		Index:      task.index,
+		RelPath:    task.relPath,
+		InputPath:  task.inputPath,
+		OutputPath: task.outputPath,
+		BytesRead:  metrics.bytesRead,
+		Err:        err,
+	}
+	if err != nil {
+		event.Type = FileFailed
+		p.failed.Add(1)
+	} else {
+		event.Duration = duration
+		event.BytesWritten = metrics.bytesWritten
+		event.DataBytes = metrics.dataBytes
+		event.PixelBytes = metrics.pixelBytes
+		p.completed.Add(1)
+	}
+	p.emit(event)
+}
+
+// fileStarted emits a FileStarted event
+func (p *progressTracker) fileStarted(task ConversionTask) {
+	p.emit(ProgressEvent{
+		Type:       FileStarted,
+		Index:      task.index,
+		RelPath:    task.relPath,
+		InputPath:  task.inputPath,
+		OutputPath: task.outputPath,
+	})
+}
+
+// emit fills in timing and totals and calls the hook
+func (p *progressTracker) emit(event ProgressEvent) {
+	if p.hook == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	event.Time = time.Now().UTC()
+	event.Elapsed = time.Since(p.start)
+	event.TotalFiles = p.totalFiles
+	event.CompletedFiles = int(p.completed.Load())
+	event.FailedFiles = int(p.failed.Load())
+	event.TotalBytesRead = p.bytesRead.Load()
+	p.hook(event)
+}
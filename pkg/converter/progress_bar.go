@@ -0,0 +1,114 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// progressBarWidth is the number of cells of the bar itself
+	progressBarWidth = 30
+	// progressRedrawInterval is the shortest time between two redraws of an interactive bar
+	progressRedrawInterval = 100 * time.Millisecond
+	// ProgressLogInterval is the time between two progress lines when the output isn't a terminal
+	ProgressLogInterval = 5 * time.Second
+)
+
+// ProgressBar shows the progress of batch conversions with throughput and an ETA.
+// Register Record as a progress hook to drive it. On a terminal it redraws a single live line,
+// otherwise it writes a progress line every ProgressLogInterval and when a batch finishes.
+type ProgressBar struct {
+	out         io.Writer
+	interactive bool
+	lastDraw    time.Duration // Elapsed time of the last redraw or line in the current batch
+	drawn       bool          // Whether anything was written for the current batch
+}
+
+// NewProgressBar creates a progress bar writing to out; interactive selects the live line for terminals
+func NewProgressBar(out io.Writer, interactive bool) *ProgressBar {
+	return &ProgressBar{out: out, interactive: interactive}
+}
+
+// Record updates the bar from a progress event
+func (b *ProgressBar) Record(event ProgressEvent) {
+	switch event.Type {
+	case BatchStarted:
+		b.lastDraw, b.drawn = 0, false
+		if b.interactive {
+			b.draw(event)
+		}
+	case FileFinished, FileFailed:
+		interval := ProgressLogInterval
+		if b.interactive {
+			interval = progressRedrawInterval
+		}
+		if !b.drawn || event.Elapsed-b.lastDraw >= interval {
+			b.draw(event)
+		}
+	case BatchFinished:
+		b.draw(event)
+		if b.interactive {
+			fmt.Fprintln(b.out)
+		}
+	}
+}
+
+// draw writes the current state, over the previous one when interactive
+func (b *ProgressBar) draw(event ProgressEvent) {
+	b.lastDraw, b.drawn = event.Elapsed, true
+	if b.interactive {
+		fmt.Fprintf(b.out, "\r\033[K%s", formatProgress(event, true))
+		return
+	}
+	fmt.Fprintln(b.out, formatProgress(event, false))
+}
+
+// formatProgress describes the progress of a batch, with a bar when withBar is set
+func formatProgress(event ProgressEvent, withBar bool) string {
+	done := event.CompletedFiles + event.FailedFiles
+	var b strings.Builder
+	if withBar {
+		filled := 0
+		if event.TotalFiles > 0 {
+			filled = done * progressBarWidth / event.TotalFiles
+		}
+		fmt.Fprintf(&b, "[%s%s] ", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled))
+	} else {
+		b.WriteString("Progress: ")
+	}
+
+	percent := 100
+	if event.TotalFiles > 0 {
+		percent = done * 100 / event.TotalFiles
+	}
+	fmt.Fprintf(&b, "%d/%d files (%d%%)", done, event.TotalFiles, percent)
+	if event.FailedFiles > 0 {
+		fmt.Fprintf(&b, ", %d failed", event.FailedFiles)
+	}
+
+	seconds := event.Elapsed.Seconds()
+	if seconds > 0 && done > 0 {
+		fmt.Fprintf(&b, ", %.1f files/s, %.1f MB/s", float64(done)/seconds, float64(event.TotalBytesRead)/1e6/seconds)
+		if remaining := event.TotalFiles - done; remaining > 0 {
+			eta := time.Duration(float64(event.Elapsed) / float64(done) * float64(remaining))
+			fmt.Fprintf(&b, ", ETA %v", eta.Round(time.Second))
+		}
+	}
+	return b.String()
+}
+
+// SetQuietFiles logs the per-file lines of batch conversions at debug level instead of info, so they
+// don't scroll a progress bar away. Failures are still logged as errors.
+func (f *FilesConverter) SetQuietFiles(quiet bool) {
+	f.quietFiles = quiet
+}
+
+// fileLogLevel returns the level of the per-file lines of batch conversions
+func (f *FilesConverter) fileLogLevel() LogLevel {
+	if f.quietFiles {
+		return LevelDebug
+	}
+	return LevelInfo
+}
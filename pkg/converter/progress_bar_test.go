@@ -0,0 +1,66 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestProgressBarInteractive tests that the bar redraws one line, throttled, and ends it when the batch finishes
+func TestProgressBarInteractive(t *testing.T) {
+	var out bytes.Buffer
+	bar := NewProgressBar(&out, true)
+	bar.Record(ProgressEvent{Type: BatchStarted, TotalFiles: 4})
+	bar.Record(ProgressEvent{Type: FileFinished, Elapsed: time.Second, TotalFiles: 4, CompletedFiles: 1, TotalBytesRead: 2e6})
+	bar.Record(ProgressEvent{Type: FileFinished, Elapsed: time.Second + time.Millisecond, TotalFiles: 4, CompletedFiles: 2, TotalBytesRead: 4e6})
+
+	output := out.String()
+	if strings.Contains(output, "\n") {
+		t.Errorf("Expected a single live line, got %q", output)
+	}
+	if got := strings.Count(output, "\r"); got != 2 {
+		t.Errorf("Expected the redraw 1ms after the previous one to be skipped, got %d draws in %q", got, output)
+	}
+	want := "[=======                       ] 1/4 files (25%), 1.0 files/s, 2.0 MB/s, ETA 3s"
+	if !strings.HasSuffix(output, want) {
+		t.Errorf("Expected the bar to end with %q, got %q", want, output)
+	}
+
+	bar.Record(ProgressEvent{Type: BatchFinished, Elapsed: 2 * time.Second, TotalFiles: 4, CompletedFiles: 3, FailedFiles: 1, TotalBytesRead: 8e6})
+	want = "[==============================] 4/4 files (100%), 1 failed, 2.0 files/s, 4.0 MB/s\n"
+	if !strings.HasSuffix(out.String(), want) {
+		t.Errorf("Expected the final bar %q, got %q", want, out.String())
+	}
+}
+
+// TestProgressBarLines tests that non-interactive output gets a line per interval and a final one
+func TestProgressBarLines(t *testing.T) {
+	var out bytes.Buffer
+	bar := NewProgressBar(&out, false)
+	bar.Record(ProgressEvent{Type: BatchStarted, TotalFiles: 10})
+	failed := 0
+	for i := 1; i <= 10; i++ {
+		event := ProgressEvent{Type: FileFinished, Elapsed: time.Duration(i) * time.Second, TotalFiles: 10}
+		if i == 3 {
+			event.Type, event.Err = FileFailed, errors.New("broken")
+			failed++
+		}
+		event.CompletedFiles, event.FailedFiles = i-failed, failed
+		bar.Record(event)
+	}
+	bar.Record(ProgressEvent{Type: BatchFinished, Elapsed: 10 * time.Second, TotalFiles: 10, CompletedFiles: 9, FailedFiles: 1})
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	// The first file, the sixth five seconds later and the final summary
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 progress lines, got %q", lines)
+	}
+	if want := "Progress: 6/10 files (60%), 1 failed, 1.0 files/s, 0.0 MB/s, ETA 4s"; lines[1] != want {
+		t.Errorf("Expected %q, got %q", want, lines[1])
+	}
+	if strings.Contains(out.String(), "\r") {
+		t.Error("Expected no carriage returns outside a terminal")
+	}
+}
@@ -0,0 +1,57 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestProgressEvents tests the sequence and totals of progress events for a batch with one failure
+func TestProgressEvents(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	setupTestFiles(t, fromDir, ".png", "png")
+	if err := os.WriteFile(filepath.Join(fromDir, "broken.png"), []byte("not a png"), 0644); err != nil {
+		t.Fatalf("Failed to write broken file: %v", err)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetMaxWorkers(3)
+
+	var events []ProgressEvent
+	filesConverter.Progress(func(event ProgressEvent) {
+		events = append(events, event)
+	})
+
+	if err := filesConverter.PngToData(fromDir, toDir); err == nil {
+		t.Fatal("Expected conversion error for broken file, got nil")
+	}
+
+	total := len(testImages) + 1
+	counts := make(map[ProgressEventType]int)
+	for _, event := range events {
+		counts[event.Type]++
+		if event.TotalFiles != total {
+			t.Errorf("%s event: expected %d total files, got %d", event.Type, total, event.TotalFiles)
+		}
+		if event.Type == FileFailed && (event.Err == nil || event.RelPath != "broken.png") {
+			t.Errorf("Unexpected failure event: %+v", event)
+		}
+	}
+
+	if events[0].Type != BatchStarted || events[len(events)-1].Type != BatchFinished {
+		t.Errorf("Expected batch to start with %s and end with %s", BatchStarted, BatchFinished)
+	}
+	if counts[FileStarted] != total || counts[FileFinished] != total-1 || counts[FileFailed] != 1 {
+		t.Errorf("Unexpected event counts: %v", counts)
+	}
+
+	last := events[len(events)-1]
+	if last.CompletedFiles != total-1 || last.FailedFiles != 1 {
+		t.Errorf("Expected %d completed and 1 failed, got %d and %d", total-1, last.CompletedFiles, last.FailedFiles)
+	}
+	if last.TotalBytesRead == 0 {
+		t.Error("Expected bytes read to be reported")
+	}
+}
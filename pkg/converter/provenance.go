@@ -0,0 +1,176 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Version is the converter version recorded in provenance.
+// Override at build time with -ldflags "-X github.com/VictoriqueMoe/celeste-converter-go/pkg/converter.Version=v1.2.3"
+var Version = "dev"
+
+// converterName identifies this tool in provenance records
+const converterName = "celeste-converter-go"
+
+// ProvenanceFileName is the name of the batch-level provenance file written to the output directory root
+const ProvenanceFileName = "provenance.json"
+
+// provenanceSidecarSuffix is appended to an output file's name to form its sidecar path
+const provenanceSidecarSuffix = ".provenance.json"
+
+// Provenance records how a single output file was produced
+type Provenance struct {
+	Converter    string            `json:"converter"`
+	Version      string            `json:"version"`
+	Conversion   string            `json:"conversion"`
+	Options      map[string]string `json:"options,omitempty"`
+	Source       string            `json:"source"`
+	SourceSHA256 string            `json:"sourceSha256"`
+	Output       string            `json:"output"`
+}
+
+// BatchProvenance records how every output file of a batch conversion was produced
+type BatchProvenance struct {
+	Converter  string            `json:"converter"`
+	Version    string            `json:"version"`
+	Conversion string            `json:"conversion"`
+	Options    map[string]string `json:"options,omitempty"`
+	Files      []FileProvenance  `json:"files"`
+}
+
+// FileProvenance is the per-file part of a BatchProvenance
+type FileProvenance struct {
+	Source       string `json:"source"`
+	SourceSHA256 string `json:"sourceSha256"`
+	Output       string `json:"output"`
+}
+
+// textChunks returns the provenance as PNG tEXt keyword/value pairs, in a stable order
+func (p *Provenance) textChunks() [][2]string {
+	chunks := [][2]string{
+		{"Software", p.Converter + " " + p.Version},
+		{"Conversion", p.Conversion},
+		{"Source", p.Source},
+		{"SourceSHA256", p.SourceSHA256},
+	}
+	if len(p.Options) > 0 {
+		chunks = append(chunks, [2]string{"Options", formatOptions(p.Options)})
+	}
+	return chunks
+}
+
+// formatOptions renders options as space-separated key=value pairs sorted by key
+func formatOptions(options map[string]string) string {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+options[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// pngSignatureAndHeaderLen is the length of the PNG signature plus the IHDR chunk, after which text chunks are inserted
+const pngSignatureAndHeaderLen = 8 + 4 + 4 + 13 + 4
+
+// pngChunkWriter inserts tEXt and other chunks into a PNG stream directly after the IHDR chunk
+type pngChunkWriter struct {
+	w      io.Writer
+	texts  [][2]string
+	chunks []pngChunk // Written after the texts
+	header []byte
+	done   bool
+}
+
+// pngChunk is the type and data of a PNG chunk
+type pngChunk struct {
+	name string
+	data []byte
+}
+
+// newPngTextWriter wraps w so that the given keyword/value pairs are embedded in the PNG written through it
+func newPngTextWriter(w io.Writer, texts [][2]string) *pngChunkWriter {
+	return &pngChunkWriter{w: w, texts: texts}
+}
+
+// Write buffers the PNG header, then passes everything else straight through
+func (p *pngChunkWriter) Write(data []byte) (int, error) {
+	if p.done {
+		return p.w.Write(data)
+	}
+
+	n := len(data)
+	need := pngSignatureAndHeaderLen - len(p.header)
+	if len(data) < need {
+		p.header = append(p.header, data...)
+		return n, nil
+	}
+
+	p.header = append(p.header, data[:need]...)
+	p.done = true
+
+	if !bytes.Equal(p.header[12:16], []byte("IHDR")) {
+		return 0, errors.New("PNG stream does not start with an IHDR chunk")
+	}
+	if _, err := p.w.Write(p.header); err != nil {
+		return 0, err
+	}
+	for _, text := range p.texts {
+		if err := writePngTextChunk(p.w, text[0], text[1]); err != nil {
+			return 0, err
+		}
+	}
+	for _, chunk := range p.chunks {
+		if err := writePngChunk(p.w, chunk.name, chunk.data); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := p.w.Write(data[need:]); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// writePngTextChunk writes a single tEXt chunk
+func writePngTextChunk(w io.Writer, keyword, text string) error {
+	if len(keyword) == 0 || len(keyword) > 79 {
+		return fmt.Errorf("invalid PNG text keyword '%s'", keyword)
+	}
+
+	data := make([]byte, 0, len(keyword)+1+len(text))
+	data = append(data, keyword...)
+	data = append(data, 0)
+	data = append(data, text...)
+	return writePngChunk(w, "tEXt", data)
+}
+
+// writePngChunk writes a chunk with its length and CRC
+func writePngChunk(w io.Writer, name string, data []byte) error {
+	payload := make([]byte, 0, 4+len(data))
+	payload = append(payload, name...)
+	payload = append(payload, data...)
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	var crc [4]byte
+	binary.BigEndian.PutUint32(crc[:], crc32.ChecksumIEEE(payload))
+
+	if _, err := w.Write(length[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	_, err := w.Write(crc[:])
+	return err
+}
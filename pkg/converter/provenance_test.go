@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFilesConverterProvenance tests that provenance is written to PNG chunks, sidecars and the batch file
+func TestFilesConverterProvenance(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetProvenance(true, map[string]string{"workers": "2"})
+
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	var batch BatchProvenance
+	readJSONFile(t, filepath.Join(toDir, ProvenanceFileName), &batch)
+	if len(batch.Files) != len(testImages) {
+		t.Fatalf("Expected %d files in batch provenance, got %d", len(testImages), len(batch.Files))
+	}
+	if batch.Conversion != "DATA -> PNG" || batch.Options["workers"] != "2" {
+		t.Errorf("Unexpected batch provenance: %+v", batch)
+	}
+
+	for _, imgName := range testImages {
+		expectedHash, err := hashFile(filepath.Join(fromDir, imgName+".data"))
+		if err != nil {
+			t.Fatalf("Failed to hash source: %v", err)
+		}
+
+		outputPath := filepath.Join(toDir, imgName+".png")
+
+		var sidecar Provenance
+		readJSONFile(t, outputPath+provenanceSidecarSuffix, &sidecar)
+		if sidecar.SourceSHA256 != expectedHash {
+			t.Errorf("Sidecar hash for %s: expected %s, got %s", imgName, expectedHash, sidecar.SourceSHA256)
+		}
+		if sidecar.Output != imgName+".png" {
+			t.Errorf("Sidecar output for %s: got %s", imgName, sidecar.Output)
+		}
+
+		pngBytes, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		texts := readPngTextChunks(t, pngBytes)
+		if texts["SourceSHA256"] != expectedHash {
+			t.Errorf("PNG text hash for %s: expected %s, got %s", imgName, expectedHash, texts["SourceSHA256"])
+		}
+		if texts["Software"] != converterName+" "+Version {
+			t.Errorf("PNG text software for %s: got %s", imgName, texts["Software"])
+		}
+
+		// The PNG must still decode after chunk insertion
+		bytesToImage(t, pngBytes)
+	}
+}
+
+// readJSONFile decodes a JSON file into v
+func readJSONFile(t *testing.T, path string, v interface{}) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("Failed to parse %s: %v", path, err)
+	}
+}
+
+// readPngTextChunks returns all tEXt keyword/value pairs of a PNG
+func readPngTextChunks(t *testing.T, data []byte) map[string]string {
+	texts := make(map[string]string)
+	for pos := 8; pos+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		if pos+12+length > len(data) {
+			t.Fatalf("Truncated PNG chunk %s", chunkType)
+		}
+		if chunkType == "tEXt" {
+			payload := data[pos+8 : pos+8+length]
+			if sep := bytes.IndexByte(payload, 0); sep >= 0 {
+				texts[string(payload[:sep])] = string(payload[sep+1:])
+			}
+		}
+		pos += 12 + length
+	}
+	return texts
+}
@@ -0,0 +1,58 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// quarantineReportSuffix is appended to a quarantined file's name to form its error report path
+const quarantineReportSuffix = ".error.txt"
+
+// SetQuarantineDir enables copying inputs that fail conversion into dir, each with an error report next to it.
+// An empty dir disables quarantining.
+func (f *FilesConverter) SetQuarantineDir(dir string) {
+	f.quarantineDir = dir
+}
+
+// quarantine copies a failed task's input into the quarantine directory, keeping its relative path,
+// and writes the conversion error alongside it
+func (f *FilesConverter) quarantine(task ConversionTask, convErr error) error {
+	destPath := filepath.Join(f.quarantineDir, task.relPath)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+
+	source, err := task.open()
+	if err != nil {
+		return err
+	}
+	err = copyFileContents(source, destPath)
+	source.Close()
+	if err != nil {
+		return err
+	}
+
+	report := fmt.Sprintf("source: %s\nerror: %v\n", task.inputPath, convErr)
+	var panicErr *PanicError
+	if errors.As(convErr, &panicErr) {
+		report += "stack:\n" + panicErr.Stack
+	}
+	return os.WriteFile(destPath+quarantineReportSuffix, []byte(report), 0644)
+}
+
+// copyFileContents copies everything read from source to dst, replacing dst if it exists
+func copyFileContents(source io.Reader, dst string) error {
+	dest, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dest, source); err != nil {
+		dest.Close()
+		return err
+	}
+	return dest.Close()
+}
@@ -0,0 +1,50 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestQuarantineFailedFiles tests that only inputs failing conversion are copied with an error report
+func TestQuarantineFailedFiles(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	quarantineDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "png", "red.png"), filepath.Join(fromDir, "red.png"))
+	if err := os.MkdirAll(filepath.Join(fromDir, "broken"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(fromDir, "broken", "bad.png"), []byte("not a png"), 0644); err != nil {
+		t.Fatalf("Failed to write broken file: %v", err)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetQuarantineDir(quarantineDir)
+
+	if err := filesConverter.PngToData(fromDir, toDir); err == nil {
+		t.Fatal("Expected conversion error for broken file, got nil")
+	}
+
+	quarantined, err := os.ReadFile(filepath.Join(quarantineDir, "broken", "bad.png"))
+	if err != nil {
+		t.Fatalf("Expected broken file in quarantine: %v", err)
+	}
+	if string(quarantined) != "not a png" {
+		t.Errorf("Quarantined file content changed: %q", quarantined)
+	}
+
+	report, err := os.ReadFile(filepath.Join(quarantineDir, "broken", "bad.png"+quarantineReportSuffix))
+	if err != nil {
+		t.Fatalf("Expected error report in quarantine: %v", err)
+	}
+	if !strings.Contains(string(report), "bad.png") {
+		t.Errorf("Error report does not mention the failed file:\n%s", report)
+	}
+
+	if _, err := os.Stat(filepath.Join(quarantineDir, "red.png")); !os.IsNotExist(err) {
+		t.Error("Expected successfully converted file not to be quarantined")
+	}
+}
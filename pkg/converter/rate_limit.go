@@ -0,0 +1,47 @@
+package converter
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// SetRateLimit throttles batch conversions to at most filesPerSecond files and bytesPerSecond input bytes.
+// A value of zero disables the respective limit. Limits are shared by all workers.
+func (f *FilesConverter) SetRateLimit(filesPerSecond float64, bytesPerSecond int64) {
+	f.fileLimiter = nil
+	if filesPerSecond > 0 {
+		f.fileLimiter = rate.NewLimiter(rate.Limit(filesPerSecond), 1)
+	}
+
+	f.byteLimiter = nil
+	if bytesPerSecond > 0 {
+		// Keep the burst small so throughput stays smooth instead of arriving in one-second spikes
+		burst := int64(dataBufferSize)
+		if bytesPerSecond < burst {
+			burst = bytesPerSecond
+		}
+		f.byteLimiter = rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst))
+	}
+}
+
+// rateLimitedReader delays reads so the bytes consumed stay within the limiter's rate
+type rateLimitedReader struct {
+	ctx     context.Context
+	limiter *rate.Limiter
+	r       io.Reader
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	if burst := l.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := l.r.Read(p)
+	if n > 0 {
+		if waitErr := l.limiter.WaitN(l.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,58 @@
+package converter
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRateLimitFiles tests that the files-per-second limit slows down a batch
+func TestRateLimitFiles(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetRateLimit(50, 0)
+
+	start := time.Now()
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	// The first file is let through immediately, every further one waits 20ms
+	minimum := time.Duration(len(testImages)-1) * 20 * time.Millisecond
+	if elapsed := time.Since(start); elapsed < minimum*9/10 {
+		t.Errorf("Expected batch to take at least %v, took %v", minimum, elapsed)
+	}
+}
+
+// TestRateLimitBytes tests that the bytes-per-second limit slows down reading
+func TestRateLimitBytes(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	setupTestFiles(t, fromDir, ".png", "png")
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+
+	var totalBytes int64
+	filesConverter.Progress(func(event ProgressEvent) {
+		totalBytes = event.TotalBytesRead
+	})
+	if err := filesConverter.PngToData(fromDir, t.TempDir()); err != nil {
+		t.Fatalf("PngToData failed: %v", err)
+	}
+
+	// The burst equals the rate for small limits, so reading everything takes about 0.3 seconds
+	filesConverter.SetRateLimit(0, totalBytes*10/13)
+
+	start := time.Now()
+	if err := filesConverter.PngToData(fromDir, toDir); err != nil {
+		t.Fatalf("PngToData failed: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected throttled batch to take at least 150ms, took %v", elapsed)
+	}
+}
@@ -0,0 +1,122 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrConversionExists is returned when registering a conversion under a name that is already taken
+	ErrConversionExists = errors.New("conversion already registered")
+	// ErrUnknownConversion is returned when running a conversion that isn't registered
+	ErrUnknownConversion = errors.New("unknown conversion")
+)
+
+// ConvertFunc converts a single file read from input, writing the result to output
+type ConvertFunc func(input io.Reader, output io.Writer) error
+
+// Conversion is a conversion between two file formats that batch conversions can run by name
+type Conversion struct {
+	Name    string // Command name, such as "data2png"
+	FromExt string // Input extension including the dot, such as ".data"
+	ToExt   string // Output extension including the dot, such as ".png"
+	Convert ConvertFunc
+}
+
+// ConversionRegistry holds the conversions a FilesConverter can run by name. It is safe for concurrent use.
+type ConversionRegistry struct {
+	mu          sync.RWMutex
+	conversions map[string]Conversion
+}
+
+// NewConversionRegistry creates an empty registry
+func NewConversionRegistry() *ConversionRegistry {
+	return &ConversionRegistry{conversions: make(map[string]Conversion)}
+}
+
+// Register adds a conversion, such as one for a format of another game or mod tool. Names are
+// case-insensitive and can't be registered twice.
+func (r *ConversionRegistry) Register(conversion Conversion) error {
+	if conversion.Name == "" || conversion.Convert == nil {
+		return errors.New("conversion needs a name and a convert function")
+	}
+	if !strings.HasPrefix(conversion.FromExt, ".") || !strings.HasPrefix(conversion.ToExt, ".") {
+		return fmt.Errorf("conversion '%s' needs extensions starting with a dot", conversion.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := strings.ToLower(conversion.Name)
+	if _, ok := r.conversions[key]; ok {
+		return fmt.Errorf("%w: %s", ErrConversionExists, conversion.Name)
+	}
+	r.conversions[key] = conversion
+	return nil
+}
+
+// Lookup returns the conversion registered under name
+func (r *ConversionRegistry) Lookup(name string) (Conversion, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conversion, ok := r.conversions[strings.ToLower(name)]
+	return conversion, ok
+}
+
+// Conversions returns every registered conversion, sorted by name
+func (r *ConversionRegistry) Conversions() []Conversion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conversions := make([]Conversion, 0, len(r.conversions))
+	for _, conversion := range r.conversions {
+		conversions = append(conversions, conversion)
+	}
+	sort.Slice(conversions, func(i, j int) bool {
+		return conversions[i].Name < conversions[j].Name
+	})
+	return conversions
+}
+
+// builtinConversions returns the conversions between Celeste's formats done by g. The extended DATA
+// conversions fail with ErrExtendedDisabled unless g has them enabled.
+func builtinConversions(g *GraphicsConverter) []Conversion {
+	return []Conversion{
+		{"data2png", ".data", ".png", g.DataToPng},
+		{"png2data", ".png", ".data", g.PngToData},
+		{"data2cdat", ".data", ".cdat.zst", g.DataToCdat},
+		{"cdat2data", ".cdat.zst", ".data", g.CdatToData},
+		{"png2cdat", ".png", ".cdat.zst", g.PngToCdat},
+		{"cdat2png", ".cdat.zst", ".png", g.CdatToPng},
+		{"data2webp", ".data", ".webp", g.DataToWebp},
+		{"webp2data", ".webp", ".data", g.WebpToData},
+		{"data2xdat", ".data", ".xdat.zst", g.DataToXdat},
+		{"xdat2data", ".xdat.zst", ".data", g.XdatToData},
+		{"png2xdat", ".png", ".xdat.zst", g.PngToXdat},
+		{"xdat2png", ".xdat.zst", ".png", g.XdatToPng},
+	}
+}
+
+// Registry returns the conversions RunConversion can run, starting with the built-in ones, so callers
+// can register their own
+func (f *FilesConverter) Registry() *ConversionRegistry {
+	return f.registry
+}
+
+// RunConversion runs the registered conversion name on all matching files in the source directory,
+// writing them to the target directory
+func (f *FilesConverter) RunConversion(name, fromDir, toDir string) error {
+	return f.RunConversionContext(context.Background(), name, fromDir, toDir)
+}
+
+// RunConversionContext is like RunConversion but stops when ctx is cancelled, removing partially written outputs
+func (f *FilesConverter) RunConversionContext(ctx context.Context, name, fromDir, toDir string) error {
+	conversion, ok := f.registry.Lookup(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownConversion, name)
+	}
+	return f.ConvertContext(ctx, fromDir, toDir, conversion.FromExt, conversion.ToExt, conversion.Convert)
+}
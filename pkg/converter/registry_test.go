@@ -0,0 +1,72 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRegistryBuiltins tests that the built-in conversions are registered and run by name
+func TestRegistryBuiltins(t *testing.T) {
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	conversion, ok := filesConverter.Registry().Lookup("DATA2PNG")
+	if !ok || conversion.FromExt != ".data" || conversion.ToExt != ".png" {
+		t.Fatalf("Expected data2png to be registered, got %+v, %v", conversion, ok)
+	}
+
+	toDir := t.TempDir()
+	if err := filesConverter.RunConversion("data2png", filepath.Join("testdata", "data"), toDir); err != nil {
+		t.Fatalf("RunConversion failed: %v", err)
+	}
+	if !fileExists(filepath.Join(toDir, "red.png")) {
+		t.Error("Expected red.png to be converted")
+	}
+
+	if err := filesConverter.RunConversion("png2xnb", filepath.Join("testdata", "png"), toDir); !errors.Is(err, ErrUnknownConversion) {
+		t.Errorf("Expected ErrUnknownConversion, got %v", err)
+	}
+}
+
+// TestRegistryRegister tests that registered conversions join the batch pipeline and names stay unique
+func TestRegistryRegister(t *testing.T) {
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	upper := Conversion{Name: "txt2upper", FromExt: ".txt", ToExt: ".upper", Convert: func(input io.Reader, output io.Writer) error {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return err
+		}
+		_, err = output.Write(bytes.ToUpper(data))
+		return err
+	}}
+	if err := filesConverter.Registry().Register(upper); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := filesConverter.Registry().Register(upper); !errors.Is(err, ErrConversionExists) {
+		t.Errorf("Expected ErrConversionExists, got %v", err)
+	}
+	if err := filesConverter.Registry().Register(Conversion{Name: "bad", FromExt: "txt", ToExt: ".upper", Convert: upper.Convert}); err == nil {
+		t.Error("Expected an extension without a dot to be rejected")
+	}
+
+	fromDir, toDir := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(fromDir, "note.txt"), []byte("madeline"), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+	if err := filesConverter.RunConversion("txt2upper", fromDir, toDir); err != nil {
+		t.Fatalf("RunConversion failed: %v", err)
+	}
+	if got := string(readFile(t, filepath.Join(toDir, "note.upper"))); got != "MADELINE" {
+		t.Errorf("Expected MADELINE, got %q", got)
+	}
+
+	names := make(map[string]bool)
+	for _, conversion := range filesConverter.Registry().Conversions() {
+		names[conversion.Name] = true
+	}
+	if !names["txt2upper"] || !names["png2data"] {
+		t.Errorf("Expected registered and built-in conversions to be listed, got %v", names)
+	}
+}
@@ -0,0 +1,173 @@
+package converter
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RemapEntry moves one texture to a new path, converting it to the format of the new path's extension
+type RemapEntry struct {
+	From string // Path relative to the source directory, e.g. "Gameplay/old/idle00.data"
+	To   string // Path relative to the target directory, e.g. "characters/player/idle00.png"
+}
+
+// ReadRemapFile reads a CSV mapping file with one "old path,new path" pair per line.
+// Lines starting with '#' and an "old,new" header line are skipped.
+func ReadRemapFile(path string) ([]RemapEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+
+	var entries []RemapEntry
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping file '%s': %w", path, err)
+		}
+		if len(entries) == 0 && strings.EqualFold(record[0], "old") && strings.EqualFold(record[1], "new") {
+			continue
+		}
+		entries = append(entries, RemapEntry{From: strings.TrimSpace(record[0]), To: strings.TrimSpace(record[1])})
+	}
+	return entries, nil
+}
+
+// cleanRemapPath validates a mapping path, which must be a texture below its directory
+func cleanRemapPath(path string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(path))
+	if path == "" || filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("'%s' is not a relative path inside its directory", path)
+	}
+	if textureExtension(cleaned) == "" {
+		return "", fmt.Errorf("'%s' is not a supported texture", path)
+	}
+	return cleaned, nil
+}
+
+// Remap converts the textures listed in mapping from fromDir to their new paths in toDir, converting
+// each to the format of its new extension and copying it unchanged if the format stays the same.
+// Outputs are staged first and only moved into place once every file has converted, so a failed
+// run leaves toDir as it was. Files not in the mapping are ignored.
+func (f *FilesConverter) Remap(fromDir, toDir string, mapping []RemapEntry) error {
+	entries := make([]RemapEntry, len(mapping))
+	sources := make(map[string]bool)
+	targets := make(map[string]string)
+	for i, entry := range mapping {
+		from, err := cleanRemapPath(entry.From)
+		if err != nil {
+			return err
+		}
+		to, err := cleanRemapPath(entry.To)
+		if err != nil {
+			return err
+		}
+		if sources[from] {
+			return fmt.Errorf("'%s' is mapped more than once", entry.From)
+		}
+		if other, ok := targets[strings.ToLower(to)]; ok {
+			return fmt.Errorf("'%s' and '%s' are both mapped to '%s'", other, entry.From, entry.To)
+		}
+		sources[from], targets[strings.ToLower(to)] = true, entry.From
+		entries[i] = RemapEntry{From: from, To: to}
+	}
+
+	if err := os.MkdirAll(toDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", toDir, err)
+	}
+	// Staging inside toDir keeps it on the same filesystem, so outputs are moved with a rename
+	stagingDir, err := os.MkdirTemp(toDir, ".remap-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	f.log.Infof("%d files to remap", len(entries))
+
+	failures := make([]error, len(entries))
+	indexes := make(chan int, len(entries))
+	for i := range entries {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < f.maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				entry := entries[i]
+				f.log.Infof("[%d/%d] %s -> %s", i+1, len(entries), entry.From, entry.To)
+				err := f.remapFile(filepath.Join(fromDir, entry.From), filepath.Join(stagingDir, entry.To))
+				if err != nil {
+					failures[i] = fmt.Errorf("failed to remap '%s': %w", entry.From, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(failures...); err != nil {
+		return fmt.Errorf("nothing was written to '%s':\n%w", toDir, err)
+	}
+
+	for _, entry := range entries {
+		outputPath := filepath.Join(toDir, entry.To)
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(outputPath), err)
+		}
+		if err := os.Rename(filepath.Join(stagingDir, entry.To), outputPath); err != nil {
+			return fmt.Errorf("failed to move '%s' into place: %w", entry.To, err)
+		}
+	}
+	return nil
+}
+
+// remapFile converts or copies a single texture to outputPath
+func (f *FilesConverter) remapFile(inputPath, outputPath string) error {
+	fromExt, toExt := textureExtension(inputPath), textureExtension(outputPath)
+
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+
+	if fromExt == toExt {
+		_, err = io.Copy(output, input)
+	} else {
+		var img image.Image
+		if img, err = f.graphicsConverter.decodeImage(input, fromExt); err == nil {
+			err = f.graphicsConverter.encodeImage(img, output, toExt)
+		}
+	}
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package converter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRemap tests converting and moving textures according to a mapping file
+func TestRemap(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := filepath.Join(t.TempDir(), "pack")
+	if err := os.MkdirAll(filepath.Join(fromDir, "old"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "old", "red.data"))
+	copyFile(t, filepath.Join("testdata", "png", "blue.png"), filepath.Join(fromDir, "blue.png"))
+	copyFile(t, filepath.Join("testdata", "png", "green.png"), filepath.Join(fromDir, "green.png"))
+
+	mapPath := filepath.Join(t.TempDir(), "rename.csv")
+	mapping := "old,new\n# Player sprites\nold/red.data,characters/player/red.png\nblue.png, blue/blue.data\ngreen.png,green.png\n"
+	if err := os.WriteFile(mapPath, []byte(mapping), 0644); err != nil {
+		t.Fatalf("Failed to write mapping: %v", err)
+	}
+	entries, err := ReadRemapFile(mapPath)
+	if err != nil {
+		t.Fatalf("ReadRemapFile failed: %v", err)
+	}
+	if len(entries) != 3 || entries[1] != (RemapEntry{From: "blue.png", To: "blue/blue.data"}) {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	if err := filesConverter.Remap(fromDir, toDir, entries); err != nil {
+		t.Fatalf("Remap failed: %v", err)
+	}
+
+	graphicsConverter := NewGraphicsConverter()
+	assertImageEquals(t, bytesToImage(t, dataToPngBytes(t, graphicsConverter, readTestResource(t, filepath.Join("data", "red.data")))),
+		bytesToImage(t, readFile(t, filepath.Join(toDir, "characters", "player", "red.png"))), 0)
+	if !bytes.Equal(readFile(t, filepath.Join(toDir, "blue", "blue.data")),
+		pngToDataBytes(t, graphicsConverter, readTestResource(t, filepath.Join("png", "blue.png")))) {
+		t.Error("Remapped blue.data doesn't match direct conversion")
+	}
+	if !bytes.Equal(readFile(t, filepath.Join(toDir, "green.png")), readTestResource(t, filepath.Join("png", "green.png"))) {
+		t.Error("Expected green.png to be copied unchanged")
+	}
+
+	dirEntries, err := os.ReadDir(toDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	for _, entry := range dirEntries {
+		if strings.HasPrefix(entry.Name(), ".remap-") {
+			t.Errorf("Staging directory %s was left behind", entry.Name())
+		}
+	}
+}
+
+// TestRemapFailureWritesNothing tests that a failing file keeps every other output out of the target
+func TestRemapFailureWritesNothing(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "png", "blue.png"), filepath.Join(fromDir, "blue.png"))
+	if err := os.WriteFile(filepath.Join(fromDir, "broken.png"), []byte("not a png"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	err := filesConverter.Remap(fromDir, toDir, []RemapEntry{
+		{From: "blue.png", To: "blue.data"},
+		{From: "broken.png", To: "broken.data"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "broken.png") {
+		t.Fatalf("Expected broken.png to fail, got %v", err)
+	}
+	if dirEntries, _ := os.ReadDir(toDir); len(dirEntries) != 0 {
+		t.Errorf("Expected an empty target directory, found %d entries", len(dirEntries))
+	}
+
+	for _, mapping := range [][]RemapEntry{
+		{{From: "../blue.png", To: "blue.data"}},
+		{{From: "blue.png", To: "blue.txt"}},
+		{{From: "blue.png", To: "a.data"}, {From: "blue.png", To: "b.data"}},
+		{{From: "blue.png", To: "same.data"}, {From: "broken.png", To: "Same.data"}},
+	} {
+		if err := filesConverter.Remap(fromDir, toDir, mapping); err == nil {
+			t.Errorf("Expected invalid mapping %+v to be rejected", mapping)
+		}
+	}
+}
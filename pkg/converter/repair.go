@@ -0,0 +1,238 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DataRepair describes what RepairData fixed in a DATA stream
+type DataRepair struct {
+	AlphaFlag     int32 `json:"alphaFlag,omitempty"`     // Invalid alpha flag that was replaced by 1
+	MissingPixels int64 `json:"missingPixels,omitempty"` // Pixels past the end of the stream, padded
+	ClampedPixels int64 `json:"clampedPixels,omitempty"` // Pixels of the last run past the end of the image, dropped
+	TrailingBytes int64 `json:"trailingBytes,omitempty"` // Bytes after the last pixel, dropped
+}
+
+// Repaired reports whether anything was fixed
+func (r DataRepair) Repaired() bool {
+	return r != DataRepair{}
+}
+
+// String lists the fixes, "nothing to repair" if there are none
+func (r DataRepair) String() string {
+	var fixes []string
+	if r.AlphaFlag != 0 {
+		fixes = append(fixes, fmt.Sprintf("replaced alpha flag %d by 1", r.AlphaFlag))
+	}
+	if r.MissingPixels > 0 {
+		fixes = append(fixes, fmt.Sprintf("padded %d missing pixels", r.MissingPixels))
+	}
+	if r.ClampedPixels > 0 {
+		fixes = append(fixes, fmt.Sprintf("clamped the last run by %d pixels", r.ClampedPixels))
+	}
+	if r.TrailingBytes > 0 {
+		fixes = append(fixes, fmt.Sprintf("dropped %d trailing bytes", r.TrailingBytes))
+	}
+	if len(fixes) == 0 {
+		return "nothing to repair"
+	}
+	return strings.Join(fixes, ", ")
+}
+
+// RepairData copies a DATA stream to output as a well-formed one: runs are copied byte for byte, the
+// run past the last pixel is clamped, pixels missing at the end are padded with transparent runs (or
+// black without alpha), a partial last run and trailing bytes are dropped, and alpha flags other than
+// 0 or 1 become 1, as lenient decoding reads them. The header keeps its byte order. Streams with invalid dimensions or an incomplete
+// header can't be repaired.
+func (g *GraphicsConverter) RepairData(input io.Reader, output io.Writer) (DataRepair, error) {
+	var repair DataRepair
+	r := getDataReader(input)
+	defer putDataReader(r)
+
+	header, order, err := g.readHeader(r)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return repair, fmt.Errorf("%w: incomplete header", ErrTruncatedData)
+		}
+		return repair, err
+	}
+	if header.Width <= 0 || header.Height <= 0 || int(header.Width) > g.maxDimension || int(header.Height) > g.maxDimension {
+		return repair, fmt.Errorf("%w: %dx%d", ErrInvalidDimensions, header.Width, header.Height)
+	}
+	if header.AlphaFlag != 0 && header.AlphaFlag != 1 {
+		repair.AlphaFlag = header.AlphaFlag
+		header.AlphaFlag = 1
+	}
+
+	w := getDataWriter(output)
+	defer putDataWriter(w)
+	if err := binary.Write(w, order, header); err != nil {
+		return repair, err
+	}
+
+	total := int64(header.Width) * int64(header.Height)
+	hasAlpha := header.HasAlpha()
+	var run [5]byte
+	i := int64(0)
+	for i < total {
+		n, err := readRun(r, run[:], hasAlpha)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break // The stream ends here, possibly within a run
+		}
+		if err != nil {
+			return repair, err
+		}
+
+		count := int64(run[0])
+		if count == 0 {
+			count = 256
+		}
+		if count > total-i {
+			repair.ClampedPixels = count - (total - i)
+			count = total - i
+			run[0] = byte(count) // Less than 256, so never 0
+		}
+		if _, err := w.Write(run[:n]); err != nil {
+			return repair, err
+		}
+		i += count
+	}
+
+	// Transparent, or black without alpha, like lenient decoding leaves them
+	repair.MissingPixels = total - i
+	for left := repair.MissingPixels; left > 0; {
+		count := min(left, int64(g.maxRunLength))
+		padding := []byte{byte(count), 0, 0, 0}
+		if hasAlpha {
+			padding = padding[:2]
+		}
+		if _, err := w.Write(padding); err != nil {
+			return repair, err
+		}
+		left -= count
+	}
+
+	if repair.MissingPixels == 0 {
+		if repair.TrailingBytes, err = io.Copy(io.Discard, r); err != nil {
+			return repair, err
+		}
+	}
+	return repair, w.Flush()
+}
+
+// readRun reads a single run into run, returning its length in bytes. A run ending early returns
+// io.ErrUnexpectedEOF, no run at all io.EOF.
+func readRun(r *bufio.Reader, run []byte, hasAlpha bool) (int, error) {
+	if _, err := io.ReadFull(r, run[:1]); err != nil {
+		return 0, err
+	}
+	n := 1
+	visible := true
+	if hasAlpha {
+		if _, err := io.ReadFull(r, run[1:2]); err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		n, visible = 2, run[1] != 0
+	}
+	if visible {
+		if _, err := io.ReadFull(r, run[n:n+3]); err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		n += 3
+	}
+	return n, nil
+}
+
+// RepairResult is the outcome of repairing a single DATA file
+type RepairResult struct {
+	Path   string // Relative to the source directory
+	Repair DataRepair
+	Err    error // Set when the file couldn't be repaired, in which case nothing was written
+}
+
+// Repair writes a well-formed copy of every .data file below fromDir to the same path below toDir,
+// with RepairData. Files that need no repair are copied unchanged, so toDir ends up complete, and
+// toDir may be fromDir to repair in place. Results are sorted by path; the returned error is only set
+// if fromDir can't be scanned.
+func (f *FilesConverter) Repair(fromDir, toDir string) ([]RepairResult, error) {
+	var files []string
+	err := filepath.WalkDir(fromDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && textureExtension(path) == ".data" {
+			relPath, err := filepath.Rel(fromDir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	f.log.Infof("%d files to repair", len(files))
+
+	results := make([]RepairResult, len(files))
+	indexes := make(chan int, len(files))
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < f.maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = f.repairFile(fromDir, toDir, files[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+	return results, nil
+}
+
+// repairFile repairs a single file, reading it entirely before writing so it can be replaced in place
+func (f *FilesConverter) repairFile(fromDir, toDir, relPath string) RepairResult {
+	result := RepairResult{Path: filepath.ToSlash(relPath)}
+	data, err := os.ReadFile(filepath.Join(fromDir, relPath))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	var repaired bytes.Buffer
+	if result.Repair, err = f.graphicsConverter.RepairData(bytes.NewReader(data), &repaired); err != nil {
+		result.Err = err
+		return result
+	}
+	if result.Repair.Repaired() {
+		f.log.Infof("Repaired %s: %v", relPath, result.Repair)
+	}
+
+	outputPath := filepath.Join(toDir, relPath)
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		result.Err = fmt.Errorf("failed to create output directory: %w", err)
+		return result
+	}
+	if err := os.WriteFile(outputPath, repaired.Bytes(), 0644); err != nil {
+		result.Err = fmt.Errorf("failed to write '%s': %w", outputPath, err)
+	}
+	return result
+}
@@ -0,0 +1,106 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRepairData tests that every recoverable kind of corruption is fixed into a valid stream
+func TestRepairData(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+		fix  DataRepair
+	}{
+		{"valid", dataStream(2, 2, 1, 3, 255, 1, 2, 3, 1, 0), dataStream(2, 2, 1, 3, 255, 1, 2, 3, 1, 0), DataRepair{}},
+		{"missing runs", dataStream(2, 2, 1, 3, 0), dataStream(2, 2, 1, 3, 0, 1, 0), DataRepair{MissingPixels: 1}},
+		{"missing runs without alpha", dataStream(2, 2, 0, 1, 1, 2, 3), dataStream(2, 2, 0, 1, 1, 2, 3, 3, 0, 0, 0), DataRepair{MissingPixels: 3}},
+		{"cut in a run", dataStream(2, 2, 1, 1, 0, 3, 255, 1), dataStream(2, 2, 1, 1, 0, 3, 0), DataRepair{MissingPixels: 3}},
+		{"overrun", dataStream(2, 2, 1, 3, 0, 5, 255, 1, 2, 3), dataStream(2, 2, 1, 3, 0, 1, 255, 1, 2, 3), DataRepair{ClampedPixels: 4}},
+		{"run of 256 overrun", dataStream(2, 2, 0, 0, 1, 2, 3), dataStream(2, 2, 0, 4, 1, 2, 3), DataRepair{ClampedPixels: 252}},
+		{"trailing bytes", dataStream(2, 2, 0, 4, 1, 2, 3, 9, 9), dataStream(2, 2, 0, 4, 1, 2, 3), DataRepair{TrailingBytes: 2}},
+		{"alpha flag", dataStream(2, 2, 7, 4, 0), dataStream(2, 2, 1, 4, 0), DataRepair{AlphaFlag: 7}},
+	}
+
+	gc := NewGraphicsConverter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			fix, err := gc.RepairData(bytes.NewReader(tt.data), &output)
+			if err != nil {
+				t.Fatalf("RepairData failed: %v", err)
+			}
+			if fix != tt.fix || fix.Repaired() != (tt.fix != DataRepair{}) {
+				t.Errorf("Expected fixes %+v, got %+v", tt.fix, fix)
+			}
+			if !bytes.Equal(output.Bytes(), tt.want) {
+				t.Errorf("Expected % x, got % x", tt.want, output.Bytes())
+			}
+			if _, err := gc.ValidateData(&output); err != nil {
+				t.Errorf("Expected a valid stream, got %v", err)
+			}
+		})
+	}
+
+	// Padding is split into runs no longer than the maximum
+	gc.SetMaxRunLength(100)
+	var output bytes.Buffer
+	fix, err := gc.RepairData(bytes.NewReader(dataStream(16, 16, 1)), &output)
+	if err != nil || fix.MissingPixels != 256 {
+		t.Fatalf("Expected 256 padded pixels, got %+v, %v", fix, err)
+	}
+	if want := dataStream(16, 16, 1, 100, 0, 100, 0, 56, 0); !bytes.Equal(output.Bytes(), want) {
+		t.Errorf("Expected % x, got % x", want, output.Bytes())
+	}
+
+	for _, data := range [][]byte{dataStream(0, 2, 1), {2, 0, 0, 0}} {
+		if _, err := gc.RepairData(bytes.NewReader(data), &bytes.Buffer{}); !errors.Is(err, ErrInvalidDimensions) && !errors.Is(err, ErrTruncatedData) {
+			t.Errorf("Expected an unrepairable header to fail, got %v", err)
+		}
+	}
+}
+
+// TestRepair tests repairing a directory in place, leaving unrepairable files alone
+func TestRepair(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		"ok.data":        dataStream(2, 2, 0, 4, 1, 2, 3),
+		"sub/short.data": dataStream(2, 2, 1, 3, 0),
+		"broken.data":    {1, 2},
+		"ignored.png":    {1, 2, 3},
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	results, err := NewFilesConverter(NewGraphicsConverter()).Repair(dir, dir)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if len(results) != 3 || results[0].Path != "broken.data" || results[2].Path != "sub/short.data" {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	if results[0].Err == nil || results[1].Err != nil || results[1].Repair.Repaired() || results[2].Repair.MissingPixels != 1 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+
+	repaired, err := os.ReadFile(filepath.Join(dir, "sub", "short.data"))
+	if err != nil {
+		t.Fatalf("Failed to read repaired file: %v", err)
+	}
+	if want := dataStream(2, 2, 1, 3, 0, 1, 0); !bytes.Equal(repaired, want) {
+		t.Errorf("Expected % x, got % x", want, repaired)
+	}
+	if broken, _ := os.ReadFile(filepath.Join(dir, "broken.data")); !bytes.Equal(broken, []byte{1, 2}) {
+		t.Error("Expected an unrepairable file to be left alone")
+	}
+}
@@ -0,0 +1,134 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RunReport is a structured record of a whole run, for CI pipelines archiving them to follow the
+// health of an asset pipeline over time: the options used, every file with its status, duration and
+// sizes, the warnings logged, such as recovered truncated inputs, and the totals. Register Record as a
+// progress hook and log through Logger to fill it.
+type RunReport struct {
+	Converter string            `json:"converter"`
+	Version   string            `json:"version"`
+	Command   string            `json:"command"`
+	Status    string            `json:"status"`            // As passed to WriteFile, such as ok, partial or interrupted
+	Options   map[string]string `json:"options,omitempty"` // Configuration of the run
+	Started   time.Time         `json:"started"`
+	Files     []ReportedFile    `json:"files"`
+	Warnings  []string          `json:"warnings"`
+	Totals    RunSummary        `json:"totals"`
+
+	mu    sync.Mutex
+	stats *RunStats
+}
+
+// ReportedFile is a file of a RunReport
+type ReportedFile struct {
+	Input           string  `json:"input"`
+	Output          string  `json:"output,omitempty"`
+	Status          string  `json:"status"` // converted, failed or interrupted
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	BytesRead       int64   `json:"bytesRead"`
+	BytesWritten    int64   `json:"bytesWritten,omitempty"`
+}
+
+// NewRunReport creates the report of a run of command with options that started at start
+func NewRunReport(command string, options map[string]string, start time.Time) *RunReport {
+	return &RunReport{
+		Converter: converterName,
+		Version:   Version,
+		Command:   command,
+		Options:   options,
+		Started:   start.UTC(),
+		Files:     []ReportedFile{},
+		Warnings:  []string{},
+		stats:     NewRunStats(start),
+	}
+}
+
+// Record adds a finished or failed file from a progress event; other events are ignored
+func (r *RunReport) Record(event ProgressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Record(event)
+	file := ReportedFile{Input: event.InputPath, Output: event.OutputPath, BytesRead: event.BytesRead}
+	switch {
+	case event.Type == FileFinished:
+		file.Status = "converted"
+		file.DurationSeconds = event.Duration.Seconds()
+		file.BytesWritten = event.BytesWritten
+	case event.Type == FileFailed && errors.Is(event.Err, context.Canceled):
+		file.Status = "interrupted"
+	case event.Type == FileFailed:
+		file.Status = "failed"
+		file.Error = event.Err.Error()
+	default:
+		return
+	}
+	r.Files = append(r.Files, file)
+}
+
+// Logger returns a Logger passing every message on to next and recording warnings in the report
+func (r *RunReport) Logger(next Logger) Logger {
+	if next == nil {
+		next = NopLogger{}
+	}
+	return reportLogger{next, r}
+}
+
+// addWarning records a warning message
+func (r *RunReport) addWarning(message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, message)
+}
+
+// WriteFile writes the report as indented JSON with the run's status and totals so far, files sorted
+// by input path
+func (r *RunReport) WriteFile(path, status string) error {
+	r.mu.Lock()
+	r.Status = status
+	r.Totals = r.stats.Summary()
+	sort.SliceStable(r.Files, func(i, j int) bool { return r.Files[i].Input < r.Files[j].Input })
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write run report '%s': %w", path, err)
+	}
+	return nil
+}
+
+// reportLogger records the warnings logged through it in a RunReport
+type reportLogger struct {
+	next   Logger
+	report *RunReport
+}
+
+func (l reportLogger) Debugf(format string, args ...any) { l.next.Debugf(format, args...) }
+func (l reportLogger) Infof(format string, args ...any)  { l.next.Infof(format, args...) }
+func (l reportLogger) Errorf(format string, args ...any) { l.next.Errorf(format, args...) }
+
+func (l reportLogger) Warnf(format string, args ...any) {
+	l.report.addWarning(fmt.Sprintf(format, args...))
+	l.next.Warnf(format, args...)
+}
+
+func (l reportLogger) LogFields(level LogLevel, message string, fields map[string]any) {
+	if level == LevelWarn {
+		l.report.addWarning(message)
+	}
+	logFields(l.next, level, message, fields)
+}
@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRunReport tests that a report records every file, the warnings logged and the totals
+func TestRunReport(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+	data := readTestResource(t, filepath.Join("data", "multi-color.data"))
+	if err := os.WriteFile(filepath.Join(fromDir, "truncated.data"), data[:len(data)-8], 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(fromDir, "broken.data"), []byte{1, 2, 3}, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	report := NewRunReport("data2png", map[string]string{"workers": "2"}, time.Now())
+	logger := report.Logger(nil)
+	filesConverter := NewFilesConverter(NewGraphicsConverter(WithGraphicsLogger(logger)),
+		WithLogger(logger), WithWorkers(2), WithContinueOnError(true), WithProgress(report.Record))
+	if err := filesConverter.DataToPng(fromDir, toDir); err == nil {
+		t.Fatal("Expected broken.data to fail")
+	}
+
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	if err := report.WriteFile(reportPath, "partial"); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	var written RunReport
+	if err := json.Unmarshal(readFile(t, reportPath), &written); err != nil {
+		t.Fatalf("Invalid report: %v", err)
+	}
+
+	if written.Command != "data2png" || written.Status != "partial" || written.Options["workers"] != "2" {
+		t.Errorf("Unexpected run details %q, %q, %v", written.Command, written.Status, written.Options)
+	}
+	if len(written.Files) != 3 {
+		t.Fatalf("Expected 3 files, got %+v", written.Files)
+	}
+	broken, red, truncated := written.Files[0], written.Files[1], written.Files[2]
+	if !strings.HasSuffix(broken.Input, "broken.data") || broken.Status != "failed" || broken.Error == "" {
+		t.Errorf("Expected broken.data to have failed, got %+v", broken)
+	}
+	if !strings.HasSuffix(red.Input, "red.data") || red.Status != "converted" || red.BytesRead == 0 || red.BytesWritten == 0 {
+		t.Errorf("Expected red.data to be converted with its sizes, got %+v", red)
+	}
+	if truncated.Status != "converted" {
+		t.Errorf("Expected truncated.data to be recovered, got %+v", truncated)
+	}
+	if len(written.Warnings) == 0 || !strings.Contains(strings.Join(written.Warnings, "\n"), "end of file") {
+		t.Errorf("Expected the truncated input's warning, got %q", written.Warnings)
+	}
+	if written.Totals.Converted != 2 || written.Totals.Failed != 1 {
+		t.Errorf("Expected 2 converted and 1 failed in the totals, got %+v", written.Totals)
+	}
+}
@@ -0,0 +1,175 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// dataHeaderSize is the size of the DataHeader at the start of every DATA texture
+const dataHeaderSize = 12
+
+// taskMetrics are the sizes measured while converting a single file
+type taskMetrics struct {
+	bytesRead    int64
+	bytesWritten int64
+	dataBytes    int64 // Size of the DATA side of the conversion, 0 if neither side is DATA
+	pixelBytes   int64 // Uncompressed RGBA size of that DATA texture
+}
+
+// newTaskMetrics collects the metrics of a conversion from its input reader and output writer
+func newTaskMetrics(reader *contextReader, output *countingWriter) taskMetrics {
+	metrics := taskMetrics{bytesRead: reader.n, bytesWritten: output.n}
+	switch {
+	case reader.head != nil:
+		metrics.dataBytes, metrics.pixelBytes = reader.n, reader.head.pixelBytes()
+	case output.head != nil:
+		metrics.dataBytes, metrics.pixelBytes = output.n, output.head.pixelBytes()
+	}
+	if metrics.pixelBytes == 0 {
+		metrics.dataBytes = 0
+	}
+	return metrics
+}
+
+// dataHeaderRecorder keeps the first bytes of a DATA stream to read its size from afterwards
+type dataHeaderRecorder struct {
+	header [dataHeaderSize]byte
+	n      int
+}
+
+// record keeps the part of p that still belongs to the header
+func (r *dataHeaderRecorder) record(p []byte) {
+	r.n += copy(r.header[r.n:], p)
+}
+
+// pixelBytes returns the uncompressed RGBA size of the texture, 0 if the header is incomplete or invalid
+func (r *dataHeaderRecorder) pixelBytes() int64 {
+	if r.n < dataHeaderSize {
+		return 0
+	}
+	header := parseDataHeader(r.header[:], dataHeaderByteOrder(r.header[:], EndianAuto, DefaultMaxDimension))
+	if header.Width <= 0 || header.Height <= 0 {
+		return 0
+	}
+	return int64(header.Width) * int64(header.Height) * 4
+}
+
+// countingWriter counts the bytes written to an output, keeping its DATA header if head is set
+type countingWriter struct {
+	w    io.Writer
+	n    int64
+	head *dataHeaderRecorder
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	if c.head != nil {
+		c.head.record(p[:n])
+	}
+	return n, err
+}
+
+// RunStats collects the statistics of every batch of a run. Register Record as a progress hook to
+// feed it; like the hook, it isn't safe for concurrent use.
+type RunStats struct {
+	start     time.Time
+	durations []time.Duration
+	summary   RunSummary
+}
+
+// RunSummary holds the totals of a run
+type RunSummary struct {
+	Converted      int     `json:"converted"`
+	Failed         int     `json:"failed"`
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+	FileP50Seconds float64 `json:"fileP50Seconds"` // Median time spent converting a file
+	FileP95Seconds float64 `json:"fileP95Seconds"`
+	BytesRead      int64   `json:"bytesRead"`
+	BytesWritten   int64   `json:"bytesWritten"`
+	// DATA textures read or written and their uncompressed RGBA size, whose ratio is the RLE compression
+	DataBytes  int64 `json:"dataBytes"`
+	PixelBytes int64 `json:"pixelBytes"`
+}
+
+// NewRunStats creates statistics for a run that started at start
+func NewRunStats(start time.Time) *RunStats {
+	return &RunStats{start: start}
+}
+
+// Record adds a progress event to the statistics
+func (s *RunStats) Record(event ProgressEvent) {
+	switch event.Type {
+	case FileFinished:
+		s.summary.Converted++
+		s.summary.BytesRead += event.BytesRead
+		s.summary.BytesWritten += event.BytesWritten
+		s.summary.DataBytes += event.DataBytes
+		s.summary.PixelBytes += event.PixelBytes
+		s.durations = append(s.durations, event.Duration)
+	case FileFailed:
+		s.summary.Failed++
+		s.summary.BytesRead += event.BytesRead
+	}
+}
+
+// Summary returns the totals so far, timing the run until now
+func (s *RunStats) Summary() RunSummary {
+	summary := s.summary
+	summary.ElapsedSeconds = time.Since(s.start).Seconds()
+	durations := slices.Clone(s.durations)
+	slices.Sort(durations)
+	summary.FileP50Seconds = percentile(durations, 50).Seconds()
+	summary.FileP95Seconds = percentile(durations, 95).Seconds()
+	return summary
+}
+
+// percentile returns the nearest-rank percentile p of sorted durations, 0 if there are none
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (len(sorted)*p + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// CompressionRatio returns how many times smaller the DATA textures are than their pixels, 0 if
+// no DATA textures were converted
+func (s RunSummary) CompressionRatio() float64 {
+	if s.DataBytes == 0 {
+		return 0
+	}
+	return float64(s.PixelBytes) / float64(s.DataBytes)
+}
+
+// String formats the summary as a few lines of text
+func (s RunSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Files:    %d converted, %d failed in %v\n", s.Converted, s.Failed,
+		time.Duration(s.ElapsedSeconds*float64(time.Second)).Round(time.Millisecond))
+	if s.Converted > 0 {
+		fmt.Fprintf(&b, "Per file: p50 %v, p95 %v\n", roundSeconds(s.FileP50Seconds), roundSeconds(s.FileP95Seconds))
+	}
+	fmt.Fprintf(&b, "Size:     %s read, %s written\n", formatMB(s.BytesRead), formatMB(s.BytesWritten))
+	if ratio := s.CompressionRatio(); ratio > 0 {
+		fmt.Fprintf(&b, "RLE:      %s of pixels in %s of DATA, %.2f:1\n", formatMB(s.PixelBytes), formatMB(s.DataBytes), ratio)
+	}
+	return b.String()
+}
+
+// roundSeconds turns seconds into a duration rounded for display
+func roundSeconds(seconds float64) time.Duration {
+	d := time.Duration(seconds * float64(time.Second))
+	if d >= time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+// formatMB formats a byte count in megabytes, like the progress bar's throughput
+func formatMB(n int64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/1e6)
+}
@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// treeSize returns the total size of the files with ext in dir and the RGBA size of the DATA ones
+func treeSize(t *testing.T, dir, ext string) (size, pixelBytes int64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+		file, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", entry.Name(), err)
+		}
+		info, _ := file.Stat()
+		size += info.Size()
+		if ext == ".data" {
+			config, err := DecodeDataConfig(file)
+			if err != nil {
+				t.Fatalf("Failed to read header of %s: %v", entry.Name(), err)
+			}
+			pixelBytes += int64(config.Width) * int64(config.Height) * 4
+		}
+		file.Close()
+	}
+	return size, pixelBytes
+}
+
+// TestRunStats tests the sizes and timings collected over a run, with DATA on either side
+func TestRunStats(t *testing.T) {
+	fromDir := t.TempDir()
+	pngDir := t.TempDir()
+	dataDir := t.TempDir()
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	stats := NewRunStats(time.Now())
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithProgress(stats.Record))
+	if err := filesConverter.DataToPng(fromDir, pngDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	summary := stats.Summary()
+	dataSize, pixelBytes := treeSize(t, fromDir, ".data")
+	pngSize, _ := treeSize(t, pngDir, ".png")
+	if summary.Converted != len(testImages) || summary.Failed != 0 {
+		t.Errorf("Expected %d files converted, got %d and %d failed", len(testImages), summary.Converted, summary.Failed)
+	}
+	if summary.BytesRead != dataSize || summary.BytesWritten != pngSize {
+		t.Errorf("Expected %d bytes read and %d written, got %d and %d", dataSize, pngSize, summary.BytesRead, summary.BytesWritten)
+	}
+	if summary.DataBytes != dataSize || summary.PixelBytes != pixelBytes {
+		t.Errorf("Expected %d DATA bytes holding %d pixel bytes, got %d and %d", dataSize, pixelBytes, summary.DataBytes, summary.PixelBytes)
+	}
+	if summary.FileP50Seconds <= 0 || summary.FileP95Seconds < summary.FileP50Seconds {
+		t.Errorf("Expected 0 < p50 <= p95, got %v and %v", summary.FileP50Seconds, summary.FileP95Seconds)
+	}
+
+	// The same run continued the other way round counts the written DATA files
+	if err := filesConverter.PngToData(pngDir, dataDir); err != nil {
+		t.Fatalf("PngToData failed: %v", err)
+	}
+	writtenSize, writtenPixels := treeSize(t, dataDir, ".data")
+	summary = stats.Summary()
+	if summary.Converted != 2*len(testImages) || summary.DataBytes != dataSize+writtenSize || summary.PixelBytes != pixelBytes+writtenPixels {
+		t.Errorf("Unexpected totals after the second batch: %+v", summary)
+	}
+	if !strings.Contains(summary.String(), "RLE:") {
+		t.Errorf("Expected the compression ratio in the summary, got:\n%s", summary)
+	}
+}
+
+// TestPercentile tests nearest-rank percentiles
+func TestPercentile(t *testing.T) {
+	var durations []time.Duration
+	for i := 1; i <= 20; i++ {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	for _, test := range []struct {
+		durations []time.Duration
+		p         int
+		want      time.Duration
+	}{
+		{durations, 50, 10 * time.Millisecond},
+		{durations, 95, 19 * time.Millisecond},
+		{durations[:1], 95, time.Millisecond},
+		{nil, 50, 0},
+	} {
+		if got := percentile(test.durations, test.p); got != test.want {
+			t.Errorf("p%d of %d durations: expected %v, got %v", test.p, len(test.durations), test.want, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package converter
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Schedule decides the order in which batch conversions hand files to their workers
+type Schedule int
+
+const (
+	// ScheduleInputOrder converts files in directory order
+	ScheduleInputOrder Schedule = iota
+	// ScheduleLargestFirst converts the largest inputs first, so a few huge atlas pages don't end up
+	// on one worker at the end of the run while the others sit idle
+	ScheduleLargestFirst
+)
+
+// scheduleNames maps the names accepted by ParseSchedule to schedules
+var scheduleNames = map[string]Schedule{
+	"input":         ScheduleInputOrder,
+	"largest-first": ScheduleLargestFirst,
+}
+
+// ParseSchedule parses "input" or "largest-first"
+func ParseSchedule(s string) (Schedule, error) {
+	schedule, ok := scheduleNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown schedule '%s', expected input or largest-first", s)
+	}
+	return schedule, nil
+}
+
+// String returns the name of the schedule as accepted by ParseSchedule
+func (s Schedule) String() string {
+	for name, schedule := range scheduleNames {
+		if schedule == s {
+			return name
+		}
+	}
+	return fmt.Sprintf("Schedule(%d)", int(s))
+}
+
+// SetSchedule sets the order in which files are converted, ScheduleInputOrder by default.
+// Progress and failures are still reported with the files' positions in input order.
+func (f *FilesConverter) SetSchedule(schedule Schedule) {
+	f.schedule = schedule
+}
+
+// SetLargeFilePool dedicates workers of the batch's workers to inputs of at least minSize bytes, so
+// small files keep flowing while huge ones are converted. Workers that run out of files of their own
+// size take the other pool's remaining files. A minSize or workers of 0 disables the pool.
+func (f *FilesConverter) SetLargeFilePool(minSize int64, workers int) {
+	if minSize <= 0 || workers <= 0 {
+		f.largeFileSize, f.largeFileWorkers = 0, 0
+		return
+	}
+	f.largeFileSize, f.largeFileWorkers = minSize, workers
+}
+
+// taskQueues holds the queued tasks of a batch, split by size when there is a large file pool
+type taskQueues struct {
+	small        chan ConversionTask
+	large        chan ConversionTask // nil without a large file pool
+	largeWorkers int                 // Number of workers preferring large files
+}
+
+// newTaskQueues orders the tasks by the schedule and queues them for the workers
+func (f *FilesConverter) newTaskQueues(tasks []ConversionTask) *taskQueues {
+	var sizes map[int]int64
+	if f.schedule == ScheduleLargestFirst || f.largeFileSize > 0 {
+		sizes = taskSizes(tasks)
+	}
+	if f.schedule == ScheduleLargestFirst {
+		tasks = slices.Clone(tasks)
+		// Stable, so files of equal size stay in input order
+		slices.SortStableFunc(tasks, func(a, b ConversionTask) int {
+			return cmp.Compare(sizes[b.index], sizes[a.index])
+		})
+	}
+
+	queues := &taskQueues{small: make(chan ConversionTask, len(tasks))}
+	if f.largeFileSize > 0 {
+		queues.large = make(chan ConversionTask, len(tasks))
+		// At least one worker is left to the small files, which the large pool steals if there is only one
+		queues.largeWorkers = max(min(f.largeFileWorkers, f.maxWorkers-1), 0)
+	}
+	for _, task := range tasks {
+		if queues.large != nil && sizes[task.index] >= f.largeFileSize {
+			queues.large <- task
+		} else {
+			queues.small <- task
+		}
+	}
+	close(queues.small) // No more tasks will be added
+	if queues.large != nil {
+		close(queues.large)
+	}
+	return queues
+}
+
+// forWorker yields the tasks worker w converts: its own pool's, then those left in the other pool
+func (q *taskQueues) forWorker(w int) iter.Seq[ConversionTask] {
+	queues := []chan ConversionTask{q.small, q.large}
+	if q.large == nil {
+		queues = queues[:1]
+	} else if w < q.largeWorkers {
+		queues[0], queues[1] = q.large, q.small
+	}
+	return func(yield func(ConversionTask) bool) {
+		for _, queue := range queues {
+			for task := range queue {
+				if !yield(task) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// taskSizes returns the input size of every task by index, 0 for inputs that can't be stat'ed
+func taskSizes(tasks []ConversionTask) map[int]int64 {
+	sizes := make(map[int]int64, len(tasks))
+	for _, task := range tasks {
+		if info, err := fs.Stat(task.source, filepath.ToSlash(task.relPath)); err == nil {
+			sizes[task.index] = info.Size()
+		}
+	}
+	return sizes
+}
@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// testSizedTasks returns tasks reading files of the given sizes from an in-memory filesystem
+func testSizedTasks(sizes ...int) []ConversionTask {
+	source := fstest.MapFS{}
+	tasks := make([]ConversionTask, len(sizes))
+	for i, size := range sizes {
+		name := strings.Repeat("f", i+1) + ".data"
+		source[name] = &fstest.MapFile{Data: make([]byte, size)}
+		tasks[i] = ConversionTask{index: i + 1, relPath: name, source: source}
+	}
+	return tasks
+}
+
+// drain returns the input sizes of the tasks worker w takes, in order
+func drain(queues *taskQueues, w int) []int {
+	var sizes []int
+	for task := range queues.forWorker(w) {
+		sizes = append(sizes, len(task.source.(fstest.MapFS)[task.relPath].Data))
+	}
+	return sizes
+}
+
+// TestSchedule tests the order tasks are handed out in, with and without a large file pool
+func TestSchedule(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		options  []FilesOption
+		worker   int
+		expected []int
+	}{
+		{"input order", nil, 0, []int{10, 1000, 5, 2000}},
+		{"largest first", []FilesOption{WithSchedule(ScheduleLargestFirst)}, 0, []int{2000, 1000, 10, 5}},
+		{"large pool worker", []FilesOption{WithLargeFilePool(100, 1)}, 0, []int{1000, 2000, 10, 5}},
+		{"small pool worker", []FilesOption{WithLargeFilePool(100, 1)}, 1, []int{10, 5, 1000, 2000}},
+		{"both", []FilesOption{WithSchedule(ScheduleLargestFirst), WithLargeFilePool(100, 1)}, 1, []int{10, 5, 2000, 1000}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f := NewFilesConverter(NewGraphicsConverter(), append(test.options, WithWorkers(3))...)
+			got := drain(f.newTaskQueues(testSizedTasks(10, 1000, 5, 2000)), test.worker)
+			if len(got) != len(test.expected) {
+				t.Fatalf("Expected %v, got %v", test.expected, got)
+			}
+			for i := range got {
+				if got[i] != test.expected[i] {
+					t.Fatalf("Expected %v, got %v", test.expected, got)
+				}
+			}
+		})
+	}
+}
+
+// TestScheduleLargestFirst tests that a batch converts its largest inputs first
+func TestScheduleLargestFirst(t *testing.T) {
+	fromDir := t.TempDir()
+	setupTestFiles(t, fromDir, ".data", "data")
+
+	var sizes []int64
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithWorkers(1), WithSchedule(ScheduleLargestFirst),
+		WithProgress(func(event ProgressEvent) {
+			if event.Type == FileStarted {
+				info, err := os.Stat(event.InputPath)
+				if err != nil {
+					t.Errorf("Failed to stat %s: %v", event.InputPath, err)
+					return
+				}
+				sizes = append(sizes, info.Size())
+			}
+		}))
+	if err := filesConverter.DataToPng(fromDir, t.TempDir()); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	if len(sizes) != len(testImages) {
+		t.Fatalf("Expected %d files, got %d", len(testImages), len(sizes))
+	}
+	for i := 1; i < len(sizes); i++ {
+		if sizes[i] > sizes[i-1] {
+			t.Fatalf("Expected inputs largest first, got sizes %v", sizes)
+		}
+	}
+}
+
+// TestParseSchedule tests that schedule names round-trip
+func TestParseSchedule(t *testing.T) {
+	for _, schedule := range []Schedule{ScheduleInputOrder, ScheduleLargestFirst} {
+		parsed, err := ParseSchedule(schedule.String())
+		if err != nil || parsed != schedule {
+			t.Errorf("Expected %v to round-trip, got %v, %v", schedule, parsed, err)
+		}
+	}
+	if _, err := ParseSchedule("random"); err == nil {
+		t.Error("Expected an unknown schedule to be rejected")
+	}
+}
@@ -0,0 +1,233 @@
+package converter
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Layout of a contact sheet cell: the texture sits in a square above its label, with a margin all around
+const (
+	sheetPadding     = 4
+	sheetLabelHeight = 16
+)
+
+// ErrNoTextures is returned when building a contact sheet of a directory without any texture
+var ErrNoTextures = errors.New("no textures found")
+
+// ContactSheetEntry is one texture of a contact sheet
+type ContactSheetEntry struct {
+	Name          string          // Slash-separated path without extension, used as the label
+	Width, Height int             // Size of the texture itself
+	Cell          image.Rectangle // Area of the sheet holding the texture and its label
+}
+
+// ContactSheet is a single image showing every texture of a directory with its name
+type ContactSheet struct {
+	Image   *image.NRGBA
+	Entries []ContactSheetEntry
+}
+
+// ContactSheetBuilder composes texture trees into contact sheets, so artists can browse a whole atlas
+// dump at a glance
+type ContactSheetBuilder struct {
+	graphicsConverter *GraphicsConverter
+	log               Logger
+	columns           int
+	cellSize          int
+	background        color.NRGBA
+	maxWorkers        int
+}
+
+// NewContactSheetBuilder creates a new ContactSheetBuilder instance
+func NewContactSheetBuilder(graphicsConverter *GraphicsConverter) *ContactSheetBuilder {
+	return &ContactSheetBuilder{
+		graphicsConverter: graphicsConverter,
+		log:               DefaultLogger(),
+		columns:           8,
+		cellSize:          128,
+		background:        color.NRGBA{0x20, 0x20, 0x20, 0xff},
+		maxWorkers:        runtime.NumCPU(),
+	}
+}
+
+// SetColumns sets the number of cells per row of the sheet
+func (b *ContactSheetBuilder) SetColumns(columns int) {
+	if columns > 0 {
+		b.columns = columns
+	}
+}
+
+// SetCellSize sets the largest width and height textures are shown with; larger ones are scaled
+// down with nearest-neighbour sampling like gallery thumbnails
+func (b *ContactSheetBuilder) SetCellSize(size int) {
+	if size > 0 {
+		b.cellSize = size
+	}
+}
+
+// SetBackground sets the color behind the textures, which shows through their transparent pixels.
+// Labels are drawn in black or white, whichever stands out against it.
+func (b *ContactSheetBuilder) SetBackground(background color.NRGBA) {
+	b.background = background
+}
+
+// SetMaxWorkers allows overriding the number of textures decoded in parallel
+func (b *ContactSheetBuilder) SetMaxWorkers(workers int) {
+	if workers > 0 {
+		b.maxWorkers = workers
+	}
+}
+
+// WriteFile builds the contact sheet of fromDir and writes it to sheetPath, in the format of its extension
+func (b *ContactSheetBuilder) WriteFile(fromDir, sheetPath string) (*ContactSheet, error) {
+	sheet, err := b.Build(fromDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.graphicsConverter.encodeFile(sheet.Image, sheetPath); err != nil {
+		return nil, err
+	}
+	return sheet, nil
+}
+
+// Build decodes every texture below fromDir and lays them out in name order, labeled with their paths.
+// Textures that fail to decode are logged and left out. The sheet is checked against the graphics
+// converter's image limits before it is allocated.
+func (b *ContactSheetBuilder) Build(fromDir string) (*ContactSheet, error) {
+	var sources []string
+	err := filepath.WalkDir(fromDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && textureExtension(filePath) != "" {
+			relPath, err := filepath.Rel(fromDir, filePath)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	// Only the scaled down textures are kept, so huge atlas pages don't pile up in memory
+	thumbs := make([]image.Image, len(sources))
+	entries := make([]*ContactSheetEntry, len(sources))
+	indexes := make(chan int, len(sources))
+	for i := range sources {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < b.maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				img, err := b.graphicsConverter.decodeFile(filepath.Join(fromDir, sources[i]))
+				if err != nil {
+					b.log.Warnf("Skipping %s: %v", sources[i], err)
+					continue
+				}
+				slashPath := filepath.ToSlash(sources[i])
+				entries[i] = &ContactSheetEntry{
+					Name:   slashPath[:len(slashPath)-len(textureExtension(slashPath))],
+					Width:  img.Bounds().Dx(),
+					Height: img.Bounds().Dy(),
+				}
+				thumbs[i] = thumbnail(img, b.cellSize)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sheet := &ContactSheet{}
+	var cells []image.Image
+	for i, entry := range entries {
+		if entry != nil {
+			sheet.Entries = append(sheet.Entries, *entry)
+			cells = append(cells, thumbs[i])
+		}
+	}
+	if len(cells) == 0 {
+		return nil, fmt.Errorf("%w in '%s'", ErrNoTextures, fromDir)
+	}
+
+	cellWidth := b.cellSize + 2*sheetPadding
+	cellHeight := b.cellSize + sheetLabelHeight + 2*sheetPadding
+	columns := min(b.columns, len(cells))
+	rows := (len(cells) + columns - 1) / columns
+	if err := b.graphicsConverter.checkImageSize(columns*cellWidth, rows*cellHeight, 4); err != nil {
+		return nil, fmt.Errorf("contact sheet of %d textures: %w", len(cells), err)
+	}
+
+	sheet.Image = image.NewNRGBA(image.Rect(0, 0, columns*cellWidth, rows*cellHeight))
+	draw.Draw(sheet.Image, sheet.Image.Bounds(), image.NewUniform(b.background), image.Point{}, draw.Src)
+	labels := &font.Drawer{Dst: sheet.Image, Src: image.NewUniform(labelColor(b.background)), Face: basicfont.Face7x13}
+	for i, thumb := range cells {
+		cell := image.Rect(0, 0, cellWidth, cellHeight).Add(image.Pt(i%columns*cellWidth, i/columns*cellHeight))
+		sheet.Entries[i].Cell = cell
+
+		// Centered in the square above the label
+		bounds := thumb.Bounds()
+		at := cell.Min.Add(image.Pt(sheetPadding+(b.cellSize-bounds.Dx())/2, sheetPadding+(b.cellSize-bounds.Dy())/2))
+		draw.Draw(sheet.Image, bounds.Sub(bounds.Min).Add(at), thumb, bounds.Min, draw.Over)
+
+		label := fitLabel(labels, sheet.Entries[i].Name, b.cellSize)
+		labels.Dot = fixed.P(cell.Min.X+sheetPadding+(b.cellSize-labels.MeasureString(label).Round())/2,
+			cell.Max.Y-sheetPadding-(sheetLabelHeight-basicfont.Face7x13.Ascent)/2)
+		labels.DrawString(label)
+	}
+	return sheet, nil
+}
+
+// labelColor returns black or white, whichever contrasts more with the background
+func labelColor(background color.NRGBA) color.Color {
+	luma := 299*int(background.R) + 587*int(background.G) + 114*int(background.B)
+	if background.A < 128 || luma < 128*1000 {
+		return color.White
+	}
+	return color.Black
+}
+
+// fitLabel shortens name to fit width pixels, keeping its end, which holds the texture's own name
+func fitLabel(drawer *font.Drawer, name string, width int) string {
+	if drawer.MeasureString(name).Round() <= width {
+		return name
+	}
+	for i := range name {
+		if label := "..." + name[i:]; drawer.MeasureString(label).Round() <= width {
+			return label
+		}
+	}
+	return ""
+}
+
+// ParseHexColor parses a color written as #rrggbb or #rrggbbaa, with the # optional
+func ParseHexColor(s string) (color.NRGBA, error) {
+	digits, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || (len(digits) != 3 && len(digits) != 4) {
+		return color.NRGBA{}, fmt.Errorf("invalid color '%s', expected #rrggbb or #rrggbbaa", s)
+	}
+	c := color.NRGBA{digits[0], digits[1], digits[2], 0xff}
+	if len(digits) == 4 {
+		c.A = digits[3]
+	}
+	return c, nil
+}
@@ -0,0 +1,113 @@
+package converter
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestContactSheet tests laying out textures in name order on the background, with their labels
+func TestContactSheet(t *testing.T) {
+	fromDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(fromDir, "player"), 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "data", "big-test.data"), filepath.Join(fromDir, "big-test.data"))
+	copyFile(t, filepath.Join("testdata", "png", "red.png"), filepath.Join(fromDir, "player", "red.png"))
+	copyFile(t, filepath.Join("testdata", "data", "transparent.data"), filepath.Join(fromDir, "player", "transparent.data"))
+	if err := os.WriteFile(filepath.Join(fromDir, "broken.data"), []byte{1, 2}, 0644); err != nil {
+		t.Fatalf("Failed to write broken texture: %v", err)
+	}
+
+	background := color.NRGBA{0, 0, 255, 255}
+	builder := NewContactSheetBuilder(NewGraphicsConverter())
+	builder.SetColumns(2)
+	builder.SetCellSize(32)
+	builder.SetBackground(background)
+	sheetPath := filepath.Join(t.TempDir(), "sheet.png")
+	sheet, err := builder.WriteFile(fromDir, sheetPath)
+	if err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	var names []string
+	for _, entry := range sheet.Entries {
+		names = append(names, entry.Name)
+	}
+	if len(names) != 3 || names[0] != "big-test" || names[1] != "player/red" || names[2] != "player/transparent" {
+		t.Fatalf("Unexpected entries: %v", names)
+	}
+	if sheet.Entries[0].Width != 2048 {
+		t.Errorf("Expected the texture's own width of 2048, got %d", sheet.Entries[0].Width)
+	}
+
+	// Two columns and two rows of 40×56 cells
+	cellWidth, cellHeight := 32+2*sheetPadding, 32+sheetLabelHeight+2*sheetPadding
+	if size := sheet.Image.Bounds().Size(); size != image.Pt(2*cellWidth, 2*cellHeight) {
+		t.Fatalf("Expected a %dx%d sheet, got %v", 2*cellWidth, 2*cellHeight, size)
+	}
+	if want := image.Rect(cellWidth, 0, 2*cellWidth, cellHeight); sheet.Entries[1].Cell != want {
+		t.Errorf("Expected player/red in %v, got %v", want, sheet.Entries[1].Cell)
+	}
+
+	center := sheet.Entries[1].Cell.Min.Add(image.Pt(sheetPadding+16, sheetPadding+16))
+	if got := sheet.Image.NRGBAAt(center.X, center.Y); got != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected the red texture at the center of its cell, got %v", got)
+	}
+	for _, p := range []image.Point{{0, 0}, sheet.Entries[2].Cell.Min.Add(image.Pt(sheetPadding+16, sheetPadding+16))} {
+		if got := sheet.Image.NRGBAAt(p.X, p.Y); got != background {
+			t.Errorf("Expected the background at %v, got %v", p, got)
+		}
+	}
+
+	label := false
+	labelRow := sheet.Entries[1].Cell.Max.Y - sheetPadding - sheetLabelHeight/2
+	for x := sheet.Entries[1].Cell.Min.X; x < sheet.Entries[1].Cell.Max.X; x++ {
+		label = label || sheet.Image.NRGBAAt(x, labelRow) != background
+	}
+	if !label {
+		t.Error("Expected a label below the texture")
+	}
+
+	if _, err := NewGraphicsConverter().decodeFile(sheetPath); err != nil {
+		t.Errorf("Failed to decode the written sheet: %v", err)
+	}
+}
+
+// TestContactSheetLimits tests that empty directories and sheets past the image limits are rejected
+func TestContactSheetLimits(t *testing.T) {
+	if _, err := NewContactSheetBuilder(NewGraphicsConverter()).Build(t.TempDir()); !errors.Is(err, ErrNoTextures) {
+		t.Errorf("Expected ErrNoTextures for an empty directory, got %v", err)
+	}
+
+	fromDir := t.TempDir()
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		copyFile(t, filepath.Join("testdata", "png", "red.png"), filepath.Join(fromDir, name))
+	}
+	builder := NewContactSheetBuilder(NewGraphicsConverter(WithMaxDimension(100)))
+	builder.SetCellSize(64)
+	if _, err := builder.Build(fromDir); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge for a sheet wider than the limit, got %v", err)
+	}
+}
+
+// TestParseHexColor tests parsing background colors
+func TestParseHexColor(t *testing.T) {
+	for input, want := range map[string]color.NRGBA{
+		"#202020":   {0x20, 0x20, 0x20, 0xff},
+		"ff000080":  {0xff, 0, 0, 0x80},
+		"#00000000": {},
+	} {
+		if c, err := ParseHexColor(input); err != nil || c != want {
+			t.Errorf("ParseHexColor(%q): expected %v, got %v, %v", input, want, c, err)
+		}
+	}
+	for _, input := range []string{"", "#fff", "#12345", "red"} {
+		if _, err := ParseHexColor(input); err == nil {
+			t.Errorf("ParseHexColor(%q): expected an error", input)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// zstdMagic starts every zstd frame, including .cdat.zst containers
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// mapSignature starts every Celeste map .bin file: the length-prefixed string "CELESTE MAP"
+var mapSignature = []byte("\x0bCELESTE MAP")
+
+// sniffLen is the number of leading bytes inspected when sniffing a file's format
+const sniffLen = 16
+
+// SetSniff makes batch conversions select inputs by content rather than extension.
+// Every file whose content matches the conversion's input format is converted, whatever its name;
+// everything else is skipped.
+func (f *FilesConverter) SetSniff(enabled bool) {
+	f.sniff = enabled
+}
+
+// SniffFormat returns the extension of the format input's content looks like:
+// ".png", ".cdat.zst", ".bin" for Celeste maps, ".webp" or ".data", and "" if it matches none of them
+func SniffFormat(input io.Reader) (string, error) {
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(input, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, pngSignature):
+		return ".png", nil
+	case bytes.HasPrefix(header, zstdMagic):
+		// Any zstd stream starts like this, so look inside for the container magic
+		decoder, err := zstd.NewReader(io.MultiReader(bytes.NewReader(header), input), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return "", nil
+		}
+		defer decoder.Close()
+		var magic [4]byte
+		if _, err := io.ReadFull(decoder, magic[:]); err == nil && magic == cdatMagic {
+			return ".cdat.zst", nil
+		}
+		return "", nil
+	case bytes.HasPrefix(header, mapSignature):
+		return ".bin", nil
+	case len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WEBP":
+		return ".webp", nil
+	case plausibleDataHeader(header):
+		return ".data", nil
+	}
+	return "", nil
+}
+
+// plausibleDataHeader reports whether header looks like the start of a DATA file:
+// sensible dimensions followed by a boolean alpha flag, little-endian or big-endian
+func plausibleDataHeader(header []byte) bool {
+	if len(header) < 9 {
+		return false
+	}
+	width := int32(binary.LittleEndian.Uint32(header[0:]))
+	height := int32(binary.LittleEndian.Uint32(header[4:]))
+	alpha := header[8]
+	if width > 0 && width <= 8192 && height > 0 && height <= 8192 && alpha <= 1 {
+		return true
+	}
+	if len(header) < dataHeaderSize {
+		return false
+	}
+	big := parseDataHeader(header, binary.BigEndian)
+	return validDimensions(big, 8192) && (big.AlphaFlag == 0 || big.AlphaFlag == 1)
+}
+
+// sniffFile sniffs the format of a file in source
+func sniffFile(source fs.FS, path string) (string, error) {
+	file, err := source.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return SniffFormat(file)
+}
+
+// fileExtension returns a path's extension, recognizing multi-part texture extensions such as .cdat.zst
+func fileExtension(path string) string {
+	if ext := textureExtension(path); ext != "" {
+		return path[len(path)-len(ext):]
+	}
+	return filepath.Ext(path)
+}
+
+// sniffMatches sniffs a file and reports whether its content is in the format named by fromExt
+func (f *FilesConverter) sniffMatches(source fs.FS, path, fromExt string) (bool, error) {
+	format, err := sniffFile(source, path)
+	if err != nil {
+		return false, fmt.Errorf("failed to sniff '%s': %w", path, err)
+	}
+	if !strings.EqualFold(format, fromExt) {
+		f.log.Debugf("Skipping %s: content is not %s", path, formatLabel(fromExt))
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,73 @@
+package converter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSniffFormat tests content-based format detection
+func TestSniffFormat(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+	pngBytes := readTestResource(t, filepath.Join("png", "red.png"))
+
+	var cdat bytes.Buffer
+	if err := graphicsConverter.PngToCdat(bytes.NewReader(pngBytes), &cdat); err != nil {
+		t.Fatalf("PngToCdat failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		content  []byte
+		expected string
+	}{
+		{"png", pngBytes, ".png"},
+		{"data", readTestResource(t, filepath.Join("data", "red.data")), ".data"},
+		{"cdat", cdat.Bytes(), ".cdat.zst"},
+		{"map", []byte("\x0bCELESTE MAP\x05Hello"), ".bin"},
+		{"text", []byte("just some text that is long enough"), ""},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		format, err := SniffFormat(bytes.NewReader(tt.content))
+		if err != nil {
+			t.Fatalf("SniffFormat(%s) failed: %v", tt.name, err)
+		}
+		if format != tt.expected {
+			t.Errorf("SniffFormat(%s) = %q, expected %q", tt.name, format, tt.expected)
+		}
+	}
+}
+
+// TestSniffConversion tests that sniffing converts inputs by content, regardless of their extension
+func TestSniffConversion(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.bin"))
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(fromDir, "blue"))
+	copyFile(t, filepath.Join("testdata", "png", "green.png"), filepath.Join(fromDir, "green.data"))
+	if err := os.WriteFile(filepath.Join(fromDir, "notes.txt"), []byte("not a texture at all"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetSniff(true)
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(toDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 2 || names[0] != "blue.png" || names[1] != "red.png" {
+		t.Errorf("Expected blue.png and red.png, got %v", names)
+	}
+}
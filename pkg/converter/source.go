@@ -0,0 +1,48 @@
+package converter
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// IsZipArchive reports whether path names a .zip file, such as a packaged Everest mod,
+// that batch conversions read as a source directory
+func IsZipArchive(path string) bool {
+	if !hasZipExtension(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// nopCloser is the io.Closer of sources that need no cleanup
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// openSource returns the files below fromDir, which is either a directory or a .zip archive.
+// The returned closer must be closed once the files are no longer needed.
+func openSource(fromDir string) (fs.FS, io.Closer, error) {
+	if !IsZipArchive(fromDir) {
+		return os.DirFS(fromDir), nopCloser{}, nil
+	}
+
+	archive, err := zip.OpenReader(fromDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open zip archive '%s': %w", fromDir, err)
+	}
+	return archive, archive, nil
+}
+
+// open opens the task's input file from its source
+func (t ConversionTask) open() (fs.File, error) {
+	file, err := t.source.Open(filepath.ToSlash(t.relPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file '%s': %w", t.inputPath, err)
+	}
+	return file, nil
+}
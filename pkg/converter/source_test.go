@@ -0,0 +1,74 @@
+package converter
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestZip creates a zip archive holding the given test resources under their new names
+func writeTestZip(t *testing.T, path string, entries map[string]string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create zip: %v", err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for name, resource := range entries {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s to zip: %v", name, err)
+		}
+		if _, err := w.Write(readTestResource(t, resource)); err != nil {
+			t.Fatalf("Failed to write %s to zip: %v", name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+}
+
+// TestConvertFromZip tests that a zip archive can be used as the source directory
+func TestConvertFromZip(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "MyMod.zip")
+	toDir := t.TempDir()
+
+	writeTestZip(t, zipPath, map[string]string{
+		"Graphics/Atlases/Gameplay/red.data":          filepath.Join("data", "red.data"),
+		"Graphics/Atlases/Gameplay/objects/blue.data": filepath.Join("data", "blue.data"),
+		"everest.yaml": filepath.Join("data", "green.data"),
+	})
+
+	if !IsZipArchive(zipPath) {
+		t.Fatalf("Expected %s to be detected as a zip archive", zipPath)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	if err := filesConverter.DataToPng(zipPath, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	graphicsConverter := NewGraphicsConverter()
+	for _, name := range []string{"red", filepath.Join("objects", "blue")} {
+		outputPath := filepath.Join(toDir, "Graphics", "Atlases", "Gameplay", name+".png")
+		dataBytes := readTestResource(t, filepath.Join("data", filepath.Base(name)+".data"))
+		expected := bytesToImage(t, dataToPngBytes(t, graphicsConverter, dataBytes))
+		actual := bytesToImage(t, readFile(t, outputPath))
+		assertImageEquals(t, expected, actual, 0)
+	}
+
+	if _, err := os.Stat(filepath.Join(toDir, "everest.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected entries without the source extension to be skipped")
+	}
+}
+
+// readFile reads a file or fails the test
+func readFile(t *testing.T, path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return data
+}
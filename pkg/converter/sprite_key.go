@@ -0,0 +1,60 @@
+package converter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// NormalizeSpriteKey turns a sprite path into the key Celeste looks it up by: backslashes become
+// forward slashes, leading "./" and "/" are dropped and the extension of the file name is removed,
+// so "characters\player\idle00.png" becomes "characters/player/idle00"
+func NormalizeSpriteKey(spritePath string) string {
+	key := strings.ReplaceAll(spritePath, "\\", "/")
+	for strings.HasPrefix(key, "./") || strings.HasPrefix(key, "/") {
+		key = strings.TrimPrefix(strings.TrimPrefix(key, "."), "/")
+	}
+	return strings.TrimSuffix(key, path.Ext(key))
+}
+
+// ValidateSpriteKey returns an error if the game can't load a sprite under a normalized key:
+// empty keys or path segments, "." and ".." segments, control characters, and segments starting
+// or ending with whitespace, which Windows drops from file names.
+func ValidateSpriteKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("invalid sprite key '%s': empty", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		switch {
+		case segment == "":
+			return fmt.Errorf("invalid sprite key '%s': empty path segment", key)
+		case segment == "." || segment == "..":
+			return fmt.Errorf("invalid sprite key '%s': relative path segment '%s'", key, segment)
+		case strings.IndexFunc(segment, unicode.IsControl) >= 0:
+			return fmt.Errorf("invalid sprite key '%s': control character", key)
+		case strings.TrimSpace(segment) != segment:
+			return fmt.Errorf("invalid sprite key '%s': segment '%s' starts or ends with whitespace", key, segment)
+		}
+	}
+	return nil
+}
+
+// spriteKeysEqual reports whether two keys name the same sprite. Celeste's atlases look sprites up
+// case-insensitively, and the engine turns backslashes in .meta keys into forward slashes.
+func spriteKeysEqual(a, b string) bool {
+	return strings.EqualFold(strings.ReplaceAll(a, "\\", "/"), strings.ReplaceAll(b, "\\", "/"))
+}
+
+// spriteKeySet detects sprite keys the game would treat as the same sprite
+type spriteKeySet map[string]string // Lowercased key to the first key seen
+
+// add records key, returning an error naming the earlier key if the game can't tell them apart
+func (s spriteKeySet) add(key string) error {
+	folded := strings.ToLower(key)
+	if existing, ok := s[folded]; ok {
+		return fmt.Errorf("sprite key '%s' collides with '%s', keys are case-insensitive", key, existing)
+	}
+	s[folded] = key
+	return nil
+}
@@ -0,0 +1,54 @@
+package converter
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestNormalizeSpriteKey tests that sprite paths become the keys the game uses
+func TestNormalizeSpriteKey(t *testing.T) {
+	tests := map[string]string{
+		"characters/player/idle00.png":      "characters/player/idle00",
+		`characters\player\idle00.png`:      "characters/player/idle00",
+		"./objects/door.png":                "objects/door",
+		"/objects/door":                     "objects/door",
+		"decals/1-forsakencity/sign.v2.png": "decals/1-forsakencity/sign.v2",
+		"dir.name/sprite":                   "dir.name/sprite",
+	}
+	for input, want := range tests {
+		if got := NormalizeSpriteKey(input); got != want {
+			t.Errorf("NormalizeSpriteKey(%q): expected %q, got %q", input, want, got)
+		}
+	}
+}
+
+// TestValidateSpriteKey tests that keys the game can't load are rejected
+func TestValidateSpriteKey(t *testing.T) {
+	for _, key := range []string{"characters/player/idle00", "objects/door", "bg/1-forsakencity/sign.v2"} {
+		if err := ValidateSpriteKey(key); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "objects//door", "objects/door/", "../door", "objects/./door", "objects/do\x00or", "objects/door "} {
+		if err := ValidateSpriteKey(key); err == nil {
+			t.Errorf("Expected %q to be invalid, got nil", key)
+		}
+	}
+
+	if !spriteKeysEqual(`Characters\Player\idle00`, "characters/player/IDLE00") {
+		t.Error("Expected keys differing in case and slashes to be equal")
+	}
+}
+
+// TestAtlasPackerRejectsKeyCollisions tests that sprites differing only in case aren't packed
+func TestAtlasPackerRejectsKeyCollisions(t *testing.T) {
+	fromDir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "png", "red.png"), filepath.Join(fromDir, "Idle.png"))
+	copyFile(t, filepath.Join("testdata", "png", "blue.png"), filepath.Join(fromDir, "idle.png"))
+
+	err := NewAtlasPacker(NewGraphicsConverter()).Pack(fromDir, t.TempDir(), "Gameplay")
+	if err == nil || !strings.Contains(err.Error(), "case-insensitive") {
+		t.Errorf("Expected a key collision error, got %v", err)
+	}
+}
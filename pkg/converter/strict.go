@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors returned for malformed DATA streams in strict mode
+var (
+	ErrTruncatedData    = errors.New("DATA stream ends before the last pixel")
+	ErrOverlongData     = errors.New("DATA run extends past the last pixel")
+	ErrTrailingData     = errors.New("DATA stream has bytes after the last pixel")
+	ErrInvalidAlphaFlag = errors.New("invalid DATA alpha flag")
+)
+
+// SetStrict makes decoding DATA fail on truncated streams, runs past the last pixel, alpha flags other
+// than 0 or 1 and trailing bytes, instead of warning and returning what could be read. Lenient decoding
+// is kept as the default for recovering what is left of damaged assets.
+func (g *GraphicsConverter) SetStrict(enabled bool) {
+	g.strict = enabled
+}
+
+// truncated handles a stream ending after i of total pixels: an error in strict mode, a warning otherwise
+func (g *GraphicsConverter) truncated(i, total int) error {
+	if g.strict {
+		return fmt.Errorf("%w: %d/%d pixels decoded", ErrTruncatedData, i, total)
+	}
+	g.log.Warnf("Reached end of file with %d/%d pixels processed", i, total)
+	return nil
+}
+
+// overlong handles a run of count pixels starting at pixel i running past the last of total pixels
+func (g *GraphicsConverter) overlong(i, count, total int) error {
+	if g.strict {
+		return fmt.Errorf("%w: run of %d pixels at pixel %d of %d", ErrOverlongData, count, i, total)
+	}
+	return nil
+}
+
+// checkAlphaFlag validates the alpha flag of a DATA header in strict mode
+func (g *GraphicsConverter) checkAlphaFlag(flag int32) error {
+	if g.strict && flag != 0 && flag != 1 {
+		return fmt.Errorf("%w: %d", ErrInvalidAlphaFlag, flag)
+	}
+	return nil
+}
+
+// checkTrailing makes sure nothing follows the last run in strict mode
+func (g *GraphicsConverter) checkTrailing(r *bufio.Reader) error {
+	if !g.strict {
+		return nil
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return ErrTrailingData
+	}
+	return nil
+}
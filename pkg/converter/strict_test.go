@@ -0,0 +1,77 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// dataStream builds a raw DATA stream from a header and runs
+func dataStream(width, height, alphaFlag int32, runs ...byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, [3]int32{width, height, alphaFlag})
+	buf.Write(runs)
+	return buf.Bytes()
+}
+
+// TestStrictDecoding tests that strict mode rejects malformed streams that lenient mode decodes
+func TestStrictDecoding(t *testing.T) {
+	valid := dataStream(4, 1, 1, 2, 255, 1, 2, 3, 2, 0)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"valid", valid, nil},
+		{"truncated between runs", valid[:17], ErrTruncatedData},
+		{"truncated within a run", valid[:15], ErrTruncatedData},
+		{"over-long run", dataStream(4, 1, 1, 2, 255, 1, 2, 3, 3, 0), ErrOverlongData},
+		{"trailing bytes", append(append([]byte{}, valid...), 7), ErrTrailingData},
+		{"invalid alpha flag", dataStream(4, 1, 2, 2, 255, 1, 2, 3, 2, 0), ErrInvalidAlphaFlag},
+	}
+
+	for _, workers := range []int{1, 4} {
+		strict := NewGraphicsConverter()
+		strict.SetStrict(true)
+		strict.SetImageWorkers(workers)
+		lenient := NewGraphicsConverter()
+		lenient.SetImageWorkers(workers)
+
+		for _, tt := range tests {
+			_, err := strict.decodeData(bytes.NewReader(tt.data))
+			if tt.expected == nil && err != nil || tt.expected != nil && !errors.Is(err, tt.expected) {
+				t.Errorf("%s, %d workers: expected %v, got %v", tt.name, workers, tt.expected, err)
+			}
+			if tt.name == "truncated within a run" {
+				continue // A partial color is an error either way
+			}
+			if _, err := lenient.decodeData(bytes.NewReader(tt.data)); err != nil {
+				t.Errorf("%s, %d workers: lenient decoding failed: %v", tt.name, workers, err)
+			}
+		}
+	}
+}
+
+// TestStrictDecodingParallel tests strict mode on images large enough to be decoded in bands
+func TestStrictDecodingParallel(t *testing.T) {
+	var data bytes.Buffer
+	if err := NewGraphicsConverter().encodeData(parallelTestImage(), &data); err != nil {
+		t.Fatalf("encodeData failed: %v", err)
+	}
+
+	strict := NewGraphicsConverter()
+	strict.SetStrict(true)
+	strict.SetImageWorkers(4)
+	if _, err := strict.decodeData(bytes.NewReader(data.Bytes())); err != nil {
+		t.Fatalf("Expected a complete stream to decode, got %v", err)
+	}
+	if _, err := strict.decodeData(bytes.NewReader(data.Bytes()[:data.Len()/2])); !errors.Is(err, ErrTruncatedData) {
+		t.Errorf("Expected ErrTruncatedData, got %v", err)
+	}
+	trailing := append(append([]byte{}, data.Bytes()...), 1, 2, 3)
+	if _, err := strict.decodeData(bytes.NewReader(trailing)); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("Expected ErrTrailingData, got %v", err)
+	}
+}
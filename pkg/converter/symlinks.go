@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SetFollowSymlinks makes directory conversions follow symlinks to files and directories, for mod
+// workspaces assembled from symlinks. Off by default, symlinks are skipped with a warning. Symlinks to a
+// directory the walk is already inside are skipped as cycles, and symlinks inside zip archives are never
+// followed.
+func (f *FilesConverter) SetFollowSymlinks(enabled bool) {
+	f.followSymlinks = enabled
+}
+
+// walkSource walks source, the contents of fromDir, like fs.WalkDir, handling symlinks as configured.
+// fromDir is empty for sources that aren't on disk.
+// Followed symlinks are passed to fn as the file or directory they point to, under the symlink's path.
+func (f *FilesConverter) walkSource(source fs.FS, fromDir string, fn fs.WalkDirFunc) error {
+	var walk fs.WalkDirFunc
+	walk = func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return fn(filePath, d, err)
+		}
+		if !f.followSymlinks {
+			f.log.Warnf("Skipping symlink %s: following symlinks is disabled", filePath)
+			return nil
+		}
+		if fromDir == "" {
+			f.log.Warnf("Skipping symlink %s: symlinks in fs.FS sources can't be followed", filePath)
+			return nil
+		}
+		if IsZipArchive(fromDir) {
+			f.log.Warnf("Skipping symlink %s: symlinks in zip archives can't be followed", filePath)
+			return nil
+		}
+
+		info, err := fs.Stat(source, filePath)
+		if err != nil {
+			f.log.Warnf("Skipping broken symlink %s: %v", filePath, err)
+			return nil
+		}
+		if !info.IsDir() {
+			return fn(filePath, fs.FileInfoToDirEntry(info), nil)
+		}
+		if cycle, err := symlinkCycle(fromDir, filePath); err != nil || cycle {
+			f.log.Warnf("Skipping symlink %s: it points to a directory containing it", filePath)
+			return nil
+		}
+		return fs.WalkDir(source, filePath, walk)
+	}
+	return fs.WalkDir(source, ".", walk)
+}
+
+// symlinkCycle reports whether the directory symlink at the slash-separated linkPath below fromDir points
+// to one of the directories the walk passed through to reach it, or to a directory containing one.
+// Following it would lead back to the symlink itself.
+func symlinkCycle(fromDir, linkPath string) (bool, error) {
+	target, err := filepath.EvalSymlinks(filepath.Join(fromDir, filepath.FromSlash(linkPath)))
+	if err != nil {
+		return false, err
+	}
+	for dir := path.Dir(linkPath); ; dir = path.Dir(dir) {
+		realDir, err := filepath.EvalSymlinks(filepath.Join(fromDir, filepath.FromSlash(dir)))
+		if err != nil {
+			return false, err
+		}
+		if isWithin(target, realDir) {
+			return true, nil
+		}
+		if dir == "." {
+			return false, nil
+		}
+	}
+}
+
+// isWithin reports whether path is dir or lies below it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
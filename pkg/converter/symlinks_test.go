@@ -0,0 +1,72 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupSymlinkWorkspace creates a source directory with a symlinked file, a symlinked directory from
+// outside it and a symlink back to the source directory itself
+func setupSymlinkWorkspace(t *testing.T) string {
+	workspace := t.TempDir()
+	fromDir := filepath.Join(workspace, "src")
+	shared := filepath.Join(workspace, "shared")
+	for _, dir := range []string{fromDir, shared} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(fromDir, "red.data"))
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(shared, "blue.data"))
+	copyFile(t, filepath.Join("testdata", "data", "green.data"), filepath.Join(workspace, "green.data"))
+
+	for link, target := range map[string]string{
+		filepath.Join(fromDir, "green.data"): filepath.Join(workspace, "green.data"),
+		filepath.Join(fromDir, "shared"):     shared,
+		filepath.Join(shared, "loop"):        fromDir,
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("Symlinks are not supported: %v", err)
+		}
+	}
+	return fromDir
+}
+
+// TestSymlinksSkipped tests that symlinks are skipped by default
+func TestSymlinksSkipped(t *testing.T) {
+	fromDir := setupSymlinkWorkspace(t)
+	toDir := t.TempDir()
+
+	if err := NewFilesConverter(NewGraphicsConverter()).DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	if !fileExists(filepath.Join(toDir, "red.png")) {
+		t.Error("Expected red.png to be converted")
+	}
+	for _, name := range []string{"green.png", filepath.Join("shared", "blue.png")} {
+		if fileExists(filepath.Join(toDir, name)) {
+			t.Errorf("Expected symlinked %s to be skipped", name)
+		}
+	}
+}
+
+// TestFollowSymlinks tests that symlinked files and directories are converted, stopping at cycles
+func TestFollowSymlinks(t *testing.T) {
+	fromDir := setupSymlinkWorkspace(t)
+	toDir := t.TempDir()
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetFollowSymlinks(true)
+	if err := filesConverter.DataToPng(fromDir, toDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	for _, name := range []string{"red.png", "green.png", filepath.Join("shared", "blue.png")} {
+		if !fileExists(filepath.Join(toDir, name)) {
+			t.Errorf("Expected %s to be converted", name)
+		}
+	}
+	if fileExists(filepath.Join(toDir, "shared", "loop")) {
+		t.Error("Expected the symlink back to the source directory to be skipped")
+	}
+}
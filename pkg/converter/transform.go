@@ -0,0 +1,409 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Transform changes an image between decoding and encoding, e.g. to scale or rotate it
+type Transform interface {
+	// Size returns the size of the image Apply makes from an image of the given size, so the result
+	// can be checked against the limits before it is allocated
+	Size(width, height int) (int, int)
+	// Apply returns the transformed image, anchored at the origin. img must not be modified.
+	Apply(img image.Image) image.Image
+}
+
+// SetTransforms sets the transforms applied in order to every converted image between decoding and
+// encoding, none by default. Transformed images are checked against the same limits as decoded ones.
+// With trimming, images are cropped after the transforms and padded back before them.
+func (g *GraphicsConverter) SetTransforms(transforms ...Transform) {
+	g.transforms = transforms
+}
+
+// applyTransforms runs img through the transforms, returning img itself when there are none
+func (g *GraphicsConverter) applyTransforms(img image.Image) (image.Image, error) {
+	for _, transform := range g.transforms {
+		bounds := img.Bounds()
+		width, height := transform.Size(bounds.Dx(), bounds.Dy())
+		if err := g.checkImageSize(width, height, 4); err != nil {
+			return nil, fmt.Errorf("transformed image: %w", err)
+		}
+		img = transform.Apply(img)
+	}
+	return img, nil
+}
+
+// ScaleFilter selects how pixels are sampled when scaling
+type ScaleFilter int
+
+const (
+	// FilterNearest copies the nearest source pixel, keeping pixel art sharp. Integer upscales repeat
+	// every pixel exactly.
+	FilterNearest ScaleFilter = iota
+	// FilterBox averages the source pixels each output pixel covers, weighted by coverage and alpha,
+	// for smooth downscales
+	FilterBox
+)
+
+// scaleFilters maps the names accepted by ParseScaleFilter to filters
+var scaleFilters = map[string]ScaleFilter{
+	"nearest": FilterNearest,
+	"box":     FilterBox,
+}
+
+// ParseScaleFilter parses a scale filter name: nearest or box
+func ParseScaleFilter(name string) (ScaleFilter, error) {
+	filter, ok := scaleFilters[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown scale filter '%s', expected nearest or box", name)
+	}
+	return filter, nil
+}
+
+// String returns the name of a scale filter
+func (f ScaleFilter) String() string {
+	for name, filter := range scaleFilters {
+		if filter == f {
+			return name
+		}
+	}
+	return fmt.Sprintf("ScaleFilter(%d)", int(f))
+}
+
+// ParseScale parses a scale factor such as "4x", "0.5x" or "2"
+func ParseScale(s string) (float64, error) {
+	factor, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(s), "x"), 64)
+	if err != nil || !(factor > 0) || math.IsInf(factor, 0) {
+		return 0, fmt.Errorf("invalid scale '%s', expected a positive factor such as 4x or 0.5x", s)
+	}
+	return factor, nil
+}
+
+// Scale returns a transform resizing images by factor, rounding their size to whole pixels of at
+// least 1
+func Scale(factor float64, filter ScaleFilter) Transform {
+	return scaleTransform{factor: factor, filter: filter}
+}
+
+type scaleTransform struct {
+	factor float64
+	filter ScaleFilter
+}
+
+func (s scaleTransform) Size(width, height int) (int, int) {
+	return max(int(math.Round(float64(width)*s.factor)), 1), max(int(math.Round(float64(height)*s.factor)), 1)
+}
+
+func (s scaleTransform) Apply(img image.Image) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	width, height := s.Size(srcWidth, srcHeight)
+	if s.filter == FilterBox {
+		return boxScale(img, width, height)
+	}
+	// Sample at the center of every output pixel
+	return remapPixels(img, width, height, func(x, y int) (int, int) {
+		return (2*x + 1) * srcWidth / (2 * width), (2*y + 1) * srcHeight / (2 * height)
+	})
+}
+
+// FlipAxis selects the axis an image is mirrored across
+type FlipAxis int
+
+const (
+	// FlipHorizontal mirrors the image left to right
+	FlipHorizontal FlipAxis = iota
+	// FlipVertical mirrors the image top to bottom
+	FlipVertical
+)
+
+// flipAxes maps the names accepted by ParseFlipAxis to axes
+var flipAxes = map[string]FlipAxis{
+	"horizontal": FlipHorizontal,
+	"vertical":   FlipVertical,
+}
+
+// ParseFlipAxis parses a flip axis: horizontal or vertical
+func ParseFlipAxis(name string) (FlipAxis, error) {
+	axis, ok := flipAxes[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown flip '%s', expected horizontal or vertical", name)
+	}
+	return axis, nil
+}
+
+// String returns the name of a flip axis
+func (a FlipAxis) String() string {
+	for name, axis := range flipAxes {
+		if axis == a {
+			return name
+		}
+	}
+	return fmt.Sprintf("FlipAxis(%d)", int(a))
+}
+
+// Flip returns a transform mirroring images across axis
+func Flip(axis FlipAxis) Transform {
+	return flipTransform{axis: axis}
+}
+
+type flipTransform struct {
+	axis FlipAxis
+}
+
+func (f flipTransform) Size(width, height int) (int, int) {
+	return width, height
+}
+
+func (f flipTransform) Apply(img image.Image) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	return remapPixels(img, width, height, func(x, y int) (int, int) {
+		if f.axis == FlipVertical {
+			return x, height - 1 - y
+		}
+		return width - 1 - x, y
+	})
+}
+
+// Rotation is a clockwise rotation by a multiple of 90 degrees
+type Rotation int
+
+const (
+	// Rotate90 turns images a quarter clockwise, swapping their width and height
+	Rotate90 Rotation = iota
+	// Rotate180 turns images upside down
+	Rotate180
+	// Rotate270 turns images a quarter counterclockwise
+	Rotate270
+)
+
+// rotations maps the names accepted by ParseRotation to rotations
+var rotations = map[string]Rotation{
+	"90":  Rotate90,
+	"180": Rotate180,
+	"270": Rotate270,
+}
+
+// ParseRotation parses a clockwise rotation in degrees: 90, 180 or 270
+func ParseRotation(degrees string) (Rotation, error) {
+	rotation, ok := rotations[degrees]
+	if !ok {
+		return 0, fmt.Errorf("unknown rotation '%s', expected 90, 180 or 270", degrees)
+	}
+	return rotation, nil
+}
+
+// String returns the degrees of a rotation
+func (r Rotation) String() string {
+	for name, rotation := range rotations {
+		if rotation == r {
+			return name
+		}
+	}
+	return fmt.Sprintf("Rotation(%d)", int(r))
+}
+
+// Rotate returns a transform rotating images clockwise
+func Rotate(rotation Rotation) Transform {
+	return rotateTransform{rotation: rotation}
+}
+
+type rotateTransform struct {
+	rotation Rotation
+}
+
+func (r rotateTransform) Size(width, height int) (int, int) {
+	if r.rotation == Rotate180 {
+		return width, height
+	}
+	return height, width
+}
+
+func (r rotateTransform) Apply(img image.Image) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	width, height := r.Size(srcWidth, srcHeight)
+	return remapPixels(img, width, height, func(x, y int) (int, int) {
+		switch r.rotation {
+		case Rotate90:
+			return y, srcHeight - 1 - x
+		case Rotate180:
+			return srcWidth - 1 - x, srcHeight - 1 - y
+		default:
+			return srcWidth - 1 - y, x
+		}
+	})
+}
+
+// pixelBuffer is the pixel layout shared by the standard library's in-memory image types, with
+// coordinates relative to the image's minimum point
+type pixelBuffer struct {
+	pix           []byte
+	stride        int
+	bytesPerPixel int
+}
+
+// at returns the bytes of the pixel at (x, y)
+func (p pixelBuffer) at(x, y int) []byte {
+	offset := y*p.stride + x*p.bytesPerPixel
+	return p.pix[offset : offset+p.bytesPerPixel]
+}
+
+// rawPixels returns the pixel layout of img, false for image types without one
+func rawPixels(img image.Image) (pixelBuffer, bool) {
+	switch m := img.(type) {
+	case *image.NRGBA:
+		return pixelBuffer{m.Pix, m.Stride, 4}, true
+	case *image.RGBA:
+		return pixelBuffer{m.Pix, m.Stride, 4}, true
+	case *image.NRGBA64:
+		return pixelBuffer{m.Pix, m.Stride, 8}, true
+	case *image.RGBA64:
+		return pixelBuffer{m.Pix, m.Stride, 8}, true
+	case *image.Gray:
+		return pixelBuffer{m.Pix, m.Stride, 1}, true
+	case *image.Gray16:
+		return pixelBuffer{m.Pix, m.Stride, 2}, true
+	case *image.Paletted:
+		return pixelBuffer{m.Pix, m.Stride, 1}, true
+	}
+	return pixelBuffer{}, false
+}
+
+// newImageLike returns a blank image of img's type with the given bounds, sharing its palette
+func newImageLike(img image.Image, rect image.Rectangle) image.Image {
+	switch m := img.(type) {
+	case *image.RGBA:
+		return image.NewRGBA(rect)
+	case *image.NRGBA64:
+		return image.NewNRGBA64(rect)
+	case *image.RGBA64:
+		return image.NewRGBA64(rect)
+	case *image.Gray:
+		return image.NewGray(rect)
+	case *image.Gray16:
+		return image.NewGray16(rect)
+	case *image.Paletted:
+		return image.NewPaletted(rect, m.Palette)
+	}
+	return image.NewNRGBA(rect)
+}
+
+// remapPixels builds a width×height image whose pixel (x, y) is the pixel of img that from returns,
+// relative to img's minimum point. Pixels are copied as they are, so the image keeps its type, depth
+// and palette; other image types become NRGBA.
+func remapPixels(img image.Image, width, height int, from func(x, y int) (int, int)) image.Image {
+	src, ok := rawPixels(img)
+	if !ok {
+		img = toNRGBA(img)
+		src, _ = rawPixels(img)
+	}
+	dst := newImageLike(img, image.Rect(0, 0, width, height))
+	out, _ := rawPixels(dst)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			copy(out.at(x, y), src.at(from(x, y)))
+		}
+	}
+	return dst
+}
+
+// sourceWeight is a source pixel covered by an output pixel and the share of the output it makes up
+type sourceWeight struct {
+	index  int
+	weight float64
+}
+
+// boxWeights returns, for each of n output pixels spread over size source pixels, the source pixels
+// it overlaps weighted by how much of it they cover
+func boxWeights(size, n int) [][]sourceWeight {
+	weights := make([][]sourceWeight, n)
+	step := float64(size) / float64(n)
+	for i := range weights {
+		lo, hi := float64(i)*step, float64(i+1)*step
+		for j := int(lo); j < size && float64(j) < hi; j++ {
+			if overlap := math.Min(hi, float64(j+1)) - math.Max(lo, float64(j)); overlap > 0 {
+				weights[i] = append(weights[i], sourceWeight{j, overlap / step})
+			}
+		}
+	}
+	return weights
+}
+
+// boxScale resizes img to width×height with a box filter. Colors are averaged premultiplied, so fully
+// transparent pixels don't bleed into their neighbours. 16-bit images are scaled to NRGBA64, others to
+// NRGBA.
+func boxScale(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	xWeights, yWeights := boxWeights(bounds.Dx(), width), boxWeights(bounds.Dy(), height)
+
+	// Premultiplied 16-bit channels of a source pixel
+	pixel := func(x, y int) [4]float64 {
+		r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return [4]float64{float64(r), float64(g), float64(b), float64(a)}
+	}
+	deep := false
+	switch img.(type) {
+	case *image.NRGBA64, *image.RGBA64, *image.Gray16:
+		deep = true
+	default:
+		nrgba := toNRGBA(img)
+		pixel = func(x, y int) [4]float64 {
+			p := nrgba.Pix[y*nrgba.Stride+x*4:]
+			a := float64(p[3]) * 257
+			return [4]float64{float64(p[0]) * a / 255, float64(p[1]) * a / 255, float64(p[2]) * a / 255, a}
+		}
+	}
+
+	var out8 *image.NRGBA
+	var out16 *image.NRGBA64
+	if deep {
+		out16 = image.NewNRGBA64(image.Rect(0, 0, width, height))
+	} else {
+		out8 = image.NewNRGBA(image.Rect(0, 0, width, height))
+	}
+	for y, ys := range yWeights {
+		for x, xs := range xWeights {
+			var sum [4]float64
+			for _, sy := range ys {
+				for _, sx := range xs {
+					p, w := pixel(sx.index, sy.index), sx.weight*sy.weight
+					for i := range sum {
+						sum[i] += p[i] * w
+					}
+				}
+			}
+
+			// Back to straight alpha
+			sum[3] = math.Min(sum[3], 0xffff)
+			var c [4]float64
+			if sum[3] > 0 {
+				for i := 0; i < 3; i++ {
+					c[i] = math.Min(sum[i]/sum[3], 1)
+				}
+			}
+			if deep {
+				out16.SetNRGBA64(x, y, color.NRGBA64{
+					R: uint16(math.Round(c[0] * 0xffff)),
+					G: uint16(math.Round(c[1] * 0xffff)),
+					B: uint16(math.Round(c[2] * 0xffff)),
+					A: uint16(math.Round(sum[3])),
+				})
+			} else {
+				p := out8.Pix[y*out8.Stride+x*4:]
+				p[0], p[1], p[2] = uint8(math.Round(c[0]*255)), uint8(math.Round(c[1]*255)), uint8(math.Round(c[2]*255))
+				p[3] = uint8(math.Round(sum[3] / 257))
+			}
+		}
+	}
+	if deep {
+		return out16
+	}
+	return out8
+}
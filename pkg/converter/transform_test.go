@@ -0,0 +1,151 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"testing"
+)
+
+// numberedImage returns a width×height image whose pixels hold their own coordinates
+func numberedImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	return img
+}
+
+// TestTransforms tests where every transform moves the pixels of an image
+func TestTransforms(t *testing.T) {
+	tests := []struct {
+		name          string
+		transform     Transform
+		width, height int
+		// Source coordinates of the output pixel at (x, y)
+		from func(x, y int) (int, int)
+	}{
+		{"flip horizontal", Flip(FlipHorizontal), 3, 2, func(x, y int) (int, int) { return 2 - x, y }},
+		{"flip vertical", Flip(FlipVertical), 3, 2, func(x, y int) (int, int) { return x, 1 - y }},
+		{"rotate 90", Rotate(Rotate90), 2, 3, func(x, y int) (int, int) { return y, 1 - x }},
+		{"rotate 180", Rotate(Rotate180), 3, 2, func(x, y int) (int, int) { return 2 - x, 1 - y }},
+		{"rotate 270", Rotate(Rotate270), 2, 3, func(x, y int) (int, int) { return 2 - y, x }},
+		{"scale 4x", Scale(4, FilterNearest), 12, 8, func(x, y int) (int, int) { return x / 4, y / 4 }},
+		{"scale 4x box", Scale(4, FilterBox), 12, 8, func(x, y int) (int, int) { return x / 4, y / 4 }},
+	}
+
+	src := numberedImage(3, 2)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if width, height := tt.transform.Size(3, 2); width != tt.width || height != tt.height {
+				t.Errorf("Expected a size of %dx%d, got %dx%d", tt.width, tt.height, width, height)
+			}
+			out := toNRGBA(tt.transform.Apply(src))
+			if out.Bounds() != image.Rect(0, 0, tt.width, tt.height) {
+				t.Fatalf("Expected bounds of %dx%d, got %v", tt.width, tt.height, out.Bounds())
+			}
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					sx, sy := tt.from(x, y)
+					if got, want := out.NRGBAAt(x, y), src.NRGBAAt(sx, sy); got != want {
+						t.Errorf("Pixel %d,%d: expected %v, got %v", x, y, want, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestBoxScale tests that downscaling averages colors weighted by alpha
+func TestBoxScale(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{200, 0, 0, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{0, 0, 200, 255})
+	img.SetNRGBA(0, 1, color.NRGBA{0, 255, 0, 0}) // Transparent, so its color must not show
+	img.SetNRGBA(1, 1, color.NRGBA{0, 255, 0, 0})
+
+	out := toNRGBA(Scale(0.5, FilterBox).Apply(img))
+	if got, want := out.NRGBAAt(0, 0), (color.NRGBA{100, 0, 100, 128}); got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// 16-bit images keep their depth
+	if _, ok := Scale(0.5, FilterBox).Apply(image.NewNRGBA64(image.Rect(0, 0, 4, 4))).(*image.NRGBA64); !ok {
+		t.Error("Expected a 16-bit image to be scaled to NRGBA64")
+	}
+}
+
+// TestRemapKeepsPalette tests that transforms copying pixels keep paletted images paletted
+func TestRemapKeepsPalette(t *testing.T) {
+	palette := color.Palette{color.NRGBA{}, color.NRGBA{255, 0, 0, 255}}
+	img := image.NewPaletted(image.Rect(0, 0, 2, 1), palette)
+	img.SetColorIndex(1, 0, 1)
+
+	flipped, ok := Flip(FlipHorizontal).Apply(img).(*image.Paletted)
+	if !ok {
+		t.Fatal("Expected a paletted image")
+	}
+	if flipped.ColorIndexAt(0, 0) != 1 || flipped.ColorIndexAt(1, 0) != 0 {
+		t.Errorf("Expected the palette indices to be flipped, got %v", flipped.Pix)
+	}
+}
+
+// TestTransformedConversion tests that conversions run the transforms between decoding and encoding,
+// checking their result against the limits
+func TestTransformedConversion(t *testing.T) {
+	data := readTestResource(t, filepath.Join("data", "multi-color.data"))
+	header, err := NewGraphicsConverter().ReadDataHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadDataHeader failed: %v", err)
+	}
+
+	var output bytes.Buffer
+	gc := NewGraphicsConverter(WithTransforms(Scale(2, FilterNearest), Rotate(Rotate90)))
+	if err := gc.DataToPng(bytes.NewReader(data), &output); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	config, err := png.DecodeConfig(&output)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+	if config.Width != int(header.Height)*2 || config.Height != int(header.Width)*2 {
+		t.Errorf("Expected a %dx%d PNG, got %dx%d", header.Height*2, header.Width*2, config.Width, config.Height)
+	}
+
+	gc = NewGraphicsConverter(WithMaxDimension(int(max(header.Width, header.Height))), WithTransforms(Scale(2, FilterNearest)))
+	if err := gc.DataToPng(bytes.NewReader(data), &output); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge for an upscale past the limits, got %v", err)
+	}
+}
+
+// TestParseTransforms tests parsing scale factors and transform settings
+func TestParseTransforms(t *testing.T) {
+	for input, want := range map[string]float64{"4x": 4, "0.5X": 0.5, "2": 2} {
+		if factor, err := ParseScale(input); err != nil || factor != want {
+			t.Errorf("ParseScale(%q): expected %v, got %v, %v", input, want, factor, err)
+		}
+	}
+	for _, input := range []string{"", "x", "0x", "-2x", "fast"} {
+		if _, err := ParseScale(input); err == nil {
+			t.Errorf("ParseScale(%q): expected an error", input)
+		}
+	}
+
+	if filter, err := ParseScaleFilter("Box"); err != nil || filter != FilterBox {
+		t.Errorf("Expected FilterBox, got %v, %v", filter, err)
+	}
+	if axis, err := ParseFlipAxis("vertical"); err != nil || axis.String() != "vertical" {
+		t.Errorf("Expected FlipVertical, got %v, %v", axis, err)
+	}
+	if rotation, err := ParseRotation("270"); err != nil || rotation != Rotate270 {
+		t.Errorf("Expected Rotate270, got %v, %v", rotation, err)
+	}
+	if _, err := ParseRotation("45"); err == nil {
+		t.Error("Expected an error for a rotation that isn't a multiple of 90")
+	}
+}
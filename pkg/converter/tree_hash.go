@@ -0,0 +1,238 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// textureExtensions lists the texture formats whose pixel content can be decoded, longest first
+var textureExtensions = []string{".cdat.zst", ".data", ".png", ".webp"}
+
+// TreeHash is a Merkle-style hash over the decoded pixel content of a texture tree
+type TreeHash struct {
+	Root  string            // Hex-encoded hash of the whole tree
+	Files map[string]string // Hex-encoded leaf hash keyed by slash-separated path without extension
+}
+
+// TreeHasher computes format-independent hashes of texture trees, so that a DATA tree
+// and its PNG conversion hash to the same value
+type TreeHasher struct {
+	graphicsConverter *GraphicsConverter
+	log               Logger
+}
+
+// NewTreeHasher creates a new TreeHasher instance
+func NewTreeHasher(graphicsConverter *GraphicsConverter) *TreeHasher {
+	return &TreeHasher{
+		graphicsConverter: graphicsConverter,
+		log:               DefaultLogger(),
+	}
+}
+
+// HashTree hashes every texture below dir.
+// Leaves hash the image size and pixels; directories hash their sorted children's names and hashes.
+func (h *TreeHasher) HashTree(dir string) (*TreeHash, error) {
+	files := make(map[string]string)
+	sources := make(map[string]string)
+
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		ext := textureExtension(filePath)
+		if ext == "" {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relPath[:len(relPath)-len(ext)])
+		if other, ok := sources[key]; ok {
+			return fmt.Errorf("'%s' and '%s' describe the same texture", other, relPath)
+		}
+		sources[key] = relPath
+
+		leaf, err := h.hashFile(filePath, ext)
+		if err != nil {
+			return fmt.Errorf("failed to hash '%s': %w", relPath, err)
+		}
+		h.log.Debugf("%s  %s", leaf, key)
+		files[key] = leaf
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	return &TreeHash{Root: merkleRoot(files), Files: files}, nil
+}
+
+// hashFile decodes a texture and hashes its dimensions and pixels
+func (h *TreeHasher) hashFile(filePath, ext string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	img, err := h.graphicsConverter.decodeImage(file, ext)
+	if err != nil {
+		return "", err
+	}
+	return hashPixels(img), nil
+}
+
+// decodeImage decodes a texture based on its extension
+func (g *GraphicsConverter) decodeImage(input io.Reader, ext string) (image.Image, error) {
+	switch ext {
+	case ".data":
+		return g.decodeData(input)
+	case ".png":
+		return g.decodePng(input)
+	case ".cdat.zst":
+		return g.decodeCdat(input)
+	case ".webp":
+		return g.decodeWebp(input)
+	}
+	return nil, fmt.Errorf("unsupported texture format '%s'", ext)
+}
+
+// encodeImage encodes a texture based on its extension
+func (g *GraphicsConverter) encodeImage(img image.Image, output io.Writer, ext string) error {
+	switch ext {
+	case ".data":
+		return g.encodeData(img, output)
+	case ".png":
+		return g.encodePng(output, img)
+	case ".cdat.zst":
+		return g.encodeCdat(img, output)
+	case ".webp":
+		return encodeWebp(output, img)
+	}
+	return fmt.Errorf("unsupported texture format '%s'", ext)
+}
+
+// decodeFile decodes a texture file based on its extension
+func (g *GraphicsConverter) decodeFile(path string) (image.Image, error) {
+	ext := textureExtension(path)
+	if ext == "" {
+		return nil, fmt.Errorf("'%s' is not a supported texture", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return g.decodeImage(file, ext)
+}
+
+// encodeFile encodes a texture file based on its extension, removing it again on failure
+func (g *GraphicsConverter) encodeFile(img image.Image, path string) error {
+	ext := textureExtension(path)
+	if ext == "" {
+		return fmt.Errorf("'%s' is not a supported texture", path)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file '%s': %w", path, err)
+	}
+	err = g.encodeImage(img, file, ext)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// textureExtension returns the decodable texture extension of a path, or "" if there is none
+func textureExtension(filePath string) string {
+	lower := strings.ToLower(filePath)
+	for _, ext := range textureExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// hashPixels hashes an image's size and straight-alpha 8-bit pixels, matching what a PNG stores
+func hashPixels(img image.Image) string {
+	bounds := img.Bounds()
+	hasher := sha256.New()
+
+	var header [8]byte
+	binary.LittleEndian.PutUint32(header[0:], uint32(bounds.Dx()))
+	binary.LittleEndian.PutUint32(header[4:], uint32(bounds.Dy()))
+	hasher.Write(header[:])
+
+	row := make([]byte, bounds.Dx()*4)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			i := (x - bounds.Min.X) * 4
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, c.A
+		}
+		hasher.Write(row)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// merkleRoot folds leaf hashes keyed by slash-separated paths into a single directory-tree hash
+func merkleRoot(files map[string]string) string {
+	children := make(map[string]map[string]string)
+	leaves := make(map[string]string)
+
+	for key, leaf := range files {
+		if dir, rest, ok := strings.Cut(key, "/"); ok {
+			if children[dir] == nil {
+				children[dir] = make(map[string]string)
+			}
+			children[dir][rest] = leaf
+		} else {
+			leaves[key] = leaf
+		}
+	}
+
+	type entry struct {
+		kind, name, hash string
+	}
+	var entries []entry
+	for name, leaf := range leaves {
+		entries = append(entries, entry{"file", name, leaf})
+	}
+	for name, sub := range children {
+		entries = append(entries, entry{"dir", name, merkleRoot(sub)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].name != entries[j].name {
+			return entries[i].name < entries[j].name
+		}
+		return entries[i].kind < entries[j].kind
+	})
+
+	hasher := sha256.New()
+	for _, e := range entries {
+		fmt.Fprintf(hasher, "%s %s\x00%s\n", e.kind, e.name, e.hash)
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
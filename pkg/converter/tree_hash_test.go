@@ -0,0 +1,88 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestHashTreeFormatIndependent tests that a DATA tree and its PNG conversion hash the same
+func TestHashTreeFormatIndependent(t *testing.T) {
+	dataDir := t.TempDir()
+	pngDir := t.TempDir()
+
+	setupTestFiles(t, filepath.Join(dataDir, "nested"), ".data", "data")
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(dataDir, "red.data"))
+
+	graphicsConverter := NewGraphicsConverter()
+	filesConverter := NewFilesConverter(graphicsConverter)
+	if err := filesConverter.DataToPng(dataDir, pngDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	treeHasher := NewTreeHasher(graphicsConverter)
+
+	dataHash, err := treeHasher.HashTree(dataDir)
+	if err != nil {
+		t.Fatalf("HashTree failed for DATA tree: %v", err)
+	}
+	pngHash, err := treeHasher.HashTree(pngDir)
+	if err != nil {
+		t.Fatalf("HashTree failed for PNG tree: %v", err)
+	}
+
+	if len(dataHash.Files) != len(testImages)+1 {
+		t.Errorf("Expected %d leaves, got %d", len(testImages)+1, len(dataHash.Files))
+	}
+	for key, leaf := range dataHash.Files {
+		if pngHash.Files[key] != leaf {
+			t.Errorf("Leaf hash mismatch for %s", key)
+		}
+	}
+	if dataHash.Root != pngHash.Root {
+		t.Errorf("Root hash mismatch: DATA %s, PNG %s", dataHash.Root, pngHash.Root)
+	}
+}
+
+// TestHashTreeDetectsChanges tests that renaming or changing a texture changes the root hash
+func TestHashTreeDetectsChanges(t *testing.T) {
+	dir := t.TempDir()
+	setupTestFiles(t, dir, ".data", "data")
+
+	treeHasher := NewTreeHasher(NewGraphicsConverter())
+	before, err := treeHasher.HashTree(dir)
+	if err != nil {
+		t.Fatalf("HashTree failed: %v", err)
+	}
+
+	if err := os.Rename(filepath.Join(dir, "red.data"), filepath.Join(dir, "red2.data")); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	renamed, err := treeHasher.HashTree(dir)
+	if err != nil {
+		t.Fatalf("HashTree failed: %v", err)
+	}
+	if renamed.Root == before.Root {
+		t.Error("Expected root hash to change after rename")
+	}
+
+	copyFile(t, filepath.Join("testdata", "data", "blue.data"), filepath.Join(dir, "red2.data"))
+	changed, err := treeHasher.HashTree(dir)
+	if err != nil {
+		t.Fatalf("HashTree failed: %v", err)
+	}
+	if changed.Root == renamed.Root {
+		t.Error("Expected root hash to change after content change")
+	}
+}
+
+// TestHashTreeDuplicateKeys tests that two formats of the same texture in one tree are rejected
+func TestHashTreeDuplicateKeys(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "data", "red.data"), filepath.Join(dir, "red.data"))
+	copyFile(t, filepath.Join("testdata", "png", "red.png"), filepath.Join(dir, "red.png"))
+
+	if _, err := NewTreeHasher(NewGraphicsConverter()).HashTree(dir); err == nil {
+		t.Fatal("Expected error for duplicate texture keys, got nil")
+	}
+}
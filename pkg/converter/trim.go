@@ -0,0 +1,165 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+)
+
+// TrimSidecarSuffix is appended to the name of a trimmed output to form the path of its TrimInfo
+const TrimSidecarSuffix = ".trim.json"
+
+// TrimInfo records where a trimmed image sat on its original canvas, to pad it back
+type TrimInfo struct {
+	Width  int `json:"width"` // Size of the original canvas
+	Height int `json:"height"`
+	X      int `json:"x"` // Offset of the trimmed image on the canvas
+	Y      int `json:"y"`
+}
+
+// SetTrim crops the fully transparent margins of converted images, writing a TrimInfo sidecar next to
+// each output that was cropped. Conversions to DATA do the opposite: inputs with a sidecar are padded
+// back to their original canvas, as Celeste expects. It only applies to conversions between DATA, PNG,
+// CDAT and WebP.
+func (f *FilesConverter) SetTrim(enabled bool) {
+	f.trim = enabled
+}
+
+// checkTrim rejects trimming for conversions between formats it can't decode and encode
+func (f *FilesConverter) checkTrim(fromExt, toExt string) error {
+	if f.trim && (!slices.Contains(textureExtensions, fromExt) || !slices.Contains(textureExtensions, toExt)) {
+		return fmt.Errorf("trimming isn't supported for %s -> %s conversions", formatLabel(fromExt), formatLabel(toExt))
+	}
+	return nil
+}
+
+// trimmedConvert returns the conversion of task with trimming enabled. For outputs other than DATA, the
+// returned TrimInfo is filled in during the conversion if the image was cropped.
+func (f *FilesConverter) trimmedConvert(batch *conversionBatch, task ConversionTask) (func(io.Reader, io.Writer) error, *TrimInfo, error) {
+	g := f.graphicsConverter
+	if batch.toExt == ".data" {
+		info, err := readTrimInfo(task)
+		if err != nil || info == nil {
+			return batch.convertFunc, nil, err
+		}
+		if err := g.checkImageSize(info.Width, info.Height, 4); err != nil {
+			return nil, nil, fmt.Errorf("invalid trim sidecar of '%s': %w", task.relPath, err)
+		}
+		return func(input io.Reader, output io.Writer) error {
+			img, err := g.decodeImage(input, batch.fromExt)
+			if err != nil {
+				return err
+			}
+			padded, err := padImage(img, *info)
+			if err != nil {
+				return err
+			}
+			if padded, err = g.applyTransforms(padded); err != nil {
+				return err
+			}
+			return g.encodeData(padded, output)
+		}, nil, nil
+	}
+
+	trimmed := new(TrimInfo)
+	return func(input io.Reader, output io.Writer) error {
+		img, err := g.decodeImage(input, batch.fromExt)
+		if err != nil {
+			return err
+		}
+		if nrgba, ok := img.(*image.NRGBA); ok && batch.fromExt == ".data" {
+			defer releaseNRGBA(nrgba)
+		}
+		if img, err = g.applyTransforms(img); err != nil {
+			return err
+		}
+
+		bounds := img.Bounds()
+		visible := visibleBounds(img)
+		// Fully transparent images are written as they are, having no visible part to keep
+		if !visible.Empty() && visible != bounds {
+			*trimmed = TrimInfo{
+				Width:  bounds.Dx(),
+				Height: bounds.Dy(),
+				X:      visible.Min.X - bounds.Min.X,
+				Y:      visible.Min.Y - bounds.Min.Y,
+			}
+			img = subImage(img, visible)
+		}
+		return g.encodeImage(img, output, batch.toExt)
+	}, trimmed, nil
+}
+
+// readTrimInfo reads the TrimInfo sidecar of task's input, nil if it has none
+func readTrimInfo(task ConversionTask) (*TrimInfo, error) {
+	data, err := fs.ReadFile(task.source, filepath.ToSlash(task.relPath)+TrimSidecarSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trim sidecar of '%s': %w", task.relPath, err)
+	}
+	var info TrimInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid trim sidecar of '%s': %w", task.relPath, err)
+	}
+	return &info, nil
+}
+
+// visibleBounds returns the smallest rectangle holding every pixel of img that isn't fully transparent,
+// empty if there is none
+func visibleBounds(img image.Image) image.Rectangle {
+	bounds := img.Bounds()
+	alpha := func(x, y int) bool {
+		_, _, _, a := img.At(x, y).RGBA()
+		return a != 0
+	}
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		alpha = func(x, y int) bool { return nrgba.Pix[nrgba.PixOffset(x, y)+3] != 0 }
+	}
+
+	visible := image.Rectangle{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !alpha(x, y) {
+				continue
+			}
+			if visible.Empty() {
+				visible = image.Rect(x, y, x+1, y+1)
+			}
+			visible.Min.X, visible.Min.Y = min(visible.Min.X, x), min(visible.Min.Y, y)
+			visible.Max.X, visible.Max.Y = max(visible.Max.X, x+1), max(visible.Max.Y, y+1)
+		}
+	}
+	return visible
+}
+
+// subImage crops img to rect, copying it if its type can't share its pixels
+func subImage(img image.Image, rect image.Rectangle) image.Image {
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+	cropped := image.NewNRGBA(rect)
+	draw.Draw(cropped, rect, img, rect.Min, draw.Src)
+	return cropped
+}
+
+// padImage places img at the offset of info on a transparent canvas of its original size
+func padImage(img image.Image, info TrimInfo) (image.Image, error) {
+	bounds := img.Bounds()
+	if info.X < 0 || info.Y < 0 || info.X+bounds.Dx() > info.Width || info.Y+bounds.Dy() > info.Height {
+		return nil, fmt.Errorf("a %dx%d image at %d,%d doesn't fit the %dx%d canvas of its trim sidecar",
+			bounds.Dx(), bounds.Dy(), info.X, info.Y, info.Width, info.Height)
+	}
+	canvas := image.NewNRGBA(image.Rect(0, 0, info.Width, info.Height))
+	draw.Draw(canvas, bounds.Sub(bounds.Min).Add(image.Pt(info.X, info.Y)), img, bounds.Min, draw.Src)
+	return canvas, nil
+}
@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestData encodes img as a DATA file at path and returns its contents
+func writeTestData(t *testing.T, path string, img image.Image) []byte {
+	var data bytes.Buffer
+	if err := EncodeData(&data, img); err != nil {
+		t.Fatalf("EncodeData failed: %v", err)
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	return data.Bytes()
+}
+
+// TestTrim tests cropping transparent margins to PNG and padding them back to DATA
+func TestTrim(t *testing.T) {
+	fromDir := t.TempDir()
+	pngDir := t.TempDir()
+	dataDir := t.TempDir()
+
+	sprite := image.NewNRGBA(image.Rect(0, 0, 8, 6))
+	for y := 1; y < 4; y++ {
+		for x := 3; x < 5; x++ {
+			sprite.SetNRGBA(x, y, color.NRGBA{200, uint8(x * 40), 0, 255})
+		}
+	}
+	sprite.SetNRGBA(4, 3, color.NRGBA{255, 255, 255, 128})
+	spriteData := writeTestData(t, filepath.Join(fromDir, "sprite.data"), sprite)
+	writeTestData(t, filepath.Join(fromDir, "empty.data"), image.NewNRGBA(image.Rect(0, 0, 4, 4)))
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter(), WithTrim(true))
+	if err := filesConverter.DataToPng(fromDir, pngDir); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+
+	trimmed, err := png.DecodeConfig(bytes.NewReader(readFile(t, filepath.Join(pngDir, "sprite.png"))))
+	if err != nil {
+		t.Fatalf("Failed to decode sprite.png: %v", err)
+	}
+	if trimmed.Width != 2 || trimmed.Height != 3 {
+		t.Errorf("Expected a 2x3 PNG, got %dx%d", trimmed.Width, trimmed.Height)
+	}
+	var info TrimInfo
+	if err := json.Unmarshal(readFile(t, filepath.Join(pngDir, "sprite.png"+TrimSidecarSuffix)), &info); err != nil {
+		t.Fatalf("Failed to read trim sidecar: %v", err)
+	}
+	if info != (TrimInfo{Width: 8, Height: 6, X: 3, Y: 1}) {
+		t.Errorf("Unexpected trim sidecar %+v", info)
+	}
+	if fileExists(filepath.Join(pngDir, "empty.png"+TrimSidecarSuffix)) {
+		t.Error("Expected no sidecar for a fully transparent image, which is written as it is")
+	}
+
+	if err := filesConverter.PngToData(pngDir, dataDir); err != nil {
+		t.Fatalf("PngToData failed: %v", err)
+	}
+	if !bytes.Equal(readFile(t, filepath.Join(dataDir, "sprite.data")), spriteData) {
+		t.Error("Expected padding to restore the original DATA file")
+	}
+}
+
+// TestTrimRejectsBadSidecar tests that a trimmed image that doesn't fit its canvas fails
+func TestTrimRejectsBadSidecar(t *testing.T) {
+	fromDir := t.TempDir()
+	setupTestFiles(t, fromDir, ".png", "png")
+	if err := os.WriteFile(filepath.Join(fromDir, "red.png"+TrimSidecarSuffix), []byte(`{"width":1,"height":1,"x":0,"y":0}`), 0644); err != nil {
+		t.Fatalf("Failed to write sidecar: %v", err)
+	}
+	if err := NewFilesConverter(NewGraphicsConverter(), WithTrim(true)).PngToData(fromDir, t.TempDir()); err == nil {
+		t.Error("Expected red.png not to fit its 1x1 canvas")
+	}
+}
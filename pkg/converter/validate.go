@@ -0,0 +1,196 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrInvalidDimensions is returned by ValidateData for DATA headers with a width or height that is
+// zero, negative or above the maximum dimension
+var ErrInvalidDimensions = errors.New("invalid DATA image dimensions")
+
+// validationProblems names the problems ValidateData reports, for the Problem of a ValidationResult
+var validationProblems = []struct {
+	err  error
+	name string
+}{
+	{ErrInvalidDimensions, "invalid-dimensions"},
+	{ErrInvalidAlphaFlag, "invalid-alpha-flag"},
+	{ErrTruncatedData, "truncated"},
+	{ErrOverlongData, "overrun"},
+	{ErrTrailingData, "trailing-bytes"},
+}
+
+// ValidationResult is the outcome of validating a single DATA file
+type ValidationResult struct {
+	Path     string `json:"path"` // Relative to the validated directory
+	Size     int64  `json:"size"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	HasAlpha bool   `json:"hasAlpha"`
+	// Kind of corruption: invalid-dimensions, invalid-alpha-flag, truncated, overrun, trailing-bytes,
+	// or unreadable for files that couldn't be read at all. Empty for valid files.
+	Problem string `json:"problem,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Err     error  `json:"-"`
+}
+
+// Valid reports whether the file is a well-formed DATA texture
+func (r *ValidationResult) Valid() bool {
+	return r.Err == nil
+}
+
+// ValidateData parses the header and run-length stream of a DATA texture without decoding its pixels,
+// returning the header and the first problem found. Unlike decoding, even outside strict mode,
+// truncated streams, runs past the last pixel, invalid alpha flags and trailing bytes are errors.
+func (g *GraphicsConverter) ValidateData(input io.Reader) (DataHeader, error) {
+	r := getDataReader(input)
+	defer putDataReader(r)
+
+	header, _, err := g.readHeader(r)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return header, fmt.Errorf("%w: incomplete header", ErrTruncatedData)
+		}
+		return header, err
+	}
+	if header.Width <= 0 || header.Height <= 0 || int(header.Width) > g.maxDimension || int(header.Height) > g.maxDimension {
+		return header, fmt.Errorf("%w: %dx%d", ErrInvalidDimensions, header.Width, header.Height)
+	}
+	if header.AlphaFlag != 0 && header.AlphaFlag != 1 {
+		return header, fmt.Errorf("%w: %d", ErrInvalidAlphaFlag, header.AlphaFlag)
+	}
+
+	total := int64(header.Width) * int64(header.Height)
+	hasAlpha := header.HasAlpha()
+	for i := int64(0); i < total; {
+		truncated := func(err error) error {
+			if err == io.EOF {
+				return fmt.Errorf("%w: %d/%d pixels decoded", ErrTruncatedData, i, total)
+			}
+			return err
+		}
+
+		countByte, err := r.ReadByte()
+		if err != nil {
+			return header, truncated(err)
+		}
+		count := int64(countByte)
+		if count == 0 {
+			count = 256
+		}
+
+		// Alpha images only store RGB for non-transparent runs
+		visible := true
+		if hasAlpha {
+			alpha, err := r.ReadByte()
+			if err != nil {
+				return header, truncated(err)
+			}
+			visible = alpha != 0
+		}
+		if visible {
+			if _, err := r.Discard(3); err != nil {
+				return header, truncated(err)
+			}
+		}
+
+		if count > total-i {
+			return header, fmt.Errorf("%w: run of %d pixels at pixel %d of %d", ErrOverlongData, count, i, total)
+		}
+		i += count
+	}
+
+	trailing, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return header, err
+	}
+	if trailing > 0 {
+		return header, fmt.Errorf("%w: %d bytes", ErrTrailingData, trailing)
+	}
+	return header, nil
+}
+
+// Validate checks every .data file below dir with ValidateData, without writing anything. Results are
+// sorted by path; the returned error is only set if dir can't be scanned.
+func (f *FilesConverter) Validate(dir string) ([]ValidationResult, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && textureExtension(path) == ".data" {
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	f.log.Infof("%d files to validate", len(files))
+
+	results := make([]ValidationResult, len(files))
+	indexes := make(chan int, len(files))
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < f.maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = f.validateFile(dir, files[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+	return results, nil
+}
+
+// validateFile validates a single DATA file
+func (f *FilesConverter) validateFile(dir, relPath string) ValidationResult {
+	result := ValidationResult{Path: filepath.ToSlash(relPath)}
+	err := func() error {
+		file, err := os.Open(filepath.Join(dir, relPath))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		stat, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		result.Size = stat.Size()
+		header, err := f.graphicsConverter.ValidateData(file)
+		result.Width, result.Height, result.HasAlpha = int(header.Width), int(header.Height), header.HasAlpha()
+		return err
+	}()
+	if err != nil {
+		result.Err, result.Error, result.Problem = err, err.Error(), "unreadable"
+		for _, problem := range validationProblems {
+			if errors.Is(err, problem.err) {
+				result.Problem = problem.name
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestValidateData tests detecting every kind of DATA corruption
+func TestValidateData(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"valid", dataStream(2, 2, 1, 3, 255, 1, 2, 3, 1, 0), nil},
+		{"valid without alpha", dataStream(2, 2, 0, 4, 1, 2, 3), nil},
+		{"run of 256", dataStream(16, 16, 0, 0, 1, 2, 3), nil},
+		{"zero width", dataStream(0, 2, 1), ErrInvalidDimensions},
+		{"negative height", dataStream(2, -1, 1), ErrInvalidDimensions},
+		{"too large", dataStream(1<<20, 1, 1), ErrInvalidDimensions},
+		{"alpha flag", dataStream(2, 2, 7, 4, 0), ErrInvalidAlphaFlag},
+		{"short header", []byte{2, 0, 0, 0, 2}, ErrTruncatedData},
+		{"missing runs", dataStream(2, 2, 1, 3, 0), ErrTruncatedData},
+		{"cut in a run", dataStream(2, 2, 1, 4, 255, 1), ErrTruncatedData},
+		{"overrun", dataStream(2, 2, 1, 5, 0), ErrOverlongData},
+		{"trailing bytes", dataStream(2, 2, 0, 4, 1, 2, 3, 9, 9), ErrTrailingData},
+	}
+
+	gc := NewGraphicsConverter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gc.ValidateData(bytes.NewReader(tt.data))
+			if tt.want == nil && err != nil {
+				t.Errorf("Expected a valid stream, got %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Whatever the encoder writes is valid
+	for _, name := range []string{"transparent", "multi-color", "big-test"} {
+		var data bytes.Buffer
+		if err := gc.PngToData(bytes.NewReader(readTestResource(t, filepath.Join("png", name+".png"))), &data); err != nil {
+			t.Fatalf("PngToData failed: %v", err)
+		}
+		if _, err := gc.ValidateData(&data); err != nil {
+			t.Errorf("%s: expected the encoded texture to be valid, got %v", name, err)
+		}
+	}
+}
+
+// TestValidate tests validating a directory, reporting the problem of every file
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		"ok.data":           dataStream(2, 2, 0, 4, 1, 2, 3),
+		"sub/trailing.data": dataStream(2, 2, 0, 4, 1, 2, 3, 9),
+		"sub/overrun.data":  dataStream(2, 2, 1, 5, 0),
+		"empty.data":        {},
+		"ignored.png":       {1, 2, 3},
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	results, err := NewFilesConverter(NewGraphicsConverter()).Validate(dir)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	want := []struct{ path, problem string }{
+		{"empty.data", "truncated"},
+		{"ok.data", ""},
+		{"sub/overrun.data", "overrun"},
+		{"sub/trailing.data", "trailing-bytes"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %+v", len(want), results)
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Path != w.path || r.Problem != w.problem || r.Valid() != (w.problem == "") {
+			t.Errorf("Expected %s with problem %q, got %s with %q (%v)", w.path, w.problem, r.Path, r.Problem, r.Err)
+		}
+	}
+	if ok := results[1]; ok.Width != 2 || ok.Height != 2 || ok.Size != 16 {
+		t.Errorf("Expected a 2x2 texture of 16 bytes, got %+v", ok)
+	}
+}
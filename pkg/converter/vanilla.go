@@ -0,0 +1,225 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// TextureComparison describes how a mod texture differs from the vanilla asset it overrides
+type TextureComparison struct {
+	Atlas        string // Atlas name, e.g. "Gameplay"
+	Key          string // Sprite key within the atlas, e.g. "characters/player/idle00"
+	ModPath      string
+	VanillaFound bool // False when the mod adds a texture rather than overriding one
+
+	VanillaSize image.Point
+	ModSize     image.Point
+
+	ChangedPixels int             // Pixels whose color differs, including pixels outside the shared area
+	MaxDelta      int             // Largest per-channel difference within the shared area
+	ChangedBounds image.Rectangle // Bounding box of changed pixels in mod coordinates, empty if identical
+	PSNR          float64         // Peak signal-to-noise ratio of the shared area in decibels, +Inf if identical
+}
+
+// SizeChanged reports whether the mod texture has different dimensions than the vanilla one
+func (c *TextureComparison) SizeChanged() bool {
+	return c.VanillaFound && c.VanillaSize != c.ModSize
+}
+
+// VanillaComparer pairs mod textures with the vanilla assets of a Celeste installation
+type VanillaComparer struct {
+	graphicsConverter *GraphicsConverter
+	log               Logger
+	atlasesDir        string                  // <install>/Content/Graphics/Atlases
+	metas             map[string]*AtlasMeta   // Parsed atlas metas by atlas name, nil if the atlas has none
+	pages             map[string]*image.NRGBA // Decoded atlas pages by path
+}
+
+// NewVanillaComparer creates a comparer for the Celeste installation at installDir.
+// installDir may be the installation root or its Content directory.
+func NewVanillaComparer(graphicsConverter *GraphicsConverter, installDir string) *VanillaComparer {
+	contentDir := filepath.Join(installDir, "Content")
+	if info, err := os.Stat(contentDir); err != nil || !info.IsDir() {
+		contentDir = installDir
+	}
+
+	return &VanillaComparer{
+		graphicsConverter: graphicsConverter,
+		log:               DefaultLogger(),
+		atlasesDir:        filepath.Join(contentDir, "Graphics", "Atlases"),
+		metas:             make(map[string]*AtlasMeta),
+		pages:             make(map[string]*image.NRGBA),
+	}
+}
+
+// Compare pairs every texture of a mod with the vanilla asset it overrides.
+// Textures are expected under <modDir>/Graphics/Atlases/<Atlas>/<key>; if that directory
+// doesn't exist, modDir itself is treated as the Atlases directory.
+func (v *VanillaComparer) Compare(modDir string) ([]TextureComparison, error) {
+	modAtlases := filepath.Join(modDir, "Graphics", "Atlases")
+	if info, err := os.Stat(modAtlases); err != nil || !info.IsDir() {
+		modAtlases = modDir
+	}
+
+	var comparisons []TextureComparison
+	err := filepath.Walk(modAtlases, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := textureExtension(path)
+		if info.IsDir() || ext == "" {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(modAtlases, path)
+		if err != nil {
+			return err
+		}
+		atlas, key, ok := strings.Cut(filepath.ToSlash(relPath[:len(relPath)-len(ext)]), "/")
+		if !ok {
+			return nil // Files directly in the Atlases directory aren't sprites
+		}
+
+		comparison, err := v.compareTexture(path, atlas, key)
+		if err != nil {
+			return fmt.Errorf("failed to compare '%s': %w", relPath, err)
+		}
+		comparisons = append(comparisons, *comparison)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		if comparisons[i].Atlas != comparisons[j].Atlas {
+			return comparisons[i].Atlas < comparisons[j].Atlas
+		}
+		return comparisons[i].Key < comparisons[j].Key
+	})
+	return comparisons, nil
+}
+
+// compareTexture compares a single mod texture against its vanilla counterpart
+func (v *VanillaComparer) compareTexture(path, atlas, key string) (*TextureComparison, error) {
+	modImg, err := v.graphicsConverter.decodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &TextureComparison{
+		Atlas:   atlas,
+		Key:     key,
+		ModPath: path,
+		ModSize: modImg.Bounds().Size(),
+	}
+
+	vanillaImg, err := v.VanillaTexture(atlas, key)
+	if err != nil {
+		return nil, err
+	}
+	if vanillaImg == nil {
+		return comparison, nil
+	}
+
+	diff := imagecompare.Compare(vanillaImg, modImg, 0)
+	comparison.VanillaFound = true
+	comparison.VanillaSize = vanillaImg.Bounds().Size()
+	comparison.ChangedPixels = diff.ChangedPixels
+	comparison.MaxDelta = diff.MaxDelta
+	comparison.ChangedBounds = diff.Bounds
+	comparison.PSNR = diff.PSNR()
+	return comparison, nil
+}
+
+// VanillaTexture returns the vanilla texture for an atlas key, or nil if the installation has none.
+// Packed atlases are looked up through their .meta file, other atlases as loose .data files.
+func (v *VanillaComparer) VanillaTexture(atlas, key string) (image.Image, error) {
+	meta, err := v.atlasMeta(atlas)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta != nil {
+		for _, page := range meta.Pages {
+			for _, sprite := range page.Sprites {
+				if !spriteKeysEqual(sprite.Key, key) {
+					continue
+				}
+				pageImg, err := v.page(filepath.Join(v.atlasesDir, page.Name+".data"))
+				if err != nil {
+					return nil, err
+				}
+				return extractSprite(pageImg, sprite), nil
+			}
+		}
+	}
+
+	loosePath := filepath.Join(v.atlasesDir, atlas, filepath.FromSlash(key)+".data")
+	if _, err := os.Stat(loosePath); err != nil {
+		return nil, nil
+	}
+	return v.graphicsConverter.decodeFile(loosePath)
+}
+
+// atlasMeta loads and caches an atlas's .meta file, returning nil if it has none
+func (v *VanillaComparer) atlasMeta(atlas string) (*AtlasMeta, error) {
+	if meta, ok := v.metas[atlas]; ok {
+		return meta, nil
+	}
+
+	metaFile, err := os.Open(filepath.Join(v.atlasesDir, atlas+".meta"))
+	if err != nil {
+		v.metas[atlas] = nil
+		return nil, nil
+	}
+	defer metaFile.Close()
+
+	meta, err := ReadAtlasMeta(metaFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s.meta: %w", atlas, err)
+	}
+	v.metas[atlas] = meta
+	return meta, nil
+}
+
+// page decodes and caches an atlas page
+func (v *VanillaComparer) page(path string) (*image.NRGBA, error) {
+	if img, ok := v.pages[path]; ok {
+		return img, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := v.graphicsConverter.decodeData(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode atlas page '%s': %w", path, err)
+	}
+	v.pages[path] = img
+	return img, nil
+}
+
+// extractSprite cuts a sprite out of an atlas page, restoring the transparent border trimmed by the packer
+func extractSprite(page image.Image, sprite AtlasSprite) image.Image {
+	width, height := int(sprite.RealWidth), int(sprite.RealHeight)
+	if width == 0 || height == 0 {
+		width, height = int(sprite.Width), int(sprite.Height)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	// Celeste stores the trim offset negated
+	dest := image.Rect(0, 0, int(sprite.Width), int(sprite.Height)).Add(image.Pt(-int(sprite.OffsetX), -int(sprite.OffsetY)))
+	draw.Draw(img, dest, page, image.Pt(int(sprite.X), int(sprite.Y)), draw.Src)
+	return img
+}
@@ -0,0 +1,110 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupVanillaInstall creates a fake Celeste installation with a packed Gameplay atlas and a loose Mountain atlas
+func setupVanillaInstall(t *testing.T) string {
+	installDir := t.TempDir()
+	atlasesDir := filepath.Join(installDir, "Content", "Graphics", "Atlases")
+
+	spritesDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(spritesDir, "objects"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, imgName := range []string{"red", "blue"} {
+		copyFile(t, filepath.Join("testdata", "png", imgName+".png"), filepath.Join(spritesDir, "objects", imgName+".png"))
+	}
+	if err := NewAtlasPacker(NewGraphicsConverter()).Pack(spritesDir, atlasesDir, "Gameplay"); err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(atlasesDir, "Mountain"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	greenData := pngToDataBytes(t, NewGraphicsConverter(), readTestResource(t, filepath.Join("png", "green.png")))
+	if err := os.WriteFile(filepath.Join(atlasesDir, "Mountain", "green.data"), greenData, 0644); err != nil {
+		t.Fatalf("Failed to write loose texture: %v", err)
+	}
+
+	return installDir
+}
+
+// TestVanillaComparerCompare tests that mod textures are paired with packed and loose vanilla assets
+func TestVanillaComparerCompare(t *testing.T) {
+	installDir := setupVanillaInstall(t)
+	modDir := t.TempDir()
+	modAtlases := filepath.Join(modDir, "Graphics", "Atlases")
+
+	for _, dir := range []string{filepath.Join(modAtlases, "Gameplay", "objects"), filepath.Join(modAtlases, "Mountain")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	copyFile(t, filepath.Join("testdata", "png", "red.png"), filepath.Join(modAtlases, "Gameplay", "objects", "red.png"))
+	copyFile(t, filepath.Join("testdata", "png", "green.png"), filepath.Join(modAtlases, "Gameplay", "objects", "blue.png"))
+	copyFile(t, filepath.Join("testdata", "png", "yellow.png"), filepath.Join(modAtlases, "Gameplay", "objects", "new.png"))
+	copyFile(t, filepath.Join("testdata", "png", "green.png"), filepath.Join(modAtlases, "Mountain", "green.png"))
+
+	comparer := NewVanillaComparer(NewGraphicsConverter(), installDir)
+	comparisons, err := comparer.Compare(modDir)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+
+	if len(comparisons) != 4 {
+		t.Fatalf("Expected 4 comparisons, got %d", len(comparisons))
+	}
+
+	byKey := make(map[string]TextureComparison)
+	for _, c := range comparisons {
+		byKey[c.Atlas+"/"+c.Key] = c
+	}
+
+	for _, key := range []string{"Gameplay/objects/red", "Mountain/green"} {
+		c := byKey[key]
+		if !c.VanillaFound || c.ChangedPixels != 0 || c.SizeChanged() {
+			t.Errorf("Expected %s to match vanilla: %+v", key, c)
+		}
+	}
+
+	blue := byKey["Gameplay/objects/blue"]
+	if !blue.VanillaFound {
+		t.Fatalf("Expected vanilla asset for objects/blue")
+	}
+	pixels := blue.ModSize.X * blue.ModSize.Y
+	if blue.ChangedPixels != pixels || blue.MaxDelta != 255 {
+		t.Errorf("Expected all %d pixels of objects/blue changed by 255, got %d by %d", pixels, blue.ChangedPixels, blue.MaxDelta)
+	}
+	if blue.ChangedBounds != image.Rect(0, 0, blue.ModSize.X, blue.ModSize.Y) {
+		t.Errorf("Unexpected changed bounds %v", blue.ChangedBounds)
+	}
+
+	if byKey["Gameplay/objects/new"].VanillaFound {
+		t.Errorf("Expected objects/new to have no vanilla asset")
+	}
+}
+
+// TestExtractSpriteRestoresTrim tests that trimmed sprites are padded back to their real size
+func TestExtractSpriteRestoresTrim(t *testing.T) {
+	page := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	page.Set(5, 6, color.NRGBA{R: 255, A: 255})
+
+	sprite := AtlasSprite{X: 5, Y: 6, Width: 1, Height: 1, OffsetX: -2, OffsetY: -3, RealWidth: 4, RealHeight: 5}
+	img := extractSprite(page, sprite)
+
+	if img.Bounds() != image.Rect(0, 0, 4, 5) {
+		t.Fatalf("Expected 4x5 sprite, got %v", img.Bounds())
+	}
+	if c := color.NRGBAModel.Convert(img.At(2, 3)).(color.NRGBA); c.R != 255 || c.A != 255 {
+		t.Errorf("Expected packed pixel at trim offset, got %v", c)
+	}
+	if c := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); c.A != 0 {
+		t.Errorf("Expected transparent border, got %v", c)
+	}
+}
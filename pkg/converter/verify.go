@@ -0,0 +1,125 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/imagecompare"
+)
+
+// VerifyResult describes how a single file survived a round trip through the other texture format
+type VerifyResult struct {
+	RelPath          string
+	Err              error           // Set when the file couldn't be decoded or round-tripped at all
+	MismatchedPixels int             // Pixels differing by more than the tolerance
+	MaxDelta         int             // Largest per-channel difference
+	PSNR             float64         // Peak signal-to-noise ratio in decibels, +Inf if the pixels are unchanged
+	Bounds           image.Rectangle // Bounding box of the mismatched pixels, empty if none
+}
+
+// OK reports whether the file survived the round trip within the tolerance
+func (r *VerifyResult) OK() bool {
+	return r.Err == nil && r.MismatchedPixels == 0
+}
+
+// Verify round-trips every .data file below dir through PNG and back (and every .png file through DATA and back)
+// in memory, comparing the decoded pixels before and after. Channel differences up to tolerance are accepted.
+// Results are sorted by path; the returned error is only set if dir can't be scanned.
+func (f *FilesConverter) Verify(dir string, tolerance int) ([]VerifyResult, error) {
+	return f.VerifyWith(dir, imagecompare.Options{Tolerance: imagecompare.UniformTolerance(tolerance)})
+}
+
+// VerifyWith is Verify with a tolerance per channel and optionally alpha-weighted color differences
+func (f *FilesConverter) VerifyWith(dir string, options imagecompare.Options) ([]VerifyResult, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := textureExtension(path); !d.IsDir() && (ext == ".data" || ext == ".png") {
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	f.log.Infof("%d files to verify", len(files))
+
+	results := make([]VerifyResult, len(files))
+	indexes := make(chan int, len(files))
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < f.maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = f.verifyFile(dir, files[i], options)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].RelPath < results[j].RelPath
+	})
+	return results, nil
+}
+
+// verifyFile round-trips a single file and compares its pixels
+func (f *FilesConverter) verifyFile(dir, relPath string, options imagecompare.Options) VerifyResult {
+	result := VerifyResult{RelPath: relPath}
+
+	original, err := f.graphicsConverter.decodeFile(filepath.Join(dir, relPath))
+	if err != nil {
+		result.Err = fmt.Errorf("failed to decode: %w", err)
+		return result
+	}
+
+	// Through the other format and back to the original one
+	via := ".png"
+	if textureExtension(relPath) == ".png" {
+		via = ".data"
+	}
+	roundTripped := original
+	for _, ext := range []string{via, textureExtension(relPath)} {
+		roundTripped, err = f.roundTrip(roundTripped, ext)
+		if err != nil {
+			result.Err = fmt.Errorf("failed to round-trip through %s: %w", formatLabel(ext), err)
+			return result
+		}
+	}
+
+	if original.Bounds().Size() != roundTripped.Bounds().Size() {
+		result.Err = fmt.Errorf("size changed from %v to %v", original.Bounds().Size(), roundTripped.Bounds().Size())
+		return result
+	}
+	diff := imagecompare.CompareWith(original, roundTripped, options)
+	result.MismatchedPixels, result.MaxDelta, result.PSNR = diff.ChangedPixels, diff.MaxDelta, diff.PSNR()
+	result.Bounds = diff.Bounds
+	return result
+}
+
+// roundTrip encodes img in the format named by ext and decodes it again
+func (f *FilesConverter) roundTrip(img image.Image, ext string) (image.Image, error) {
+	var buf bytes.Buffer
+	if err := f.graphicsConverter.encodeImage(img, &buf, ext); err != nil {
+		return nil, err
+	}
+	return f.graphicsConverter.decodeImage(&buf, ext)
+}
@@ -0,0 +1,42 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestVerify tests that intact files pass verification and corrupt ones are reported
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "data", "multi-color.data"), filepath.Join(dir, "multi-color.data"))
+	copyFile(t, filepath.Join("testdata", "png", "red.png"), filepath.Join(dir, "red.png"))
+
+	truncated := readTestResource(t, filepath.Join("data", "red.data"))[:10]
+	if err := os.WriteFile(filepath.Join(dir, "broken.data"), truncated, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	results, err := filesConverter.Verify(dir, 0)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for _, result := range results {
+		switch result.RelPath {
+		case "broken.data":
+			if result.OK() || result.Err == nil {
+				t.Errorf("Expected broken.data to fail verification: %+v", result)
+			}
+		default:
+			if !result.OK() {
+				t.Errorf("Expected %s to pass verification: %+v", result.RelPath, result)
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long a file must stay untouched before it is converted,
+// since editors often save a file in several writes
+const watchDebounce = 200 * time.Millisecond
+
+// Watch converts every fromExt file below fromDir, then keeps converting files as they are created
+// or modified until ctx is cancelled. Outputs of removed or renamed inputs are deleted.
+// Failed conversions are logged and retried on the next change instead of stopping the watch.
+func (f *FilesConverter) Watch(
+	ctx context.Context,
+	fromDir, toDir, fromExt, toExt string,
+	convertFunc func(io.Reader, io.Writer) error,
+) error {
+	if IsZipArchive(fromDir) {
+		return errors.New("zip archives can't be watched")
+	}
+	if hasZipExtension(toDir) {
+		return errors.New("watch can't write into zip archives")
+	}
+	if f.outputTemplate != nil {
+		return errors.New("watch doesn't support output templates")
+	}
+	if f.rewritesPaths() {
+		return errors.New("watch doesn't support flattening or path prefixes")
+	}
+	if err := f.checkTrim(fromExt, toExt); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch before the initial conversion so changes made during it aren't missed
+	if err := f.watchTree(watcher, fromDir); err != nil {
+		return err
+	}
+
+	f.log.Infof("Converting %s -> %s", formatLabel(fromExt), formatLabel(toExt))
+	if err := f.convert(ctx, fromDir, toDir, fromExt, toExt, convertFunc); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		f.log.Warnf("Initial conversion failed: %v", err)
+	}
+
+	batch := &conversionBatch{
+		toDir:       toDir,
+		fromExt:     fromExt,
+		toExt:       toExt,
+		conversion:  formatLabel(fromExt) + " -> " + formatLabel(toExt),
+		convertFunc: convertFunc,
+		sink:        dirSink{},
+	}
+
+	f.log.Infof("Watching %s for changes", fromDir)
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+
+	matches := func(path string) bool {
+		if !strings.HasSuffix(strings.ToLower(path), strings.ToLower(fromExt)) {
+			return false
+		}
+		relPath, err := filepath.Rel(fromDir, path)
+		return err == nil && !f.filtered(filepath.ToSlash(relPath))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			f.log.Warnf("File watcher error: %v", err)
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// New directories aren't watched automatically, and may already contain files
+					if err := f.watchTree(watcher, event.Name); err != nil {
+						f.log.Warnf("%v", err)
+					}
+					f.queueTree(event.Name, matches, pending)
+					timer.Reset(watchDebounce)
+					continue
+				}
+			}
+
+			if !matches(event.Name) {
+				continue
+			}
+
+			switch {
+			case event.Has(fsnotify.Create), event.Has(fsnotify.Write):
+				pending[event.Name] = true
+				timer.Reset(watchDebounce)
+			case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+				delete(pending, event.Name)
+				f.removeWatchOutput(fromDir, toDir, fromExt, toExt, event.Name)
+			}
+
+		case <-timer.C:
+			paths := make([]string, 0, len(pending))
+			for path := range pending {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			clear(pending)
+
+			for _, path := range paths {
+				f.convertWatched(ctx, batch, fromDir, fromExt, path)
+			}
+		}
+	}
+}
+
+// watchTree adds dir and all of its subdirectories to the watcher
+func (f *FilesConverter) watchTree(watcher *fsnotify.Watcher, dir string) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch '%s': %w", dir, err)
+	}
+	return nil
+}
+
+// queueTree marks every matching file below dir as pending
+func (f *FilesConverter) queueTree(dir string, matches func(string) bool, pending map[string]bool) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && matches(path) {
+			pending[path] = true
+		}
+		return nil
+	})
+}
+
+// convertWatched converts a single changed file, logging rather than returning failures
+func (f *FilesConverter) convertWatched(ctx context.Context, batch *conversionBatch, fromDir, fromExt, path string) {
+	if _, err := os.Stat(path); err != nil {
+		return // Removed again before it could be converted
+	}
+
+	relPath, err := filepath.Rel(fromDir, path)
+	if err != nil {
+		f.log.Warnf("Skipping %s: %v", path, err)
+		return
+	}
+
+	task := ConversionTask{
+		index:      1,
+		totalFiles: 1,
+		relPath:    relPath,
+		inputPath:  path,
+		outputPath: outputPathFor(batch.toDir, relPath, fromExt, batch.toExt),
+		source:     os.DirFS(fromDir),
+	}
+	if f.provenance {
+		batch.fileProvenance = make([]FileProvenance, 1)
+	}
+	if f.index != nil {
+		batch.indexRecords = make([]AssetRecord, 1)
+	}
+
+	if err := f.pause.wait(ctx); err != nil {
+		return
+	}
+
+	f.log.Infof("Converting %s", relPath)
+	if _, err := f.runTask(ctx, batch, task); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		f.log.Errorf("Failed to convert %s: %v", relPath, err)
+		if f.quarantineDir != "" {
+			if qErr := f.quarantine(task, err); qErr != nil {
+				f.log.Warnf("Failed to quarantine %s: %v", relPath, qErr)
+			}
+		}
+		return
+	}
+
+	if f.index != nil {
+		if err := f.putIndexRecords(batch); err != nil {
+			f.log.Errorf("%v", err)
+		}
+	}
+}
+
+// removeWatchOutput deletes the output of an input that was removed or renamed away
+func (f *FilesConverter) removeWatchOutput(fromDir, toDir, fromExt, toExt, path string) {
+	relPath, err := filepath.Rel(fromDir, path)
+	if err != nil {
+		return
+	}
+
+	outputPath := outputPathFor(toDir, relPath, fromExt, toExt)
+	if err := os.Remove(outputPath); err == nil {
+		f.log.Infof("Removed %s", relOrSelf(toDir, outputPath))
+	} else if !os.IsNotExist(err) {
+		f.log.Warnf("Failed to remove %s: %v", outputPath, err)
+	}
+}
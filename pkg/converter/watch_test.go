@@ -0,0 +1,66 @@
+package converter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForFile polls until path exists (or no longer exists) or the timeout expires
+func waitForFile(t *testing.T, path string, exists bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		_, err := os.Stat(path)
+		if (err == nil) == exists {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s (exists=%v)", path, exists)
+}
+
+// TestWatchConvertsChanges tests that existing, added and removed files are mirrored into the target directory
+func TestWatchConvertsChanges(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	copyFile(t, filepath.Join("testdata", "png", "red.png"), filepath.Join(fromDir, "red.png"))
+
+	graphicsConverter := NewGraphicsConverter()
+	filesConverter := NewFilesConverter(graphicsConverter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- filesConverter.Watch(ctx, fromDir, toDir, ".png", ".data", graphicsConverter.PngToData)
+	}()
+
+	// Existing files are converted up front
+	waitForFile(t, filepath.Join(toDir, "red.data"), true)
+
+	// Files added to new subdirectories are picked up
+	subDir := filepath.Join(fromDir, "sub")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	copyFile(t, filepath.Join("testdata", "png", "blue.png"), filepath.Join(subDir, "blue.png"))
+	waitForFile(t, filepath.Join(toDir, "sub", "blue.data"), true)
+
+	// Removing an input removes its output
+	if err := os.Remove(filepath.Join(fromDir, "red.png")); err != nil {
+		t.Fatalf("Failed to remove input: %v", err)
+	}
+	waitForFile(t, filepath.Join(toDir, "red.data"), false)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Watch did not stop after cancellation")
+	}
+}
@@ -0,0 +1,162 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStalled is the error of a file given up on after its conversion made no progress for the stall timeout
+var ErrStalled = errors.New("conversion stalled")
+
+// ErrFileTimeout is the error of a file whose conversion took longer than the file timeout
+var ErrFileTimeout = errors.New("conversion timed out")
+
+// fileTimeoutGrace is how long a timed out conversion gets to notice its cancellation and remove its
+// partial output before the batch moves on without it
+const fileTimeoutGrace = time.Second
+
+// SetStallTimeout enables the watchdog: a file whose conversion neither reads input nor writes output
+// for timeout, for example on hung network storage or a deadlock, is logged with a dump of every
+// goroutine. Zero disables the watchdog.
+func (f *FilesConverter) SetStallTimeout(timeout time.Duration) {
+	if timeout >= 0 {
+		f.stallTimeout = timeout
+	}
+}
+
+// SetSkipStalled makes the watchdog give up on stalled files, failing them with ErrStalled so the batch
+// carries on and terminates. The stalled conversion is cancelled, removing its partial output once it
+// returns, but a goroutine blocked in a read that never returns can't be stopped and is left behind.
+func (f *FilesConverter) SetSkipStalled(enabled bool) {
+	f.skipStalled = enabled
+}
+
+// SetFileTimeout limits how long a single file may take to convert, however steadily it progresses, so a
+// pathological or crafted input can't hold up a batch. A file over the limit is cancelled, removing its
+// partial output, and fails with ErrFileTimeout while the other files carry on. A conversion busy in a
+// decoder notices the cancellation only at its next read, so it may keep running in the background for a
+// while. Zero disables the limit.
+func (f *FilesConverter) SetFileTimeout(timeout time.Duration) {
+	if timeout >= 0 {
+		f.fileTimeout = timeout
+	}
+}
+
+// heartbeat records when a task last made progress
+type heartbeat struct {
+	last atomic.Int64 // Unix nanoseconds
+}
+
+func newHeartbeat() *heartbeat {
+	h := &heartbeat{}
+	h.touch()
+	return h
+}
+
+// touch records progress now
+func (h *heartbeat) touch() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// idle returns how long ago the task last made progress
+func (h *heartbeat) idle() time.Duration {
+	return time.Since(time.Unix(0, h.last.Load()))
+}
+
+// heartbeatWriter counts every write as progress
+type heartbeatWriter struct {
+	w         io.Writer
+	heartbeat *heartbeat
+}
+
+func (h *heartbeatWriter) Write(p []byte) (int, error) {
+	n, err := h.w.Write(p)
+	h.heartbeat.touch()
+	return n, err
+}
+
+// runWatched runs a task, under the watchdog if a stall timeout or file timeout is set
+func (f *FilesConverter) runWatched(ctx context.Context, batch *conversionBatch, task ConversionTask) (taskMetrics, error) {
+	if f.stallTimeout <= 0 && f.fileTimeout <= 0 {
+		return f.runTask(ctx, batch, task)
+	}
+
+	if f.stallTimeout > 0 {
+		task.heartbeat = newHeartbeat()
+	}
+	taskCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		metrics taskMetrics
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		metrics, err := f.runTask(taskCtx, batch, task)
+		done <- result{metrics, err}
+	}()
+
+	// Disabled checks leave their channel nil, so it never fires
+	var stallCheck, deadline <-chan time.Time
+	if f.stallTimeout > 0 {
+		ticker := time.NewTicker(max(f.stallTimeout/4, time.Millisecond))
+		defer ticker.Stop()
+		stallCheck = ticker.C
+	}
+	if f.fileTimeout > 0 {
+		timer := time.NewTimer(f.fileTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	reported := false
+	for {
+		select {
+		case r := <-done:
+			return r.metrics, r.err
+		case <-deadline:
+			cancel()
+			var metrics taskMetrics
+			select {
+			case r := <-done:
+				metrics = r.metrics
+			case <-time.After(fileTimeoutGrace):
+				f.log.Warnf("Conversion of %s still running after being cancelled, leaving it behind", task.relPath)
+			}
+			return metrics, fmt.Errorf("%w: converting '%s' took longer than %v", ErrFileTimeout, task.relPath, f.fileTimeout)
+		case <-stallCheck:
+			idle := task.heartbeat.idle()
+			if idle < f.stallTimeout {
+				reported = false // Progressing again, report it if it stalls once more
+				continue
+			}
+			if !reported {
+				f.log.Errorf("No progress converting %s for %v, goroutines:\n%s",
+					task.relPath, idle.Round(time.Second), goroutineDump())
+				reported = true
+			}
+			if f.skipStalled {
+				f.log.Warnf("Skipping stalled file %s", task.relPath)
+				return taskMetrics{}, fmt.Errorf("%w: no progress converting '%s' for %v", ErrStalled, task.relPath, idle.Round(time.Second))
+			}
+		}
+	}
+}
+
+// goroutineDump returns the stack traces of every goroutine
+func goroutineDump() string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
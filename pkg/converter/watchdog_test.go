@@ -0,0 +1,137 @@
+package converter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSkipStalled tests that a hung file is failed with ErrStalled while the rest of the batch converts
+func TestSkipStalled(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	// Each byte decides how long reading it takes
+	for name, content := range map[string]string{"hung": "h123456789", "slow": "ssssssssss", "quick": "q123456789"} {
+		if err := os.WriteFile(filepath.Join(fromDir, name+".txt"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	hang := make(chan struct{})
+	convertFunc := func(r io.Reader, w io.Writer) error {
+		buf := make([]byte, 1)
+		for {
+			n, err := r.Read(buf)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			switch string(buf[:n]) {
+			case "h": // Hangs like a read from dead network storage
+				<-hang
+				return errors.New("released")
+			case "s": // Slower in total than the timeout, but never idle for it
+				time.Sleep(20 * time.Millisecond)
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetMaxWorkers(1)
+	filesConverter.SetContinueOnError(true)
+	filesConverter.SetStallTimeout(100 * time.Millisecond)
+	filesConverter.SetSkipStalled(true)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- filesConverter.Convert(fromDir, toDir, ".txt", ".out", convertFunc)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the batch to finish despite the hung file")
+	}
+
+	if !errors.Is(err, ErrStalled) || !strings.Contains(err.Error(), "hung.txt") {
+		t.Fatalf("Expected hung.txt to fail with ErrStalled, got %v", err)
+	}
+	if strings.Contains(err.Error(), "slow.txt") {
+		t.Errorf("Expected slow.txt to count as progressing, got %v", err)
+	}
+	for _, name := range []string{"slow", "quick"} {
+		if _, err := os.Stat(filepath.Join(toDir, name+".out")); err != nil {
+			t.Errorf("Expected %s.out to be written: %v", name, err)
+		}
+	}
+
+	// The abandoned conversion removes its partial output once it returns
+	close(hang)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(toDir, "hung.out")); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the partial output of the stalled file to be removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestFileTimeout tests that a file converting for longer than the file timeout fails with ErrFileTimeout
+// and leaves no output, even though it never stops progressing
+func TestFileTimeout(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+	for name, content := range map[string]string{"slow": "ssssssssssssssssssss", "quick": "q123456789"} {
+		if err := os.WriteFile(filepath.Join(fromDir, name+".txt"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	convertFunc := func(r io.Reader, w io.Writer) error {
+		buf := make([]byte, 1)
+		for {
+			n, err := r.Read(buf)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if buf[0] == 's' {
+				time.Sleep(20 * time.Millisecond)
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+	}
+
+	filesConverter := NewFilesConverter(NewGraphicsConverter())
+	filesConverter.SetMaxWorkers(1)
+	filesConverter.SetContinueOnError(true)
+	filesConverter.SetStallTimeout(time.Second)
+	filesConverter.SetFileTimeout(100 * time.Millisecond)
+
+	err := filesConverter.Convert(fromDir, toDir, ".txt", ".out", convertFunc)
+	if !errors.Is(err, ErrFileTimeout) || !strings.Contains(err.Error(), "slow.txt") {
+		t.Fatalf("Expected slow.txt to fail with ErrFileTimeout, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(toDir, "slow.out")); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial output of the timed out file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(toDir, "quick.out")); err != nil {
+		t.Errorf("Expected quick.out to be written: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// DataToWebp converts from Celeste's DATA format to a lossless WebP image, which is much smaller
+// than PNG for web previews while keeping every pixel
+func (g *GraphicsConverter) DataToWebp(input io.Reader, output io.Writer) error {
+	img, err := g.decodeData(input)
+	if err != nil {
+		return err
+	}
+	defer releaseNRGBA(img)
+	transformed, err := g.applyTransforms(img)
+	if err != nil {
+		return err
+	}
+	return encodeWebp(output, transformed)
+}
+
+// WebpToData converts from a lossy or lossless WebP image to Celeste's DATA format
+func (g *GraphicsConverter) WebpToData(input io.Reader, output io.Writer) error {
+	img, err := g.decodeWebp(input)
+	if err != nil {
+		return err
+	}
+	if img, err = g.applyTransforms(img); err != nil {
+		return err
+	}
+	return g.encodeData(img, output)
+}
+
+// encodeWebp writes img as lossless WebP (VP8L) with a pure Go encoder, so builds stay cgo-free
+func encodeWebp(output io.Writer, img image.Image) error {
+	return nativewebp.Encode(output, toNRGBA(img), nil)
+}
+
+// decodeWebp decodes a WebP image, checking its dimensions against the limits before decoding the pixels
+func (g *GraphicsConverter) decodeWebp(input io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := nativewebp.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebP: %w", err)
+	}
+	g.log.Debugf("WebP image parameters: %dx%d", config.Width, config.Height)
+	if err := g.checkImageSize(config.Width, config.Height, 4); err != nil {
+		return nil, err
+	}
+
+	img, err := nativewebp.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebP: %w", err)
+	}
+	return img, nil
+}
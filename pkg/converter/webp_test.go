@@ -0,0 +1,53 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestWebpRoundTrip tests that DATA -> WebP -> DATA keeps every pixel, since the encoder is lossless
+func TestWebpRoundTrip(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+
+	for _, imageName := range []string{"multi-color", "transparent", "red"} {
+		t.Run(imageName, func(t *testing.T) {
+			dataBytes := readTestResource(t, filepath.Join("data", imageName+".data"))
+			expected := bytesToImage(t, dataToPngBytes(t, graphicsConverter, dataBytes))
+
+			var webpBuf bytes.Buffer
+			if err := graphicsConverter.DataToWebp(bytes.NewReader(dataBytes), &webpBuf); err != nil {
+				t.Fatalf("DataToWebp failed: %v", err)
+			}
+			if format, err := SniffFormat(bytes.NewReader(webpBuf.Bytes())); err != nil || format != ".webp" {
+				t.Errorf("Expected WebP output to sniff as .webp, got %q (%v)", format, err)
+			}
+
+			var dataBuf bytes.Buffer
+			if err := graphicsConverter.WebpToData(bytes.NewReader(webpBuf.Bytes()), &dataBuf); err != nil {
+				t.Fatalf("WebpToData failed: %v", err)
+			}
+			assertImageEquals(t, expected, bytesToImage(t, dataToPngBytes(t, graphicsConverter, dataBuf.Bytes())), 0)
+		})
+	}
+}
+
+// TestWebpSizeLimit tests that WebP dimensions are checked against the limits before decoding
+func TestWebpSizeLimit(t *testing.T) {
+	graphicsConverter := NewGraphicsConverter()
+	var webpBuf bytes.Buffer
+	if err := graphicsConverter.DataToWebp(bytes.NewReader(readTestResource(t, filepath.Join("data", "red.data"))), &webpBuf); err != nil {
+		t.Fatalf("DataToWebp failed: %v", err)
+	}
+
+	graphicsConverter.SetMaxDimension(1)
+	err := graphicsConverter.WebpToData(bytes.NewReader(webpBuf.Bytes()), &bytes.Buffer{})
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge, got %v", err)
+	}
+
+	if err := NewGraphicsConverter().WebpToData(bytes.NewReader([]byte("RIFF....WEBPVP8L")), &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for truncated WebP, got nil")
+	}
+}
@@ -0,0 +1,193 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// WritableFS is the minimal filesystem ConvertFS writes outputs to. Names are slash-separated paths
+// valid for fs.ValidPath.
+type WritableFS interface {
+	// Create creates or truncates the file name, creating missing parent directories
+	Create(name string) (io.WriteCloser, error)
+	// Remove removes the file name, to drop the partial output of a failed conversion
+	Remove(name string) error
+}
+
+// DirWriter is a WritableFS writing below a directory on disk, the counterpart of os.DirFS
+type DirWriter string
+
+// Create creates or truncates the file name below the directory
+func (d DirWriter) Create(name string) (io.WriteCloser, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrInvalid}
+	}
+	fullPath := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(fullPath)
+}
+
+// Remove removes the file name below the directory
+func (d DirWriter) Remove(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Remove(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// MemoryFS is a WritableFS keeping outputs in memory, for embedding and tests. It is safe for concurrent use.
+type MemoryFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemoryFS creates an empty MemoryFS
+func NewMemoryFS() *MemoryFS {
+	return &MemoryFS{files: make(map[string][]byte)}
+}
+
+// Create starts the file name, which holds what was written once the returned writer is closed
+func (m *MemoryFS) Create(name string) (io.WriteCloser, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrInvalid}
+	}
+	return &memoryFile{fs: m, name: name}, nil
+}
+
+// Remove removes the file name
+func (m *MemoryFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// ReadFile returns the contents of the file name
+func (m *MemoryFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(data), nil
+}
+
+// Names returns the names of all files, in no particular order
+func (m *MemoryFS) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	return names
+}
+
+// memoryFile buffers a MemoryFS file until it is closed
+type memoryFile struct {
+	bytes.Buffer
+	fs   *MemoryFS
+	name string
+}
+
+func (f *memoryFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	f.fs.files[f.name] = f.Bytes()
+	return nil
+}
+
+// ConvertFS converts all files with fromExt in source, such as an embed.FS, an fstest.MapFS or a zip
+// reader, writing toExt files with the same relative paths to target. Features working on files on disk
+// (incremental runs, overwrite policies other than overwriting, dry runs, indexing, deduplication and
+// attribute preservation) aren't supported, and symlinks in source are skipped.
+func (f *FilesConverter) ConvertFS(
+	ctx context.Context,
+	source fs.FS, target WritableFS,
+	fromExt, toExt string,
+	convertFunc func(io.Reader, io.Writer) error,
+) error {
+	if err := f.checkFSTarget(); err != nil {
+		return err
+	}
+	f.log.Infof("Converting %s -> %s", formatLabel(fromExt), formatLabel(toExt))
+	tasks, err := f.collectTasks(ctx, source, "", ".", fromExt, toExt)
+	if err != nil {
+		return err
+	}
+	return f.runTasks(ctx, tasks, ".", target, fromExt, toExt, convertFunc)
+}
+
+// checkFSTarget rejects the settings ConvertFS can't honour
+func (f *FilesConverter) checkFSTarget() error {
+	var unsupported string
+	switch {
+	case f.incremental:
+		unsupported = "incremental conversion"
+	case f.overwritePolicy != OverwriteExisting:
+		unsupported = "overwrite policy " + f.overwritePolicy.String()
+	case f.dryRun || f.plan:
+		unsupported = "dry runs"
+	case f.index != nil:
+		unsupported = "indexing"
+	case f.dedupe != DedupeOff:
+		unsupported = "deduplication"
+	case f.preserveAttributes:
+		unsupported = "preserving attributes"
+	case f.resume:
+		unsupported = "resuming"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%s isn't supported when converting to a WritableFS", unsupported)
+}
+
+// fsSink writes outputs into a WritableFS, the batch's target directory being "."
+type fsSink struct {
+	target WritableFS
+}
+
+func (s fsSink) create(outputPath string) (outputFile, error) {
+	name := path.Clean(filepath.ToSlash(outputPath))
+	writer, err := s.target.Create(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file '%s': %w", name, err)
+	}
+	return &fsOutputFile{WriteCloser: writer, sink: s, name: name}, nil
+}
+
+func (fsSink) close() error { return nil }
+
+func (fsSink) abort() {}
+
+// fsOutputFile is an output being written to a WritableFS
+type fsOutputFile struct {
+	io.WriteCloser
+	sink fsSink
+	name string
+}
+
+func (o *fsOutputFile) commit() error {
+	if err := o.Close(); err != nil {
+		return fmt.Errorf("failed to close output file '%s': %w", o.name, err)
+	}
+	return nil
+}
+
+func (o *fsOutputFile) discard() {
+	o.Close()
+	o.sink.target.Remove(o.name)
+}
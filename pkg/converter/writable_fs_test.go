@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+// testMapFS returns an in-memory source with a nested DATA file, a broken one and an unrelated file
+func testMapFS(t *testing.T) fstest.MapFS {
+	return fstest.MapFS{
+		"red.data":         {Data: readTestResource(t, filepath.Join("data", "red.data"))},
+		"sprites/one.data": {Data: readTestResource(t, filepath.Join("data", "blue.data"))},
+		"broken.data":      {Data: []byte{1, 2}},
+		"notes.txt":        {Data: []byte("not a texture")},
+	}
+}
+
+// TestConvertFS tests converting between in-memory filesystems
+func TestConvertFS(t *testing.T) {
+	source := testMapFS(t)
+	target := NewMemoryFS()
+	gc := NewGraphicsConverter()
+
+	filesConverter := NewFilesConverter(gc)
+	filesConverter.SetContinueOnError(true)
+	if err := filesConverter.ConvertFS(context.Background(), source, target, ".data", ".png", gc.DataToPng); err == nil {
+		t.Fatal("Expected broken.data to fail")
+	}
+
+	names := target.Names()
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "red.png" || names[1] != "sprites/one.png" {
+		t.Fatalf("Expected red.png and sprites/one.png without the failed output, got %v", names)
+	}
+	for name, input := range map[string]string{"red.png": "red.data", "sprites/one.png": "sprites/one.data"} {
+		output, err := target.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if want := dataToPngBytes(t, gc, source[input].Data); !bytes.Equal(output, want) {
+			t.Errorf("Expected %s to be the conversion of %s", name, input)
+		}
+	}
+}
+
+// TestConvertFSDirWriter tests writing to a directory on disk through DirWriter
+func TestConvertFSDirWriter(t *testing.T) {
+	source := testMapFS(t)
+	delete(source, "broken.data")
+	toDir := t.TempDir()
+
+	gc := NewGraphicsConverter()
+	if err := NewFilesConverter(gc).ConvertFS(context.Background(), source, DirWriter(toDir), ".data", ".png", gc.DataToPng); err != nil {
+		t.Fatalf("ConvertFS failed: %v", err)
+	}
+	for _, name := range []string{"red.png", filepath.Join("sprites", "one.png")} {
+		if !fileExists(filepath.Join(toDir, name)) {
+			t.Errorf("Expected %s to be written", name)
+		}
+	}
+}
+
+// TestConvertFSRejectsDiskFeatures tests that settings working on files on disk are refused
+func TestConvertFSRejectsDiskFeatures(t *testing.T) {
+	gc := NewGraphicsConverter()
+	filesConverter := NewFilesConverter(gc)
+	filesConverter.SetIncremental(true)
+	if err := filesConverter.ConvertFS(context.Background(), testMapFS(t), NewMemoryFS(), ".data", ".png", gc.DataToPng); err == nil {
+		t.Error("Expected incremental conversion to be refused")
+	}
+}
+
+// TestMemoryFSRejectsInvalidPaths tests that MemoryFS only accepts fs.ValidPath names
+func TestMemoryFSRejectsInvalidPaths(t *testing.T) {
+	for _, name := range []string{"/abs.png", "../up.png", "a//b.png"} {
+		if _, err := NewMemoryFS().Create(name); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
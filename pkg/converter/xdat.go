@@ -0,0 +1,284 @@
+package converter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrExtendedDisabled is returned by the extended DATA conversions unless SetExtended enabled them
+var ErrExtendedDisabled = errors.New("extended DATA is disabled")
+
+// xdatMagic identifies the extended DATA container (.xdat.zst). It is not a format Celeste can load.
+var xdatMagic = [4]byte{'X', 'D', 'A', 'T'}
+
+// xdatVersion is the current version of the container layout
+const xdatVersion uint8 = 1
+
+// Pixel layouts of an extended DATA container
+const (
+	xdatRGBA8   uint8 = iota // 8-bit straight alpha RGBA, what DATA holds
+	xdatRGBA16               // 16-bit straight alpha RGBA, big-endian like PNG
+	xdatPalette              // Up to 256 8-bit straight alpha RGBA colors, then one index byte per pixel
+)
+
+// xdatHeader is the metadata stored ahead of the pixels in an .xdat.zst container, followed by
+// PaletteSize colors for the palette layout. Like .cdat.zst, the whole container is a single zstd stream.
+type xdatHeader struct {
+	Magic       [4]byte
+	Version     uint8
+	Layout      uint8
+	Width       int32
+	Height      int32
+	HasAlpha    uint8
+	PaletteSize uint16
+}
+
+// SetExtended enables the extended DATA conversions, an experimental superset of DATA for tooling
+// pipelines that keeps 16-bit channels and palettes lossless. Celeste can't load it: convert back to
+// DATA before shipping.
+func (g *GraphicsConverter) SetExtended(enabled bool) {
+	g.extended = enabled
+}
+
+// DataToXdat converts from Celeste's DATA format to the extended DATA container
+func (g *GraphicsConverter) DataToXdat(input io.Reader, output io.Writer) error {
+	if !g.extended {
+		return ErrExtendedDisabled
+	}
+	img, err := g.decodeData(input)
+	if err != nil {
+		return err
+	}
+	transformed, err := g.applyTransforms(img)
+	if err != nil {
+		return err
+	}
+	return g.encodeXdat(transformed, output)
+}
+
+// XdatToData converts from the extended DATA container to Celeste's DATA format, reducing 16-bit
+// channels to 8 bits like 16-bit PNGs are
+func (g *GraphicsConverter) XdatToData(input io.Reader, output io.Writer) error {
+	if !g.extended {
+		return ErrExtendedDisabled
+	}
+	img, err := g.decodeXdat(input)
+	if err != nil {
+		return err
+	}
+	if img, err = g.applyTransforms(img); err != nil {
+		return err
+	}
+	if _, ok := img.(*image.NRGBA64); ok {
+		img = reduceTo8Bit(img, g.dither)
+	}
+	return g.encodeData(img, output)
+}
+
+// PngToXdat converts from a PNG image to the extended DATA container, keeping 16-bit channels and
+// palettes as they are
+func (g *GraphicsConverter) PngToXdat(input io.Reader, output io.Writer) error {
+	if !g.extended {
+		return ErrExtendedDisabled
+	}
+	img, _, err := g.decodePngImage(input)
+	if err != nil {
+		return err
+	}
+	if img, err = g.applyTransforms(img); err != nil {
+		return err
+	}
+	return g.encodeXdat(img, output)
+}
+
+// XdatToPng converts from the extended DATA container to a PNG image of the same depth, or an indexed
+// PNG for palettes
+func (g *GraphicsConverter) XdatToPng(input io.Reader, output io.Writer) error {
+	if !g.extended {
+		return ErrExtendedDisabled
+	}
+	img, err := g.decodeXdat(input)
+	if err != nil {
+		return err
+	}
+	if img, err = g.applyTransforms(img); err != nil {
+		return err
+	}
+	return g.encodePng(output, img)
+}
+
+// xdatLayout picks the layout that stores img without loss
+func xdatLayout(img image.Image) uint8 {
+	switch img.(type) {
+	case *image.Paletted:
+		return xdatPalette
+	case *image.NRGBA64, *image.RGBA64, *image.Gray16:
+		return xdatRGBA16
+	}
+	return xdatRGBA8
+}
+
+// encodeXdat writes img in the extended DATA container, in the layout that stores it without loss
+func (g *GraphicsConverter) encodeXdat(img image.Image, output io.Writer) error {
+	bounds := img.Bounds()
+	header := xdatHeader{
+		Magic:   xdatMagic,
+		Version: xdatVersion,
+		Layout:  xdatLayout(img),
+		Width:   int32(bounds.Dx()),
+		Height:  int32(bounds.Dy()),
+	}
+	if hasAlphaChannel(img) {
+		header.HasAlpha = 1
+	}
+
+	var palette []color.NRGBA
+	var pix []byte
+	var stride int
+	switch header.Layout {
+	case xdatPalette:
+		paletted := img.(*image.Paletted)
+		for _, c := range paletted.Palette {
+			palette = append(palette, color.NRGBAModel.Convert(c).(color.NRGBA))
+		}
+		header.PaletteSize = uint16(len(palette))
+		pix, stride = paletted.Pix[paletted.PixOffset(bounds.Min.X, bounds.Min.Y):], paletted.Stride
+	case xdatRGBA16:
+		nrgba64, ok := img.(*image.NRGBA64)
+		if !ok || nrgba64.Rect.Min != (image.Point{}) {
+			nrgba64 = image.NewNRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+			draw.Draw(nrgba64, nrgba64.Bounds(), img, bounds.Min, draw.Src)
+		}
+		pix, stride = nrgba64.Pix, nrgba64.Stride
+	default:
+		nrgba := toNRGBA(img)
+		pix, stride = nrgba.Pix, nrgba.Stride
+	}
+
+	g.log.Debugf("XDAT image parameters: %dx%d, %s, %s", header.Width, header.Height,
+		xdatLayoutName(header.Layout), boolToFormat(header.HasAlpha != 0))
+
+	encoder, err := zstd.NewWriter(output, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+
+	if err := binary.Write(encoder, binary.LittleEndian, header); err != nil {
+		encoder.Close()
+		return err
+	}
+	if err := binary.Write(encoder, binary.LittleEndian, palette); err != nil {
+		encoder.Close()
+		return err
+	}
+	rowBytes := bounds.Dx() * xdatBytesPerPixel(header.Layout)
+	for y := 0; y < bounds.Dy(); y++ {
+		if _, err := encoder.Write(pix[y*stride : y*stride+rowBytes]); err != nil {
+			encoder.Close()
+			return err
+		}
+	}
+
+	return encoder.Close()
+}
+
+// decodeXdat reads an image from the extended DATA container: an *image.NRGBA, *image.NRGBA64 or
+// *image.Paletted depending on its layout
+func (g *GraphicsConverter) decodeXdat(input io.Reader) (image.Image, error) {
+	decoder, err := zstd.NewReader(input, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	r := bufio.NewReader(decoder)
+
+	var header xdatHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read XDAT header: %w", err)
+	}
+	if header.Magic != xdatMagic {
+		return nil, errors.New("not an XDAT container")
+	}
+	if header.Version != xdatVersion {
+		return nil, fmt.Errorf("unsupported XDAT version %d", header.Version)
+	}
+	if header.Layout > xdatPalette {
+		return nil, fmt.Errorf("unsupported XDAT layout %d", header.Layout)
+	}
+	if header.Layout == xdatPalette && (header.PaletteSize == 0 || header.PaletteSize > 256) {
+		return nil, fmt.Errorf("invalid XDAT palette size %d", header.PaletteSize)
+	}
+
+	g.log.Debugf("XDAT image parameters: %dx%d, %s, %s", header.Width, header.Height,
+		xdatLayoutName(header.Layout), boolToFormat(header.HasAlpha != 0))
+
+	if err := g.checkImageSize(int(header.Width), int(header.Height), xdatBytesPerPixel(header.Layout)); err != nil {
+		return nil, err
+	}
+
+	rect := image.Rect(0, 0, int(header.Width), int(header.Height))
+	var img image.Image
+	var pix []byte
+	switch header.Layout {
+	case xdatPalette:
+		colors := make([]color.NRGBA, header.PaletteSize)
+		if err := binary.Read(r, binary.LittleEndian, colors); err != nil {
+			return nil, fmt.Errorf("failed to read XDAT palette: %w", err)
+		}
+		palette := make(color.Palette, len(colors))
+		for i, c := range colors {
+			palette[i] = c
+		}
+		paletted := image.NewPaletted(rect, palette)
+		img, pix = paletted, paletted.Pix
+	case xdatRGBA16:
+		nrgba64 := image.NewNRGBA64(rect)
+		img, pix = nrgba64, nrgba64.Pix
+	default:
+		nrgba := image.NewNRGBA(rect)
+		img, pix = nrgba, nrgba.Pix
+	}
+	if _, err := io.ReadFull(r, pix); err != nil {
+		return nil, fmt.Errorf("failed to read XDAT pixels: %w", err)
+	}
+
+	if paletted, ok := img.(*image.Paletted); ok {
+		for _, index := range paletted.Pix {
+			if int(index) >= len(paletted.Palette) {
+				return nil, fmt.Errorf("XDAT palette index %d out of range", index)
+			}
+		}
+	}
+	return img, nil
+}
+
+// xdatBytesPerPixel returns the size of a pixel in a layout
+func xdatBytesPerPixel(layout uint8) int {
+	switch layout {
+	case xdatPalette:
+		return 1
+	case xdatRGBA16:
+		return 8
+	}
+	return 4
+}
+
+// xdatLayoutName describes a layout for logging
+func xdatLayoutName(layout uint8) string {
+	switch layout {
+	case xdatPalette:
+		return "palette"
+	case xdatRGBA16:
+		return "16-bit"
+	}
+	return "8-bit"
+}
@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newExtendedConverter returns a GraphicsConverter with the extended DATA conversions enabled
+func newExtendedConverter() *GraphicsConverter {
+	graphicsConverter := NewGraphicsConverter()
+	graphicsConverter.SetExtended(true)
+	return graphicsConverter
+}
+
+// TestXdatDisabled tests that the extended conversions are off unless enabled
+func TestXdatDisabled(t *testing.T) {
+	pngBytes := readTestResource(t, filepath.Join("png", "red.png"))
+	err := NewGraphicsConverter().PngToXdat(bytes.NewReader(pngBytes), new(bytes.Buffer))
+	if !errors.Is(err, ErrExtendedDisabled) {
+		t.Fatalf("Expected ErrExtendedDisabled, got %v", err)
+	}
+}
+
+// TestXdat16BitRoundTrip tests that 16-bit channels survive PNG -> XDAT -> PNG, and that XDAT -> DATA
+// matches converting the PNG directly
+func TestXdat16BitRoundTrip(t *testing.T) {
+	graphicsConverter := newExtendedConverter()
+
+	src := image.NewNRGBA64(image.Rect(0, 0, 7, 5))
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 7; x++ {
+			src.SetNRGBA64(x, y, color.NRGBA64{R: uint16(x * 9001), G: uint16(y * 12345), B: 0x0101 + uint16(x*y), A: 0xffff - uint16(x*3)})
+		}
+	}
+	pngBytes := new(bytes.Buffer)
+	if err := png.Encode(pngBytes, src); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	xdat := new(bytes.Buffer)
+	if err := graphicsConverter.PngToXdat(bytes.NewReader(pngBytes.Bytes()), xdat); err != nil {
+		t.Fatalf("PngToXdat failed: %v", err)
+	}
+	roundTrip := new(bytes.Buffer)
+	if err := graphicsConverter.XdatToPng(bytes.NewReader(xdat.Bytes()), roundTrip); err != nil {
+		t.Fatalf("XdatToPng failed: %v", err)
+	}
+	decoded, ok := bytesToImage(t, roundTrip.Bytes()).(*image.NRGBA64)
+	if !ok || !bytes.Equal(decoded.Pix, src.Pix) {
+		t.Error("Expected 16-bit pixels to survive the round trip exactly")
+	}
+
+	data := new(bytes.Buffer)
+	if err := graphicsConverter.XdatToData(bytes.NewReader(xdat.Bytes()), data); err != nil {
+		t.Fatalf("XdatToData failed: %v", err)
+	}
+	if !bytes.Equal(data.Bytes(), pngToDataBytes(t, graphicsConverter, pngBytes.Bytes())) {
+		t.Error("Expected XDAT -> DATA to match PNG -> DATA")
+	}
+}
+
+// TestXdatPaletteRoundTrip tests that an indexed PNG keeps its palette and indices through XDAT
+func TestXdatPaletteRoundTrip(t *testing.T) {
+	graphicsConverter := newExtendedConverter()
+	pngBytes := readTestResource(t, filepath.Join("png", "red.png"))
+
+	xdat := new(bytes.Buffer)
+	if err := graphicsConverter.PngToXdat(bytes.NewReader(pngBytes), xdat); err != nil {
+		t.Fatalf("PngToXdat failed: %v", err)
+	}
+	roundTrip := new(bytes.Buffer)
+	if err := graphicsConverter.XdatToPng(xdat, roundTrip); err != nil {
+		t.Fatalf("XdatToPng failed: %v", err)
+	}
+
+	original := bytesToImage(t, pngBytes).(*image.Paletted)
+	decoded, ok := bytesToImage(t, roundTrip.Bytes()).(*image.Paletted)
+	if !ok {
+		t.Fatal("Expected an indexed PNG")
+	}
+	if !bytes.Equal(decoded.Pix, original.Pix) || !reflect.DeepEqual(paletteNRGBA(decoded.Palette), paletteNRGBA(original.Palette)) {
+		t.Error("Expected the palette and indices to survive the round trip")
+	}
+}
+
+// paletteNRGBA returns a palette's colors as straight alpha, however the PNG decoder typed them
+func paletteNRGBA(palette color.Palette) []color.NRGBA {
+	colors := make([]color.NRGBA, len(palette))
+	for i, c := range palette {
+		colors[i] = color.NRGBAModel.Convert(c).(color.NRGBA)
+	}
+	return colors
+}
+
+// TestXdatDataRoundTrip tests that DATA -> XDAT -> DATA preserves the image exactly
+func TestXdatDataRoundTrip(t *testing.T) {
+	graphicsConverter := newExtendedConverter()
+	dataBytes := readTestResource(t, filepath.Join("data", "multi-color.data"))
+
+	xdat := new(bytes.Buffer)
+	if err := graphicsConverter.DataToXdat(bytes.NewReader(dataBytes), xdat); err != nil {
+		t.Fatalf("DataToXdat failed: %v", err)
+	}
+	roundTrip := new(bytes.Buffer)
+	if err := graphicsConverter.XdatToData(xdat, roundTrip); err != nil {
+		t.Fatalf("XdatToData failed: %v", err)
+	}
+
+	originalImage := bytesToImage(t, dataToPngBytes(t, graphicsConverter, dataBytes))
+	convertedImage := bytesToImage(t, dataToPngBytes(t, graphicsConverter, roundTrip.Bytes()))
+	assertImageEquals(t, originalImage, convertedImage, 0)
+}
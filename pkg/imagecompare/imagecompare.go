@@ -0,0 +1,297 @@
+// Package imagecompare compares decoded textures pixel by pixel in straight alpha, reporting how many
+// pixels differ, where, and how visible the differences are.
+package imagecompare
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Result summarizes the differences between two images
+type Result struct {
+	ChangedPixels int             // Pixels differing by more than the tolerance, including pixels outside the shared area
+	MaxDelta      int             // Largest per-channel difference within the shared area
+	Bounds        image.Rectangle // Bounding box of changed pixels relative to the second image's origin, empty if none
+	MSE           float64         // Mean squared channel difference within the shared area
+	MeanDelta     float64         // Mean absolute channel difference within the shared area
+}
+
+// PSNR returns the peak signal-to-noise ratio in decibels, +Inf for identical images
+func (r Result) PSNR() float64 {
+	if r.MSE == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255/r.MSE)
+}
+
+// Tolerance is the largest difference accepted in each channel: red, green, blue and alpha
+type Tolerance [4]int
+
+// UniformTolerance returns a tolerance accepting differences up to n in every channel
+func UniformTolerance(n int) Tolerance {
+	return Tolerance{n, n, n, n}
+}
+
+// ParseTolerance parses a tolerance as a single value for every channel, such as "4", or as
+// comma-separated red, green, blue and alpha values, such as "4,4,4,0"
+func ParseTolerance(s string) (Tolerance, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 1 && len(parts) != 4 {
+		return Tolerance{}, fmt.Errorf("invalid tolerance '%s', expected N or R,G,B,A", s)
+	}
+	var tolerance Tolerance
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 || n > 255 {
+			return Tolerance{}, fmt.Errorf("invalid tolerance '%s', expected values from 0 to 255", s)
+		}
+		tolerance[i] = n
+	}
+	if len(parts) == 1 {
+		tolerance = UniformTolerance(tolerance[0])
+	}
+	return tolerance, nil
+}
+
+// String formats the tolerance as accepted by ParseTolerance
+func (t Tolerance) String() string {
+	if t == UniformTolerance(t[0]) {
+		return strconv.Itoa(t[0])
+	}
+	return fmt.Sprintf("%d,%d,%d,%d", t[0], t[1], t[2], t[3])
+}
+
+// accepts reports whether every channel difference of deltas lies within the tolerance
+func (t Tolerance) accepts(deltas [4]int) bool {
+	for i, d := range deltas {
+		if d > t[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Options configures CompareWith, CheckWith and HeatmapWith
+type Options struct {
+	Tolerance Tolerance
+	// AlphaWeighted compares colors premultiplied by their alpha, so color differences of barely
+	// visible pixels count for as little as they show
+	AlphaWeighted bool
+}
+
+// Compare compares b against a. Both are read relative to their origin, and pixels of b outside
+// the area shared with a count as changed. Channel differences up to tolerance are accepted.
+func Compare(a, b image.Image, tolerance int) Result {
+	return CompareWith(a, b, Options{Tolerance: UniformTolerance(tolerance)})
+}
+
+// CompareWith compares b against a like Compare, with a tolerance per channel and optionally
+// alpha-weighted color differences, which then also make up MaxDelta, MSE and MeanDelta
+func CompareWith(a, b image.Image, options Options) Result {
+	var result Result
+	ab, bb := a.Bounds(), b.Bounds()
+	var squared, absolute float64
+	shared := 0
+
+	for y := 0; y < bb.Dy(); y++ {
+		for x := 0; x < bb.Dx(); x++ {
+			changed := true
+			if x < ab.Dx() && y < ab.Dy() {
+				deltas := channelDeltas(Pixel(a, x, y), Pixel(b, x, y), options.AlphaWeighted)
+				for _, d := range deltas {
+					result.MaxDelta = max(result.MaxDelta, d)
+					squared += float64(d * d)
+					absolute += float64(d)
+				}
+				shared++
+				changed = !options.Tolerance.accepts(deltas)
+			}
+
+			if changed {
+				result.ChangedPixels++
+				result.Bounds = result.Bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	if shared > 0 {
+		result.MSE = squared / float64(shared*4)
+		result.MeanDelta = absolute / float64(shared*4)
+	}
+	return result
+}
+
+// Check returns an error describing the first pixel where actual differs from expected by more than
+// tolerance in any channel, or where their sizes differ; nil if they match
+func Check(expected, actual image.Image, tolerance int) error {
+	return CheckWith(expected, actual, Options{Tolerance: UniformTolerance(tolerance)})
+}
+
+// CheckWith is Check with a tolerance per channel and optionally alpha-weighted color differences.
+// Downstream test suites can fail with its error as it is.
+func CheckWith(expected, actual image.Image, options Options) error {
+	es, as := expected.Bounds().Size(), actual.Bounds().Size()
+	if es != as {
+		return fmt.Errorf("image dimensions don't match: expected %dx%d, got %dx%d", es.X, es.Y, as.X, as.Y)
+	}
+	for y := 0; y < es.Y; y++ {
+		for x := 0; x < es.X; x++ {
+			ec, ac := Pixel(expected, x, y), Pixel(actual, x, y)
+			if !options.Tolerance.accepts(channelDeltas(ec, ac, options.AlphaWeighted)) {
+				return fmt.Errorf("pixel mismatch at (%d,%d): expected rgba(%d,%d,%d,%d), got rgba(%d,%d,%d,%d)",
+					x, y, ec.R, ec.G, ec.B, ec.A, ac.R, ac.G, ac.B, ac.A)
+			}
+		}
+	}
+	return nil
+}
+
+// Pixel returns the straight-alpha color of img at (x, y) relative to its origin, transparent outside it
+func Pixel(img image.Image, x, y int) color.NRGBA {
+	bounds := img.Bounds()
+	if x < 0 || y < 0 || x >= bounds.Dx() || y >= bounds.Dy() {
+		return color.NRGBA{}
+	}
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return nrgba.NRGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+	}
+	return color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+}
+
+// ChannelDelta returns the largest absolute difference between two colors' channels.
+// Fully transparent pixels are equal regardless of their color channels.
+func ChannelDelta(a, b color.NRGBA) int {
+	deltas := channelDeltas(a, b, false)
+	return slices.Max(deltas[:])
+}
+
+// channelDeltas returns the absolute differences between the red, green, blue and alpha channels of
+// two colors, with the color channels premultiplied by alpha if alphaWeighted. Fully transparent
+// pixels are equal regardless of their color channels.
+func channelDeltas(a, b color.NRGBA, alphaWeighted bool) [4]int {
+	if a.A == 0 && b.A == 0 {
+		return [4]int{}
+	}
+	ac := [4]int{int(a.R), int(a.G), int(a.B), int(a.A)}
+	bc := [4]int{int(b.R), int(b.G), int(b.B), int(b.A)}
+	var deltas [4]int
+	for i := range deltas {
+		if alphaWeighted && i < 3 {
+			ac[i], bc[i] = (ac[i]*int(a.A)+127)/255, (bc[i]*int(b.A)+127)/255
+		}
+		deltas[i] = ac[i] - bc[i]
+		if deltas[i] < 0 {
+			deltas[i] = -deltas[i]
+		}
+	}
+	return deltas
+}
+
+// heatmapOutside colors heatmap pixels outside the area shared by both images
+var heatmapOutside = color.NRGBA{R: 255, B: 255, A: 255}
+
+// heatmapMinRed is the red of the smallest change shown in a heatmap, so small changes stand out
+const heatmapMinRed = 96
+
+// Heatmap returns an image showing where b differs from a, both read relative to their origin and
+// covering the larger of their sizes. Changed pixels are red, brighter for larger channel differences;
+// pixels only one image has are magenta; unchanged pixels are b's luma dimmed to a third, for context.
+func Heatmap(a, b image.Image, tolerance int) *image.NRGBA {
+	return HeatmapWith(a, b, Options{Tolerance: UniformTolerance(tolerance)})
+}
+
+// HeatmapWith is Heatmap with a tolerance per channel and optionally alpha-weighted color differences
+func HeatmapWith(a, b image.Image, options Options) *image.NRGBA {
+	ab, bb := a.Bounds(), b.Bounds()
+	heatmap := image.NewNRGBA(image.Rect(0, 0, max(ab.Dx(), bb.Dx()), max(ab.Dy(), bb.Dy())))
+	size := heatmap.Rect.Size()
+
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if x >= ab.Dx() || y >= ab.Dy() || x >= bb.Dx() || y >= bb.Dy() {
+				heatmap.SetNRGBA(x, y, heatmapOutside)
+				continue
+			}
+			bc := Pixel(b, x, y)
+			if deltas := channelDeltas(Pixel(a, x, y), bc, options.AlphaWeighted); !options.Tolerance.accepts(deltas) {
+				delta := slices.Max(deltas[:])
+				heatmap.SetNRGBA(x, y, color.NRGBA{R: uint8(heatmapMinRed + delta*(255-heatmapMinRed)/255), A: 255})
+				continue
+			}
+			gray := uint8((0.299*float64(bc.R) + 0.587*float64(bc.G) + 0.114*float64(bc.B)) * float64(bc.A) / 255 / 3)
+			heatmap.SetNRGBA(x, y, color.NRGBA{R: gray, G: gray, B: gray, A: 255})
+		}
+	}
+	return heatmap
+}
+
+// SSIM window size and step, and the stabilizing constants for 8-bit values
+const (
+	ssimWindow = 8
+	ssimStep   = 4
+	ssimC1     = (0.01 * 255) * (0.01 * 255)
+	ssimC2     = (0.03 * 255) * (0.03 * 255)
+)
+
+// SSIM returns the mean structural similarity of two equally sized images, from 1 for identical images
+// down to around 0 for unrelated ones. It compares the luma of each pixel composited onto black over
+// 8×8 windows, so fully transparent pixels match whatever their color channels. Images smaller than
+// a window are compared as a single window.
+func SSIM(a, b image.Image) (float64, error) {
+	size := a.Bounds().Size()
+	if size != b.Bounds().Size() {
+		return 0, fmt.Errorf("image dimensions don't match: %dx%d and %dx%d", size.X, size.Y,
+			b.Bounds().Dx(), b.Bounds().Dy())
+	}
+	if size.X == 0 || size.Y == 0 {
+		return 1, nil
+	}
+
+	la, lb := luma(a), luma(b)
+	windowW, windowH := min(ssimWindow, size.X), min(ssimWindow, size.Y)
+
+	var total float64
+	windows := 0
+	for y0 := 0; y0+windowH <= size.Y; y0 += ssimStep {
+		for x0 := 0; x0+windowW <= size.X; x0 += ssimStep {
+			var sumA, sumB, sumAA, sumBB, sumAB float64
+			for y := y0; y < y0+windowH; y++ {
+				for x := x0; x < x0+windowW; x++ {
+					va, vb := la[y*size.X+x], lb[y*size.X+x]
+					sumA += va
+					sumB += vb
+					sumAA += va * va
+					sumBB += vb * vb
+					sumAB += va * vb
+				}
+			}
+			n := float64(windowW * windowH)
+			meanA, meanB := sumA/n, sumB/n
+			varA, varB := sumAA/n-meanA*meanA, sumBB/n-meanB*meanB
+			covariance := sumAB/n - meanA*meanB
+
+			total += (2*meanA*meanB + ssimC1) * (2*covariance + ssimC2) /
+				((meanA*meanA + meanB*meanB + ssimC1) * (varA + varB + ssimC2))
+			windows++
+		}
+	}
+	return total / float64(windows), nil
+}
+
+// luma returns the Rec. 601 luma of every pixel of img composited onto black, row by row
+func luma(img image.Image) []float64 {
+	size := img.Bounds().Size()
+	values := make([]float64, size.X*size.Y)
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			c := Pixel(img, x, y)
+			values[y*size.X+x] = (0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)) * float64(c.A) / 255
+		}
+	}
+	return values
+}
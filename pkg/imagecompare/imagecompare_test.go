@@ -0,0 +1,226 @@
+package imagecompare
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// filled returns an NRGBA image of the given size filled with c
+func filled(width, height int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// TestCompareWithTolerance tests that differences up to the tolerance are accepted
+func TestCompareWithTolerance(t *testing.T) {
+	a := filled(4, 4, color.NRGBA{R: 255, A: 255})
+	b := filled(4, 4, color.NRGBA{A: 255})
+
+	if result := Compare(a, b, 254); result.ChangedPixels != 16 || result.MaxDelta != 255 {
+		t.Errorf("Expected 16 changed pixels with max delta 255, got %d with %d", result.ChangedPixels, result.MaxDelta)
+	}
+	if result := Compare(a, b, 255); result.ChangedPixels != 0 || !result.Bounds.Empty() {
+		t.Errorf("Expected no changed pixels at tolerance 255, got %d in %v", result.ChangedPixels, result.Bounds)
+	}
+}
+
+// TestCompareSizeChange tests that pixels outside the first image's area count as changed
+func TestCompareSizeChange(t *testing.T) {
+	a := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	b := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+
+	result := Compare(a, b, 0)
+	if result.ChangedPixels != 2 || result.MaxDelta != 0 {
+		t.Errorf("Expected 2 changed pixels with no delta, got %d with %d", result.ChangedPixels, result.MaxDelta)
+	}
+	if result.Bounds != image.Rect(2, 0, 3, 2) {
+		t.Errorf("Unexpected changed bounds %v", result.Bounds)
+	}
+}
+
+// TestCompareOrigin tests that images are compared relative to their origin
+func TestCompareOrigin(t *testing.T) {
+	a := filled(3, 3, color.NRGBA{G: 128, A: 255})
+	b := filled(3, 3, color.NRGBA{G: 128, A: 255}).SubImage(image.Rect(1, 1, 3, 3))
+	a.SetNRGBA(1, 0, color.NRGBA{R: 10, G: 128, A: 255})
+
+	result := Compare(a, b, 0)
+	if result.ChangedPixels != 1 || result.Bounds != image.Rect(1, 0, 2, 1) {
+		t.Errorf("Expected one changed pixel at (1,0), got %d in %v", result.ChangedPixels, result.Bounds)
+	}
+}
+
+// TestPSNR tests the peak signal-to-noise ratio of identical and uniformly shifted images
+func TestPSNR(t *testing.T) {
+	a := filled(4, 4, color.NRGBA{R: 100, G: 100, B: 100, A: 255})
+	if psnr := Compare(a, a, 0).PSNR(); !math.IsInf(psnr, 1) {
+		t.Errorf("Expected infinite PSNR for identical images, got %f", psnr)
+	}
+
+	// Every color channel is off by 4 and alpha matches, so the MSE is 3*16/4
+	b := filled(4, 4, color.NRGBA{R: 104, G: 104, B: 104, A: 255})
+	want := 10 * math.Log10(255*255/12.0)
+	if psnr := Compare(a, b, 0).PSNR(); math.Abs(psnr-want) > 1e-9 {
+		t.Errorf("Expected PSNR %f, got %f", want, psnr)
+	}
+	if mean := Compare(a, b, 0).MeanDelta; mean != 3 {
+		t.Errorf("Expected mean delta 3, got %f", mean)
+	}
+}
+
+// TestHeatmap tests that changes, pixels only one image has and unchanged pixels are told apart
+func TestHeatmap(t *testing.T) {
+	a := filled(2, 2, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	b := filled(3, 2, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	b.SetNRGBA(0, 0, color.NRGBA{A: 255})
+	b.SetNRGBA(1, 0, color.NRGBA{R: 254, G: 255, B: 255, A: 255})
+
+	heatmap := Heatmap(a, b, 0)
+	if heatmap.Bounds() != image.Rect(0, 0, 3, 2) {
+		t.Fatalf("Expected a heatmap covering both images, got %v", heatmap.Bounds())
+	}
+	if c := heatmap.NRGBAAt(0, 0); c != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Expected the largest change in full red, got %v", c)
+	}
+	if c := heatmap.NRGBAAt(1, 0); c.R != heatmapMinRed || c.G != 0 {
+		t.Errorf("Expected the smallest change in dark red, got %v", c)
+	}
+	if c := heatmap.NRGBAAt(2, 1); c != heatmapOutside {
+		t.Errorf("Expected a pixel only one image has in magenta, got %v", c)
+	}
+	if c := heatmap.NRGBAAt(0, 1); c != (color.NRGBA{R: 85, G: 85, B: 85, A: 255}) {
+		t.Errorf("Expected an unchanged white pixel dimmed to a third, got %v", c)
+	}
+	if c := Heatmap(a, b, 1).NRGBAAt(1, 0); c.R != c.G {
+		t.Errorf("Expected a change within the tolerance to show as unchanged, got %v", c)
+	}
+}
+
+// TestSSIM tests that similarity drops with noise and ignores the colors of transparent pixels
+func TestSSIM(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	a := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for i := range a.Pix {
+		a.Pix[i] = uint8(random.Intn(256))
+	}
+	for i := 3; i < len(a.Pix); i += 4 {
+		a.Pix[i] = 255
+	}
+
+	if ssim, err := SSIM(a, a); err != nil || math.Abs(ssim-1) > 1e-9 {
+		t.Errorf("Expected SSIM 1 for identical images, got %f (%v)", ssim, err)
+	}
+
+	noisy := image.NewNRGBA(a.Rect)
+	copy(noisy.Pix, a.Pix)
+	for i := 0; i < len(noisy.Pix); i += 4 {
+		noisy.Pix[i] += uint8(random.Intn(64))
+	}
+	ssim, err := SSIM(a, noisy)
+	if err != nil {
+		t.Fatalf("SSIM failed: %v", err)
+	}
+	if ssim >= 0.99 || ssim <= 0 {
+		t.Errorf("Expected SSIM between 0 and 0.99 for a noisy image, got %f", ssim)
+	}
+
+	transparentA := filled(4, 4, color.NRGBA{R: 255})
+	transparentB := filled(4, 4, color.NRGBA{B: 255})
+	if ssim, err := SSIM(transparentA, transparentB); err != nil || ssim != 1 {
+		t.Errorf("Expected transparent images to match, got %f (%v)", ssim, err)
+	}
+
+	if _, err := SSIM(a, transparentA); err == nil {
+		t.Error("Expected error for different sizes, got nil")
+	}
+}
+
+// TestCheck tests that Check names the first mismatched pixel
+func TestCheck(t *testing.T) {
+	a := filled(2, 2, color.NRGBA{R: 10, A: 255})
+	b := filled(2, 2, color.NRGBA{R: 10, A: 255})
+	b.SetNRGBA(1, 1, color.NRGBA{R: 13, A: 255})
+
+	if err := Check(a, b, 3); err != nil {
+		t.Errorf("Expected match within tolerance, got %v", err)
+	}
+	err := Check(a, b, 2)
+	if err == nil || !strings.Contains(err.Error(), "(1,1)") {
+		t.Errorf("Expected mismatch at (1,1), got %v", err)
+	}
+	if err := Check(a, filled(3, 2, color.NRGBA{}), 0); err == nil {
+		t.Error("Expected error for different sizes, got nil")
+	}
+}
+
+// TestParseTolerance tests single and per-channel tolerances
+func TestParseTolerance(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Tolerance
+	}{
+		{"4", Tolerance{4, 4, 4, 4}},
+		{"2, 2, 2, 0", Tolerance{2, 2, 2, 0}},
+	}
+	for _, test := range tests {
+		tolerance, err := ParseTolerance(test.input)
+		if err != nil || tolerance != test.expected {
+			t.Errorf("ParseTolerance(%q): expected %v, got %v, %v", test.input, test.expected, tolerance, err)
+		}
+		if again, err := ParseTolerance(tolerance.String()); err != nil || again != tolerance {
+			t.Errorf("Expected %q to parse back to %v, got %v, %v", tolerance.String(), tolerance, again, err)
+		}
+	}
+	for _, input := range []string{"", "-1", "256", "1,2", "a,b,c,d"} {
+		if _, err := ParseTolerance(input); err == nil {
+			t.Errorf("ParseTolerance(%q): expected an error", input)
+		}
+	}
+}
+
+// TestCompareWithChannelTolerance tests that each channel is held to its own tolerance
+func TestCompareWithChannelTolerance(t *testing.T) {
+	a := filled(2, 2, color.NRGBA{R: 100, A: 255})
+	b := filled(2, 2, color.NRGBA{R: 104, A: 255})
+	b.SetNRGBA(0, 0, color.NRGBA{R: 100, A: 254})
+
+	result := CompareWith(a, b, Options{Tolerance: Tolerance{4, 4, 4, 0}})
+	if result.ChangedPixels != 1 || result.Bounds != image.Rect(0, 0, 1, 1) || result.MaxDelta != 4 {
+		t.Errorf("Expected only the alpha change at (0,0), got %d in %v with max delta %d",
+			result.ChangedPixels, result.Bounds, result.MaxDelta)
+	}
+	if err := CheckWith(a, b, Options{Tolerance: Tolerance{4, 4, 4, 0}}); err == nil || !strings.Contains(err.Error(), "(0,0)") {
+		t.Errorf("Expected mismatch at (0,0), got %v", err)
+	}
+	if err := CheckWith(a, b, Options{Tolerance: Tolerance{4, 0, 0, 1}}); err != nil {
+		t.Errorf("Expected match within tolerance, got %v", err)
+	}
+}
+
+// TestCompareAlphaWeighted tests that color differences count in proportion to alpha
+func TestCompareAlphaWeighted(t *testing.T) {
+	a := filled(2, 1, color.NRGBA{R: 255, A: 255})
+	b := filled(2, 1, color.NRGBA{R: 255, A: 255})
+	a.SetNRGBA(0, 0, color.NRGBA{R: 200, A: 8})
+	b.SetNRGBA(0, 0, color.NRGBA{B: 200, A: 8})
+
+	if result := Compare(a, b, 10); result.ChangedPixels != 1 || result.MaxDelta != 200 {
+		t.Errorf("Expected the faint pixel to differ by 200, got %d changed with %d", result.ChangedPixels, result.MaxDelta)
+	}
+	options := Options{Tolerance: UniformTolerance(10), AlphaWeighted: true}
+	if result := CompareWith(a, b, options); result.ChangedPixels != 0 || result.MaxDelta != 6 {
+		t.Errorf("Expected the faint pixel to differ by 6 weighted, got %d changed with %d", result.ChangedPixels, result.MaxDelta)
+	}
+	if heatmap := HeatmapWith(a, b, options); heatmap.NRGBAAt(0, 0).R >= heatmapMinRed {
+		t.Errorf("Expected no change in the alpha-weighted heatmap, got %v", heatmap.NRGBAAt(0, 0))
+	}
+}
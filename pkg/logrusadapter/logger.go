@@ -0,0 +1,30 @@
+// Package logrusadapter connects the converters' logging to logrus, which the CLI logs with.
+package logrusadapter
+
+import (
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+	"github.com/sirupsen/logrus"
+)
+
+// levels maps converter log levels to logrus levels
+var levels = map[converter.LogLevel]logrus.Level{
+	converter.LevelDebug: logrus.DebugLevel,
+	converter.LevelInfo:  logrus.InfoLevel,
+	converter.LevelWarn:  logrus.WarnLevel,
+	converter.LevelError: logrus.ErrorLevel,
+}
+
+// Logger is a converter.FieldLogger logging to a logrus logger, with fields as logrus fields
+type Logger struct {
+	*logrus.Logger
+}
+
+// New creates a Logger writing to logger
+func New(logger *logrus.Logger) *Logger {
+	return &Logger{logger}
+}
+
+// LogFields logs message with fields at level
+func (l *Logger) LogFields(level converter.LogLevel, message string, fields map[string]any) {
+	l.WithFields(fields).Log(levels[level], message)
+}
@@ -0,0 +1,29 @@
+package logrusadapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+	"github.com/sirupsen/logrus"
+)
+
+// TestLogFields tests that fields become logrus fields at the mapped level
+func TestLogFields(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := logrus.New()
+	logger.SetOutput(output)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	var fieldLogger converter.FieldLogger = New(logger)
+	fieldLogger.LogFields(converter.LevelWarn, "File converted", map[string]any{"file": "red.data", "index": 1})
+
+	var entry map[string]any
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatalf("Log line is not JSON: %s", output.String())
+	}
+	if entry["level"] != "warning" || entry["msg"] != "File converted" || entry["file"] != "red.data" || entry["index"] != float64(1) {
+		t.Errorf("Unexpected entry: %v", entry)
+	}
+}
@@ -0,0 +1,33 @@
+package logrusadapter
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ElapsedField is the log field holding seconds elapsed since the formatter was created
+const ElapsedField = "elapsed"
+
+// UTCFormatter wraps a logrus formatter so every entry is stamped in UTC and carries
+// the monotonic time elapsed since start, which keeps logs from different machines sortable and mergeable
+type UTCFormatter struct {
+	Formatter logrus.Formatter
+	start     time.Time
+}
+
+// NewUTCFormatter creates a UTCFormatter around formatter, measuring elapsed time from now
+func NewUTCFormatter(formatter logrus.Formatter) *UTCFormatter {
+	return &UTCFormatter{
+		Formatter: formatter,
+		start:     time.Now(),
+	}
+}
+
+// Format converts the entry time to UTC, adds the elapsed field and delegates to the wrapped formatter
+func (u *UTCFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry.Time = entry.Time.UTC()
+	// time.Since uses the monotonic clock, so wall clock adjustments don't skew the elapsed time
+	entry.Data[ElapsedField] = time.Since(u.start).Seconds()
+	return u.Formatter.Format(entry)
+}
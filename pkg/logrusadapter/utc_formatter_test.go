@@ -0,0 +1,34 @@
+package logrusadapter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestUTCFormatter tests that entries are stamped in UTC RFC3339 and carry the elapsed field
+func TestUTCFormatter(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := logrus.New()
+	logger.SetOutput(output)
+	logger.SetFormatter(NewUTCFormatter(&logrus.TextFormatter{
+		DisableColors:   true,
+		FullTimestamp:   true,
+		TimestampFormat: time.RFC3339Nano,
+	}))
+
+	entry := logrus.NewEntry(logger)
+	entry.Time = time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("UTC+9", 9*60*60))
+	entry.Info("hello")
+
+	line := output.String()
+	if !strings.Contains(line, `time="2024-03-01T03:00:00Z"`) {
+		t.Errorf("Expected UTC timestamp in log line, got %s", line)
+	}
+	if !strings.Contains(line, ElapsedField+"=") {
+		t.Errorf("Expected elapsed field in log line, got %s", line)
+	}
+}
@@ -0,0 +1,37 @@
+package mapformat
+
+// Attribute value types used by Celeste's binary element format
+const (
+	TypeBool    = "bool"
+	TypeByte    = "byte"
+	TypeInt16   = "int16"
+	TypeInt32   = "int32"
+	TypeFloat32 = "float32"
+	TypeLookup  = "lookup" // String stored as an index into the lookup table
+	TypeString  = "string" // String stored inline
+	TypeRLE     = "rle"    // String stored run-length encoded, used for tile data
+)
+
+// valueTypes maps the on-disk type byte to its name
+var valueTypes = []string{TypeBool, TypeByte, TypeInt16, TypeInt32, TypeFloat32, TypeLookup, TypeString, TypeRLE}
+
+// Map is a decoded Celeste map (.bin) file
+type Map struct {
+	Package string   `json:"package"`
+	Root    *Element `json:"root"`
+}
+
+// Element is a node of the map's element tree
+type Element struct {
+	Name       string      `json:"name"`
+	Attributes []Attribute `json:"attributes,omitempty"`
+	Children   []*Element  `json:"children,omitempty"`
+}
+
+// Attribute is a typed key/value pair on an element.
+// The type is kept so the element tree can be re-encoded without loss.
+type Attribute struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
@@ -0,0 +1,512 @@
+package mapformat
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+)
+
+// mapHeader is the string every Celeste map file starts with
+const mapHeader = "CELESTE MAP"
+
+// MapConverter handles the conversion between Celeste's binary map format and JSON
+type MapConverter struct {
+	log converter.Logger
+}
+
+// NewMapConverter creates a new MapConverter instance
+func NewMapConverter() *MapConverter {
+	return &MapConverter{
+		log: converter.DefaultLogger(),
+	}
+}
+
+// SetLogger sets where the converter logs, nil to discard its messages
+func (m *MapConverter) SetLogger(logger converter.Logger) {
+	if logger == nil {
+		logger = converter.NopLogger{}
+	}
+	m.log = logger
+}
+
+// BinToJson converts from Celeste's binary map format to pretty-printed JSON
+func (m *MapConverter) BinToJson(input io.Reader, output io.Writer) error {
+	celesteMap, err := m.Decode(input)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(celesteMap)
+}
+
+// JsonToBin converts from JSON produced by BinToJson back to Celeste's binary map format
+func (m *MapConverter) JsonToBin(input io.Reader, output io.Writer) error {
+	decoder := json.NewDecoder(input)
+	decoder.UseNumber()
+
+	var celesteMap Map
+	if err := decoder.Decode(&celesteMap); err != nil {
+		return fmt.Errorf("failed to parse map JSON: %w", err)
+	}
+
+	return m.Encode(&celesteMap, output)
+}
+
+// Decode reads a map in Celeste's binary format
+func (m *MapConverter) Decode(input io.Reader) (*Map, error) {
+	r := bufio.NewReader(input)
+
+	header, err := readString(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map header: %w", err)
+	}
+	if header != mapHeader {
+		return nil, errors.New("not a Celeste map file")
+	}
+
+	packageName, err := readString(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package name: %w", err)
+	}
+
+	var lookupCount int16
+	if err := binary.Read(r, binary.LittleEndian, &lookupCount); err != nil {
+		return nil, fmt.Errorf("failed to read lookup table size: %w", err)
+	}
+	if lookupCount < 0 {
+		return nil, fmt.Errorf("invalid lookup table size %d", lookupCount)
+	}
+
+	lookup := make([]string, lookupCount)
+	for i := range lookup {
+		if lookup[i], err = readString(r); err != nil {
+			return nil, fmt.Errorf("failed to read lookup table: %w", err)
+		}
+	}
+
+	m.log.Infof("MAP parameters: package %s, %d lookup strings", packageName, len(lookup))
+
+	root, err := m.readElement(r, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Map{Package: packageName, Root: root}, nil
+}
+
+// Encode writes a map in Celeste's binary format, rebuilding the string lookup table
+func (m *MapConverter) Encode(celesteMap *Map, output io.Writer) error {
+	if celesteMap.Root == nil {
+		return errors.New("map has no root element")
+	}
+
+	lookup := newLookupTable()
+	if err := lookup.collect(celesteMap.Root); err != nil {
+		return err
+	}
+	if len(lookup.strings) > math.MaxInt16 {
+		return fmt.Errorf("too many lookup strings (%d)", len(lookup.strings))
+	}
+
+	m.log.Infof("MAP parameters: package %s, %d lookup strings", celesteMap.Package, len(lookup.strings))
+
+	w := bufio.NewWriter(output)
+
+	if err := writeString(w, mapHeader); err != nil {
+		return err
+	}
+	if err := writeString(w, celesteMap.Package); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, int16(len(lookup.strings))); err != nil {
+		return err
+	}
+	for _, str := range lookup.strings {
+		if err := writeString(w, str); err != nil {
+			return err
+		}
+	}
+
+	if err := writeElement(w, celesteMap.Root, lookup); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// lookupTable assigns indices to strings in first-use order
+type lookupTable struct {
+	strings []string
+	indices map[string]int16
+}
+
+func newLookupTable() *lookupTable {
+	return &lookupTable{indices: make(map[string]int16)}
+}
+
+// add registers a string if it is not already in the table
+func (l *lookupTable) add(str string) {
+	if _, ok := l.indices[str]; ok {
+		return
+	}
+	l.indices[str] = int16(len(l.strings))
+	l.strings = append(l.strings, str)
+}
+
+// collect walks the element tree in the same order Celeste's packer does:
+// element name, then each attribute name followed by its value if it is a lookup string, then children
+func (l *lookupTable) collect(element *Element) error {
+	l.add(element.Name)
+	for _, attr := range element.Attributes {
+		l.add(attr.Name)
+		if attr.Type == TypeLookup {
+			str, ok := attr.Value.(string)
+			if !ok {
+				return fmt.Errorf("attribute '%s' of '%s' has type %s but value %v", attr.Name, element.Name, attr.Type, attr.Value)
+			}
+			l.add(str)
+		}
+	}
+	for _, child := range element.Children {
+		if err := l.collect(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeElement writes an element and all of its children
+func writeElement(w *bufio.Writer, element *Element, lookup *lookupTable) error {
+	if len(element.Attributes) > math.MaxUint8 {
+		return fmt.Errorf("element '%s' has too many attributes (%d)", element.Name, len(element.Attributes))
+	}
+	if len(element.Children) > math.MaxInt16 {
+		return fmt.Errorf("element '%s' has too many children (%d)", element.Name, len(element.Children))
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, lookup.indices[element.Name]); err != nil {
+		return err
+	}
+	if err := w.WriteByte(byte(len(element.Attributes))); err != nil {
+		return err
+	}
+
+	for _, attr := range element.Attributes {
+		if err := writeAttribute(w, attr, lookup); err != nil {
+			return fmt.Errorf("failed to write attribute '%s' of '%s': %w", attr.Name, element.Name, err)
+		}
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, int16(len(element.Children))); err != nil {
+		return err
+	}
+	for _, child := range element.Children {
+		if err := writeElement(w, child, lookup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAttribute writes a single typed attribute
+func writeAttribute(w *bufio.Writer, attr Attribute, lookup *lookupTable) error {
+	typeByte := -1
+	for i, name := range valueTypes {
+		if name == attr.Type {
+			typeByte = i
+		}
+	}
+	if typeByte < 0 {
+		return fmt.Errorf("unknown value type '%s'", attr.Type)
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, lookup.indices[attr.Name]); err != nil {
+		return err
+	}
+	if err := w.WriteByte(byte(typeByte)); err != nil {
+		return err
+	}
+
+	switch attr.Type {
+	case TypeBool:
+		v, ok := attr.Value.(bool)
+		if !ok {
+			return fmt.Errorf("expected bool, got %v", attr.Value)
+		}
+		if v {
+			return w.WriteByte(1)
+		}
+		return w.WriteByte(0)
+	case TypeByte:
+		v, err := integerValue(attr.Value, 0, math.MaxUint8)
+		if err != nil {
+			return err
+		}
+		return w.WriteByte(byte(v))
+	case TypeInt16:
+		v, err := integerValue(attr.Value, math.MinInt16, math.MaxInt16)
+		if err != nil {
+			return err
+		}
+		return binary.Write(w, binary.LittleEndian, int16(v))
+	case TypeInt32:
+		v, err := integerValue(attr.Value, math.MinInt32, math.MaxInt32)
+		if err != nil {
+			return err
+		}
+		return binary.Write(w, binary.LittleEndian, int32(v))
+	case TypeFloat32:
+		v, err := floatValue(attr.Value)
+		if err != nil {
+			return err
+		}
+		return binary.Write(w, binary.LittleEndian, float32(v))
+	}
+
+	str, ok := attr.Value.(string)
+	if !ok {
+		return fmt.Errorf("expected string, got %v", attr.Value)
+	}
+	switch attr.Type {
+	case TypeLookup:
+		return binary.Write(w, binary.LittleEndian, lookup.indices[str])
+	case TypeString:
+		return writeString(w, str)
+	default:
+		return writeRLEString(w, str)
+	}
+}
+
+// integerValue converts a decoded JSON or Go integer to int64, checking it lies within [min, max]
+func integerValue(value interface{}, min, max int64) (int64, error) {
+	var v int64
+	switch n := value.(type) {
+	case json.Number:
+		parsed, err := n.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("expected integer, got %s", n)
+		}
+		v = parsed
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, fmt.Errorf("expected integer, got %v", n)
+		}
+		v = int64(n)
+	case byte:
+		v = int64(n)
+	case int16:
+		v = int64(n)
+	case int32:
+		v = int64(n)
+	case int:
+		v = int64(n)
+	case int64:
+		v = n
+	default:
+		return 0, fmt.Errorf("expected integer, got %v", value)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, min, max)
+	}
+	return v, nil
+}
+
+// floatValue converts a decoded JSON or Go number to float64
+func floatValue(value interface{}) (float64, error) {
+	switch n := value.(type) {
+	case json.Number:
+		return n.Float64()
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	}
+	if v, err := integerValue(value, math.MinInt64, math.MaxInt64); err == nil {
+		return float64(v), nil
+	}
+	return 0, fmt.Errorf("expected number, got %v", value)
+}
+
+// readElement reads an element and all of its children
+func (m *MapConverter) readElement(r *bufio.Reader, lookup []string) (*Element, error) {
+	name, err := readLookup(r, lookup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read element name: %w", err)
+	}
+	element := &Element{Name: name}
+
+	attrCount, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attribute count of '%s': %w", name, err)
+	}
+
+	for i := 0; i < int(attrCount); i++ {
+		attr, err := readAttribute(r, lookup)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attribute of '%s': %w", name, err)
+		}
+		element.Attributes = append(element.Attributes, attr)
+	}
+
+	var childCount int16
+	if err := binary.Read(r, binary.LittleEndian, &childCount); err != nil {
+		return nil, fmt.Errorf("failed to read child count of '%s': %w", name, err)
+	}
+
+	for i := 0; i < int(childCount); i++ {
+		child, err := m.readElement(r, lookup)
+		if err != nil {
+			return nil, err
+		}
+		element.Children = append(element.Children, child)
+	}
+
+	return element, nil
+}
+
+// readAttribute reads a single typed attribute
+func readAttribute(r *bufio.Reader, lookup []string) (Attribute, error) {
+	name, err := readLookup(r, lookup)
+	if err != nil {
+		return Attribute{}, err
+	}
+
+	typeByte, err := r.ReadByte()
+	if err != nil {
+		return Attribute{}, err
+	}
+	if int(typeByte) >= len(valueTypes) {
+		return Attribute{}, fmt.Errorf("unknown value type %d for '%s'", typeByte, name)
+	}
+	attr := Attribute{Name: name, Type: valueTypes[typeByte]}
+
+	switch attr.Type {
+	case TypeBool:
+		b, err := r.ReadByte()
+		if err != nil {
+			return attr, err
+		}
+		attr.Value = b != 0
+	case TypeByte:
+		b, err := r.ReadByte()
+		if err != nil {
+			return attr, err
+		}
+		attr.Value = b
+	case TypeInt16:
+		var v int16
+		err = binary.Read(r, binary.LittleEndian, &v)
+		attr.Value = v
+	case TypeInt32:
+		var v int32
+		err = binary.Read(r, binary.LittleEndian, &v)
+		attr.Value = v
+	case TypeFloat32:
+		var v float32
+		err = binary.Read(r, binary.LittleEndian, &v)
+		attr.Value = v
+	case TypeLookup:
+		attr.Value, err = readLookup(r, lookup)
+	case TypeString:
+		attr.Value, err = readString(r)
+	case TypeRLE:
+		attr.Value, err = readRLEString(r)
+	}
+
+	return attr, err
+}
+
+// readLookup reads an int16 index and resolves it against the lookup table
+func readLookup(r io.Reader, lookup []string) (string, error) {
+	var index int16
+	if err := binary.Read(r, binary.LittleEndian, &index); err != nil {
+		return "", err
+	}
+	if index < 0 || int(index) >= len(lookup) {
+		return "", fmt.Errorf("lookup index %d out of range", index)
+	}
+	return lookup[index], nil
+}
+
+// readString reads a string prefixed with a 7-bit encoded length, as written by .NET's BinaryWriter
+func readString(r *bufio.Reader) (string, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if length > 1<<24 {
+		return "", fmt.Errorf("string length %d too large", length)
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// readRLEString reads a run-length encoded string made of (count, character) byte pairs
+func readRLEString(r io.Reader) (string, error) {
+	var length int16
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	if length < 0 || length%2 != 0 {
+		return "", fmt.Errorf("invalid run-length string size %d", length)
+	}
+
+	pairs := make([]byte, length)
+	if _, err := io.ReadFull(r, pairs); err != nil {
+		return "", err
+	}
+
+	var out []byte
+	for i := 0; i < len(pairs); i += 2 {
+		for j := 0; j < int(pairs[i]); j++ {
+			out = append(out, pairs[i+1])
+		}
+	}
+	return string(out), nil
+}
+
+// writeString writes a string prefixed with a 7-bit encoded length, as read by .NET's BinaryReader
+func writeString(w io.Writer, s string) error {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(s)))
+	if _, err := w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+// writeRLEString writes a string as (count, character) byte pairs with runs of at most 255
+func writeRLEString(w io.Writer, s string) error {
+	var pairs []byte
+	for i := 0; i < len(s); {
+		count := 1
+		for i+count < len(s) && s[i+count] == s[i] && count < math.MaxUint8 {
+			count++
+		}
+		pairs = append(pairs, byte(count), s[i])
+		i += count
+	}
+	if len(pairs) > math.MaxInt16 {
+		return fmt.Errorf("run-length string too long (%d bytes)", len(pairs))
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, int16(len(pairs))); err != nil {
+		return err
+	}
+	_, err := w.Write(pairs)
+	return err
+}
@@ -0,0 +1,205 @@
+package mapformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// testMapBuilder assembles a binary map by hand for decoder tests
+type testMapBuilder struct {
+	buf bytes.Buffer
+}
+
+func (b *testMapBuilder) str(s string) {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(s)))
+	b.buf.Write(lenBuf[:n])
+	b.buf.WriteString(s)
+}
+
+func (b *testMapBuilder) write(v interface{}) {
+	binary.Write(&b.buf, binary.LittleEndian, v)
+}
+
+// buildTestMap returns a small map exercising every attribute type
+func buildTestMap() []byte {
+	b := &testMapBuilder{}
+	b.str(mapHeader)
+	b.str("1-ForsakenCity")
+
+	// Strings are listed in first-use order, matching what Encode rebuilds
+	lookup := []string{"Map", "levels", "level", "name", "x", "dark", "alpha", "tileset", "scale", "solids", "innerText"}
+	b.write(int16(len(lookup)))
+	for _, s := range lookup {
+		b.str(s)
+	}
+
+	// Map
+	b.write(int16(0))
+	b.buf.WriteByte(0)
+	b.write(int16(1))
+
+	// levels
+	b.write(int16(1))
+	b.buf.WriteByte(0)
+	b.write(int16(1))
+
+	// level
+	b.write(int16(2))
+	b.buf.WriteByte(6)
+	b.write(int16(3)) // name: string
+	b.buf.WriteByte(6)
+	b.str("a-00")
+	b.write(int16(4)) // x: int32
+	b.buf.WriteByte(3)
+	b.write(int32(-320))
+	b.write(int16(5)) // dark: bool
+	b.buf.WriteByte(0)
+	b.buf.WriteByte(1)
+	b.write(int16(6)) // alpha: byte
+	b.buf.WriteByte(1)
+	b.buf.WriteByte(200)
+	b.write(int16(7)) // tileset: lookup
+	b.buf.WriteByte(5)
+	b.write(int16(1))
+	b.write(int16(8)) // scale: float32
+	b.buf.WriteByte(4)
+	b.write(math.Float32bits(0.5))
+	b.write(int16(1))
+
+	// solids
+	b.write(int16(9))
+	b.buf.WriteByte(1)
+	b.write(int16(10)) // innerText: rle "000111\n"
+	b.buf.WriteByte(7)
+	b.write(int16(6))
+	b.buf.Write([]byte{3, '0', 3, '1', 1, '\n'})
+	b.write(int16(0))
+
+	return b.buf.Bytes()
+}
+
+// TestDecode tests decoding every attribute type from a hand-built map
+func TestDecode(t *testing.T) {
+	celesteMap, err := NewMapConverter().Decode(bytes.NewReader(buildTestMap()))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if celesteMap.Package != "1-ForsakenCity" {
+		t.Errorf("Expected package '1-ForsakenCity', got '%s'", celesteMap.Package)
+	}
+
+	level := celesteMap.Root.Children[0].Children[0]
+	if level.Name != "level" {
+		t.Fatalf("Expected element 'level', got '%s'", level.Name)
+	}
+
+	expected := []Attribute{
+		{Name: "name", Type: TypeString, Value: "a-00"},
+		{Name: "x", Type: TypeInt32, Value: int32(-320)},
+		{Name: "dark", Type: TypeBool, Value: true},
+		{Name: "alpha", Type: TypeByte, Value: byte(200)},
+		{Name: "tileset", Type: TypeLookup, Value: "levels"},
+		{Name: "scale", Type: TypeFloat32, Value: float32(0.5)},
+	}
+	if len(level.Attributes) != len(expected) {
+		t.Fatalf("Expected %d attributes, got %d", len(expected), len(level.Attributes))
+	}
+	for i, attr := range level.Attributes {
+		if attr != expected[i] {
+			t.Errorf("Attribute %d: expected %+v, got %+v", i, expected[i], attr)
+		}
+	}
+
+	solids := level.Children[0]
+	if solids.Attributes[0].Value != "000111\n" {
+		t.Errorf("Expected decoded tiles '000111\\n', got %q", solids.Attributes[0].Value)
+	}
+}
+
+// TestBinToJson tests that the JSON output is valid and keeps the element tree
+func TestBinToJson(t *testing.T) {
+	output := new(bytes.Buffer)
+	if err := NewMapConverter().BinToJson(bytes.NewReader(buildTestMap()), output); err != nil {
+		t.Fatalf("BinToJson failed: %v", err)
+	}
+
+	var decoded Map
+	if err := json.Unmarshal(output.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if decoded.Root.Name != "Map" || decoded.Root.Children[0].Children[0].Children[0].Name != "solids" {
+		t.Errorf("Unexpected element tree in JSON output:\n%s", output.String())
+	}
+}
+
+// TestDecodeRejectsNonMap tests that files without the map header are rejected
+func TestDecodeRejectsNonMap(t *testing.T) {
+	b := &testMapBuilder{}
+	b.str("NOT A MAP")
+
+	if _, err := NewMapConverter().Decode(bytes.NewReader(b.buf.Bytes())); err == nil {
+		t.Fatal("Expected error for invalid header, got nil")
+	}
+}
+
+// TestJsonToBinRoundTrip tests that BIN -> JSON -> BIN reproduces the original bytes
+func TestJsonToBinRoundTrip(t *testing.T) {
+	mapConverter := NewMapConverter()
+	original := buildTestMap()
+
+	jsonOutput := new(bytes.Buffer)
+	if err := mapConverter.BinToJson(bytes.NewReader(original), jsonOutput); err != nil {
+		t.Fatalf("BinToJson failed: %v", err)
+	}
+
+	binOutput := new(bytes.Buffer)
+	if err := mapConverter.JsonToBin(jsonOutput, binOutput); err != nil {
+		t.Fatalf("JsonToBin failed: %v", err)
+	}
+
+	if !bytes.Equal(original, binOutput.Bytes()) {
+		t.Fatalf("Round trip mismatch:\nexpected %x\ngot      %x", original, binOutput.Bytes())
+	}
+}
+
+// TestEncodeLongRuns tests that runs longer than 255 characters are split
+func TestEncodeLongRuns(t *testing.T) {
+	tiles := strings.Repeat("0", 600) + "\n"
+	celesteMap := &Map{
+		Package: "test",
+		Root: &Element{
+			Name:       "solids",
+			Attributes: []Attribute{{Name: "innerText", Type: TypeRLE, Value: tiles}},
+		},
+	}
+
+	mapConverter := NewMapConverter()
+	output := new(bytes.Buffer)
+	if err := mapConverter.Encode(celesteMap, output); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	decoded, err := mapConverter.Decode(output)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.Root.Attributes[0].Value != tiles {
+		t.Errorf("Tiles did not survive round trip")
+	}
+}
+
+// TestJsonToBinRejectsOutOfRange tests that values not fitting their declared type are rejected
+func TestJsonToBinRejectsOutOfRange(t *testing.T) {
+	input := `{"package":"test","root":{"name":"Map","attributes":[{"name":"x","type":"byte","value":300}]}}`
+
+	err := NewMapConverter().JsonToBin(strings.NewReader(input), new(bytes.Buffer))
+	if err == nil {
+		t.Fatal("Expected error for out-of-range byte, got nil")
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+)
+
+// durationBuckets are the upper bounds in seconds of the conversion duration histogram
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metrics counts the conversions of a server, served on /metrics in the Prometheus text format
+type metrics struct {
+	mu          sync.Mutex
+	conversions map[string]*conversionMetrics
+	inFlight    atomic.Int64
+	started     time.Time
+}
+
+// conversionMetrics are the counters of a single conversion
+type conversionMetrics struct {
+	total, failed           int64
+	bytesRead, bytesWritten int64
+	buckets                 []int64 // Conversions per duration bucket, not cumulative
+	durationSum             float64
+}
+
+// newMetrics creates empty metrics for a server started now
+func newMetrics() *metrics {
+	return &metrics{conversions: make(map[string]*conversionMetrics), started: time.Now()}
+}
+
+// lookup returns the counters of a conversion, creating them if needed. The caller holds the lock.
+func (m *metrics) lookup(name string) *conversionMetrics {
+	c, ok := m.conversions[name]
+	if !ok {
+		c = &conversionMetrics{buckets: make([]int64, len(durationBuckets))}
+		m.conversions[name] = c
+	}
+	return c
+}
+
+// record counts a file converted by the conversion named name, failed if err isn't nil
+func (m *metrics) record(name string, duration time.Duration, bytesRead, bytesWritten int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.lookup(name)
+	c.total++
+	if err != nil {
+		c.failed++
+	}
+	c.bytesRead += bytesRead
+	c.bytesWritten += bytesWritten
+	seconds := duration.Seconds()
+	c.durationSum += seconds
+	if i := sort.SearchFloat64s(durationBuckets, seconds); i < len(durationBuckets) {
+		c.buckets[i]++
+	}
+}
+
+// write writes the metrics in the Prometheus text format, with a series for each of names even
+// before it is first used, and workers as the number of files converted at once
+func (m *metrics) write(w io.Writer, names []string, workers int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range names {
+		m.lookup(name)
+	}
+	names = names[:0:0]
+	for name := range m.conversions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeHeader(w, "celeste_converter_build_info", "gauge", "Version of the running converter.")
+	fmt.Fprintf(w, "celeste_converter_build_info{version=%s} 1\n", strconv.Quote(converter.Version))
+	writeHeader(w, "celeste_converter_start_time_seconds", "gauge", "Start time of the server in seconds since the Unix epoch.")
+	fmt.Fprintf(w, "celeste_converter_start_time_seconds %d\n", m.started.Unix())
+	writeHeader(w, "celeste_converter_workers", "gauge", "Files converted at once at most.")
+	fmt.Fprintf(w, "celeste_converter_workers %d\n", workers)
+	writeHeader(w, "celeste_converter_conversions_in_flight", "gauge", "Files being converted.")
+	fmt.Fprintf(w, "celeste_converter_conversions_in_flight %d\n", m.inFlight.Load())
+
+	counters := []struct {
+		name, help string
+		value      func(*conversionMetrics) int64
+	}{
+		{"celeste_converter_conversions_total", "Files converted, failed ones included.", func(c *conversionMetrics) int64 { return c.total }},
+		{"celeste_converter_conversion_failures_total", "Files that failed to convert.", func(c *conversionMetrics) int64 { return c.failed }},
+		{"celeste_converter_read_bytes_total", "Bytes of input files read.", func(c *conversionMetrics) int64 { return c.bytesRead }},
+		{"celeste_converter_written_bytes_total", "Bytes of converted files written.", func(c *conversionMetrics) int64 { return c.bytesWritten }},
+	}
+	for _, counter := range counters {
+		writeHeader(w, counter.name, "counter", counter.help)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{conversion=%s} %d\n", counter.name, strconv.Quote(name), counter.value(m.conversions[name]))
+		}
+	}
+
+	writeHeader(w, "celeste_converter_conversion_duration_seconds", "histogram", "Time taken to convert a file, waiting for a worker excluded.")
+	for _, name := range names {
+		c := m.conversions[name]
+		label := strconv.Quote(name)
+		cumulative := int64(0)
+		for i, bound := range durationBuckets {
+			cumulative += c.buckets[i]
+			fmt.Fprintf(w, "celeste_converter_conversion_duration_seconds_bucket{conversion=%s,le=\"%g\"} %d\n", label, bound, cumulative)
+		}
+		fmt.Fprintf(w, "celeste_converter_conversion_duration_seconds_bucket{conversion=%s,le=\"+Inf\"} %d\n", label, c.total)
+		fmt.Fprintf(w, "celeste_converter_conversion_duration_seconds_sum{conversion=%s} %g\n", label, c.durationSum)
+		fmt.Fprintf(w, "celeste_converter_conversion_duration_seconds_count{conversion=%s} %d\n", label, c.total)
+	}
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// handleMetrics serves the metrics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	var names []string
+	for _, c := range s.registry.Conversions() {
+		names = append(names, c.Name)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.write(w, names, cap(s.slots))
+}
+
+// handleHealth reports that the server is up
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok\n")
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
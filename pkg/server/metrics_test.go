@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// getText fetches a text endpoint of the test server
+func getText(t *testing.T, url string) (int, string) {
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return response.StatusCode, string(body)
+}
+
+// TestMetrics tests that conversions are counted on /metrics
+func TestMetrics(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	server.SetMaxConcurrent(3)
+	red := readTestData(t, "red.data")
+
+	_, body := getText(t, httpServer.URL+"/metrics")
+	if !strings.Contains(body, `celeste_converter_conversions_total{conversion="png2data"} 0`+"\n") {
+		t.Errorf("Expected a zero series for every conversion before any request, got:\n%s", body)
+	}
+
+	for _, input := range [][]byte{red, red, {1, 2}} {
+		response, err := http.Post(httpServer.URL+"/convert/data2png", "application/octet-stream", bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+	}
+
+	status, body := getText(t, httpServer.URL+"/metrics")
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	for _, line := range []string{
+		`celeste_converter_workers 3`,
+		`celeste_converter_conversions_in_flight 0`,
+		`celeste_converter_conversions_total{conversion="data2png"} 3`,
+		`celeste_converter_conversion_failures_total{conversion="data2png"} 1`,
+		fmt.Sprintf(`celeste_converter_read_bytes_total{conversion="data2png"} %d`, 2*len(red)+2),
+		`celeste_converter_conversion_duration_seconds_bucket{conversion="data2png",le="+Inf"} 3`,
+		`celeste_converter_conversion_duration_seconds_count{conversion="data2png"} 3`,
+		`# TYPE celeste_converter_conversion_duration_seconds histogram`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, `celeste_converter_written_bytes_total{conversion="data2png"} 0`) {
+		t.Error("Expected the written bytes to be counted")
+	}
+}
+
+// TestHealth tests the health endpoint
+func TestHealth(t *testing.T) {
+	_, httpServer := newTestServer(t)
+	if status, body := getText(t, httpServer.URL+"/healthz"); status != http.StatusOK || body != "ok\n" {
+		t.Errorf("Expected 200 ok, got %d %q", status, body)
+	}
+}
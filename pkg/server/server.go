@@ -0,0 +1,372 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+)
+
+const (
+	// defaultMaxRequestSize bounds a request body, a single file or a whole batch
+	defaultMaxRequestSize = 256 << 20
+	// defaultMaxUnzippedSize bounds the files of a zip archive upload once decompressed
+	defaultMaxUnzippedSize = 1 << 30
+	// ErrorsFileName is the entry of batch responses listing the files that failed to convert
+	ErrorsFileName = "errors.json"
+	// shutdownTimeout is how long Run waits for requests in flight once its context is cancelled
+	shutdownTimeout = 10 * time.Second
+)
+
+// errTooLarge is the error of a file of a batch over the size limits
+var errTooLarge = errors.New("file too large")
+
+// Server is an HTTP service converting uploaded files with the conversions of a registry, for asset
+// pipelines running on another machine than the game:
+//
+//	GET  /conversions     lists the conversions as JSON
+//	POST /convert/{name}  converts the request body and responds with the result
+//	GET  /metrics         serves conversion counts, failures, durations and sizes for Prometheus
+//	GET  /healthz         answers ok while the server is up
+//
+// The body is a single file, a multipart/form-data batch of files or a zip archive (Content-Type
+// application/zip). Batches are answered with a zip archive of the converted files, plus ErrorsFileName
+// if any of them failed.
+type Server struct {
+	registry       *converter.ConversionRegistry
+	log            converter.Logger
+	maxRequestSize int64
+	maxUnzipped    int64
+	slots          chan struct{} // Limits files converted at once
+	metrics        *metrics
+}
+
+// NewServer creates a new Server running the conversions of registry
+func NewServer(registry *converter.ConversionRegistry) *Server {
+	return &Server{
+		registry:       registry,
+		log:            converter.DefaultLogger(),
+		maxRequestSize: defaultMaxRequestSize,
+		maxUnzipped:    defaultMaxUnzippedSize,
+		slots:          make(chan struct{}, runtime.NumCPU()),
+		metrics:        newMetrics(),
+	}
+}
+
+// SetLogger sets where the server logs, nil to discard its messages
+func (s *Server) SetLogger(logger converter.Logger) {
+	if logger == nil {
+		logger = converter.NopLogger{}
+	}
+	s.log = logger
+}
+
+// SetMaxRequestSize sets the largest request body in bytes the server accepts
+func (s *Server) SetMaxRequestSize(size int64) {
+	if size > 0 {
+		s.maxRequestSize = size
+	}
+}
+
+// SetMaxUnzippedSize sets the most bytes the files of a zip archive upload may decompress to in total.
+// Each file is also limited to the largest request body.
+func (s *Server) SetMaxUnzippedSize(size int64) {
+	if size > 0 {
+		s.maxUnzipped = size
+	}
+}
+
+// SetMaxConcurrent sets how many files are converted at once across all requests
+func (s *Server) SetMaxConcurrent(files int) {
+	if files > 0 {
+		s.slots = make(chan struct{}, files)
+	}
+}
+
+// Handler returns the HTTP handler serving the endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /conversions", s.handleConversions)
+	mux.HandleFunc("POST /convert/{name}", s.handleConvert)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	return mux
+}
+
+// Run listens on addr, such as "localhost:8080", and serves requests until ctx is cancelled
+func (s *Server) Run(ctx context.Context, addr string) error {
+	httpServer := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		done <- httpServer.Shutdown(shutdownCtx)
+	}()
+
+	s.log.Infof("Serving conversions on %s", addr)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-done
+}
+
+// conversionInfo describes a conversion in the /conversions listing
+type conversionInfo struct {
+	Name    string `json:"name"`
+	FromExt string `json:"fromExt"`
+	ToExt   string `json:"toExt"`
+}
+
+// handleConversions lists the available conversions
+func (s *Server) handleConversions(w http.ResponseWriter, _ *http.Request) {
+	conversions := s.registry.Conversions()
+	infos := make([]conversionInfo, 0, len(conversions))
+	for _, c := range conversions {
+		infos = append(infos, conversionInfo{c.Name, c.FromExt, c.ToExt})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(infos); err != nil {
+		s.log.Warnf("Failed to write conversions: %v", err)
+	}
+}
+
+// handleConvert converts a single file, a multipart batch or a zip archive
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	conversion, ok := s.registry.Lookup(r.PathValue("name"))
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown conversion '%s'", r.PathValue("name")), http.StatusNotFound)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxRequestSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		s.convertMultipart(w, r, conversion)
+	case "application/zip", "application/x-zip-compressed":
+		s.convertZip(w, r, conversion)
+	default:
+		s.convertSingle(w, r, conversion)
+	}
+}
+
+// convertSingle converts the request body, named by the name query parameter, into the response
+func (s *Server) convertSingle(w http.ResponseWriter, r *http.Request, conversion converter.Conversion) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "texture" + conversion.FromExt
+	}
+	s.log.Infof("Converting %s with %s", name, conversion.Name)
+
+	// Read the whole file first, lenient decoders would take a cut-off body for a truncated file
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read %s: %v", name, err), statusFor(err))
+		return
+	}
+	var output bytes.Buffer
+	if err := s.convert(conversion, bytes.NewReader(data), &output); err != nil {
+		s.log.Warnf("Failed to convert %s: %v", name, err)
+		http.Error(w, fmt.Sprintf("couldn't convert %s: %v", name, err), statusFor(err))
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(conversion.ToExt))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": outputName(path.Base(name), conversion),
+	}))
+	if _, err := w.Write(output.Bytes()); err != nil {
+		s.log.Warnf("Failed to send %s: %v", name, err)
+	}
+}
+
+// convertMultipart converts every file part of a multipart batch, streaming the results back as a zip archive
+func (s *Server) convertMultipart(w http.ResponseWriter, r *http.Request, conversion converter.Conversion) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid multipart batch: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	batch := s.newBatchWriter(w, conversion)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			batch.fail("request", err)
+			break
+		}
+		if part.FileName() != "" {
+			batch.add(part.FileName(), part)
+		}
+		part.Close()
+	}
+	batch.close()
+}
+
+// convertZip converts the entries of an uploaded zip archive with the conversion's input extension,
+// streaming the results back as a zip archive with the same layout
+func (s *Server) convertZip(w http.ResponseWriter, r *http.Request, conversion converter.Conversion) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read archive: %v", err), statusFor(err))
+		return
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid zip archive: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// The sizes the archive declares are checked before answering, archive/zip fails entries running past them
+	var entries []*zip.File
+	var total uint64
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || !hasExtension(entry.Name, conversion.FromExt) {
+			continue
+		}
+		total += entry.UncompressedSize64
+		if entry.UncompressedSize64 > uint64(s.maxRequestSize) {
+			http.Error(w, fmt.Sprintf("%s is over %d bytes decompressed", entry.Name, s.maxRequestSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if total > uint64(s.maxUnzipped) {
+			http.Error(w, fmt.Sprintf("archive is over %d bytes decompressed", s.maxUnzipped), http.StatusRequestEntityTooLarge)
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	batch := s.newBatchWriter(w, conversion)
+	batch.remaining = s.maxUnzipped
+	for _, entry := range entries {
+		file, err := entry.Open()
+		if err != nil {
+			batch.fail(entry.Name, err)
+			continue
+		}
+		batch.add(entry.Name, file)
+		file.Close()
+	}
+	batch.close()
+}
+
+// batchWriter streams the converted files of a batch into a zip archive response
+type batchWriter struct {
+	server     *Server
+	conversion converter.Conversion
+	w          http.ResponseWriter
+	archive    *zip.Writer
+	failed     []converter.FailedConversion
+	remaining  int64 // Bytes the rest of the files may read
+}
+
+// newBatchWriter starts a zip archive response
+func (s *Server) newBatchWriter(w http.ResponseWriter, conversion converter.Conversion) *batchWriter {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+conversion.Name+`.zip"`)
+	return &batchWriter{server: s, conversion: conversion, w: w, archive: zip.NewWriter(w), remaining: s.maxRequestSize}
+}
+
+// add converts a file of the batch and writes it into the archive; failures are recorded instead
+func (b *batchWriter) add(name string, input io.Reader) {
+	b.server.log.Infof("Converting %s with %s", name, b.conversion.Name)
+	limit := max(min(b.server.maxRequestSize, b.remaining), 0)
+	data, err := io.ReadAll(io.LimitReader(input, limit+1))
+	b.remaining -= int64(len(data))
+	if err == nil && int64(len(data)) > limit {
+		err = fmt.Errorf("%w: over %d bytes", errTooLarge, limit)
+	}
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	var output bytes.Buffer
+	if err := b.server.convert(b.conversion, bytes.NewReader(data), &output); err != nil {
+		b.fail(name, err)
+		return
+	}
+	entry, err := b.archive.Create(outputName(strings.TrimLeft(path.Clean("/"+name), "/"), b.conversion))
+	if err == nil {
+		_, err = entry.Write(output.Bytes())
+	}
+	if err != nil {
+		b.server.log.Warnf("Failed to send %s: %v", name, err)
+	}
+}
+
+// fail records a file of the batch that couldn't be converted
+func (b *batchWriter) fail(name string, err error) {
+	b.server.log.Warnf("Failed to convert %s: %v", name, err)
+	b.failed = append(b.failed, converter.FailedConversion{Path: name, Reason: err.Error()})
+}
+
+// close adds ErrorsFileName if any file failed and finishes the archive
+func (b *batchWriter) close() {
+	if len(b.failed) > 0 {
+		entry, err := b.archive.Create(ErrorsFileName)
+		if err == nil {
+			err = json.NewEncoder(entry).Encode(b.failed)
+		}
+		if err != nil {
+			b.server.log.Warnf("Failed to send %s: %v", ErrorsFileName, err)
+		}
+	}
+	if err := b.archive.Close(); err != nil {
+		b.server.log.Warnf("Failed to finish batch response: %v", err)
+	}
+}
+
+// convert runs a conversion once a slot is free, recording it in the metrics
+func (s *Server) convert(conversion converter.Conversion, input io.Reader, output io.Writer) error {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+	s.metrics.inFlight.Add(1)
+	defer s.metrics.inFlight.Add(-1)
+
+	counted, countedOutput := &countingReader{r: input}, &countingWriter{w: output}
+	start := time.Now()
+	err := conversion.Convert(counted, countedOutput)
+	s.metrics.record(conversion.Name, time.Since(start), counted.n, countedOutput.n, err)
+	return err
+}
+
+// outputName replaces the conversion's input extension of name with its output extension
+func outputName(name string, conversion converter.Conversion) string {
+	if hasExtension(name, conversion.FromExt) {
+		name = name[:len(name)-len(conversion.FromExt)]
+	}
+	return name + conversion.ToExt
+}
+
+// hasExtension reports whether name ends with ext, ignoring case
+func hasExtension(name, ext string) bool {
+	return len(name) >= len(ext) && strings.EqualFold(name[len(name)-len(ext):], ext)
+}
+
+// statusFor returns the status of a failed conversion: 413 for oversized requests, 422 otherwise
+func statusFor(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, errTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusUnprocessableEntity
+}
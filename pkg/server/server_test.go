@@ -0,0 +1,229 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VictoriqueMoe/celeste-converter-go/pkg/converter"
+)
+
+// newTestServer starts a server with the built-in conversions
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	server := NewServer(converter.NewFilesConverter(converter.NewGraphicsConverter()).Registry())
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	return server, httpServer
+}
+
+// readTestData reads a file from the converter package's test data
+func readTestData(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("..", "converter", "testdata", "data", name))
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	return data
+}
+
+// expectedPng converts DATA to PNG directly, for comparison with the server's output
+func expectedPng(t *testing.T, data []byte) []byte {
+	var output bytes.Buffer
+	if err := converter.NewGraphicsConverter().DataToPng(bytes.NewReader(data), &output); err != nil {
+		t.Fatalf("DataToPng failed: %v", err)
+	}
+	return output.Bytes()
+}
+
+// readZipResponse returns the entries of a zip archive response by name
+func readZipResponse(t *testing.T, response *http.Response) map[string][]byte {
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("Expected a zip archive: %v", err)
+	}
+	entries := make(map[string][]byte)
+	for _, entry := range archive.File {
+		file, err := entry.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", entry.Name, err)
+		}
+		entries[entry.Name], _ = io.ReadAll(file)
+		file.Close()
+	}
+	return entries
+}
+
+// TestConvertSingle tests converting a single uploaded file
+func TestConvertSingle(t *testing.T) {
+	_, httpServer := newTestServer(t)
+	data := readTestData(t, "red.data")
+
+	response, err := http.Post(httpServer.URL+"/convert/data2png?name=red.data", "application/octet-stream", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", response.StatusCode)
+	}
+	if got := response.Header.Get("Content-Disposition"); !strings.Contains(got, "red.png") {
+		t.Errorf("Expected the output to be named red.png, got %q", got)
+	}
+	body, _ := io.ReadAll(response.Body)
+	if !bytes.Equal(body, expectedPng(t, data)) {
+		t.Error("Expected the response to be the converted PNG")
+	}
+}
+
+// TestConvertMultipart tests that a multipart batch is answered with a zip archive listing failures
+func TestConvertMultipart(t *testing.T) {
+	_, httpServer := newTestServer(t)
+	red := readTestData(t, "red.data")
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, content := range map[string][]byte{"red.data": red, "broken.data": {1, 2}} {
+		part, err := form.CreateFormFile("files", name)
+		if err != nil {
+			t.Fatalf("Failed to create part: %v", err)
+		}
+		part.Write(content)
+	}
+	form.Close()
+
+	response, err := http.Post(httpServer.URL+"/convert/data2png", form.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer response.Body.Close()
+	entries := readZipResponse(t, response)
+	if !bytes.Equal(entries["red.png"], expectedPng(t, red)) {
+		t.Error("Expected red.png in the batch response")
+	}
+	var failed []converter.FailedConversion
+	if err := json.Unmarshal(entries[ErrorsFileName], &failed); err != nil || len(failed) != 1 || failed[0].Path != "broken.data" {
+		t.Errorf("Expected broken.data to be listed in %s, got %v, %v", ErrorsFileName, failed, err)
+	}
+}
+
+// TestConvertZip tests that zip uploads are converted keeping their layout and skipping other files
+func TestConvertZip(t *testing.T) {
+	_, httpServer := newTestServer(t)
+	blue := readTestData(t, "blue.data")
+
+	var body bytes.Buffer
+	archive := zip.NewWriter(&body)
+	for name, content := range map[string][]byte{"sprites/blue.data": blue, "readme.txt": []byte("hi")} {
+		entry, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+		entry.Write(content)
+	}
+	archive.Close()
+
+	response, err := http.Post(httpServer.URL+"/convert/data2png", "application/zip", &body)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer response.Body.Close()
+	entries := readZipResponse(t, response)
+	if len(entries) != 1 || !bytes.Equal(entries["sprites/blue.png"], expectedPng(t, blue)) {
+		t.Errorf("Expected only sprites/blue.png in the response, got %d entries", len(entries))
+	}
+}
+
+// TestConvertErrors tests the status of unknown conversions, invalid and oversized files
+func TestConvertErrors(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	server.SetMaxRequestSize(16)
+
+	red := readTestData(t, "red.data") // 29 bytes
+	for _, test := range []struct {
+		url  string
+		body []byte
+		want int
+	}{
+		{"/convert/data2gif", []byte("texture"), http.StatusNotFound},
+		{"/convert/png2data", []byte("not a png"), http.StatusUnprocessableEntity},
+		{"/convert/data2png", red, http.StatusRequestEntityTooLarge},
+	} {
+		response, err := http.Post(httpServer.URL+test.url, "application/octet-stream", bytes.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != test.want {
+			t.Errorf("%s: expected %d, got %d", test.url, test.want, response.StatusCode)
+		}
+	}
+}
+
+// TestConvertZipTooLarge tests that zip uploads decompressing past the limits are refused
+func TestConvertZipTooLarge(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	server.SetMaxRequestSize(4096)
+	server.SetMaxUnzippedSize(8192)
+
+	for _, test := range []struct {
+		name        string
+		files, size int
+	}{
+		{"file over the request size", 1, 5000},
+		{"files over the decompressed size", 3, 3000},
+	} {
+		// Zeros compress well, so the archive itself fits in a request
+		var body bytes.Buffer
+		archive := zip.NewWriter(&body)
+		for i := 0; i < test.files; i++ {
+			entry, err := archive.Create(fmt.Sprintf("sprite%d.data", i))
+			if err != nil {
+				t.Fatalf("Failed to create entry: %v", err)
+			}
+			entry.Write(make([]byte, test.size))
+		}
+		archive.Close()
+
+		response, err := http.Post(httpServer.URL+"/convert/data2png", "application/zip", &body)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: expected %d, got %d", test.name, http.StatusRequestEntityTooLarge, response.StatusCode)
+		}
+	}
+}
+
+// TestConversions tests listing the conversions
+func TestConversions(t *testing.T) {
+	_, httpServer := newTestServer(t)
+	response, err := http.Get(httpServer.URL + "/conversions")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer response.Body.Close()
+	var infos []conversionInfo
+	if err := json.NewDecoder(response.Body).Decode(&infos); err != nil {
+		t.Fatalf("Failed to decode listing: %v", err)
+	}
+	found := false
+	for _, info := range infos {
+		found = found || (info.Name == "data2png" && info.FromExt == ".data" && info.ToExt == ".png")
+	}
+	if !found {
+		t.Errorf("Expected data2png to be listed, got %v", infos)
+	}
+}
@@ -0,0 +1,71 @@
+package xnb
+
+import "errors"
+
+// errLz4Corrupt is returned for LZ4 blocks that reference data outside their output
+var errLz4Corrupt = errors.New("corrupt LZ4 block")
+
+// lz4Decompress decodes src, a raw LZ4 block as MonoGame writes compressed content, into size bytes
+func lz4Decompress(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, min(size, maxPreallocation))
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		// Literals
+		literals, err := lz4Length(src, &i, int(token>>4))
+		if err != nil {
+			return nil, err
+		}
+		if literals > len(src)-i {
+			return nil, errLz4Corrupt
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+		if i == len(src) {
+			break // The last sequence has no match
+		}
+
+		// Match
+		if len(src)-i < 2 {
+			return nil, errLz4Corrupt
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+		length, err := lz4Length(src, &i, int(token&0x0f))
+		if err != nil {
+			return nil, err
+		}
+		length += 4
+		if offset == 0 || offset > len(dst) || len(dst)+length > size {
+			return nil, errLz4Corrupt
+		}
+		// Byte by byte, since a match may overlap its own output
+		start := len(dst) - offset
+		for n := 0; n < length; n++ {
+			dst = append(dst, dst[start+n])
+		}
+	}
+	if len(dst) != size {
+		return nil, errors.New("LZ4 block decodes to the wrong size")
+	}
+	return dst, nil
+}
+
+// lz4Length extends a 4-bit length of 15 with the following bytes of src, starting at *i
+func lz4Length(src []byte, i *int, length int) (int, error) {
+	if length != 15 {
+		return length, nil
+	}
+	for {
+		if *i >= len(src) {
+			return 0, errLz4Corrupt
+		}
+		b := src[*i]
+		*i++
+		length += int(b)
+		if b != 255 {
+			return length, nil
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package xnb
+
+import (
+	"bytes"
+	"testing"
+)
+
+// lz4Literals encodes data as a raw LZ4 block of a single literal run
+func lz4Literals(data []byte) []byte {
+	block := []byte{byte(min(len(data), 15)) << 4}
+	if len(data) >= 15 {
+		rest := len(data) - 15
+		for ; rest >= 255; rest -= 255 {
+			block = append(block, 255)
+		}
+		block = append(block, byte(rest))
+	}
+	return append(block, data...)
+}
+
+// TestLz4Decompress tests literal runs, overlapping matches and long lengths
+func TestLz4Decompress(t *testing.T) {
+	long := bytes.Repeat([]byte("0123456789"), 40)
+	if actual, err := lz4Decompress(lz4Literals(long), len(long)); err != nil || !bytes.Equal(actual, long) {
+		t.Errorf("Expected the literals back, got %q, %v", actual, err)
+	}
+
+	// "ab", then a match of 4+15+1 bytes at offset 2, then "c"
+	block := []byte{0x2f, 'a', 'b', 2, 0, 1, 0x10, 'c'}
+	expected := "ab" + string(bytes.Repeat([]byte("ab"), 10)) + "c"
+	if actual, err := lz4Decompress(block, len(expected)); err != nil || string(actual) != expected {
+		t.Errorf("Expected %q, got %q, %v", expected, actual, err)
+	}
+
+	for _, corrupt := range [][]byte{{0x10, 'a', 5, 0, 0x10, 'b'}, {0x30, 'a'}, {0x10, 'a', 1}} {
+		if _, err := lz4Decompress(corrupt, 8); err == nil {
+			t.Errorf("Expected %v to be rejected", corrupt)
+		}
+	}
+}
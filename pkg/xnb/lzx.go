@@ -0,0 +1,455 @@
+package xnb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// LZX block types
+const (
+	lzxBlockVerbatim     = 1
+	lzxBlockAligned      = 2
+	lzxBlockUncompressed = 3
+)
+
+const (
+	lzxWindowBits       = 16 // XNB content is always compressed with a 64 KiB window
+	lzxMinMatch         = 2
+	lzxPretreeElements  = 20
+	lzxAlignedElements  = 8
+	lzxLengthElements   = 249
+	lzxNumChars         = 256
+	lzxMaxPositionSlots = 50
+)
+
+// lzxExtraBits and lzxPositionBase are the extra bit counts and base offsets of the position slots
+var lzxExtraBits, lzxPositionBase = lzxPositionTables()
+
+func lzxPositionTables() (extraBits [lzxMaxPositionSlots + 2]int, positionBase [lzxMaxPositionSlots + 1]uint32) {
+	for i, bits := 0, 0; i <= lzxMaxPositionSlots; i += 2 {
+		extraBits[i], extraBits[i+1] = bits, bits
+		if i != 0 && bits < 17 {
+			bits++
+		}
+	}
+	for i, base := 0, uint32(0); i <= lzxMaxPositionSlots; i++ {
+		positionBase[i] = base
+		base += 1 << extraBits[i]
+	}
+	return extraBits, positionBase
+}
+
+// errLzxCorrupt is returned for LZX streams that can't be decoded
+var errLzxCorrupt = errors.New("corrupt LZX stream")
+
+// lzxDecoder decodes the LZX stream of compressed XNB content frame by frame, keeping the window,
+// repeated offsets and Huffman code lengths that carry over between frames
+type lzxDecoder struct {
+	window    []byte
+	windowPos int
+
+	r0, r1, r2   uint32 // Repeated match offsets
+	mainElements int
+
+	headerRead     bool
+	blockType      int
+	blockLength    int
+	blockRemaining int
+
+	intelFileSize int32 // Size given for the x86 call translation, 0 without it
+	intelCurPos   int32
+	intelStarted  bool
+	framesRead    int
+
+	mainLengths    [lzxNumChars + lzxMaxPositionSlots*8]byte
+	lengthLengths  [lzxLengthElements]byte
+	alignedLengths [lzxAlignedElements]byte
+	mainTree       huffmanTree
+	lengthTree     huffmanTree
+	alignedTree    huffmanTree
+}
+
+// newLzxDecoder creates a decoder for the 64 KiB window of XNB content
+func newLzxDecoder() *lzxDecoder {
+	return &lzxDecoder{
+		window:       make([]byte, 1<<lzxWindowBits),
+		r0:           1,
+		r1:           1,
+		r2:           1,
+		mainElements: lzxNumChars + (lzxWindowBits<<1)*8,
+	}
+}
+
+// decompress decodes the next frame of outLen bytes from in
+func (d *lzxDecoder) decompress(in []byte, outLen int) ([]byte, error) {
+	bits := &bitReader{data: in}
+	if !d.headerRead {
+		if bits.read(1) == 1 {
+			d.intelFileSize = int32(bits.read(16)<<16 | bits.read(16))
+		}
+		d.headerRead = true
+	}
+
+	windowSize := len(d.window)
+	toGo := outLen
+	for toGo > 0 {
+		if d.blockRemaining == 0 {
+			if err := d.readBlockHeader(bits); err != nil {
+				return nil, err
+			}
+		}
+
+		for d.blockRemaining > 0 && toGo > 0 {
+			run := min(d.blockRemaining, toGo)
+			toGo -= run
+			d.blockRemaining -= run
+			d.windowPos &= windowSize - 1
+			if d.windowPos+run > windowSize {
+				return nil, errLzxCorrupt
+			}
+
+			var err error
+			if d.blockType == lzxBlockUncompressed {
+				if len(in)-bits.pos < run {
+					return nil, errLzxCorrupt
+				}
+				copy(d.window[d.windowPos:], in[bits.pos:bits.pos+run])
+				bits.pos += run
+				d.windowPos += run
+			} else if run, err = d.decodeRun(bits, run); err != nil {
+				return nil, err
+			}
+
+			// A match may run past the end of the frame into the rest of the block
+			if run < 0 {
+				if -run > d.blockRemaining {
+					return nil, errLzxCorrupt
+				}
+				d.blockRemaining += run
+			}
+		}
+	}
+	if bits.overrun() {
+		return nil, errLzxCorrupt
+	}
+
+	end := d.windowPos
+	if end == 0 {
+		end = windowSize
+	}
+	if end < outLen {
+		return nil, errLzxCorrupt
+	}
+	out := make([]byte, outLen)
+	copy(out, d.window[end-outLen:end])
+	d.translateIntelCalls(out)
+	return out, nil
+}
+
+// readBlockHeader reads the type, length and trees or offsets of the next block
+func (d *lzxDecoder) readBlockHeader(bits *bitReader) error {
+	// Uncompressed blocks are padded to a 16-bit boundary
+	if d.blockType == lzxBlockUncompressed {
+		if d.blockLength&1 == 1 {
+			bits.pos++
+		}
+		bits.reset()
+	}
+
+	d.blockType = int(bits.read(3))
+	d.blockLength = int(bits.read(16)<<8 | bits.read(8))
+	d.blockRemaining = d.blockLength
+
+	var err error
+	switch d.blockType {
+	case lzxBlockAligned:
+		for i := range d.alignedLengths {
+			d.alignedLengths[i] = byte(bits.read(3))
+		}
+		if d.alignedTree, err = newHuffmanTree(d.alignedLengths[:]); err != nil {
+			return err
+		}
+		fallthrough
+	case lzxBlockVerbatim:
+		if err := readLzxLengths(bits, d.mainLengths[:lzxNumChars]); err != nil {
+			return err
+		}
+		if err := readLzxLengths(bits, d.mainLengths[lzxNumChars:d.mainElements]); err != nil {
+			return err
+		}
+		if d.mainTree, err = newHuffmanTree(d.mainLengths[:d.mainElements]); err != nil {
+			return err
+		}
+		if d.mainLengths[0xe8] != 0 {
+			d.intelStarted = true
+		}
+		if err := readLzxLengths(bits, d.lengthLengths[:]); err != nil {
+			return err
+		}
+		if d.lengthTree, err = newHuffmanTree(d.lengthLengths[:]); err != nil {
+			return err
+		}
+	case lzxBlockUncompressed:
+		d.intelStarted = true
+		// The offsets start at the next 16-bit boundary, giving back a prefetched word
+		bits.ensure(16)
+		if bits.bitsLeft > 16 {
+			bits.pos -= 2
+		}
+		if len(bits.data)-bits.pos < 12 {
+			return errLzxCorrupt
+		}
+		d.r0 = binary.LittleEndian.Uint32(bits.data[bits.pos:])
+		d.r1 = binary.LittleEndian.Uint32(bits.data[bits.pos+4:])
+		d.r2 = binary.LittleEndian.Uint32(bits.data[bits.pos+8:])
+		bits.pos += 12
+	default:
+		return fmt.Errorf("%w: invalid block type %d", errLzxCorrupt, d.blockType)
+	}
+	return nil
+}
+
+// decodeRun decodes run bytes of a verbatim or aligned block into the window, returning how far the
+// last match overshot them as a negative number
+func (d *lzxDecoder) decodeRun(bits *bitReader, run int) (int, error) {
+	windowMask := len(d.window) - 1
+	for run > 0 {
+		element, err := d.mainTree.decode(bits)
+		if err != nil {
+			return 0, err
+		}
+		if element < lzxNumChars {
+			d.window[d.windowPos] = byte(element)
+			d.windowPos++
+			run--
+			continue
+		}
+
+		element -= lzxNumChars
+		matchLength := element & 7
+		if matchLength == 7 {
+			extra, err := d.lengthTree.decode(bits)
+			if err != nil {
+				return 0, err
+			}
+			matchLength += extra
+		}
+		matchLength += lzxMinMatch
+
+		matchOffset, err := d.matchOffset(bits, element>>3)
+		if err != nil {
+			return 0, err
+		}
+		if matchOffset == 0 || int(matchOffset) > len(d.window) || d.windowPos+matchLength > len(d.window) {
+			return 0, errLzxCorrupt
+		}
+		// Byte by byte, since a match may overlap its own output or wrap around the window
+		for i := 0; i < matchLength; i++ {
+			d.window[d.windowPos] = d.window[(d.windowPos-int(matchOffset))&windowMask]
+			d.windowPos++
+		}
+		run -= matchLength
+	}
+	return run, nil
+}
+
+// matchOffset decodes the offset of a match in position slot, updating the repeated offsets
+func (d *lzxDecoder) matchOffset(bits *bitReader, slot int) (uint32, error) {
+	switch slot {
+	case 0:
+		return d.r0, nil
+	case 1:
+		d.r1, d.r0 = d.r0, d.r1
+		return d.r0, nil
+	case 2:
+		d.r2, d.r0 = d.r0, d.r2
+		return d.r0, nil
+	}
+
+	extra := lzxExtraBits[slot]
+	offset := lzxPositionBase[slot] - 2
+	if d.blockType == lzxBlockAligned && extra >= 3 {
+		// The lowest three bits come from the aligned offset tree
+		offset += bits.read(extra-3) << 3
+		aligned, err := d.alignedTree.decode(bits)
+		if err != nil {
+			return 0, err
+		}
+		offset += uint32(aligned)
+	} else if extra > 0 {
+		offset += bits.read(extra)
+	} else {
+		offset = 1
+	}
+	d.r2, d.r1, d.r0 = d.r1, d.r0, offset
+	return offset, nil
+}
+
+// translateIntelCalls undoes the x86 call translation of a decoded frame, which XNB content only
+// uses when the compressor was asked for it
+func (d *lzxDecoder) translateIntelCalls(frame []byte) {
+	d.framesRead++
+	if !d.intelStarted || d.intelFileSize == 0 || d.framesRead > 32768 || len(frame) <= 10 {
+		d.intelCurPos += int32(len(frame))
+		return
+	}
+	curPos := d.intelCurPos
+	for i := 0; i < len(frame)-10; {
+		if frame[i] != 0xe8 {
+			i++
+			curPos++
+			continue
+		}
+		absOffset := int32(binary.LittleEndian.Uint32(frame[i+1:]))
+		if absOffset >= -curPos && absOffset < d.intelFileSize {
+			relOffset := absOffset - curPos
+			if absOffset < 0 {
+				relOffset = absOffset + d.intelFileSize
+			}
+			binary.LittleEndian.PutUint32(frame[i+1:], uint32(relOffset))
+		}
+		i += 5
+		curPos += 5
+	}
+	d.intelCurPos += int32(len(frame))
+}
+
+// readLzxLengths reads Huffman code lengths, coded as changes to the previous block's lengths through
+// a pretree
+func readLzxLengths(bits *bitReader, lengths []byte) error {
+	var pretreeLengths [lzxPretreeElements]byte
+	for i := range pretreeLengths {
+		pretreeLengths[i] = byte(bits.read(4))
+	}
+	pretree, err := newHuffmanTree(pretreeLengths[:])
+	if err != nil {
+		return err
+	}
+
+	// delta applies a pretree symbol to a previous length
+	delta := func(previous byte, symbol int) byte {
+		return byte(((int(previous)-symbol)%17 + 17) % 17)
+	}
+	for i := 0; i < len(lengths); {
+		symbol, err := pretree.decode(bits)
+		if err != nil {
+			return err
+		}
+		var count int
+		var length byte
+		switch symbol {
+		case 17:
+			count = 4 + int(bits.read(4))
+		case 18:
+			count = 20 + int(bits.read(5))
+		case 19:
+			count = 4 + int(bits.read(1))
+			if symbol, err = pretree.decode(bits); err != nil {
+				return err
+			}
+			// Only a length change can follow, not another run
+			if symbol > 16 {
+				return fmt.Errorf("%w: run of pretree symbol %d", errLzxCorrupt, symbol)
+			}
+			length = delta(lengths[i], symbol)
+		default:
+			count, length = 1, delta(lengths[i], symbol)
+		}
+		if count > len(lengths)-i {
+			return errLzxCorrupt
+		}
+		for ; count > 0; count-- {
+			lengths[i] = length
+			i++
+		}
+	}
+	return nil
+}
+
+// bitReader reads the LZX bitstream: 16-bit little-endian words, each from its highest bit down.
+// Reading past the end yields zero bits.
+type bitReader struct {
+	data     []byte
+	pos      int
+	buffer   uint32 // Unread bits, from the highest down
+	bitsLeft int
+}
+
+// ensure buffers at least n bits, n being at most 17
+func (b *bitReader) ensure(n int) {
+	for b.bitsLeft < n {
+		var word uint32
+		if b.pos+1 < len(b.data) {
+			word = uint32(b.data[b.pos]) | uint32(b.data[b.pos+1])<<8
+		}
+		b.pos += 2
+		b.buffer |= word << (16 - b.bitsLeft)
+		b.bitsLeft += 16
+	}
+}
+
+// read returns the next n bits, n being at most 17
+func (b *bitReader) read(n int) uint32 {
+	if n == 0 {
+		return 0
+	}
+	b.ensure(n)
+	value := b.buffer >> (32 - n)
+	b.buffer <<= n
+	b.bitsLeft -= n
+	return value
+}
+
+// reset drops the buffered bits, to continue at pos
+func (b *bitReader) reset() {
+	b.buffer, b.bitsLeft = 0, 0
+}
+
+// overrun reports whether bits past the end of the data were used, beyond the word prefetched
+func (b *bitReader) overrun() bool {
+	return b.pos-b.bitsLeft/8 > len(b.data)
+}
+
+// huffmanTree decodes the canonical Huffman code of a set of code lengths, shorter codes first and
+// codes of the same length in symbol order
+type huffmanTree struct {
+	counts  [17]int // Number of codes of each length
+	symbols []int   // Symbols by code
+}
+
+// newHuffmanTree builds the code of lengths, symbols of length 0 having no code
+func newHuffmanTree(lengths []byte) (huffmanTree, error) {
+	var tree huffmanTree
+	for _, length := range lengths {
+		if int(length) >= len(tree.counts) {
+			return huffmanTree{}, fmt.Errorf("%w: code length %d", errLzxCorrupt, length)
+		}
+		tree.counts[length]++
+	}
+	tree.counts[0] = 0
+	for length := 1; length <= 16; length++ {
+		for symbol, symbolLength := range lengths {
+			if int(symbolLength) == length {
+				tree.symbols = append(tree.symbols, symbol)
+			}
+		}
+	}
+	return tree, nil
+}
+
+// decode reads a symbol, a bit at a time
+func (t *huffmanTree) decode(bits *bitReader) (int, error) {
+	code, first, index := 0, 0, 0
+	for length := 1; length <= 16; length++ {
+		code |= int(bits.read(1))
+		count := t.counts[length]
+		if code-first < count {
+			return t.symbols[index+code-first], nil
+		}
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+	return 0, fmt.Errorf("%w: invalid Huffman code", errLzxCorrupt)
+}
@@ -0,0 +1,174 @@
+package xnb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// bitWriter writes the LZX bitstream, the inverse of bitReader
+type bitWriter struct {
+	out   []byte
+	word  uint16
+	count int
+}
+
+func (w *bitWriter) write(value uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.word = w.word<<1 | uint16(value>>i&1)
+		if w.count++; w.count == 16 {
+			w.out = binary.LittleEndian.AppendUint16(w.out, w.word)
+			w.word, w.count = 0, 0
+		}
+	}
+}
+
+// align pads the stream to the next 16-bit word
+func (w *bitWriter) align() {
+	if w.count != 0 {
+		w.write(0, 16-w.count)
+	}
+}
+
+// writeFlatLengths writes lengths of 9 for every symbol, or 0 with zero set, through a pretree where
+// symbol 0 keeps a length of 0 and symbol 8 turns it into 9
+func writeFlatLengths(w *bitWriter, count int, zero bool) {
+	for i := 0; i < lzxPretreeElements; i++ {
+		length := uint32(0)
+		if i == 0 || i == 8 {
+			length = 1
+		}
+		w.write(length, 4)
+	}
+	for i := 0; i < count; i++ {
+		if zero {
+			w.write(0, 1)
+		} else {
+			w.write(1, 1)
+		}
+	}
+}
+
+// lzxUncompressed encodes data as a frame holding one uncompressed block, continuing a stream whose
+// header was written when first is unset
+func lzxUncompressed(data []byte, first bool) []byte {
+	var w bitWriter
+	if first {
+		w.write(0, 1)
+	}
+	w.write(lzxBlockUncompressed, 3)
+	w.write(uint32(len(data))>>8, 16)
+	w.write(uint32(len(data))&0xff, 8)
+	if w.count == 0 {
+		w.write(0, 16)
+	}
+	w.align()
+	for range 3 {
+		w.out = binary.LittleEndian.AppendUint32(w.out, 1)
+	}
+	w.out = append(w.out, data...)
+	if len(data)%2 == 1 {
+		w.out = append(w.out, 0)
+	}
+	return w.out
+}
+
+// lzxLiterals encodes data as a frame holding one verbatim block of literals, every main symbol having
+// a 9-bit code equal to its number, continuing a stream whose header was written when first is unset
+func lzxLiterals(data []byte, first bool) []byte {
+	var w bitWriter
+	if first {
+		w.write(0, 1)
+	}
+	w.write(lzxBlockVerbatim, 3)
+	w.write(uint32(len(data))>>8, 16)
+	w.write(uint32(len(data))&0xff, 8)
+	writeFlatLengths(&w, lzxNumChars, false)
+	writeFlatLengths(&w, 256, false)
+	writeFlatLengths(&w, lzxLengthElements, true)
+	for _, b := range data {
+		w.write(uint32(b), 9)
+	}
+	w.align()
+	return w.out
+}
+
+// lzxBody splits content into frames of frameSize, each encoded as one block by encode, prefixed by
+// their frame and block sizes like in XNB files
+func lzxBody(content []byte, frameSize int, encode func(frame []byte, first bool) []byte) []byte {
+	var body []byte
+	for i := 0; i < len(content); i += frameSize {
+		frame := content[i:min(i+frameSize, len(content))]
+		block := encode(frame, i == 0)
+		body = append(body, 0xff, byte(len(frame)>>8), byte(len(frame)), byte(len(block)>>8), byte(len(block)))
+		body = append(body, block...)
+	}
+	return body
+}
+
+// TestLzxDecompress tests a verbatim block of literals and a repeated-offset match, followed by an
+// uncompressed block in the next frame
+func TestLzxDecompress(t *testing.T) {
+	var w bitWriter
+	w.write(0, 1) // No x86 call translation
+	w.write(lzxBlockVerbatim, 3)
+	w.write(0, 16)
+	w.write(7, 8)
+	writeFlatLengths(&w, lzxNumChars, false)
+	writeFlatLengths(&w, 256, false)
+	writeFlatLengths(&w, lzxLengthElements, true)
+	// Every main symbol has a 9-bit code equal to its number
+	w.write('a', 9)
+	w.write('b', 9)
+	w.write(lzxNumChars+3, 9) // Match of 3+2 bytes at the initial repeated offset of 1
+	w.align()
+
+	decoder := newLzxDecoder()
+	frame, err := decoder.decompress(w.out, 7)
+	if err != nil || string(frame) != "abbbbbb" {
+		t.Fatalf("Expected abbbbbb, got %q, %v", frame, err)
+	}
+	frame, err = decoder.decompress(lzxUncompressed([]byte("hello"), false), 5)
+	if err != nil || string(frame) != "hello" {
+		t.Fatalf("Expected hello, got %q, %v", frame, err)
+	}
+
+	if _, err := newLzxDecoder().decompress([]byte{0xff, 0xff}, 4); err == nil {
+		t.Error("Expected an invalid block type to be rejected")
+	}
+}
+
+// TestLzxPositionTables tests the position slot tables against known values
+func TestLzxPositionTables(t *testing.T) {
+	if lzxExtraBits[3] != 0 || lzxExtraBits[4] != 1 || lzxExtraBits[36] != 17 || lzxExtraBits[50] != 17 {
+		t.Errorf("Unexpected extra bits %v", lzxExtraBits)
+	}
+	if lzxPositionBase[4] != 4 || lzxPositionBase[6] != 8 || lzxPositionBase[37] != 393216 {
+		t.Errorf("Unexpected position bases %v", lzxPositionBase)
+	}
+	if !bytes.Equal(lzxUncompressed(nil, true)[4:8], []byte{1, 0, 0, 0}) {
+		t.Error("Unexpected uncompressed block layout")
+	}
+}
+
+// TestReadLzxLengthsCorrupt tests that a run of pretree symbol 19 repeating another run is rejected
+func TestReadLzxLengthsCorrupt(t *testing.T) {
+	var w bitWriter
+	// Every pretree symbol has a 5-bit code equal to its number
+	for range lzxPretreeElements {
+		w.write(5, 4)
+	}
+	w.write(19, 5)
+	w.write(0, 1)
+	w.write(18, 5)
+	w.align()
+
+	lengths := make([]byte, 8)
+	if err := readLzxLengths(&bitReader{data: w.out}, lengths); !errors.Is(err, errLzxCorrupt) {
+		t.Errorf("Expected %v, got %v", errLzxCorrupt, err)
+	}
+	if _, err := newHuffmanTree([]byte{1, 17}); !errors.Is(err, errLzxCorrupt) {
+		t.Errorf("Expected a code length over 16 to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,545 @@
+// Package xnb reads and writes Texture2D assets in the XNB container of XNA, FNA and MonoGame games,
+// which other FNA games and some older Celeste tools store their textures in.
+package xnb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// XNB header flags
+const (
+	flagHiDef = 0x01 // Content targets the HiDef graphics profile
+	flagLz4   = 0x40 // Content is LZ4-compressed, as MonoGame writes it
+	flagLzx   = 0x80 // Content is LZX-compressed, as XNA writes it
+)
+
+const (
+	headerSize         = 10 // Magic, platform, version, flags and file size
+	formatVersion      = 5  // XNA Game Studio 4.0, also written by FNA and MonoGame
+	texture2DReader    = "Microsoft.Xna.Framework.Content.Texture2DReader"
+	texture2DReaderAQN = texture2DReader + ", Microsoft.Xna.Framework.Graphics, Version=4.0.0.0, Culture=neutral, PublicKeyToken=842cf8be1de50553"
+	lzxFrameSize       = 0x8000
+	maxPreallocation   = 64 << 20 // Largest buffer allocated from a size read from a header
+)
+
+// Default limits on decoded textures, matching the converter package's
+const (
+	DefaultMaxDimension   = 16384
+	DefaultMaxImageMemory = 256 << 20
+)
+
+// SurfaceFormat is the pixel format of a Texture2D, numbered as in XNA 4.0
+type SurfaceFormat int32
+
+// Surface formats that can be decoded; Color is the only one written
+const (
+	SurfaceColor    SurfaceFormat = 0  // 8-bit RGBA
+	SurfaceBgr565   SurfaceFormat = 1  // 16-bit packed RGB
+	SurfaceBgra5551 SurfaceFormat = 2  // 16-bit packed RGB with 1-bit alpha
+	SurfaceBgra4444 SurfaceFormat = 3  // 16-bit packed RGBA
+	SurfaceDxt1     SurfaceFormat = 4  // BC1 blocks
+	SurfaceDxt3     SurfaceFormat = 5  // BC2 blocks
+	SurfaceDxt5     SurfaceFormat = 6  // BC3 blocks
+	SurfaceAlpha8   SurfaceFormat = 12 // 8-bit alpha only
+)
+
+// surfaceFormatNames names the decodable surface formats
+var surfaceFormatNames = map[SurfaceFormat]string{
+	SurfaceColor:    "Color",
+	SurfaceBgr565:   "Bgr565",
+	SurfaceBgra5551: "Bgra5551",
+	SurfaceBgra4444: "Bgra4444",
+	SurfaceDxt1:     "Dxt1",
+	SurfaceDxt3:     "Dxt3",
+	SurfaceDxt5:     "Dxt5",
+	SurfaceAlpha8:   "Alpha8",
+}
+
+// String returns the XNA name of the format
+func (f SurfaceFormat) String() string {
+	if name, ok := surfaceFormatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("SurfaceFormat(%d)", int32(f))
+}
+
+// TextureConverter converts between XNB Texture2D assets and PNG images
+type TextureConverter struct {
+	premultiplied  bool
+	maxDimension   int
+	maxImageMemory int64 // Largest decoded pixel buffer in bytes
+}
+
+// NewTextureConverter creates a converter for textures with premultiplied alpha, as the XNA content
+// pipeline stores them by default
+func NewTextureConverter() *TextureConverter {
+	return &TextureConverter{premultiplied: true, maxDimension: DefaultMaxDimension, maxImageMemory: DefaultMaxImageMemory}
+}
+
+// SetPremultiplied sets whether XNB colors are premultiplied by alpha. Disable it for textures built
+// with PremultiplyAlpha turned off.
+func (c *TextureConverter) SetPremultiplied(premultiplied bool) {
+	c.premultiplied = premultiplied
+}
+
+// SetMaxDimension sets the largest texture width and height accepted when decoding XNB or PNG
+func (c *TextureConverter) SetMaxDimension(maxDimension int) {
+	c.maxDimension = maxDimension
+}
+
+// SetMaxImageMemory sets the largest decoded pixel buffer in bytes accepted when decoding XNB or PNG
+func (c *TextureConverter) SetMaxImageMemory(bytes int64) {
+	if bytes > 0 {
+		c.maxImageMemory = bytes
+	}
+}
+
+// checkSize validates texture dimensions against the limits, for pixels of bytesPerPixel bytes
+func (c *TextureConverter) checkSize(width, height, bytesPerPixel int) error {
+	if width <= 0 || height <= 0 || width > c.maxDimension || height > c.maxDimension {
+		return fmt.Errorf("invalid texture size %dx%d", width, height)
+	}
+	if size := int64(width) * int64(height) * int64(bytesPerPixel); size > c.maxImageMemory {
+		return fmt.Errorf("texture of %dx%d needs %d bytes, more than the budget of %d bytes", width, height, size, c.maxImageMemory)
+	}
+	return nil
+}
+
+// XnbToPng converts an XNB Texture2D asset to a PNG image of its largest mip level
+func (c *TextureConverter) XnbToPng(input io.Reader, output io.Writer) error {
+	img, err := c.Decode(input)
+	if err != nil {
+		return err
+	}
+	return png.Encode(output, img)
+}
+
+// PngToXnb converts a PNG image to an uncompressed XNB Texture2D asset
+func (c *TextureConverter) PngToXnb(input io.Reader, output io.Writer) error {
+	// Check the size in the header before decoding, keeping the bytes read to decode them again
+	var header bytes.Buffer
+	config, err := png.DecodeConfig(io.TeeReader(input, &header))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	bytesPerPixel := 4
+	if config.ColorModel == color.RGBA64Model || config.ColorModel == color.NRGBA64Model {
+		bytesPerPixel = 8
+	}
+	if err := c.checkSize(config.Width, config.Height, bytesPerPixel); err != nil {
+		return err
+	}
+
+	img, err := png.Decode(io.MultiReader(&header, input))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	return c.Encode(output, img)
+}
+
+// Decode reads an XNB Texture2D asset, uncompressed or LZX- or LZ4-compressed, returning its largest
+// mip level
+func (c *TextureConverter) Decode(input io.Reader) (*image.NRGBA, error) {
+	content, err := readContent(input)
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(content)
+
+	// Type readers, of which the primary asset must use a Texture2DReader
+	readerCount, err := binary.ReadUvarint(r)
+	if err != nil || readerCount > uint64(r.Len()) {
+		return nil, errors.New("invalid XNB type reader count")
+	}
+	readers := make([]string, readerCount)
+	for i := range readers {
+		if readers[i], err = readString(r); err != nil {
+			return nil, fmt.Errorf("invalid XNB type reader: %w", err)
+		}
+		var version int32
+		if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+			return nil, fmt.Errorf("invalid XNB type reader: %w", err)
+		}
+	}
+	if _, err := binary.ReadUvarint(r); err != nil {
+		return nil, errors.New("invalid XNB shared resource count")
+	}
+	typeID, err := binary.ReadUvarint(r)
+	if err != nil || typeID == 0 || typeID > readerCount {
+		return nil, errors.New("XNB asset has no valid type")
+	}
+	if reader, _, _ := strings.Cut(readers[typeID-1], ","); strings.TrimSpace(reader) != texture2DReader {
+		return nil, fmt.Errorf("XNB asset is not a Texture2D but read by %s", reader)
+	}
+
+	var header struct {
+		Format         SurfaceFormat
+		Width, Height  uint32
+		MipCount, Size uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("invalid Texture2D header: %w", err)
+	}
+	if header.Width > uint32(c.maxDimension) || header.Height > uint32(c.maxDimension) {
+		return nil, fmt.Errorf("invalid texture size %dx%d", header.Width, header.Height)
+	}
+	if err := c.checkSize(int(header.Width), int(header.Height), 4); err != nil {
+		return nil, err
+	}
+	if header.MipCount == 0 {
+		return nil, errors.New("Texture2D has no mip levels")
+	}
+	width, height := int(header.Width), int(header.Height)
+	expected, err := surfaceSize(header.Format, width, height)
+	if err != nil {
+		return nil, err
+	}
+	if int64(header.Size) != expected || int64(r.Len()) < expected {
+		return nil, fmt.Errorf("Texture2D holds %d bytes of %s pixels instead of %d", min(int64(header.Size), int64(r.Len())), header.Format, expected)
+	}
+	data := content[len(content)-r.Len():][:expected]
+
+	img := decodeSurface(header.Format, data, width, height)
+	if c.premultiplied {
+		unpremultiply(img)
+	}
+	return img, nil
+}
+
+// Encode writes img as an uncompressed XNB Texture2D asset for Windows, in the Color format with a
+// single mip level
+func (c *TextureConverter) Encode(output io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return errors.New("cannot encode an empty image")
+	}
+	pixels := image.NewNRGBA(image.Rect(0, 0, width, height))
+	switch src := img.(type) {
+	case *image.NRGBA:
+		for y := 0; y < height; y++ {
+			copy(pixels.Pix[y*pixels.Stride:][:width*4], src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y):])
+		}
+	case *image.Paletted:
+		// draw.Draw would round translucent palette colors through premultiplied alpha
+		palette := make([]color.NRGBA, len(src.Palette))
+		for i, c := range src.Palette {
+			palette[i] = color.NRGBAModel.Convert(c).(color.NRGBA)
+		}
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				pixels.SetNRGBA(x, y, palette[src.ColorIndexAt(bounds.Min.X+x, bounds.Min.Y+y)])
+			}
+		}
+	default:
+		draw.Draw(pixels, pixels.Bounds(), img, bounds.Min, draw.Src)
+	}
+	if c.premultiplied {
+		premultiply(pixels)
+	}
+
+	var content bytes.Buffer
+	content.Write(binary.AppendUvarint(nil, 1))
+	writeString(&content, texture2DReaderAQN)
+	binary.Write(&content, binary.LittleEndian, int32(0))
+	content.Write(binary.AppendUvarint(nil, 0)) // Shared resources
+	content.Write(binary.AppendUvarint(nil, 1)) // Type of the asset, the first reader
+	binary.Write(&content, binary.LittleEndian, []uint32{
+		uint32(SurfaceColor), uint32(width), uint32(height), 1, uint32(len(pixels.Pix)),
+	})
+	content.Write(pixels.Pix)
+
+	header := []byte{'X', 'N', 'B', 'w', formatVersion, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(header[6:], uint32(headerSize+content.Len()))
+	if _, err := output.Write(header); err != nil {
+		return err
+	}
+	_, err := content.WriteTo(output)
+	return err
+}
+
+// readContent reads the XNB header and returns the content following it, decompressed
+func readContent(input io.Reader) ([]byte, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(input, header); err != nil {
+		return nil, fmt.Errorf("failed to read XNB header: %w", err)
+	}
+	if string(header[:3]) != "XNB" {
+		return nil, errors.New("not an XNB file")
+	}
+	if version := header[4]; version != formatVersion {
+		return nil, fmt.Errorf("unsupported XNB format version %d, only XNA 4.0 files are supported", version)
+	}
+	flags := header[5]
+	fileSize := binary.LittleEndian.Uint32(header[6:])
+	if fileSize < headerSize {
+		return nil, fmt.Errorf("invalid XNB file size %d", fileSize)
+	}
+
+	compressed := flags&(flagLz4|flagLzx) != 0
+	bodySize := int64(fileSize) - headerSize
+	var decompressedSize uint32
+	if compressed {
+		if err := binary.Read(input, binary.LittleEndian, &decompressedSize); err != nil {
+			return nil, fmt.Errorf("failed to read XNB header: %w", err)
+		}
+		bodySize -= 4
+	}
+	if bodySize < 0 {
+		return nil, fmt.Errorf("invalid XNB file size %d", fileSize)
+	}
+	var body bytes.Buffer
+	body.Grow(int(min(bodySize, maxPreallocation)))
+	if n, err := io.Copy(&body, io.LimitReader(bufio.NewReader(input), bodySize)); err != nil {
+		return nil, err
+	} else if n < bodySize {
+		return nil, fmt.Errorf("XNB file is truncated: %d of %d bytes", n+headerSize, fileSize)
+	}
+
+	switch {
+	case flags&flagLzx != 0:
+		return decompressLzx(body.Bytes(), int(decompressedSize))
+	case flags&flagLz4 != 0:
+		return lz4Decompress(body.Bytes(), int(decompressedSize))
+	}
+	return body.Bytes(), nil
+}
+
+// decompressLzx decodes LZX-compressed XNB content: blocks of up to a 32 KiB frame each, prefixed by
+// their compressed size, and their frame size too when it differs from the default
+func decompressLzx(body []byte, size int) ([]byte, error) {
+	decoder := newLzxDecoder()
+	content := make([]byte, 0, min(size, maxPreallocation))
+	for pos := 0; pos+2 <= len(body) && len(content) < size; {
+		frameSize, blockSize := lzxFrameSize, int(body[pos])<<8|int(body[pos+1])
+		pos += 2
+		if body[pos-2] == 0xff {
+			if pos+3 > len(body) {
+				return nil, errLzxCorrupt
+			}
+			frameSize = int(body[pos-1])<<8 | int(body[pos])
+			blockSize = int(body[pos+1])<<8 | int(body[pos+2])
+			pos += 3
+		}
+		if blockSize == 0 || frameSize == 0 {
+			break
+		}
+		if pos+blockSize > len(body) {
+			return nil, errLzxCorrupt
+		}
+		frame, err := decoder.decompress(body[pos:pos+blockSize], min(frameSize, size-len(content)))
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, frame...)
+		pos += blockSize
+	}
+	if len(content) != size {
+		return nil, fmt.Errorf("LZX content decodes to %d bytes instead of %d", len(content), size)
+	}
+	return content, nil
+}
+
+// readString reads a .NET string: its UTF-8 length as a 7-bit encoded integer, then its bytes
+func readString(r *bytes.Reader) (string, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if length > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// writeString writes a .NET string
+func writeString(w *bytes.Buffer, value string) {
+	w.Write(binary.AppendUvarint(nil, uint64(len(value))))
+	w.WriteString(value)
+}
+
+// surfaceSize returns the size in bytes of a width x height surface in format
+func surfaceSize(format SurfaceFormat, width, height int) (int64, error) {
+	pixels := int64(width) * int64(height)
+	blocks := int64((width+3)/4) * int64((height+3)/4)
+	switch format {
+	case SurfaceColor:
+		return pixels * 4, nil
+	case SurfaceBgr565, SurfaceBgra5551, SurfaceBgra4444:
+		return pixels * 2, nil
+	case SurfaceAlpha8:
+		return pixels, nil
+	case SurfaceDxt1:
+		return blocks * 8, nil
+	case SurfaceDxt3, SurfaceDxt5:
+		return blocks * 16, nil
+	}
+	return 0, fmt.Errorf("unsupported surface format %s", format)
+}
+
+// decodeSurface decodes the pixels of a surface of a supported format, its size checked by the caller
+func decodeSurface(format SurfaceFormat, data []byte, width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	switch format {
+	case SurfaceColor:
+		copy(img.Pix, data)
+	case SurfaceBgr565, SurfaceBgra5551, SurfaceBgra4444:
+		for i := 0; i < width*height; i++ {
+			img.Pix[i*4], img.Pix[i*4+1], img.Pix[i*4+2], img.Pix[i*4+3] = unpack16(format, binary.LittleEndian.Uint16(data[i*2:]))
+		}
+	case SurfaceAlpha8:
+		for i, alpha := range data {
+			img.Pix[i*4+3] = alpha
+		}
+	case SurfaceDxt1, SurfaceDxt3, SurfaceDxt5:
+		decodeDxt(format, data, img)
+	}
+	return img
+}
+
+// unpack16 expands a 16-bit packed pixel to 8-bit RGBA
+func unpack16(format SurfaceFormat, v uint16) (r, g, b, a uint8) {
+	switch format {
+	case SurfaceBgr565:
+		return expand(v>>11, 5), expand(v>>5&0x3f, 6), expand(v&0x1f, 5), 0xff
+	case SurfaceBgra5551:
+		return expand(v>>10&0x1f, 5), expand(v>>5&0x1f, 5), expand(v&0x1f, 5), uint8(v>>15) * 0xff
+	}
+	return expand(v>>8&0xf, 4), expand(v>>4&0xf, 4), expand(v&0xf, 4), expand(v>>12, 4)
+}
+
+// expand scales a channel of the given bit count to 8 bits
+func expand(v uint16, bits int) uint8 {
+	return uint8(uint32(v) * 255 / (1<<bits - 1))
+}
+
+// decodeDxt decodes BC1, BC2 or BC3 blocks into img
+func decodeDxt(format SurfaceFormat, data []byte, img *image.NRGBA) {
+	blockSize := 16
+	if format == SurfaceDxt1 {
+		blockSize = 8
+	}
+	bounds := img.Bounds()
+	for by := 0; by < bounds.Dy(); by += 4 {
+		for bx := 0; bx < bounds.Dx(); bx += 4 {
+			block := data[:blockSize]
+			data = data[blockSize:]
+
+			var alphas [16]uint8
+			colorBlock := block
+			switch format {
+			case SurfaceDxt3:
+				for i := range alphas {
+					alphas[i] = (block[i/2] >> (4 * (i % 2)) & 0xf) * 0x11
+				}
+				colorBlock = block[8:]
+			case SurfaceDxt5:
+				alphas = dxt5Alphas(block)
+				colorBlock = block[8:]
+			}
+			colors := dxtColors(colorBlock, format == SurfaceDxt1)
+			indices := binary.LittleEndian.Uint32(colorBlock[4:])
+
+			for i := 0; i < 16; i++ {
+				x, y := bx+i%4, by+i/4
+				if x >= bounds.Dx() || y >= bounds.Dy() {
+					continue
+				}
+				c := colors[indices>>(2*i)&3]
+				if format != SurfaceDxt1 {
+					c.A = alphas[i]
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// dxtColors returns the four colors of a BC1 color block; BC1 textures use the 3-color mode with
+// transparent black when the first endpoint isn't above the second
+func dxtColors(block []byte, dxt1 bool) [4]color.NRGBA {
+	c0, c1 := binary.LittleEndian.Uint16(block), binary.LittleEndian.Uint16(block[2:])
+	var colors [4]color.NRGBA
+	for i, v := range []uint16{c0, c1} {
+		r, g, b, _ := unpack16(SurfaceBgr565, v)
+		colors[i] = color.NRGBA{r, g, b, 0xff}
+	}
+	mix := func(a, b uint8, wa, wb, total int) uint8 {
+		return uint8((int(a)*wa + int(b)*wb) / total)
+	}
+	if c0 > c1 || !dxt1 {
+		for i, w := range [][2]int{{2, 1}, {1, 2}} {
+			colors[2+i] = color.NRGBA{mix(colors[0].R, colors[1].R, w[0], w[1], 3),
+				mix(colors[0].G, colors[1].G, w[0], w[1], 3), mix(colors[0].B, colors[1].B, w[0], w[1], 3), 0xff}
+		}
+	} else {
+		colors[2] = color.NRGBA{mix(colors[0].R, colors[1].R, 1, 1, 2), mix(colors[0].G, colors[1].G, 1, 1, 2),
+			mix(colors[0].B, colors[1].B, 1, 1, 2), 0xff}
+		colors[3] = color.NRGBA{}
+	}
+	return colors
+}
+
+// dxt5Alphas returns the alpha of each pixel of a BC3 alpha block
+func dxt5Alphas(block []byte) [16]uint8 {
+	a0, a1 := int(block[0]), int(block[1])
+	var palette [8]uint8
+	palette[0], palette[1] = uint8(a0), uint8(a1)
+	if a0 > a1 {
+		for i := 1; i < 7; i++ {
+			palette[i+1] = uint8(((7-i)*a0 + i*a1) / 7)
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			palette[i+1] = uint8(((5-i)*a0 + i*a1) / 5)
+		}
+		palette[6], palette[7] = 0, 0xff
+	}
+
+	var bits uint64
+	for i := 0; i < 6; i++ {
+		bits |= uint64(block[2+i]) << (8 * i)
+	}
+	var alphas [16]uint8
+	for i := range alphas {
+		alphas[i] = palette[bits>>(3*i)&7]
+	}
+	return alphas
+}
+
+// premultiply scales the colors of img by their alpha
+func premultiply(img *image.NRGBA) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := uint32(img.Pix[i+3])
+		for c := 0; c < 3; c++ {
+			img.Pix[i+c] = uint8((uint32(img.Pix[i+c])*a + 127) / 255)
+		}
+	}
+}
+
+// unpremultiply divides the colors of img by their alpha, clamping colors brighter than their alpha
+func unpremultiply(img *image.NRGBA) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := uint32(img.Pix[i+3])
+		if a == 0 || a == 255 {
+			continue
+		}
+		for c := 0; c < 3; c++ {
+			img.Pix[i+c] = uint8(min((uint32(img.Pix[i+c])*255+a/2)/a, 255))
+		}
+	}
+}
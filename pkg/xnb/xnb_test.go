@@ -0,0 +1,204 @@
+package xnb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testImage returns a 5x3 image with opaque, transparent and half-transparent pixels
+func testImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 50), uint8(y * 100), 200, 0xff})
+		}
+	}
+	img.SetNRGBA(1, 1, color.NRGBA{})
+	img.SetNRGBA(2, 1, color.NRGBA{255, 0, 0, 128})
+	return img
+}
+
+// compressXnb rewrites an uncompressed XNB file with its content compressed by compress and flag set
+func compressXnb(t testing.TB, xnb []byte, flag byte, compress func([]byte) []byte) []byte {
+	t.Helper()
+	content := xnb[headerSize:]
+	body := compress(content)
+	compressed := append([]byte{}, xnb[:headerSize]...)
+	compressed[5] |= flag
+	binary.LittleEndian.PutUint32(compressed[6:], uint32(headerSize+4+len(body)))
+	compressed = binary.LittleEndian.AppendUint32(compressed, uint32(len(content)))
+	return append(compressed, body...)
+}
+
+// TestTextureRoundTrip tests that PNGs survive conversion to XNB and back, uncompressed and compressed
+func TestTextureRoundTrip(t *testing.T) {
+	converter := NewTextureConverter()
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, testImage()); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	var xnb bytes.Buffer
+	if err := converter.PngToXnb(bytes.NewReader(pngData.Bytes()), &xnb); err != nil {
+		t.Fatalf("PngToXnb failed: %v", err)
+	}
+	if !bytes.HasPrefix(xnb.Bytes(), []byte("XNBw\x05\x00")) || binary.LittleEndian.Uint32(xnb.Bytes()[6:]) != uint32(xnb.Len()) {
+		t.Fatalf("Unexpected XNB header % x", xnb.Bytes()[:headerSize])
+	}
+
+	// LZX content in frames of 64 bytes, each an uncompressed block
+	lzx := func(content []byte) []byte {
+		return lzxBody(content, 64, lzxUncompressed)
+	}
+	variants := map[string][]byte{
+		"uncompressed": xnb.Bytes(),
+		"lzx":          compressXnb(t, xnb.Bytes(), flagLzx, lzx),
+		"lz4":          compressXnb(t, xnb.Bytes(), flagLz4, lz4Literals),
+	}
+	for name, data := range variants {
+		t.Run(name, func(t *testing.T) {
+			var output bytes.Buffer
+			if err := converter.XnbToPng(bytes.NewReader(data), &output); err != nil {
+				t.Fatalf("XnbToPng failed: %v", err)
+			}
+			img, err := png.Decode(&output)
+			if err != nil {
+				t.Fatalf("Failed to decode PNG: %v", err)
+			}
+			expected := testImage()
+			for y := 0; y < 3; y++ {
+				for x := 0; x < 5; x++ {
+					if actual := color.NRGBAModel.Convert(img.At(x, y)); actual != expected.At(x, y) {
+						t.Errorf("Pixel (%d,%d): expected %v, got %v", x, y, expected.At(x, y), actual)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestDecodeDxt1 tests decoding a BC1 texture with transparent pixels in the 3-color mode
+func TestDecodeDxt1(t *testing.T) {
+	// Endpoints white and black in 3-color mode; rows of indices 0, 1, 2 and 3
+	block := []byte{0x00, 0x00, 0xff, 0xff, 0x00, 0x55, 0xaa, 0xff}
+	img := decodeSurface(SurfaceDxt1, block, 4, 4)
+	expected := []color.NRGBA{{0, 0, 0, 0xff}, {0xff, 0xff, 0xff, 0xff}, {0x7f, 0x7f, 0x7f, 0xff}, {}}
+	for y, c := range expected {
+		if actual := img.NRGBAAt(3, y); actual != c {
+			t.Errorf("Row %d: expected %v, got %v", y, c, actual)
+		}
+	}
+}
+
+// TestDecodeErrors tests that other assets and damaged files are rejected
+func TestDecodeErrors(t *testing.T) {
+	converter := NewTextureConverter()
+	var xnb bytes.Buffer
+	if err := converter.Encode(&xnb, testImage()); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	valid := xnb.Bytes()
+
+	oldVersion := append([]byte{}, valid...)
+	oldVersion[4] = 4
+	otherAsset := bytes.Replace(valid, []byte("Texture2DReader"), []byte("SoundEffectRdr!"), 1)
+	for name, data := range map[string][]byte{
+		"not xnb":     []byte("PNG stuff"),
+		"xna 3.1":     oldVersion,
+		"sound":       otherAsset,
+		"truncated":   valid[:len(valid)-4],
+		"bad lz4":     compressXnb(t, valid, flagLz4, func([]byte) []byte { return []byte{0x10, 'a', 9, 0} }),
+		"short frame": compressXnb(t, valid, flagLzx, func(content []byte) []byte { return []byte{0, 4, 1, 2, 3, 4} }),
+	} {
+		if _, err := converter.Decode(bytes.NewReader(data)); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+// FuzzDecode tests that damaged XNB files, compressed or not, fail to decode instead of panicking
+func FuzzDecode(f *testing.F) {
+	converter := NewTextureConverter()
+	var xnb bytes.Buffer
+	if err := converter.Encode(&xnb, testImage()); err != nil {
+		f.Fatalf("Encode failed: %v", err)
+	}
+	f.Add(xnb.Bytes())
+	f.Add(compressXnb(f, xnb.Bytes(), flagLzx, func(content []byte) []byte { return lzxBody(content, 64, lzxUncompressed) }))
+	f.Add(compressXnb(f, xnb.Bytes(), flagLzx, func(content []byte) []byte { return lzxBody(content, lzxFrameSize, lzxLiterals) }))
+	f.Add(compressXnb(f, xnb.Bytes(), flagLz4, lz4Literals))
+
+	converter.SetMaxDimension(256)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		converter.Decode(bytes.NewReader(data))
+	})
+}
+
+// TestPngToXnbTooLarge tests that PNGs over the limits are rejected from their header, before decoding
+func TestPngToXnbTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	// Claim 20000x20000 in the IHDR, leaving the image data of 5x3
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 20000)
+	binary.BigEndian.PutUint32(data[20:], 20000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	converter := NewTextureConverter()
+	if err := converter.PngToXnb(bytes.NewReader(data), io.Discard); err == nil || !strings.Contains(err.Error(), "20000x20000") {
+		t.Errorf("Expected the size to be rejected, got %v", err)
+	}
+	converter.SetMaxDimension(32768)
+	if err := converter.PngToXnb(bytes.NewReader(data), io.Discard); err == nil || !strings.Contains(err.Error(), "budget") {
+		t.Errorf("Expected the memory budget to be exceeded, got %v", err)
+	}
+}
+
+// TestEncodeImageTypes tests that every image type encodes to the colors its pixels convert to
+func TestEncodeImageTypes(t *testing.T) {
+	palette := color.Palette{color.NRGBA{255, 0, 0, 128}, color.NRGBA{1, 2, 3, 4}, color.RGBA{10, 20, 30, 255}}
+	paletted := image.NewPaletted(image.Rect(0, 0, 5, 3), palette)
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(i % len(palette))
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, 5, 3))
+	draw.Draw(rgba, rgba.Bounds(), testImage(), image.Point{}, draw.Src)
+	big := image.NewNRGBA(image.Rect(0, 0, 9, 9))
+	draw.Draw(big, image.Rect(2, 4, 7, 7), testImage(), image.Point{}, draw.Src)
+
+	converter := NewTextureConverter()
+	converter.SetPremultiplied(false)
+	for name, img := range map[string]image.Image{
+		"nrgba":    testImage(),
+		"sub":      big.SubImage(image.Rect(2, 4, 7, 7)),
+		"paletted": paletted,
+		"rgba":     rgba,
+	} {
+		var xnb bytes.Buffer
+		if err := converter.Encode(&xnb, img); err != nil {
+			t.Fatalf("%s: Encode failed: %v", name, err)
+		}
+		decoded, err := converter.Decode(bytes.NewReader(xnb.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: Decode failed: %v", name, err)
+		}
+		bounds := img.Bounds()
+		for y := 0; y < bounds.Dy(); y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				expected := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+				if actual := decoded.NRGBAAt(x, y); actual != expected {
+					t.Errorf("%s: pixel (%d,%d): expected %v, got %v", name, x, y, expected, actual)
+				}
+			}
+		}
+	}
+}